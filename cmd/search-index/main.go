@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log"
+
+	"github.com/yooerizkilab/library-system/internal/config"
+	"github.com/yooerizkilab/library-system/internal/database"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/search"
+	"github.com/yooerizkilab/library-system/internal/services"
+)
+
+// search-index rebuilds the catalog search index from the database and
+// writes it to SEARCH_INDEX_PATH, where the server picks it up on start.
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+	if cfg.SearchIndexPath == "" {
+		log.Fatal("SEARCH_INDEX_PATH is not set")
+	}
+
+	if err := database.ConnectDB(cfg); err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	db := database.GetDB()
+
+	searchIndex := search.NewMemoryIndex()
+	auditService := services.NewAuditService(repositories.NewAuditRepository(db))
+	bookService := services.NewBookService(
+		repositories.NewBookRepository(db),
+		repositories.NewAuthorRepository(db),
+		repositories.NewPublisherRepository(db),
+		repositories.NewClassificationRepository(db),
+		repositories.NewSubjectRepository(db),
+		repositories.NewWorkRepository(db),
+		auditService,
+		searchIndex,
+		search.NewMemorySuggester(),
+	)
+
+	count, err := bookService.RebuildSearchIndex()
+	if err != nil {
+		log.Fatal("Failed to rebuild search index:", err)
+	}
+
+	if err := search.SaveSnapshot(searchIndex, cfg.SearchIndexPath); err != nil {
+		log.Fatal("Failed to write search index:", err)
+	}
+
+	log.Printf("Indexed %d books into %s", count, cfg.SearchIndexPath)
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
 	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/yooerizkilab/library-system/internal/config"
 	"github.com/yooerizkilab/library-system/internal/database"
+	"github.com/yooerizkilab/library-system/internal/jobs"
 	"github.com/yooerizkilab/library-system/internal/routes"
 )
 
@@ -26,8 +28,13 @@ func main() {
 	}
 
 	// Create Fiber app
+	// Bodies over the limit are streamed rather than buffered, so upload
+	// routes can take larger files; middleware.BodyLimit checks them all
 	app := fiber.New(fiber.Config{
-		AppName: "Library System API v1.0.0",
+		AppName:                      "Library System API v1.0.0",
+		BodyLimit:                    cfg.BodyLimitMB << 20,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
 	// Rate limiting middleware
@@ -46,11 +53,12 @@ func main() {
 	}))
 
 	// Middleware
+	app.Use(requestid.New())
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,HEAD,PUT,DELETE,PATCH,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Authorization, X-Request-ID",
 	}))
 
 	// Health check endpoint
@@ -61,8 +69,10 @@ func main() {
 		})
 	})
 
-	// Setup routes
-	routes.SetupRoutes(app)
+	// Setup routes and background jobs
+	scheduler := jobs.NewScheduler()
+	routes.SetupRoutes(app, cfg, scheduler)
+	scheduler.Start()
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.AppPort)
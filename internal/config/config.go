@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +16,48 @@ type Config struct {
 	AppPort    string
 	AppEnv     string
 	JWTSecret  string
+
+	// Shown as the title of catalog feeds; both head printed letters
+	LibraryName    string
+	LibraryAddress string
+
+	// Largest accepted request body, e.g. file uploads
+	BodyLimitMB int
+
+	// Privacy
+	ErasureRetentionDays int
+	HistoryRetentionDays int
+
+	// Circulation
+	HoldPickupDays int
+
+	// Digital lending; download links are signed with DigitalURLSecret,
+	// or JWTSecret when it is empty
+	DigitalLoanDays    int
+	DigitalLinkMinutes int
+	DigitalURLSecret   string
+	DigitalMaxMB       int
+
+	// Acquisitions; fiscal years start on the first day of this month
+	FiscalYearStartMonth int
+
+	// Search
+	SearchIndexPath string
+
+	// Metadata enrichment
+	MetadataProviderURL string
+	MetadataCacheHours  int
+
+	// Blob storage for cover images and digital items: "local" or "s3"
+	BlobStore     string
+	BlobLocalDir  string
+	S3Endpoint    string
+	S3Region      string
+	S3Bucket      string
+	S3AccessKeyID string
+	S3SecretKey   string
+	S3PathStyle   bool
+	CoverMaxMB    int
 }
 
 func LoadConfig() (*Config, error) {
@@ -32,6 +75,38 @@ func LoadConfig() (*Config, error) {
 		AppPort:    getEnv("APP_PORT", "3000"),
 		AppEnv:     getEnv("APP_ENV", "development"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key-change-this-in-production"),
+
+		LibraryName:    getEnv("LIBRARY_NAME", "Library"),
+		LibraryAddress: getEnv("LIBRARY_ADDRESS", ""),
+
+		BodyLimitMB: getEnvInt("BODY_LIMIT_MB", 20),
+
+		ErasureRetentionDays: getEnvInt("ERASURE_RETENTION_DAYS", 30),
+		HistoryRetentionDays: getEnvInt("HISTORY_RETENTION_DAYS", 90),
+
+		HoldPickupDays: getEnvInt("HOLD_PICKUP_DAYS", 3),
+
+		DigitalLoanDays:    getEnvInt("DIGITAL_LOAN_DAYS", 14),
+		DigitalLinkMinutes: getEnvInt("DIGITAL_LINK_MINUTES", 60),
+		DigitalURLSecret:   getEnv("DIGITAL_URL_SECRET", ""),
+		DigitalMaxMB:       getEnvInt("DIGITAL_MAX_MB", 100),
+
+		FiscalYearStartMonth: getEnvInt("FISCAL_YEAR_START_MONTH", 1),
+
+		SearchIndexPath: getEnv("SEARCH_INDEX_PATH", ""),
+
+		MetadataProviderURL: getEnv("METADATA_PROVIDER_URL", "https://openlibrary.org"),
+		MetadataCacheHours:  getEnvInt("METADATA_CACHE_HOURS", 24),
+
+		BlobStore:     getEnv("BLOB_STORE", "local"),
+		BlobLocalDir:  getEnv("BLOB_LOCAL_DIR", "./storage"),
+		S3Endpoint:    getEnv("S3_ENDPOINT", ""),
+		S3Region:      getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:      getEnv("S3_BUCKET", ""),
+		S3AccessKeyID: getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretKey:   getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3PathStyle:   getEnv("S3_PATH_STYLE", "false") == "true",
+		CoverMaxMB:    getEnvInt("COVER_MAX_MB", 5),
 	}
 
 	return config, nil
@@ -43,3 +118,12 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -42,6 +42,41 @@ func AutoMigrate() error {
 		&models.User{},
 		&models.Book{},
 		&models.Borrow{},
+		&models.ErasureRequest{},
+		&models.AuditLog{},
+		&models.ImportJob{},
+		&models.Author{},
+		&models.AuthorAlias{},
+		&models.BookContributor{},
+		&models.Publisher{},
+		&models.PublisherAlias{},
+		&models.Classification{},
+		&models.Subject{},
+		&models.BookSubject{},
+		&models.Work{},
+		&models.Hold{},
+		&models.DigitalFile{},
+		&models.Notification{},
+		&models.PurchaseSuggestion{},
+		&models.SuggestionVote{},
+		&models.Vendor{},
+		&models.PurchaseOrder{},
+		&models.OrderLine{},
+		&models.Fund{},
+		&models.Budget{},
+		&models.FundTransaction{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+		&models.Donor{},
+		&models.Donation{},
+		&models.DonationItem{},
+		&models.Serial{},
+		&models.SerialSubscription{},
+		&models.SerialIssue{},
+		&models.SerialLoan{},
+		&models.Stocktake{},
+		&models.StocktakeScan{},
+		&models.StocktakeCount{},
 	)
 }
 
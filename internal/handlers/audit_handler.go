@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type AuditHandler struct {
+	auditService services.AuditService
+}
+
+func NewAuditHandler(auditService services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// currentActor builds the audit actor for the request. Public endpoints have
+// no authenticated user, so only the IP and request ID are known.
+func currentActor(c *fiber.Ctx) *models.Actor {
+	actor := &models.Actor{
+		IP: c.IP(),
+	}
+	if userID, ok := c.Locals("user_id").(uint); ok {
+		actor.UserID = userID
+	}
+	if role, ok := c.Locals("user_role").(string); ok {
+		actor.Role = role
+	}
+	if requestID, ok := c.Locals("requestid").(string); ok {
+		actor.RequestID = requestID
+	}
+	return actor
+}
+
+func (h *AuditHandler) SearchAuditLog(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	entries, page, err := h.auditService.Search(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to search audit log", err.Error())
+	}
+
+	return response.Paginated(c, "Audit log retrieved successfully", entries, page)
+}
+
+func (h *AuditHandler) VerifyAuditLog(c *fiber.Ctx) error {
+	result, err := h.auditService.Verify()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to verify audit log", err.Error())
+	}
+
+	return response.Success(c, "Audit log verified", result)
+}
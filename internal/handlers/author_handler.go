@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type AuthorHandler struct {
+	authorService services.AuthorService
+}
+
+func NewAuthorHandler(authorService services.AuthorService) *AuthorHandler {
+	return &AuthorHandler{
+		authorService: authorService,
+	}
+}
+
+func (h *AuthorHandler) GetAllAuthors(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	authors, page, err := h.authorService.GetAllAuthors(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get authors", err.Error())
+	}
+
+	return response.Paginated(c, "Authors retrieved successfully", authors, page)
+}
+
+func (h *AuthorHandler) GetAuthorByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid author ID", err.Error())
+	}
+
+	author, err := h.authorService.GetAuthorByID(uint(id))
+	if err != nil {
+		if err.Error() == "author not found" {
+			return response.NotFound(c, "Author not found")
+		}
+		return response.InternalServerError(c, "Failed to get author", err.Error())
+	}
+
+	return response.Success(c, "Author retrieved successfully", author)
+}
+
+func (h *AuthorHandler) GetAuthorBooks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid author ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.authorService.GetAuthorBooks(uint(id), spec)
+	if err != nil {
+		if err.Error() == "author not found" {
+			return response.NotFound(c, "Author not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get books", err.Error())
+	}
+
+	return response.Paginated(c, "Books retrieved successfully", books, page)
+}
+
+func (h *AuthorHandler) UpdateAuthor(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid author ID", err.Error())
+	}
+
+	var req models.UpdateAuthorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	author, err := h.authorService.UpdateAuthor(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "author not found" {
+			return response.NotFound(c, "Author not found")
+		}
+		return response.BadRequest(c, "Failed to update author", err.Error())
+	}
+
+	return response.Success(c, "Author updated successfully", author)
+}
+
+func (h *AuthorHandler) MergeAuthors(c *fiber.Ctx) error {
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	author, err := h.authorService.MergeAuthors(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "author not found" {
+			return response.NotFound(c, "Author not found")
+		}
+		return response.BadRequest(c, "Failed to merge authors", err.Error())
+	}
+
+	return response.Success(c, "Authors merged successfully", author)
+}
+
+func (h *AuthorHandler) GetDuplicateAuthors(c *fiber.Ctx) error {
+	duplicates, err := h.authorService.FindDuplicates()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to find duplicate authors", err.Error())
+	}
+
+	return response.Success(c, "Possible duplicate authors retrieved successfully", duplicates)
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yooerizkilab/library-system/internal/models"
 	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"github.com/yooerizkilab/library-system/pkg/response"
 )
 
@@ -25,7 +27,7 @@ func (h *BookHandler) CreateBook(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	book, err := h.bookService.CreateBook(&req)
+	book, err := h.bookService.CreateBook(currentActor(c), &req)
 	if err != nil {
 		return response.BadRequest(c, "Failed to create book", err.Error())
 	}
@@ -34,12 +36,20 @@ func (h *BookHandler) CreateBook(c *fiber.Ctx) error {
 }
 
 func (h *BookHandler) GetAllBooks(c *fiber.Ctx) error {
-	books, err := h.bookService.GetAllBooks()
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.bookService.GetAllBooks(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get books", err.Error())
 	}
 
-	return response.Success(c, "Books retrieved successfully", books)
+	return response.Paginated(c, "Books retrieved successfully", books, page)
 }
 
 func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
@@ -59,6 +69,21 @@ func (h *BookHandler) GetBookByID(c *fiber.Ctx) error {
 	return response.Success(c, "Book retrieved successfully", book)
 }
 
+func (h *BookHandler) GetBookByISBN(c *fiber.Ctx) error {
+	book, err := h.bookService.GetBookByISBN(c.Params("isbn"))
+	if err != nil {
+		if err.Error() == "invalid ISBN" {
+			return response.BadRequest(c, "Invalid ISBN", nil)
+		}
+		if err.Error() == "book not found" {
+			return response.NotFound(c, "Book not found")
+		}
+		return response.InternalServerError(c, "Failed to get book", err.Error())
+	}
+
+	return response.Success(c, "Book retrieved successfully", book)
+}
+
 func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
 	if err != nil {
@@ -70,7 +95,7 @@ func (h *BookHandler) UpdateBook(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	book, err := h.bookService.UpdateBook(uint(id), &req)
+	book, err := h.bookService.UpdateBook(currentActor(c), uint(id), &req)
 	if err != nil {
 		if err.Error() == "book not found" {
 			return response.NotFound(c, "Book not found")
@@ -87,7 +112,7 @@ func (h *BookHandler) DeleteBook(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid book ID", err.Error())
 	}
 
-	err = h.bookService.DeleteBook(uint(id))
+	err = h.bookService.DeleteBook(currentActor(c), uint(id))
 	if err != nil {
 		if err.Error() == "book not found" {
 			return response.NotFound(c, "Book not found")
@@ -99,17 +124,44 @@ func (h *BookHandler) DeleteBook(c *fiber.Ctx) error {
 }
 
 func (h *BookHandler) SearchBooks(c *fiber.Ctx) error {
-	query := c.Query("q")
-	if query == "" {
+	q := c.Query("q")
+	if q == "" {
 		return response.BadRequest(c, "Search query is required", nil)
 	}
 
-	books, err := h.bookService.SearchBooks(query)
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, facets, err := h.bookService.SearchBooks(q, spec)
 	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to search books", err.Error())
 	}
 
-	return response.Success(c, "Books search completed", books)
+	return response.Faceted(c, "Books search completed", books, page, facets)
+}
+
+func (h *BookHandler) SuggestBooks(c *fiber.Ctx) error {
+	q := c.Query("q")
+	if q == "" {
+		return response.BadRequest(c, "Search query is required", nil)
+	}
+
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 20 {
+		return response.BadRequest(c, "Invalid query parameters", "limit must be between 1 and 20")
+	}
+
+	suggestions, err := h.bookService.SuggestBooks(q, limit)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to get suggestions", err.Error())
+	}
+
+	return response.Success(c, "Suggestions retrieved successfully", suggestions)
 }
 
 func (h *BookHandler) GetBooksByCategory(c *fiber.Ctx) error {
@@ -118,19 +170,46 @@ func (h *BookHandler) GetBooksByCategory(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Category is required", nil)
 	}
 
-	books, err := h.bookService.GetBooksByCategory(category)
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.bookService.GetBooksByCategory(category, spec)
 	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get books by category", err.Error())
 	}
 
-	return response.Success(c, "Books retrieved successfully", books)
+	return response.Paginated(c, "Books retrieved successfully", books, page)
+}
+
+func (h *BookHandler) RebuildSearchIndex(c *fiber.Ctx) error {
+	count, err := h.bookService.RebuildSearchIndex()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to rebuild search index", err.Error())
+	}
+
+	return response.Success(c, "Search index rebuilt successfully", fiber.Map{
+		"indexed": count,
+	})
 }
 
 func (h *BookHandler) GetAvailableBooks(c *fiber.Ctx) error {
-	books, err := h.bookService.GetAvailableBooks()
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.bookService.GetAvailableBooks(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get available books", err.Error())
 	}
 
-	return response.Success(c, "Available books retrieved successfully", books)
+	return response.Paginated(c, "Available books retrieved successfully", books, page)
 }
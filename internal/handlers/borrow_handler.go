@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yooerizkilab/library-system/internal/models"
 	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"github.com/yooerizkilab/library-system/pkg/response"
 )
 
@@ -25,7 +27,7 @@ func (h *BorrowHandler) BorrowBook(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	borrow, err := h.borrowService.BorrowBook(&req)
+	borrow, err := h.borrowService.BorrowBook(currentActor(c), &req)
 	if err != nil {
 		return response.BadRequest(c, "Failed to borrow book", err.Error())
 	}
@@ -44,7 +46,7 @@ func (h *BorrowHandler) ReturnBook(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	borrow, err := h.borrowService.ReturnBook(uint(id), &req)
+	borrow, err := h.borrowService.ReturnBook(currentActor(c), uint(id), &req)
 	if err != nil {
 		if err.Error() == "borrow record not found" {
 			return response.NotFound(c, "Borrow record not found")
@@ -56,12 +58,20 @@ func (h *BorrowHandler) ReturnBook(c *fiber.Ctx) error {
 }
 
 func (h *BorrowHandler) GetAllBorrows(c *fiber.Ctx) error {
-	borrows, err := h.borrowService.GetAllBorrows()
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	borrows, page, err := h.borrowService.GetAllBorrows(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get borrows", err.Error())
 	}
 
-	return response.Success(c, "Borrows retrieved successfully", borrows)
+	return response.Paginated(c, "Borrows retrieved successfully", borrows, page)
 }
 
 func (h *BorrowHandler) GetBorrowByID(c *fiber.Ctx) error {
@@ -87,15 +97,31 @@ func (h *BorrowHandler) GetBorrowsByUser(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid user ID", err.Error())
 	}
 
-	borrows, err := h.borrowService.GetBorrowsByUser(uint(id))
+	return h.respondBorrowsByUser(c, uint(id))
+}
+
+func (h *BorrowHandler) GetMyBorrows(c *fiber.Ctx) error {
+	return h.respondBorrowsByUser(c, c.Locals("user_id").(uint))
+}
+
+func (h *BorrowHandler) respondBorrowsByUser(c *fiber.Ctx, userID uint) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	borrows, page, err := h.borrowService.GetBorrowsByUser(userID, spec)
 	if err != nil {
 		if err.Error() == "user not found" {
 			return response.NotFound(c, "User not found")
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get user borrows", err.Error())
 	}
 
-	return response.Success(c, "User borrows retrieved successfully", borrows)
+	return response.Paginated(c, "User borrows retrieved successfully", borrows, page)
 }
 
 func (h *BorrowHandler) GetBorrowsByBook(c *fiber.Ctx) error {
@@ -104,15 +130,23 @@ func (h *BorrowHandler) GetBorrowsByBook(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid book ID", err.Error())
 	}
 
-	borrows, err := h.borrowService.GetBorrowsByBook(uint(id))
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	borrows, page, err := h.borrowService.GetBorrowsByBook(uint(id), spec)
 	if err != nil {
 		if err.Error() == "book not found" {
 			return response.NotFound(c, "Book not found")
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get book borrows", err.Error())
 	}
 
-	return response.Success(c, "Book borrows retrieved successfully", borrows)
+	return response.Paginated(c, "Book borrows retrieved successfully", borrows, page)
 }
 
 func (h *BorrowHandler) UpdateBorrow(c *fiber.Ctx) error {
@@ -126,7 +160,7 @@ func (h *BorrowHandler) UpdateBorrow(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	borrow, err := h.borrowService.UpdateBorrow(uint(id), &req)
+	borrow, err := h.borrowService.UpdateBorrow(currentActor(c), uint(id), &req)
 	if err != nil {
 		if err.Error() == "borrow record not found" {
 			return response.NotFound(c, "Borrow record not found")
@@ -138,21 +172,37 @@ func (h *BorrowHandler) UpdateBorrow(c *fiber.Ctx) error {
 }
 
 func (h *BorrowHandler) GetActiveBorrows(c *fiber.Ctx) error {
-	borrows, err := h.borrowService.GetActiveBorrows()
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	borrows, page, err := h.borrowService.GetActiveBorrows(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get active borrows", err.Error())
 	}
 
-	return response.Success(c, "Active borrows retrieved successfully", borrows)
+	return response.Paginated(c, "Active borrows retrieved successfully", borrows, page)
 }
 
 func (h *BorrowHandler) GetOverdueBorrows(c *fiber.Ctx) error {
-	borrows, err := h.borrowService.GetOverdueBorrows()
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	borrows, page, err := h.borrowService.GetOverdueBorrows(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get overdue borrows", err.Error())
 	}
 
-	return response.Success(c, "Overdue borrows retrieved successfully", borrows)
+	return response.Paginated(c, "Overdue borrows retrieved successfully", borrows, page)
 }
 
 func (h *BorrowHandler) GetBorrowHistory(c *fiber.Ctx) error {
@@ -161,13 +211,29 @@ func (h *BorrowHandler) GetBorrowHistory(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid user ID", err.Error())
 	}
 
-	borrows, err := h.borrowService.GetBorrowHistory(uint(id))
+	return h.respondBorrowHistory(c, uint(id))
+}
+
+func (h *BorrowHandler) GetMyHistory(c *fiber.Ctx) error {
+	return h.respondBorrowHistory(c, c.Locals("user_id").(uint))
+}
+
+func (h *BorrowHandler) respondBorrowHistory(c *fiber.Ctx, userID uint) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	borrows, page, err := h.borrowService.GetBorrowHistory(userID, spec)
 	if err != nil {
 		if err.Error() == "user not found" {
 			return response.NotFound(c, "User not found")
 		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get borrow history", err.Error())
 	}
 
-	return response.Success(c, "Borrow history retrieved successfully", borrows)
+	return response.Paginated(c, "Borrow history retrieved successfully", borrows, page)
 }
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type ClassificationHandler struct {
+	classificationService services.ClassificationService
+}
+
+func NewClassificationHandler(classificationService services.ClassificationService) *ClassificationHandler {
+	return &ClassificationHandler{
+		classificationService: classificationService,
+	}
+}
+
+func (h *ClassificationHandler) GetClassifications(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	classifications, page, err := h.classificationService.GetClassifications(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get classifications", err.Error())
+	}
+
+	return response.Paginated(c, "Classifications retrieved successfully", classifications, page)
+}
+
+func (h *ClassificationHandler) GetClassificationByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	classification, err := h.classificationService.GetClassificationByID(uint(id))
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		return response.InternalServerError(c, "Failed to get classification", err.Error())
+	}
+
+	return response.Success(c, "Classification retrieved successfully", classification)
+}
+
+func (h *ClassificationHandler) GetClassificationBooks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.classificationService.GetClassificationBooks(uint(id), spec)
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get books", err.Error())
+	}
+
+	return response.Paginated(c, "Books retrieved successfully", books, page)
+}
+
+func (h *ClassificationHandler) CreateClassification(c *fiber.Ctx) error {
+	var req models.CreateClassificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	classification, err := h.classificationService.CreateClassification(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Parent classification not found")
+		}
+		return response.BadRequest(c, "Failed to create classification", err.Error())
+	}
+
+	return response.Created(c, "Classification created successfully", classification)
+}
+
+func (h *ClassificationHandler) UpdateClassification(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	var req models.UpdateClassificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	classification, err := h.classificationService.UpdateClassification(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		return response.BadRequest(c, "Failed to update classification", err.Error())
+	}
+
+	return response.Success(c, "Classification updated successfully", classification)
+}
+
+func (h *ClassificationHandler) DeleteClassification(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	err = h.classificationService.DeleteClassification(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		return response.BadRequest(c, "Failed to delete classification", err.Error())
+	}
+
+	return response.Success(c, "Classification deleted successfully", nil)
+}
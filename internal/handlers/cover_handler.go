@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type CoverHandler struct {
+	coverService services.CoverService
+}
+
+func NewCoverHandler(coverService services.CoverService) *CoverHandler {
+	return &CoverHandler{
+		coverService: coverService,
+	}
+}
+
+// UploadCover accepts the image either as a multipart "file" or as the raw
+// request body.
+func (h *CoverHandler) UploadCover(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	data := c.Body()
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return response.BadRequest(c, "Invalid cover image", err.Error())
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			return response.BadRequest(c, "Invalid cover image", err.Error())
+		}
+	}
+
+	book, err := h.coverService.UploadCover(currentActor(c), uint(id), data)
+	if err != nil {
+		if err.Error() == "book not found" {
+			return response.NotFound(c, "Book not found")
+		}
+		return response.BadRequest(c, "Failed to upload cover", err.Error())
+	}
+
+	return response.Success(c, "Cover uploaded successfully", book)
+}
+
+// GetCover serves one size of a book cover. Responses may be cached for a
+// day, and for good when the URL carries the cover version as ?v=.
+func (h *CoverHandler) GetCover(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	cover, err := h.coverService.GetCover(uint(id), c.Query("size"))
+	if err != nil {
+		switch err.Error() {
+		case "book not found":
+			return response.NotFound(c, "Book not found")
+		case "book has no cover":
+			return response.NotFound(c, "Book has no cover")
+		case "unknown cover size":
+			return response.BadRequest(c, "Unknown cover size", "size must be small, medium or large")
+		}
+		return response.InternalServerError(c, "Failed to get cover", err.Error())
+	}
+
+	version := strconv.FormatInt(cover.UpdatedAt.Unix(), 10)
+	if c.Query("v") == version {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=31536000, immutable")
+	} else {
+		c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+	}
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d-%s-%s"`, id, cover.Size, version))
+	c.Set(fiber.HeaderLastModified, cover.UpdatedAt.UTC().Format(http.TimeFormat))
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, cover.ContentType)
+	return c.Send(cover.Data)
+}
+
+func (h *CoverHandler) DeleteCover(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	book, err := h.coverService.DeleteCover(currentActor(c), uint(id))
+	if err != nil {
+		switch err.Error() {
+		case "book not found":
+			return response.NotFound(c, "Book not found")
+		case "book has no cover":
+			return response.NotFound(c, "Book has no cover")
+		}
+		return response.InternalServerError(c, "Failed to delete cover", err.Error())
+	}
+
+	return response.Success(c, "Cover deleted successfully", book)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type DigitalHandler struct {
+	digitalService services.DigitalService
+}
+
+func NewDigitalHandler(digitalService services.DigitalService) *DigitalHandler {
+	return &DigitalHandler{
+		digitalService: digitalService,
+	}
+}
+
+// UploadFile accepts an e-book or audiobook file as a multipart "file".
+func (h *DigitalHandler) UploadFile(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return response.BadRequest(c, "File is required", err.Error())
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		return response.BadRequest(c, "Invalid file", err.Error())
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return response.BadRequest(c, "Invalid file", err.Error())
+	}
+
+	digitalFile, err := h.digitalService.UploadFile(currentActor(c), uint(id), fileHeader.Filename, data)
+	if err != nil {
+		if err.Error() == "book not found" {
+			return response.NotFound(c, "Book not found")
+		}
+		return response.BadRequest(c, "Failed to upload file", err.Error())
+	}
+
+	return response.Created(c, "File uploaded successfully", digitalFile)
+}
+
+func (h *DigitalHandler) DeleteFile(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+	fileID, err := strconv.ParseUint(c.Params("fileId"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid file ID", err.Error())
+	}
+
+	if err := h.digitalService.DeleteFile(currentActor(c), uint(id), uint(fileID)); err != nil {
+		if err.Error() == "file not found" {
+			return response.NotFound(c, "File not found")
+		}
+		return response.InternalServerError(c, "Failed to delete file", err.Error())
+	}
+
+	return response.Success(c, "File deleted successfully", nil)
+}
+
+// Checkout lends a digital item to the current user.
+func (h *DigitalHandler) Checkout(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	borrow, err := h.digitalService.Checkout(currentActor(c), c.Locals("user_id").(uint), uint(id))
+	if err != nil {
+		switch err.Error() {
+		case "book not found":
+			return response.NotFound(c, "Book not found")
+		case "user not found":
+			return response.NotFound(c, "User not found")
+		}
+		return response.BadRequest(c, "Failed to borrow book", err.Error())
+	}
+
+	return response.Created(c, "Book borrowed successfully", borrow)
+}
+
+// Return ends one of the current user's digital loans.
+func (h *DigitalHandler) Return(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid borrow ID", err.Error())
+	}
+
+	borrow, err := h.digitalService.Return(currentActor(c), c.Locals("user_id").(uint), uint(id))
+	if err != nil {
+		if err.Error() == "borrow record not found" {
+			return response.NotFound(c, "Borrow record not found")
+		}
+		return response.BadRequest(c, "Failed to return book", err.Error())
+	}
+
+	return response.Success(c, "Book returned successfully", borrow)
+}
+
+// GetDownloads returns signed download links for one of the current
+// user's digital loans.
+func (h *DigitalHandler) GetDownloads(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid borrow ID", err.Error())
+	}
+
+	downloads, err := h.digitalService.GetDownloads(c.Locals("user_id").(uint), uint(id), c.BaseURL()+"/api/v1")
+	if err != nil {
+		switch err.Error() {
+		case "borrow record not found":
+			return response.NotFound(c, "Borrow record not found")
+		case "loan has ended":
+			return response.Gone(c, "Loan has ended")
+		case "book is not a digital item":
+			return response.BadRequest(c, "Book is not a digital item", nil)
+		}
+		return response.InternalServerError(c, "Failed to get downloads", err.Error())
+	}
+
+	return response.Success(c, "Downloads retrieved successfully", downloads)
+}
+
+// Download serves a file through a signed link. It needs no login, so
+// e-reader apps can fetch the link directly.
+func (h *DigitalHandler) Download(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid file ID", err.Error())
+	}
+	var req models.DownloadRequest
+	if err := c.QueryParser(&req); err != nil {
+		return response.Forbidden(c, "Download link is invalid")
+	}
+	req.FileID = uint(id)
+
+	content, err := h.digitalService.Download(&req)
+	if err != nil {
+		switch err.Error() {
+		case "download link is invalid":
+			return response.Forbidden(c, "Download link is invalid")
+		case "download link has expired":
+			return response.Forbidden(c, "Download link has expired")
+		case "loan has ended":
+			return response.Gone(c, "Loan has ended")
+		case "file not found":
+			return response.NotFound(c, "File not found")
+		}
+		return response.InternalServerError(c, "Failed to download file", err.Error())
+	}
+
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": content.FileName}))
+	c.Set(fiber.HeaderContentType, content.ContentType)
+	return c.Send(content.Data)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/pdf"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type DonationHandler struct {
+	donationService services.DonationService
+}
+
+func NewDonationHandler(donationService services.DonationService) *DonationHandler {
+	return &DonationHandler{
+		donationService: donationService,
+	}
+}
+
+func (h *DonationHandler) CreateDonation(c *fiber.Ctx) error {
+	var req models.CreateDonationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	donation, err := h.donationService.CreateDonation(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "donor not found" {
+			return response.NotFound(c, "Donor not found")
+		}
+		return response.BadRequest(c, "Failed to record donation", err.Error())
+	}
+
+	return response.Created(c, "Donation recorded successfully", donation)
+}
+
+func (h *DonationHandler) GetAllDonations(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	donations, page, err := h.donationService.GetAllDonations(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get donations", err.Error())
+	}
+
+	return response.Paginated(c, "Donations retrieved successfully", donations, page)
+}
+
+func (h *DonationHandler) GetDonationByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donation ID", err.Error())
+	}
+
+	donation, err := h.donationService.GetDonationByID(uint(id))
+	if err != nil {
+		if err.Error() == "donation not found" {
+			return response.NotFound(c, "Donation not found")
+		}
+		return response.InternalServerError(c, "Failed to get donation", err.Error())
+	}
+
+	return response.Success(c, "Donation retrieved successfully", donation)
+}
+
+func (h *DonationHandler) AddItems(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donation ID", err.Error())
+	}
+
+	var req models.AddDonationItemsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	donation, err := h.donationService.AddItems(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "donation not found" {
+			return response.NotFound(c, "Donation not found")
+		}
+		return response.BadRequest(c, "Failed to add donation items", err.Error())
+	}
+
+	return response.Success(c, "Donation items added successfully", donation)
+}
+
+func (h *DonationHandler) DecideItems(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donation ID", err.Error())
+	}
+
+	var req models.DecideDonationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	donation, err := h.donationService.DecideItems(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "donation not found" {
+			return response.NotFound(c, "Donation not found")
+		}
+		return response.BadRequest(c, "Failed to decide donation items", err.Error())
+	}
+
+	return response.Success(c, "Donation items decided successfully", donation)
+}
+
+// GetLetter downloads the acknowledgment letter of a donation as PDF.
+func (h *DonationHandler) GetLetter(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donation ID", err.Error())
+	}
+
+	letter, donation, err := h.donationService.AcknowledgmentLetter(uint(id))
+	if err != nil {
+		if err.Error() == "donation not found" {
+			return response.NotFound(c, "Donation not found")
+		}
+		return response.InternalServerError(c, "Failed to generate letter", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, pdf.ContentType)
+	c.Attachment(fmt.Sprintf("%s-letter.pdf", donation.Number))
+	return c.Send(letter)
+}
+
+func (h *DonationHandler) AcknowledgeDonation(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donation ID", err.Error())
+	}
+
+	donation, err := h.donationService.AcknowledgeDonation(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "donation not found" {
+			return response.NotFound(c, "Donation not found")
+		}
+		return response.InternalServerError(c, "Failed to acknowledge donation", err.Error())
+	}
+
+	return response.Success(c, "Donation acknowledged successfully", donation)
+}
+
+// GetDonorReport sums the donations of ?year, the current one by default,
+// as JSON or, with ?format=pdf, as a PDF table.
+func (h *DonationHandler) GetDonorReport(c *fiber.Ctx) error {
+	year := 0
+	if value := c.Query("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return response.BadRequest(c, "Invalid year", err.Error())
+		}
+		year = parsed
+	}
+
+	report, err := h.donationService.GetDonorReport(year)
+	if err != nil {
+		if err.Error() == "invalid year" {
+			return response.BadRequest(c, "Invalid year", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to build donor report", err.Error())
+	}
+
+	if c.Query("format") == "pdf" {
+		c.Set(fiber.HeaderContentType, pdf.ContentType)
+		c.Attachment(fmt.Sprintf("donor-report-%d.pdf", report.Year))
+		return c.Send(h.donationService.DonorReportPDF(report))
+	}
+
+	return response.Success(c, "Donor report retrieved successfully", report)
+}
+
+// GetBookProvenance lists the donations a book's copies came from.
+func (h *DonationHandler) GetBookProvenance(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	items, err := h.donationService.GetBookProvenance(uint(id))
+	if err != nil {
+		if err.Error() == "book not found" {
+			return response.NotFound(c, "Book not found")
+		}
+		return response.InternalServerError(c, "Failed to get book provenance", err.Error())
+	}
+
+	return response.Success(c, "Book provenance retrieved successfully", items)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type DonorHandler struct {
+	donorService services.DonorService
+}
+
+func NewDonorHandler(donorService services.DonorService) *DonorHandler {
+	return &DonorHandler{
+		donorService: donorService,
+	}
+}
+
+func (h *DonorHandler) CreateDonor(c *fiber.Ctx) error {
+	var req models.DonorInput
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	donor, err := h.donorService.CreateDonor(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to create donor", err.Error())
+	}
+
+	return response.Created(c, "Donor created successfully", donor)
+}
+
+func (h *DonorHandler) GetAllDonors(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	donors, page, err := h.donorService.GetAllDonors(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get donors", err.Error())
+	}
+
+	return response.Paginated(c, "Donors retrieved successfully", donors, page)
+}
+
+func (h *DonorHandler) GetDonorByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donor ID", err.Error())
+	}
+
+	donor, err := h.donorService.GetDonorByID(uint(id))
+	if err != nil {
+		if err.Error() == "donor not found" {
+			return response.NotFound(c, "Donor not found")
+		}
+		return response.InternalServerError(c, "Failed to get donor", err.Error())
+	}
+
+	return response.Success(c, "Donor retrieved successfully", donor)
+}
+
+func (h *DonorHandler) UpdateDonor(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid donor ID", err.Error())
+	}
+
+	var req models.UpdateDonorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	donor, err := h.donorService.UpdateDonor(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "donor not found" {
+			return response.NotFound(c, "Donor not found")
+		}
+		return response.BadRequest(c, "Failed to update donor", err.Error())
+	}
+
+	return response.Success(c, "Donor updated successfully", donor)
+}
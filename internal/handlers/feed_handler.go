@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/response"
+	"github.com/yooerizkilab/library-system/pkg/syndication"
+)
+
+// FeedHandler serves news feeds of new books as Atom or RSS, picked by the
+// extension, e.g. /feeds/new.atom or /feeds/new.rss.
+type FeedHandler struct {
+	feedService services.FeedService
+}
+
+func NewFeedHandler(feedService services.FeedService) *FeedHandler {
+	return &FeedHandler{
+		feedService: feedService,
+	}
+}
+
+func (h *FeedHandler) GetNewArrivals(c *fiber.Ctx) error {
+	format, ok := feedFormat(c)
+	if !ok {
+		return response.BadRequest(c, "Unsupported feed format, use atom or rss", nil)
+	}
+
+	feed, err := h.feedService.GetNewArrivals(c.BaseURL() + "/api/v1")
+	if err != nil {
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return sendSyndication(c, feed, format)
+}
+
+func (h *FeedHandler) GetCategoryFeed(c *fiber.Ctx) error {
+	format, ok := feedFormat(c)
+	if !ok {
+		return response.BadRequest(c, "Unsupported feed format, use atom or rss", nil)
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	feed, err := h.feedService.GetCategoryFeed(c.BaseURL()+"/api/v1", uint(id))
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return sendSyndication(c, feed, format)
+}
+
+func (h *FeedHandler) GetAuthorFeed(c *fiber.Ctx) error {
+	format, ok := feedFormat(c)
+	if !ok {
+		return response.BadRequest(c, "Unsupported feed format, use atom or rss", nil)
+	}
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid author ID", err.Error())
+	}
+
+	feed, err := h.feedService.GetAuthorFeed(c.BaseURL()+"/api/v1", uint(id))
+	if err != nil {
+		if err.Error() == "author not found" {
+			return response.NotFound(c, "Author not found")
+		}
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return sendSyndication(c, feed, format)
+}
+
+func feedFormat(c *fiber.Ctx) (string, bool) {
+	format := c.Params("format")
+	return format, format == syndication.FormatAtom || format == syndication.FormatRSS
+}
+
+// sendSyndication answers 304 when the reader already has this version of
+// the feed. The ETag changes whenever an item is added, removed or edited.
+func sendSyndication(c *fiber.Ctx, feed *syndication.Feed, format string) error {
+	feed.Self = c.BaseURL() + c.OriginalURL()
+
+	hash := sha1.New()
+	hash.Write([]byte(format + "\n" + feed.Title + "\n"))
+	for _, item := range feed.Items {
+		hash.Write([]byte(item.ID + " " + strconv.FormatInt(item.Updated.UnixNano(), 10) + "\n"))
+	}
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	c.Set(fiber.HeaderETag, `"`+hex.EncodeToString(hash.Sum(nil))+`"`)
+	c.Set(fiber.HeaderLastModified, feed.Updated.UTC().Format(http.TimeFormat))
+	if c.Fresh() {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	data, err := syndication.Encode(feed, format)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to encode feed", err.Error())
+	}
+	c.Set(fiber.HeaderContentType, syndication.ContentType(format)+"; charset=utf-8")
+	return c.Send(data)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type FundHandler struct {
+	fundService services.FundService
+}
+
+func NewFundHandler(fundService services.FundService) *FundHandler {
+	return &FundHandler{
+		fundService: fundService,
+	}
+}
+
+func (h *FundHandler) CreateFund(c *fiber.Ctx) error {
+	var req models.CreateFundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	fund, err := h.fundService.CreateFund(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to create fund", err.Error())
+	}
+
+	return response.Created(c, "Fund created successfully", fund)
+}
+
+func (h *FundHandler) GetAllFunds(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	funds, page, err := h.fundService.GetAllFunds(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get funds", err.Error())
+	}
+
+	return response.Paginated(c, "Funds retrieved successfully", funds, page)
+}
+
+func (h *FundHandler) GetFundByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid fund ID", err.Error())
+	}
+
+	fund, err := h.fundService.GetFundByID(uint(id))
+	if err != nil {
+		if err.Error() == "fund not found" {
+			return response.NotFound(c, "Fund not found")
+		}
+		return response.InternalServerError(c, "Failed to get fund", err.Error())
+	}
+
+	return response.Success(c, "Fund retrieved successfully", fund)
+}
+
+func (h *FundHandler) UpdateFund(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid fund ID", err.Error())
+	}
+
+	var req models.UpdateFundRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	fund, err := h.fundService.UpdateFund(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "fund not found" {
+			return response.NotFound(c, "Fund not found")
+		}
+		return response.BadRequest(c, "Failed to update fund", err.Error())
+	}
+
+	return response.Success(c, "Fund updated successfully", fund)
+}
+
+// AllocateBudget sets a fund's allocation for the fiscal year in the path.
+func (h *FundHandler) AllocateBudget(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid fund ID", err.Error())
+	}
+	year, err := strconv.Atoi(c.Params("year"))
+	if err != nil {
+		return response.BadRequest(c, "Invalid fiscal year", err.Error())
+	}
+
+	var req models.AllocateBudgetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	budget, err := h.fundService.AllocateBudget(currentActor(c), uint(id), year, &req)
+	if err != nil {
+		if err.Error() == "fund not found" {
+			return response.NotFound(c, "Fund not found")
+		}
+		return response.BadRequest(c, "Failed to allocate budget", err.Error())
+	}
+
+	return response.Success(c, "Budget allocated successfully", budget)
+}
+
+func (h *FundHandler) GetTransactions(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid fund ID", err.Error())
+	}
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	transactions, page, err := h.fundService.GetTransactions(uint(id), spec)
+	if err != nil {
+		if err.Error() == "fund not found" {
+			return response.NotFound(c, "Fund not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get fund transactions", err.Error())
+	}
+
+	return response.Paginated(c, "Fund transactions retrieved successfully", transactions, page)
+}
+
+// GetBudgetReport sums the funds for ?fiscal_year, the current one by
+// default.
+func (h *FundHandler) GetBudgetReport(c *fiber.Ctx) error {
+	year := 0
+	if value := c.Query("fiscal_year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return response.BadRequest(c, "Invalid fiscal year", err.Error())
+		}
+		year = parsed
+	}
+
+	report, err := h.fundService.GetBudgetReport(year)
+	if err != nil {
+		return response.InternalServerError(c, "Failed to build budget report", err.Error())
+	}
+
+	return response.Success(c, "Budget report retrieved successfully", report)
+}
+
+func (h *FundHandler) Rollover(c *fiber.Ctx) error {
+	var req models.RolloverRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	report, err := h.fundService.Rollover(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to roll over budgets", err.Error())
+	}
+
+	return response.Success(c, "Budgets rolled over successfully", report)
+}
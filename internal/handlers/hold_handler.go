@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type HoldHandler struct {
+	holdService services.HoldService
+}
+
+func NewHoldHandler(holdService services.HoldService) *HoldHandler {
+	return &HoldHandler{
+		holdService: holdService,
+	}
+}
+
+// PlaceHold places a hold for the current user. Librarians and admins may
+// place one for another user with user_id.
+func (h *HoldHandler) PlaceHold(c *fiber.Ctx) error {
+	var req models.PlaceHoldRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	if req.UserID == 0 || c.Locals("user_role").(string) == "member" {
+		req.UserID = c.Locals("user_id").(uint)
+	}
+
+	hold, err := h.holdService.PlaceHold(currentActor(c), &req)
+	if err != nil {
+		switch err.Error() {
+		case "book not found":
+			return response.NotFound(c, "Book not found")
+		case "work not found":
+			return response.NotFound(c, "Work not found")
+		case "user not found":
+			return response.NotFound(c, "User not found")
+		}
+		return response.BadRequest(c, "Failed to place hold", err.Error())
+	}
+
+	return response.Created(c, "Hold placed successfully", hold)
+}
+
+func (h *HoldHandler) GetAllHolds(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	holds, page, err := h.holdService.GetAllHolds(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get holds", err.Error())
+	}
+
+	return response.Paginated(c, "Holds retrieved successfully", holds, page)
+}
+
+func (h *HoldHandler) GetHoldByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid hold ID", err.Error())
+	}
+
+	hold, err := h.holdService.GetHoldByID(uint(id))
+	if err != nil {
+		if err.Error() == "hold not found" {
+			return response.NotFound(c, "Hold not found")
+		}
+		return response.InternalServerError(c, "Failed to get hold", err.Error())
+	}
+
+	return response.Success(c, "Hold retrieved successfully", hold)
+}
+
+func (h *HoldHandler) GetMyHolds(c *fiber.Ctx) error {
+	return h.respondHoldsByUser(c, c.Locals("user_id").(uint))
+}
+
+func (h *HoldHandler) GetHoldsByUser(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("userId"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid user ID", err.Error())
+	}
+	return h.respondHoldsByUser(c, uint(userID))
+}
+
+func (h *HoldHandler) respondHoldsByUser(c *fiber.Ctx, userID uint) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	holds, page, err := h.holdService.GetHoldsByUser(userID, spec)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return response.NotFound(c, "User not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get user holds", err.Error())
+	}
+
+	return response.Paginated(c, "User holds retrieved successfully", holds, page)
+}
+
+func (h *HoldHandler) CancelHold(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid hold ID", err.Error())
+	}
+
+	hold, err := h.holdService.CancelHold(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "hold not found" {
+			return response.NotFound(c, "Hold not found")
+		}
+		return response.BadRequest(c, "Failed to cancel hold", err.Error())
+	}
+
+	return response.Success(c, "Hold cancelled successfully", hold)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type ImportHandler struct {
+	importService services.ImportService
+}
+
+func NewImportHandler(importService services.ImportService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+	}
+}
+
+// StartImport accepts a multipart upload with the file in "file", an
+// optional JSON column mapping in "mapping" and "on_duplicate".
+func (h *ImportHandler) StartImport(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return response.BadRequest(c, "Import file is required", err.Error())
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return response.BadRequest(c, "Invalid import file", err.Error())
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return response.BadRequest(c, "Invalid import file", err.Error())
+	}
+
+	req := models.ImportRequest{
+		FileName:    fileHeader.Filename,
+		Data:        data,
+		OnDuplicate: models.DuplicateMode(c.FormValue("on_duplicate")),
+	}
+	if mapping := c.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &req.Mapping); err != nil {
+			return response.BadRequest(c, "Invalid column mapping", err.Error())
+		}
+	}
+
+	job, err := h.importService.StartImport(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to start import", err.Error())
+	}
+
+	return response.Accepted(c, "Import dry run started", job)
+}
+
+func (h *ImportHandler) CommitImport(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid import job ID", err.Error())
+	}
+
+	job, err := h.importService.CommitImport(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "import job not found" {
+			return response.NotFound(c, "Import job not found")
+		}
+		return response.BadRequest(c, "Failed to commit import", err.Error())
+	}
+
+	return response.Accepted(c, "Import started", job)
+}
+
+func (h *ImportHandler) GetImportJob(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid import job ID", err.Error())
+	}
+
+	job, err := h.importService.GetImportJob(uint(id))
+	if err != nil {
+		if err.Error() == "import job not found" {
+			return response.NotFound(c, "Import job not found")
+		}
+		return response.InternalServerError(c, "Failed to get import job", err.Error())
+	}
+
+	return response.Success(c, "Import job retrieved successfully", job)
+}
+
+func (h *ImportHandler) GetImportJobs(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	jobs, page, err := h.importService.GetImportJobs(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get import jobs", err.Error())
+	}
+
+	return response.Paginated(c, "Import jobs retrieved successfully", jobs, page)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/marc"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type MARCHandler struct {
+	marcService services.MARCService
+}
+
+func NewMARCHandler(marcService services.MARCService) *MARCHandler {
+	return &MARCHandler{
+		marcService: marcService,
+	}
+}
+
+// ImportRecords accepts MARC21 or MARCXML either as a multipart "file" or
+// as the raw request body.
+func (h *MARCHandler) ImportRecords(c *fiber.Ctx) error {
+	data := c.Body()
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return response.BadRequest(c, "Invalid MARC file", err.Error())
+		}
+		defer file.Close()
+
+		if data, err = io.ReadAll(file); err != nil {
+			return response.BadRequest(c, "Invalid MARC file", err.Error())
+		}
+	}
+	if len(data) == 0 {
+		return response.BadRequest(c, "MARC records are required", nil)
+	}
+
+	result, err := h.marcService.ImportRecords(currentActor(c), data)
+	if err != nil {
+		return response.BadRequest(c, "Failed to import MARC records", err.Error())
+	}
+
+	return response.Success(c, "MARC records imported", result)
+}
+
+func (h *MARCHandler) ExportBook(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	format := c.Query("format", marc.FormatXML)
+	data, err := h.marcService.ExportBook(uint(id), format)
+	if err != nil {
+		if err.Error() == "book not found" {
+			return response.NotFound(c, "Book not found")
+		}
+		if errors.Is(err, marc.ErrUnsupportedFormat) {
+			return response.BadRequest(c, "Invalid format", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to export book", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, marc.ContentType(format))
+	return c.Send(data)
+}
+
+func (h *MARCHandler) ExportCatalog(c *fiber.Ctx) error {
+	format := c.Query("format", marc.FormatISO2709)
+
+	var buf bytes.Buffer
+	if err := h.marcService.ExportCatalog(&buf, format); err != nil {
+		if errors.Is(err, marc.ErrUnsupportedFormat) {
+			return response.BadRequest(c, "Invalid format", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to export catalog", err.Error())
+	}
+
+	filename := "catalog.mrc"
+	if format == marc.FormatXML {
+		filename = "catalog.xml"
+	}
+	c.Set(fiber.HeaderContentType, marc.ContentType(format))
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type MetadataHandler struct {
+	metadataService services.MetadataService
+}
+
+func NewMetadataHandler(metadataService services.MetadataService) *MetadataHandler {
+	return &MetadataHandler{
+		metadataService: metadataService,
+	}
+}
+
+func (h *MetadataHandler) LookupISBN(c *fiber.Ctx) error {
+	code := c.Query("isbn")
+	if code == "" {
+		return response.BadRequest(c, "ISBN is required", nil)
+	}
+
+	req, err := h.metadataService.LookupISBN(code)
+	if err != nil {
+		switch err.Error() {
+		case "invalid ISBN":
+			return response.BadRequest(c, "Invalid ISBN", nil)
+		case "no metadata found for this ISBN":
+			return response.NotFound(c, "No metadata found for this ISBN")
+		}
+		return response.BadGateway(c, "Metadata provider is unavailable", err.Error())
+	}
+
+	return response.Success(c, "Book metadata retrieved successfully", req)
+}
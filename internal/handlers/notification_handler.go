@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type NotificationHandler struct {
+	notificationService services.NotificationService
+}
+
+func NewNotificationHandler(notificationService services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{
+		notificationService: notificationService,
+	}
+}
+
+func (h *NotificationHandler) GetMyNotifications(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	notifications, page, err := h.notificationService.GetNotifications(c.Locals("user_id").(uint), spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get notifications", err.Error())
+	}
+
+	return response.Paginated(c, "Notifications retrieved successfully", notifications, page)
+}
+
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid notification ID", err.Error())
+	}
+
+	notification, err := h.notificationService.MarkRead(c.Locals("user_id").(uint), uint(id))
+	if err != nil {
+		if err.Error() == "notification not found" {
+			return response.NotFound(c, "Notification not found")
+		}
+		return response.InternalServerError(c, "Failed to update notification", err.Error())
+	}
+
+	return response.Success(c, "Notification marked as read", notification)
+}
+
+func (h *NotificationHandler) MarkAllRead(c *fiber.Ctx) error {
+	count, err := h.notificationService.MarkAllRead(c.Locals("user_id").(uint))
+	if err != nil {
+		return response.InternalServerError(c, "Failed to update notifications", err.Error())
+	}
+
+	return response.Success(c, "Notifications marked as read", fiber.Map{"marked": count})
+}
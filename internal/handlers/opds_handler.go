@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/opds"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+// OPDSHandler serves the catalog to e-reader apps, as OPDS 1.2 (Atom) when
+// version is 1 and as OPDS 2.0 (JSON) when it is 2.
+type OPDSHandler struct {
+	opdsService services.OPDSService
+	version     int
+}
+
+func NewOPDSHandler(opdsService services.OPDSService, version int) *OPDSHandler {
+	return &OPDSHandler{
+		opdsService: opdsService,
+		version:     version,
+	}
+}
+
+func (h *OPDSHandler) GetRoot(c *fiber.Ctx) error {
+	return h.sendFeed(c, h.opdsService.GetRoot(h.catalog(c)))
+}
+
+func (h *OPDSHandler) GetNewArrivals(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	feed, err := h.opdsService.GetNewArrivals(h.catalog(c), spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return h.sendFeed(c, feed)
+}
+
+func (h *OPDSHandler) GetCategories(c *fiber.Ctx) error {
+	feed, err := h.opdsService.GetCategories(h.catalog(c))
+	if err != nil {
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return h.sendFeed(c, feed)
+}
+
+func (h *OPDSHandler) GetCategory(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	feed, err := h.opdsService.GetCategory(h.catalog(c), uint(id))
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return h.sendFeed(c, feed)
+}
+
+func (h *OPDSHandler) GetCategoryBooks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid classification ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	feed, err := h.opdsService.GetCategoryBooks(h.catalog(c), uint(id), spec)
+	if err != nil {
+		if err.Error() == "classification not found" {
+			return response.NotFound(c, "Classification not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to build feed", err.Error())
+	}
+
+	return h.sendFeed(c, feed)
+}
+
+// Search takes the terms as ?q=, or as ?query= from the OPDS 2.0 template.
+func (h *OPDSHandler) Search(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+	q := c.Query("q", c.Query("query"))
+	delete(spec.Filters, "query")
+
+	feed, err := h.opdsService.Search(h.catalog(c), q, spec)
+	if err != nil {
+		if err.Error() == "search query is required" {
+			return response.BadRequest(c, "Search query is required", nil)
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to search books", err.Error())
+	}
+
+	return h.sendFeed(c, feed)
+}
+
+func (h *OPDSHandler) GetPublication(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid book ID", err.Error())
+	}
+
+	publication, err := h.opdsService.GetPublication(h.catalog(c), uint(id))
+	if err != nil {
+		if err.Error() == "book not found" {
+			return response.NotFound(c, "Book not found")
+		}
+		return response.InternalServerError(c, "Failed to get book", err.Error())
+	}
+
+	var data []byte
+	if h.version == 2 {
+		c.Set(fiber.HeaderContentType, opds.JSONPublicationType)
+		data, err = opds.EncodePublicationJSON(publication)
+	} else {
+		c.Set(fiber.HeaderContentType, opds.AtomEntryType)
+		data, err = opds.EncodeAtomEntry(publication)
+	}
+	if err != nil {
+		return response.InternalServerError(c, "Failed to encode entry", err.Error())
+	}
+	return c.Send(data)
+}
+
+// GetOpenSearch describes the search feed for OPDS 1.2 clients.
+func (h *OPDSHandler) GetOpenSearch(c *fiber.Ctx) error {
+	name := h.opdsService.LibraryName()
+	data, err := opds.EncodeOpenSearch(name, "Search the "+name+" catalog", h.catalog(c).SearchTemplate())
+	if err != nil {
+		return response.InternalServerError(c, "Failed to encode search description", err.Error())
+	}
+
+	c.Set(fiber.HeaderContentType, opds.OpenSearchType)
+	return c.Send(data)
+}
+
+func (h *OPDSHandler) catalog(c *fiber.Ctx) services.OPDSCatalog {
+	return services.OPDSCatalog{BaseURL: c.BaseURL() + "/api/v1", Version: h.version}
+}
+
+func (h *OPDSHandler) sendFeed(c *fiber.Ctx, feed *opds.Feed) error {
+	var data []byte
+	var err error
+	if h.version == 2 {
+		c.Set(fiber.HeaderContentType, opds.JSONType)
+		data, err = opds.EncodeJSON(feed)
+	} else if feed.Kind == opds.TypeNavigation {
+		c.Set(fiber.HeaderContentType, opds.AtomNavigationType)
+		data, err = opds.EncodeAtom(feed)
+	} else {
+		c.Set(fiber.HeaderContentType, opds.AtomAcquisitionType)
+		data, err = opds.EncodeAtom(feed)
+	}
+	if err != nil {
+		return response.InternalServerError(c, "Failed to encode feed", err.Error())
+	}
+	return c.Send(data)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type PrivacyHandler struct {
+	privacyService services.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService services.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+func (h *PrivacyHandler) ExportMyData(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	export, err := h.privacyService.ExportUserData(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return response.NotFound(c, "User not found")
+		}
+		return response.InternalServerError(c, "Failed to export data", err.Error())
+	}
+
+	if c.Query("format") == "json" {
+		return response.Success(c, "Data exported successfully", export)
+	}
+
+	var buf bytes.Buffer
+	if err := services.WriteExportArchive(&buf, export); err != nil {
+		return response.InternalServerError(c, "Failed to build export archive", err.Error())
+	}
+
+	filename := fmt.Sprintf("user-%d-data-export-%s.zip", userID, export.ExportedAt.Format("20060102"))
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Attachment(filename)
+	return c.Send(buf.Bytes())
+}
+
+func (h *PrivacyHandler) RequestMyErasure(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.ErasureRequestInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	request, err := h.privacyService.RequestErasure(currentActor(c), userID, &req)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return response.NotFound(c, "User not found")
+		}
+		return response.BadRequest(c, "Failed to request erasure", err.Error())
+	}
+
+	return response.Created(c, "Erasure requested successfully", request)
+}
+
+func (h *PrivacyHandler) GetErasureRequests(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	requests, page, err := h.privacyService.GetErasureRequests(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get erasure requests", err.Error())
+	}
+
+	return response.Paginated(c, "Erasure requests retrieved successfully", requests, page)
+}
+
+func (h *PrivacyHandler) CancelErasure(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid erasure request ID", err.Error())
+	}
+
+	request, err := h.privacyService.CancelErasure(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "erasure request not found" {
+			return response.NotFound(c, "Erasure request not found")
+		}
+		return response.BadRequest(c, "Failed to cancel erasure", err.Error())
+	}
+
+	return response.Success(c, "Erasure request cancelled successfully", request)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type PublisherHandler struct {
+	publisherService services.PublisherService
+}
+
+func NewPublisherHandler(publisherService services.PublisherService) *PublisherHandler {
+	return &PublisherHandler{
+		publisherService: publisherService,
+	}
+}
+
+func (h *PublisherHandler) GetAllPublishers(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	publishers, page, err := h.publisherService.GetAllPublishers(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get publishers", err.Error())
+	}
+
+	return response.Paginated(c, "Publishers retrieved successfully", publishers, page)
+}
+
+func (h *PublisherHandler) GetPublisherByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid publisher ID", err.Error())
+	}
+
+	publisher, err := h.publisherService.GetPublisherByID(uint(id))
+	if err != nil {
+		if err.Error() == "publisher not found" {
+			return response.NotFound(c, "Publisher not found")
+		}
+		return response.InternalServerError(c, "Failed to get publisher", err.Error())
+	}
+
+	return response.Success(c, "Publisher retrieved successfully", publisher)
+}
+
+func (h *PublisherHandler) GetPublisherBooks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid publisher ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.publisherService.GetPublisherBooks(uint(id), spec)
+	if err != nil {
+		if err.Error() == "publisher not found" {
+			return response.NotFound(c, "Publisher not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get books", err.Error())
+	}
+
+	return response.Paginated(c, "Books retrieved successfully", books, page)
+}
+
+func (h *PublisherHandler) UpdatePublisher(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid publisher ID", err.Error())
+	}
+
+	var req models.UpdatePublisherRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	publisher, err := h.publisherService.UpdatePublisher(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "publisher not found" {
+			return response.NotFound(c, "Publisher not found")
+		}
+		return response.BadRequest(c, "Failed to update publisher", err.Error())
+	}
+
+	return response.Success(c, "Publisher updated successfully", publisher)
+}
+
+func (h *PublisherHandler) MergePublishers(c *fiber.Ctx) error {
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	publisher, err := h.publisherService.MergePublishers(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "publisher not found" {
+			return response.NotFound(c, "Publisher not found")
+		}
+		return response.BadRequest(c, "Failed to merge publishers", err.Error())
+	}
+
+	return response.Success(c, "Publishers merged successfully", publisher)
+}
+
+func (h *PublisherHandler) GetDuplicatePublishers(c *fiber.Ctx) error {
+	duplicates, err := h.publisherService.FindDuplicates()
+	if err != nil {
+		return response.InternalServerError(c, "Failed to find duplicate publishers", err.Error())
+	}
+
+	return response.Success(c, "Possible duplicate publishers retrieved successfully", duplicates)
+}
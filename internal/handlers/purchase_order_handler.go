@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type PurchaseOrderHandler struct {
+	orderService services.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(orderService services.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{
+		orderService: orderService,
+	}
+}
+
+func (h *PurchaseOrderHandler) CreateOrder(c *fiber.Ctx) error {
+	var req models.CreateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	order, err := h.orderService.CreateOrder(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			return response.NotFound(c, "Vendor not found")
+		}
+		return response.BadRequest(c, "Failed to create purchase order", err.Error())
+	}
+
+	return response.Created(c, "Purchase order created successfully", order)
+}
+
+// GetAllOrders lists purchase orders; ?late=true lists the ones to claim.
+func (h *PurchaseOrderHandler) GetAllOrders(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	orders, page, err := h.orderService.GetAllOrders(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get purchase orders", err.Error())
+	}
+
+	return response.Paginated(c, "Purchase orders retrieved successfully", orders, page)
+}
+
+func (h *PurchaseOrderHandler) GetOrderByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	order, err := h.orderService.GetOrderByID(uint(id))
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			return response.NotFound(c, "Purchase order not found")
+		}
+		return response.InternalServerError(c, "Failed to get purchase order", err.Error())
+	}
+
+	return response.Success(c, "Purchase order retrieved successfully", order)
+}
+
+func (h *PurchaseOrderHandler) UpdateOrder(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	var req models.UpdateOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	order, err := h.orderService.UpdateOrder(currentActor(c), uint(id), &req)
+	if err != nil {
+		switch err.Error() {
+		case "purchase order not found":
+			return response.NotFound(c, "Purchase order not found")
+		case "vendor not found":
+			return response.NotFound(c, "Vendor not found")
+		}
+		return response.BadRequest(c, "Failed to update purchase order", err.Error())
+	}
+
+	return response.Success(c, "Purchase order updated successfully", order)
+}
+
+func (h *PurchaseOrderHandler) PlaceOrder(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	order, err := h.orderService.PlaceOrder(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			return response.NotFound(c, "Purchase order not found")
+		}
+		return response.BadRequest(c, "Failed to place purchase order", err.Error())
+	}
+
+	return response.Success(c, "Purchase order placed successfully", order)
+}
+
+func (h *PurchaseOrderHandler) ReceiveOrder(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	var req models.ReceiveOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	order, err := h.orderService.ReceiveOrder(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			return response.NotFound(c, "Purchase order not found")
+		}
+		return response.BadRequest(c, "Failed to receive purchase order", err.Error())
+	}
+
+	return response.Success(c, "Delivery received successfully", order)
+}
+
+func (h *PurchaseOrderHandler) CancelOrder(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	var req models.CancelOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	order, err := h.orderService.CancelOrder(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			return response.NotFound(c, "Purchase order not found")
+		}
+		return response.BadRequest(c, "Failed to cancel purchase order", err.Error())
+	}
+
+	return response.Success(c, "Purchase order cancelled successfully", order)
+}
+
+func (h *PurchaseOrderHandler) ClaimOrder(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	var req models.ClaimOrderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	order, err := h.orderService.ClaimOrder(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			return response.NotFound(c, "Purchase order not found")
+		}
+		return response.BadRequest(c, "Failed to claim purchase order", err.Error())
+	}
+
+	return response.Success(c, "Claim recorded successfully", order)
+}
+
+func (h *PurchaseOrderHandler) InvoiceOrder(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid purchase order ID", err.Error())
+	}
+
+	var req models.CreateInvoiceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	order, err := h.orderService.InvoiceOrder(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "purchase order not found" {
+			return response.NotFound(c, "Purchase order not found")
+		}
+		return response.BadRequest(c, "Failed to record invoice", err.Error())
+	}
+
+	return response.Created(c, "Invoice recorded successfully", order)
+}
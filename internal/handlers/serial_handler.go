@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type SerialHandler struct {
+	serialService services.SerialService
+}
+
+func NewSerialHandler(serialService services.SerialService) *SerialHandler {
+	return &SerialHandler{
+		serialService: serialService,
+	}
+}
+
+func (h *SerialHandler) CreateSerial(c *fiber.Ctx) error {
+	var req models.CreateSerialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	serial, err := h.serialService.CreateSerial(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to create serial", err.Error())
+	}
+
+	return response.Created(c, "Serial created successfully", serial)
+}
+
+func (h *SerialHandler) GetAllSerials(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	serials, page, err := h.serialService.GetAllSerials(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get serials", err.Error())
+	}
+
+	return response.Paginated(c, "Serials retrieved successfully", serials, page)
+}
+
+func (h *SerialHandler) GetSerialByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid serial ID", err.Error())
+	}
+
+	serial, err := h.serialService.GetSerialByID(uint(id))
+	if err != nil {
+		if err.Error() == "serial not found" {
+			return response.NotFound(c, "Serial not found")
+		}
+		return response.InternalServerError(c, "Failed to get serial", err.Error())
+	}
+
+	return response.Success(c, "Serial retrieved successfully", serial)
+}
+
+func (h *SerialHandler) UpdateSerial(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid serial ID", err.Error())
+	}
+
+	var req models.UpdateSerialRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	serial, err := h.serialService.UpdateSerial(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "serial not found" {
+			return response.NotFound(c, "Serial not found")
+		}
+		return response.BadRequest(c, "Failed to update serial", err.Error())
+	}
+
+	return response.Success(c, "Serial updated successfully", serial)
+}
+
+func (h *SerialHandler) AddSubscription(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid serial ID", err.Error())
+	}
+
+	var req models.CreateSubscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	subscription, err := h.serialService.AddSubscription(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "serial not found" {
+			return response.NotFound(c, "Serial not found")
+		}
+		return response.BadRequest(c, "Failed to add subscription", err.Error())
+	}
+
+	return response.Created(c, "Subscription added successfully", subscription)
+}
+
+func (h *SerialHandler) GetAllIssues(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	issues, page, err := h.serialService.GetIssues(0, spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get issues", err.Error())
+	}
+
+	return response.Paginated(c, "Issues retrieved successfully", issues, page)
+}
+
+func (h *SerialHandler) GetSerialIssues(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid serial ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	issues, page, err := h.serialService.GetIssues(uint(id), spec)
+	if err != nil {
+		if err.Error() == "serial not found" {
+			return response.NotFound(c, "Serial not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get issues", err.Error())
+	}
+
+	return response.Paginated(c, "Issues retrieved successfully", issues, page)
+}
+
+func (h *SerialHandler) GetIssueByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid issue ID", err.Error())
+	}
+
+	issue, err := h.serialService.GetIssueByID(uint(id))
+	if err != nil {
+		if err.Error() == "issue not found" {
+			return response.NotFound(c, "Issue not found")
+		}
+		return response.InternalServerError(c, "Failed to get issue", err.Error())
+	}
+
+	return response.Success(c, "Issue retrieved successfully", issue)
+}
+
+func (h *SerialHandler) AddIssue(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid serial ID", err.Error())
+	}
+
+	var req models.AddIssueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	issue, err := h.serialService.AddIssue(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "serial not found" {
+			return response.NotFound(c, "Serial not found")
+		}
+		return response.BadRequest(c, "Failed to add issue", err.Error())
+	}
+
+	return response.Created(c, "Issue added successfully", issue)
+}
+
+func (h *SerialHandler) CheckInIssue(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid issue ID", err.Error())
+	}
+
+	var req models.CheckInIssueRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	issue, err := h.serialService.CheckInIssue(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "issue not found" {
+			return response.NotFound(c, "Issue not found")
+		}
+		return response.BadRequest(c, "Failed to check in issue", err.Error())
+	}
+
+	return response.Success(c, "Issue checked in successfully", issue)
+}
+
+func (h *SerialHandler) ClaimIssue(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid issue ID", err.Error())
+	}
+
+	var req models.ClaimIssueRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	issue, err := h.serialService.ClaimIssue(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "issue not found" {
+			return response.NotFound(c, "Issue not found")
+		}
+		return response.BadRequest(c, "Failed to claim issue", err.Error())
+	}
+
+	return response.Success(c, "Issue claimed successfully", issue)
+}
+
+func (h *SerialHandler) MarkIssueMissing(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid issue ID", err.Error())
+	}
+
+	var req models.MissingIssueRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	issue, err := h.serialService.MarkIssueMissing(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "issue not found" {
+			return response.NotFound(c, "Issue not found")
+		}
+		return response.BadRequest(c, "Failed to mark issue missing", err.Error())
+	}
+
+	return response.Success(c, "Issue marked missing successfully", issue)
+}
+
+func (h *SerialHandler) SetLoanRules(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid issue ID", err.Error())
+	}
+
+	var req models.IssueLoanRulesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	issue, err := h.serialService.SetLoanRules(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "issue not found" {
+			return response.NotFound(c, "Issue not found")
+		}
+		return response.BadRequest(c, "Failed to update loan rules", err.Error())
+	}
+
+	return response.Success(c, "Loan rules updated successfully", issue)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type SerialLoanHandler struct {
+	loanService services.SerialLoanService
+}
+
+func NewSerialLoanHandler(loanService services.SerialLoanService) *SerialLoanHandler {
+	return &SerialLoanHandler{
+		loanService: loanService,
+	}
+}
+
+func (h *SerialLoanHandler) BorrowIssue(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid issue ID", err.Error())
+	}
+
+	var req models.BorrowIssueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	loan, err := h.loanService.BorrowIssue(currentActor(c), uint(id), &req)
+	if err != nil {
+		switch err.Error() {
+		case "issue not found":
+			return response.NotFound(c, "Issue not found")
+		case "user not found":
+			return response.NotFound(c, "User not found")
+		}
+		return response.BadRequest(c, "Failed to borrow issue", err.Error())
+	}
+
+	return response.Created(c, "Issue borrowed successfully", loan)
+}
+
+func (h *SerialLoanHandler) ReturnIssue(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid loan ID", err.Error())
+	}
+
+	var req models.ReturnIssueRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	loan, err := h.loanService.ReturnIssue(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "serial loan not found" {
+			return response.NotFound(c, "Serial loan not found")
+		}
+		return response.BadRequest(c, "Failed to return issue", err.Error())
+	}
+
+	return response.Success(c, "Issue returned successfully", loan)
+}
+
+func (h *SerialLoanHandler) GetAllLoans(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	loans, page, err := h.loanService.GetAllLoans(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get serial loans", err.Error())
+	}
+
+	return response.Paginated(c, "Serial loans retrieved successfully", loans, page)
+}
+
+func (h *SerialLoanHandler) GetMyLoans(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	loans, page, err := h.loanService.GetLoansByUser(c.Locals("user_id").(uint), spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get serial loans", err.Error())
+	}
+
+	return response.Paginated(c, "Serial loans retrieved successfully", loans, page)
+}
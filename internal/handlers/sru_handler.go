@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/sru"
+)
+
+// SRUHandler serves the catalog over SRU 1.2. Errors are reported as SRU
+// diagnostics in a 200 response, as the protocol requires.
+type SRUHandler struct {
+	sruService services.SRUService
+}
+
+func NewSRUHandler(sruService services.SRUService) *SRUHandler {
+	return &SRUHandler{
+		sruService: sruService,
+	}
+}
+
+// Handle dispatches on ?operation=. Without one, a request with a query is
+// a searchRetrieve, one with a scanClause a scan, and anything else explain.
+func (h *SRUHandler) Handle(c *fiber.Ctx) error {
+	req := &models.SRURequest{
+		Operation:     c.Query("operation"),
+		Version:       c.Query("version"),
+		Query:         c.Query("query"),
+		RecordSchema:  c.Query("recordSchema"),
+		RecordPacking: c.Query("recordPacking"),
+		ScanClause:    c.Query("scanClause"),
+	}
+	if req.Operation == "" {
+		switch {
+		case req.Query != "":
+			req.Operation = "searchRetrieve"
+		case req.ScanClause != "":
+			req.Operation = "scan"
+		default:
+			req.Operation = "explain"
+		}
+	}
+
+	if req.Version != "" && req.Version != "1.1" && req.Version != sru.Version {
+		return h.fail(c, req.Operation, sru.NewDiagnostic(sru.DiagUnsupportedVersion, sru.Version, "Unsupported version"))
+	}
+
+	var diagnostic *sru.Diagnostic
+	readInt := func(name string) *int {
+		value := c.Query(name)
+		if value == "" || diagnostic != nil {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			diagnostic = sru.NewDiagnostic(sru.DiagUnsupportedValue, name, "Unsupported parameter value")
+			return nil
+		}
+		return &n
+	}
+	if n := readInt("startRecord"); n != nil {
+		req.StartRecord = *n
+	}
+	req.MaximumRecords = readInt("maximumRecords")
+	req.ResponsePosition = readInt("responsePosition")
+	if n := readInt("maximumTerms"); n != nil {
+		req.MaximumTerms = *n
+	}
+	if diagnostic != nil {
+		return h.fail(c, req.Operation, diagnostic)
+	}
+
+	switch req.Operation {
+	case "explain":
+		host, port := serverAddress(c)
+		response, err := h.sruService.Explain(host, port, strings.TrimPrefix(c.Path(), "/"))
+		if err != nil {
+			return h.fail(c, req.Operation, err)
+		}
+		return sendSRU(c, response.Encode)
+	case "searchRetrieve":
+		response, err := h.sruService.SearchRetrieve(req)
+		if err != nil {
+			return h.fail(c, req.Operation, err)
+		}
+		return sendSRU(c, response.Encode)
+	case "scan":
+		response, err := h.sruService.Scan(req)
+		if err != nil {
+			return h.fail(c, req.Operation, err)
+		}
+		return sendSRU(c, response.Encode)
+	}
+	return h.fail(c, "explain", sru.NewDiagnostic(sru.DiagUnsupportedOperation, req.Operation, "Unsupported operation"))
+}
+
+// fail answers with err as a diagnostic in the response of operation.
+// Errors that are not diagnostics become general system errors.
+func (h *SRUHandler) fail(c *fiber.Ctx, operation string, err error) error {
+	var diagnostic *sru.Diagnostic
+	if !errors.As(err, &diagnostic) {
+		diagnostic = sru.NewDiagnostic(sru.DiagGeneral, err.Error(), "General system error")
+	}
+	diagnostics := []*sru.Diagnostic{diagnostic}
+
+	switch operation {
+	case "searchRetrieve":
+		return sendSRU(c, (&sru.SearchRetrieveResponse{Diagnostics: diagnostics}).Encode)
+	case "scan":
+		return sendSRU(c, (&sru.ScanResponse{Diagnostics: diagnostics}).Encode)
+	}
+	return sendSRU(c, (&sru.ExplainResponse{Diagnostics: diagnostics}).Encode)
+}
+
+func sendSRU(c *fiber.Ctx, encode func() ([]byte, error)) error {
+	data, err := encode()
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, sru.ContentType)
+	return c.Send(data)
+}
+
+// serverAddress returns the host and port clients reached us on.
+func serverAddress(c *fiber.Ctx) (string, int) {
+	port := 80
+	if c.Protocol() == "https" {
+		port = 443
+	}
+	host := c.Hostname()
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host = h
+		if n, err := strconv.Atoi(p); err == nil {
+			port = n
+		}
+	}
+	return host, port
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type StocktakeHandler struct {
+	stocktakeService services.StocktakeService
+}
+
+func NewStocktakeHandler(stocktakeService services.StocktakeService) *StocktakeHandler {
+	return &StocktakeHandler{
+		stocktakeService: stocktakeService,
+	}
+}
+
+func (h *StocktakeHandler) CreateStocktake(c *fiber.Ctx) error {
+	var req models.CreateStocktakeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	stocktake, err := h.stocktakeService.CreateStocktake(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to create stocktake", err.Error())
+	}
+
+	return response.Created(c, "Stocktake created successfully", stocktake)
+}
+
+func (h *StocktakeHandler) GetAllStocktakes(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	stocktakes, page, err := h.stocktakeService.GetAllStocktakes(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get stocktakes", err.Error())
+	}
+
+	return response.Paginated(c, "Stocktakes retrieved successfully", stocktakes, page)
+}
+
+func (h *StocktakeHandler) GetStocktakeByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	stocktake, err := h.stocktakeService.GetStocktakeByID(uint(id))
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		return response.InternalServerError(c, "Failed to get stocktake", err.Error())
+	}
+
+	return response.Success(c, "Stocktake retrieved successfully", stocktake)
+}
+
+func (h *StocktakeHandler) CloseStocktake(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	stocktake, err := h.stocktakeService.CloseStocktake(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		return response.BadRequest(c, "Failed to close stocktake", err.Error())
+	}
+
+	return response.Success(c, "Stocktake closed successfully", stocktake)
+}
+
+// RecordScans takes a JSON body, or plain text with one code per line and
+// the shelf in the location query parameter, as hand scanners send it.
+func (h *StocktakeHandler) RecordScans(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	var req models.ScanRequest
+	if c.Is("json") {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	} else {
+		req.Location = c.Query("location")
+		req.Codes = strings.Split(string(c.Body()), "\n")
+	}
+
+	result, err := h.stocktakeService.RecordScans(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		return response.BadRequest(c, "Failed to record scans", err.Error())
+	}
+
+	return response.Created(c, "Scans recorded successfully", result)
+}
+
+func (h *StocktakeHandler) GetScans(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	scans, page, err := h.stocktakeService.GetScans(uint(id), spec)
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get scans", err.Error())
+	}
+
+	return response.Paginated(c, "Scans retrieved successfully", scans, page)
+}
+
+func (h *StocktakeHandler) DeleteScan(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+	scanID, err := strconv.ParseUint(c.Params("scanId"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid scan ID", err.Error())
+	}
+
+	if err := h.stocktakeService.DeleteScan(uint(id), uint(scanID)); err != nil {
+		switch err.Error() {
+		case "stocktake not found":
+			return response.NotFound(c, "Stocktake not found")
+		case "scan not found":
+			return response.NotFound(c, "Scan not found")
+		}
+		return response.BadRequest(c, "Failed to delete scan", err.Error())
+	}
+
+	return response.Success(c, "Scan deleted successfully", nil)
+}
+
+func (h *StocktakeHandler) GetReport(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	report, err := h.stocktakeService.Reconcile(uint(id))
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		return response.InternalServerError(c, "Failed to reconcile stocktake", err.Error())
+	}
+
+	return response.Success(c, "Stocktake reconciled successfully", report)
+}
+
+func (h *StocktakeHandler) MarkMissing(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	var req models.MarkMissingRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	report, err := h.stocktakeService.MarkMissing(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		return response.BadRequest(c, "Failed to mark copies missing", err.Error())
+	}
+
+	return response.Success(c, "Missing copies written off successfully", report)
+}
+
+func (h *StocktakeHandler) CorrectLocations(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid stocktake ID", err.Error())
+	}
+
+	var req models.CorrectLocationsRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return response.BadRequest(c, "Invalid request body", err.Error())
+		}
+	}
+
+	report, err := h.stocktakeService.CorrectLocations(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "stocktake not found" {
+			return response.NotFound(c, "Stocktake not found")
+		}
+		return response.BadRequest(c, "Failed to correct locations", err.Error())
+	}
+
+	return response.Success(c, "Locations corrected successfully", report)
+}
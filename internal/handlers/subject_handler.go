@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type SubjectHandler struct {
+	subjectService services.SubjectService
+}
+
+func NewSubjectHandler(subjectService services.SubjectService) *SubjectHandler {
+	return &SubjectHandler{
+		subjectService: subjectService,
+	}
+}
+
+func (h *SubjectHandler) GetAllSubjects(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	subjects, page, err := h.subjectService.GetAllSubjects(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get subjects", err.Error())
+	}
+
+	return response.Paginated(c, "Subjects retrieved successfully", subjects, page)
+}
+
+func (h *SubjectHandler) GetSubjectByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid subject ID", err.Error())
+	}
+
+	subject, err := h.subjectService.GetSubjectByID(uint(id))
+	if err != nil {
+		if err.Error() == "subject not found" {
+			return response.NotFound(c, "Subject not found")
+		}
+		return response.InternalServerError(c, "Failed to get subject", err.Error())
+	}
+
+	return response.Success(c, "Subject retrieved successfully", subject)
+}
+
+func (h *SubjectHandler) GetSubjectBooks(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid subject ID", err.Error())
+	}
+
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	books, page, err := h.subjectService.GetSubjectBooks(uint(id), spec)
+	if err != nil {
+		if err.Error() == "subject not found" {
+			return response.NotFound(c, "Subject not found")
+		}
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get books", err.Error())
+	}
+
+	return response.Paginated(c, "Books retrieved successfully", books, page)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type SuggestionHandler struct {
+	suggestionService services.SuggestionService
+}
+
+func NewSuggestionHandler(suggestionService services.SuggestionService) *SuggestionHandler {
+	return &SuggestionHandler{
+		suggestionService: suggestionService,
+	}
+}
+
+// Suggest asks the library to buy a book for the current user.
+func (h *SuggestionHandler) Suggest(c *fiber.Ctx) error {
+	var req models.CreateSuggestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	suggestion, err := h.suggestionService.Suggest(currentActor(c), c.Locals("user_id").(uint), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to submit suggestion", err.Error())
+	}
+
+	return response.Created(c, "Suggestion submitted successfully", suggestion)
+}
+
+func (h *SuggestionHandler) GetAllSuggestions(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	suggestions, page, err := h.suggestionService.GetAllSuggestions(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get suggestions", err.Error())
+	}
+
+	return response.Paginated(c, "Suggestions retrieved successfully", suggestions, page)
+}
+
+func (h *SuggestionHandler) GetMySuggestions(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	suggestions, page, err := h.suggestionService.GetUserSuggestions(c.Locals("user_id").(uint), spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get suggestions", err.Error())
+	}
+
+	return response.Paginated(c, "Suggestions retrieved successfully", suggestions, page)
+}
+
+func (h *SuggestionHandler) GetSuggestionByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid suggestion ID", err.Error())
+	}
+
+	suggestion, err := h.suggestionService.GetSuggestionByID(uint(id))
+	if err != nil {
+		if err.Error() == "suggestion not found" {
+			return response.NotFound(c, "Suggestion not found")
+		}
+		return response.InternalServerError(c, "Failed to get suggestion", err.Error())
+	}
+
+	return response.Success(c, "Suggestion retrieved successfully", suggestion)
+}
+
+func (h *SuggestionHandler) ReviewSuggestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid suggestion ID", err.Error())
+	}
+
+	var req models.ReviewSuggestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	suggestion, err := h.suggestionService.ReviewSuggestion(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "suggestion not found" {
+			return response.NotFound(c, "Suggestion not found")
+		}
+		return response.BadRequest(c, "Failed to review suggestion", err.Error())
+	}
+
+	return response.Success(c, "Suggestion reviewed successfully", suggestion)
+}
+
+func (h *SuggestionHandler) CatalogueSuggestion(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid suggestion ID", err.Error())
+	}
+
+	var req models.CatalogueSuggestionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	suggestion, err := h.suggestionService.CatalogueSuggestion(currentActor(c), uint(id), &req)
+	if err != nil {
+		switch err.Error() {
+		case "suggestion not found":
+			return response.NotFound(c, "Suggestion not found")
+		case "book not found":
+			return response.NotFound(c, "Book not found")
+		}
+		return response.BadRequest(c, "Failed to catalogue suggestion", err.Error())
+	}
+
+	return response.Success(c, "Suggestion catalogued successfully", suggestion)
+}
+
+func (h *SuggestionHandler) MergeSuggestions(c *fiber.Ctx) error {
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	suggestion, err := h.suggestionService.MergeSuggestions(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "suggestion not found" {
+			return response.NotFound(c, "Suggestion not found")
+		}
+		return response.BadRequest(c, "Failed to merge suggestions", err.Error())
+	}
+
+	return response.Success(c, "Suggestions merged successfully", suggestion)
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/yooerizkilab/library-system/internal/models"
 	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"github.com/yooerizkilab/library-system/pkg/response"
 )
 
@@ -25,7 +27,7 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(currentActor(c), &req)
 	if err != nil {
 		return response.BadRequest(c, "Failed to create user", err.Error())
 	}
@@ -34,12 +36,20 @@ func (h *UserHandler) CreateUser(c *fiber.Ctx) error {
 }
 
 func (h *UserHandler) GetAllUsers(c *fiber.Ctx) error {
-	users, err := h.userService.GetAllUsers()
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	users, page, err := h.userService.GetAllUsers(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to get users", err.Error())
 	}
 
-	return response.Success(c, "Users retrieved successfully", users)
+	return response.Paginated(c, "Users retrieved successfully", users, page)
 }
 
 func (h *UserHandler) GetUserByID(c *fiber.Ctx) error {
@@ -70,7 +80,7 @@ func (h *UserHandler) UpdateUser(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	user, err := h.userService.UpdateUser(uint(id), &req)
+	user, err := h.userService.UpdateUser(currentActor(c), uint(id), &req)
 	if err != nil {
 		if err.Error() == "user not found" {
 			return response.NotFound(c, "User not found")
@@ -87,7 +97,7 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid user ID", err.Error())
 	}
 
-	err = h.userService.DeleteUser(uint(id))
+	err = h.userService.DeleteUser(currentActor(c), uint(id))
 	if err != nil {
 		if err.Error() == "user not found" {
 			return response.NotFound(c, "User not found")
@@ -99,17 +109,25 @@ func (h *UserHandler) DeleteUser(c *fiber.Ctx) error {
 }
 
 func (h *UserHandler) SearchUsers(c *fiber.Ctx) error {
-	query := c.Query("q")
-	if query == "" {
+	q := c.Query("q")
+	if q == "" {
 		return response.BadRequest(c, "Search query is required", nil)
 	}
 
-	users, err := h.userService.SearchUsers(query)
+	spec, err := query.FromRequest(c)
 	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	users, page, err := h.userService.SearchUsers(q, spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
 		return response.InternalServerError(c, "Failed to search users", err.Error())
 	}
 
-	return response.Success(c, "Users search completed", users)
+	return response.Paginated(c, "Users search completed", users, page)
 }
 
 func (h *UserHandler) Login(c *fiber.Ctx) error {
@@ -134,7 +152,7 @@ func (h *UserHandler) ChangePassword(c *fiber.Ctx) error {
 		return response.BadRequest(c, "Invalid request body", err.Error())
 	}
 
-	err := h.userService.ChangePassword(userID, &req)
+	err := h.userService.ChangePassword(currentActor(c), userID, &req)
 	if err != nil {
 		return response.BadRequest(c, "Failed to change password", err.Error())
 	}
@@ -142,6 +160,25 @@ func (h *UserHandler) ChangePassword(c *fiber.Ctx) error {
 	return response.Success(c, "Password changed successfully", nil)
 }
 
+func (h *UserHandler) UpdatePrivacyPreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var req models.PrivacyPreferencesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	user, err := h.userService.UpdatePrivacyPreferences(currentActor(c), userID, &req)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return response.NotFound(c, "User not found")
+		}
+		return response.BadRequest(c, "Failed to update privacy preferences", err.Error())
+	}
+
+	return response.Success(c, "Privacy preferences updated successfully", user)
+}
+
 func (h *UserHandler) GetProfile(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type VendorHandler struct {
+	vendorService services.VendorService
+}
+
+func NewVendorHandler(vendorService services.VendorService) *VendorHandler {
+	return &VendorHandler{
+		vendorService: vendorService,
+	}
+}
+
+func (h *VendorHandler) CreateVendor(c *fiber.Ctx) error {
+	var req models.CreateVendorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	vendor, err := h.vendorService.CreateVendor(currentActor(c), &req)
+	if err != nil {
+		return response.BadRequest(c, "Failed to create vendor", err.Error())
+	}
+
+	return response.Created(c, "Vendor created successfully", vendor)
+}
+
+func (h *VendorHandler) GetAllVendors(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	vendors, page, err := h.vendorService.GetAllVendors(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get vendors", err.Error())
+	}
+
+	return response.Paginated(c, "Vendors retrieved successfully", vendors, page)
+}
+
+func (h *VendorHandler) GetVendorByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid vendor ID", err.Error())
+	}
+
+	vendor, err := h.vendorService.GetVendorByID(uint(id))
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			return response.NotFound(c, "Vendor not found")
+		}
+		return response.InternalServerError(c, "Failed to get vendor", err.Error())
+	}
+
+	return response.Success(c, "Vendor retrieved successfully", vendor)
+}
+
+func (h *VendorHandler) UpdateVendor(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid vendor ID", err.Error())
+	}
+
+	var req models.UpdateVendorRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	vendor, err := h.vendorService.UpdateVendor(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			return response.NotFound(c, "Vendor not found")
+		}
+		return response.BadRequest(c, "Failed to update vendor", err.Error())
+	}
+
+	return response.Success(c, "Vendor updated successfully", vendor)
+}
+
+func (h *VendorHandler) DeleteVendor(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid vendor ID", err.Error())
+	}
+
+	err = h.vendorService.DeleteVendor(currentActor(c), uint(id))
+	if err != nil {
+		if err.Error() == "vendor not found" {
+			return response.NotFound(c, "Vendor not found")
+		}
+		return response.BadRequest(c, "Failed to delete vendor", err.Error())
+	}
+
+	return response.Success(c, "Vendor deleted successfully", nil)
+}
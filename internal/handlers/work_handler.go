@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+type WorkHandler struct {
+	workService services.WorkService
+}
+
+func NewWorkHandler(workService services.WorkService) *WorkHandler {
+	return &WorkHandler{
+		workService: workService,
+	}
+}
+
+func (h *WorkHandler) GetAllWorks(c *fiber.Ctx) error {
+	spec, err := query.FromRequest(c)
+	if err != nil {
+		return response.BadRequest(c, "Invalid query parameters", err.Error())
+	}
+
+	works, page, err := h.workService.GetAllWorks(spec)
+	if err != nil {
+		if errors.Is(err, query.ErrInvalid) {
+			return response.BadRequest(c, "Invalid query parameters", err.Error())
+		}
+		return response.InternalServerError(c, "Failed to get works", err.Error())
+	}
+
+	return response.Paginated(c, "Works retrieved successfully", works, page)
+}
+
+func (h *WorkHandler) GetWorkByID(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid work ID", err.Error())
+	}
+
+	work, err := h.workService.GetWorkByID(uint(id))
+	if err != nil {
+		if err.Error() == "work not found" {
+			return response.NotFound(c, "Work not found")
+		}
+		return response.InternalServerError(c, "Failed to get work", err.Error())
+	}
+
+	return response.Success(c, "Work retrieved successfully", work)
+}
+
+func (h *WorkHandler) UpdateWork(c *fiber.Ctx) error {
+	id, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return response.BadRequest(c, "Invalid work ID", err.Error())
+	}
+
+	var req models.UpdateWorkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	work, err := h.workService.UpdateWork(currentActor(c), uint(id), &req)
+	if err != nil {
+		if err.Error() == "work not found" {
+			return response.NotFound(c, "Work not found")
+		}
+		return response.BadRequest(c, "Failed to update work", err.Error())
+	}
+
+	return response.Success(c, "Work updated successfully", work)
+}
+
+func (h *WorkHandler) MergeWorks(c *fiber.Ctx) error {
+	var req models.MergeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.BadRequest(c, "Invalid request body", err.Error())
+	}
+
+	work, err := h.workService.MergeWorks(currentActor(c), &req)
+	if err != nil {
+		if err.Error() == "work not found" {
+			return response.NotFound(c, "Work not found")
+		}
+		return response.BadRequest(c, "Failed to merge works", err.Error())
+	}
+
+	return response.Success(c, "Works merged successfully", work)
+}
@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"log"
+	"time"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+// Scheduler runs background maintenance jobs at a fixed interval.
+type Scheduler struct {
+	jobs []job
+	stop chan struct{}
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+// Every registers a job. Jobs must be registered before Start is called.
+func (s *Scheduler) Every(interval time.Duration, name string, run func() error) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+// Start runs every registered job once and then on its interval.
+func (s *Scheduler) Start() {
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+func (s *Scheduler) loop(j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.run(); err != nil {
+			log.Printf("Job %s failed: %v", j.name, err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+	}
+}
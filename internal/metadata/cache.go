@@ -0,0 +1,80 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Misses are cached for a shorter time, as providers add records often
+const notFoundTTLDivisor = 24
+
+// CachingProvider remembers lookups of another provider for a while,
+// including ISBNs it does not know. Failed lookups are not cached.
+type CachingProvider struct {
+	provider MetadataProvider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	record  *Record
+	expires time.Time
+}
+
+func NewCachingProvider(provider MetadataProvider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider: provider,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]cacheEntry),
+	}
+}
+
+func (c *CachingProvider) LookupISBN(ctx context.Context, isbn string) (*Record, error) {
+	now := c.now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[isbn]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		if entry.record == nil {
+			return nil, ErrNotFound
+		}
+		copied := *entry.record
+		return &copied, nil
+	}
+
+	record, err := c.provider.LookupISBN(ctx, isbn)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	ttl := c.ttl
+	if record == nil {
+		ttl /= notFoundTTLDivisor
+	}
+	c.mu.Lock()
+	c.prune(now)
+	c.entries[isbn] = cacheEntry{record: record, expires: now.Add(ttl)}
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// prune drops expired entries. The caller holds c.mu.
+func (c *CachingProvider) prune(now time.Time) {
+	for isbn, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, isbn)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubProvider answers from a fixed map and counts lookups.
+type stubProvider struct {
+	records map[string]*Record
+	err     error
+	calls   int
+}
+
+func (p *stubProvider) LookupISBN(ctx context.Context, isbn string) (*Record, error) {
+	p.calls++
+	if p.err != nil {
+		return nil, p.err
+	}
+	record, ok := p.records[isbn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+func newTestCache(provider MetadataProvider, ttl time.Duration) (*CachingProvider, *time.Time) {
+	clock := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	cache := NewCachingProvider(provider, ttl)
+	cache.now = func() time.Time { return clock }
+	return cache, &clock
+}
+
+func TestCachingProviderTTL(t *testing.T) {
+	provider := &stubProvider{records: map[string]*Record{
+		"9780099590088": {ISBN: "9780099590088", Title: "Sapiens"},
+	}}
+	cache, clock := newTestCache(provider, 24*time.Hour)
+	ctx := context.Background()
+
+	first, err := cache.LookupISBN(ctx, "9780099590088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Callers get copies, so changing one does not change the cache
+	first.Title = "changed"
+
+	*clock = clock.Add(23 * time.Hour)
+	second, err := cache.LookupISBN(ctx, "9780099590088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times within the TTL, want 1", provider.calls)
+	}
+	if second.Title != "Sapiens" {
+		t.Errorf("cached title = %q, want Sapiens", second.Title)
+	}
+
+	*clock = clock.Add(time.Hour)
+	if _, err := cache.LookupISBN(ctx, "9780099590088"); err != nil {
+		t.Fatal(err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times after expiry, want 2", provider.calls)
+	}
+}
+
+func TestCachingProviderNegative(t *testing.T) {
+	provider := &stubProvider{records: map[string]*Record{}}
+	cache, clock := newTestCache(provider, 24*time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.LookupISBN(ctx, "9780000000002"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("lookup %d: err = %v, want ErrNotFound", i+1, err)
+		}
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times for a cached miss, want 1", provider.calls)
+	}
+
+	// Misses expire after a day's TTL divided by notFoundTTLDivisor, an hour
+	provider.records["9780000000002"] = &Record{ISBN: "9780000000002", Title: "Baru"}
+	*clock = clock.Add(59 * time.Minute)
+	if _, err := cache.LookupISBN(ctx, "9780000000002"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("err = %v before the miss expired, want ErrNotFound", err)
+	}
+	*clock = clock.Add(time.Minute)
+	record, err := cache.LookupISBN(ctx, "9780000000002")
+	if err != nil || record.Title != "Baru" {
+		t.Errorf("after the miss expired got %+v, %v", record, err)
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times, want 2", provider.calls)
+	}
+}
+
+func TestCachingProviderFailuresNotCached(t *testing.T) {
+	provider := &stubProvider{err: errors.New("connection refused")}
+	cache, _ := newTestCache(provider, time.Hour)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.LookupISBN(ctx, "9780099590088"); err == nil || errors.Is(err, ErrNotFound) {
+			t.Fatalf("lookup %d: err = %v, want the provider failure", i+1, err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("provider called %d times, want 2", provider.calls)
+	}
+
+	provider.err = nil
+	provider.records = map[string]*Record{"9780099590088": {Title: "Sapiens"}}
+	if _, err := cache.LookupISBN(ctx, "9780099590088"); err != nil {
+		t.Errorf("err = %v once the provider recovered", err)
+	}
+}
+
+func TestCachingProviderPrunesExpired(t *testing.T) {
+	provider := &stubProvider{records: map[string]*Record{
+		"9780099590088": {Title: "Sapiens"},
+		"9789799731234": {Title: "Bumi manusia"},
+	}}
+	cache, clock := newTestCache(provider, time.Hour)
+	ctx := context.Background()
+
+	cache.LookupISBN(ctx, "9780099590088")
+	*clock = clock.Add(2 * time.Hour)
+	cache.LookupISBN(ctx, "9789799731234")
+
+	if _, ok := cache.entries["9780099590088"]; ok {
+		t.Error("expired entry was not pruned")
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("cache holds %d entries, want 1", len(cache.entries))
+	}
+}
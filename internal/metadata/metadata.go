@@ -0,0 +1,34 @@
+// Package metadata looks up bibliographic data for new catalog records from
+// external providers.
+package metadata
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound means the provider has no record for the ISBN.
+var ErrNotFound = errors.New("metadata not found")
+
+// MetadataProvider finds bibliographic data by ISBN. ISBNs are passed in
+// canonical ISBN-13 form.
+type MetadataProvider interface {
+	LookupISBN(ctx context.Context, isbn string) (*Record, error)
+}
+
+// Record is what a provider knows about an edition. Fields it does not know
+// are left empty. Language is a MARC 21 language code such as "eng".
+type Record struct {
+	ISBN        string
+	Title       string
+	Subtitle    string
+	Authors     []string
+	Publishers  []string
+	PublishYear int
+	Pages       int
+	Subjects    []string
+	Description string
+	Language    string
+	CoverURL    string
+	Source      string
+}
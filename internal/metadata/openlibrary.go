@@ -0,0 +1,148 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var publishYearPattern = regexp.MustCompile(`\d{4}`)
+
+// OpenLibrary is a MetadataProvider for the Open Library books API, or any
+// service that implements its /api/books endpoint.
+type OpenLibrary struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewOpenLibrary(baseURL string, timeout time.Duration) *OpenLibrary {
+	return &OpenLibrary{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+type openLibraryBook struct {
+	Title         string               `json:"title"`
+	Subtitle      string               `json:"subtitle"`
+	Authors       []openLibraryName    `json:"authors"`
+	Publishers    []openLibraryName    `json:"publishers"`
+	PublishDate   string               `json:"publish_date"`
+	NumberOfPages int                  `json:"number_of_pages"`
+	Subjects      []openLibraryName    `json:"subjects"`
+	Notes         openLibraryText      `json:"notes"`
+	Excerpts      []openLibraryExcerpt `json:"excerpts"`
+	Languages     []openLibraryKey     `json:"languages"`
+	Cover         struct {
+		Medium string `json:"medium"`
+	} `json:"cover"`
+}
+
+type openLibraryName struct {
+	Name string `json:"name"`
+}
+
+// openLibraryKey references another Open Library record, e.g.
+// {"key": "/languages/eng"}.
+type openLibraryKey struct {
+	Key string `json:"key"`
+}
+
+type openLibraryExcerpt struct {
+	Text string `json:"text"`
+}
+
+// openLibraryText is either a plain string or a {"type", "value"} object.
+type openLibraryText string
+
+func (t *openLibraryText) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = openLibraryText(s)
+		return nil
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	*t = openLibraryText(typed.Value)
+	return nil
+}
+
+func (p *OpenLibrary) LookupISBN(ctx context.Context, isbn string) (*Record, error) {
+	bibKey := "ISBN:" + isbn
+	params := url.Values{
+		"bibkeys": {bibKey},
+		"format":  {"json"},
+		"jscmd":   {"data"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/api/books?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("open library: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("open library: unexpected status %s", resp.Status)
+	}
+
+	var books map[string]openLibraryBook
+	if err := json.NewDecoder(resp.Body).Decode(&books); err != nil {
+		return nil, fmt.Errorf("open library: %w", err)
+	}
+	book, ok := books[bibKey]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	record := &Record{
+		ISBN:        isbn,
+		Title:       book.Title,
+		Subtitle:    book.Subtitle,
+		Authors:     names(book.Authors),
+		Publishers:  names(book.Publishers),
+		Pages:       book.NumberOfPages,
+		Subjects:    names(book.Subjects),
+		Description: string(book.Notes),
+		CoverURL:    book.Cover.Medium,
+		Source:      "openlibrary",
+	}
+	if record.Description == "" && len(book.Excerpts) > 0 {
+		record.Description = book.Excerpts[0].Text
+	}
+	if len(book.Languages) > 0 {
+		record.Language = strings.TrimPrefix(book.Languages[0].Key, "/languages/")
+	}
+	if year := publishYearPattern.FindString(book.PublishDate); year != "" {
+		record.PublishYear, _ = strconv.Atoi(year)
+	}
+	return record, nil
+}
+
+func names(values []openLibraryName) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if name := strings.TrimSpace(v.Name); name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const openLibraryFound = `{
+  "ISBN:9780099590088": {
+    "title": "Sapiens",
+    "subtitle": "a brief history of humankind",
+    "authors": [{"name": "Yuval Noah Harari"}, {"name": " "}],
+    "publishers": [{"name": "Vintage"}],
+    "publish_date": "May 2014",
+    "number_of_pages": 498,
+    "subjects": [{"name": "Civilization"}, {"name": "Human evolution"}],
+    "notes": {"type": "/type/text", "value": "Originally published in Hebrew."},
+    "languages": [{"key": "/languages/eng"}],
+    "cover": {"medium": "https://covers.example/b/id/1-M.jpg"}
+  }
+}`
+
+func openLibraryServer(t *testing.T, status int, body string) *OpenLibrary {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/books" {
+			t.Errorf("path = %q, want /api/books", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("jscmd"); got != "data" {
+			t.Errorf("jscmd = %q, want data", got)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewOpenLibrary(server.URL+"/", time.Second)
+}
+
+func TestOpenLibraryFound(t *testing.T) {
+	provider := openLibraryServer(t, http.StatusOK, openLibraryFound)
+
+	record, err := provider.LookupISBN(context.Background(), "9780099590088")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Record{
+		ISBN:        "9780099590088",
+		Title:       "Sapiens",
+		Subtitle:    "a brief history of humankind",
+		Authors:     []string{"Yuval Noah Harari"},
+		Publishers:  []string{"Vintage"},
+		PublishYear: 2014,
+		Pages:       498,
+		Subjects:    []string{"Civilization", "Human evolution"},
+		Description: "Originally published in Hebrew.",
+		Language:    "eng",
+		CoverURL:    "https://covers.example/b/id/1-M.jpg",
+		Source:      "openlibrary",
+	}
+	if !reflect.DeepEqual(record, want) {
+		t.Errorf("got  %+v\nwant %+v", record, want)
+	}
+}
+
+func TestOpenLibraryNotesString(t *testing.T) {
+	provider := openLibraryServer(t, http.StatusOK, `{"ISBN:9789799731234": {"title": "Bumi manusia", "notes": "Tetralogi Buru, buku pertama."}}`)
+
+	record, err := provider.LookupISBN(context.Background(), "9789799731234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Description != "Tetralogi Buru, buku pertama." {
+		t.Errorf("description = %q", record.Description)
+	}
+	if record.Language != "" || record.PublishYear != 0 {
+		t.Errorf("unknown fields filled: language %q, year %d", record.Language, record.PublishYear)
+	}
+}
+
+func TestOpenLibraryExcerptFallback(t *testing.T) {
+	provider := openLibraryServer(t, http.StatusOK, `{"ISBN:9789799731234": {"title": "Bumi manusia", "excerpts": [{"text": "Orang memanggil aku: Minke."}]}}`)
+
+	record, err := provider.LookupISBN(context.Background(), "9789799731234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Description != "Orang memanggil aku: Minke." {
+		t.Errorf("description = %q", record.Description)
+	}
+}
+
+func TestOpenLibraryNotFound(t *testing.T) {
+	for name, server := range map[string]struct {
+		status int
+		body   string
+	}{
+		"empty result": {http.StatusOK, `{}`},
+		"404":          {http.StatusNotFound, ``},
+	} {
+		provider := openLibraryServer(t, server.status, server.body)
+		if _, err := provider.LookupISBN(context.Background(), "9780000000002"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestOpenLibraryErrors(t *testing.T) {
+	for name, server := range map[string]struct {
+		status int
+		body   string
+	}{
+		"server error": {http.StatusInternalServerError, `oops`},
+		"rate limited": {http.StatusTooManyRequests, ``},
+		"bad json":     {http.StatusOK, `{"ISBN:9780000000002": [`},
+		"bad notes":    {http.StatusOK, `{"ISBN:9780000000002": {"notes": 42}}`},
+	} {
+		provider := openLibraryServer(t, server.status, server.body)
+		_, err := provider.LookupISBN(context.Background(), "9780000000002")
+		if err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: err = %v, want a lookup failure", name, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/pkg/response"
+)
+
+// UploadLimit raises the body limit for requests with Method to a path
+// matching Path, a path.Match pattern such as /api/v1/books/*/cover.
+type UploadLimit struct {
+	Method string
+	Path   string
+	Limit  int
+}
+
+// BodyLimit rejects request bodies over limit bytes, or over the limit of
+// the upload route they are sent to. The server streams bodies larger than
+// its own limit instead of buffering them, so only these routes read more.
+func BodyLimit(limit int, uploads ...UploadLimit) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		max := limit
+		for _, upload := range uploads {
+			if matched, _ := path.Match(upload.Path, c.Path()); matched && c.Method() == upload.Method {
+				max = upload.Limit
+				break
+			}
+		}
+
+		// The unread rest of a rejected body cannot be told from the next
+		// request, so the connection closes with the response
+		tooLarge := func() error {
+			c.Context().SetConnectionClose()
+			return response.PayloadTooLarge(c, fmt.Sprintf("Request body exceeds %d MB", max>>20))
+		}
+
+		length := c.Request().Header.ContentLength()
+		if length > max {
+			return tooLarge()
+		}
+
+		// A chunked body has no length up front, so read it up to the limit
+		if length == -1 && c.Request().IsBodyStream() {
+			body, err := io.ReadAll(io.LimitReader(c.Request().BodyStream(), int64(max)+1))
+			if err != nil {
+				return response.BadRequest(c, "Invalid request body", err.Error())
+			}
+			if len(body) > max {
+				return tooLarge()
+			}
+			c.Request().SetBody(body)
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBodyLimit(t *testing.T) {
+	// Configured as the server is, with kilobytes for megabytes
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    1 << 10,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(BodyLimit(1<<10, UploadLimit{Method: fiber.MethodPost, Path: "/books/*/files", Limit: 4 << 10}))
+	echo := func(c *fiber.Ctx) error {
+		return c.SendString(strconv.Itoa(len(c.Body())))
+	}
+	app.Post("/books/:id/files", echo)
+	app.Post("/books", echo)
+	app.Put("/books/:id/files", echo)
+
+	for _, tc := range []struct {
+		method, path string
+		size         int
+		chunked      bool
+		status       int
+	}{
+		{fiber.MethodPost, "/books", 512, false, fiber.StatusOK},
+		{fiber.MethodPost, "/books", 2 << 10, false, fiber.StatusRequestEntityTooLarge},
+		{fiber.MethodPost, "/books", 2 << 10, true, fiber.StatusRequestEntityTooLarge},
+		{fiber.MethodPost, "/books/7/files", 3 << 10, false, fiber.StatusOK},
+		{fiber.MethodPost, "/books/7/files", 3 << 10, true, fiber.StatusOK},
+		{fiber.MethodPost, "/books/7/files", 5 << 10, false, fiber.StatusRequestEntityTooLarge},
+		{fiber.MethodPost, "/books/7/files", 5 << 10, true, fiber.StatusRequestEntityTooLarge},
+		{fiber.MethodPut, "/books/7/files", 3 << 10, false, fiber.StatusRequestEntityTooLarge},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewReader(bytes.Repeat([]byte("x"), tc.size)))
+		if tc.chunked {
+			req.ContentLength = -1
+			req.TransferEncoding = []string{"chunked"}
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s %s with %d bytes (chunked %v): status %d, want %d",
+				tc.method, tc.path, tc.size, tc.chunked, resp.StatusCode, tc.status)
+			continue
+		}
+		if tc.status == fiber.StatusOK {
+			got, _ := io.ReadAll(resp.Body)
+			if string(got) != strconv.Itoa(tc.size) {
+				t.Errorf("%s %s: handler read %s bytes, want %d", tc.method, tc.path, got, tc.size)
+			}
+		}
+	}
+}
+
+// Uploads over the server's own limit reach the handler as a stream, which
+// multipart parsing reads without buffering the whole body.
+func TestBodyLimitMultipartUpload(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		BodyLimit:                    1 << 10,
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
+	app.Use(BodyLimit(1<<10, UploadLimit{Method: fiber.MethodPut, Path: "/books/*/cover", Limit: 8 << 10}))
+	app.Put("/books/:id/cover", func(c *fiber.Ctx) error {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return err
+		}
+		return c.SendString(strconv.FormatInt(file.Size, 10))
+	})
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "cover.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte("x"), 4<<10))
+	form.Close()
+
+	req := httptest.NewRequest(fiber.MethodPut, "/books/7/cover", &body)
+	req.Header.Set(fiber.HeaderContentType, form.FormDataContentType())
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != fiber.StatusOK || string(got) != "4096" {
+		t.Errorf("status %d, body %q; want the 4096 byte file", resp.StatusCode, got)
+	}
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Vendor is a bookseller or distributor the library orders from.
+type Vendor struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	Name          string         `json:"name" gorm:"type:varchar(100);not null;index"`
+	ContactName   string         `json:"contact_name" gorm:"type:varchar(100)"`
+	Email         string         `json:"email" gorm:"type:varchar(100)"`
+	Phone         string         `json:"phone" gorm:"type:varchar(30)"`
+	Address       string         `json:"address" gorm:"type:text"`
+	AccountNumber string         `json:"account_number" gorm:"type:varchar(50)"`
+	IsActive      bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Usual lead time, used when an order gives no expected date
+	DeliveryDays int `json:"delivery_days" gorm:"default:14"`
+}
+
+type CreateVendorRequest struct {
+	Name          string `json:"name" validate:"required,max=100"`
+	ContactName   string `json:"contact_name" validate:"max=100"`
+	Email         string `json:"email" validate:"omitempty,email,max=100"`
+	Phone         string `json:"phone" validate:"max=30"`
+	Address       string `json:"address"`
+	AccountNumber string `json:"account_number" validate:"max=50"`
+	DeliveryDays  int    `json:"delivery_days" validate:"min=0"`
+}
+
+type UpdateVendorRequest struct {
+	Name          string  `json:"name" validate:"max=100"`
+	ContactName   *string `json:"contact_name" validate:"omitempty,max=100"`
+	Email         *string `json:"email" validate:"omitempty,max=100"`
+	Phone         *string `json:"phone" validate:"omitempty,max=30"`
+	Address       *string `json:"address"`
+	AccountNumber *string `json:"account_number" validate:"omitempty,max=50"`
+	DeliveryDays  *int    `json:"delivery_days" validate:"omitempty,min=0"`
+	IsActive      *bool   `json:"is_active"`
+}
+
+type OrderStatus string
+
+const (
+	// OrderDraft can still be edited and has not been sent to the vendor
+	OrderDraft   OrderStatus = "draft"
+	OrderPlaced  OrderStatus = "ordered"
+	OrderPartial OrderStatus = "partial"
+	// OrderReceived and OrderCancelled have nothing left outstanding
+	OrderReceived  OrderStatus = "received"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// PurchaseOrder is one order sent to a vendor.
+type PurchaseOrder struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Number       string         `json:"number" gorm:"type:varchar(30);index"`
+	VendorID     uint           `json:"vendor_id" gorm:"not null;index"`
+	Status       OrderStatus    `json:"status" gorm:"type:varchar(20);default:draft;index"`
+	Notes        string         `json:"notes" gorm:"type:text"`
+	CreatedBy    *uint          `json:"created_by"`
+	OrderedAt    *time.Time     `json:"ordered_at"`
+	ExpectedAt   *time.Time     `json:"expected_at" gorm:"index"`
+	ClosedAt     *time.Time     `json:"closed_at"`
+	CancelReason string         `json:"cancel_reason" gorm:"type:text"`
+	ClaimCount   int            `json:"claim_count" gorm:"default:0"`
+	ClaimedAt    *time.Time     `json:"claimed_at"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Vendor   *Vendor     `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+	Lines    []OrderLine `json:"lines,omitempty" gorm:"foreignKey:OrderID"`
+	Invoices []Invoice   `json:"invoices,omitempty" gorm:"foreignKey:OrderID"`
+}
+
+// Open tells whether the order still waits for deliveries.
+func (o *PurchaseOrder) Open() bool {
+	return o.Status == OrderPlaced || o.Status == OrderPartial
+}
+
+// Late tells whether an open order is past its expected date.
+func (o *PurchaseOrder) Late(now time.Time) bool {
+	return o.Open() && o.ExpectedAt != nil && o.ExpectedAt.Before(now)
+}
+
+// OrderLine is a quantity of one ISBN on an order. Title and the other
+// details describe the book to catalogue when it is not in the catalog
+// yet; BookID is set once it is.
+type OrderLine struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	OrderID      uint      `json:"order_id" gorm:"not null;index"`
+	ISBN         string    `json:"isbn" gorm:"type:varchar(20);index"`
+	Title        string    `json:"title" gorm:"type:varchar(200);not null"`
+	Author       string    `json:"author" gorm:"type:varchar(100)"`
+	Publisher    string    `json:"publisher" gorm:"type:varchar(100)"`
+	Category     string    `json:"category" gorm:"type:varchar(50)"`
+	PublishYear  int       `json:"publish_year"`
+	Quantity     int       `json:"quantity" gorm:"not null"`
+	Received     int       `json:"received" gorm:"default:0"`
+	Cancelled    int       `json:"cancelled" gorm:"default:0"`
+	UnitPrice    float64   `json:"unit_price" gorm:"type:decimal(12,2);default:0"`
+	BookID       *uint     `json:"book_id" gorm:"index"`
+	SuggestionID *uint     `json:"suggestion_id" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// The fund charged and, once the order is placed, the budget holding
+	// the line's encumbrance; Spent is what was invoiced so far
+	FundID     *uint   `json:"fund_id" gorm:"index"`
+	BudgetID   *uint   `json:"budget_id" gorm:"index"`
+	Encumbered float64 `json:"encumbered" gorm:"type:decimal(14,2);default:0"`
+	Invoiced   int     `json:"invoiced" gorm:"default:0"`
+	Spent      float64 `json:"spent" gorm:"type:decimal(14,2);default:0"`
+
+	Book *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
+}
+
+// Outstanding is how many copies are still to be delivered.
+func (l *OrderLine) Outstanding() int {
+	return l.Quantity - l.Received - l.Cancelled
+}
+
+// Uninvoiced is how many copies the vendor may still bill.
+func (l *OrderLine) Uninvoiced() int {
+	return l.Quantity - l.Cancelled - l.Invoiced
+}
+
+// OrderLineInput orders copies of an ISBN. Details left out are taken
+// from the catalog or the metadata provider.
+type OrderLineInput struct {
+	ISBN         string  `json:"isbn" validate:"required,isbn"`
+	Title        string  `json:"title" validate:"max=200"`
+	Author       string  `json:"author" validate:"max=100"`
+	Publisher    string  `json:"publisher" validate:"max=100"`
+	Category     string  `json:"category" validate:"max=50"`
+	PublishYear  int     `json:"publish_year"`
+	Quantity     int     `json:"quantity" validate:"required,min=1"`
+	UnitPrice    float64 `json:"unit_price" validate:"min=0"`
+	SuggestionID *uint   `json:"suggestion_id"`
+	FundID       *uint   `json:"fund_id"`
+}
+
+// CreateOrderRequest drafts an order. FundID is charged for lines that
+// do not name a fund of their own.
+type CreateOrderRequest struct {
+	VendorID   uint             `json:"vendor_id" validate:"required"`
+	FundID     *uint            `json:"fund_id"`
+	ExpectedAt *time.Time       `json:"expected_at"`
+	Notes      string           `json:"notes"`
+	Lines      []OrderLineInput `json:"lines" validate:"required,min=1,dive"`
+}
+
+// UpdateOrderRequest edits a draft order; lines replace all lines when
+// given.
+type UpdateOrderRequest struct {
+	VendorID   uint             `json:"vendor_id"`
+	FundID     *uint            `json:"fund_id"`
+	ExpectedAt *time.Time       `json:"expected_at"`
+	Notes      *string          `json:"notes"`
+	Lines      []OrderLineInput `json:"lines" validate:"dive"`
+}
+
+type ReceiveLineInput struct {
+	LineID   uint `json:"line_id" validate:"required"`
+	Quantity int  `json:"quantity" validate:"required,min=1"`
+}
+
+// ReceiveOrderRequest books in a delivery, which may cover only part of
+// the order. Location is the shelf of books catalogued from it.
+type ReceiveOrderRequest struct {
+	Lines    []ReceiveLineInput `json:"lines" validate:"required,min=1,dive"`
+	Location string             `json:"location" validate:"max=50"`
+}
+
+// CancelOrderRequest cancels what is still outstanding on the given
+// lines, or on the whole order when none are given.
+type CancelOrderRequest struct {
+	LineIDs []uint `json:"line_ids"`
+	Reason  string `json:"reason" validate:"max=1000"`
+}
+
+// ClaimOrderRequest records chasing the vendor for a late delivery and
+// the new date they promised, if any.
+type ClaimOrderRequest struct {
+	Note       string     `json:"note" validate:"max=1000"`
+	ExpectedAt *time.Time `json:"expected_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Actor identifies who triggered a mutating service call. A nil actor means
+// the change was made by the system itself, e.g. a scheduled job.
+type Actor struct {
+	UserID    uint
+	Role      string
+	IP        string
+	RequestID string
+}
+
+// AuditLog is an append-only record of a single change. Each entry stores the
+// hash of the previous entry so any edit or removal breaks the chain.
+type AuditLog struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    *uint     `json:"actor_id" gorm:"index"`
+	ActorRole  string    `json:"actor_role" gorm:"type:varchar(20)"`
+	Action     string    `json:"action" gorm:"type:varchar(50);not null;index"`
+	EntityType string    `json:"entity_type" gorm:"type:varchar(50);not null;index:idx_audit_entity"`
+	EntityID   uint      `json:"entity_id" gorm:"index:idx_audit_entity"`
+	Before     string    `json:"before" gorm:"type:longtext"`
+	After      string    `json:"after" gorm:"type:longtext"`
+	Changes    string    `json:"changes" gorm:"type:longtext"`
+	IP         string    `json:"ip" gorm:"type:varchar(45)"`
+	RequestID  string    `json:"request_id" gorm:"type:varchar(64);index"`
+	PrevHash   string    `json:"prev_hash" gorm:"type:char(64)"`
+	Hash       string    `json:"hash" gorm:"type:char(64);not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+type AuditVerification struct {
+	Valid    bool  `json:"valid"`
+	Checked  int   `json:"checked"`
+	BrokenAt *uint `json:"broken_at,omitempty"`
+}
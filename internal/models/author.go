@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+type ContributorRole string
+
+const (
+	RoleAuthor      ContributorRole = "author"
+	RoleEditor      ContributorRole = "editor"
+	RoleTranslator  ContributorRole = "translator"
+	RoleIllustrator ContributorRole = "illustrator"
+)
+
+// Author is a person or organization credited on books. NameKey is the
+// folded, order-independent form of the name, so "Toer, Pramoedya Ananta"
+// and "Pramoedya Ananta Toer" are one author.
+type Author struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	SortName  string    `json:"sort_name" gorm:"type:varchar(100);index"`
+	NameKey   string    `json:"-" gorm:"type:varchar(100);uniqueIndex"`
+	Biography string    `json:"biography" gorm:"type:text"`
+	BookCount int64     `json:"book_count,omitempty" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AuthorAlias keeps the name key of an author merged into another, so the
+// old spelling keeps resolving to the surviving author.
+type AuthorAlias struct {
+	NameKey  string `gorm:"type:varchar(100);primaryKey"`
+	AuthorID uint   `gorm:"index"`
+}
+
+// BookContributor links a book to an author in one role.
+type BookContributor struct {
+	BookID   uint            `json:"book_id" gorm:"primaryKey"`
+	AuthorID uint            `json:"author_id" gorm:"primaryKey;index"`
+	Role     ContributorRole `json:"role" gorm:"primaryKey;type:varchar(20)"`
+	Position int             `json:"position"`
+
+	Author *Author `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+type Publisher struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"type:varchar(100);not null"`
+	NameKey   string    `json:"-" gorm:"type:varchar(100);uniqueIndex"`
+	City      string    `json:"city" gorm:"type:varchar(100)"`
+	BookCount int64     `json:"book_count,omitempty" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PublisherAlias struct {
+	NameKey     string `gorm:"type:varchar(100);primaryKey"`
+	PublisherID uint   `gorm:"index"`
+}
+
+// ContributorInput credits a book to an existing author by ID or to an
+// author found or created by name.
+type ContributorInput struct {
+	AuthorID uint            `json:"author_id"`
+	Name     string          `json:"name" validate:"max=100"`
+	Role     ContributorRole `json:"role" validate:"omitempty,oneof=author editor translator illustrator"`
+}
+
+type UpdateAuthorRequest struct {
+	Name      string  `json:"name" validate:"max=100"`
+	SortName  string  `json:"sort_name" validate:"max=100"`
+	Biography *string `json:"biography"`
+}
+
+type UpdatePublisherRequest struct {
+	Name string  `json:"name" validate:"max=100"`
+	City *string `json:"city"`
+}
+
+// MergeRequest folds the source records into the target, which keeps its
+// name and takes over all their books.
+type MergeRequest struct {
+	TargetID  uint   `json:"target_id" validate:"required"`
+	SourceIDs []uint `json:"source_ids" validate:"required,min=1"`
+}
+
+// AuthorDuplicates is a group of authors whose names look like the same
+// person, e.g. "P. A. Toer" and "Pramoedya Ananta Toer".
+type AuthorDuplicates struct {
+	Key     string   `json:"key"`
+	Authors []Author `json:"authors"`
+}
+
+type PublisherDuplicates struct {
+	Key        string      `json:"key"`
+	Publishers []Publisher `json:"publishers"`
+}
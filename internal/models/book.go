@@ -10,7 +10,7 @@ type Book struct {
 	ID          uint           `json:"id" gorm:"primaryKey"`
 	Title       string         `json:"title" gorm:"type:varchar(200);not null" validate:"required,min=1,max=200"`
 	Author      string         `json:"author" gorm:"type:varchar(100);not null" validate:"required,min=1,max=100"`
-	ISBN        string         `json:"isbn" gorm:"type:varchar(20);uniqueIndex" validate:"required,min=10,max=17"`
+	ISBN        string         `json:"isbn" gorm:"type:varchar(20);uniqueIndex" validate:"required,isbn"`
 	Publisher   string         `json:"publisher" gorm:"type:varchar(100)" validate:"max=100"`
 	Category    string         `json:"category" gorm:"type:varchar(50);not null" validate:"required,max=50"`
 	Language    string         `json:"language" gorm:"type:varchar(30);default:Indonesian" validate:"max=30"`
@@ -25,28 +25,69 @@ type Book struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
+	// Author and Publisher above are display strings kept in sync with
+	// the linked records
+	PublisherID *uint `json:"publisher_id" gorm:"index"`
+
+	// Category above is the label of the classification
+	ClassificationID *uint  `json:"classification_id" gorm:"index"`
+	CallNumber       string `json:"call_number" gorm:"type:varchar(50);index"`
+
+	WorkID *uint `json:"work_id" gorm:"index"`
+
+	// Set while the book has a cover, served from /books/:id/cover
+	CoverUpdatedAt *time.Time `json:"cover_updated_at"`
+
+	// For digital items Stock and Available count concurrent licenses
+	ItemType ItemType `json:"item_type" gorm:"type:varchar(20);default:print;index"`
+
 	// Relationships
-	Borrows []Borrow `json:"borrows,omitempty" gorm:"foreignKey:BookID"`
+	Borrows         []Borrow          `json:"borrows,omitempty" gorm:"foreignKey:BookID"`
+	Contributors    []BookContributor `json:"contributors,omitempty" gorm:"foreignKey:BookID"`
+	PublisherRecord *Publisher        `json:"publisher_record,omitempty" gorm:"foreignKey:PublisherID"`
+	Classification  *Classification   `json:"classification,omitempty" gorm:"foreignKey:ClassificationID"`
+	Subjects        []BookSubject     `json:"subjects,omitempty" gorm:"foreignKey:BookID"`
+	Files           []DigitalFile     `json:"files,omitempty" gorm:"foreignKey:BookID"`
+
+	// Other editions of the same work, filled in by search
+	Editions []Book `json:"editions,omitempty" gorm:"-"`
 }
 
 type CreateBookRequest struct {
 	Title       string `json:"title" validate:"required,min=1,max=200"`
-	Author      string `json:"author" validate:"required,min=1,max=100"`
-	ISBN        string `json:"isbn" validate:"required,min=10,max=17"`
+	Author      string `json:"author" validate:"required_without=Contributors,max=100"`
+	ISBN        string `json:"isbn" validate:"required,isbn"`
 	Publisher   string `json:"publisher" validate:"max=100"`
-	Category    string `json:"category" validate:"required,max=50"`
+	Category    string `json:"category" validate:"required_without_all=ClassificationID ClassNumber,max=50"`
 	Language    string `json:"language" validate:"max=30"`
 	Pages       int    `json:"pages" validate:"min=1"`
 	PublishYear int    `json:"publish_year" validate:"min=1000,max=2100"`
 	Stock       int    `json:"stock" validate:"min=1"`
 	Description string `json:"description"`
 	Location    string `json:"location" validate:"max=50"`
+
+	// ItemType defaults to print; stock is the license count of digital items
+	ItemType ItemType `json:"item_type" validate:"omitempty,oneof=print ebook audiobook"`
+
+	// Contributors replace the author string when given
+	Contributors []ContributorInput `json:"contributors" validate:"dive"`
+
+	// ClassificationID, or a class number such as Dewey 899.221, replaces
+	// the category when given; the call number is generated from it unless
+	// given
+	ClassificationID *uint    `json:"classification_id"`
+	ClassNumber      string   `json:"class_number" validate:"max=30"`
+	Subjects         []string `json:"subjects" validate:"dive,max=200"`
+	CallNumber       string   `json:"call_number" validate:"max=50"`
+
+	// WorkID groups the book with other editions of a work
+	WorkID *uint `json:"work_id"`
 }
 
 type UpdateBookRequest struct {
 	Title       string `json:"title" validate:"min=1,max=200"`
 	Author      string `json:"author" validate:"min=1,max=100"`
-	ISBN        string `json:"isbn" validate:"min=10,max=17"`
+	ISBN        string `json:"isbn" validate:"omitempty,isbn"`
 	Publisher   string `json:"publisher" validate:"max=100"`
 	Category    string `json:"category" validate:"max=50"`
 	Language    string `json:"language" validate:"max=30"`
@@ -56,4 +97,56 @@ type UpdateBookRequest struct {
 	Description string `json:"description"`
 	Location    string `json:"location" validate:"max=50"`
 	IsActive    *bool  `json:"is_active"`
+
+	ItemType ItemType `json:"item_type" validate:"omitempty,oneof=print ebook audiobook"`
+
+	// Contributors replace all credits when given
+	Contributors []ContributorInput `json:"contributors" validate:"dive"`
+
+	// Subjects replace all subjects when given; an empty list clears them
+	ClassificationID *uint    `json:"classification_id"`
+	ClassNumber      string   `json:"class_number" validate:"max=30"`
+	Subjects         []string `json:"subjects" validate:"dive,max=200"`
+	CallNumber       string   `json:"call_number" validate:"max=50"`
+
+	// WorkID groups the book with other editions of a work
+	WorkID *uint `json:"work_id"`
+}
+
+// IsDigital tells whether the book is lent as files rather than copies.
+func (b *Book) IsDigital() bool {
+	return b.ItemType == ItemEBook || b.ItemType == ItemAudiobook
+}
+
+// FacetCount is how many matching books share one facet value. Value is
+// what the matching list filter accepts.
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// YearFacet counts matching books published in [From, To].
+type YearFacet struct {
+	From  int   `json:"from"`
+	To    int   `json:"to"`
+	Count int64 `json:"count"`
+}
+
+// BookFacets summarizes search results for refinement. Each facet is
+// counted with every other active filter applied, but not its own, so a
+// client can offer alternative values for a filter already in use.
+type BookFacets struct {
+	Category    []FacetCount `json:"category"`
+	Language    []FacetCount `json:"language"`
+	Location    []FacetCount `json:"location"`
+	Available   []FacetCount `json:"available"`
+	PublishYear []YearFacet  `json:"publish_year"`
+}
+
+// CoverImage is one size of a book cover.
+type CoverImage struct {
+	Size        string
+	Data        []byte
+	ContentType string
+	UpdatedAt   time.Time
 }
@@ -17,21 +17,22 @@ const (
 
 type Borrow struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
-	UserID     uint           `json:"user_id" gorm:"not null" validate:"required"`
+	UserID     *uint          `json:"user_id"` // nil once the loan is detached from its reader
 	BookID     uint           `json:"book_id" gorm:"not null" validate:"required"`
 	BorrowDate time.Time      `json:"borrow_date" gorm:"not null default:current_timestamp" validate:"required"` // not null and default current timestamp
 	DueDate    time.Time      `json:"due_date" gorm:"not null" validate:"required"`
 	ReturnDate *time.Time     `json:"return_date"`
 	Status     BorrowStatus   `json:"status" gorm:"default:borrowed" validate:"oneof=borrowed returned overdue lost"`
 	Fine       float64        `json:"fine" gorm:"default:0"`
+	FinePaid   bool           `json:"fine_paid" gorm:"default:false"`
 	Notes      string         `json:"notes" gorm:"type:text"`
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	User User `json:"user" gorm:"foreignKey:UserID"`
-	Book Book `json:"book" gorm:"foreignKey:BookID"`
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Book Book  `json:"book" gorm:"foreignKey:BookID"`
 }
 
 type CreateBorrowRequest struct {
@@ -46,6 +47,7 @@ type UpdateBorrowRequest struct {
 	ReturnDate *time.Time    `json:"return_date"`
 	Status     *BorrowStatus `json:"status" validate:"omitempty,oneof=borrowed returned overdue lost"`
 	Fine       *float64      `json:"fine" validate:"omitempty,min=0"`
+	FinePaid   *bool         `json:"fine_paid"`
 	Notes      string        `json:"notes"`
 }
 
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+const (
+	SchemeDewey  = "ddc"
+	SchemeUDC    = "udc"
+	SchemeCustom = "custom"
+)
+
+// Classification is one node of a classification scheme, e.g. Dewey
+// 899.221 "Indonesian fiction" under 899 and 800. Path lists the IDs from
+// the root down to the node ("/8/90/412/"), so a subtree is every node
+// whose path starts with the node's path.
+type Classification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Scheme    string    `json:"scheme" gorm:"type:varchar(20);not null;uniqueIndex:idx_scheme_notation"`
+	Notation  string    `json:"notation" gorm:"type:varchar(30);not null;uniqueIndex:idx_scheme_notation"`
+	Label     string    `json:"label" gorm:"type:varchar(50);not null;index"`
+	ParentID  *uint     `json:"parent_id" gorm:"index"`
+	Path      string    `json:"-" gorm:"type:varchar(255);index"`
+	Depth     int       `json:"depth"`
+	BookCount int64     `json:"book_count" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Ancestors []Classification `json:"ancestors,omitempty" gorm:"-"`
+	Children  []Classification `json:"children,omitempty" gorm:"-"`
+}
+
+// Subject is a subject heading, e.g. "Indonesia -- History -- 1945-1949".
+type Subject struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Heading    string    `json:"heading" gorm:"type:varchar(200);not null"`
+	HeadingKey string    `json:"-" gorm:"type:varchar(200);uniqueIndex"`
+	BookCount  int64     `json:"book_count,omitempty" gorm:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BookSubject assigns a subject heading to a book.
+type BookSubject struct {
+	BookID    uint `json:"book_id" gorm:"primaryKey"`
+	SubjectID uint `json:"subject_id" gorm:"primaryKey;index"`
+	Position  int  `json:"position"`
+
+	Subject *Subject `json:"subject,omitempty" gorm:"foreignKey:SubjectID"`
+}
+
+type CreateClassificationRequest struct {
+	Scheme   string `json:"scheme" validate:"required,oneof=ddc udc custom"`
+	Notation string `json:"notation" validate:"required,max=30"`
+	Label    string `json:"label" validate:"required,max=50"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+type UpdateClassificationRequest struct {
+	Notation string `json:"notation" validate:"max=30"`
+	Label    string `json:"label" validate:"max=50"`
+	// ParentID moves the node with its subtree; 0 makes it a root
+	ParentID *uint `json:"parent_id"`
+}
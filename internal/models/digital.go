@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ItemType says whether a book is a physical copy or a digital item.
+type ItemType string
+
+const (
+	ItemPrint     ItemType = "print"
+	ItemEBook     ItemType = "ebook"
+	ItemAudiobook ItemType = "audiobook"
+)
+
+// DigitalFile is one uploaded file of an e-book or audiobook, e.g. the EPUB
+// or the PDF edition. The content lives in the blob store.
+type DigitalFile struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	BookID      uint           `json:"book_id" gorm:"not null;index"`
+	Format      string         `json:"format" gorm:"type:varchar(10);not null"`
+	FileName    string         `json:"file_name" gorm:"type:varchar(255);not null"`
+	ContentType string         `json:"content_type" gorm:"type:varchar(100);not null"`
+	Size        int64          `json:"size"`
+	StorageKey  string         `json:"-" gorm:"type:varchar(255);not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// DigitalDownload is a signed link to one file of a digital loan. It stops
+// working at ExpiresAt or when the loan ends, whichever comes first.
+type DigitalDownload struct {
+	FileID      uint      `json:"file_id"`
+	Format      string    `json:"format"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// DigitalContent is a downloaded file.
+type DigitalContent struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// DownloadRequest carries the signed parameters of a download link.
+type DownloadRequest struct {
+	FileID    uint   `query:"-"`
+	LoanID    uint   `query:"loan"`
+	Expires   int64  `query:"expires"`
+	Signature string `query:"signature"`
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Donor is a person or organization that gives items to the library.
+type Donor struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"type:varchar(150);not null;index"`
+	Email     string         `json:"email" gorm:"type:varchar(100)"`
+	Phone     string         `json:"phone" gorm:"type:varchar(30)"`
+	Address   string         `json:"address" gorm:"type:text"`
+	Notes     string         `json:"notes" gorm:"type:text"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type DonorInput struct {
+	Name    string `json:"name" validate:"required,max=150"`
+	Email   string `json:"email" validate:"omitempty,email,max=100"`
+	Phone   string `json:"phone" validate:"max=30"`
+	Address string `json:"address"`
+	Notes   string `json:"notes"`
+}
+
+type UpdateDonorRequest struct {
+	Name    string  `json:"name" validate:"max=150"`
+	Email   *string `json:"email" validate:"omitempty,max=100"`
+	Phone   *string `json:"phone" validate:"omitempty,max=30"`
+	Address *string `json:"address"`
+	Notes   *string `json:"notes"`
+}
+
+type DonationStatus string
+
+const (
+	// DonationPending still has items waiting for a decision
+	DonationPending   DonationStatus = "pending"
+	DonationProcessed DonationStatus = "processed"
+)
+
+// Donation is one gift of items from a donor, recorded when it arrives.
+type Donation struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Number     string         `json:"number" gorm:"type:varchar(30);index"`
+	DonorID    uint           `json:"donor_id" gorm:"not null;index"`
+	ReceivedAt time.Time      `json:"received_at" gorm:"index"`
+	Status     DonationStatus `json:"status" gorm:"type:varchar(20);default:pending;index"`
+	Notes      string         `json:"notes" gorm:"type:text"`
+	CreatedBy  *uint          `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Set when the acknowledgment letter is first generated
+	AcknowledgedAt *time.Time `json:"acknowledged_at"`
+
+	// Relationships
+	Donor *Donor         `json:"donor,omitempty" gorm:"foreignKey:DonorID"`
+	Items []DonationItem `json:"items,omitempty" gorm:"foreignKey:DonationID"`
+}
+
+type ItemDecision string
+
+const (
+	ItemPending   ItemDecision = "pending"
+	ItemAccepted  ItemDecision = "accepted"
+	ItemDiscarded ItemDecision = "discarded"
+)
+
+// DonationItem is one title in a donation, possibly several copies of it.
+// BookID is the catalog record accepted copies were added to.
+type DonationItem struct {
+	ID          uint         `json:"id" gorm:"primaryKey"`
+	DonationID  uint         `json:"donation_id" gorm:"not null;index"`
+	ISBN        string       `json:"isbn" gorm:"type:varchar(20);index"`
+	Title       string       `json:"title" gorm:"type:varchar(200);not null"`
+	Author      string       `json:"author" gorm:"type:varchar(100)"`
+	Publisher   string       `json:"publisher" gorm:"type:varchar(100)"`
+	Category    string       `json:"category" gorm:"type:varchar(50)"`
+	PublishYear int          `json:"publish_year"`
+	Quantity    int          `json:"quantity" gorm:"default:1"`
+	Condition   string       `json:"condition" gorm:"type:varchar(20)"`
+	Decision    ItemDecision `json:"decision" gorm:"type:varchar(20);default:pending;index"`
+	Reason      string       `json:"reason" gorm:"type:varchar(255)"`
+	DecidedBy   *uint        `json:"decided_by"`
+	DecidedAt   *time.Time   `json:"decided_at"`
+	BookID      *uint        `json:"book_id" gorm:"index"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+
+	Donation *Donation `json:"donation,omitempty" gorm:"foreignKey:DonationID"`
+	Book     *Book     `json:"book,omitempty" gorm:"foreignKey:BookID"`
+}
+
+// DonationItemInput records a donated title. Details left out are looked
+// up by ISBN; items without an ISBN can be recorded but not catalogued.
+type DonationItemInput struct {
+	ISBN        string `json:"isbn" validate:"omitempty,isbn"`
+	Title       string `json:"title" validate:"max=200"`
+	Author      string `json:"author" validate:"max=100"`
+	Publisher   string `json:"publisher" validate:"max=100"`
+	Category    string `json:"category" validate:"max=50"`
+	PublishYear int    `json:"publish_year"`
+	Quantity    int    `json:"quantity" validate:"min=0"`
+	Condition   string `json:"condition" validate:"omitempty,oneof=new good fair poor"`
+}
+
+// CreateDonationRequest records a donation from a known donor, or from a
+// new one described by Donor.
+type CreateDonationRequest struct {
+	DonorID    *uint               `json:"donor_id"`
+	Donor      *DonorInput         `json:"donor"`
+	ReceivedAt *time.Time          `json:"received_at"`
+	Notes      string              `json:"notes"`
+	Items      []DonationItemInput `json:"items" validate:"required,min=1,dive"`
+}
+
+type AddDonationItemsRequest struct {
+	Items []DonationItemInput `json:"items" validate:"required,min=1,dive"`
+}
+
+type DecideItemInput struct {
+	ItemID   uint         `json:"item_id" validate:"required"`
+	Decision ItemDecision `json:"decision" validate:"required,oneof=accepted discarded"`
+	Reason   string       `json:"reason" validate:"max=255"`
+	Location string       `json:"location" validate:"max=50"`
+}
+
+// DecideDonationRequest accepts or discards items. Location is the shelf
+// of accepted items that do not give their own.
+type DecideDonationRequest struct {
+	Items    []DecideItemInput `json:"items" validate:"required,min=1,dive"`
+	Location string            `json:"location" validate:"max=50"`
+}
+
+// DonorReportRow sums one donor's donations in a year, or everyone's in
+// a total. Items count copies.
+type DonorReportRow struct {
+	DonorID   uint   `json:"donor_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Donations int    `json:"donations"`
+	Items     int    `json:"items"`
+	Accepted  int    `json:"accepted"`
+	Discarded int    `json:"discarded"`
+	Pending   int    `json:"pending"`
+}
+
+type DonorReport struct {
+	Year   int              `json:"year"`
+	Donors []DonorReportRow `json:"donors"`
+	Total  DonorReportRow   `json:"total"`
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Fund is a budget line of the library, such as children's books or
+// reference, with a Budget for each fiscal year.
+type Fund struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Code        string         `json:"code" gorm:"type:varchar(20);uniqueIndex;not null"`
+	Name        string         `json:"name" gorm:"type:varchar(100);not null"`
+	Description string         `json:"description" gorm:"type:text"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Rollover rules: whether next year starts with the same allocation,
+	// and whether money left unspent is carried into it
+	RolloverAllocation bool `json:"rollover_allocation" gorm:"default:false"`
+	RolloverBalance    bool `json:"rollover_balance" gorm:"default:false"`
+
+	Budgets []Budget `json:"budgets,omitempty" gorm:"foreignKey:FundID"`
+}
+
+// Budget is what a fund may spend in one fiscal year. Encumbered is
+// committed to open orders and Spent is invoiced.
+type Budget struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	FundID     uint      `json:"fund_id" gorm:"not null;uniqueIndex:idx_fund_year"`
+	FiscalYear int       `json:"fiscal_year" gorm:"not null;uniqueIndex:idx_fund_year"`
+	Allocated  float64   `json:"allocated" gorm:"type:decimal(14,2);default:0"`
+	CarriedIn  float64   `json:"carried_in" gorm:"type:decimal(14,2);default:0"`
+	CarriedOut float64   `json:"carried_out" gorm:"type:decimal(14,2);default:0"`
+	Encumbered float64   `json:"encumbered" gorm:"type:decimal(14,2);default:0"`
+	Spent      float64   `json:"spent" gorm:"type:decimal(14,2);default:0"`
+	Closed     bool      `json:"closed" gorm:"default:false"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	Available float64 `json:"available" gorm:"-"`
+
+	Fund *Fund `json:"fund,omitempty" gorm:"foreignKey:FundID"`
+}
+
+// Free is what is neither committed nor spent yet.
+func (b *Budget) Free() float64 {
+	return b.Allocated + b.CarriedIn - b.CarriedOut - b.Encumbered - b.Spent
+}
+
+type FundTransactionType string
+
+const (
+	FundAllocation  FundTransactionType = "allocation"
+	FundEncumbrance FundTransactionType = "encumbrance"
+	FundRelease     FundTransactionType = "release"
+	FundExpenditure FundTransactionType = "expenditure"
+	FundCarryIn     FundTransactionType = "carry_in"
+	FundCarryOut    FundTransactionType = "carry_out"
+)
+
+// FundTransaction is one entry of a fund's ledger. Posting it moves the
+// matching total of its budget by Amount.
+type FundTransaction struct {
+	ID          uint                `json:"id" gorm:"primaryKey"`
+	FundID      uint                `json:"fund_id" gorm:"not null;index"`
+	BudgetID    uint                `json:"budget_id" gorm:"not null;index"`
+	FiscalYear  int                 `json:"fiscal_year" gorm:"index"`
+	Type        FundTransactionType `json:"type" gorm:"type:varchar(20);index"`
+	Amount      float64             `json:"amount" gorm:"type:decimal(14,2)"`
+	OrderID     *uint               `json:"order_id" gorm:"index"`
+	OrderLineID *uint               `json:"order_line_id"`
+	InvoiceID   *uint               `json:"invoice_id" gorm:"index"`
+	Note        string              `json:"note" gorm:"type:varchar(255)"`
+	CreatedBy   *uint               `json:"created_by"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+// Invoice is a vendor's bill for copies on an order.
+type Invoice struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	OrderID     uint          `json:"order_id" gorm:"not null;index"`
+	VendorID    uint          `json:"vendor_id" gorm:"not null;index"`
+	Number      string        `json:"number" gorm:"type:varchar(50);not null;index"`
+	InvoiceDate time.Time     `json:"invoice_date"`
+	Total       float64       `json:"total" gorm:"type:decimal(14,2)"`
+	Notes       string        `json:"notes" gorm:"type:text"`
+	CreatedBy   *uint         `json:"created_by"`
+	CreatedAt   time.Time     `json:"created_at"`
+	Lines       []InvoiceLine `json:"lines,omitempty" gorm:"foreignKey:InvoiceID"`
+}
+
+type InvoiceLine struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	InvoiceID   uint    `json:"invoice_id" gorm:"not null;index"`
+	OrderLineID uint    `json:"order_line_id" gorm:"not null;index"`
+	Quantity    int     `json:"quantity"`
+	Amount      float64 `json:"amount" gorm:"type:decimal(14,2)"`
+	BudgetID    *uint   `json:"budget_id"`
+}
+
+type CreateFundRequest struct {
+	Code               string `json:"code" validate:"required,max=20"`
+	Name               string `json:"name" validate:"required,max=100"`
+	Description        string `json:"description"`
+	RolloverAllocation bool   `json:"rollover_allocation"`
+	RolloverBalance    bool   `json:"rollover_balance"`
+}
+
+type UpdateFundRequest struct {
+	Name               string  `json:"name" validate:"max=100"`
+	Description        *string `json:"description"`
+	RolloverAllocation *bool   `json:"rollover_allocation"`
+	RolloverBalance    *bool   `json:"rollover_balance"`
+	IsActive           *bool   `json:"is_active"`
+}
+
+// AllocateBudgetRequest sets a fund's allocation for a fiscal year.
+type AllocateBudgetRequest struct {
+	Allocated float64 `json:"allocated" validate:"min=0"`
+	Note      string  `json:"note" validate:"max=255"`
+}
+
+// RolloverRequest closes the budgets of a fiscal year that has ended.
+type RolloverRequest struct {
+	FiscalYear int `json:"fiscal_year" validate:"required"`
+}
+
+type InvoiceLineInput struct {
+	LineID   uint `json:"line_id" validate:"required"`
+	Quantity int  `json:"quantity" validate:"required,min=1"`
+	// Amount defaults to the ordered unit price times quantity
+	Amount *float64 `json:"amount" validate:"omitempty,min=0"`
+}
+
+type CreateInvoiceRequest struct {
+	Number      string             `json:"number" validate:"required,max=50"`
+	InvoiceDate *time.Time         `json:"invoice_date"`
+	Notes       string             `json:"notes"`
+	Lines       []InvoiceLineInput `json:"lines" validate:"required,min=1,dive"`
+}
+
+// BudgetReportRow sums one fund's budget, or all of them in a total.
+type BudgetReportRow struct {
+	FundID       uint    `json:"fund_id,omitempty"`
+	Code         string  `json:"code,omitempty"`
+	Name         string  `json:"name,omitempty"`
+	Allocated    float64 `json:"allocated"`
+	CarriedIn    float64 `json:"carried_in"`
+	CarriedOut   float64 `json:"carried_out"`
+	Encumbered   float64 `json:"encumbered"`
+	Spent        float64 `json:"spent"`
+	Available    float64 `json:"available"`
+	SpentPercent float64 `json:"spent_percent"`
+	Closed       bool    `json:"closed"`
+}
+
+type BudgetReport struct {
+	FiscalYear int               `json:"fiscal_year"`
+	Funds      []BudgetReportRow `json:"funds"`
+	Total      BudgetReportRow   `json:"total"`
+}
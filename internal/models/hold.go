@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type HoldStatus string
+
+const (
+	// HoldWaiting is queued until a matching copy comes back
+	HoldWaiting HoldStatus = "waiting"
+	// HoldReady has a copy set aside for pickup until ExpiresAt
+	HoldReady     HoldStatus = "ready"
+	HoldFulfilled HoldStatus = "fulfilled"
+	HoldCancelled HoldStatus = "cancelled"
+	HoldExpired   HoldStatus = "expired"
+)
+
+// Hold is a patron's request for the next available copy of a book. A
+// hold on a work with no BookID takes any edition of it.
+type Hold struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	UserID         *uint          `json:"user_id" gorm:"index"` // nil once the hold is detached from its reader
+	WorkID         uint           `json:"work_id" gorm:"not null;index"`
+	BookID         *uint          `json:"book_id" gorm:"index"`
+	AssignedBookID *uint          `json:"assigned_book_id" gorm:"index"`
+	Status         HoldStatus     `json:"status" gorm:"type:varchar(20);default:waiting;index"`
+	ReadyAt        *time.Time     `json:"ready_at"`
+	ExpiresAt      *time.Time     `json:"expires_at"`
+	ClosedAt       *time.Time     `json:"closed_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User         *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Work         *Work `json:"work,omitempty" gorm:"foreignKey:WorkID"`
+	Book         *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	AssignedBook *Book `json:"assigned_book,omitempty" gorm:"foreignKey:AssignedBookID"`
+}
+
+// AnyEdition tells whether the hold takes any edition of its work.
+func (h *Hold) AnyEdition() bool {
+	return h.BookID == nil
+}
+
+// PlaceHoldRequest asks for a specific edition with book_id, or for any
+// edition of a work with work_id or with book_id and any_edition.
+type PlaceHoldRequest struct {
+	UserID     uint `json:"user_id"`
+	BookID     uint `json:"book_id"`
+	WorkID     uint `json:"work_id"`
+	AnyEdition bool `json:"any_edition"`
+}
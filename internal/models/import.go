@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+type ImportStatus string
+
+const (
+	ImportPending   ImportStatus = "pending"
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// DuplicateMode decides what happens to a row whose ISBN is already in the
+// catalog.
+type DuplicateMode string
+
+const (
+	DuplicateMerge DuplicateMode = "merge"
+	DuplicateSkip  DuplicateMode = "skip"
+)
+
+type ImportRowStatus string
+
+const (
+	RowCreated ImportRowStatus = "created"
+	RowMerged  ImportRowStatus = "merged"
+	RowUpdated ImportRowStatus = "updated"
+	RowSkipped ImportRowStatus = "skipped"
+	RowInvalid ImportRowStatus = "invalid"
+	RowFailed  ImportRowStatus = "failed"
+)
+
+// ImportJob is a bulk catalog import from an uploaded CSV or XLSX file. A
+// job first runs as a dry run; committing it runs the same file for real.
+// In a dry run the row statuses say what would happen.
+type ImportJob struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	CreatedBy     uint              `json:"created_by" gorm:"not null;index"`
+	FileName      string            `json:"file_name" gorm:"type:varchar(255)"`
+	Format        string            `json:"format" gorm:"type:varchar(10)"`
+	Mapping       map[string]string `json:"mapping" gorm:"serializer:json;type:text"`
+	OnDuplicate   DuplicateMode     `json:"on_duplicate" gorm:"type:varchar(10);default:merge"`
+	DryRun        bool              `json:"dry_run"`
+	Status        ImportStatus      `json:"status" gorm:"type:varchar(20);default:pending;index"`
+	TotalRows     int               `json:"total_rows"`
+	ProcessedRows int               `json:"processed_rows"`
+	Created       int               `json:"created"`
+	Merged        int               `json:"merged"`
+	Skipped       int               `json:"skipped"`
+	Invalid       int               `json:"invalid"`
+	Failed        int               `json:"failed"`
+	Error         string            `json:"error,omitempty" gorm:"type:text"`
+	Report        []ImportRowResult `json:"report,omitempty" gorm:"serializer:json;type:longtext"`
+	Data          []byte            `json:"-" gorm:"type:longblob"`
+	StartedAt     *time.Time        `json:"started_at"`
+	FinishedAt    *time.Time        `json:"finished_at"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ImportRowResult is the outcome of one data row. Row is the line number in
+// the file, counting the header as line 1.
+type ImportRowResult struct {
+	Row    int             `json:"row"`
+	Status ImportRowStatus `json:"status"`
+	ISBN   string          `json:"isbn,omitempty"`
+	BookID uint            `json:"book_id,omitempty"`
+	Errors []string        `json:"errors,omitempty"`
+}
+
+// ImportRequest starts an import. Mapping maps book fields (the JSON names
+// of CreateBookRequest) to column headers; unmapped fields are matched to a
+// header of the same name.
+type ImportRequest struct {
+	FileName    string
+	Data        []byte
+	Mapping     map[string]string
+	OnDuplicate DuplicateMode
+}
+
+// RecordImportResult summarizes a synchronous import of catalog records,
+// e.g. MARC. Row in each result is the record's position in the file.
+type RecordImportResult struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Invalid int               `json:"invalid"`
+	Failed  int               `json:"failed"`
+	Records []ImportRowResult `json:"records"`
+}
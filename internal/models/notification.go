@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Notification is a message to a user, shown in their inbox until read.
+// EntityType and EntityID point at what it is about, e.g. a suggestion.
+type Notification struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"user_id" gorm:"not null;index"`
+	Subject    string     `json:"subject" gorm:"type:varchar(200);not null"`
+	Message    string     `json:"message" gorm:"type:text"`
+	EntityType string     `json:"entity_type" gorm:"type:varchar(50)"`
+	EntityID   uint       `json:"entity_id"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+type ErasureStatus string
+
+const (
+	ErasurePending   ErasureStatus = "pending"
+	ErasureCompleted ErasureStatus = "completed"
+	ErasureCancelled ErasureStatus = "cancelled"
+	// A failed erasure is retried on the next run
+	ErasureFailed ErasureStatus = "failed"
+)
+
+// ErasureRequest is the audit record of a personal data erasure. It is kept
+// after the user has been anonymized so the library can prove when and by
+// whom the erasure was requested and carried out.
+type ErasureRequest struct {
+	ID           uint          `json:"id" gorm:"primaryKey"`
+	UserID       uint          `json:"user_id" gorm:"not null;index"`
+	RequestedBy  uint          `json:"requested_by" gorm:"not null"`
+	Reason       string        `json:"reason" gorm:"type:text"`
+	Status       ErasureStatus `json:"status" gorm:"type:varchar(20);default:pending;index"`
+	ScheduledFor time.Time     `json:"scheduled_for" gorm:"not null"`
+	CompletedAt  *time.Time    `json:"completed_at"`
+	Error        string        `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+type ErasureRequestInput struct {
+	Reason string `json:"reason"`
+}
+
+// UserDataExport holds everything the library stores about a single user.
+type UserDataExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Profile    User           `json:"profile"`
+	Loans      []ExportedLoan `json:"loans"`
+	Fines      []ExportedFine `json:"fines"`
+	Holds      []ExportedHold `json:"holds"`
+}
+
+type ExportedLoan struct {
+	ID         uint         `json:"id"`
+	BookID     uint         `json:"book_id"`
+	BookTitle  string       `json:"book_title"`
+	BookISBN   string       `json:"book_isbn"`
+	BorrowDate time.Time    `json:"borrow_date"`
+	DueDate    time.Time    `json:"due_date"`
+	ReturnDate *time.Time   `json:"return_date"`
+	Status     BorrowStatus `json:"status"`
+	Notes      string       `json:"notes"`
+}
+
+type ExportedFine struct {
+	BorrowID  uint      `json:"borrow_id"`
+	BookTitle string    `json:"book_title"`
+	Amount    float64   `json:"amount"`
+	DueDate   time.Time `json:"due_date"`
+}
+
+type ExportedHold struct {
+	ID         uint       `json:"id"`
+	WorkTitle  string     `json:"work_title"`
+	BookID     *uint      `json:"book_id"`
+	AnyEdition bool       `json:"any_edition"`
+	Status     HoldStatus `json:"status"`
+	PlacedAt   time.Time  `json:"placed_at"`
+	ReadyAt    *time.Time `json:"ready_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	ClosedAt   *time.Time `json:"closed_at"`
+}
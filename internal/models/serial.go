@@ -0,0 +1,208 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SerialFrequency string
+
+const (
+	FrequencyWeekly    SerialFrequency = "weekly"
+	FrequencyMonthly   SerialFrequency = "monthly"
+	FrequencyQuarterly SerialFrequency = "quarterly"
+)
+
+// Serial is a magazine, newspaper or journal title. The library receives
+// it in issues, which are reference-only unless made borrowable.
+type Serial struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	Title     string          `json:"title" gorm:"type:varchar(200);not null;index"`
+	ISSN      string          `json:"issn" gorm:"type:varchar(9);index"`
+	Publisher string          `json:"publisher" gorm:"type:varchar(100)"`
+	Category  string          `json:"category" gorm:"type:varchar(50)"`
+	Frequency SerialFrequency `json:"frequency" gorm:"type:varchar(20);not null"`
+	Location  string          `json:"location" gorm:"type:varchar(50)"`
+	Notes     string          `json:"notes" gorm:"type:text"`
+	IsActive  bool            `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	DeletedAt gorm.DeletedAt  `json:"-" gorm:"index"`
+
+	// Loan period of borrowable issues that do not set their own
+	LoanDays int `json:"loan_days" gorm:"default:7"`
+
+	Subscriptions []SerialSubscription `json:"subscriptions,omitempty" gorm:"foreignKey:SerialID"`
+}
+
+// SerialSubscription is a paid period of a serial. The issues it should
+// bring are predicted from the serial's frequency when it is added.
+type SerialSubscription struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	SerialID       uint      `json:"serial_id" gorm:"not null;index"`
+	VendorID       *uint     `json:"vendor_id" gorm:"index"`
+	StartDate      time.Time `json:"start_date"`
+	EndDate        time.Time `json:"end_date"`
+	FirstIssueDate time.Time `json:"first_issue_date"`
+	Price          float64   `json:"price" gorm:"type:decimal(12,2);default:0"`
+	Notes          string    `json:"notes" gorm:"type:text"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Days past an expected date before an issue is claimed, and again
+	// after each claim
+	ClaimAfterDays int `json:"claim_after_days" gorm:"default:14"`
+
+	// Numbers restart at 1 in a new volume after this many issues; 0
+	// never starts a new volume
+	IssuesPerVolume int `json:"issues_per_volume" gorm:"default:0"`
+
+	Vendor *Vendor `json:"vendor,omitempty" gorm:"foreignKey:VendorID"`
+}
+
+type IssueStatus string
+
+const (
+	IssueExpected IssueStatus = "expected"
+	// IssueLate is past its claim date and should be claimed
+	IssueLate     IssueStatus = "late"
+	IssueClaimed  IssueStatus = "claimed"
+	IssueReceived IssueStatus = "received"
+	// IssueMissing will not arrive; the vendor could not supply it
+	IssueMissing IssueStatus = "missing"
+)
+
+// SerialIssue is one issue of a serial, predicted by a subscription or
+// recorded when it arrived out of pattern, e.g. a special edition.
+type SerialIssue struct {
+	ID             uint        `json:"id" gorm:"primaryKey"`
+	SerialID       uint        `json:"serial_id" gorm:"not null;index"`
+	SubscriptionID *uint       `json:"subscription_id" gorm:"index"`
+	Volume         int         `json:"volume"`
+	Number         int         `json:"number"`
+	Label          string      `json:"label" gorm:"type:varchar(100)"`
+	ExpectedAt     time.Time   `json:"expected_at" gorm:"index"`
+	Status         IssueStatus `json:"status" gorm:"type:varchar(20);default:expected;index"`
+	ReceivedAt     *time.Time  `json:"received_at"`
+	ClaimCount     int         `json:"claim_count" gorm:"default:0"`
+	ClaimedAt      *time.Time  `json:"claimed_at"`
+	Location       string      `json:"location" gorm:"type:varchar(50)"`
+	Notes          string      `json:"notes" gorm:"type:text"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+
+	// When an issue that has not arrived turns late
+	ClaimAt time.Time `json:"claim_at" gorm:"index"`
+
+	// Loan rules; LoanDays 0 takes the serial's
+	Borrowable bool `json:"borrowable" gorm:"default:false"`
+	LoanDays   int  `json:"loan_days" gorm:"default:0"`
+	OnLoan     bool `json:"on_loan" gorm:"default:false"`
+
+	Serial *Serial `json:"serial,omitempty" gorm:"foreignKey:SerialID"`
+}
+
+// Open tells whether the issue is still awaited.
+func (i *SerialIssue) Open() bool {
+	return i.Status == IssueExpected || i.Status == IssueLate || i.Status == IssueClaimed
+}
+
+// SerialLoan lends a borrowable issue to a reader.
+type SerialLoan struct {
+	ID         uint         `json:"id" gorm:"primaryKey"`
+	IssueID    uint         `json:"issue_id" gorm:"not null;index"`
+	UserID     *uint        `json:"user_id" gorm:"index"`
+	BorrowDate time.Time    `json:"borrow_date"`
+	DueDate    time.Time    `json:"due_date" gorm:"index"`
+	ReturnDate *time.Time   `json:"return_date"`
+	Status     BorrowStatus `json:"status" gorm:"type:varchar(20);default:borrowed;index"`
+	Notes      string       `json:"notes" gorm:"type:text"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+
+	Issue *SerialIssue `json:"issue,omitempty" gorm:"foreignKey:IssueID"`
+	User  *User        `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type CreateSerialRequest struct {
+	Title     string          `json:"title" validate:"required,max=200"`
+	ISSN      string          `json:"issn" validate:"max=9"`
+	Publisher string          `json:"publisher" validate:"max=100"`
+	Category  string          `json:"category" validate:"max=50"`
+	Frequency SerialFrequency `json:"frequency" validate:"required,oneof=weekly monthly quarterly"`
+	Location  string          `json:"location" validate:"max=50"`
+	Notes     string          `json:"notes"`
+	LoanDays  int             `json:"loan_days" validate:"min=0"`
+}
+
+type UpdateSerialRequest struct {
+	Title     string          `json:"title" validate:"max=200"`
+	ISSN      *string         `json:"issn" validate:"omitempty,max=9"`
+	Publisher *string         `json:"publisher" validate:"omitempty,max=100"`
+	Category  *string         `json:"category" validate:"omitempty,max=50"`
+	Frequency SerialFrequency `json:"frequency" validate:"omitempty,oneof=weekly monthly quarterly"`
+	Location  *string         `json:"location" validate:"omitempty,max=50"`
+	Notes     *string         `json:"notes"`
+	LoanDays  *int            `json:"loan_days" validate:"omitempty,min=1"`
+	IsActive  *bool           `json:"is_active"`
+}
+
+// CreateSubscriptionRequest adds a subscription period. Issues are
+// expected from FirstIssueDate, the start date by default, through the
+// end date. Numbering continues from the serial's last issue unless a
+// start volume and number are given; IssuesPerVolume 0 never starts a
+// new volume.
+type CreateSubscriptionRequest struct {
+	VendorID        *uint      `json:"vendor_id"`
+	StartDate       time.Time  `json:"start_date" validate:"required"`
+	EndDate         time.Time  `json:"end_date" validate:"required"`
+	FirstIssueDate  *time.Time `json:"first_issue_date"`
+	StartVolume     int        `json:"start_volume" validate:"min=0"`
+	StartNumber     int        `json:"start_number" validate:"min=0"`
+	IssuesPerVolume int        `json:"issues_per_volume" validate:"min=0"`
+	ClaimAfterDays  int        `json:"claim_after_days" validate:"min=0"`
+	Price           float64    `json:"price" validate:"min=0"`
+	Notes           string     `json:"notes"`
+}
+
+// AddIssueRequest records an issue outside the predicted pattern, such
+// as a special edition, as received.
+type AddIssueRequest struct {
+	Volume   int    `json:"volume" validate:"min=0"`
+	Number   int    `json:"number" validate:"min=0"`
+	Label    string `json:"label" validate:"max=100"`
+	Location string `json:"location" validate:"max=50"`
+	Notes    string `json:"notes"`
+}
+
+type CheckInIssueRequest struct {
+	ReceivedAt *time.Time `json:"received_at"`
+	Location   string     `json:"location" validate:"max=50"`
+	Notes      string     `json:"notes"`
+}
+
+// ClaimIssueRequest records chasing the vendor for a late issue.
+type ClaimIssueRequest struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+type MissingIssueRequest struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+// IssueLoanRulesRequest makes an issue borrowable, or reference-only
+// again.
+type IssueLoanRulesRequest struct {
+	Borrowable bool `json:"borrowable"`
+	LoanDays   int  `json:"loan_days" validate:"min=0"`
+}
+
+type BorrowIssueRequest struct {
+	UserID uint   `json:"user_id" validate:"required"`
+	Notes  string `json:"notes"`
+}
+
+type ReturnIssueRequest struct {
+	Notes string `json:"notes"`
+}
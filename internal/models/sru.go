@@ -0,0 +1,18 @@
+package models
+
+// SRURequest holds the parameters of an SRU request. Numbers left out of
+// the request are zero, or nil where zero means something.
+type SRURequest struct {
+	Operation      string
+	Version        string
+	Query          string
+	StartRecord    int
+	MaximumRecords *int
+	RecordSchema   string
+	RecordPacking  string
+
+	// scan
+	ScanClause       string
+	ResponsePosition *int
+	MaximumTerms     int
+}
@@ -0,0 +1,131 @@
+package models
+
+import "time"
+
+type StocktakeStatus string
+
+const (
+	// StocktakeOpen is still taking scans
+	StocktakeOpen   StocktakeStatus = "open"
+	StocktakeClosed StocktakeStatus = "closed"
+)
+
+// Stocktake is an inventory of the shelves whose locations sort from
+// LocationFrom to LocationTo, both included.
+type Stocktake struct {
+	ID           uint            `json:"id" gorm:"primaryKey"`
+	Name         string          `json:"name" gorm:"type:varchar(100);not null"`
+	LocationFrom string          `json:"location_from" gorm:"type:varchar(50);not null"`
+	LocationTo   string          `json:"location_to" gorm:"type:varchar(50);not null"`
+	Status       StocktakeStatus `json:"status" gorm:"type:varchar(20);default:open;index"`
+	Notes        string          `json:"notes" gorm:"type:text"`
+	CreatedBy    *uint           `json:"created_by"`
+	ClosedAt     *time.Time      `json:"closed_at"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+
+	// Filled in when listing a single stocktake
+	ScanCount int `json:"scan_count" gorm:"-"`
+}
+
+// StocktakeScan is one copy scanned during a stocktake. BookID is empty
+// for codes that match no book on the shelves.
+type StocktakeScan struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	StocktakeID uint      `json:"stocktake_id" gorm:"not null;index"`
+	Code        string    `json:"code" gorm:"type:varchar(40);not null"`
+	BookID      *uint     `json:"book_id" gorm:"index"`
+	Location    string    `json:"location" gorm:"type:varchar(50)"`
+	ScannedBy   *uint     `json:"scanned_by"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	Book *Book `json:"book,omitempty" gorm:"foreignKey:BookID"`
+}
+
+// StocktakeCount is the number of copies of a book the catalog expected on
+// the shelves when its stocktake closed. Reports of a closed stocktake are
+// reconciled against these counts, so loans and returns made afterwards
+// don't change them. WrittenOff counts the missing copies taken off the
+// stock since.
+type StocktakeCount struct {
+	ID          uint `json:"id" gorm:"primaryKey"`
+	StocktakeID uint `json:"stocktake_id" gorm:"not null;uniqueIndex:idx_stocktake_count_book"`
+	BookID      uint `json:"book_id" gorm:"not null;uniqueIndex:idx_stocktake_count_book"`
+	Stock       int  `json:"stock"`
+	Expected    int  `json:"expected"`
+	WrittenOff  int  `json:"written_off"`
+}
+
+type CreateStocktakeRequest struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	LocationFrom string `json:"location_from" validate:"required,max=50"`
+	LocationTo   string `json:"location_to" validate:"max=50"`
+	Notes        string `json:"notes"`
+}
+
+// ScanRequest records scanned codes, one per copy, read at the shelf
+// given by Location when the scanner knows it.
+type ScanRequest struct {
+	Location string   `json:"location" validate:"max=50"`
+	Codes    []string `json:"codes" validate:"required,min=1,max=1000"`
+}
+
+type ScanResult struct {
+	Recorded int      `json:"recorded"`
+	Matched  int      `json:"matched"`
+	Unknown  []string `json:"unknown"`
+}
+
+// StocktakeLine is a book that did not reconcile. Count is the number of
+// copies the line is about; Loans lists the open loans of checked out
+// copies found on the shelf.
+type StocktakeLine struct {
+	BookID           uint     `json:"book_id"`
+	Title            string   `json:"title"`
+	ISBN             string   `json:"isbn"`
+	CallNumber       string   `json:"call_number"`
+	Location         string   `json:"location"`
+	ScannedLocations []string `json:"scanned_locations,omitempty"`
+	Expected         int      `json:"expected"`
+	Found            int      `json:"found"`
+	Count            int      `json:"count"`
+	Loans            []uint   `json:"loans,omitempty"`
+}
+
+type UnknownCode struct {
+	Code      string   `json:"code"`
+	Count     int      `json:"count"`
+	Locations []string `json:"locations,omitempty"`
+}
+
+// StocktakeReport reconciles the scans of a stocktake with the catalog: as
+// it is now while the stocktake is open, as it was at closing afterwards.
+// Surplus copies are found on top of every copy the catalog knows of.
+type StocktakeReport struct {
+	Stocktake       *Stocktake      `json:"stocktake"`
+	Expected        int             `json:"expected"`
+	Scanned         int             `json:"scanned"`
+	Missing         []StocktakeLine `json:"missing"`
+	Misplaced       []StocktakeLine `json:"misplaced"`
+	CheckedOutFound []StocktakeLine `json:"checked_out_found"`
+	Surplus         []StocktakeLine `json:"surplus"`
+	Unknown         []UnknownCode   `json:"unknown"`
+}
+
+// MarkMissingRequest writes off the missing copies of the given books,
+// or of every missing book when none are given.
+type MarkMissingRequest struct {
+	BookIDs []uint `json:"book_ids"`
+}
+
+type LocationCorrection struct {
+	BookID   uint   `json:"book_id" validate:"required"`
+	Location string `json:"location" validate:"max=50"`
+}
+
+// CorrectLocationsRequest moves misplaced books to a new location, by
+// default the one they were scanned at. Without items every misplaced
+// book scanned at a single location is moved there.
+type CorrectLocationsRequest struct {
+	Items []LocationCorrection `json:"items" validate:"dive"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SuggestionStatus string
+
+const (
+	SuggestionPending  SuggestionStatus = "pending"
+	SuggestionAccepted SuggestionStatus = "accepted"
+	SuggestionRejected SuggestionStatus = "rejected"
+	SuggestionOrdered  SuggestionStatus = "ordered"
+	// SuggestionCatalogued has the bought book in BookID
+	SuggestionCatalogued SuggestionStatus = "catalogued"
+)
+
+// PurchaseSuggestion is a patron's request that the library buy a book.
+// Readers suggesting the same book add a vote to it rather than a new
+// suggestion.
+type PurchaseSuggestion struct {
+	ID         uint             `json:"id" gorm:"primaryKey"`
+	Title      string           `json:"title" gorm:"type:varchar(200);not null"`
+	Author     string           `json:"author" gorm:"type:varchar(100)"`
+	ISBN       string           `json:"isbn" gorm:"type:varchar(20);index"`
+	MatchKey   string           `json:"-" gorm:"type:varchar(255);index"`
+	Status     SuggestionStatus `json:"status" gorm:"type:varchar(20);default:pending;index"`
+	Votes      int              `json:"votes" gorm:"default:0"`
+	StaffNote  string           `json:"staff_note" gorm:"type:text"`
+	ReviewedBy *uint            `json:"reviewed_by"`
+	ReviewedAt *time.Time       `json:"reviewed_at"`
+	BookID     *uint            `json:"book_id" gorm:"index"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt   `json:"-" gorm:"index"`
+
+	// Relationships
+	Book       *Book            `json:"book,omitempty" gorm:"foreignKey:BookID"`
+	Suggesters []SuggestionVote `json:"suggesters,omitempty" gorm:"foreignKey:SuggestionID"`
+}
+
+// Open tells whether the suggestion may still take votes.
+func (s *PurchaseSuggestion) Open() bool {
+	return s.Status == SuggestionPending || s.Status == SuggestionAccepted || s.Status == SuggestionOrdered
+}
+
+// SuggestionVote is one reader's request for a suggested book, with their
+// reason and whether to place a hold for them once it is catalogued.
+type SuggestionVote struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	SuggestionID uint      `json:"suggestion_id" gorm:"not null;uniqueIndex:idx_suggestion_user"`
+	UserID       uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_suggestion_user"`
+	Reason       string    `json:"reason" gorm:"type:text"`
+	AutoHold     bool      `json:"auto_hold" gorm:"default:false"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+type CreateSuggestionRequest struct {
+	Title    string `json:"title" validate:"required,min=1,max=200"`
+	Author   string `json:"author" validate:"max=100"`
+	ISBN     string `json:"isbn" validate:"omitempty,isbn"`
+	Reason   string `json:"reason" validate:"max=1000"`
+	AutoHold bool   `json:"auto_hold"`
+}
+
+// ReviewSuggestionRequest moves a suggestion to accepted, rejected or
+// ordered, with a note the suggesters see.
+type ReviewSuggestionRequest struct {
+	Status SuggestionStatus `json:"status" validate:"required,oneof=accepted rejected ordered"`
+	Note   string           `json:"note" validate:"max=1000"`
+}
+
+type CatalogueSuggestionRequest struct {
+	BookID uint `json:"book_id" validate:"required"`
+}
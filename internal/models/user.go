@@ -7,17 +7,19 @@ import (
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"not null" validate:"required,min=2,max=100"`
-	Email     string         `json:"email" gorm:"type:varchar(100);uniqueIndex;not null" validate:"required,email"`
-	Password  string         `json:"-" gorm:"not null" validate:"required,min=6"`
-	Phone     string         `json:"phone" gorm:"not null" validate:"required,min=10,max=15"`
-	Address   string         `json:"address" gorm:"type:text"`
-	Role      string         `json:"role" gorm:"default:member" validate:"oneof=admin librarian member"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	Name               string         `json:"name" gorm:"not null" validate:"required,min=2,max=100"`
+	Email              string         `json:"email" gorm:"type:varchar(100);uniqueIndex;not null" validate:"required,email"`
+	Password           string         `json:"-" gorm:"not null" validate:"required,min=6"`
+	Phone              string         `json:"phone" gorm:"not null" validate:"required,min=10,max=15"`
+	Address            string         `json:"address" gorm:"type:text"`
+	Role               string         `json:"role" gorm:"default:member" validate:"oneof=admin librarian member"`
+	IsActive           bool           `json:"is_active" gorm:"default:true"`
+	AnonymizedAt       *time.Time     `json:"anonymized_at,omitempty"`
+	KeepReadingHistory bool           `json:"keep_reading_history" gorm:"default:true"` // false detaches returned loans after a while
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Borrows []Borrow `json:"borrows,omitempty" gorm:"foreignKey:UserID"`
@@ -41,6 +43,10 @@ type UpdateUserRequest struct {
 	IsActive *bool  `json:"is_active"`
 }
 
+type PrivacyPreferencesRequest struct {
+	KeepReadingHistory *bool `json:"keep_reading_history" validate:"required"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
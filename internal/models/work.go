@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Work is the abstract book that editions and translations share, e.g.
+// "Bumi Manusia" in its Indonesian editions and its English translation
+// "This Earth of Mankind". Every book belongs to one work.
+type Work struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Title     string    `json:"title" gorm:"type:varchar(200);not null"`
+	Author    string    `json:"author" gorm:"type:varchar(100)"`
+	WorkKey   string    `json:"-" gorm:"type:varchar(255);index"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	EditionCount int64  `json:"edition_count" gorm:"-"`
+	Editions     []Book `json:"editions,omitempty" gorm:"foreignKey:WorkID"`
+}
+
+type UpdateWorkRequest struct {
+	Title  string `json:"title" validate:"max=200"`
+	Author string `json:"author" validate:"max=100"`
+}
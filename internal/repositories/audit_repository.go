@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// AuditRepository is append-only: entries can be added and read, never
+// deleted. Only the request context the hash leaves out, i.e. IP, request ID
+// and a member's actor ID, can be cleared when a reader is forgotten.
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	GetLast() (*models.AuditLog, error)
+	Search(spec *query.Spec) ([]models.AuditLog, *query.Page, error)
+	GetBatchAfter(afterID uint, limit int) ([]models.AuditLog, error)
+	ClearActor(userID uint) error
+	ClearMemberActorsBefore(cutoff time.Time) (int64, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+var auditListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"actor_id":    uintFilter("actor_id"),
+		"action":      equalsFilter("action"),
+		"entity_type": equalsFilter("entity_type"),
+		"entity_id":   uintFilter("entity_id"),
+		"request_id":  equalsFilter("request_id"),
+		"from":        timeFilter("created_at", ">="),
+		"to":          timeFilter("created_at", "<="),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+}
+
+func (r *auditRepository) Create(entry *models.AuditLog) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *auditRepository) GetLast() (*models.AuditLog, error) {
+	var entry models.AuditLog
+	err := r.db.Order("id DESC").First(&entry).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *auditRepository) Search(spec *query.Spec) ([]models.AuditLog, *query.Page, error) {
+	return paginate[models.AuditLog](r.db, spec, auditListOptions)
+}
+
+func (r *auditRepository) GetBatchAfter(afterID uint, limit int) ([]models.AuditLog, error) {
+	var entries []models.AuditLog
+	err := r.db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+// ClearActor removes the request context of everything a user did. Their
+// ID stays on entries made as staff, which the hash covers.
+func (r *auditRepository) ClearActor(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.AuditLog{}).Where("actor_id = ?", userID).
+			Updates(map[string]interface{}{"ip": "", "request_id": ""}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.AuditLog{}).Where("actor_id = ? AND actor_role = ?", userID, "member").
+			Update("actor_id", nil).Error
+	})
+}
+
+// ClearMemberActorsBefore unlinks entries older than cutoff from members who
+// chose not to keep their reading history.
+func (r *auditRepository) ClearMemberActorsBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Model(&models.AuditLog{}).
+		Where("actor_role = ? AND created_at < ?", "member", cutoff).
+		Where("actor_id IN (?)", r.db.Model(&models.User{}).Select("id").Where("keep_reading_history = ?", false)).
+		Updates(map[string]interface{}{
+			"actor_id":   nil,
+			"ip":         "",
+			"request_id": "",
+		})
+	return result.RowsAffected, result.Error
+}
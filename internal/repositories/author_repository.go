@@ -0,0 +1,237 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type AuthorRepository interface {
+	GetAll(spec *query.Spec) ([]models.Author, *query.Page, error)
+	GetByID(id uint) (*models.Author, error)
+	GetByNameKey(key string) (*models.Author, error)
+	FindOrCreate(author *models.Author) error
+	Update(author *models.Author) error
+	ReplaceContributors(bookID uint, contributors []models.BookContributor) error
+	Merge(targetID uint, sourceIDs []uint) ([]uint, error)
+}
+
+type authorRepository struct {
+	db *gorm.DB
+}
+
+func NewAuthorRepository(db *gorm.DB) AuthorRepository {
+	return &authorRepository{db: db}
+}
+
+var authorListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"sort_name":  "sort_name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name": likeFilter("name"),
+	},
+	defaultSort: []query.SortField{{Field: "sort_name"}},
+}
+
+// GetAll lists authors with the number of active books credited to each.
+func (r *authorRepository) GetAll(spec *query.Spec) ([]models.Author, *query.Page, error) {
+	authors, page, err := paginate[models.Author](r.db, spec, authorListOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint, len(authors))
+	for i := range authors {
+		ids[i] = authors[i].ID
+	}
+	counts, err := r.bookCounts(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range authors {
+		authors[i].BookCount = counts[authors[i].ID]
+	}
+	return authors, page, nil
+}
+
+func (r *authorRepository) GetByID(id uint) (*models.Author, error) {
+	var author models.Author
+	if err := r.db.First(&author, id).Error; err != nil {
+		return nil, err
+	}
+
+	counts, err := r.bookCounts([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	author.BookCount = counts[id]
+	return &author, nil
+}
+
+func (r *authorRepository) bookCounts(ids []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		AuthorID uint
+		Count    int64
+	}
+	err := r.db.Table("book_contributors").
+		Select("book_contributors.author_id, COUNT(DISTINCT book_contributors.book_id) AS count").
+		Joins("JOIN books ON books.id = book_contributors.book_id").
+		Where("book_contributors.author_id IN ? AND books.is_active = ? AND books.deleted_at IS NULL", ids, true).
+		Group("book_contributors.author_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.AuthorID] = row.Count
+	}
+	return counts, err
+}
+
+// FindOrCreate loads the author with the same name key, or one merged
+// under that key, and creates it otherwise.
+func (r *authorRepository) FindOrCreate(author *models.Author) error {
+	found, err := r.GetByNameKey(author.NameKey)
+	if err == nil {
+		*author = *found
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := r.db.Create(author).Error; err != nil {
+		// Created concurrently under the same key
+		if found, findErr := r.GetByNameKey(author.NameKey); findErr == nil {
+			*author = *found
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByNameKey finds the author with a name key, or the one it was merged
+// into.
+func (r *authorRepository) GetByNameKey(key string) (*models.Author, error) {
+	var author models.Author
+	err := r.db.Where("name_key = ?", key).First(&author).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var alias models.AuthorAlias
+		if err := r.db.Where("name_key = ?", key).First(&alias).Error; err != nil {
+			return nil, err
+		}
+		err = r.db.First(&author, alias.AuthorID).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+func (r *authorRepository) Update(author *models.Author) error {
+	return r.db.Save(author).Error
+}
+
+// ReplaceContributors sets the full list of credits for a book.
+func (r *authorRepository) ReplaceContributors(bookID uint, contributors []models.BookContributor) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id = ?", bookID).Delete(&models.BookContributor{}).Error; err != nil {
+			return err
+		}
+		if len(contributors) == 0 {
+			return nil
+		}
+		rows := make([]models.BookContributor, len(contributors))
+		for i, contributor := range contributors {
+			rows[i] = models.BookContributor{
+				BookID:   bookID,
+				AuthorID: contributor.AuthorID,
+				Role:     contributor.Role,
+				Position: contributor.Position,
+			}
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// Merge moves every credit of the source authors to the target and deletes
+// the sources, keeping their name keys as aliases of the target. It returns
+// the IDs of the books whose credits changed.
+func (r *authorRepository) Merge(targetID uint, sourceIDs []uint) ([]uint, error) {
+	var bookIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var credits []models.BookContributor
+		if err := tx.Where("author_id IN ?", append([]uint{targetID}, sourceIDs...)).
+			Order("book_id, position").Find(&credits).Error; err != nil {
+			return err
+		}
+
+		// A book credited to both in the same role keeps a single credit
+		type credit struct {
+			bookID uint
+			role   models.ContributorRole
+		}
+		kept := make(map[credit]bool)
+		for _, c := range credits {
+			if c.AuthorID == targetID {
+				kept[credit{c.BookID, c.Role}] = true
+			}
+		}
+		var moved []models.BookContributor
+		touched := make(map[uint]bool)
+		for _, c := range credits {
+			if c.AuthorID == targetID {
+				continue
+			}
+			touched[c.BookID] = true
+			key := credit{c.BookID, c.Role}
+			if kept[key] {
+				continue
+			}
+			kept[key] = true
+			c.AuthorID = targetID
+			moved = append(moved, c)
+		}
+		for id := range touched {
+			bookIDs = append(bookIDs, id)
+		}
+
+		if err := tx.Where("author_id IN ?", sourceIDs).Delete(&models.BookContributor{}).Error; err != nil {
+			return err
+		}
+		if len(moved) > 0 {
+			if err := tx.Create(&moved).Error; err != nil {
+				return err
+			}
+		}
+
+		var sources []models.Author
+		if err := tx.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.AuthorAlias{}).Where("author_id IN ?", sourceIDs).
+			Update("author_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Author{}, sourceIDs).Error; err != nil {
+			return err
+		}
+		for _, source := range sources {
+			alias := models.AuthorAlias{NameKey: source.NameKey, AuthorID: targetID}
+			if err := tx.Save(&alias).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return bookIDs, err
+}
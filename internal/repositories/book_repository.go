@@ -1,21 +1,58 @@
 package repositories
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookRepository interface {
 	Create(book *models.Book) error
-	GetAll() ([]models.Book, error)
+	GetAll(spec *query.Spec) ([]models.Book, *query.Page, error)
 	GetByID(id uint) (*models.Book, error)
 	GetByISBN(isbn string) (*models.Book, error)
+	GetByIDs(ids []uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	Facets(ids []uint, spec *query.Spec) (*models.BookFacets, error)
 	Update(book *models.Book) error
 	Delete(id uint) error
-	Search(query string) ([]models.Book, error)
-	GetByCategory(category string) ([]models.Book, error)
-	GetAvailableBooks() ([]models.Book, error)
-	UpdateStock(id uint, stock, available int) error
+	GetByCategory(category string, spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetAvailableBooks(spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetByClassification(classification *models.Classification, spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetBySubject(subjectID uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetCatalog() ([]models.Book, error)
+	GetCatalogByIDs(ids []uint) ([]models.Book, error)
+	GetActiveIDs() ([]uint, error)
+	MatchIDs(field, op, value string) ([]uint, error)
+	ScanTerms(field, from string, limit int, backwards bool) ([]models.FacetCount, error)
+	GetWithoutClassification(afterID uint, limit int) ([]models.Book, error)
+	GetIDsByClassification(classificationID uint) ([]uint, error)
+	UpdateClassification(id uint, classificationID *uint, category, callNumber string) error
+	GetByAuthor(authorID uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetByPublisher(publisherID uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetWithoutContributors(afterID uint, limit int) ([]models.Book, error)
+	GetWithoutPublisher(afterID uint, limit int) ([]models.Book, error)
+	UpdateCredits(id uint, author, publisher string, publisherID *uint) error
+	AddStock(id uint, quantity int) error
+	UpdateLocation(id uint, location string) error
+	GetShelvedBetween(from, to string) ([]models.Book, error)
+	GetShelvedByISBNs(codes []string) ([]models.Book, error)
+	GetShelvedByIDs(ids []uint) ([]models.Book, error)
+	GetAvailableEditions(workID uint) ([]models.Book, error)
+	GetWithoutWork(afterID uint, limit int) ([]models.Book, error)
+	UpdateWork(id uint, workID uint) error
+	GetSummary(id uint) (*models.Book, error)
+	UpdateCover(id uint, updatedAt *time.Time) error
+	GetWithNonCanonicalISBN() ([]models.Book, error)
+	UpdateISBN(id uint, isbn string) error
+	LastModified() (time.Time, error)
 }
 
 type bookRepository struct {
@@ -26,66 +63,532 @@ func NewBookRepository(db *gorm.DB) BookRepository {
 	return &bookRepository{db: db}
 }
 
+var bookListOptions = listOptions{
+	sorts: map[string]string{
+		"id":           "id",
+		"title":        "title",
+		"author":       "author",
+		"category":     "category",
+		"publish_year": "publish_year",
+		"available":    "available",
+		"created_at":   "created_at",
+		"call_number":  "call_number",
+	},
+	filters: map[string]filterFunc{
+		"category":          equalsFilter("category"),
+		"language":          equalsFilter("language"),
+		"author":            likeFilter("author"),
+		"publisher":         likeFilter("publisher"),
+		"publisher_id":      uintFilter("publisher_id"),
+		"work_id":           uintFilter("work_id"),
+		"classification_id": classificationFilter,
+		"subject_id":        subjectFilter,
+		"location":          equalsFilter("location"),
+		"item_type":         equalsFilter("item_type"),
+		"publish_year_from": intRangeFilter("publish_year", ">="),
+		"publish_year_to":   intRangeFilter("publish_year", "<="),
+		"available": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			if value == "true" {
+				return db.Where("available > 0"), nil
+			}
+			return db.Where("available = 0"), nil
+		},
+		"created_after":  timeFilter("created_at", ">="),
+		"created_before": timeFilter("created_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "title"}},
+	preloads:    []string{"Files"},
+}
+
+// classificationFilter matches books classified under a node or anywhere
+// below it.
+func classificationFilter(db *gorm.DB, value string) (*gorm.DB, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: classification_id must be a number", query.ErrInvalid)
+	}
+	subtree := db.Session(&gorm.Session{NewDB: true}).
+		Table("classifications AS sub").
+		Select("sub.id").
+		Joins("JOIN classifications AS node ON sub.path LIKE CONCAT(node.path, '%')").
+		Where("node.id = ?", id)
+	return db.Where("classification_id IN (?)", subtree), nil
+}
+
+func subjectFilter(db *gorm.DB, value string) (*gorm.DB, error) {
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subject_id must be a number", query.ErrInvalid)
+	}
+	tagged := db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.BookSubject{}).Select("book_id").Where("subject_id = ?", id)
+	return db.Where("id IN (?)", tagged), nil
+}
+
 func (r *bookRepository) Create(book *models.Book) error {
 	// Set available same as stock initially
 	book.Available = book.Stock
-	return r.db.Create(book).Error
+	return r.db.Omit(clause.Associations).Create(book).Error
 }
 
-func (r *bookRepository) GetAll() ([]models.Book, error) {
-	var books []models.Book
-	err := r.db.Where("is_active = ?", true).Find(&books).Error
-	return books, err
+func (r *bookRepository) GetAll(spec *query.Spec) ([]models.Book, *query.Page, error) {
+	return paginate[models.Book](r.db.Where("is_active = ?", true), spec, bookListOptions)
 }
 
 func (r *bookRepository) GetByID(id uint) (*models.Book, error) {
 	var book models.Book
-	err := r.db.Preload("Borrows").First(&book, id).Error
+	err := r.db.Preload("Borrows").
+		Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Contributors.Author").
+		Preload("PublisherRecord").
+		Preload("Classification").
+		Preload("Subjects", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Subjects.Subject").
+		Preload("Files").
+		First(&book, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
-func (r *bookRepository) GetByISBN(isbn string) (*models.Book, error) {
+// GetByISBN finds a book by any form of its ISBN.
+func (r *bookRepository) GetByISBN(code string) (*models.Book, error) {
+	variants := isbn.Variants(code)
+	if len(variants) == 0 {
+		variants = []string{code}
+	}
+
 	var book models.Book
-	err := r.db.Where("isbn = ?", isbn).First(&book).Error
+	err := r.db.Where("isbn IN ?", variants).First(&book).Error
 	if err != nil {
 		return nil, err
 	}
 	return &book, nil
 }
 
+// Update saves the book's own columns; related records are saved through
+// their own repositories.
 func (r *bookRepository) Update(book *models.Book) error {
-	return r.db.Save(book).Error
+	return r.db.Omit(clause.Associations).Save(book).Error
 }
 
 func (r *bookRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Book{}, id).Error
 }
 
-func (r *bookRepository) Search(query string) ([]models.Book, error) {
+// GetByIDs returns the active books among ids, e.g. search hits.
+func (r *bookRepository) GetByIDs(ids []uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	db := r.db.Where("id IN ? AND is_active = ?", ids, true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+// GetByCategory matches the category label ignoring case.
+func (r *bookRepository) GetByCategory(category string, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	db := r.db.Where("LOWER(category) = LOWER(?) AND is_active = ?", category, true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+// GetByClassification returns the active books classified under a node or
+// anywhere below it.
+func (r *bookRepository) GetByClassification(classification *models.Classification, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	subtree := r.db.Model(&models.Classification{}).Select("id").Where("path LIKE ?", classification.Path+"%")
+	db := r.db.Where("classification_id IN (?) AND is_active = ?", subtree, true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+func (r *bookRepository) GetBySubject(subjectID uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	tagged := r.db.Model(&models.BookSubject{}).Select("book_id").Where("subject_id = ?", subjectID)
+	db := r.db.Where("id IN (?) AND is_active = ?", tagged, true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+// GetCatalog returns every active book with its contributors,
+// classification and subjects, e.g. for a full export.
+func (r *bookRepository) GetCatalog() ([]models.Book, error) {
 	var books []models.Book
-	err := r.db.Where("is_active = ? AND (title LIKE ? OR author LIKE ? OR isbn LIKE ? OR category LIKE ?)",
-		true, "%"+query+"%", "%"+query+"%", "%"+query+"%", "%"+query+"%").Find(&books).Error
+	err := r.db.Where("is_active = ?", true).
+		Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Contributors.Author").
+		Preload("Classification").
+		Preload("Subjects", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Subjects.Subject").
+		Order("id").
+		Find(&books).Error
 	return books, err
 }
 
-func (r *bookRepository) GetByCategory(category string) ([]models.Book, error) {
+// GetCatalogByIDs returns the active books among ids with everything
+// GetCatalog loads, in id order.
+func (r *bookRepository) GetCatalogByIDs(ids []uint) ([]models.Book, error) {
 	var books []models.Book
-	err := r.db.Where("category = ? AND is_active = ?", category, true).Find(&books).Error
+	err := r.db.Where("id IN ? AND is_active = ?", ids, true).
+		Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Contributors.Author").
+		Preload("Classification").
+		Preload("Subjects", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Subjects.Subject").
+		Order("id").
+		Find(&books).Error
 	return books, err
 }
 
-func (r *bookRepository) GetAvailableBooks() ([]models.Book, error) {
+func (r *bookRepository) GetActiveIDs() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Book{}).Where("is_active = ?", true).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetWithoutClassification returns up to limit books after afterID that
+// have a category but no classification.
+func (r *bookRepository) GetWithoutClassification(afterID uint, limit int) ([]models.Book, error) {
 	var books []models.Book
-	err := r.db.Where("available > 0 AND is_active = ?", true).Find(&books).Error
+	err := r.db.Preload("Contributors.Author").
+		Where("id > ? AND category <> '' AND classification_id IS NULL", afterID).
+		Order("id").Limit(limit).Find(&books).Error
 	return books, err
 }
 
-func (r *bookRepository) UpdateStock(id uint, stock, available int) error {
+// GetIDsByClassification returns the books, active or not, classified
+// directly under a node.
+func (r *bookRepository) GetIDsByClassification(classificationID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Book{}).Where("classification_id = ?", classificationID).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *bookRepository) UpdateClassification(id uint, classificationID *uint, category, callNumber string) error {
 	return r.db.Model(&models.Book{}).Where("id = ?", id).Updates(map[string]interface{}{
-		"stock":     stock,
-		"available": available,
+		"classification_id": classificationID,
+		"category":          category,
+		"call_number":       callNumber,
 	}).Error
 }
+
+func (r *bookRepository) GetAvailableBooks(spec *query.Spec) ([]models.Book, *query.Page, error) {
+	db := r.db.Where("available > 0 AND is_active = ?", true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+// GetByAuthor returns the active books an author is credited on in any role.
+func (r *bookRepository) GetByAuthor(authorID uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	credited := r.db.Model(&models.BookContributor{}).Select("book_id").Where("author_id = ?", authorID)
+	db := r.db.Where("id IN (?) AND is_active = ?", credited, true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+func (r *bookRepository) GetByPublisher(publisherID uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	db := r.db.Where("publisher_id = ? AND is_active = ?", publisherID, true)
+	return paginate[models.Book](db, spec, bookListOptions)
+}
+
+// GetWithoutContributors returns up to limit books after afterID that have
+// an author string but no linked contributors.
+func (r *bookRepository) GetWithoutContributors(afterID uint, limit int) ([]models.Book, error) {
+	var books []models.Book
+	credited := r.db.Model(&models.BookContributor{}).Select("book_id")
+	err := r.db.Where("id > ? AND author <> '' AND id NOT IN (?)", afterID, credited).
+		Order("id").Limit(limit).Find(&books).Error
+	return books, err
+}
+
+// GetWithoutPublisher returns up to limit books after afterID that have a
+// publisher string but no linked publisher.
+func (r *bookRepository) GetWithoutPublisher(afterID uint, limit int) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("id > ? AND publisher <> '' AND publisher_id IS NULL", afterID).
+		Order("id").Limit(limit).Find(&books).Error
+	return books, err
+}
+
+// UpdateCredits stores the display author and publisher of a book along
+// with its linked publisher.
+func (r *bookRepository) UpdateCredits(id uint, author, publisher string, publisherID *uint) error {
+	return r.db.Model(&models.Book{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"author":       author,
+		"publisher":    publisher,
+		"publisher_id": publisherID,
+	}).Error
+}
+
+// AddStock puts copies on the shelf relative to the current counts, so
+// loans and returns meanwhile are not overwritten. It leaves updated_at
+// alone: it marks catalog changes, which the search index snapshot is
+// checked against, and stock doesn't change the catalog.
+func (r *bookRepository) AddStock(id uint, quantity int) error {
+	result := r.db.Model(&models.Book{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"stock":     gorm.Expr("stock + ?", quantity),
+		"available": gorm.Expr("available + ?", quantity),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *bookRepository) UpdateLocation(id uint, location string) error {
+	return r.db.Model(&models.Book{}).Where("id = ?", id).Update("location", location).Error
+}
+
+// GetShelvedBetween returns the active print books with copies whose
+// location sorts from from to to, in shelf order.
+func (r *bookRepository) GetShelvedBetween(from, to string) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("is_active = ? AND item_type = ? AND stock > 0 AND location BETWEEN ? AND ?",
+		true, models.ItemPrint, from, to).
+		Order("location").Order("call_number").Order("id").
+		Find(&books).Error
+	return books, err
+}
+
+// GetShelvedByIDs returns the active books among ids in shelf order.
+func (r *bookRepository) GetShelvedByIDs(ids []uint) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("id IN ? AND is_active = ?", ids, true).
+		Order("location").Order("call_number").Order("id").
+		Find(&books).Error
+	return books, err
+}
+
+// GetShelvedByISBNs returns the active print books among the given
+// canonical ISBNs.
+func (r *bookRepository) GetShelvedByISBNs(codes []string) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("isbn IN ? AND is_active = ? AND item_type = ?", codes, true, models.ItemPrint).
+		Find(&books).Error
+	return books, err
+}
+
+// GetAvailableEditions returns the active editions of a work with a copy
+// on the shelf, oldest first.
+func (r *bookRepository) GetAvailableEditions(workID uint) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Where("work_id = ? AND is_active = ? AND available > 0", workID, true).
+		Order("publish_year, id").Find(&books).Error
+	return books, err
+}
+
+// GetWithoutWork returns up to limit books after afterID, including deleted
+// ones, that belong to no work yet.
+func (r *bookRepository) GetWithoutWork(afterID uint, limit int) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Unscoped().Preload("Contributors", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Contributors.Author").
+		Where("id > ? AND work_id IS NULL", afterID).
+		Order("id").Limit(limit).Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) UpdateWork(id uint, workID uint) error {
+	return r.db.Unscoped().Model(&models.Book{}).Where("id = ?", id).Update("work_id", workID).Error
+}
+
+// GetSummary loads a book's own columns without its relations.
+func (r *bookRepository) GetSummary(id uint) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.First(&book, id).Error; err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
+
+func (r *bookRepository) UpdateCover(id uint, updatedAt *time.Time) error {
+	return r.db.Model(&models.Book{}).Where("id = ?", id).Update("cover_updated_at", updatedAt).Error
+}
+
+// GetWithNonCanonicalISBN returns books, including deleted ones, whose ISBN
+// is not stored as 13 plain digits.
+func (r *bookRepository) GetWithNonCanonicalISBN() ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.Unscoped().Where("isbn NOT REGEXP ?", "^97[89][0-9]{10}$").Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) UpdateISBN(id uint, isbn string) error {
+	return r.db.Unscoped().Model(&models.Book{}).Where("id = ?", id).Update("isbn", isbn).Error
+}
+
+// Most values returned for a single text facet
+const maxFacetValues = 50
+
+// facetWork identifies the work a book is counted under in facets. The ids
+// are unsigned, so they are made signed before negating.
+const facetWork = "COALESCE(CAST(work_id AS SIGNED), -CAST(id AS SIGNED))"
+
+// Facets counts the active books among ids by category, language,
+// location, availability and publishing decade. Editions of one work count
+// once, as search lists them.
+func (r *bookRepository) Facets(ids []uint, spec *query.Spec) (*models.BookFacets, error) {
+	facets := &models.BookFacets{
+		Category:    []models.FacetCount{},
+		Language:    []models.FacetCount{},
+		Location:    []models.FacetCount{},
+		Available:   []models.FacetCount{},
+		PublishYear: []models.YearFacet{},
+	}
+	if len(ids) == 0 {
+		return facets, nil
+	}
+
+	var err error
+	if facets.Category, err = r.facetCounts(ids, spec, "category", "category"); err != nil {
+		return nil, err
+	}
+	if facets.Language, err = r.facetCounts(ids, spec, "language", "language"); err != nil {
+		return nil, err
+	}
+	if facets.Location, err = r.facetCounts(ids, spec, "location", "location"); err != nil {
+		return nil, err
+	}
+	if facets.Available, err = r.facetCounts(ids, spec,
+		"CASE WHEN available > 0 THEN 'true' ELSE 'false' END", "available"); err != nil {
+		return nil, err
+	}
+
+	decades, err := r.facetCounts(ids, spec, "FLOOR(publish_year / 10) * 10",
+		"publish_year_from", "publish_year_to")
+	if err != nil {
+		return nil, err
+	}
+	for _, decade := range decades {
+		from, err := strconv.Atoi(decade.Value)
+		if err != nil {
+			continue
+		}
+		facets.PublishYear = append(facets.PublishYear, models.YearFacet{
+			From:  from,
+			To:    from + 9,
+			Count: decade.Count,
+		})
+	}
+	sort.Slice(facets.PublishYear, func(i, j int) bool {
+		return facets.PublishYear[i].From < facets.PublishYear[j].From
+	})
+
+	return facets, nil
+}
+
+// facetCounts groups the matching books by expr, ignoring the filters the
+// facet itself controls, and counts the works among them. A book without a
+// work counts as its own, under its negated id.
+func (r *bookRepository) facetCounts(ids []uint, spec *query.Spec, expr string, ownFilters ...string) ([]models.FacetCount, error) {
+	db, err := applyFilters(r.db.Model(&models.Book{}).
+		Where("id IN ? AND is_active = ?", ids, true), spec, bookListOptions, ownFilters...)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := []models.FacetCount{}
+	err = db.Select(expr + " AS value, COUNT(DISTINCT " + facetWork + ") AS count").
+		Group("value").
+		Having("value IS NOT NULL AND value <> ''").
+		Order("count DESC, value ASC").
+		Limit(maxFacetValues).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// LastModified returns the latest time any book was created, changed or
+// deleted. Changes to copy counts don't count.
+func (r *bookRepository) LastModified() (time.Time, error) {
+	var result struct {
+		Updated *time.Time
+		Deleted *time.Time
+	}
+	err := r.db.Unscoped().Model(&models.Book{}).
+		Select("MAX(updated_at) AS updated, MAX(deleted_at) AS deleted").
+		Scan(&result).Error
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var last time.Time
+	if result.Updated != nil {
+		last = *result.Updated
+	}
+	if result.Deleted != nil && result.Deleted.After(last) {
+		last = *result.Deleted
+	}
+	return last, nil
+}
+
+// Columns MatchIDs and ScanTerms accept. "subject" is handled apart.
+var matchColumns = map[string]string{
+	"id":        "id",
+	"title":     "title",
+	"author":    "author",
+	"publisher": "publisher",
+	"isbn":      "isbn",
+	"language":  "language",
+	"category":  "category",
+	"year":      "publish_year",
+}
+
+var matchOperators = map[string]bool{
+	"like": true, "=": true, "<>": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// MatchIDs returns the active books, in id order, whose field compares to
+// value with op. The "like" op takes a LIKE pattern. A subject matches the
+// category or any subject heading of a book.
+func (r *bookRepository) MatchIDs(field, op, value string) ([]uint, error) {
+	if !matchOperators[op] {
+		return nil, fmt.Errorf("unsupported operator %q", op)
+	}
+	condition := " " + strings.ToUpper(op) + " ?"
+
+	db := r.db.Model(&models.Book{}).Where("is_active = ?", true)
+	if field == "subject" {
+		headings := r.db.Model(&models.BookSubject{}).Select("book_subjects.book_id").
+			Joins("JOIN subjects ON subjects.id = book_subjects.subject_id").
+			Where("subjects.heading"+condition, value)
+		db = db.Where("category"+condition+" OR id IN (?)", value, headings)
+	} else {
+		column, ok := matchColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("unsupported field %q", field)
+		}
+		db = db.Where(column+condition, value)
+	}
+
+	var ids []uint
+	err := db.Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ScanTerms lists up to limit distinct values of field from the first one
+// at or after from, with how many active books have each. Backwards lists
+// the values before from, nearest first.
+func (r *bookRepository) ScanTerms(field, from string, limit int, backwards bool) ([]models.FacetCount, error) {
+	condition, order := " >= ?", " ASC"
+	if backwards {
+		condition, order = " < ?", " DESC"
+	}
+
+	var db *gorm.DB
+	if field == "subject" {
+		db = r.db.Model(&models.Subject{}).Select("subjects.heading AS value, COUNT(*) AS count").
+			Joins("JOIN book_subjects ON book_subjects.subject_id = subjects.id").
+			Joins("JOIN books ON books.id = book_subjects.book_id AND books.is_active = ? AND books.deleted_at IS NULL", true).
+			Where("subjects.heading"+condition, from).
+			Group("subjects.heading").
+			Order("subjects.heading" + order)
+	} else {
+		column, ok := matchColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("unsupported field %q", field)
+		}
+		db = r.db.Model(&models.Book{}).Select(column+" AS value, COUNT(*) AS count").
+			Where("is_active = ? AND "+column+" <> ''", true).
+			Where(column+condition, from).
+			Group(column).
+			Order(column + order)
+	}
+
+	var terms []models.FacetCount
+	err := db.Limit(limit).Scan(&terms).Error
+	return terms, err
+}
@@ -1,24 +1,34 @@
 package repositories
 
 import (
+	"errors"
 	"time"
 
 	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"gorm.io/gorm"
 )
 
+// ErrNotBorrowed means a loan was returned meanwhile.
+var ErrNotBorrowed = errors.New("book is not currently borrowed")
+
 type BorrowRepository interface {
 	Create(borrow *models.Borrow) error
-	GetAll() ([]models.Borrow, error)
+	Lend(borrow *models.Borrow, takeCopy bool) error
+	Return(borrow *models.Borrow) error
+	GetAll(spec *query.Spec) ([]models.Borrow, *query.Page, error)
 	GetByID(id uint) (*models.Borrow, error)
-	GetByUserID(userID uint) ([]models.Borrow, error)
-	GetByBookID(bookID uint) ([]models.Borrow, error)
+	GetByUserID(userID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	GetByBookID(bookID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error)
 	Update(borrow *models.Borrow) error
 	Delete(id uint) error
-	GetActiveBorrows() ([]models.Borrow, error)
-	GetOverdueBorrows() ([]models.Borrow, error)
-	GetBorrowHistory(userID uint) ([]models.Borrow, error)
+	GetActiveBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	GetOverdueBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	GetExpiredDigitalLoans(now time.Time) ([]models.Borrow, error)
+	GetBorrowHistory(userID uint, openOnly bool, spec *query.Spec) ([]models.Borrow, *query.Page, error)
 	CheckActiveUserBorrow(userID, bookID uint) (*models.Borrow, error)
+	ClearNotesByUserID(userID uint) error
+	DetachReturnedBefore(cutoff time.Time) (int64, error)
 }
 
 type borrowRepository struct {
@@ -29,14 +39,108 @@ func NewBorrowRepository(db *gorm.DB) BorrowRepository {
 	return &borrowRepository{db: db}
 }
 
+var borrowListOptions = listOptions{
+	sorts: map[string]string{
+		"id":          "id",
+		"borrow_date": "borrow_date",
+		"due_date":    "due_date",
+		"status":      "status",
+		"fine":        "fine",
+		"created_at":  "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			statuses := query.Values(value)
+			// Overdue also covers loans past their due date that the
+			// overdue job hasn't marked yet
+			for _, status := range statuses {
+				if status == string(models.StatusOverdue) {
+					return db.Where("status IN ? OR (status = ? AND due_date < ?)",
+						statuses, models.StatusBorrowed, time.Now()), nil
+				}
+			}
+			return db.Where("status IN ?", statuses), nil
+		},
+		"user_id":         uintFilter("user_id"),
+		"book_id":         uintFilter("book_id"),
+		"due_before":      timeFilter("due_date", "<"),
+		"due_after":       timeFilter("due_date", ">="),
+		"borrowed_before": timeFilter("borrow_date", "<"),
+		"borrowed_after":  timeFilter("borrow_date", ">="),
+		"has_fine": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			if value == "true" {
+				return db.Where("fine > 0"), nil
+			}
+			return db.Where("fine = 0"), nil
+		},
+		"fine_paid": boolFilter("fine_paid"),
+		"category": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("book_id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+				Model(&models.Book{}).Select("id").Where("category IN ?", query.Values(value))), nil
+		},
+	},
+	defaultSort: []query.SortField{{Field: "id"}},
+	preloads:    []string{"User", "Book"},
+}
+
+var borrowHistoryOptions = func() listOptions {
+	opts := borrowListOptions
+	opts.defaultSort = []query.SortField{{Field: "created_at", Desc: true}}
+	return opts
+}()
+
 func (r *borrowRepository) Create(borrow *models.Borrow) error {
 	return r.db.Create(borrow).Error
 }
 
-func (r *borrowRepository) GetAll() ([]models.Borrow, error) {
-	var borrows []models.Borrow
-	err := r.db.Preload("User").Preload("Book").Find(&borrows).Error
-	return borrows, err
+// Lend records a loan and, when takeCopy is set, takes a copy of the book
+// off the shelf with it. It returns ErrNoCopy when no copy is left, e.g.
+// because a hold set the last one aside meanwhile.
+func (r *borrowRepository) Lend(borrow *models.Borrow, takeCopy bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if takeCopy {
+			result := tx.Model(&models.Book{}).
+				Where("id = ? AND available > 0", borrow.BookID).
+				UpdateColumn("available", gorm.Expr("available - 1"))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return ErrNoCopy
+			}
+		}
+		return tx.Create(borrow).Error
+	})
+}
+
+// Return closes a loan and puts its copy back on the shelf, unless every
+// copy already is, e.g. after copies were written off. It returns
+// ErrNotBorrowed when the loan was returned meanwhile.
+func (r *borrowRepository) Return(borrow *models.Borrow) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Borrow{}).
+			Where("id = ? AND status = ?", borrow.ID, models.StatusBorrowed).
+			Updates(map[string]interface{}{
+				"return_date": borrow.ReturnDate,
+				"status":      borrow.Status,
+				"fine":        borrow.Fine,
+				"notes":       borrow.Notes,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrNotBorrowed
+		}
+
+		return tx.Model(&models.Book{}).
+			Where("id = ? AND available < stock", borrow.BookID).
+			UpdateColumn("available", gorm.Expr("available + 1")).Error
+	})
+}
+
+func (r *borrowRepository) GetAll(spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return paginate[models.Borrow](r.db, spec, borrowListOptions)
 }
 
 func (r *borrowRepository) GetByID(id uint) (*models.Borrow, error) {
@@ -48,16 +152,12 @@ func (r *borrowRepository) GetByID(id uint) (*models.Borrow, error) {
 	return &borrow, nil
 }
 
-func (r *borrowRepository) GetByUserID(userID uint) ([]models.Borrow, error) {
-	var borrows []models.Borrow
-	err := r.db.Preload("User").Preload("Book").Where("user_id = ?", userID).Find(&borrows).Error
-	return borrows, err
+func (r *borrowRepository) GetByUserID(userID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return paginate[models.Borrow](r.db.Where("user_id = ?", userID), spec, borrowListOptions)
 }
 
-func (r *borrowRepository) GetByBookID(bookID uint) ([]models.Borrow, error) {
-	var borrows []models.Borrow
-	err := r.db.Preload("User").Preload("Book").Where("book_id = ?", bookID).Find(&borrows).Error
-	return borrows, err
+func (r *borrowRepository) GetByBookID(bookID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return paginate[models.Borrow](r.db.Where("book_id = ?", bookID), spec, borrowListOptions)
 }
 
 func (r *borrowRepository) Update(borrow *models.Borrow) error {
@@ -68,27 +168,45 @@ func (r *borrowRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Borrow{}, id).Error
 }
 
-func (r *borrowRepository) GetActiveBorrows() ([]models.Borrow, error) {
-	var borrows []models.Borrow
-	err := r.db.Preload("User").Preload("Book").Where("status = ?", models.StatusBorrowed).Find(&borrows).Error
-	return borrows, err
+func (r *borrowRepository) GetActiveBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	db := r.db.Where("status = ?", models.StatusBorrowed)
+	return paginate[models.Borrow](db, spec, borrowListOptions)
 }
 
-func (r *borrowRepository) GetOverdueBorrows() ([]models.Borrow, error) {
-	var borrows []models.Borrow
+// GetOverdueBorrows leaves out digital loans, which expire instead.
+func (r *borrowRepository) GetOverdueBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error) {
 	now := time.Now()
-	err := r.db.Preload("User").Preload("Book").Where("status = ? AND due_date < ?",
-		models.StatusBorrowed, now).Find(&borrows).Error
-	return borrows, err
+	db := r.db.Where("status = ? AND due_date < ?", models.StatusBorrowed, now).
+		Where("book_id NOT IN (?)", r.digitalBookIDs())
+	return paginate[models.Borrow](db, spec, borrowListOptions)
 }
 
-func (r *borrowRepository) GetBorrowHistory(userID uint) ([]models.Borrow, error) {
+// GetExpiredDigitalLoans returns open loans of digital items due before now.
+func (r *borrowRepository) GetExpiredDigitalLoans(now time.Time) ([]models.Borrow, error) {
 	var borrows []models.Borrow
-	err := r.db.Preload("User").Preload("Book").Where("user_id = ?", userID).
-		Order("created_at DESC").Find(&borrows).Error
+	err := r.db.Where("status = ? AND due_date < ?", models.StatusBorrowed, now).
+		Where("book_id IN (?)", r.digitalBookIDs()).
+		Order("due_date").
+		Find(&borrows).Error
 	return borrows, err
 }
 
+func (r *borrowRepository) digitalBookIDs() *gorm.DB {
+	return r.db.Session(&gorm.Session{NewDB: true}).Unscoped().
+		Model(&models.Book{}).Select("id").
+		Where("item_type IN ?", []models.ItemType{models.ItemEBook, models.ItemAudiobook})
+}
+
+// GetBorrowHistory returns a user's loans, newest first. With openOnly it
+// leaves out returned loans that have no unpaid fine.
+func (r *borrowRepository) GetBorrowHistory(userID uint, openOnly bool, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	db := r.db.Where("user_id = ?", userID)
+	if openOnly {
+		db = db.Where("status <> ? OR (fine > 0 AND fine_paid = ?)", models.StatusReturned, false)
+	}
+	return paginate[models.Borrow](db, spec, borrowHistoryOptions)
+}
+
 func (r *borrowRepository) CheckActiveUserBorrow(userID, bookID uint) (*models.Borrow, error) {
 	var borrow models.Borrow
 	err := r.db.Where("user_id = ? AND book_id = ? AND status = ?",
@@ -98,3 +216,33 @@ func (r *borrowRepository) CheckActiveUserBorrow(userID, bookID uint) (*models.B
 	}
 	return &borrow, nil
 }
+
+func (r *borrowRepository) ClearNotesByUserID(userID uint) error {
+	return r.db.Model(&models.Borrow{}).Where("user_id = ?", userID).Update("notes", "").Error
+}
+
+// DetachReturnedBefore unlinks returned loans from readers who chose not to
+// keep their reading history. Readers with unpaid fines are skipped so the
+// loans behind those fines stay traceable.
+func (r *borrowRepository) DetachReturnedBefore(cutoff time.Time) (int64, error) {
+	var unsettledUserIDs []uint
+	err := r.db.Model(&models.Borrow{}).Distinct("user_id").
+		Where("user_id IS NOT NULL AND fine > 0 AND fine_paid = ?", false).
+		Pluck("user_id", &unsettledUserIDs).Error
+	if err != nil {
+		return 0, err
+	}
+
+	query := r.db.Model(&models.Borrow{}).
+		Where("status = ? AND return_date < ?", models.StatusReturned, cutoff).
+		Where("user_id IN (?)", r.db.Model(&models.User{}).Select("id").Where("keep_reading_history = ?", false))
+	if len(unsettledUserIDs) > 0 {
+		query = query.Where("user_id NOT IN ?", unsettledUserIDs)
+	}
+
+	result := query.Updates(map[string]interface{}{
+		"user_id": nil,
+		"notes":   "",
+	})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,242 @@
+package repositories
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type ClassificationRepository interface {
+	Create(classification *models.Classification) error
+	GetAll(spec *query.Spec) ([]models.Classification, *query.Page, error)
+	GetByID(id uint) (*models.Classification, error)
+	GetByNotation(scheme, notation string) (*models.Classification, error)
+	GetByLabel(label string) (*models.Classification, error)
+	GetChildren(parentID uint) ([]models.Classification, error)
+	GetAncestors(classification *models.Classification) ([]models.Classification, error)
+	CountScheme(scheme string) (int64, error)
+	HasChildren(id uint) (bool, error)
+	Update(classification *models.Classification) error
+	Move(classification *models.Classification, parent *models.Classification) error
+	Delete(id uint) error
+}
+
+type classificationRepository struct {
+	db *gorm.DB
+}
+
+func NewClassificationRepository(db *gorm.DB) ClassificationRepository {
+	return &classificationRepository{db: db}
+}
+
+var classificationListOptions = listOptions{
+	sorts: map[string]string{
+		"id":       "id",
+		"notation": "notation",
+		"label":    "label",
+	},
+	filters: map[string]filterFunc{
+		"scheme":    equalsFilter("scheme"),
+		"label":     likeFilter("label"),
+		"parent_id": uintFilter("parent_id"),
+		"root": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			if value == "true" {
+				return db.Where("parent_id IS NULL"), nil
+			}
+			return db.Where("parent_id IS NOT NULL"), nil
+		},
+	},
+	defaultSort: []query.SortField{{Field: "notation"}},
+}
+
+// Create stores a node under its parent, or as a root when ParentID is
+// nil, and fills in its path and depth.
+func (r *classificationRepository) Create(classification *models.Classification) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		prefix := "/"
+		classification.Depth = 0
+		if classification.ParentID != nil {
+			var parent models.Classification
+			if err := tx.First(&parent, *classification.ParentID).Error; err != nil {
+				return err
+			}
+			prefix = parent.Path
+			classification.Depth = parent.Depth + 1
+		}
+
+		if err := tx.Create(classification).Error; err != nil {
+			return err
+		}
+		classification.Path = prefix + strconv.FormatUint(uint64(classification.ID), 10) + "/"
+		return tx.Model(classification).Update("path", classification.Path).Error
+	})
+}
+
+// GetAll lists classification nodes with the number of active books in
+// each node's subtree.
+func (r *classificationRepository) GetAll(spec *query.Spec) ([]models.Classification, *query.Page, error) {
+	classifications, page, err := paginate[models.Classification](r.db, spec, classificationListOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := r.fillBookCounts(classifications); err != nil {
+		return nil, nil, err
+	}
+	return classifications, page, nil
+}
+
+func (r *classificationRepository) GetByID(id uint) (*models.Classification, error) {
+	var classification models.Classification
+	if err := r.db.First(&classification, id).Error; err != nil {
+		return nil, err
+	}
+	nodes := []models.Classification{classification}
+	if err := r.fillBookCounts(nodes); err != nil {
+		return nil, err
+	}
+	return &nodes[0], nil
+}
+
+func (r *classificationRepository) GetByNotation(scheme, notation string) (*models.Classification, error) {
+	var classification models.Classification
+	err := r.db.Where("scheme = ? AND notation = ?", scheme, notation).First(&classification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &classification, nil
+}
+
+// GetByLabel finds a node by its label, ignoring case. Published schemes
+// win over custom ones, and broader nodes over narrower ones.
+func (r *classificationRepository) GetByLabel(label string) (*models.Classification, error) {
+	var classification models.Classification
+	err := r.db.Where("LOWER(label) = ?", strings.ToLower(strings.TrimSpace(label))).
+		Order("scheme = '" + models.SchemeCustom + "' ASC").
+		Order("depth ASC, id ASC").
+		First(&classification).Error
+	if err != nil {
+		return nil, err
+	}
+	return &classification, nil
+}
+
+func (r *classificationRepository) GetChildren(parentID uint) ([]models.Classification, error) {
+	var children []models.Classification
+	err := r.db.Where("parent_id = ?", parentID).Order("notation ASC, id ASC").Find(&children).Error
+	if err != nil {
+		return nil, err
+	}
+	return children, r.fillBookCounts(children)
+}
+
+// GetAncestors returns the nodes above classification, root first.
+func (r *classificationRepository) GetAncestors(classification *models.Classification) ([]models.Classification, error) {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(classification.Path, "/"), "/") {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err == nil && uint(id) != classification.ID {
+			ids = append(ids, uint(id))
+		}
+	}
+
+	ancestors := []models.Classification{}
+	if len(ids) == 0 {
+		return ancestors, nil
+	}
+	err := r.db.Where("id IN ?", ids).Order("depth ASC").Find(&ancestors).Error
+	return ancestors, err
+}
+
+func (r *classificationRepository) CountScheme(scheme string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Classification{}).Where("scheme = ?", scheme).Count(&count).Error
+	return count, err
+}
+
+func (r *classificationRepository) HasChildren(id uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Classification{}).Where("parent_id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *classificationRepository) Update(classification *models.Classification) error {
+	return r.db.Omit("Ancestors", "Children").Save(classification).Error
+}
+
+// Move puts classification and its subtree under parent, or at the root
+// when parent is nil.
+func (r *classificationRepository) Move(classification *models.Classification, parent *models.Classification) error {
+	oldPath := classification.Path
+	newPath := "/" + strconv.FormatUint(uint64(classification.ID), 10) + "/"
+	depth := 0
+	var parentID *uint
+	if parent != nil {
+		newPath = parent.Path + strconv.FormatUint(uint64(classification.ID), 10) + "/"
+		depth = parent.Depth + 1
+		parentID = &parent.ID
+	}
+	delta := depth - classification.Depth
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Classification{}).Where("path LIKE ?", oldPath+"%").
+			Updates(map[string]interface{}{
+				"path":  gorm.Expr("CONCAT(?, SUBSTRING(path, ?))", newPath, len(oldPath)+1),
+				"depth": gorm.Expr("depth + ?", delta),
+			}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Classification{}).Where("id = ?", classification.ID).
+			Update("parent_id", parentID).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	classification.Path = newPath
+	classification.Depth = depth
+	classification.ParentID = parentID
+	return nil
+}
+
+func (r *classificationRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Classification{}, id).Error
+}
+
+// fillBookCounts counts the active books classified anywhere in each
+// node's subtree.
+func (r *classificationRepository) fillBookCounts(classifications []models.Classification) error {
+	if len(classifications) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(classifications))
+	for i := range classifications {
+		ids[i] = classifications[i].ID
+	}
+
+	var rows []struct {
+		ID    uint
+		Count int64
+	}
+	err := r.db.Table("classifications AS node").
+		Select("node.id, COUNT(books.id) AS count").
+		Joins("JOIN classifications AS sub ON sub.path LIKE CONCAT(node.path, '%')").
+		Joins("JOIN books ON books.classification_id = sub.id AND books.is_active = ? AND books.deleted_at IS NULL", true).
+		Where("node.id IN ?", ids).
+		Group("node.id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	for i := range classifications {
+		classifications[i].BookCount = counts[classifications[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	"github.com/yooerizkilab/library-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type DigitalFileRepository interface {
+	Create(file *models.DigitalFile) error
+	GetByID(id uint) (*models.DigitalFile, error)
+	GetByBookID(bookID uint) ([]models.DigitalFile, error)
+	Delete(id uint) error
+}
+
+type digitalFileRepository struct {
+	db *gorm.DB
+}
+
+func NewDigitalFileRepository(db *gorm.DB) DigitalFileRepository {
+	return &digitalFileRepository{db: db}
+}
+
+func (r *digitalFileRepository) Create(file *models.DigitalFile) error {
+	return r.db.Create(file).Error
+}
+
+func (r *digitalFileRepository) GetByID(id uint) (*models.DigitalFile, error) {
+	var file models.DigitalFile
+	if err := r.db.First(&file, id).Error; err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *digitalFileRepository) GetByBookID(bookID uint) ([]models.DigitalFile, error) {
+	var files []models.DigitalFile
+	err := r.db.Where("book_id = ?", bookID).Order("id").Find(&files).Error
+	return files, err
+}
+
+func (r *digitalFileRepository) Delete(id uint) error {
+	return r.db.Delete(&models.DigitalFile{}, id).Error
+}
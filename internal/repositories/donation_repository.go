@@ -0,0 +1,166 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// ErrItemDecided means a donation item was decided meanwhile.
+var ErrItemDecided = errors.New("donation item is already decided")
+
+type DonationRepository interface {
+	Create(donation *models.Donation) error
+	GetAll(spec *query.Spec) ([]models.Donation, *query.Page, error)
+	GetByID(id uint) (*models.Donation, error)
+	Update(donation *models.Donation) error
+	AddItems(donationID uint, items []models.DonationItem) error
+	UpdateItem(item *models.DonationItem) error
+	DecideItem(item *models.DonationItem) error
+	Acknowledge(donation *models.Donation) error
+	GetReceivedBetween(from, to time.Time) ([]models.Donation, error)
+	GetItemsByBook(bookID uint) ([]models.DonationItem, error)
+}
+
+type donationRepository struct {
+	db *gorm.DB
+}
+
+func NewDonationRepository(db *gorm.DB) DonationRepository {
+	return &donationRepository{db: db}
+}
+
+var donationListOptions = listOptions{
+	sorts: map[string]string{
+		"id":          "id",
+		"number":      "number",
+		"received_at": "received_at",
+		"created_at":  "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"donor_id": uintFilter("donor_id"),
+		"number":   equalsFilter("number"),
+		"isbn": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			items := db.Session(&gorm.Session{NewDB: true}).
+				Model(&models.DonationItem{}).Select("donation_id").Where("isbn = ?", value)
+			return db.Where("id IN (?)", items), nil
+		},
+		// Donations that have had their letter, or still need one
+		"acknowledged": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			acknowledged, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: acknowledged must be true or false", query.ErrInvalid)
+			}
+			if acknowledged {
+				return db.Where("acknowledged_at IS NOT NULL"), nil
+			}
+			return db.Where("acknowledged_at IS NULL"), nil
+		},
+		"received_after":  timeFilter("received_at", ">="),
+		"received_before": timeFilter("received_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+	preloads:    []string{"Donor"},
+}
+
+// Create saves a donation with its items and numbers it DON-<year>-<id>.
+func (r *donationRepository) Create(donation *models.Donation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Donor", "Items.Book", "Items.Donation").Create(donation).Error; err != nil {
+			return err
+		}
+		donation.Number = fmt.Sprintf("DON-%d-%05d", donation.CreatedAt.Year(), donation.ID)
+		return tx.Model(donation).Update("number", donation.Number).Error
+	})
+}
+
+func (r *donationRepository) GetAll(spec *query.Spec) ([]models.Donation, *query.Page, error) {
+	return paginate[models.Donation](r.db, spec, donationListOptions)
+}
+
+func (r *donationRepository) GetByID(id uint) (*models.Donation, error) {
+	var donation models.Donation
+	err := r.db.Preload("Donor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Items.Book").
+		First(&donation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &donation, nil
+}
+
+// Update saves the donation itself; items are saved separately.
+func (r *donationRepository) Update(donation *models.Donation) error {
+	return r.db.Omit("Donor", "Items").Save(donation).Error
+}
+
+func (r *donationRepository) AddItems(donationID uint, items []models.DonationItem) error {
+	for i := range items {
+		items[i].DonationID = donationID
+	}
+	return r.db.Omit("Book", "Donation").Create(&items).Error
+}
+
+func (r *donationRepository) UpdateItem(item *models.DonationItem) error {
+	return r.db.Omit("Book", "Donation").Save(item).Error
+}
+
+// DecideItem saves the decision on a pending item. It returns
+// ErrItemDecided when the item was decided meanwhile, so only one decision
+// adds its copies.
+func (r *donationRepository) DecideItem(item *models.DonationItem) error {
+	result := r.db.Model(&models.DonationItem{}).
+		Where("id = ? AND decision = ?", item.ID, models.ItemPending).
+		Updates(map[string]interface{}{
+			"decision":   item.Decision,
+			"reason":     item.Reason,
+			"decided_by": item.DecidedBy,
+			"decided_at": item.DecidedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrItemDecided
+	}
+	return nil
+}
+
+// Acknowledge records when a donation was first acknowledged; later calls
+// keep that time.
+func (r *donationRepository) Acknowledge(donation *models.Donation) error {
+	return r.db.Model(&models.Donation{}).
+		Where("id = ? AND acknowledged_at IS NULL", donation.ID).
+		Update("acknowledged_at", donation.AcknowledgedAt).Error
+}
+
+// GetReceivedBetween returns the donations received in [from, to) with
+// their donors and items.
+func (r *donationRepository) GetReceivedBetween(from, to time.Time) ([]models.Donation, error) {
+	var donations []models.Donation
+	err := r.db.Preload("Donor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Items").
+		Where("received_at >= ? AND received_at < ?", from, to).
+		Order("received_at, id").Find(&donations).Error
+	return donations, err
+}
+
+// GetItemsByBook returns the accepted items that added copies to a book,
+// oldest first, with their donations and donors.
+func (r *donationRepository) GetItemsByBook(bookID uint) ([]models.DonationItem, error) {
+	var items []models.DonationItem
+	err := r.db.Preload("Donation").
+		Preload("Donation.Donor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("book_id = ? AND decision = ?", bookID, models.ItemAccepted).
+		Order("decided_at, id").Find(&items).Error
+	return items, err
+}
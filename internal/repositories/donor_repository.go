@@ -0,0 +1,55 @@
+package repositories
+
+import (
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type DonorRepository interface {
+	Create(donor *models.Donor) error
+	GetAll(spec *query.Spec) ([]models.Donor, *query.Page, error)
+	GetByID(id uint) (*models.Donor, error)
+	Update(donor *models.Donor) error
+}
+
+type donorRepository struct {
+	db *gorm.DB
+}
+
+func NewDonorRepository(db *gorm.DB) DonorRepository {
+	return &donorRepository{db: db}
+}
+
+var donorListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name":  likeFilter("name"),
+		"email": equalsFilter("email"),
+	},
+	defaultSort: []query.SortField{{Field: "name"}},
+}
+
+func (r *donorRepository) Create(donor *models.Donor) error {
+	return r.db.Create(donor).Error
+}
+
+func (r *donorRepository) GetAll(spec *query.Spec) ([]models.Donor, *query.Page, error) {
+	return paginate[models.Donor](r.db, spec, donorListOptions)
+}
+
+func (r *donorRepository) GetByID(id uint) (*models.Donor, error) {
+	var donor models.Donor
+	if err := r.db.First(&donor, id).Error; err != nil {
+		return nil, err
+	}
+	return &donor, nil
+}
+
+func (r *donorRepository) Update(donor *models.Donor) error {
+	return r.db.Save(donor).Error
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// Failed requests are still open: they are retried until they complete or
+// are cancelled.
+var openErasureStatuses = []models.ErasureStatus{models.ErasurePending, models.ErasureFailed}
+
+type ErasureRepository interface {
+	Create(request *models.ErasureRequest) error
+	GetAll(spec *query.Spec) ([]models.ErasureRequest, *query.Page, error)
+	GetByID(id uint) (*models.ErasureRequest, error)
+	GetPendingByUserID(userID uint) (*models.ErasureRequest, error)
+	GetDue(now time.Time) ([]models.ErasureRequest, error)
+	Update(request *models.ErasureRequest) error
+}
+
+type erasureRepository struct {
+	db *gorm.DB
+}
+
+func NewErasureRepository(db *gorm.DB) ErasureRepository {
+	return &erasureRepository{db: db}
+}
+
+var erasureListOptions = listOptions{
+	sorts: map[string]string{
+		"id":            "id",
+		"scheduled_for": "scheduled_for",
+		"created_at":    "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status":  equalsFilter("status"),
+		"user_id": uintFilter("user_id"),
+	},
+	defaultSort: []query.SortField{{Field: "created_at", Desc: true}},
+}
+
+func (r *erasureRepository) Create(request *models.ErasureRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *erasureRepository) GetAll(spec *query.Spec) ([]models.ErasureRequest, *query.Page, error) {
+	return paginate[models.ErasureRequest](r.db, spec, erasureListOptions)
+}
+
+func (r *erasureRepository) GetByID(id uint) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	err := r.db.First(&request, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *erasureRepository) GetPendingByUserID(userID uint) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	err := r.db.Where("user_id = ? AND status IN ?", userID, openErasureStatuses).First(&request).Error
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *erasureRepository) GetDue(now time.Time) ([]models.ErasureRequest, error) {
+	var requests []models.ErasureRequest
+	err := r.db.Where("status IN ? AND scheduled_for <= ?", openErasureStatuses, now).
+		Order("scheduled_for ASC").Find(&requests).Error
+	return requests, err
+}
+
+func (r *erasureRepository) Update(request *models.ErasureRequest) error {
+	return r.db.Save(request).Error
+}
@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBudgetExceeded means a budget is closed or cannot cover an
+	// encumbrance.
+	ErrBudgetExceeded = errors.New("budget cannot cover the encumbrance")
+	// ErrBudgetClosed means a budget was closed meanwhile.
+	ErrBudgetClosed = errors.New("budget is already closed")
+)
+
+type FundRepository interface {
+	Create(fund *models.Fund) error
+	GetAll(spec *query.Spec) ([]models.Fund, *query.Page, error)
+	GetByID(id uint) (*models.Fund, error)
+	GetByCode(code string) (*models.Fund, error)
+	Update(fund *models.Fund) error
+	GetBudget(fundID uint, fiscalYear int) (*models.Budget, error)
+	GetBudgetByID(id uint) (*models.Budget, error)
+	GetBudgetsByYear(fiscalYear int) ([]models.Budget, error)
+	CreateBudget(budget *models.Budget) error
+	Rollover(budget *models.Budget, next *models.Budget, lines []models.OrderLine, entries []models.FundTransaction) error
+	Post(entries []models.FundTransaction) error
+	GetTransactions(fundID uint, spec *query.Spec) ([]models.FundTransaction, *query.Page, error)
+}
+
+type fundRepository struct {
+	db *gorm.DB
+}
+
+func NewFundRepository(db *gorm.DB) FundRepository {
+	return &fundRepository{db: db}
+}
+
+var fundListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"code":       "code",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name":      likeFilter("name"),
+		"code":      equalsFilter("code"),
+		"is_active": boolFilter("is_active"),
+	},
+	defaultSort: []query.SortField{{Field: "code"}},
+}
+
+var fundTransactionListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"amount":     "amount",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"fiscal_year": intRangeFilter("fiscal_year", "="),
+		"type": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("type IN ?", query.Values(value)), nil
+		},
+		"order_id":       uintFilter("order_id"),
+		"invoice_id":     uintFilter("invoice_id"),
+		"created_after":  timeFilter("created_at", ">="),
+		"created_before": timeFilter("created_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+}
+
+// Budget total each kind of transaction adds to; a release takes its
+// amount off the encumbrance
+var fundTransactionColumns = map[models.FundTransactionType]string{
+	models.FundAllocation:  "allocated",
+	models.FundEncumbrance: "encumbered",
+	models.FundRelease:     "encumbered",
+	models.FundExpenditure: "spent",
+	models.FundCarryIn:     "carried_in",
+	models.FundCarryOut:    "carried_out",
+}
+
+func (r *fundRepository) Create(fund *models.Fund) error {
+	return r.db.Omit("Budgets").Create(fund).Error
+}
+
+func (r *fundRepository) GetAll(spec *query.Spec) ([]models.Fund, *query.Page, error) {
+	return paginate[models.Fund](r.db, spec, fundListOptions)
+}
+
+// GetByID returns a fund with its budgets, latest year first.
+func (r *fundRepository) GetByID(id uint) (*models.Fund, error) {
+	var fund models.Fund
+	err := r.db.Preload("Budgets", func(db *gorm.DB) *gorm.DB { return db.Order("fiscal_year DESC") }).
+		First(&fund, id).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range fund.Budgets {
+		fund.Budgets[i].Available = fund.Budgets[i].Free()
+	}
+	return &fund, nil
+}
+
+func (r *fundRepository) GetByCode(code string) (*models.Fund, error) {
+	var fund models.Fund
+	if err := r.db.Where("code = ?", code).First(&fund).Error; err != nil {
+		return nil, err
+	}
+	return &fund, nil
+}
+
+func (r *fundRepository) Update(fund *models.Fund) error {
+	return r.db.Omit("Budgets").Save(fund).Error
+}
+
+func (r *fundRepository) GetBudget(fundID uint, fiscalYear int) (*models.Budget, error) {
+	var budget models.Budget
+	err := r.db.Where("fund_id = ? AND fiscal_year = ?", fundID, fiscalYear).First(&budget).Error
+	if err != nil {
+		return nil, err
+	}
+	budget.Available = budget.Free()
+	return &budget, nil
+}
+
+func (r *fundRepository) GetBudgetByID(id uint) (*models.Budget, error) {
+	var budget models.Budget
+	if err := r.db.First(&budget, id).Error; err != nil {
+		return nil, err
+	}
+	budget.Available = budget.Free()
+	return &budget, nil
+}
+
+// GetBudgetsByYear returns every budget of a fiscal year with its fund.
+func (r *fundRepository) GetBudgetsByYear(fiscalYear int) ([]models.Budget, error) {
+	var budgets []models.Budget
+	err := r.db.Preload("Fund", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where("fiscal_year = ?", fiscalYear).Order("fund_id").Find(&budgets).Error
+	for i := range budgets {
+		budgets[i].Available = budgets[i].Free()
+	}
+	return budgets, err
+}
+
+func (r *fundRepository) CreateBudget(budget *models.Budget) error {
+	return r.db.Omit("Fund").Create(budget).Error
+}
+
+// Rollover closes a budget, moves its open order lines to the next one
+// and posts the entries carrying money over, all or nothing. It returns
+// ErrBudgetClosed when the budget was rolled over meanwhile and
+// ErrLineChanged when a line was received, invoiced or cancelled since
+// it was read.
+func (r *fundRepository) Rollover(budget *models.Budget, next *models.Budget, lines []models.OrderLine, entries []models.FundTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Budget{}).Where("id = ? AND closed = ?", budget.ID, false).Update("closed", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrBudgetClosed
+		}
+
+		for i := range lines {
+			line := &lines[i]
+			result := tx.Model(&models.OrderLine{}).
+				Where("id = ? AND budget_id = ? AND encumbered = CAST(? AS DECIMAL(14,2))", line.ID, budget.ID, line.Encumbered).
+				Update("budget_id", next.ID)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return ErrLineChanged
+			}
+			line.BudgetID = &next.ID
+		}
+
+		if err := postFundEntries(tx, entries); err != nil {
+			return err
+		}
+		budget.Closed = true
+		return nil
+	})
+}
+
+// Post records ledger entries and moves their budgets' totals, all or
+// nothing.
+func (r *fundRepository) Post(entries []models.FundTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return postFundEntries(tx, entries)
+	})
+}
+
+// postFundEntries posts ledger entries within tx. An encumbrance only goes
+// through while its budget is open and has the money free; otherwise it
+// returns ErrBudgetExceeded.
+func postFundEntries(tx *gorm.DB, entries []models.FundTransaction) error {
+	for i := range entries {
+		entry := &entries[i]
+		column, ok := fundTransactionColumns[entry.Type]
+		if !ok {
+			return fmt.Errorf("unknown fund transaction type %q", entry.Type)
+		}
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+		amount := entry.Amount
+		if entry.Type == models.FundRelease {
+			amount = -amount
+		}
+
+		update := tx.Model(&models.Budget{}).Where("id = ?", entry.BudgetID)
+		if entry.Type == models.FundEncumbrance {
+			update = update.Where("closed = ? AND encumbered + CAST(? AS DECIMAL(14,2)) <= allocated + carried_in - carried_out - spent", false, amount)
+		}
+		result := update.Update(column, gorm.Expr(column+" + ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if entry.Type == models.FundEncumbrance && result.RowsAffected != 1 {
+			return ErrBudgetExceeded
+		}
+	}
+	return nil
+}
+
+func (r *fundRepository) GetTransactions(fundID uint, spec *query.Spec) ([]models.FundTransaction, *query.Page, error) {
+	return paginate[models.FundTransaction](r.db.Where("fund_id = ?", fundID), spec, fundTransactionListOptions)
+}
@@ -0,0 +1,211 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// ErrNoCopy is returned by Assign when the book has no copy left to set
+// aside.
+var ErrNoCopy = errors.New("no copy available")
+
+type HoldRepository interface {
+	Create(hold *models.Hold) error
+	GetAll(spec *query.Spec) ([]models.Hold, *query.Page, error)
+	GetByID(id uint) (*models.Hold, error)
+	GetByUserID(userID uint, spec *query.Spec) ([]models.Hold, *query.Page, error)
+	GetActive(userID, workID uint) (*models.Hold, error)
+	GetActiveByUserID(userID uint) ([]models.Hold, error)
+	GetReady(userID, bookID uint) (*models.Hold, error)
+	GetWaiting() ([]models.Hold, error)
+	NextWaiting(book *models.Book) (*models.Hold, error)
+	GetExpired(now time.Time) ([]models.Hold, error)
+	Update(hold *models.Hold) error
+	Assign(hold *models.Hold, bookID uint, expiresAt time.Time) error
+	Release(hold *models.Hold) error
+}
+
+type holdRepository struct {
+	db *gorm.DB
+}
+
+func NewHoldRepository(db *gorm.DB) HoldRepository {
+	return &holdRepository{db: db}
+}
+
+// Holds that still wait for or keep a copy
+var activeHoldStatuses = []models.HoldStatus{models.HoldWaiting, models.HoldReady}
+
+var holdListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"status":     "status",
+		"expires_at": "expires_at",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"user_id":          uintFilter("user_id"),
+		"work_id":          uintFilter("work_id"),
+		"book_id":          uintFilter("book_id"),
+		"assigned_book_id": uintFilter("assigned_book_id"),
+		"any_edition": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			if value == "true" {
+				return db.Where("book_id IS NULL"), nil
+			}
+			return db.Where("book_id IS NOT NULL"), nil
+		},
+		"created_after":  timeFilter("created_at", ">="),
+		"created_before": timeFilter("created_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "id"}},
+	preloads:    []string{"User", "Work", "Book", "AssignedBook"},
+}
+
+func (r *holdRepository) Create(hold *models.Hold) error {
+	return r.db.Omit("User", "Work", "Book", "AssignedBook").Create(hold).Error
+}
+
+func (r *holdRepository) GetAll(spec *query.Spec) ([]models.Hold, *query.Page, error) {
+	return paginate[models.Hold](r.db, spec, holdListOptions)
+}
+
+func (r *holdRepository) GetByID(id uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.db.Preload("User").Preload("Work").Preload("Book").Preload("AssignedBook").
+		First(&hold, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *holdRepository) GetByUserID(userID uint, spec *query.Spec) ([]models.Hold, *query.Page, error) {
+	return paginate[models.Hold](r.db.Where("user_id = ?", userID), spec, holdListOptions)
+}
+
+// GetActive finds a user's waiting or ready hold on any edition of a work.
+func (r *holdRepository) GetActive(userID, workID uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.db.Where("user_id = ? AND work_id = ? AND status IN ?", userID, workID, activeHoldStatuses).
+		First(&hold).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *holdRepository) GetActiveByUserID(userID uint) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("user_id = ? AND status IN ?", userID, activeHoldStatuses).
+		Order("id").Find(&holds).Error
+	return holds, err
+}
+
+// GetReady finds a user's hold with a copy of a book set aside.
+func (r *holdRepository) GetReady(userID, bookID uint) (*models.Hold, error) {
+	var hold models.Hold
+	err := r.db.Where("user_id = ? AND assigned_book_id = ? AND status = ?", userID, bookID, models.HoldReady).
+		First(&hold).Error
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// GetWaiting returns every waiting hold, oldest first.
+func (r *holdRepository) GetWaiting() ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("status = ?", models.HoldWaiting).Order("created_at, id").Find(&holds).Error
+	return holds, err
+}
+
+// NextWaiting finds the oldest waiting hold a copy of book can satisfy:
+// one on that edition, or one on any edition of its work.
+func (r *holdRepository) NextWaiting(book *models.Book) (*models.Hold, error) {
+	db := r.db.Where("status = ?", models.HoldWaiting)
+	if book.WorkID != nil {
+		db = db.Where("book_id = ? OR (book_id IS NULL AND work_id = ?)", book.ID, *book.WorkID)
+	} else {
+		db = db.Where("book_id = ?", book.ID)
+	}
+
+	var hold models.Hold
+	if err := db.Order("created_at, id").First(&hold).Error; err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// GetExpired returns ready holds whose pickup period ended before now.
+func (r *holdRepository) GetExpired(now time.Time) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.Where("status = ? AND expires_at < ?", models.HoldReady, now).
+		Order("id").Find(&holds).Error
+	return holds, err
+}
+
+func (r *holdRepository) Update(hold *models.Hold) error {
+	return r.db.Omit("User", "Work", "Book", "AssignedBook").Save(hold).Error
+}
+
+// Assign takes a copy of a book off the shelf for a waiting hold and marks
+// the hold ready until expiresAt. It returns ErrNoCopy when the last copy
+// has just gone.
+func (r *holdRepository) Assign(hold *models.Hold, bookID uint, expiresAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Book{}).
+			Where("id = ? AND available > 0", bookID).
+			UpdateColumn("available", gorm.Expr("available - 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNoCopy
+		}
+
+		now := time.Now()
+		result = tx.Model(&models.Hold{}).
+			Where("id = ? AND status = ?", hold.ID, models.HoldWaiting).
+			Updates(map[string]interface{}{
+				"status":           models.HoldReady,
+				"assigned_book_id": bookID,
+				"ready_at":         now,
+				"expires_at":       expiresAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("hold is no longer waiting")
+		}
+
+		hold.Status = models.HoldReady
+		hold.AssignedBookID = &bookID
+		hold.ReadyAt = &now
+		hold.ExpiresAt = &expiresAt
+		return nil
+	})
+}
+
+// Release saves a hold that is closing and puts the copy that was set aside
+// for it back on the shelf.
+func (r *holdRepository) Release(hold *models.Hold) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if hold.AssignedBookID != nil {
+			err := tx.Model(&models.Book{}).
+				Where("id = ? AND available < stock", *hold.AssignedBookID).
+				UpdateColumn("available", gorm.Expr("available + 1")).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Omit("User", "Work", "Book", "AssignedBook").Save(hold).Error
+	})
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// ErrNotDryRun means an import is not, or no longer, a completed dry run.
+var ErrNotDryRun = errors.New("import is not a completed dry run")
+
+type ImportRepository interface {
+	Create(job *models.ImportJob) error
+	GetAll(spec *query.Spec) ([]models.ImportJob, *query.Page, error)
+	GetByID(id uint) (*models.ImportJob, error)
+	Update(job *models.ImportJob) error
+	Commit(job *models.ImportJob) error
+	UpdateProgress(job *models.ImportJob) error
+	FailInterrupted() error
+}
+
+type importRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &importRepository{db: db}
+}
+
+var importListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status":     equalsFilter("status"),
+		"created_by": uintFilter("created_by"),
+		"dry_run":    boolFilter("dry_run"),
+	},
+	defaultSort: []query.SortField{{Field: "created_at", Desc: true}},
+}
+
+func (r *importRepository) Create(job *models.ImportJob) error {
+	return r.db.Create(job).Error
+}
+
+// GetAll lists jobs without their file and row report.
+func (r *importRepository) GetAll(spec *query.Spec) ([]models.ImportJob, *query.Page, error) {
+	return paginate[models.ImportJob](r.db.Omit("data", "report"), spec, importListOptions)
+}
+
+func (r *importRepository) GetByID(id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := r.db.First(&job, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *importRepository) Update(job *models.ImportJob) error {
+	return r.db.Save(job).Error
+}
+
+// Commit saves a completed dry run reset for its real run. It returns
+// ErrNotDryRun when the job was committed meanwhile.
+func (r *importRepository) Commit(job *models.ImportJob) error {
+	result := r.db.Model(job).
+		Where("dry_run = ? AND status = ?", true, models.ImportCompleted).
+		Select(
+			"dry_run", "status", "report", "started_at", "finished_at", "error",
+			"processed_rows", "created", "merged", "skipped", "invalid", "failed",
+		).Updates(job)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrNotDryRun
+	}
+	return nil
+}
+
+// UpdateProgress saves the counters of a running job.
+func (r *importRepository) UpdateProgress(job *models.ImportJob) error {
+	return r.db.Model(job).Select(
+		"processed_rows", "created", "merged", "skipped", "invalid", "failed",
+	).Updates(job).Error
+}
+
+// FailInterrupted marks jobs that were running when the server stopped.
+func (r *importRepository) FailInterrupted() error {
+	return r.db.Model(&models.ImportJob{}).
+		Where("status IN ?", []models.ImportStatus{models.ImportPending, models.ImportRunning}).
+		Updates(map[string]interface{}{
+			"status": models.ImportFailed,
+			"error":  "interrupted by server restart",
+		}).Error
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	Create(notification *models.Notification) error
+	GetByID(id uint) (*models.Notification, error)
+	GetByUserID(userID uint, spec *query.Spec) ([]models.Notification, *query.Page, error)
+	MarkRead(id uint, at time.Time) error
+	MarkAllRead(userID uint, at time.Time) (int64, error)
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+var notificationListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"unread": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			if value == "true" {
+				return db.Where("read_at IS NULL"), nil
+			}
+			return db.Where("read_at IS NOT NULL"), nil
+		},
+		"entity_type": equalsFilter("entity_type"),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+}
+
+func (r *notificationRepository) Create(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+func (r *notificationRepository) GetByID(id uint) (*models.Notification, error) {
+	var notification models.Notification
+	if err := r.db.First(&notification, id).Error; err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (r *notificationRepository) GetByUserID(userID uint, spec *query.Spec) ([]models.Notification, *query.Page, error) {
+	return paginate[models.Notification](r.db.Where("user_id = ?", userID), spec, notificationListOptions)
+}
+
+func (r *notificationRepository) MarkRead(id uint, at time.Time) error {
+	return r.db.Model(&models.Notification{}).Where("id = ? AND read_at IS NULL", id).
+		Update("read_at", at).Error
+}
+
+func (r *notificationRepository) MarkAllRead(userID uint, at time.Time) (int64, error) {
+	result := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at)
+	return result.RowsAffected, result.Error
+}
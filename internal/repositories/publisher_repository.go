@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type PublisherRepository interface {
+	GetAll(spec *query.Spec) ([]models.Publisher, *query.Page, error)
+	GetByID(id uint) (*models.Publisher, error)
+	GetByNameKey(key string) (*models.Publisher, error)
+	FindOrCreate(publisher *models.Publisher) error
+	Update(publisher *models.Publisher) error
+	Merge(targetID uint, sourceIDs []uint) ([]uint, error)
+}
+
+type publisherRepository struct {
+	db *gorm.DB
+}
+
+func NewPublisherRepository(db *gorm.DB) PublisherRepository {
+	return &publisherRepository{db: db}
+}
+
+var publisherListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name": likeFilter("name"),
+		"city": equalsFilter("city"),
+	},
+	defaultSort: []query.SortField{{Field: "name"}},
+}
+
+// GetAll lists publishers with the number of active books from each.
+func (r *publisherRepository) GetAll(spec *query.Spec) ([]models.Publisher, *query.Page, error) {
+	publishers, page, err := paginate[models.Publisher](r.db, spec, publisherListOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint, len(publishers))
+	for i := range publishers {
+		ids[i] = publishers[i].ID
+	}
+	counts, err := r.bookCounts(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range publishers {
+		publishers[i].BookCount = counts[publishers[i].ID]
+	}
+	return publishers, page, nil
+}
+
+func (r *publisherRepository) GetByID(id uint) (*models.Publisher, error) {
+	var publisher models.Publisher
+	if err := r.db.First(&publisher, id).Error; err != nil {
+		return nil, err
+	}
+
+	counts, err := r.bookCounts([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	publisher.BookCount = counts[id]
+	return &publisher, nil
+}
+
+func (r *publisherRepository) bookCounts(ids []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		PublisherID uint
+		Count       int64
+	}
+	err := r.db.Model(&models.Book{}).
+		Select("publisher_id, COUNT(*) AS count").
+		Where("publisher_id IN ? AND is_active = ?", ids, true).
+		Group("publisher_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.PublisherID] = row.Count
+	}
+	return counts, err
+}
+
+// FindOrCreate loads the publisher with the same name key, or one merged
+// under that key, and creates it otherwise.
+func (r *publisherRepository) FindOrCreate(publisher *models.Publisher) error {
+	found, err := r.GetByNameKey(publisher.NameKey)
+	if err == nil {
+		*publisher = *found
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := r.db.Create(publisher).Error; err != nil {
+		// Created concurrently under the same key
+		if found, findErr := r.GetByNameKey(publisher.NameKey); findErr == nil {
+			*publisher = *found
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// GetByNameKey finds the publisher with a name key, or the one it was merged
+// into.
+func (r *publisherRepository) GetByNameKey(key string) (*models.Publisher, error) {
+	var publisher models.Publisher
+	err := r.db.Where("name_key = ?", key).First(&publisher).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var alias models.PublisherAlias
+		if err := r.db.Where("name_key = ?", key).First(&alias).Error; err != nil {
+			return nil, err
+		}
+		err = r.db.First(&publisher, alias.PublisherID).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &publisher, nil
+}
+
+func (r *publisherRepository) Update(publisher *models.Publisher) error {
+	return r.db.Save(publisher).Error
+}
+
+// Merge moves the books of the source publishers to the target and deletes
+// the sources, keeping their name keys as aliases of the target. It returns
+// the IDs of the books moved.
+func (r *publisherRepository) Merge(targetID uint, sourceIDs []uint) ([]uint, error) {
+	var bookIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Book{}).Where("publisher_id IN ?", sourceIDs).
+			Pluck("id", &bookIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Book{}).Where("publisher_id IN ?", sourceIDs).
+			Update("publisher_id", targetID).Error; err != nil {
+			return err
+		}
+
+		var sources []models.Publisher
+		if err := tx.Where("id IN ?", sourceIDs).Find(&sources).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.PublisherAlias{}).Where("publisher_id IN ?", sourceIDs).
+			Update("publisher_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Publisher{}, sourceIDs).Error; err != nil {
+			return err
+		}
+		for _, source := range sources {
+			alias := models.PublisherAlias{NameKey: source.NameKey, PublisherID: targetID}
+			if err := tx.Save(&alias).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return bookIDs, err
+}
@@ -0,0 +1,320 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotDraft = errors.New("order is no longer a draft")
+	// ErrLineChanged means copies of an order line were received,
+	// invoiced or cancelled meanwhile.
+	ErrLineChanged = errors.New("order line has changed")
+)
+
+type PurchaseOrderRepository interface {
+	Create(order *models.PurchaseOrder) error
+	GetAll(spec *query.Spec) ([]models.PurchaseOrder, *query.Page, error)
+	GetByID(id uint) (*models.PurchaseOrder, error)
+	Update(order *models.PurchaseOrder) error
+	ReplaceLines(orderID uint, lines []models.OrderLine) error
+	UpdateLine(line *models.OrderLine) error
+	CountOpenByVendor(vendorID uint) (int64, error)
+	Place(order *models.PurchaseOrder, entries []models.FundTransaction) error
+	Receive(line *models.OrderLine, quantity int, addStock bool) error
+	Unreceive(line *models.OrderLine, quantity int) error
+	SetLineBook(lineID, bookID uint) error
+	Cancel(line *models.OrderLine, copies int, entries []models.FundTransaction) error
+	CreateInvoice(invoice *models.Invoice, lines []*models.OrderLine, entries []models.FundTransaction) error
+	InvoiceExists(vendorID uint, number string) (bool, error)
+	GetOpenLines(budgetID uint) ([]models.OrderLine, error)
+}
+
+type purchaseOrderRepository struct {
+	db *gorm.DB
+}
+
+func NewPurchaseOrderRepository(db *gorm.DB) PurchaseOrderRepository {
+	return &purchaseOrderRepository{db: db}
+}
+
+var purchaseOrderListOptions = listOptions{
+	sorts: map[string]string{
+		"id":          "id",
+		"number":      "number",
+		"status":      "status",
+		"ordered_at":  "ordered_at",
+		"expected_at": "expected_at",
+		"created_at":  "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"vendor_id": uintFilter("vendor_id"),
+		"number":    equalsFilter("number"),
+		// Open orders past their expected date
+		"late": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			late, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: late must be true or false", query.ErrInvalid)
+			}
+			condition := "status IN ? AND expected_at < ?"
+			if !late {
+				condition = "NOT (" + condition + ")"
+			}
+			return db.Where(condition, openOrderStatuses, time.Now()), nil
+		},
+		"isbn": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			lines := db.Session(&gorm.Session{NewDB: true}).
+				Model(&models.OrderLine{}).Select("order_id").Where("isbn = ?", value)
+			return db.Where("id IN (?)", lines), nil
+		},
+		"ordered_after":  timeFilter("ordered_at", ">="),
+		"ordered_before": timeFilter("ordered_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+	preloads:    []string{"Vendor"},
+}
+
+// Orders still waiting for deliveries
+var openOrderStatuses = []models.OrderStatus{models.OrderPlaced, models.OrderPartial}
+
+// Create saves an order with its lines and numbers it PO-<year>-<id>.
+func (r *purchaseOrderRepository) Create(order *models.PurchaseOrder) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Vendor", "Lines.Book", "Invoices").Create(order).Error; err != nil {
+			return err
+		}
+		order.Number = fmt.Sprintf("PO-%d-%05d", order.CreatedAt.Year(), order.ID)
+		return tx.Model(order).Update("number", order.Number).Error
+	})
+}
+
+func (r *purchaseOrderRepository) GetAll(spec *query.Spec) ([]models.PurchaseOrder, *query.Page, error) {
+	return paginate[models.PurchaseOrder](r.db, spec, purchaseOrderListOptions)
+}
+
+func (r *purchaseOrderRepository) GetByID(id uint) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := r.db.Preload("Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.Book").
+		Preload("Invoices", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Invoices.Lines").
+		First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Update saves the order itself; lines are saved separately.
+func (r *purchaseOrderRepository) Update(order *models.PurchaseOrder) error {
+	return r.db.Omit("Vendor", "Lines", "Invoices").Save(order).Error
+}
+
+// ReplaceLines swaps all lines of a draft order.
+func (r *purchaseOrderRepository) ReplaceLines(orderID uint, lines []models.OrderLine) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("order_id = ?", orderID).Delete(&models.OrderLine{}).Error; err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return nil
+		}
+		for i := range lines {
+			lines[i].OrderID = orderID
+		}
+		return tx.Omit("Book").Create(&lines).Error
+	})
+}
+
+func (r *purchaseOrderRepository) UpdateLine(line *models.OrderLine) error {
+	return r.db.Omit("Book").Save(line).Error
+}
+
+// CountOpenByVendor counts the vendor's orders that are not closed yet,
+// drafts included.
+func (r *purchaseOrderRepository) CountOpenByVendor(vendorID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.PurchaseOrder{}).
+		Where("vendor_id = ? AND status IN ?", vendorID, []models.OrderStatus{models.OrderDraft, models.OrderPlaced, models.OrderPartial}).
+		Count(&count).Error
+	return count, err
+}
+
+// Place marks a draft order placed, saves the budgets and encumbrances of
+// its lines and posts the encumbrance entries, all or nothing. It returns
+// ErrOrderNotDraft when the order was placed or cancelled meanwhile, and
+// ErrBudgetExceeded when a budget can no longer cover its share.
+func (r *purchaseOrderRepository) Place(order *models.PurchaseOrder, entries []models.FundTransaction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.PurchaseOrder{}).
+			Where("id = ? AND status = ?", order.ID, models.OrderDraft).
+			Updates(map[string]interface{}{
+				"status":      order.Status,
+				"ordered_at":  order.OrderedAt,
+				"expected_at": order.ExpectedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrOrderNotDraft
+		}
+
+		for i := range order.Lines {
+			line := &order.Lines[i]
+			if line.BudgetID == nil {
+				continue
+			}
+			err := tx.Model(&models.OrderLine{}).Where("id = ?", line.ID).Updates(map[string]interface{}{
+				"budget_id":  line.BudgetID,
+				"encumbered": line.Encumbered,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return postFundEntries(tx, entries)
+	})
+}
+
+// Receive books in copies of a line and, with addStock, puts them on the
+// shelf of the line's book, all or nothing. It returns ErrLineChanged when
+// fewer copies are outstanding than that.
+func (r *purchaseOrderRepository) Receive(line *models.OrderLine, quantity int, addStock bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.OrderLine{}).
+			Where("id = ? AND quantity - cancelled - received >= ?", line.ID, quantity).
+			Updates(map[string]interface{}{
+				"received": gorm.Expr("received + ?", quantity),
+				"book_id":  line.BookID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrLineChanged
+		}
+
+		if addStock {
+			err := tx.Model(&models.Book{}).Where("id = ?", *line.BookID).UpdateColumns(map[string]interface{}{
+				"stock":     gorm.Expr("stock + ?", quantity),
+				"available": gorm.Expr("available + ?", quantity),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		line.Received += quantity
+		return nil
+	})
+}
+
+// Unreceive takes back copies booked in on a line that could not be put on
+// the shelf.
+func (r *purchaseOrderRepository) Unreceive(line *models.OrderLine, quantity int) error {
+	err := r.db.Model(&models.OrderLine{}).
+		Where("id = ? AND received >= ?", line.ID, quantity).
+		UpdateColumn("received", gorm.Expr("received - ?", quantity)).Error
+	if err != nil {
+		return err
+	}
+	line.Received -= quantity
+	return nil
+}
+
+// SetLineBook links a line to the book its copies were catalogued as.
+func (r *purchaseOrderRepository) SetLineBook(lineID, bookID uint) error {
+	return r.db.Model(&models.OrderLine{}).Where("id = ?", lineID).Update("book_id", bookID).Error
+}
+
+// Cancel cancels outstanding copies of a line and posts the release of
+// what they had committed, all or nothing. It returns ErrLineChanged when
+// copies of the line were received or invoiced since it was read.
+func (r *purchaseOrderRepository) Cancel(line *models.OrderLine, copies int, entries []models.FundTransaction) error {
+	released := 0.0
+	for _, entry := range entries {
+		if entry.Type == models.FundRelease {
+			released += entry.Amount
+		}
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.OrderLine{}).
+			Where("id = ? AND invoiced = ? AND quantity - cancelled - received >= ?", line.ID, line.Invoiced, copies).
+			Updates(map[string]interface{}{
+				"cancelled":  gorm.Expr("cancelled + ?", copies),
+				"encumbered": gorm.Expr("encumbered - ?", released),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrLineChanged
+		}
+		return postFundEntries(tx, entries)
+	})
+}
+
+// CreateInvoice records an invoice with the order lines it bills and the
+// fund entries charging it, all or nothing. The lines hold their totals
+// after the invoice; it returns ErrLineChanged when one was invoiced or
+// cancelled since it was read.
+func (r *purchaseOrderRepository) CreateInvoice(invoice *models.Invoice, lines []*models.OrderLine, entries []models.FundTransaction) error {
+	billed := make(map[uint]int, len(invoice.Lines))
+	for _, line := range invoice.Lines {
+		billed[line.OrderLineID] += line.Quantity
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
+			return err
+		}
+
+		for _, line := range lines {
+			result := tx.Model(&models.OrderLine{}).
+				Where("id = ? AND invoiced = ? AND cancelled = ?", line.ID, line.Invoiced-billed[line.ID], line.Cancelled).
+				Updates(map[string]interface{}{
+					"invoiced":   line.Invoiced,
+					"spent":      line.Spent,
+					"encumbered": line.Encumbered,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != 1 {
+				return ErrLineChanged
+			}
+		}
+
+		for i := range entries {
+			entries[i].InvoiceID = &invoice.ID
+		}
+		return postFundEntries(tx, entries)
+	})
+}
+
+func (r *purchaseOrderRepository) InvoiceExists(vendorID uint, number string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Invoice{}).Where("vendor_id = ? AND number = ?", vendorID, number).Count(&count).Error
+	return count > 0, err
+}
+
+// GetOpenLines returns the lines of a budget that still hold money or
+// have copies left to bill.
+func (r *purchaseOrderRepository) GetOpenLines(budgetID uint) ([]models.OrderLine, error) {
+	var lines []models.OrderLine
+	err := r.db.Where("budget_id = ? AND (encumbered > 0 OR quantity - cancelled - invoiced > 0)", budgetID).
+		Order("id").Find(&lines).Error
+	return lines, err
+}
@@ -0,0 +1,294 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// filterFunc narrows a list query by one ?key=value filter.
+type filterFunc func(db *gorm.DB, value string) (*gorm.DB, error)
+
+// listOptions describes what a list endpoint may be sorted and filtered by.
+type listOptions struct {
+	sorts       map[string]string // API field name -> column
+	filters     map[string]filterFunc
+	defaultSort []query.SortField
+	preloads    []string
+}
+
+// paginate applies a query spec to db and loads one page of rows. The id
+// column is always used as the final sort key so pages are stable.
+func paginate[T any](db *gorm.DB, spec *query.Spec, opts listOptions) ([]T, *query.Page, error) {
+	var items []T
+
+	if spec == nil {
+		// Every row in default order
+		db = applySort(db, opts, opts.defaultSort)
+		err := applyPreloads(db, opts).Find(&items).Error
+		return items, &query.Page{Limit: len(items), Total: int64(len(items))}, err
+	}
+
+	db, err := applyFilters(db, spec, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Model(new(T)).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+
+	sortFields := spec.Sort
+	if len(sortFields) == 0 {
+		sortFields = opts.defaultSort
+	}
+	for _, field := range sortFields {
+		if _, ok := opts.sorts[field.Field]; !ok {
+			return nil, nil, fmt.Errorf("%w: cannot sort by %q", query.ErrInvalid, field.Field)
+		}
+	}
+
+	if spec.Limit <= 0 {
+		err := applyPreloads(applySort(db, opts, sortFields), opts).Find(&items).Error
+		return items, &query.Page{Limit: len(items), Total: total}, err
+	}
+
+	page := &query.Page{Limit: spec.Limit, Total: total}
+
+	if spec.Cursor != nil {
+		if len(sortFields) > 1 {
+			return nil, nil, fmt.Errorf("%w: cursor pagination supports a single sort field", query.ErrInvalid)
+		}
+		db, err = applyCursor(db, spec.Cursor, sortFields, opts, new(T))
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		page.Page = spec.Page
+		page.TotalPages = int((total + int64(spec.Limit) - 1) / int64(spec.Limit))
+		db = db.Offset((spec.Page - 1) * spec.Limit)
+	}
+
+	// One extra row tells whether another page follows
+	db = applySort(db, opts, sortFields).Limit(spec.Limit + 1)
+	result := applyPreloads(db, opts).Find(&items)
+	if result.Error != nil {
+		return nil, nil, result.Error
+	}
+
+	if len(items) > spec.Limit {
+		items = items[:spec.Limit]
+		page.HasMore = true
+	}
+
+	if page.HasMore && (spec.Cursor != nil || len(sortFields) <= 1) {
+		cursor, err := nextCursor(result, reflect.ValueOf(&items[len(items)-1]).Elem(), sortFields, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		page.NextCursor = cursor
+	}
+
+	return items, page, nil
+}
+
+// applyFilters applies every filter in spec except the skipped keys.
+func applyFilters(db *gorm.DB, spec *query.Spec, opts listOptions, skip ...string) (*gorm.DB, error) {
+	if spec == nil {
+		return db, nil
+	}
+
+	// Filters are applied in a fixed order so the generated SQL is stable
+	keys := make([]string, 0, len(spec.Filters))
+	for key := range spec.Filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		filter, ok := opts.filters[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown filter %q", query.ErrInvalid, key)
+		}
+		if slices.Contains(skip, key) {
+			continue
+		}
+		var err error
+		db, err = filter(db, spec.Filters[key])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func applySort(db *gorm.DB, opts listOptions, fields []query.SortField) *gorm.DB {
+	hasID := false
+	for _, field := range fields {
+		column := opts.sorts[field.Field]
+		if column == "id" {
+			hasID = true
+		}
+		if field.Desc {
+			db = db.Order(column + " DESC")
+		} else {
+			db = db.Order(column + " ASC")
+		}
+	}
+	if !hasID {
+		db = db.Order("id ASC")
+	}
+	return db
+}
+
+func applyPreloads(db *gorm.DB, opts listOptions) *gorm.DB {
+	for _, preload := range opts.preloads {
+		db = db.Preload(preload)
+	}
+	return db
+}
+
+// applyCursor restricts the query to rows after the cursor in sort order.
+func applyCursor(db *gorm.DB, cursor *query.Cursor, fields []query.SortField, opts listOptions, model interface{}) (*gorm.DB, error) {
+	field := query.SortField{Field: "id"}
+	if len(fields) == 1 {
+		field = fields[0]
+	}
+	column := opts.sorts[field.Field]
+	operator := ">"
+	if field.Desc {
+		operator = "<"
+	}
+
+	if column == "id" {
+		return db.Where("id "+operator+" ?", cursor.ID), nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	schemaField := stmt.Schema.LookUpField(column)
+	if schemaField == nil {
+		return nil, fmt.Errorf("%w: cannot page by %q", query.ErrInvalid, field.Field)
+	}
+
+	value := reflect.New(schemaField.FieldType)
+	if err := json.Unmarshal(cursor.Value, value.Interface()); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", query.ErrInvalid)
+	}
+
+	return db.Where(
+		fmt.Sprintf("(%s %s ?) OR (%s = ? AND id > ?)", column, operator, column),
+		value.Elem().Interface(), value.Elem().Interface(), cursor.ID,
+	), nil
+}
+
+func nextCursor(result *gorm.DB, lastValue reflect.Value, fields []query.SortField, opts listOptions) (string, error) {
+	schema := result.Statement.Schema
+
+	idField := schema.LookUpField("id")
+	idValue, _ := idField.ValueOf(context.Background(), lastValue)
+	cursor := &query.Cursor{ID: toUint(idValue)}
+
+	if len(fields) == 1 && opts.sorts[fields[0].Field] != "id" {
+		field := schema.LookUpField(opts.sorts[fields[0].Field])
+		if field == nil {
+			return "", nil
+		}
+		value, _ := field.ValueOf(context.Background(), lastValue)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		cursor.Value = data
+	}
+
+	return query.EncodeCursor(cursor), nil
+}
+
+func toUint(value interface{}) uint {
+	switch v := value.(type) {
+	case uint:
+		return v
+	case *uint:
+		if v != nil {
+			return *v
+		}
+	}
+	return 0
+}
+
+// Common filter builders
+
+func equalsFilter(column string) filterFunc {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		values := query.Values(value)
+		if len(values) > 1 {
+			return db.Where(column+" IN ?", values), nil
+		}
+		return db.Where(column+" = ?", value), nil
+	}
+}
+
+func likeFilter(column string) filterFunc {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		return db.Where(column+" LIKE ?", "%"+value+"%"), nil
+	}
+}
+
+func uintFilter(column string) filterFunc {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be a number", query.ErrInvalid, column)
+		}
+		return db.Where(column+" = ?", id), nil
+	}
+}
+
+func intRangeFilter(column, operator string) filterFunc {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s filter must be a number", query.ErrInvalid, column)
+		}
+		return db.Where(column+" "+operator+" ?", number), nil
+	}
+}
+
+func boolFilter(column string) filterFunc {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s must be true or false", query.ErrInvalid, column)
+		}
+		return db.Where(column+" = ?", b), nil
+	}
+}
+
+func timeFilter(column, operator string) filterFunc {
+	return func(db *gorm.DB, value string) (*gorm.DB, error) {
+		t, err := parseFilterTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s filter must be a date (YYYY-MM-DD) or RFC3339 time", query.ErrInvalid, column)
+		}
+		return db.Where(column+" "+operator+" ?", t), nil
+	}
+}
+
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", strings.TrimSpace(value), time.Local)
+}
@@ -0,0 +1,76 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type SerialLoanRepository interface {
+	Create(loan *models.SerialLoan) error
+	GetAll(spec *query.Spec) ([]models.SerialLoan, *query.Page, error)
+	GetByID(id uint) (*models.SerialLoan, error)
+	GetByUser(userID uint, spec *query.Spec) ([]models.SerialLoan, *query.Page, error)
+	Update(loan *models.SerialLoan) error
+	GetOverdue(now time.Time) ([]models.SerialLoan, error)
+}
+
+type serialLoanRepository struct {
+	db *gorm.DB
+}
+
+func NewSerialLoanRepository(db *gorm.DB) SerialLoanRepository {
+	return &serialLoanRepository{db: db}
+}
+
+var serialLoanListOptions = listOptions{
+	sorts: map[string]string{
+		"id":          "id",
+		"borrow_date": "borrow_date",
+		"due_date":    "due_date",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"user_id":    uintFilter("user_id"),
+		"issue_id":   uintFilter("issue_id"),
+		"due_before": timeFilter("due_date", "<"),
+		"due_after":  timeFilter("due_date", ">="),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+	preloads:    []string{"Issue", "Issue.Serial", "User"},
+}
+
+func (r *serialLoanRepository) Create(loan *models.SerialLoan) error {
+	return r.db.Omit("Issue", "User").Create(loan).Error
+}
+
+func (r *serialLoanRepository) GetAll(spec *query.Spec) ([]models.SerialLoan, *query.Page, error) {
+	return paginate[models.SerialLoan](r.db, spec, serialLoanListOptions)
+}
+
+func (r *serialLoanRepository) GetByID(id uint) (*models.SerialLoan, error) {
+	var loan models.SerialLoan
+	if err := r.db.Preload("Issue").Preload("Issue.Serial").Preload("User").First(&loan, id).Error; err != nil {
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (r *serialLoanRepository) GetByUser(userID uint, spec *query.Spec) ([]models.SerialLoan, *query.Page, error) {
+	return paginate[models.SerialLoan](r.db.Where("user_id = ?", userID), spec, serialLoanListOptions)
+}
+
+func (r *serialLoanRepository) Update(loan *models.SerialLoan) error {
+	return r.db.Omit("Issue", "User").Save(loan).Error
+}
+
+// GetOverdue returns loans still marked borrowed past their due date.
+func (r *serialLoanRepository) GetOverdue(now time.Time) ([]models.SerialLoan, error) {
+	var loans []models.SerialLoan
+	err := r.db.Where("status = ? AND due_date < ?", models.StatusBorrowed, now).Find(&loans).Error
+	return loans, err
+}
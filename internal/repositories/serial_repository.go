@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type SerialRepository interface {
+	Create(serial *models.Serial) error
+	GetAll(spec *query.Spec) ([]models.Serial, *query.Page, error)
+	GetByID(id uint) (*models.Serial, error)
+	Update(serial *models.Serial) error
+	CreateSubscription(subscription *models.SerialSubscription, issues []models.SerialIssue) error
+	GetSubscription(id uint) (*models.SerialSubscription, error)
+	GetIssues(serialID uint, spec *query.Spec) ([]models.SerialIssue, *query.Page, error)
+	GetIssueByID(id uint) (*models.SerialIssue, error)
+	GetLastIssue(serialID uint) (*models.SerialIssue, error)
+	CreateIssue(issue *models.SerialIssue) error
+	UpdateIssue(issue *models.SerialIssue) error
+	GetIssuesToClaim(now time.Time) ([]models.SerialIssue, error)
+}
+
+type serialRepository struct {
+	db *gorm.DB
+}
+
+func NewSerialRepository(db *gorm.DB) SerialRepository {
+	return &serialRepository{db: db}
+}
+
+var serialListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"title":      "title",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"title": likeFilter("title"),
+		"issn":  equalsFilter("issn"),
+		"frequency": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("frequency IN ?", query.Values(value)), nil
+		},
+		"category":  equalsFilter("category"),
+		"is_active": boolFilter("is_active"),
+	},
+	defaultSort: []query.SortField{{Field: "title"}},
+}
+
+var serialIssueListOptions = listOptions{
+	sorts: map[string]string{
+		"id":          "id",
+		"expected_at": "expected_at",
+		"received_at": "received_at",
+		"claim_at":    "claim_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"serial_id":       uintFilter("serial_id"),
+		"subscription_id": uintFilter("subscription_id"),
+		"volume":          intRangeFilter("volume", "="),
+		"borrowable":      boolFilter("borrowable"),
+		"on_loan":         boolFilter("on_loan"),
+		"expected_after":  timeFilter("expected_at", ">="),
+		"expected_before": timeFilter("expected_at", "<"),
+		"received_after":  timeFilter("received_at", ">="),
+		"received_before": timeFilter("received_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "expected_at", Desc: true}},
+	preloads:    []string{"Serial"},
+}
+
+func (r *serialRepository) Create(serial *models.Serial) error {
+	return r.db.Omit("Subscriptions").Create(serial).Error
+}
+
+func (r *serialRepository) GetAll(spec *query.Spec) ([]models.Serial, *query.Page, error) {
+	return paginate[models.Serial](r.db, spec, serialListOptions)
+}
+
+// GetByID returns a serial with its subscriptions, oldest first.
+func (r *serialRepository) GetByID(id uint) (*models.Serial, error) {
+	var serial models.Serial
+	err := r.db.Preload("Subscriptions", func(db *gorm.DB) *gorm.DB { return db.Order("start_date") }).
+		Preload("Subscriptions.Vendor", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&serial, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &serial, nil
+}
+
+func (r *serialRepository) Update(serial *models.Serial) error {
+	return r.db.Omit("Subscriptions").Save(serial).Error
+}
+
+// CreateSubscription saves a subscription with the issues predicted for
+// it, all or nothing.
+func (r *serialRepository) CreateSubscription(subscription *models.SerialSubscription, issues []models.SerialIssue) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Vendor").Create(subscription).Error; err != nil {
+			return err
+		}
+		if len(issues) == 0 {
+			return nil
+		}
+		for i := range issues {
+			issues[i].SubscriptionID = &subscription.ID
+		}
+		return tx.Omit("Serial").CreateInBatches(&issues, 100).Error
+	})
+}
+
+func (r *serialRepository) GetSubscription(id uint) (*models.SerialSubscription, error) {
+	var subscription models.SerialSubscription
+	if err := r.db.First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetIssues lists the issues of a serial, or of every serial when
+// serialID is 0.
+func (r *serialRepository) GetIssues(serialID uint, spec *query.Spec) ([]models.SerialIssue, *query.Page, error) {
+	db := r.db
+	if serialID != 0 {
+		db = db.Where("serial_id = ?", serialID)
+	}
+	return paginate[models.SerialIssue](db, spec, serialIssueListOptions)
+}
+
+func (r *serialRepository) GetIssueByID(id uint) (*models.SerialIssue, error) {
+	var issue models.SerialIssue
+	if err := r.db.Preload("Serial").First(&issue, id).Error; err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// GetLastIssue returns the serial's issue with the highest numbering.
+func (r *serialRepository) GetLastIssue(serialID uint) (*models.SerialIssue, error) {
+	var issue models.SerialIssue
+	err := r.db.Where("serial_id = ?", serialID).
+		Order("volume DESC, number DESC, expected_at DESC").First(&issue).Error
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+func (r *serialRepository) CreateIssue(issue *models.SerialIssue) error {
+	return r.db.Omit("Serial").Create(issue).Error
+}
+
+func (r *serialRepository) UpdateIssue(issue *models.SerialIssue) error {
+	return r.db.Omit("Serial").Save(issue).Error
+}
+
+// GetIssuesToClaim returns awaited issues past their claim date that are
+// not marked late yet, with their serials.
+func (r *serialRepository) GetIssuesToClaim(now time.Time) ([]models.SerialIssue, error) {
+	var issues []models.SerialIssue
+	err := r.db.Preload("Serial").
+		Where("status IN ? AND claim_at <= ?", []models.IssueStatus{models.IssueExpected, models.IssueClaimed}, now).
+		Order("serial_id, expected_at").Find(&issues).Error
+	return issues, err
+}
@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrStocktakeClosed = errors.New("stocktake is already closed")
+	// ErrCountChanged means the copies were written off meanwhile.
+	ErrCountChanged = errors.New("stocktake count has changed")
+)
+
+type StocktakeRepository interface {
+	Create(stocktake *models.Stocktake) error
+	GetAll(spec *query.Spec) ([]models.Stocktake, *query.Page, error)
+	GetByID(id uint) (*models.Stocktake, error)
+	AddScans(scans []models.StocktakeScan) error
+	GetScans(stocktakeID uint, spec *query.Spec) ([]models.StocktakeScan, *query.Page, error)
+	GetAllScans(stocktakeID uint) ([]models.StocktakeScan, error)
+	CountScans(stocktakeID uint) (int64, error)
+	GetScan(stocktakeID, id uint) (*models.StocktakeScan, error)
+	DeleteScan(id uint) error
+	Close(stocktake *models.Stocktake, counts []models.StocktakeCount) error
+	GetCounts(stocktakeID uint) ([]models.StocktakeCount, error)
+	WriteOff(count *models.StocktakeCount, copies int) error
+}
+
+type stocktakeRepository struct {
+	db *gorm.DB
+}
+
+func NewStocktakeRepository(db *gorm.DB) StocktakeRepository {
+	return &stocktakeRepository{db: db}
+}
+
+var stocktakeListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"name":           likeFilter("name"),
+		"created_after":  timeFilter("created_at", ">="),
+		"created_before": timeFilter("created_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+}
+
+var stocktakeScanListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"code":       "code",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"book_id":  uintFilter("book_id"),
+		"location": equalsFilter("location"),
+		"code":     equalsFilter("code"),
+		"matched": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			if value == "true" {
+				return db.Where("book_id IS NOT NULL"), nil
+			}
+			return db.Where("book_id IS NULL"), nil
+		},
+	},
+	defaultSort: []query.SortField{{Field: "id", Desc: true}},
+	preloads:    []string{"Book"},
+}
+
+func (r *stocktakeRepository) Create(stocktake *models.Stocktake) error {
+	return r.db.Create(stocktake).Error
+}
+
+func (r *stocktakeRepository) GetAll(spec *query.Spec) ([]models.Stocktake, *query.Page, error) {
+	return paginate[models.Stocktake](r.db, spec, stocktakeListOptions)
+}
+
+func (r *stocktakeRepository) GetByID(id uint) (*models.Stocktake, error) {
+	var stocktake models.Stocktake
+	if err := r.db.First(&stocktake, id).Error; err != nil {
+		return nil, err
+	}
+	return &stocktake, nil
+}
+
+func (r *stocktakeRepository) AddScans(scans []models.StocktakeScan) error {
+	return r.db.Omit("Book").CreateInBatches(&scans, 200).Error
+}
+
+func (r *stocktakeRepository) GetScans(stocktakeID uint, spec *query.Spec) ([]models.StocktakeScan, *query.Page, error) {
+	return paginate[models.StocktakeScan](r.db.Where("stocktake_id = ?", stocktakeID), spec, stocktakeScanListOptions)
+}
+
+// GetAllScans returns every scan of a stocktake in the order taken.
+func (r *stocktakeRepository) GetAllScans(stocktakeID uint) ([]models.StocktakeScan, error) {
+	var scans []models.StocktakeScan
+	err := r.db.Where("stocktake_id = ?", stocktakeID).Order("id").Find(&scans).Error
+	return scans, err
+}
+
+func (r *stocktakeRepository) CountScans(stocktakeID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.StocktakeScan{}).Where("stocktake_id = ?", stocktakeID).Count(&count).Error
+	return count, err
+}
+
+func (r *stocktakeRepository) GetScan(stocktakeID, id uint) (*models.StocktakeScan, error) {
+	var scan models.StocktakeScan
+	if err := r.db.Where("stocktake_id = ?", stocktakeID).First(&scan, id).Error; err != nil {
+		return nil, err
+	}
+	return &scan, nil
+}
+
+func (r *stocktakeRepository) DeleteScan(id uint) error {
+	return r.db.Delete(&models.StocktakeScan{}, id).Error
+}
+
+// Close marks an open stocktake closed and stores the counts expected at
+// closing. It returns ErrStocktakeClosed when the stocktake was closed
+// meanwhile.
+func (r *stocktakeRepository) Close(stocktake *models.Stocktake, counts []models.StocktakeCount) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Stocktake{}).
+			Where("id = ? AND status = ?", stocktake.ID, models.StocktakeOpen).
+			Updates(map[string]interface{}{"status": models.StocktakeClosed, "closed_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrStocktakeClosed
+		}
+
+		for i := range counts {
+			counts[i].StocktakeID = stocktake.ID
+		}
+		if len(counts) > 0 {
+			if err := tx.CreateInBatches(&counts, 200).Error; err != nil {
+				return err
+			}
+		}
+
+		stocktake.Status = models.StocktakeClosed
+		stocktake.ClosedAt = &now
+		return nil
+	})
+}
+
+func (r *stocktakeRepository) GetCounts(stocktakeID uint) ([]models.StocktakeCount, error) {
+	var counts []models.StocktakeCount
+	err := r.db.Where("stocktake_id = ?", stocktakeID).Order("id").Find(&counts).Error
+	return counts, err
+}
+
+// WriteOff takes copies of a book off its stock and adds them to the
+// written off copies of its count. It returns ErrCountChanged when the
+// count was written off meanwhile, and ErrNoCopy when fewer copies than
+// that are left on the shelf.
+func (r *stocktakeRepository) WriteOff(count *models.StocktakeCount, copies int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.StocktakeCount{}).
+			Where("id = ? AND written_off = ?", count.ID, count.WrittenOff).
+			UpdateColumn("written_off", gorm.Expr("written_off + ?", copies))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrCountChanged
+		}
+
+		result = tx.Model(&models.Book{}).
+			Where("id = ? AND available >= ?", count.BookID, copies).
+			UpdateColumns(map[string]interface{}{
+				"stock":     gorm.Expr("stock - ?", copies),
+				"available": gorm.Expr("available - ?", copies),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return ErrNoCopy
+		}
+
+		count.WrittenOff += copies
+		return nil
+	})
+}
@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type SubjectRepository interface {
+	GetAll(spec *query.Spec) ([]models.Subject, *query.Page, error)
+	GetByID(id uint) (*models.Subject, error)
+	FindOrCreate(subject *models.Subject) error
+	ReplaceBookSubjects(bookID uint, subjects []models.BookSubject) error
+}
+
+type subjectRepository struct {
+	db *gorm.DB
+}
+
+func NewSubjectRepository(db *gorm.DB) SubjectRepository {
+	return &subjectRepository{db: db}
+}
+
+var subjectListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"heading":    "heading",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"heading": likeFilter("heading"),
+	},
+	defaultSort: []query.SortField{{Field: "heading"}},
+}
+
+// GetAll lists subject headings with the number of active books under each.
+func (r *subjectRepository) GetAll(spec *query.Spec) ([]models.Subject, *query.Page, error) {
+	subjects, page, err := paginate[models.Subject](r.db, spec, subjectListOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint, len(subjects))
+	for i := range subjects {
+		ids[i] = subjects[i].ID
+	}
+	counts, err := r.bookCounts(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range subjects {
+		subjects[i].BookCount = counts[subjects[i].ID]
+	}
+	return subjects, page, nil
+}
+
+func (r *subjectRepository) GetByID(id uint) (*models.Subject, error) {
+	var subject models.Subject
+	if err := r.db.First(&subject, id).Error; err != nil {
+		return nil, err
+	}
+
+	counts, err := r.bookCounts([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	subject.BookCount = counts[id]
+	return &subject, nil
+}
+
+func (r *subjectRepository) bookCounts(ids []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		SubjectID uint
+		Count     int64
+	}
+	err := r.db.Table("book_subjects").
+		Select("book_subjects.subject_id, COUNT(*) AS count").
+		Joins("JOIN books ON books.id = book_subjects.book_id").
+		Where("book_subjects.subject_id IN ? AND books.is_active = ? AND books.deleted_at IS NULL", ids, true).
+		Group("book_subjects.subject_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.SubjectID] = row.Count
+	}
+	return counts, err
+}
+
+// FindOrCreate loads the subject with the same heading key and creates it
+// otherwise.
+func (r *subjectRepository) FindOrCreate(subject *models.Subject) error {
+	var found models.Subject
+	err := r.db.Where("heading_key = ?", subject.HeadingKey).First(&found).Error
+	if err == nil {
+		*subject = found
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := r.db.Create(subject).Error; err != nil {
+		// Created concurrently under the same key
+		if r.db.Where("heading_key = ?", subject.HeadingKey).First(&found).Error == nil {
+			*subject = found
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// ReplaceBookSubjects sets the full list of subjects for a book.
+func (r *subjectRepository) ReplaceBookSubjects(bookID uint, subjects []models.BookSubject) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id = ?", bookID).Delete(&models.BookSubject{}).Error; err != nil {
+			return err
+		}
+		if len(subjects) == 0 {
+			return nil
+		}
+		rows := make([]models.BookSubject, len(subjects))
+		for i, subject := range subjects {
+			rows[i] = models.BookSubject{
+				BookID:    bookID,
+				SubjectID: subject.SubjectID,
+				Position:  subject.Position,
+			}
+		}
+		return tx.Create(&rows).Error
+	})
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// ErrSuggestionChanged means a suggestion was ordered or closed meanwhile.
+var ErrSuggestionChanged = errors.New("suggestion has changed")
+
+// Suggestions no order line refers to yet, which may be merged away
+var mergeableSuggestionStatuses = []models.SuggestionStatus{models.SuggestionPending, models.SuggestionAccepted}
+
+type SuggestionRepository interface {
+	Create(suggestion *models.PurchaseSuggestion) error
+	GetAll(spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error)
+	GetByID(id uint) (*models.PurchaseSuggestion, error)
+	GetByUserID(userID uint, spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error)
+	FindOpen(isbn, matchKey string) (*models.PurchaseSuggestion, error)
+	GetUncatalogued() ([]models.PurchaseSuggestion, error)
+	Update(suggestion *models.PurchaseSuggestion) error
+	GetVote(suggestionID, userID uint) (*models.SuggestionVote, error)
+	AddVote(vote *models.SuggestionVote) error
+	Merge(targetID uint, sourceIDs []uint) error
+}
+
+type suggestionRepository struct {
+	db *gorm.DB
+}
+
+func NewSuggestionRepository(db *gorm.DB) SuggestionRepository {
+	return &suggestionRepository{db: db}
+}
+
+// Suggestions that still take votes
+var openSuggestionStatuses = []models.SuggestionStatus{
+	models.SuggestionPending, models.SuggestionAccepted, models.SuggestionOrdered,
+}
+
+var suggestionListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"title":      "title",
+		"votes":      "votes",
+		"status":     "status",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"status": func(db *gorm.DB, value string) (*gorm.DB, error) {
+			return db.Where("status IN ?", query.Values(value)), nil
+		},
+		"title":          likeFilter("title"),
+		"author":         likeFilter("author"),
+		"isbn":           equalsFilter("isbn"),
+		"created_after":  timeFilter("created_at", ">="),
+		"created_before": timeFilter("created_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "votes", Desc: true}},
+}
+
+func (r *suggestionRepository) Create(suggestion *models.PurchaseSuggestion) error {
+	return r.db.Omit("Book", "Suggesters").Create(suggestion).Error
+}
+
+func (r *suggestionRepository) GetAll(spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error) {
+	return paginate[models.PurchaseSuggestion](r.db, spec, suggestionListOptions)
+}
+
+func (r *suggestionRepository) GetByID(id uint) (*models.PurchaseSuggestion, error) {
+	var suggestion models.PurchaseSuggestion
+	err := r.db.Preload("Book").
+		Preload("Suggesters", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Suggesters.User").
+		First(&suggestion, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// GetByUserID lists the suggestions a user made or voted for.
+func (r *suggestionRepository) GetByUserID(userID uint, spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error) {
+	voted := r.db.Session(&gorm.Session{NewDB: true}).
+		Model(&models.SuggestionVote{}).Select("suggestion_id").Where("user_id = ?", userID)
+	return paginate[models.PurchaseSuggestion](r.db.Where("id IN (?)", voted), spec, suggestionListOptions)
+}
+
+// FindOpen finds an open suggestion for the same ISBN or, failing that,
+// the same title and author.
+func (r *suggestionRepository) FindOpen(isbn, matchKey string) (*models.PurchaseSuggestion, error) {
+	var suggestion models.PurchaseSuggestion
+	if isbn != "" {
+		err := r.db.Where("isbn = ? AND status IN ?", isbn, openSuggestionStatuses).First(&suggestion).Error
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return &suggestion, err
+		}
+	}
+	err := r.db.Where("match_key = ? AND status IN ?", matchKey, openSuggestionStatuses).
+		Order("id").First(&suggestion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// GetUncatalogued returns the open suggestions with an ISBN, which may
+// have arrived in the catalog since.
+func (r *suggestionRepository) GetUncatalogued() ([]models.PurchaseSuggestion, error) {
+	var suggestions []models.PurchaseSuggestion
+	err := r.db.Where("status IN ? AND isbn <> ''", openSuggestionStatuses).
+		Order("id").Find(&suggestions).Error
+	return suggestions, err
+}
+
+func (r *suggestionRepository) Update(suggestion *models.PurchaseSuggestion) error {
+	return r.db.Omit("Book", "Suggesters").Save(suggestion).Error
+}
+
+func (r *suggestionRepository) GetVote(suggestionID, userID uint) (*models.SuggestionVote, error) {
+	var vote models.SuggestionVote
+	err := r.db.Where("suggestion_id = ? AND user_id = ?", suggestionID, userID).First(&vote).Error
+	if err != nil {
+		return nil, err
+	}
+	return &vote, nil
+}
+
+// AddVote records a vote and counts it on the suggestion.
+func (r *suggestionRepository) AddVote(vote *models.SuggestionVote) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("User").Create(vote).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PurchaseSuggestion{}).Where("id = ?", vote.SuggestionID).
+			Update("votes", gorm.Expr("votes + 1")).Error
+	})
+}
+
+// Merge moves the votes of the source suggestions to the target, keeping
+// one vote per reader, recounts the target and deletes the sources. It
+// returns ErrSuggestionChanged when a source was ordered or closed
+// meanwhile.
+func (r *suggestionRepository) Merge(targetID uint, sourceIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var votes []models.SuggestionVote
+		if err := tx.Where("suggestion_id IN ?", append([]uint{targetID}, sourceIDs...)).
+			Order("id").Find(&votes).Error; err != nil {
+			return err
+		}
+
+		moved, dropped, voters := mergeVotes(targetID, votes)
+		if len(dropped) > 0 {
+			if err := tx.Delete(&models.SuggestionVote{}, dropped).Error; err != nil {
+				return err
+			}
+		}
+		if len(moved) > 0 {
+			if err := tx.Model(&models.SuggestionVote{}).Where("id IN ?", moved).
+				Update("suggestion_id", targetID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.PurchaseSuggestion{}).Where("id = ?", targetID).
+			Update("votes", voters).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("status IN ?", mergeableSuggestionStatuses).Delete(&models.PurchaseSuggestion{}, sourceIDs)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(sourceIDs)) {
+			return ErrSuggestionChanged
+		}
+		return nil
+	})
+}
+
+// mergeVotes picks the votes to move to the target and those to drop, as
+// a reader who voted on several keeps their vote on the target, or else
+// their first one, and counts the readers left.
+func mergeVotes(targetID uint, votes []models.SuggestionVote) (moved, dropped []uint, voters int) {
+	voted := make(map[uint]bool)
+	for _, vote := range votes {
+		if vote.SuggestionID == targetID {
+			voted[vote.UserID] = true
+		}
+	}
+	for _, vote := range votes {
+		if vote.SuggestionID == targetID {
+			continue
+		}
+		if voted[vote.UserID] {
+			dropped = append(dropped, vote.ID)
+			continue
+		}
+		voted[vote.UserID] = true
+		moved = append(moved, vote.ID)
+	}
+	return moved, dropped, len(voted)
+}
@@ -0,0 +1,29 @@
+package repositories
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+)
+
+func TestMergeVotes(t *testing.T) {
+	votes := []models.SuggestionVote{
+		{ID: 1, SuggestionID: 10, UserID: 100},
+		{ID: 2, SuggestionID: 11, UserID: 100}, // already voted on the target
+		{ID: 3, SuggestionID: 11, UserID: 101},
+		{ID: 4, SuggestionID: 12, UserID: 101}, // voted on two sources
+		{ID: 5, SuggestionID: 12, UserID: 102},
+	}
+
+	moved, dropped, voters := mergeVotes(10, votes)
+	if want := []uint{3, 5}; !reflect.DeepEqual(moved, want) {
+		t.Errorf("moved %v, want %v", moved, want)
+	}
+	if want := []uint{2, 4}; !reflect.DeepEqual(dropped, want) {
+		t.Errorf("dropped %v, want %v", dropped, want)
+	}
+	if voters != 3 {
+		t.Errorf("voters = %d, want 3", voters)
+	}
+}
@@ -2,17 +2,20 @@ package repositories
 
 import (
 	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"gorm.io/gorm"
 )
 
 type UserRepository interface {
 	Create(user *models.User) error
-	GetAll() ([]models.User, error)
+	GetAll(spec *query.Spec) ([]models.User, *query.Page, error)
 	GetByID(id uint) (*models.User, error)
+	GetByIDUnscoped(id uint) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
 	Update(user *models.User) error
+	UpdateUnscoped(user *models.User) error
 	Delete(id uint) error
-	Search(query string) ([]models.User, error)
+	Search(q string, spec *query.Spec) ([]models.User, *query.Page, error)
 }
 
 type userRepository struct {
@@ -23,14 +26,29 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+var userListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"email":      "email",
+		"role":       "role",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"role":           equalsFilter("role"),
+		"is_active":      boolFilter("is_active"),
+		"created_after":  timeFilter("created_at", ">="),
+		"created_before": timeFilter("created_at", "<"),
+	},
+	defaultSort: []query.SortField{{Field: "id"}},
+}
+
 func (r *userRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
 }
 
-func (r *userRepository) GetAll() ([]models.User, error) {
-	var users []models.User
-	err := r.db.Find(&users).Error
-	return users, err
+func (r *userRepository) GetAll(spec *query.Spec) ([]models.User, *query.Page, error) {
+	return paginate[models.User](r.db, spec, userListOptions)
 }
 
 func (r *userRepository) GetByID(id uint) (*models.User, error) {
@@ -42,6 +60,16 @@ func (r *userRepository) GetByID(id uint) (*models.User, error) {
 	return &user, nil
 }
 
+// GetByIDUnscoped also returns soft-deleted users.
+func (r *userRepository) GetByIDUnscoped(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.Unscoped().First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = ?", email).First(&user).Error
@@ -55,13 +83,16 @@ func (r *userRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
+func (r *userRepository) UpdateUnscoped(user *models.User) error {
+	return r.db.Unscoped().Save(user).Error
+}
+
 func (r *userRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
 
-func (r *userRepository) Search(query string) ([]models.User, error) {
-	var users []models.User
-	err := r.db.Where("name LIKE ? OR email LIKE ? OR phone LIKE ?",
-		"%"+query+"%", "%"+query+"%", "%"+query+"%").Find(&users).Error
-	return users, err
+func (r *userRepository) Search(q string, spec *query.Spec) ([]models.User, *query.Page, error) {
+	db := r.db.Where("name LIKE ? OR email LIKE ? OR phone LIKE ?",
+		"%"+q+"%", "%"+q+"%", "%"+q+"%")
+	return paginate[models.User](db, spec, userListOptions)
 }
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type VendorRepository interface {
+	Create(vendor *models.Vendor) error
+	GetAll(spec *query.Spec) ([]models.Vendor, *query.Page, error)
+	GetByID(id uint) (*models.Vendor, error)
+	Update(vendor *models.Vendor) error
+	Delete(id uint) error
+}
+
+type vendorRepository struct {
+	db *gorm.DB
+}
+
+func NewVendorRepository(db *gorm.DB) VendorRepository {
+	return &vendorRepository{db: db}
+}
+
+var vendorListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"name":      likeFilter("name"),
+		"is_active": boolFilter("is_active"),
+	},
+	defaultSort: []query.SortField{{Field: "name"}},
+}
+
+func (r *vendorRepository) Create(vendor *models.Vendor) error {
+	return r.db.Create(vendor).Error
+}
+
+func (r *vendorRepository) GetAll(spec *query.Spec) ([]models.Vendor, *query.Page, error) {
+	return paginate[models.Vendor](r.db, spec, vendorListOptions)
+}
+
+func (r *vendorRepository) GetByID(id uint) (*models.Vendor, error) {
+	var vendor models.Vendor
+	if err := r.db.First(&vendor, id).Error; err != nil {
+		return nil, err
+	}
+	return &vendor, nil
+}
+
+func (r *vendorRepository) Update(vendor *models.Vendor) error {
+	return r.db.Save(vendor).Error
+}
+
+func (r *vendorRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Vendor{}, id).Error
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type WorkRepository interface {
+	Create(work *models.Work) error
+	GetAll(spec *query.Spec) ([]models.Work, *query.Page, error)
+	GetByID(id uint) (*models.Work, error)
+	GetByKey(key string) (*models.Work, error)
+	Update(work *models.Work) error
+	Merge(targetID uint, sourceIDs []uint) ([]uint, error)
+	DeleteOrphans(ids []uint) error
+}
+
+type workRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkRepository(db *gorm.DB) WorkRepository {
+	return &workRepository{db: db}
+}
+
+var workListOptions = listOptions{
+	sorts: map[string]string{
+		"id":         "id",
+		"title":      "title",
+		"author":     "author",
+		"created_at": "created_at",
+	},
+	filters: map[string]filterFunc{
+		"title":  likeFilter("title"),
+		"author": likeFilter("author"),
+	},
+	defaultSort: []query.SortField{{Field: "title"}},
+}
+
+func (r *workRepository) Create(work *models.Work) error {
+	return r.db.Omit("Editions").Create(work).Error
+}
+
+// GetAll lists works with the number of active editions of each.
+func (r *workRepository) GetAll(spec *query.Spec) ([]models.Work, *query.Page, error) {
+	works, page, err := paginate[models.Work](r.db, spec, workListOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uint, len(works))
+	for i := range works {
+		ids[i] = works[i].ID
+	}
+	counts, err := r.editionCounts(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range works {
+		works[i].EditionCount = counts[works[i].ID]
+	}
+	return works, page, nil
+}
+
+// GetByID loads a work with its active editions, oldest first.
+func (r *workRepository) GetByID(id uint) (*models.Work, error) {
+	var work models.Work
+	err := r.db.Preload("Editions", func(db *gorm.DB) *gorm.DB {
+		return db.Where("is_active = ?", true).Order("publish_year, id")
+	}).First(&work, id).Error
+	if err != nil {
+		return nil, err
+	}
+	work.EditionCount = int64(len(work.Editions))
+	return &work, nil
+}
+
+// GetByKey finds the oldest work with a title and author key.
+func (r *workRepository) GetByKey(key string) (*models.Work, error) {
+	var work models.Work
+	if err := r.db.Where("work_key = ?", key).Order("id").First(&work).Error; err != nil {
+		return nil, err
+	}
+	return &work, nil
+}
+
+func (r *workRepository) Update(work *models.Work) error {
+	return r.db.Omit("Editions").Save(work).Error
+}
+
+func (r *workRepository) editionCounts(ids []uint) (map[uint]int64, error) {
+	counts := make(map[uint]int64, len(ids))
+	if len(ids) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		WorkID uint
+		Count  int64
+	}
+	err := r.db.Model(&models.Book{}).
+		Select("work_id, COUNT(*) AS count").
+		Where("work_id IN ? AND is_active = ?", ids, true).
+		Group("work_id").
+		Scan(&rows).Error
+	for _, row := range rows {
+		counts[row.WorkID] = row.Count
+	}
+	return counts, err
+}
+
+// Merge moves the editions and holds of the source works to the target and
+// deletes the sources. It returns the IDs of the books that moved.
+func (r *workRepository) Merge(targetID uint, sourceIDs []uint) ([]uint, error) {
+	var bookIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Book{}).Where("work_id IN ?", sourceIDs).
+			Pluck("id", &bookIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Book{}).Where("work_id IN ?", sourceIDs).
+			Update("work_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.Hold{}).Where("work_id IN ?", sourceIDs).
+			Update("work_id", targetID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Work{}, sourceIDs).Error
+	})
+	return bookIDs, err
+}
+
+// DeleteOrphans deletes those of the works that no book and no hold
+// belongs to any more.
+func (r *workRepository) DeleteOrphans(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	editions := r.db.Unscoped().Model(&models.Book{}).Select("work_id").Where("work_id IN ?", ids)
+	holds := r.db.Unscoped().Model(&models.Hold{}).Select("work_id").Where("work_id IN ?", ids)
+	return r.db.Where("id IN ? AND id NOT IN (?) AND id NOT IN (?)", ids, editions, holds).
+		Delete(&models.Work{}).Error
+}
@@ -1,16 +1,25 @@
 package routes
 
 import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/config"
 	"github.com/yooerizkilab/library-system/internal/database"
 	"github.com/yooerizkilab/library-system/internal/handlers"
+	"github.com/yooerizkilab/library-system/internal/jobs"
+	"github.com/yooerizkilab/library-system/internal/metadata"
 	"github.com/yooerizkilab/library-system/internal/middleware"
 	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/search"
 	"github.com/yooerizkilab/library-system/internal/services"
+	"github.com/yooerizkilab/library-system/internal/storage"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, cfg *config.Config, scheduler *jobs.Scheduler) {
 	// Get database instance
 	db := database.GetDB()
 
@@ -18,16 +27,166 @@ func SetupRoutes(app *fiber.App) {
 	userRepo := repositories.NewUserRepository(db)
 	bookRepo := repositories.NewBookRepository(db)
 	borrowRepo := repositories.NewBorrowRepository(db)
+	erasureRepo := repositories.NewErasureRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	importRepo := repositories.NewImportRepository(db)
+	authorRepo := repositories.NewAuthorRepository(db)
+	publisherRepo := repositories.NewPublisherRepository(db)
+	classificationRepo := repositories.NewClassificationRepository(db)
+	subjectRepo := repositories.NewSubjectRepository(db)
+	workRepo := repositories.NewWorkRepository(db)
+	holdRepo := repositories.NewHoldRepository(db)
+	digitalFileRepo := repositories.NewDigitalFileRepository(db)
+	notificationRepo := repositories.NewNotificationRepository(db)
+	suggestionRepo := repositories.NewSuggestionRepository(db)
+	vendorRepo := repositories.NewVendorRepository(db)
+	purchaseOrderRepo := repositories.NewPurchaseOrderRepository(db)
+	fundRepo := repositories.NewFundRepository(db)
+	donorRepo := repositories.NewDonorRepository(db)
+	donationRepo := repositories.NewDonationRepository(db)
+	serialRepo := repositories.NewSerialRepository(db)
+	serialLoanRepo := repositories.NewSerialLoanRepository(db)
+	stocktakeRepo := repositories.NewStocktakeRepository(db)
 
 	// Initialize services
-	userService := services.NewUserService(userRepo)
-	bookService := services.NewBookService(bookRepo)
-	borrowService := services.NewBorrowService(borrowRepo, userRepo, bookRepo)
+	auditService := services.NewAuditService(auditRepo)
+	erasureRetention := time.Duration(cfg.ErasureRetentionDays) * 24 * time.Hour
+	holdPickup := time.Duration(cfg.HoldPickupDays) * 24 * time.Hour
+	holdService := services.NewHoldService(holdRepo, bookRepo, workRepo, userRepo, auditService, holdPickup)
+	userService := services.NewUserService(userRepo, erasureRepo, holdService, auditService, erasureRetention)
+	searchIndex := search.NewMemoryIndex()
+	bookService := services.NewBookService(bookRepo, authorRepo, publisherRepo, classificationRepo, subjectRepo,
+		workRepo, auditService, searchIndex, search.NewMemorySuggester())
+	authorService := services.NewAuthorService(authorRepo, bookRepo, bookService, auditService)
+	publisherService := services.NewPublisherService(publisherRepo, bookRepo, bookService, auditService)
+	classificationService := services.NewClassificationService(classificationRepo, bookRepo, bookService, auditService)
+	subjectService := services.NewSubjectService(subjectRepo, bookRepo)
+	workService := services.NewWorkService(workRepo, auditService)
+	historyRetention := time.Duration(cfg.HistoryRetentionDays) * 24 * time.Hour
+	borrowService := services.NewBorrowService(borrowRepo, userRepo, bookRepo, holdService, auditService, historyRetention)
+	importService := services.NewImportService(importRepo, bookRepo, bookService, auditService)
+	marcService := services.NewMARCService(bookRepo, bookService)
+	metadataProvider := metadata.NewCachingProvider(
+		metadata.NewOpenLibrary(cfg.MetadataProviderURL, 10*time.Second),
+		time.Duration(cfg.MetadataCacheHours)*time.Hour,
+	)
+	metadataService := services.NewMetadataService(metadataProvider)
+	blobStore, err := newBlobStore(cfg)
+	if err != nil {
+		log.Fatal("Failed to set up blob storage:", err)
+	}
+	coverService := services.NewCoverService(bookRepo, blobStore, auditService, cfg.CoverMaxMB<<20)
+	digitalSecret := cfg.DigitalURLSecret
+	if digitalSecret == "" {
+		digitalSecret = cfg.JWTSecret
+	}
+	digitalService := services.NewDigitalService(digitalFileRepo, bookRepo, borrowService, blobStore, auditService, services.DigitalLending{
+		LoanPeriod: time.Duration(cfg.DigitalLoanDays) * 24 * time.Hour,
+		LinkTTL:    time.Duration(cfg.DigitalLinkMinutes) * time.Minute,
+		Secret:     digitalSecret,
+		MaxBytes:   cfg.DigitalMaxMB << 20,
+	})
+	opdsService := services.NewOPDSService(bookService, classificationService, cfg.LibraryName)
+	sruService := services.NewSRUService(bookRepo, bookService, cfg.LibraryName)
+	feedService := services.NewFeedService(bookService, classificationService, authorService, cfg.LibraryName)
+	notificationService := services.NewNotificationService(notificationRepo)
+	suggestionService := services.NewSuggestionService(suggestionRepo, bookRepo, holdService, notificationService, auditService)
+	vendorService := services.NewVendorService(vendorRepo, purchaseOrderRepo, auditService)
+	fundService := services.NewFundService(fundRepo, purchaseOrderRepo, auditService, time.Month(cfg.FiscalYearStartMonth))
+	purchaseOrderService := services.NewPurchaseOrderService(purchaseOrderRepo, vendorRepo, bookRepo, bookService, holdService,
+		metadataService, suggestionService, fundService, auditService)
+	donorService := services.NewDonorService(donorRepo, auditService)
+	donationService := services.NewDonationService(donationRepo, bookRepo, donorService, bookService, holdService,
+		metadataService, auditService, cfg.LibraryName, cfg.LibraryAddress)
+	serialService := services.NewSerialService(serialRepo, vendorRepo, userRepo, notificationService, auditService)
+	serialLoanService := services.NewSerialLoanService(serialLoanRepo, serialRepo, userRepo, auditService)
+	stocktakeService := services.NewStocktakeService(stocktakeRepo, bookRepo, borrowRepo, auditService)
+	privacyService := services.NewPrivacyService(userRepo, borrowRepo, erasureRepo, holdService, auditService, erasureRetention)
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
 	bookHandler := handlers.NewBookHandler(bookService)
 	borrowHandler := handlers.NewBorrowHandler(borrowService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	importHandler := handlers.NewImportHandler(importService)
+	marcHandler := handlers.NewMARCHandler(marcService)
+	metadataHandler := handlers.NewMetadataHandler(metadataService)
+	authorHandler := handlers.NewAuthorHandler(authorService)
+	publisherHandler := handlers.NewPublisherHandler(publisherService)
+	classificationHandler := handlers.NewClassificationHandler(classificationService)
+	subjectHandler := handlers.NewSubjectHandler(subjectService)
+	workHandler := handlers.NewWorkHandler(workService)
+	holdHandler := handlers.NewHoldHandler(holdService)
+	coverHandler := handlers.NewCoverHandler(coverService)
+	digitalHandler := handlers.NewDigitalHandler(digitalService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	suggestionHandler := handlers.NewSuggestionHandler(suggestionService)
+	vendorHandler := handlers.NewVendorHandler(vendorService)
+	purchaseOrderHandler := handlers.NewPurchaseOrderHandler(purchaseOrderService)
+	fundHandler := handlers.NewFundHandler(fundService)
+	donorHandler := handlers.NewDonorHandler(donorService)
+	donationHandler := handlers.NewDonationHandler(donationService)
+	serialHandler := handlers.NewSerialHandler(serialService)
+	serialLoanHandler := handlers.NewSerialLoanHandler(serialLoanService)
+	stocktakeHandler := handlers.NewStocktakeHandler(stocktakeService)
+	opdsHandler := handlers.NewOPDSHandler(opdsService, 1)
+	opdsJSONHandler := handlers.NewOPDSHandler(opdsService, 2)
+	sruHandler := handlers.NewSRUHandler(sruService)
+	feedHandler := handlers.NewFeedHandler(feedService)
+
+	// Store every ISBN as ISBN-13 before indexing
+	if _, err := bookService.CanonicalizeISBNs(); err != nil {
+		log.Printf("Failed to normalize ISBNs: %v", err)
+	}
+
+	// Link author and publisher strings to records
+	if _, err := bookService.MigrateContributors(); err != nil {
+		log.Printf("Failed to migrate authors and publishers: %v", err)
+	}
+
+	// Classification tree, then categories onto it
+	if err := classificationService.SeedDewey(); err != nil {
+		log.Printf("Failed to seed Dewey classes: %v", err)
+	}
+	if _, err := bookService.MigrateCategories(); err != nil {
+		log.Printf("Failed to migrate categories: %v", err)
+	}
+
+	// Group editions into works
+	if _, err := bookService.MigrateWorks(); err != nil {
+		log.Printf("Failed to group books into works: %v", err)
+	}
+
+	// Search index
+	if err := bookService.WarmSearchIndex(cfg.SearchIndexPath); err != nil {
+		log.Printf("Failed to build search index: %v", err)
+	}
+
+	if err := importService.FailInterrupted(); err != nil {
+		log.Printf("Failed to close interrupted import jobs: %v", err)
+	}
+
+	// Background jobs
+	scheduler.Every(time.Hour, "process-erasures", privacyService.ProcessDueErasures)
+	scheduler.Every(24*time.Hour, "detach-reading-history", borrowService.DetachOldHistory)
+	scheduler.Every(15*time.Minute, "process-holds", holdService.ProcessHolds)
+	scheduler.Every(5*time.Minute, "expire-digital-loans", borrowService.ExpireDigitalLoans)
+	scheduler.Every(time.Hour, "link-catalogued-suggestions", suggestionService.LinkCatalogued)
+	scheduler.Every(time.Hour, "serial-claims", serialService.ProcessClaims)
+	scheduler.Every(time.Hour, "serial-overdue", serialLoanService.UpdateOverdueLoans)
+	if cfg.SearchIndexPath != "" {
+		scheduler.Every(time.Hour, "save-search-index", func() error {
+			return search.SaveSnapshot(searchIndex, cfg.SearchIndexPath)
+		})
+	}
+
+	// Covers and digital files are larger than other bodies; a megabyte on
+	// top leaves room for the multipart envelope
+	app.Use(middleware.BodyLimit(cfg.BodyLimitMB<<20,
+		middleware.UploadLimit{Method: fiber.MethodPut, Path: "/api/v1/books/manage/*/cover", Limit: (cfg.CoverMaxMB + 1) << 20},
+		middleware.UploadLimit{Method: fiber.MethodPost, Path: "/api/v1/books/manage/*/files", Limit: (cfg.DigitalMaxMB + 1) << 20},
+	))
 
 	// API version 1
 	v1 := app.Group("/api/v1")
@@ -41,9 +200,72 @@ func SetupRoutes(app *fiber.App) {
 	publicBooks := v1.Group("/books")
 	publicBooks.Get("/", bookHandler.GetAllBooks)
 	publicBooks.Get("/search", bookHandler.SearchBooks)
+	publicBooks.Get("/suggest", bookHandler.SuggestBooks)
 	publicBooks.Get("/available", bookHandler.GetAvailableBooks)
 	publicBooks.Get("/category/:category", bookHandler.GetBooksByCategory)
+	publicBooks.Get("/isbn/:isbn", bookHandler.GetBookByISBN)
 	publicBooks.Get("/:id", bookHandler.GetBookByID)
+	publicBooks.Get("/:id/marc", marcHandler.ExportBook)
+	publicBooks.Get("/:id/cover", coverHandler.GetCover)
+
+	// Public author and publisher endpoints (read-only)
+	publicAuthors := v1.Group("/authors")
+	publicAuthors.Get("/", authorHandler.GetAllAuthors)
+	publicAuthors.Get("/:id", authorHandler.GetAuthorByID)
+	publicAuthors.Get("/:id/books", authorHandler.GetAuthorBooks)
+
+	publicPublishers := v1.Group("/publishers")
+	publicPublishers.Get("/", publisherHandler.GetAllPublishers)
+	publicPublishers.Get("/:id", publisherHandler.GetPublisherByID)
+	publicPublishers.Get("/:id/books", publisherHandler.GetPublisherBooks)
+
+	// Public classification and subject endpoints (read-only)
+	publicClassifications := v1.Group("/classifications")
+	publicClassifications.Get("/", classificationHandler.GetClassifications)
+	publicClassifications.Get("/:id", classificationHandler.GetClassificationByID)
+	publicClassifications.Get("/:id/books", classificationHandler.GetClassificationBooks)
+
+	publicSubjects := v1.Group("/subjects")
+	publicSubjects.Get("/", subjectHandler.GetAllSubjects)
+	publicSubjects.Get("/:id", subjectHandler.GetSubjectByID)
+	publicSubjects.Get("/:id/books", subjectHandler.GetSubjectBooks)
+
+	// Public work endpoints (read-only)
+	publicWorks := v1.Group("/works")
+	publicWorks.Get("/", workHandler.GetAllWorks)
+	publicWorks.Get("/:id", workHandler.GetWorkByID)
+
+	// Public OPDS catalog for e-reader apps: 1.2 (Atom) and 2.0 (JSON)
+	opdsCatalog := v1.Group("/opds")
+	opdsCatalog.Get("/", opdsHandler.GetRoot)
+	opdsCatalog.Get("/opensearch.xml", opdsHandler.GetOpenSearch)
+	opdsCatalog.Get("/new", opdsHandler.GetNewArrivals)
+	opdsCatalog.Get("/categories", opdsHandler.GetCategories)
+	opdsCatalog.Get("/categories/:id", opdsHandler.GetCategory)
+	opdsCatalog.Get("/categories/:id/books", opdsHandler.GetCategoryBooks)
+	opdsCatalog.Get("/search", opdsHandler.Search)
+	opdsCatalog.Get("/books/:id", opdsHandler.GetPublication)
+
+	opdsJSON := opdsCatalog.Group("/v2")
+	opdsJSON.Get("/", opdsJSONHandler.GetRoot)
+	opdsJSON.Get("/new", opdsJSONHandler.GetNewArrivals)
+	opdsJSON.Get("/categories", opdsJSONHandler.GetCategories)
+	opdsJSON.Get("/categories/:id", opdsJSONHandler.GetCategory)
+	opdsJSON.Get("/categories/:id/books", opdsJSONHandler.GetCategoryBooks)
+	opdsJSON.Get("/search", opdsJSONHandler.Search)
+	opdsJSON.Get("/books/:id", opdsJSONHandler.GetPublication)
+
+	// Public SRU endpoint for partner libraries
+	v1.Get("/sru", sruHandler.Handle)
+
+	// Signed download links of digital loans; the signature stands in for a login
+	v1.Get("/digital/files/:id", digitalHandler.Download)
+
+	// Public news feeds of new books, as .atom or .rss
+	feeds := v1.Group("/feeds")
+	feeds.Get("/new.:format", feedHandler.GetNewArrivals)
+	feeds.Get("/categories/:id.:format", feedHandler.GetCategoryFeed)
+	feeds.Get("/authors/:id.:format", feedHandler.GetAuthorFeed)
 
 	// Protected routes (authentication required)
 	protected := v1.Group("", middleware.AuthRequired())
@@ -52,6 +274,7 @@ func SetupRoutes(app *fiber.App) {
 	profile := protected.Group("/profile")
 	profile.Get("/", userHandler.GetProfile)
 	profile.Put("/password", userHandler.ChangePassword)
+	profile.Put("/privacy", userHandler.UpdatePrivacyPreferences)
 
 	// User management routes (admin and librarian only)
 	users := protected.Group("/users", middleware.RoleRequired("admin", "librarian"))
@@ -64,9 +287,43 @@ func SetupRoutes(app *fiber.App) {
 	// Book management routes (admin and librarian only)
 	bookManagement := protected.Group("/books/manage", middleware.RoleRequired("admin", "librarian"))
 	bookManagement.Post("/", bookHandler.CreateBook)
+	bookManagement.Post("/lookup", metadataHandler.LookupISBN)
+	bookManagement.Post("/import", importHandler.StartImport)
+	bookManagement.Get("/import", importHandler.GetImportJobs)
+	bookManagement.Get("/import/:id", importHandler.GetImportJob)
+	bookManagement.Post("/import/:id/commit", importHandler.CommitImport)
+	bookManagement.Post("/marc", marcHandler.ImportRecords)
+	bookManagement.Get("/marc", marcHandler.ExportCatalog)
 	bookManagement.Put("/:id", bookHandler.UpdateBook)
+	bookManagement.Put("/:id/cover", coverHandler.UploadCover)
+	bookManagement.Delete("/:id/cover", coverHandler.DeleteCover)
+	bookManagement.Post("/:id/files", digitalHandler.UploadFile)
+	bookManagement.Delete("/:id/files/:fileId", digitalHandler.DeleteFile)
+	bookManagement.Get("/:id/provenance", donationHandler.GetBookProvenance)
 	bookManagement.Delete("/:id", middleware.RoleRequired("admin"), bookHandler.DeleteBook) // Only admin can delete
 
+	// Author and publisher management routes (admin and librarian only)
+	authorManagement := protected.Group("/authors/manage", middleware.RoleRequired("admin", "librarian"))
+	authorManagement.Get("/duplicates", authorHandler.GetDuplicateAuthors)
+	authorManagement.Post("/merge", authorHandler.MergeAuthors)
+	authorManagement.Put("/:id", authorHandler.UpdateAuthor)
+
+	publisherManagement := protected.Group("/publishers/manage", middleware.RoleRequired("admin", "librarian"))
+	publisherManagement.Get("/duplicates", publisherHandler.GetDuplicatePublishers)
+	publisherManagement.Post("/merge", publisherHandler.MergePublishers)
+	publisherManagement.Put("/:id", publisherHandler.UpdatePublisher)
+
+	// Classification management routes (admin and librarian only)
+	classificationManagement := protected.Group("/classifications/manage", middleware.RoleRequired("admin", "librarian"))
+	classificationManagement.Post("/", classificationHandler.CreateClassification)
+	classificationManagement.Put("/:id", classificationHandler.UpdateClassification)
+	classificationManagement.Delete("/:id", classificationHandler.DeleteClassification)
+
+	// Work management routes (admin and librarian only)
+	workManagement := protected.Group("/works/manage", middleware.RoleRequired("admin", "librarian"))
+	workManagement.Post("/merge", workHandler.MergeWorks)
+	workManagement.Put("/:id", workHandler.UpdateWork)
+
 	// Borrow routes
 	borrows := protected.Group("/borrows")
 
@@ -78,8 +335,7 @@ func SetupRoutes(app *fiber.App) {
 		userRole := c.Locals("user_role").(string)
 		if userRole == "member" {
 			// Members can only see their own borrows
-			// userID := c.Locals("user_id").(uint)
-			return borrowHandler.GetBorrowsByUser(c)
+			return borrowHandler.GetMyBorrows(c)
 		}
 		// Admins and librarians can see all borrows
 		return borrowHandler.GetAllBorrows(c)
@@ -97,18 +353,142 @@ func SetupRoutes(app *fiber.App) {
 	borrowManagement.Get("/book/:bookId", borrowHandler.GetBorrowsByBook)
 	borrowManagement.Get("/user/:userId/history", borrowHandler.GetBorrowHistory)
 
+	// Hold routes; members place and cancel their own holds
+	holds := protected.Group("/holds")
+	holds.Post("/", holdHandler.PlaceHold)
+	holds.Put("/:id/cancel", holdHandler.CancelHold)
+
+	holdManagement := holds.Group("", middleware.RoleRequired("admin", "librarian"))
+	holdManagement.Get("/", holdHandler.GetAllHolds)
+	holdManagement.Get("/user/:userId", holdHandler.GetHoldsByUser)
+	holdManagement.Get("/:id", holdHandler.GetHoldByID)
+
+	// Digital lending; members check out, return and download their own loans
+	digital := protected.Group("/digital")
+	digital.Post("/books/:id/checkout", digitalHandler.Checkout)
+	digital.Put("/loans/:id/return", digitalHandler.Return)
+	digital.Get("/loans/:id/downloads", digitalHandler.GetDownloads)
+
+	// Purchase suggestions; members suggest, staff review
+	suggestions := protected.Group("/suggestions")
+	suggestions.Post("/", suggestionHandler.Suggest)
+
+	suggestionManagement := suggestions.Group("", middleware.RoleRequired("admin", "librarian"))
+	suggestionManagement.Get("/", suggestionHandler.GetAllSuggestions)
+	suggestionManagement.Post("/merge", suggestionHandler.MergeSuggestions)
+	suggestionManagement.Get("/:id", suggestionHandler.GetSuggestionByID)
+	suggestionManagement.Put("/:id/review", suggestionHandler.ReviewSuggestion)
+	suggestionManagement.Put("/:id/catalogue", suggestionHandler.CatalogueSuggestion)
+
+	// Acquisitions: vendors, purchase orders and funds (admin and librarian only)
+	acquisitions := protected.Group("/acquisitions", middleware.RoleRequired("admin", "librarian"))
+	acquisitions.Post("/vendors", vendorHandler.CreateVendor)
+	acquisitions.Get("/vendors", vendorHandler.GetAllVendors)
+	acquisitions.Get("/vendors/:id", vendorHandler.GetVendorByID)
+	acquisitions.Put("/vendors/:id", vendorHandler.UpdateVendor)
+	acquisitions.Delete("/vendors/:id", vendorHandler.DeleteVendor)
+	acquisitions.Post("/orders", purchaseOrderHandler.CreateOrder)
+	acquisitions.Get("/orders", purchaseOrderHandler.GetAllOrders)
+	acquisitions.Get("/orders/:id", purchaseOrderHandler.GetOrderByID)
+	acquisitions.Put("/orders/:id", purchaseOrderHandler.UpdateOrder)
+	acquisitions.Post("/orders/:id/place", purchaseOrderHandler.PlaceOrder)
+	acquisitions.Post("/orders/:id/receive", purchaseOrderHandler.ReceiveOrder)
+	acquisitions.Post("/orders/:id/cancel", purchaseOrderHandler.CancelOrder)
+	acquisitions.Post("/orders/:id/claim", purchaseOrderHandler.ClaimOrder)
+	acquisitions.Post("/orders/:id/invoices", purchaseOrderHandler.InvoiceOrder)
+	acquisitions.Post("/funds", fundHandler.CreateFund)
+	acquisitions.Get("/funds", fundHandler.GetAllFunds)
+	acquisitions.Get("/funds/report", fundHandler.GetBudgetReport)
+	acquisitions.Post("/funds/rollover", fundHandler.Rollover)
+	acquisitions.Get("/funds/:id", fundHandler.GetFundByID)
+	acquisitions.Put("/funds/:id", fundHandler.UpdateFund)
+	acquisitions.Put("/funds/:id/budgets/:year", fundHandler.AllocateBudget)
+	acquisitions.Get("/funds/:id/transactions", fundHandler.GetTransactions)
+
+	// Donations and donors (admin and librarian only)
+	donations := protected.Group("/donations", middleware.RoleRequired("admin", "librarian"))
+	donations.Post("/", donationHandler.CreateDonation)
+	donations.Get("/", donationHandler.GetAllDonations)
+	donations.Get("/report", donationHandler.GetDonorReport)
+	donations.Post("/donors", donorHandler.CreateDonor)
+	donations.Get("/donors", donorHandler.GetAllDonors)
+	donations.Get("/donors/:id", donorHandler.GetDonorByID)
+	donations.Put("/donors/:id", donorHandler.UpdateDonor)
+	donations.Get("/:id", donationHandler.GetDonationByID)
+	donations.Post("/:id/items", donationHandler.AddItems)
+	donations.Post("/:id/decide", donationHandler.DecideItems)
+	donations.Get("/:id/letter", donationHandler.GetLetter)
+	donations.Post("/:id/acknowledge", donationHandler.AcknowledgeDonation)
+
+	// Serials, their issues and issue loans (admin and librarian only)
+	serials := protected.Group("/serials", middleware.RoleRequired("admin", "librarian"))
+	serials.Post("/", serialHandler.CreateSerial)
+	serials.Get("/", serialHandler.GetAllSerials)
+	serials.Get("/issues", serialHandler.GetAllIssues)
+	serials.Get("/issues/:id", serialHandler.GetIssueByID)
+	serials.Post("/issues/:id/check-in", serialHandler.CheckInIssue)
+	serials.Post("/issues/:id/claim", serialHandler.ClaimIssue)
+	serials.Post("/issues/:id/missing", serialHandler.MarkIssueMissing)
+	serials.Put("/issues/:id/loan-rules", serialHandler.SetLoanRules)
+	serials.Post("/issues/:id/borrow", serialLoanHandler.BorrowIssue)
+	serials.Get("/loans", serialLoanHandler.GetAllLoans)
+	serials.Post("/loans/:id/return", serialLoanHandler.ReturnIssue)
+	serials.Get("/:id", serialHandler.GetSerialByID)
+	serials.Put("/:id", serialHandler.UpdateSerial)
+	serials.Post("/:id/subscriptions", serialHandler.AddSubscription)
+	serials.Get("/:id/issues", serialHandler.GetSerialIssues)
+	serials.Post("/:id/issues", serialHandler.AddIssue)
+
+	// Shelf inventory (admin and librarian only)
+	stocktakes := protected.Group("/stocktakes", middleware.RoleRequired("admin", "librarian"))
+	stocktakes.Post("/", stocktakeHandler.CreateStocktake)
+	stocktakes.Get("/", stocktakeHandler.GetAllStocktakes)
+	stocktakes.Get("/:id", stocktakeHandler.GetStocktakeByID)
+	stocktakes.Post("/:id/close", stocktakeHandler.CloseStocktake)
+	stocktakes.Post("/:id/scans", stocktakeHandler.RecordScans)
+	stocktakes.Get("/:id/scans", stocktakeHandler.GetScans)
+	stocktakes.Delete("/:id/scans/:scanId", stocktakeHandler.DeleteScan)
+	stocktakes.Get("/:id/report", stocktakeHandler.GetReport)
+	stocktakes.Post("/:id/mark-missing", stocktakeHandler.MarkMissing)
+	stocktakes.Post("/:id/correct-locations", stocktakeHandler.CorrectLocations)
+
 	// User-specific routes (users can access their own data)
 	userSpecific := protected.Group("/my")
-	userSpecific.Get("/borrows", func(c *fiber.Ctx) error {
-		// Set user ID from token to params
-		userID := c.Locals("user_id").(uint)
-		c.Params("userId", string(rune(userID)))
-		return borrowHandler.GetBorrowsByUser(c)
-	})
-	userSpecific.Get("/history", func(c *fiber.Ctx) error {
-		// Set user ID from token to params
-		userID := c.Locals("user_id").(uint)
-		c.Params("userId", string(rune(userID)))
-		return borrowHandler.GetBorrowHistory(c)
-	})
+	userSpecific.Get("/borrows", borrowHandler.GetMyBorrows)
+	userSpecific.Get("/holds", holdHandler.GetMyHolds)
+	userSpecific.Get("/suggestions", suggestionHandler.GetMySuggestions)
+	userSpecific.Get("/serial-loans", serialLoanHandler.GetMyLoans)
+	userSpecific.Get("/notifications", notificationHandler.GetMyNotifications)
+	userSpecific.Put("/notifications/read", notificationHandler.MarkAllRead)
+	userSpecific.Put("/notifications/:id/read", notificationHandler.MarkRead)
+	userSpecific.Get("/history", borrowHandler.GetMyHistory)
+	userSpecific.Get("/data-export", privacyHandler.ExportMyData)
+	userSpecific.Post("/erasure", privacyHandler.RequestMyErasure)
+
+	// Admin routes
+	admin := protected.Group("/admin", middleware.RoleRequired("admin"))
+	admin.Get("/erasures", privacyHandler.GetErasureRequests)
+	admin.Put("/erasures/:id/cancel", privacyHandler.CancelErasure)
+	admin.Get("/audit", auditHandler.SearchAuditLog)
+	admin.Get("/audit/verify", auditHandler.VerifyAuditLog)
+	admin.Post("/search/rebuild", bookHandler.RebuildSearchIndex)
+}
+
+// newBlobStore opens the blob store chosen by BLOB_STORE.
+func newBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	switch cfg.BlobStore {
+	case "local":
+		return storage.NewLocalStore(cfg.BlobLocalDir)
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKeyID,
+			SecretKey: cfg.S3SecretKey,
+			PathStyle: cfg.S3PathStyle,
+		}, 30*time.Second)
+	default:
+		return nil, fmt.Errorf("unknown BLOB_STORE %q", cfg.BlobStore)
+	}
 }
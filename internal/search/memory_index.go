@@ -0,0 +1,342 @@
+package search
+
+import (
+	"encoding/gob"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// Score multiplier for terms found by fuzzy matching instead of exactly
+	fuzzyPenalty = 0.6
+)
+
+type memoryDoc struct {
+	Length float64
+	Terms  map[string]float64 // term -> weighted term frequency
+}
+
+// MemoryIndex is an in-memory inverted index ranked with BM25. Terms are
+// stemmed with the document language's stemmer; queries are expanded to
+// every stemmer's output so language doesn't need to be known at query time.
+type MemoryIndex struct {
+	mu          sync.RWMutex
+	postings    map[string]map[uint]float64
+	docs        map[uint]*memoryDoc
+	totalLength float64
+	builtAt     time.Time
+}
+
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{
+		postings: map[string]map[uint]float64{},
+		docs:     map[uint]*memoryDoc{},
+		builtAt:  time.Now(),
+	}
+}
+
+func (ix *MemoryIndex) Index(doc Document) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(doc.ID)
+	ix.add(doc)
+	ix.builtAt = time.Now()
+	return nil
+}
+
+func (ix *MemoryIndex) Remove(id uint) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.remove(id)
+	ix.builtAt = time.Now()
+	return nil
+}
+
+func (ix *MemoryIndex) Rebuild(docs []Document) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.postings = map[string]map[uint]float64{}
+	ix.docs = map[uint]*memoryDoc{}
+	ix.totalLength = 0
+	for _, doc := range docs {
+		ix.add(doc)
+	}
+	ix.builtAt = time.Now()
+	return nil
+}
+
+func (ix *MemoryIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.docs)
+}
+
+func (ix *MemoryIndex) BuiltAt() time.Time {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.builtAt
+}
+
+func (ix *MemoryIndex) add(doc Document) {
+	stem := stemmerFor(doc.Language)
+	entry := &memoryDoc{Terms: map[string]float64{}}
+
+	for _, field := range doc.Fields {
+		weight := field.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if field.Exact {
+			if token := exactToken(field.Text); token != "" {
+				entry.Terms[token] += weight
+				entry.Length += weight
+			}
+			continue
+		}
+		for _, token := range tokenize(field.Text) {
+			entry.Terms[stem(token)] += weight
+			entry.Length += weight
+		}
+	}
+
+	for term, tf := range entry.Terms {
+		postings, ok := ix.postings[term]
+		if !ok {
+			postings = map[uint]float64{}
+			ix.postings[term] = postings
+		}
+		postings[doc.ID] = tf
+	}
+	ix.docs[doc.ID] = entry
+	ix.totalLength += entry.Length
+}
+
+func (ix *MemoryIndex) remove(id uint) {
+	entry, ok := ix.docs[id]
+	if !ok {
+		return
+	}
+	for term := range entry.Terms {
+		delete(ix.postings[term], id)
+		if len(ix.postings[term]) == 0 {
+			delete(ix.postings, term)
+		}
+	}
+	ix.totalLength -= entry.Length
+	delete(ix.docs, id)
+}
+
+// Search ranks documents by the sum of each query word's best BM25 score.
+// A word matches a term when one of its stemmed variants equals the term;
+// words with no exact match fall back to terms within a small edit distance.
+func (ix *MemoryIndex) Search(q string, limit int) ([]Hit, error) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+
+	if len(ix.docs) == 0 {
+		return []Hit{}, nil
+	}
+
+	words := tokenize(q)
+	// A query that is a whole identifier, such as a hyphenated ISBN
+	if exact := exactToken(q); exact != "" && len(words) > 1 && ix.postings[exact] != nil {
+		words = append(words, exact)
+	}
+
+	avgLength := ix.totalLength / float64(len(ix.docs))
+	scores := map[uint]float64{}
+
+	for _, word := range words {
+		candidates := map[string]float64{}
+		for _, variant := range queryVariants(word) {
+			if _, ok := ix.postings[variant]; ok {
+				candidates[variant] = 1
+			}
+		}
+		if len(candidates) == 0 {
+			for _, term := range ix.fuzzyTerms(word) {
+				candidates[term] = fuzzyPenalty
+			}
+		}
+
+		// A document scores for the best matching variant of each word
+		best := map[uint]float64{}
+		for term, factor := range candidates {
+			postings := ix.postings[term]
+			idf := math.Log(1 + (float64(len(ix.docs))-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+			for id, tf := range postings {
+				length := ix.docs[id].Length
+				score := factor * idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+				if score > best[id] {
+					best[id] = score
+				}
+			}
+		}
+		for id, score := range best {
+			scores[id] += score
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// fuzzyTerms returns indexed terms within one edit of word, or two edits for
+// longer words.
+func (ix *MemoryIndex) fuzzyTerms(word string) []string {
+	maxDistance := 1
+	if len(word) >= 8 {
+		maxDistance = 2
+	}
+	if len(word) < 4 {
+		return nil
+	}
+
+	var terms []string
+	for _, variant := range queryVariants(word) {
+		for term := range ix.postings {
+			if abs(len(term)-len(variant)) > maxDistance {
+				continue
+			}
+			if editDistance(variant, term, maxDistance) <= maxDistance {
+				terms = append(terms, term)
+			}
+		}
+	}
+	return terms
+}
+
+func queryVariants(word string) []string {
+	variants := []string{word}
+	for _, stem := range []func(string) string{stemEnglish, stemIndonesian} {
+		variant := stem(word)
+		found := false
+		for _, v := range variants {
+			if v == variant {
+				found = true
+				break
+			}
+		}
+		if !found {
+			variants = append(variants, variant)
+		}
+	}
+	return variants
+}
+
+func stemmerFor(language string) func(string) string {
+	language = strings.ToLower(language)
+	switch {
+	case strings.HasPrefix(language, "en"):
+		return stemEnglish
+	case strings.HasPrefix(language, "id"), strings.HasPrefix(language, "indo"), language == "":
+		return stemIndonesian
+	}
+	return func(word string) string { return word }
+}
+
+// editDistance computes the optimal string alignment distance between a and
+// b, giving up early once it exceeds maxDistance.
+func editDistance(a, b string, maxDistance int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > maxDistance {
+			return maxDistance + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// snapshot is the on-disk form of a MemoryIndex.
+type snapshot struct {
+	Docs    map[uint]*memoryDoc
+	BuiltAt time.Time
+}
+
+// Save writes the index so a later process can Load it instead of
+// rebuilding from the database.
+func (ix *MemoryIndex) Save(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return gob.NewEncoder(w).Encode(snapshot{Docs: ix.docs, BuiltAt: ix.builtAt})
+}
+
+func (ix *MemoryIndex) Load(r io.Reader) error {
+	var snap snapshot
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	ix.postings = map[string]map[uint]float64{}
+	ix.docs = map[uint]*memoryDoc{}
+	ix.totalLength = 0
+	for id, entry := range snap.Docs {
+		for term, tf := range entry.Terms {
+			postings, ok := ix.postings[term]
+			if !ok {
+				postings = map[uint]float64{}
+				ix.postings[term] = postings
+			}
+			postings[id] = tf
+		}
+		ix.docs[id] = entry
+		ix.totalLength += entry.Length
+	}
+	ix.builtAt = snap.BuiltAt
+	return nil
+}
@@ -0,0 +1,58 @@
+package search
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func testDocument(id uint, title string) Document {
+	return Document{ID: id, Language: "Indonesian", Fields: []Field{{Name: "title", Text: title, Weight: 3}}}
+}
+
+// BuiltAt follows every change, so a snapshot saved after incremental
+// updates is not mistaken for a stale one.
+func TestMemoryIndexBuiltAtFollowsChanges(t *testing.T) {
+	ix := NewMemoryIndex()
+	ix.Rebuild([]Document{testDocument(1, "Bumi manusia")})
+	built := ix.BuiltAt()
+
+	time.Sleep(time.Millisecond)
+	ix.Index(testDocument(2, "Anak semua bangsa"))
+	indexed := ix.BuiltAt()
+	if !indexed.After(built) {
+		t.Errorf("Index left BuiltAt at %v", indexed)
+	}
+
+	time.Sleep(time.Millisecond)
+	ix.Remove(1)
+	if !ix.BuiltAt().After(indexed) {
+		t.Errorf("Remove left BuiltAt at %v", ix.BuiltAt())
+	}
+}
+
+func TestMemoryIndexSnapshot(t *testing.T) {
+	ix := NewMemoryIndex()
+	ix.Rebuild([]Document{testDocument(1, "Bumi manusia"), testDocument(2, "Anak semua bangsa")})
+	ix.Index(testDocument(3, "Jejak langkah"))
+
+	var buf bytes.Buffer
+	if err := ix.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewMemoryIndex()
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !loaded.BuiltAt().Equal(ix.BuiltAt()) || loaded.Len() != 3 {
+		t.Errorf("loaded %d docs built at %v, want 3 at %v", loaded.Len(), loaded.BuiltAt(), ix.BuiltAt())
+	}
+	hits, err := loaded.Search("langkah", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].ID != 3 {
+		t.Errorf("hits = %+v, want book 3", hits)
+	}
+}
@@ -0,0 +1,293 @@
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Phrases are also completed from their later words, e.g. "manusia" finds
+// "Bumi Manusia", up to this many words in.
+const maxWordStarts = 8
+
+// MemorySuggester is an in-memory Suggester. Every phrase is stored under
+// sorted keys starting at each of its words, so the completions for a
+// prefix are one contiguous range found by binary search.
+type MemorySuggester struct {
+	mu      sync.RWMutex
+	phrases map[phraseID]*phrase
+	docs    map[uint][]*phrase
+	keys    []prefixKey
+	// words counts phrase references per word, for corrections
+	words map[string]int
+}
+
+type phraseID struct {
+	kind string
+	norm string
+}
+
+type phrase struct {
+	phraseID
+	text  string
+	count int
+}
+
+type prefixKey struct {
+	key    string
+	phrase *phrase
+}
+
+func NewMemorySuggester() *MemorySuggester {
+	return &MemorySuggester{
+		phrases: make(map[phraseID]*phrase),
+		docs:    make(map[uint][]*phrase),
+		words:   make(map[string]int),
+	}
+}
+
+func (s *MemorySuggester) Index(doc Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(doc.ID)
+	for _, key := range s.add(doc) {
+		i := s.search(key.key)
+		s.keys = append(s.keys, prefixKey{})
+		copy(s.keys[i+1:], s.keys[i:])
+		s.keys[i] = key
+	}
+	return nil
+}
+
+func (s *MemorySuggester) Remove(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+	return nil
+}
+
+func (s *MemorySuggester) Rebuild(docs []Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.phrases = make(map[phraseID]*phrase)
+	s.docs = make(map[uint][]*phrase)
+	s.words = make(map[string]int)
+	s.keys = nil
+	for _, doc := range docs {
+		s.keys = append(s.keys, s.add(doc)...)
+	}
+	slices.SortFunc(s.keys, func(a, b prefixKey) int {
+		return strings.Compare(a.key, b.key)
+	})
+	return nil
+}
+
+// add records the phrases of doc and returns the keys of phrases seen for
+// the first time.
+func (s *MemorySuggester) add(doc Document) []prefixKey {
+	var keys []prefixKey
+	for _, field := range doc.Fields {
+		words := Words(field.Text)
+		if len(words) == 0 {
+			continue
+		}
+
+		id := phraseID{kind: field.Name, norm: strings.Join(words, " ")}
+		p, ok := s.phrases[id]
+		if !ok {
+			p = &phrase{phraseID: id, text: strings.TrimSpace(field.Text)}
+			s.phrases[id] = p
+			for i := 0; i < len(words) && i < maxWordStarts; i++ {
+				keys = append(keys, prefixKey{key: strings.Join(words[i:], " "), phrase: p})
+			}
+		} else if containsPhrase(s.docs[doc.ID], p) {
+			continue
+		}
+
+		p.count++
+		s.docs[doc.ID] = append(s.docs[doc.ID], p)
+		for _, word := range words {
+			s.words[word]++
+		}
+	}
+	return keys
+}
+
+func (s *MemorySuggester) remove(id uint) {
+	for _, p := range s.docs[id] {
+		for _, word := range strings.Split(p.norm, " ") {
+			if s.words[word]--; s.words[word] <= 0 {
+				delete(s.words, word)
+			}
+		}
+
+		p.count--
+		if p.count > 0 {
+			continue
+		}
+		delete(s.phrases, p.phraseID)
+		kept := s.keys[:0]
+		for _, key := range s.keys {
+			if key.phrase != p {
+				kept = append(kept, key)
+			}
+		}
+		s.keys = kept
+	}
+	delete(s.docs, id)
+}
+
+func containsPhrase(phrases []*phrase, p *phrase) bool {
+	for _, existing := range phrases {
+		if existing == p {
+			return true
+		}
+	}
+	return false
+}
+
+// search returns the index of the first key not less than prefix.
+func (s *MemorySuggester) search(prefix string) int {
+	return sort.Search(len(s.keys), func(i int) bool {
+		return s.keys[i].key >= prefix
+	})
+}
+
+func (s *MemorySuggester) Suggest(q string, limit int) (*Suggestions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := &Suggestions{Completions: []Suggestion{}}
+	words := Words(q)
+	if len(words) == 0 {
+		return result, nil
+	}
+
+	result.Completions = s.complete(strings.Join(words, " "), limit)
+	if corrected, ok := s.correct(words); ok {
+		result.DidYouMean = corrected
+		if len(result.Completions) == 0 {
+			result.Completions = s.complete(corrected, limit)
+		}
+	}
+	return result, nil
+}
+
+// complete ranks phrases with a key starting with prefix. Phrases that start
+// with the prefix come before those matching at a later word, then more
+// common and shorter phrases first.
+func (s *MemorySuggester) complete(prefix string, limit int) []Suggestion {
+	better := func(a, b candidate) bool {
+		if a.fromStart != b.fromStart {
+			return a.fromStart
+		}
+		if a.phrase.count != b.phrase.count {
+			return a.phrase.count > b.phrase.count
+		}
+		if len(a.phrase.norm) != len(b.phrase.norm) {
+			return len(a.phrase.norm) < len(b.phrase.norm)
+		}
+		return a.phrase.norm < b.phrase.norm
+	}
+
+	// Keep the best limit candidates in order. A phrase has one key per
+	// word start, so it is ranked by its best match.
+	var top []candidate
+	for i := s.search(prefix); i < len(s.keys) && strings.HasPrefix(s.keys[i].key, prefix); i++ {
+		c := candidate{phrase: s.keys[i].phrase, fromStart: s.keys[i].key == s.keys[i].phrase.norm}
+		if limit > 0 && len(top) == limit && !better(c, top[limit-1]) {
+			continue
+		}
+		top = insertCandidate(top, c, better)
+		if limit > 0 && len(top) > limit {
+			top = top[:limit]
+		}
+	}
+
+	completions := make([]Suggestion, len(top))
+	for i, c := range top {
+		completions[i] = Suggestion{Text: c.phrase.text, Kind: c.phrase.kind, Count: c.phrase.count}
+	}
+	return completions
+}
+
+type candidate struct {
+	phrase    *phrase
+	fromStart bool
+}
+
+// insertCandidate adds c to the ordered list, replacing a worse entry for the
+// same phrase.
+func insertCandidate(top []candidate, c candidate, better func(a, b candidate) bool) []candidate {
+	for i, existing := range top {
+		if existing.phrase == c.phrase {
+			if !better(c, existing) {
+				return top
+			}
+			top = append(top[:i], top[i+1:]...)
+			break
+		}
+	}
+
+	pos := sort.Search(len(top), func(i int) bool { return better(c, top[i]) })
+	top = append(top, candidate{})
+	copy(top[pos+1:], top[pos:])
+	top[pos] = c
+	return top
+}
+
+// correct replaces unknown words in the query with the closest known word.
+// The last word may still be being typed, so it is kept when it starts a
+// known word.
+func (s *MemorySuggester) correct(words []string) (string, bool) {
+	corrected := make([]string, len(words))
+	changed := false
+	for i, word := range words {
+		corrected[i] = word
+		if s.words[word] > 0 {
+			continue
+		}
+		if i == len(words)-1 && s.startsWord(word) {
+			continue
+		}
+		if replacement, ok := s.closestWord(word); ok {
+			corrected[i] = replacement
+			changed = true
+		}
+	}
+	return strings.Join(corrected, " "), changed
+}
+
+func (s *MemorySuggester) startsWord(prefix string) bool {
+	i := s.search(prefix)
+	return i < len(s.keys) && strings.HasPrefix(s.keys[i].key, prefix)
+}
+
+// closestWord finds the known word within one edit of word, or two for
+// longer words, preferring fewer edits and then more frequent words.
+func (s *MemorySuggester) closestWord(word string) (string, bool) {
+	if len(word) < 3 {
+		return "", false
+	}
+	maxDistance := 1
+	if len(word) >= 8 {
+		maxDistance = 2
+	}
+
+	best, bestDistance, bestCount := "", maxDistance+1, 0
+	for candidate, count := range s.words {
+		if abs(len(candidate)-len(word)) > maxDistance {
+			continue
+		}
+		distance := editDistance(word, candidate, maxDistance)
+		if distance < bestDistance ||
+			(distance == bestDistance && (count > bestCount || (count == bestCount && candidate < best))) {
+			best, bestDistance, bestCount = candidate, distance, count
+		}
+	}
+	return best, bestDistance <= maxDistance
+}
@@ -0,0 +1,68 @@
+// Package search provides catalog full-text search behind the SearchIndex
+// interface, with an embedded in-memory implementation.
+package search
+
+import (
+	"time"
+)
+
+// SearchIndex keeps a ranked, searchable copy of catalog records.
+type SearchIndex interface {
+	// Index adds a document or replaces the one with the same ID.
+	Index(doc Document) error
+	Remove(id uint) error
+	// Search returns up to limit hits, best first. A limit of 0 returns
+	// every match.
+	Search(q string, limit int) ([]Hit, error)
+	// Rebuild replaces the whole index content.
+	Rebuild(docs []Document) error
+	Len() int
+	// BuiltAt is when the index last took in a change, so it matches the
+	// catalog as of then.
+	BuiltAt() time.Time
+}
+
+// Field is one searchable part of a document. Weight scales how much a
+// match in this field counts towards the score.
+type Field struct {
+	Name   string
+	Text   string
+	Weight float64
+	// Exact fields are indexed as a single normalized token, e.g. ISBNs.
+	Exact bool
+}
+
+type Document struct {
+	ID       uint
+	Language string
+	Fields   []Field
+}
+
+type Hit struct {
+	ID    uint    `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Suggester completes partially typed queries from short catalog phrases
+// such as titles and author names. Each document field is one phrase and
+// the field name is its kind.
+type Suggester interface {
+	Index(doc Document) error
+	Remove(id uint) error
+	Rebuild(docs []Document) error
+	// Suggest returns up to limit completions for q, best first, and a
+	// corrected query when q contains unknown words.
+	Suggest(q string, limit int) (*Suggestions, error)
+}
+
+type Suggestion struct {
+	Text string `json:"text"`
+	Kind string `json:"kind"`
+	// Count is how many documents share the phrase.
+	Count int `json:"count"`
+}
+
+type Suggestions struct {
+	Completions []Suggestion `json:"completions"`
+	DidYouMean  string       `json:"did_you_mean,omitempty"`
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Persistent is implemented by indexes that can be written to disk.
+type Persistent interface {
+	Save(w io.Writer) error
+	Load(r io.Reader) error
+}
+
+// SaveSnapshot writes index to path atomically. Indexes that aren't
+// Persistent are skipped.
+func SaveSnapshot(index SearchIndex, path string) error {
+	persistent, ok := index.(Persistent)
+	if !ok || path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := persistent.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadSnapshot fills index from path. It reports false when there is no
+// snapshot to load.
+func LoadSnapshot(index SearchIndex, path string) (bool, error) {
+	persistent, ok := index.(Persistent)
+	if !ok || path == "" {
+		return false, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	if err := persistent.Load(file); err != nil {
+		return false, err
+	}
+	return true, nil
+}
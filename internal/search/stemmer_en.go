@@ -0,0 +1,242 @@
+package search
+
+import (
+	"strings"
+)
+
+// stemEnglish implements the Porter (1980) stemming algorithm.
+func stemEnglish(word string) string {
+	if len(word) <= 2 || !isASCIILower(word) {
+		return word
+	}
+
+	w := []byte(word)
+	w = porterStep1a(w)
+	w = porterStep1b(w)
+	w = porterStep1c(w)
+	w = porterStep2(w)
+	w = porterStep3(w)
+	w = porterStep4(w)
+	w = porterStep5(w)
+	return string(w)
+}
+
+func isASCIILower(word string) bool {
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isConsonant reports whether w[i] is a consonant in the Porter sense, where
+// y is a consonant only when it follows a vowel.
+func isConsonant(w []byte, i int) bool {
+	switch w[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		if i == 0 {
+			return true
+		}
+		return !isConsonant(w, i-1)
+	}
+	return true
+}
+
+// measure counts the VC sequences in w, the m in [C](VC){m}[V].
+func measure(w []byte) int {
+	n := 0
+	i := 0
+	for i < len(w) && isConsonant(w, i) {
+		i++
+	}
+	for i < len(w) {
+		for i < len(w) && !isConsonant(w, i) {
+			i++
+		}
+		if i >= len(w) {
+			break
+		}
+		for i < len(w) && isConsonant(w, i) {
+			i++
+		}
+		n++
+	}
+	return n
+}
+
+func containsVowel(w []byte) bool {
+	for i := range w {
+		if !isConsonant(w, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsDoubleConsonant(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && isConsonant(w, n-1)
+}
+
+// endsCVC reports whether w ends consonant-vowel-consonant where the last
+// consonant is not w, x or y.
+func endsCVC(w []byte) bool {
+	n := len(w)
+	if n < 3 || !isConsonant(w, n-1) || isConsonant(w, n-2) || !isConsonant(w, n-3) {
+		return false
+	}
+	switch w[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+func hasSuffix(w []byte, suffix string) bool {
+	return strings.HasSuffix(string(w), suffix)
+}
+
+// replaceSuffix swaps suffix for replacement when the remaining stem has a
+// measure greater than minMeasure.
+func replaceSuffix(w []byte, suffix, replacement string, minMeasure int) ([]byte, bool) {
+	if !hasSuffix(w, suffix) {
+		return w, false
+	}
+	stem := w[:len(w)-len(suffix)]
+	if measure(stem) > minMeasure {
+		return append(append([]byte{}, stem...), replacement...), true
+	}
+	return w, true
+}
+
+func porterStep1a(w []byte) []byte {
+	switch {
+	case hasSuffix(w, "sses"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ies"):
+		return w[:len(w)-2]
+	case hasSuffix(w, "ss"):
+		return w
+	case hasSuffix(w, "s"):
+		return w[:len(w)-1]
+	}
+	return w
+}
+
+func porterStep1b(w []byte) []byte {
+	if hasSuffix(w, "eed") {
+		if measure(w[:len(w)-3]) > 0 {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+
+	var stem []byte
+	switch {
+	case hasSuffix(w, "ed") && containsVowel(w[:len(w)-2]):
+		stem = w[:len(w)-2]
+	case hasSuffix(w, "ing") && containsVowel(w[:len(w)-3]):
+		stem = w[:len(w)-3]
+	default:
+		return w
+	}
+
+	switch {
+	case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+		return append(append([]byte{}, stem...), 'e')
+	case endsDoubleConsonant(stem):
+		last := stem[len(stem)-1]
+		if last != 'l' && last != 's' && last != 'z' {
+			return stem[:len(stem)-1]
+		}
+	case measure(stem) == 1 && endsCVC(stem):
+		return append(append([]byte{}, stem...), 'e')
+	}
+	return stem
+}
+
+func porterStep1c(w []byte) []byte {
+	if hasSuffix(w, "y") && containsVowel(w[:len(w)-1]) {
+		out := append([]byte{}, w...)
+		out[len(out)-1] = 'i'
+		return out
+	}
+	return w
+}
+
+var porterStep2Rules = [][2]string{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"abli", "able"}, {"alli", "al"}, {"entli", "ent"},
+	{"eli", "e"}, {"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"},
+	{"ator", "ate"}, {"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"},
+	{"ousness", "ous"}, {"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"},
+}
+
+func porterStep2(w []byte) []byte {
+	for _, rule := range porterStep2Rules {
+		if out, matched := replaceSuffix(w, rule[0], rule[1], 0); matched {
+			return out
+		}
+	}
+	return w
+}
+
+var porterStep3Rules = [][2]string{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+func porterStep3(w []byte) []byte {
+	for _, rule := range porterStep3Rules {
+		if out, matched := replaceSuffix(w, rule[0], rule[1], 0); matched {
+			return out
+		}
+	}
+	return w
+}
+
+var porterStep4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment",
+	"ent", "ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+func porterStep4(w []byte) []byte {
+	// Longest matching suffix wins
+	best := ""
+	for _, suffix := range porterStep4Suffixes {
+		if hasSuffix(w, suffix) && len(suffix) > len(best) {
+			best = suffix
+		}
+	}
+	if best == "" {
+		return w
+	}
+
+	stem := w[:len(w)-len(best)]
+	if best == "ion" {
+		if len(stem) == 0 || (stem[len(stem)-1] != 's' && stem[len(stem)-1] != 't') {
+			return w
+		}
+	}
+	if measure(stem) > 1 {
+		return stem
+	}
+	return w
+}
+
+func porterStep5(w []byte) []byte {
+	if hasSuffix(w, "e") {
+		stem := w[:len(w)-1]
+		m := measure(stem)
+		if m > 1 || (m == 1 && !endsCVC(stem)) {
+			w = stem
+		}
+	}
+	if measure(w) > 1 && endsDoubleConsonant(w) && w[len(w)-1] == 'l' {
+		w = w[:len(w)-1]
+	}
+	return w
+}
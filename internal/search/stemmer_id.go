@@ -0,0 +1,127 @@
+package search
+
+import (
+	"strings"
+)
+
+// stemIndonesian implements a dictionary-free Indonesian stemmer based on the
+// Tala algorithm: particles, possessive pronouns, first and second order
+// prefixes and derivational suffixes are removed while the word keeps at
+// least two syllables.
+func stemIndonesian(word string) string {
+	if syllables(word) <= 2 {
+		return word
+	}
+
+	word = trimSuffixOnce(word, []string{"kah", "lah", "tah", "pun"})
+	word = trimSuffixOnce(word, []string{"nya", "ku", "mu"})
+
+	if stem, ok := removeFirstOrderPrefix(word); ok {
+		word = stem
+		if stem, ok := removeSecondOrderPrefix(word); ok {
+			word = stem
+		}
+		word = removeDerivationalSuffix(word)
+		return word
+	}
+
+	word = removeDerivationalSuffix(word)
+	if stem, ok := removeSecondOrderPrefix(word); ok {
+		word = stem
+	}
+	return word
+}
+
+func isVowel(b byte) bool {
+	switch b {
+	case 'a', 'e', 'i', 'o', 'u':
+		return true
+	}
+	return false
+}
+
+func syllables(word string) int {
+	n := 0
+	for i := 0; i < len(word); i++ {
+		if isVowel(word[i]) && (i == 0 || !isVowel(word[i-1])) {
+			n++
+		}
+	}
+	return n
+}
+
+func trimSuffixOnce(word string, suffixes []string) string {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && syllables(word[:len(word)-len(suffix)]) >= 2 {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
+
+// First order prefixes with the letter that the nasal replaced, if any:
+// "menulis" -> "tulis", "memakai" -> "pakai", "menyapu" -> "sapu".
+func removeFirstOrderPrefix(word string) (string, bool) {
+	type rule struct {
+		prefix      string
+		replacement string
+		beforeVowel bool
+	}
+	rules := []rule{
+		{"meng", "", false},
+		{"meny", "s", true},
+		{"men", "t", true},
+		{"men", "", false},
+		{"mem", "p", true},
+		{"mem", "", false},
+		{"me", "", false},
+		{"peng", "", false},
+		{"peny", "s", true},
+		{"pen", "t", true},
+		{"pen", "", false},
+		{"pem", "p", true},
+		{"pem", "", false},
+		{"di", "", false},
+		{"ter", "", false},
+		{"ke", "", false},
+	}
+
+	for _, r := range rules {
+		if !strings.HasPrefix(word, r.prefix) || len(word) <= len(r.prefix) {
+			continue
+		}
+		rest := word[len(r.prefix):]
+		if r.beforeVowel && !isVowel(rest[0]) {
+			continue
+		}
+		stem := r.replacement + rest
+		if syllables(stem) < 2 {
+			return word, false
+		}
+		return stem, true
+	}
+	return word, false
+}
+
+func removeSecondOrderPrefix(word string) (string, bool) {
+	// "bel-" and "pel-" only occur in these two common words
+	switch word {
+	case "belajar", "pelajar":
+		return "ajar", true
+	}
+	for _, prefix := range []string{"ber", "per", "be", "pe"} {
+		if strings.HasPrefix(word, prefix) && syllables(word[len(prefix):]) >= 2 {
+			return word[len(prefix):], true
+		}
+	}
+	return word, false
+}
+
+func removeDerivationalSuffix(word string) string {
+	for _, suffix := range []string{"kan", "an", "i"} {
+		if strings.HasSuffix(word, suffix) && syllables(word[:len(word)-len(suffix)]) >= 2 {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+var stopwords = map[string]bool{
+	// English
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "for": true, "from": true, "in": true, "is": true, "it": true, "of": true,
+	"on": true, "or": true, "that": true, "the": true, "to": true, "was": true, "with": true,
+	// Indonesian
+	"dan": true, "di": true, "ke": true, "dari": true, "yang": true, "untuk": true,
+	"dengan": true, "pada": true, "ini": true, "itu": true, "atau": true, "dalam": true,
+	"adalah": true, "sebagai": true, "oleh": true, "para": true, "sang": true,
+}
+
+// Fold lowercases text and strips diacritics, so "Café" matches "cafe".
+func Fold(text string) string {
+	if isASCII(text) {
+		return strings.ToLower(text)
+	}
+
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, text)
+	if err != nil {
+		folded = text
+	}
+	return strings.ToLower(folded)
+}
+
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Words splits text into folded words.
+func Words(text string) []string {
+	return strings.FieldsFunc(Fold(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// tokenize splits text into folded words, dropping stopwords.
+func tokenize(text string) []string {
+	words := Words(text)
+
+	tokens := words[:0]
+	for _, word := range words {
+		if !stopwords[word] {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// exactToken normalizes an identifier such as an ISBN to one token by
+// keeping only its letters and digits.
+func exactToken(text string) string {
+	var b strings.Builder
+	for _, r := range Fold(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
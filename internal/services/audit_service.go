@@ -0,0 +1,321 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// Nested relations are left out of the stored state; updated_at changes on
+// every save and is left out of the diff.
+var auditRelationFields = []string{"borrows", "user", "book", "contributors", "publisher_record", "classification", "subjects", "editions", "work", "assigned_book"}
+
+const auditTimestampField = "updated_at"
+
+// Personal data and borrower IDs are never stored in the state: entries
+// cannot change once hashed, and erasure and history detachment must leave
+// nothing behind. The diff still shows that such a field changed. Who acted
+// and from where is kept outside the hash instead, so it can be cleared.
+var auditPersonalFields = map[string][]string{
+	"user": {"name", "email", "phone", "address"},
+}
+
+var auditBorrowerFields = []string{"user_id"}
+
+const auditRedacted = "[redacted]"
+
+type AuditService interface {
+	Record(actor *models.Actor, action, entityType string, entityID uint, before, after interface{})
+	Search(spec *query.Spec) ([]models.AuditLog, *query.Page, error)
+	Verify() (*models.AuditVerification, error)
+	ForgetActor(userID uint) error
+	ForgetMemberActorsBefore(cutoff time.Time) (int64, error)
+}
+
+type auditService struct {
+	auditRepo repositories.AuditRepository
+	mu        sync.Mutex
+}
+
+func NewAuditService(auditRepo repositories.AuditRepository) AuditService {
+	return &auditService{
+		auditRepo: auditRepo,
+	}
+}
+
+// Record appends an entry to the audit log. Failures are logged rather than
+// returned because the change being audited has already been committed.
+func (s *auditService) Record(actor *models.Actor, action, entityType string, entityID uint, before, after interface{}) {
+	if err := s.record(actor, action, entityType, entityID, before, after); err != nil {
+		log.Printf("audit: failed to record %s on %s %d: %v", action, entityType, entityID, err)
+	}
+}
+
+func (s *auditService) record(actor *models.Actor, action, entityType string, entityID uint, before, after interface{}) error {
+	beforeJSON, err := marshalAuditState(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshalAuditState(after)
+	if err != nil {
+		return err
+	}
+	changes, err := diffAuditStates(beforeJSON, afterJSON)
+	if err != nil {
+		return err
+	}
+	redacted := append([]string{}, auditBorrowerFields...)
+	redacted = append(redacted, auditPersonalFields[entityType]...)
+	if beforeJSON, err = redactAuditState(beforeJSON, redacted); err != nil {
+		return err
+	}
+	if afterJSON, err = redactAuditState(afterJSON, redacted); err != nil {
+		return err
+	}
+	if changes, err = redactAuditChanges(changes, redacted); err != nil {
+		return err
+	}
+
+	entry := &models.AuditLog{
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		Changes:    changes,
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if actor != nil {
+		if actor.UserID != 0 {
+			actorID := actor.UserID
+			entry.ActorID = &actorID
+		}
+		entry.ActorRole = actor.Role
+		entry.IP = actor.IP
+		entry.RequestID = actor.RequestID
+	}
+
+	// Appends are serialized so each entry links to the one written before it
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last, err := s.auditRepo.GetLast()
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if last != nil {
+		entry.PrevHash = last.Hash
+	}
+	entry.Hash = hashAuditEntry(entry)
+
+	return s.auditRepo.Create(entry)
+}
+
+// ForgetActor clears the IP and request ID of a user's entries and, where
+// they acted as a member, their ID, when the user is erased.
+func (s *auditService) ForgetActor(userID uint) error {
+	return s.auditRepo.ClearActor(userID)
+}
+
+// ForgetMemberActorsBefore unlinks old entries from members who opted out
+// of keeping their reading history, like their returned loans.
+func (s *auditService) ForgetMemberActorsBefore(cutoff time.Time) (int64, error) {
+	return s.auditRepo.ClearMemberActorsBefore(cutoff)
+}
+
+func (s *auditService) Search(spec *query.Spec) ([]models.AuditLog, *query.Page, error) {
+	return s.auditRepo.Search(spec)
+}
+
+// Verify walks the whole log in insertion order and recomputes every hash.
+func (s *auditService) Verify() (*models.AuditVerification, error) {
+	result := &models.AuditVerification{Valid: true}
+	prevHash := ""
+	var lastID uint
+
+	for {
+		entries, err := s.auditRepo.GetBatchAfter(lastID, 1000)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		for _, entry := range entries {
+			if entry.PrevHash != prevHash || entry.Hash != hashAuditEntry(&entry) {
+				brokenAt := entry.ID
+				result.Valid = false
+				result.BrokenAt = &brokenAt
+				return result, nil
+			}
+			prevHash = entry.Hash
+			lastID = entry.ID
+			result.Checked++
+		}
+	}
+
+	return result, nil
+}
+
+// hashAuditEntry covers everything but the request context: the IP, the
+// request ID and the ID of a member, which is a borrower ID as well, stay
+// out so they can be cleared when the reader is forgotten.
+func hashAuditEntry(entry *models.AuditLog) string {
+	actorID := ""
+	if entry.ActorID != nil && entry.ActorRole != "member" {
+		actorID = strconv.FormatUint(uint64(*entry.ActorID), 10)
+	}
+
+	payload := strings.Join([]string{
+		entry.PrevHash,
+		actorID,
+		entry.ActorRole,
+		entry.Action,
+		entry.EntityType,
+		strconv.FormatUint(uint64(entry.EntityID), 10),
+		entry.Before,
+		entry.After,
+		entry.Changes,
+		entry.CreatedAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+	}, "\x1f")
+
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// snapshot captures the state of an entity before it is modified in place.
+func snapshot(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func marshalAuditState(v interface{}) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, ok := v.(json.RawMessage)
+	if !ok {
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// Not an object, store it as is
+		return string(data), nil
+	}
+	for _, field := range auditRelationFields {
+		delete(fields, field)
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// redactAuditState replaces the values of the given fields. States that are
+// not objects are left alone.
+func redactAuditState(state string, redacted []string) (string, error) {
+	fields := map[string]interface{}{}
+	if state == "" || json.Unmarshal([]byte(state), &fields) != nil {
+		return state, nil
+	}
+	for _, field := range redacted {
+		if value, ok := fields[field]; ok && value != nil {
+			fields[field] = auditRedacted
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// redactAuditChanges replaces both sides of changes to the given fields.
+func redactAuditChanges(changes string, redacted []string) (string, error) {
+	fields := map[string]map[string]interface{}{}
+	if err := json.Unmarshal([]byte(changes), &fields); err != nil {
+		return "", err
+	}
+	for _, field := range redacted {
+		change, ok := fields[field]
+		if !ok {
+			continue
+		}
+		for side, value := range change {
+			if value != nil {
+				change[side] = auditRedacted
+			}
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// diffAuditStates returns the changed top-level fields as
+// {"field": {"from": ..., "to": ...}}.
+func diffAuditStates(before, after string) (string, error) {
+	beforeFields := map[string]interface{}{}
+	afterFields := map[string]interface{}{}
+	if before != "" {
+		if err := json.Unmarshal([]byte(before), &beforeFields); err != nil {
+			return "", err
+		}
+	}
+	if after != "" {
+		if err := json.Unmarshal([]byte(after), &afterFields); err != nil {
+			return "", err
+		}
+	}
+
+	changes := map[string]map[string]interface{}{}
+	for field, value := range afterFields {
+		if field == auditTimestampField {
+			continue
+		}
+		if old, ok := beforeFields[field]; !ok || !reflect.DeepEqual(old, value) {
+			changes[field] = map[string]interface{}{"from": beforeFields[field], "to": value}
+		}
+	}
+	for field, old := range beforeFields {
+		if field == auditTimestampField {
+			continue
+		}
+		if _, ok := afterFields[field]; !ok {
+			changes[field] = map[string]interface{}{"from": old, "to": nil}
+		}
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
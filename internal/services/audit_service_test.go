@@ -0,0 +1,211 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// memoryAuditRepository keeps entries in a slice.
+type memoryAuditRepository struct {
+	entries []models.AuditLog
+}
+
+func (r *memoryAuditRepository) Create(entry *models.AuditLog) error {
+	entry.ID = uint(len(r.entries) + 1)
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *memoryAuditRepository) GetLast() (*models.AuditLog, error) {
+	if len(r.entries) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	last := r.entries[len(r.entries)-1]
+	return &last, nil
+}
+
+func (r *memoryAuditRepository) Search(spec *query.Spec) ([]models.AuditLog, *query.Page, error) {
+	return r.entries, &query.Page{Total: int64(len(r.entries))}, nil
+}
+
+func (r *memoryAuditRepository) GetBatchAfter(afterID uint, limit int) ([]models.AuditLog, error) {
+	var batch []models.AuditLog
+	for _, entry := range r.entries {
+		if entry.ID > afterID && len(batch) < limit {
+			batch = append(batch, entry)
+		}
+	}
+	return batch, nil
+}
+
+func (r *memoryAuditRepository) ClearActor(userID uint) error {
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.ActorID == nil || *entry.ActorID != userID {
+			continue
+		}
+		entry.IP, entry.RequestID = "", ""
+		if entry.ActorRole == "member" {
+			entry.ActorID = nil
+		}
+	}
+	return nil
+}
+
+// ClearMemberActorsBefore treats every member as having opted out.
+func (r *memoryAuditRepository) ClearMemberActorsBefore(cutoff time.Time) (int64, error) {
+	var cleared int64
+	for i := range r.entries {
+		entry := &r.entries[i]
+		if entry.ActorRole == "member" && entry.ActorID != nil && entry.CreatedAt.Before(cutoff) {
+			entry.ActorID, entry.IP, entry.RequestID = nil, "", ""
+			cleared++
+		}
+	}
+	return cleared, nil
+}
+
+func TestAuditRedactsPersonalData(t *testing.T) {
+	repo := &memoryAuditRepository{}
+	service := NewAuditService(repo)
+	staff := &models.Actor{UserID: 1, Role: "admin", IP: "10.0.0.1"}
+
+	user := &models.User{ID: 42, Name: "Siti Rahma", Email: "siti@example.com", Phone: "081234567890", Address: "Jl. Merdeka 1", Role: "member", IsActive: true}
+	before := snapshot(user)
+	anonymizeUser(user)
+	service.Record(staff, "user.anonymize", "user", user.ID, before, user)
+
+	borrowerID := uint(42)
+	service.Record(staff, "borrow.create", "borrow", 9, nil, &models.Borrow{ID: 9, UserID: &borrowerID, BookID: 3})
+
+	if len(repo.entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(repo.entries))
+	}
+	for _, entry := range repo.entries {
+		stored := entry.Before + entry.After + entry.Changes
+		for _, personal := range []string{"Siti", "siti@example.com", "081234567890", "Merdeka", "Erased User", "anonymized.invalid", `"user_id":42`} {
+			if strings.Contains(stored, personal) {
+				t.Errorf("%s entry stores %q: %s", entry.Action, personal, stored)
+			}
+		}
+	}
+
+	// The diff still says which personal fields the erasure changed
+	var changes map[string]map[string]interface{}
+	if err := json.Unmarshal([]byte(repo.entries[0].Changes), &changes); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"name", "email", "phone", "address"} {
+		if changes[field]["from"] != auditRedacted || changes[field]["to"] != auditRedacted {
+			t.Errorf("change to %s = %v", field, changes[field])
+		}
+	}
+	if _, ok := changes["is_active"]; !ok {
+		t.Error("is_active change missing")
+	}
+
+	var after map[string]interface{}
+	if err := json.Unmarshal([]byte(repo.entries[1].After), &after); err != nil {
+		t.Fatal(err)
+	}
+	if after["user_id"] != auditRedacted || after["book_id"] != float64(3) {
+		t.Errorf("borrow state user_id %v, book_id %v", after["user_id"], after["book_id"])
+	}
+
+	result, err := service.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != 2 {
+		t.Errorf("verify = %+v", result)
+	}
+}
+
+func TestAuditMemberActor(t *testing.T) {
+	repo := &memoryAuditRepository{}
+	service := NewAuditService(repo)
+
+	service.Record(&models.Actor{UserID: 42, Role: "member", IP: "10.0.0.9", RequestID: "req-1"}, "hold.create", "hold", 5, nil, map[string]interface{}{"id": 5, "book_id": 3})
+	service.Record(&models.Actor{UserID: 2, Role: "librarian", IP: "10.0.0.2"}, "book.update", "book", 3, map[string]interface{}{"title": "Lama"}, map[string]interface{}{"title": "Baru"})
+
+	if repo.entries[0].ActorID == nil || *repo.entries[0].ActorID != 42 || repo.entries[0].ActorRole != "member" || repo.entries[0].RequestID != "req-1" {
+		t.Errorf("member entry actor %v, role %q, request %q", repo.entries[0].ActorID, repo.entries[0].ActorRole, repo.entries[0].RequestID)
+	}
+	if repo.entries[1].ActorID == nil || *repo.entries[1].ActorID != 2 {
+		t.Errorf("staff entry actor %v, want 2", repo.entries[1].ActorID)
+	}
+	if repo.entries[1].PrevHash != repo.entries[0].Hash {
+		t.Error("second entry does not link to the first")
+	}
+	if repo.entries[1].Changes != `{"title":{"from":"Lama","to":"Baru"}}` {
+		t.Errorf("changes = %s", repo.entries[1].Changes)
+	}
+
+	// Forgetting the member and the librarian's IP leaves the chain intact
+	if err := service.ForgetActor(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ForgetActor(2); err != nil {
+		t.Fatal(err)
+	}
+	if entry := repo.entries[0]; entry.ActorID != nil || entry.IP != "" || entry.RequestID != "" {
+		t.Errorf("forgotten member entry actor %v, IP %q, request %q", entry.ActorID, entry.IP, entry.RequestID)
+	}
+	if entry := repo.entries[1]; entry.ActorID == nil || *entry.ActorID != 2 || entry.IP != "" {
+		t.Errorf("forgotten staff entry actor %v, IP %q", entry.ActorID, entry.IP)
+	}
+	result, err := service.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != 2 {
+		t.Errorf("verify after forgetting = %+v", result)
+	}
+
+	// Editing an entry, or who on staff made it, breaks the chain from there on
+	repo.entries[0].After = `{"book_id":4,"id":5}`
+	if result, err = service.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.BrokenAt == nil || *result.BrokenAt != 1 {
+		t.Errorf("verify after tampering = %+v", result)
+	}
+	repo.entries[0].Hash = hashAuditEntry(&repo.entries[0])
+	repo.entries[1].PrevHash = repo.entries[0].Hash
+	staffID := uint(3)
+	repo.entries[1].ActorID = &staffID
+	if result, err = service.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.BrokenAt == nil || *result.BrokenAt != 2 {
+		t.Errorf("verify after changing the staff actor = %+v", result)
+	}
+}
+
+func TestAuditForgetMemberActorsBefore(t *testing.T) {
+	repo := &memoryAuditRepository{}
+	service := NewAuditService(repo)
+
+	service.Record(&models.Actor{UserID: 42, Role: "member", IP: "10.0.0.9"}, "hold.create", "hold", 5, nil, map[string]interface{}{"id": 5})
+	service.Record(&models.Actor{UserID: 2, Role: "librarian", IP: "10.0.0.2"}, "hold.cancel", "hold", 5, nil, map[string]interface{}{"id": 5})
+
+	forgotten, err := service.ForgetMemberActorsBefore(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if forgotten != 1 || repo.entries[0].ActorID != nil || repo.entries[0].IP != "" {
+		t.Errorf("forgot %d, member entry actor %v, IP %q", forgotten, repo.entries[0].ActorID, repo.entries[0].IP)
+	}
+	if repo.entries[1].ActorID == nil || repo.entries[1].IP != "10.0.0.2" {
+		t.Errorf("staff entry actor %v, IP %q", repo.entries[1].ActorID, repo.entries[1].IP)
+	}
+	if result, err := service.Verify(); err != nil || !result.Valid {
+		t.Errorf("verify = %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type AuthorService interface {
+	GetAllAuthors(spec *query.Spec) ([]models.Author, *query.Page, error)
+	GetAuthorByID(id uint) (*models.Author, error)
+	GetAuthorBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	UpdateAuthor(actor *models.Actor, id uint, req *models.UpdateAuthorRequest) (*models.Author, error)
+	MergeAuthors(actor *models.Actor, req *models.MergeRequest) (*models.Author, error)
+	FindDuplicates() ([]models.AuthorDuplicates, error)
+}
+
+type authorService struct {
+	authorRepo   repositories.AuthorRepository
+	bookRepo     repositories.BookRepository
+	bookService  BookService
+	auditService AuditService
+}
+
+func NewAuthorService(
+	authorRepo repositories.AuthorRepository,
+	bookRepo repositories.BookRepository,
+	bookService BookService,
+	auditService AuditService,
+) AuthorService {
+	return &authorService{
+		authorRepo:   authorRepo,
+		bookRepo:     bookRepo,
+		bookService:  bookService,
+		auditService: auditService,
+	}
+}
+
+func (s *authorService) GetAllAuthors(spec *query.Spec) ([]models.Author, *query.Page, error) {
+	return s.authorRepo.GetAll(spec)
+}
+
+func (s *authorService) GetAuthorByID(id uint) (*models.Author, error) {
+	author, err := s.authorRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("author not found")
+		}
+		return nil, err
+	}
+	return author, nil
+}
+
+// GetAuthorBooks lists the books an author is credited on in any role.
+func (s *authorService) GetAuthorBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	if _, err := s.GetAuthorByID(id); err != nil {
+		return nil, nil, err
+	}
+	return s.bookRepo.GetByAuthor(id, spec)
+}
+
+func (s *authorService) UpdateAuthor(actor *models.Actor, id uint, req *models.UpdateAuthorRequest) (*models.Author, error) {
+	author, err := s.GetAuthorByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(author)
+	renamed := false
+
+	if name := strings.Join(strings.Fields(req.Name), " "); name != "" && name != author.Name {
+		// A new spelling of the same name keeps the key; a different name
+		// must not take over another author's key
+		if key := authorKey(name); key != author.NameKey {
+			other, err := s.authorRepo.GetByNameKey(key)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if other != nil && other.ID != author.ID {
+				return nil, errors.New("another author has this name, merge them instead")
+			}
+			author.NameKey = key
+		}
+		author.Name = name
+		author.SortName = sortName(name)
+		renamed = true
+	}
+	if req.SortName != "" {
+		author.SortName = req.SortName
+	}
+	if req.Biography != nil {
+		author.Biography = *req.Biography
+	}
+
+	if err := s.authorRepo.Update(author); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "author.update", "author", author.ID, before, author)
+	if renamed {
+		s.refreshBooks(author.ID)
+	}
+
+	return author, nil
+}
+
+// MergeAuthors moves the books of the source authors to the target and
+// deletes the sources. Their names keep resolving to the target.
+func (s *authorService) MergeAuthors(actor *models.Actor, req *models.MergeRequest) (*models.Author, error) {
+	if req.TargetID == 0 || len(req.SourceIDs) == 0 {
+		return nil, errors.New("target_id and source_ids are required")
+	}
+	if slices.Contains(req.SourceIDs, req.TargetID) {
+		return nil, errors.New("cannot merge an author into itself")
+	}
+
+	target, err := s.GetAuthorByID(req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]*models.Author, 0, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		source, err := s.GetAuthorByID(id)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	bookIDs, err := s.authorRepo.Merge(target.ID, req.SourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		s.auditService.Record(actor, "author.merge", "author", source.ID, source, target)
+	}
+	s.bookService.RefreshBooks(bookIDs)
+
+	return s.GetAuthorByID(target.ID)
+}
+
+// FindDuplicates groups authors sharing a surname and first initial whose
+// given names do not contradict each other, e.g. "P. A. Toer" and
+// "Pramoedya Ananta Toer".
+func (s *authorService) FindDuplicates() ([]models.AuthorDuplicates, error) {
+	authors, _, err := s.authorRepo.GetAll(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]models.Author)
+	for _, author := range authors {
+		if len(strings.Fields(author.Name)) < 2 {
+			continue
+		}
+		key := surnameKey(author.Name)
+		groups[key] = append(groups[key], author)
+	}
+
+	duplicates := []models.AuthorDuplicates{}
+	for key, group := range groups {
+		if len(group) < 2 || !initialsAgree(group) {
+			continue
+		}
+		duplicates = append(duplicates, models.AuthorDuplicates{Key: key, Authors: group})
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].Key < duplicates[j].Key
+	})
+	return duplicates, nil
+}
+
+func (s *authorService) refreshBooks(authorID uint) {
+	books, _, err := s.bookRepo.GetByAuthor(authorID, nil)
+	if err != nil {
+		return
+	}
+	ids := make([]uint, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+	}
+	s.bookService.RefreshBooks(ids)
+}
+
+// initialsAgree reports whether the given names in a group could belong to
+// one person: at each position the names start with the same letter.
+func initialsAgree(authors []models.Author) bool {
+	first := givenInitials(authors[0].Name)
+	for _, author := range authors[1:] {
+		initials := givenInitials(author.Name)
+		for i := 0; i < len(initials) && i < len(first); i++ {
+			if initials[i] != first[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
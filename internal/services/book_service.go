@@ -2,34 +2,86 @@ package services
 
 import (
 	"errors"
+	"log"
+	"sort"
+	"strings"
 
 	"github.com/yooerizkilab/library-system/internal/models"
 	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/search"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"gorm.io/gorm"
 )
 
+// Upper bound on ranked hits considered for one search request
+const maxSearchHits = 1000
+
 type BookService interface {
-	CreateBook(req *models.CreateBookRequest) (*models.Book, error)
-	GetAllBooks() ([]models.Book, error)
+	CreateBook(actor *models.Actor, req *models.CreateBookRequest) (*models.Book, error)
+	GetAllBooks(spec *query.Spec) ([]models.Book, *query.Page, error)
 	GetBookByID(id uint) (*models.Book, error)
-	UpdateBook(id uint, req *models.UpdateBookRequest) (*models.Book, error)
-	DeleteBook(id uint) error
-	SearchBooks(query string) ([]models.Book, error)
-	GetBooksByCategory(category string) ([]models.Book, error)
-	GetAvailableBooks() ([]models.Book, error)
+	GetBookByISBN(code string) (*models.Book, error)
+	UpdateBook(actor *models.Actor, id uint, req *models.UpdateBookRequest) (*models.Book, error)
+	DeleteBook(actor *models.Actor, id uint) error
+	AddStock(actor *models.Actor, id uint, quantity int) (*models.Book, error)
+	SearchBooks(q string, spec *query.Spec) ([]models.Book, *query.Page, *models.BookFacets, error)
+	SearchBookIDs(q string) ([]uint, error)
+	GetBooksByCategory(category string, spec *query.Spec) ([]models.Book, *query.Page, error)
+	GetAvailableBooks(spec *query.Spec) ([]models.Book, *query.Page, error)
+	SuggestBooks(q string, limit int) (*search.Suggestions, error)
+	RebuildSearchIndex() (int, error)
+	CanonicalizeISBNs() (int, error)
+	MigrateContributors() (int, error)
+	MigrateCategories() (int, error)
+	MigrateWorks() (int, error)
+	RefreshBooks(bookIDs []uint)
+	WarmSearchIndex(snapshotPath string) error
 }
 
 type bookService struct {
-	bookRepo repositories.BookRepository
+	bookRepo           repositories.BookRepository
+	authorRepo         repositories.AuthorRepository
+	publisherRepo      repositories.PublisherRepository
+	classificationRepo repositories.ClassificationRepository
+	subjectRepo        repositories.SubjectRepository
+	workRepo           repositories.WorkRepository
+	auditService       AuditService
+	searchIndex        search.SearchIndex
+	suggester          search.Suggester
 }
 
-func NewBookService(bookRepo repositories.BookRepository) BookService {
+func NewBookService(
+	bookRepo repositories.BookRepository,
+	authorRepo repositories.AuthorRepository,
+	publisherRepo repositories.PublisherRepository,
+	classificationRepo repositories.ClassificationRepository,
+	subjectRepo repositories.SubjectRepository,
+	workRepo repositories.WorkRepository,
+	auditService AuditService,
+	searchIndex search.SearchIndex,
+	suggester search.Suggester,
+) BookService {
 	return &bookService{
-		bookRepo: bookRepo,
+		bookRepo:           bookRepo,
+		authorRepo:         authorRepo,
+		publisherRepo:      publisherRepo,
+		classificationRepo: classificationRepo,
+		subjectRepo:        subjectRepo,
+		workRepo:           workRepo,
+		auditService:       auditService,
+		searchIndex:        searchIndex,
+		suggester:          suggester,
 	}
 }
 
-func (s *bookService) CreateBook(req *models.CreateBookRequest) (*models.Book, error) {
+func (s *bookService) CreateBook(actor *models.Actor, req *models.CreateBookRequest) (*models.Book, error) {
+	canonical, err := isbn.Normalize(req.ISBN)
+	if err != nil {
+		return nil, err
+	}
+	req.ISBN = canonical
+
 	// Check if ISBN already exists
 	existingBook, err := s.bookRepo.GetByISBN(req.ISBN)
 	if err == nil && existingBook != nil {
@@ -43,10 +95,30 @@ func (s *bookService) CreateBook(req *models.CreateBookRequest) (*models.Book, e
 	if req.Stock == 0 {
 		req.Stock = 1
 	}
+	if req.ItemType == "" {
+		req.ItemType = models.ItemPrint
+	}
+
+	contributors, author, err := s.resolveContributors(req.Contributors, req.Author)
+	if err != nil {
+		return nil, err
+	}
+	publisher, err := s.resolvePublisher(req.Publisher)
+	if err != nil {
+		return nil, err
+	}
+	classification, err := s.resolveClassification(req.ClassificationID, req.ClassNumber, req.Category)
+	if err != nil {
+		return nil, err
+	}
+	subjects, err := s.resolveSubjects(req.Subjects)
+	if err != nil {
+		return nil, err
+	}
 
 	book := &models.Book{
 		Title:       req.Title,
-		Author:      req.Author,
+		Author:      author,
 		ISBN:        req.ISBN,
 		Publisher:   req.Publisher,
 		Category:    req.Category,
@@ -57,19 +129,52 @@ func (s *bookService) CreateBook(req *models.CreateBookRequest) (*models.Book, e
 		Available:   req.Stock, // Initially all books are available
 		Description: req.Description,
 		Location:    req.Location,
+		ItemType:    req.ItemType,
 		IsActive:    true,
 	}
 
+	if publisher != nil {
+		book.Publisher = publisher.Name
+		book.PublisherID = &publisher.ID
+	}
+	if classification != nil {
+		book.Category = classification.Label
+		book.ClassificationID = &classification.ID
+	}
+	book.Contributors = contributors
+	book.CallNumber = req.CallNumber
+	if book.CallNumber == "" {
+		book.CallNumber = callNumber(classification, book)
+	}
+
+	work, err := s.resolveWork(req.WorkID, book)
+	if err != nil {
+		return nil, err
+	}
+	book.WorkID = &work.ID
+
 	err = s.bookRepo.Create(book)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.authorRepo.ReplaceContributors(book.ID, contributors); err != nil {
+		return nil, err
+	}
+	if err := s.subjectRepo.ReplaceBookSubjects(book.ID, subjects); err != nil {
+		return nil, err
+	}
+	book.PublisherRecord = publisher
+	book.Classification = classification
+	book.Subjects = subjects
+
+	s.auditService.Record(actor, "book.create", "book", book.ID, nil, book)
+	s.syncSearchIndex(book)
 
 	return book, nil
 }
 
-func (s *bookService) GetAllBooks() ([]models.Book, error) {
-	return s.bookRepo.GetAll()
+func (s *bookService) GetAllBooks(spec *query.Spec) ([]models.Book, *query.Page, error) {
+	return s.bookRepo.GetAll(spec)
 }
 
 func (s *bookService) GetBookByID(id uint) (*models.Book, error) {
@@ -83,7 +188,23 @@ func (s *bookService) GetBookByID(id uint) (*models.Book, error) {
 	return book, nil
 }
 
-func (s *bookService) UpdateBook(id uint, req *models.UpdateBookRequest) (*models.Book, error) {
+// GetBookByISBN finds a book by its ISBN-10, ISBN-13 or scanned bar code.
+func (s *bookService) GetBookByISBN(code string) (*models.Book, error) {
+	if !isbn.Valid(code) {
+		return nil, errors.New("invalid ISBN")
+	}
+
+	book, err := s.bookRepo.GetByISBN(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book not found")
+		}
+		return nil, err
+	}
+	return book, nil
+}
+
+func (s *bookService) UpdateBook(actor *models.Actor, id uint, req *models.UpdateBookRequest) (*models.Book, error) {
 	book, err := s.bookRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -92,6 +213,14 @@ func (s *bookService) UpdateBook(id uint, req *models.UpdateBookRequest) (*model
 		return nil, err
 	}
 
+	if req.ISBN != "" {
+		canonical, err := isbn.Normalize(req.ISBN)
+		if err != nil {
+			return nil, err
+		}
+		req.ISBN = canonical
+	}
+
 	// Check if ISBN is being changed and if it already exists
 	if req.ISBN != "" && req.ISBN != book.ISBN {
 		existingBook, err := s.bookRepo.GetByISBN(req.ISBN)
@@ -100,6 +229,9 @@ func (s *bookService) UpdateBook(id uint, req *models.UpdateBookRequest) (*model
 		}
 	}
 
+	before := snapshot(book)
+	generatedCallNumber := callNumber(book.Classification, book)
+
 	// Store original stock to calculate available books
 	originalStock := book.Stock
 	borrowedBooks := originalStock - book.Available
@@ -108,17 +240,53 @@ func (s *bookService) UpdateBook(id uint, req *models.UpdateBookRequest) (*model
 	if req.Title != "" {
 		book.Title = req.Title
 	}
-	if req.Author != "" {
-		book.Author = req.Author
+	creditsChanged := len(req.Contributors) > 0 || (req.Author != "" && req.Author != book.Author)
+	if creditsChanged {
+		book.Contributors, book.Author, err = s.resolveContributors(req.Contributors, req.Author)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if req.ISBN != "" {
 		book.ISBN = req.ISBN
 	}
-	if req.Publisher != "" {
-		book.Publisher = req.Publisher
+	if req.Publisher != "" && req.Publisher != book.Publisher {
+		publisher, err := s.resolvePublisher(req.Publisher)
+		if err != nil {
+			return nil, err
+		}
+		book.Publisher = publisher.Name
+		book.PublisherID = &publisher.ID
+		book.PublisherRecord = publisher
 	}
-	if req.Category != "" {
-		book.Category = req.Category
+	if req.ClassificationID != nil && *req.ClassificationID == 0 {
+		book.ClassificationID = nil
+		book.Classification = nil
+	} else if req.ClassificationID != nil || req.ClassNumber != "" ||
+		(req.Category != "" && !strings.EqualFold(req.Category, book.Category)) {
+		classification, err := s.resolveClassification(req.ClassificationID, req.ClassNumber, req.Category)
+		if err != nil {
+			return nil, err
+		}
+		book.Category = classification.Label
+		book.ClassificationID = &classification.ID
+		book.Classification = classification
+	}
+	var previousWorkID *uint
+	if req.WorkID != nil && (book.WorkID == nil || *req.WorkID != *book.WorkID) {
+		work, err := s.resolveWork(req.WorkID, book)
+		if err != nil {
+			return nil, err
+		}
+		previousWorkID = book.WorkID
+		book.WorkID = &work.ID
+	}
+	var subjects []models.BookSubject
+	if req.Subjects != nil {
+		subjects, err = s.resolveSubjects(req.Subjects)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if req.Language != "" {
 		book.Language = req.Language
@@ -126,6 +294,9 @@ func (s *bookService) UpdateBook(id uint, req *models.UpdateBookRequest) (*model
 	if req.Pages > 0 {
 		book.Pages = req.Pages
 	}
+	if req.ItemType != "" {
+		book.ItemType = req.ItemType
+	}
 	if req.PublishYear > 0 {
 		book.PublishYear = req.PublishYear
 	}
@@ -146,16 +317,41 @@ func (s *bookService) UpdateBook(id uint, req *models.UpdateBookRequest) (*model
 	if req.IsActive != nil {
 		book.IsActive = *req.IsActive
 	}
+	// Generated call numbers follow the book; ones set by hand are kept
+	if req.CallNumber != "" {
+		book.CallNumber = req.CallNumber
+	} else if book.CallNumber == "" || book.CallNumber == generatedCallNumber {
+		book.CallNumber = callNumber(book.Classification, book)
+	}
 
 	err = s.bookRepo.Update(book)
 	if err != nil {
 		return nil, err
 	}
+	if creditsChanged {
+		if err := s.authorRepo.ReplaceContributors(book.ID, book.Contributors); err != nil {
+			return nil, err
+		}
+	}
+	if req.Subjects != nil {
+		if err := s.subjectRepo.ReplaceBookSubjects(book.ID, subjects); err != nil {
+			return nil, err
+		}
+		book.Subjects = subjects
+	}
+	if previousWorkID != nil {
+		if err := s.workRepo.DeleteOrphans([]uint{*previousWorkID}); err != nil {
+			log.Printf("works: failed to delete work %d: %v", *previousWorkID, err)
+		}
+	}
+
+	s.auditService.Record(actor, "book.update", "book", book.ID, before, book)
+	s.syncSearchIndex(book)
 
 	return book, nil
 }
 
-func (s *bookService) DeleteBook(id uint) error {
+func (s *bookService) DeleteBook(actor *models.Actor, id uint) error {
 	book, err := s.bookRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -173,17 +369,681 @@ func (s *bookService) DeleteBook(id uint) error {
 		}
 	}
 
-	return s.bookRepo.Delete(id)
+	if err := s.bookRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, "book.delete", "book", book.ID, book, nil)
+	s.unindexBook(book.ID)
+
+	return nil
+}
+
+// AddStock adds newly received copies of a book, which are available
+// straight away.
+func (s *bookService) AddStock(actor *models.Actor, id uint, quantity int) (*models.Book, error) {
+	if quantity < 1 {
+		return nil, errors.New("quantity must be at least 1")
+	}
+
+	book, err := s.bookRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book not found")
+		}
+		return nil, err
+	}
+
+	before := snapshot(book)
+	if err := s.bookRepo.AddStock(book.ID, quantity); err != nil {
+		return nil, err
+	}
+	if book, err = s.bookRepo.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "book.update", "book", book.ID, before, book)
+
+	return book, nil
 }
 
-func (s *bookService) SearchBooks(query string) ([]models.Book, error) {
-	return s.bookRepo.Search(query)
+// SearchBooks ranks books by relevance and counts facets over the matches.
+// Filters in spec narrow the matches; an explicit sort replaces relevance
+// order. Each work is listed once, as its best matching edition with the
+// other matching editions nested in it, except when paging by cursor,
+// which lists every edition.
+func (s *bookService) SearchBooks(q string, spec *query.Spec) ([]models.Book, *query.Page, *models.BookFacets, error) {
+	// A scanned or hyphenated ISBN finds the book by its stored form
+	if canonical, err := isbn.Normalize(q); err == nil {
+		q = canonical
+	}
+
+	hits, err := s.searchIndex.Search(q, maxSearchHits)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ids := make([]uint, len(hits))
+	rank := make(map[uint]int, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+		rank[hit.ID] = i
+	}
+
+	facets, err := s.bookRepo.Facets(ids, spec)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(ids) == 0 {
+		books, page, err := query.Slice([]models.Book{}, spec)
+		return books, page, facets, err
+	}
+
+	sorted := spec != nil && len(spec.Sort) > 0
+	if sorted && spec.Cursor != nil {
+		books, page, err := s.bookRepo.GetByIDs(ids, spec)
+		return books, page, facets, err
+	}
+
+	books, _, err := s.bookRepo.GetByIDs(ids, spec.Unpaged())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !sorted {
+		sort.SliceStable(books, func(i, j int) bool {
+			return rank[books[i].ID] < rank[books[j].ID]
+		})
+	}
+
+	books, page, err := query.Slice(groupEditions(books), spec)
+	return books, page, facets, err
 }
 
-func (s *bookService) GetBooksByCategory(category string) ([]models.Book, error) {
-	return s.bookRepo.GetByCategory(category)
+// SearchBookIDs returns the IDs of the books matching q, best first.
+func (s *bookService) SearchBookIDs(q string) ([]uint, error) {
+	if canonical, err := isbn.Normalize(q); err == nil {
+		q = canonical
+	}
+
+	hits, err := s.searchIndex.Search(q, maxSearchHits)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids, nil
 }
 
-func (s *bookService) GetAvailableBooks() ([]models.Book, error) {
-	return s.bookRepo.GetAvailableBooks()
+// groupEditions keeps the first book of each work in order and nests the
+// later editions of that work in it.
+func groupEditions(books []models.Book) []models.Book {
+	grouped := make([]models.Book, 0, len(books))
+	position := make(map[uint]int)
+	for _, book := range books {
+		if book.WorkID == nil {
+			grouped = append(grouped, book)
+			continue
+		}
+		if i, ok := position[*book.WorkID]; ok {
+			grouped[i].Editions = append(grouped[i].Editions, book)
+			continue
+		}
+		position[*book.WorkID] = len(grouped)
+		grouped = append(grouped, book)
+	}
+	return grouped
+}
+
+// GetBooksByCategory lists the books under the classification with this
+// label or class number, including narrower classifications, or else the
+// books with this category ignoring case.
+func (s *bookService) GetBooksByCategory(category string, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	classification, err := s.classificationRepo.GetByLabel(category)
+	for _, scheme := range []string{models.SchemeDewey, models.SchemeUDC} {
+		if err == nil {
+			break
+		}
+		classification, err = s.classificationRepo.GetByNotation(scheme, category)
+	}
+	if err == nil {
+		return s.bookRepo.GetByClassification(classification, spec)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	return s.bookRepo.GetByCategory(category, spec)
+}
+
+func (s *bookService) GetAvailableBooks(spec *query.Spec) ([]models.Book, *query.Page, error) {
+	return s.bookRepo.GetAvailableBooks(spec)
+}
+
+// SuggestBooks completes a partially typed title, author or subject.
+func (s *bookService) SuggestBooks(q string, limit int) (*search.Suggestions, error) {
+	return s.suggester.Suggest(q, limit)
+}
+
+// RebuildSearchIndex reindexes every active book from the database.
+func (s *bookService) RebuildSearchIndex() (int, error) {
+	return s.rebuildSearch(true)
+}
+
+// WarmSearchIndex loads the index snapshot at snapshotPath when it is newer
+// than the last catalog change, and rebuilds the index otherwise.
+// Suggestions are always built from the database.
+func (s *bookService) WarmSearchIndex(snapshotPath string) error {
+	loaded, err := search.LoadSnapshot(s.searchIndex, snapshotPath)
+	if err != nil {
+		log.Printf("search: ignoring unreadable snapshot %s: %v", snapshotPath, err)
+	}
+
+	fresh := false
+	if loaded {
+		lastModified, err := s.bookRepo.LastModified()
+		if err != nil {
+			return err
+		}
+		fresh = !lastModified.After(s.searchIndex.BuiltAt())
+	}
+
+	_, err = s.rebuildSearch(!fresh)
+	return err
+}
+
+func (s *bookService) rebuildSearch(withIndex bool) (int, error) {
+	// The catalog comes with the subject headings suggestions are made of
+	books, err := s.bookRepo.GetCatalog()
+	if err != nil {
+		return 0, err
+	}
+
+	docs := make([]search.Document, 0, len(books))
+	suggestDocs := make([]search.Document, 0, len(books))
+	for i := range books {
+		docs = append(docs, bookDocument(&books[i]))
+		suggestDocs = append(suggestDocs, bookSuggestDocument(&books[i]))
+	}
+	if withIndex {
+		if err := s.searchIndex.Rebuild(docs); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.suggester.Rebuild(suggestDocs); err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
+// CanonicalizeISBNs rewrites ISBNs stored in another valid form as their
+// ISBN-13. ISBNs that are invalid, or whose ISBN-13 belongs to another book,
+// are left for staff to fix.
+func (s *bookService) CanonicalizeISBNs() (int, error) {
+	books, err := s.bookRepo.GetWithNonCanonicalISBN()
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for i := range books {
+		book := &books[i]
+		canonical, err := isbn.Normalize(book.ISBN)
+		if err != nil {
+			log.Printf("isbn: book %d has invalid ISBN %q: %v", book.ID, book.ISBN, err)
+			continue
+		}
+		if other, err := s.bookRepo.GetByISBN(canonical); err == nil && other.ID != book.ID {
+			log.Printf("isbn: book %d has the same ISBN as book %d", book.ID, other.ID)
+			continue
+		}
+
+		before := snapshot(book)
+		if err := s.bookRepo.UpdateISBN(book.ID, canonical); err != nil {
+			return updated, err
+		}
+		book.ISBN = canonical
+		s.auditService.Record(nil, "book.update", "book", book.ID, before, book)
+		updated++
+	}
+	return updated, nil
+}
+
+// Books linked per batch when migrating author and publisher strings
+const contributorMigrationBatch = 200
+
+// MigrateContributors links books that only have author or publisher
+// strings to author and publisher records, returning how many links it
+// made. The strings are kept as they
+// are, and books already linked are skipped, so it is safe to run on every
+// start.
+func (s *bookService) MigrateContributors() (int, error) {
+	linked := 0
+
+	var afterID uint
+	for {
+		books, err := s.bookRepo.GetWithoutContributors(afterID, contributorMigrationBatch)
+		if err != nil {
+			return linked, err
+		}
+		if len(books) == 0 {
+			break
+		}
+		for i := range books {
+			book := &books[i]
+			afterID = book.ID
+			contributors, _, err := s.resolveContributors(nil, book.Author)
+			if err != nil {
+				return linked, err
+			}
+			if len(contributors) == 0 {
+				continue
+			}
+			if err := s.authorRepo.ReplaceContributors(book.ID, contributors); err != nil {
+				return linked, err
+			}
+			linked++
+		}
+	}
+
+	afterID = 0
+	for {
+		books, err := s.bookRepo.GetWithoutPublisher(afterID, contributorMigrationBatch)
+		if err != nil || len(books) == 0 {
+			return linked, err
+		}
+		for i := range books {
+			book := &books[i]
+			afterID = book.ID
+			publisher, err := s.resolvePublisher(book.Publisher)
+			if err != nil {
+				return linked, err
+			}
+			if publisher == nil {
+				continue
+			}
+			if err := s.bookRepo.UpdateCredits(book.ID, book.Author, book.Publisher, &publisher.ID); err != nil {
+				return linked, err
+			}
+			linked++
+		}
+	}
+}
+
+// RefreshBooks rewrites the author, publisher and category strings of
+// books from their linked records, e.g. after authors were merged, and
+// reindexes them.
+func (s *bookService) RefreshBooks(bookIDs []uint) {
+	for _, id := range bookIDs {
+		book, err := s.bookRepo.GetByID(id)
+		if err != nil {
+			log.Printf("credits: failed to load book %d: %v", id, err)
+			continue
+		}
+
+		book.Author = creditLine(book.Contributors)
+		if book.PublisherRecord != nil {
+			book.Publisher = book.PublisherRecord.Name
+		}
+		if err := s.bookRepo.UpdateCredits(book.ID, book.Author, book.Publisher, book.PublisherID); err != nil {
+			log.Printf("credits: failed to update book %d: %v", id, err)
+			continue
+		}
+		if book.Classification != nil && book.Category != book.Classification.Label {
+			book.Category = book.Classification.Label
+			if err := s.bookRepo.UpdateClassification(book.ID, book.ClassificationID, book.Category, book.CallNumber); err != nil {
+				log.Printf("credits: failed to update book %d: %v", id, err)
+				continue
+			}
+		}
+		s.syncSearchIndex(book)
+	}
+}
+
+// MigrateCategories links books that only have a category string to the
+// classification with that label, ignoring case, and to a new custom
+// classification when there is none. The category becomes the label, so
+// "fiction" and "Fiction" end up as one, and books without a call number
+// get one.
+func (s *bookService) MigrateCategories() (int, error) {
+	linked := 0
+
+	var afterID uint
+	for {
+		books, err := s.bookRepo.GetWithoutClassification(afterID, contributorMigrationBatch)
+		if err != nil || len(books) == 0 {
+			return linked, err
+		}
+		for i := range books {
+			book := &books[i]
+			afterID = book.ID
+			classification, err := s.resolveClassification(nil, "", book.Category)
+			if err != nil {
+				return linked, err
+			}
+			if classification == nil {
+				continue
+			}
+			if book.CallNumber == "" {
+				book.CallNumber = callNumber(classification, book)
+			}
+			if err := s.bookRepo.UpdateClassification(book.ID, &classification.ID, classification.Label, book.CallNumber); err != nil {
+				return linked, err
+			}
+			linked++
+		}
+	}
+}
+
+// MigrateWorks puts every book that belongs to no work yet into the work
+// with the same title and main author, or into a new one, returning how
+// many books it grouped. Books already in a work are skipped, so it is safe
+// to run on every start.
+func (s *bookService) MigrateWorks() (int, error) {
+	grouped := 0
+
+	var afterID uint
+	for {
+		books, err := s.bookRepo.GetWithoutWork(afterID, contributorMigrationBatch)
+		if err != nil || len(books) == 0 {
+			return grouped, err
+		}
+		for i := range books {
+			book := &books[i]
+			afterID = book.ID
+			work, err := findOrCreateWork(s.workRepo, book)
+			if err != nil {
+				return grouped, err
+			}
+			if err := s.bookRepo.UpdateWork(book.ID, work.ID); err != nil {
+				return grouped, err
+			}
+			grouped++
+		}
+	}
+}
+
+// resolveWork finds the work for a book by ID, with 0 meaning a new work
+// of its own, or else by its title and main author.
+func (s *bookService) resolveWork(id *uint, book *models.Book) (*models.Work, error) {
+	if id == nil {
+		return findOrCreateWork(s.workRepo, book)
+	}
+	if *id == 0 {
+		return createWork(s.workRepo, book)
+	}
+
+	work, err := s.workRepo.GetByID(*id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("work not found")
+		}
+		return nil, err
+	}
+	return work, nil
+}
+
+// resolveClassification finds the classification for a book by ID, by
+// class number or by category label, in that order. Dewey numbers and
+// labels that are not in the tree yet are added to it.
+func (s *bookService) resolveClassification(id *uint, classNumber, category string) (*models.Classification, error) {
+	category = strings.Join(strings.Fields(category), " ")
+
+	if id != nil && *id != 0 {
+		classification, err := s.classificationRepo.GetByID(*id)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("classification not found")
+			}
+			return nil, err
+		}
+		return classification, nil
+	}
+
+	if classNumber = strings.TrimSpace(classNumber); classNumber != "" {
+		for _, scheme := range []string{models.SchemeDewey, models.SchemeUDC, models.SchemeCustom} {
+			classification, err := s.classificationRepo.GetByNotation(scheme, classNumber)
+			if err == nil {
+				return classification, nil
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+		}
+		if !deweyNotation.MatchString(classNumber) {
+			return nil, errors.New("classification not found")
+		}
+		label := category
+		if label == "" {
+			label = classNumber
+		}
+		classification := &models.Classification{
+			Scheme:   models.SchemeDewey,
+			Notation: classNumber,
+			Label:    truncate(label, 50),
+		}
+		if err := createClassification(s.classificationRepo, classification); err != nil {
+			return nil, err
+		}
+		return classification, nil
+	}
+
+	if category == "" {
+		return nil, nil
+	}
+	classification, err := s.classificationRepo.GetByLabel(category)
+	if err == nil {
+		return classification, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// A label differing only in punctuation maps to the same custom node
+	notation := customNotation(category)
+	if classification, err := s.classificationRepo.GetByNotation(models.SchemeCustom, notation); err == nil {
+		return classification, nil
+	}
+	classification = &models.Classification{
+		Scheme:   models.SchemeCustom,
+		Notation: notation,
+		Label:    truncate(category, 50),
+	}
+	if err := s.classificationRepo.Create(classification); err != nil {
+		return nil, err
+	}
+	return classification, nil
+}
+
+// resolveSubjects finds or creates the subject headings for a book.
+func (s *bookService) resolveSubjects(headings []string) ([]models.BookSubject, error) {
+	subjects := []models.BookSubject{}
+	seen := make(map[uint]bool)
+	for _, heading := range headings {
+		heading, key := subjectHeading(heading)
+		if key == "" {
+			continue
+		}
+		subject := &models.Subject{Heading: heading, HeadingKey: key}
+		if err := s.subjectRepo.FindOrCreate(subject); err != nil {
+			return nil, err
+		}
+		if seen[subject.ID] {
+			continue
+		}
+		seen[subject.ID] = true
+		subjects = append(subjects, models.BookSubject{
+			SubjectID: subject.ID,
+			Position:  len(subjects),
+			Subject:   subject,
+		})
+	}
+	return subjects, nil
+}
+
+// resolveContributors turns contributor inputs, or a free-text author
+// string when there are none, into credits on author records. It also
+// returns the author line shown for the book.
+func (s *bookService) resolveContributors(inputs []models.ContributorInput, authorText string) ([]models.BookContributor, string, error) {
+	if len(inputs) == 0 {
+		for _, name := range splitNames(authorText) {
+			inputs = append(inputs, models.ContributorInput{Name: name})
+		}
+	}
+
+	contributors := make([]models.BookContributor, 0, len(inputs))
+	for _, input := range inputs {
+		role := input.Role
+		if role == "" {
+			role = models.RoleAuthor
+		}
+
+		var author *models.Author
+		switch {
+		case input.AuthorID != 0:
+			found, err := s.authorRepo.GetByID(input.AuthorID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, "", errors.New("author not found")
+				}
+				return nil, "", err
+			}
+			author = found
+		case strings.TrimSpace(input.Name) != "":
+			name := strings.Join(strings.Fields(input.Name), " ")
+			if strings.Count(name, ",") == 1 {
+				name = uninvert(name)
+			}
+			author = &models.Author{
+				Name:     truncate(name, 100),
+				SortName: truncate(sortName(name), 100),
+				NameKey:  authorKey(name),
+			}
+			if err := s.authorRepo.FindOrCreate(author); err != nil {
+				return nil, "", err
+			}
+		default:
+			return nil, "", errors.New("contributor needs a name or author_id")
+		}
+
+		duplicate := false
+		for _, c := range contributors {
+			if c.AuthorID == author.ID && c.Role == role {
+				duplicate = true
+			}
+		}
+		if !duplicate {
+			contributors = append(contributors, models.BookContributor{
+				AuthorID: author.ID,
+				Role:     role,
+				Position: len(contributors),
+				Author:   author,
+			})
+		}
+	}
+
+	return contributors, creditLine(contributors), nil
+}
+
+// resolvePublisher finds or creates the publisher record for a name.
+func (s *bookService) resolvePublisher(name string) (*models.Publisher, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return nil, nil
+	}
+
+	publisher := &models.Publisher{
+		Name:    truncate(name, 100),
+		NameKey: publisherKey(name),
+	}
+	if err := s.publisherRepo.FindOrCreate(publisher); err != nil {
+		return nil, err
+	}
+	return publisher, nil
+}
+
+// creditLine is the display author of a book: its authors, or every
+// contributor when no one is credited as author.
+func creditLine(contributors []models.BookContributor) string {
+	var names, others []string
+	for _, c := range contributors {
+		if c.Author == nil {
+			continue
+		}
+		if c.Role == models.RoleAuthor {
+			names = append(names, c.Author.Name)
+		} else {
+			others = append(others, c.Author.Name)
+		}
+	}
+	if len(names) == 0 {
+		names = others
+	}
+	return truncate(strings.Join(names, "; "), 100)
+}
+
+func (s *bookService) syncSearchIndex(book *models.Book) {
+	if !book.IsActive {
+		s.unindexBook(book.ID)
+		return
+	}
+
+	if err := s.searchIndex.Index(bookDocument(book)); err != nil {
+		log.Printf("search: failed to index book %d: %v", book.ID, err)
+	}
+	if err := s.suggester.Index(bookSuggestDocument(book)); err != nil {
+		log.Printf("search: failed to index suggestions for book %d: %v", book.ID, err)
+	}
+}
+
+func (s *bookService) unindexBook(id uint) {
+	if err := s.searchIndex.Remove(id); err != nil {
+		log.Printf("search: failed to remove book %d: %v", id, err)
+	}
+	if err := s.suggester.Remove(id); err != nil {
+		log.Printf("search: failed to remove suggestions for book %d: %v", id, err)
+	}
+}
+
+func bookDocument(book *models.Book) search.Document {
+	return search.Document{
+		ID:       book.ID,
+		Language: book.Language,
+		Fields: []search.Field{
+			{Name: "title", Text: book.Title, Weight: 3},
+			{Name: "author", Text: book.Author, Weight: 2},
+			{Name: "isbn", Text: book.ISBN, Weight: 3, Exact: true},
+			{Name: "isbn10", Text: isbn10(book.ISBN), Weight: 3, Exact: true},
+			{Name: "category", Text: book.Category, Weight: 1.5},
+			{Name: "publisher", Text: book.Publisher, Weight: 1},
+			{Name: "description", Text: book.Description, Weight: 1},
+		},
+	}
+}
+
+// bookSuggestDocument holds the phrases offered as completions for a book:
+// its title, author line and subject headings.
+func bookSuggestDocument(book *models.Book) search.Document {
+	doc := search.Document{
+		ID: book.ID,
+		Fields: []search.Field{
+			{Name: "title", Text: book.Title},
+			{Name: "author", Text: book.Author},
+		},
+	}
+	for _, subject := range book.Subjects {
+		if subject.Subject != nil {
+			doc.Fields = append(doc.Fields, search.Field{Name: "subject", Text: subject.Subject.Heading})
+		}
+	}
+	return doc
+}
+
+// isbn10 returns the ISBN-10 form of a stored ISBN, if it has one.
+func isbn10(code string) string {
+	isbn10, err := isbn.To10(code)
+	if err != nil {
+		return ""
+	}
+	return isbn10
 }
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/search"
+)
+
+// Subject completions come from the headings of a book, not from the
+// category its classification gives it.
+func TestBookSuggestDocumentSubjects(t *testing.T) {
+	book := &models.Book{
+		ID:       3,
+		Title:    "Cantik itu luka",
+		Author:   "Eka Kurniawan",
+		Category: "Sastra",
+		Subjects: []models.BookSubject{
+			{Subject: &models.Subject{Heading: "Indonesian fiction -- 21st century"}},
+			{Subject: &models.Subject{Heading: "Families"}},
+		},
+	}
+
+	suggester := search.NewMemorySuggester()
+	if err := suggester.Index(bookSuggestDocument(book)); err != nil {
+		t.Fatal(err)
+	}
+
+	for q, want := range map[string]string{
+		"indonesian f": "Indonesian fiction -- 21st century",
+		"fam":          "Families",
+	} {
+		result, err := suggester.Suggest(q, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Completions) != 1 || result.Completions[0].Text != want || result.Completions[0].Kind != "subject" {
+			t.Errorf("Suggest(%q) = %+v, want subject %q", q, result.Completions, want)
+		}
+	}
+	if result, _ := suggester.Suggest("sastra", 5); len(result.Completions) != 0 {
+		t.Errorf("category offered as a completion: %+v", result.Completions)
+	}
+
+	// Replacing the subjects replaces their completions
+	book.Subjects = []models.BookSubject{{Subject: &models.Subject{Heading: "Magic realism"}}}
+	if err := suggester.Index(bookSuggestDocument(book)); err != nil {
+		t.Fatal(err)
+	}
+	if result, _ := suggester.Suggest("fam", 5); len(result.Completions) != 0 {
+		t.Errorf("old subject still offered: %+v", result.Completions)
+	}
+	if result, _ := suggester.Suggest("magic", 5); len(result.Completions) != 1 {
+		t.Errorf("new subject not offered: %+v", result.Completions)
+	}
+}
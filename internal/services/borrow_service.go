@@ -2,46 +2,59 @@ package services
 
 import (
 	"errors"
+	"log"
 	"time"
 
 	"github.com/yooerizkilab/library-system/internal/models"
 	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"gorm.io/gorm"
 )
 
 type BorrowService interface {
-	BorrowBook(req *models.CreateBorrowRequest) (*models.Borrow, error)
-	ReturnBook(id uint, req *models.ReturnBookRequest) (*models.Borrow, error)
-	GetAllBorrows() ([]models.Borrow, error)
+	BorrowBook(actor *models.Actor, req *models.CreateBorrowRequest) (*models.Borrow, error)
+	ReturnBook(actor *models.Actor, id uint, req *models.ReturnBookRequest) (*models.Borrow, error)
+	GetAllBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error)
 	GetBorrowByID(id uint) (*models.Borrow, error)
-	GetBorrowsByUser(userID uint) ([]models.Borrow, error)
-	GetBorrowsByBook(bookID uint) ([]models.Borrow, error)
-	UpdateBorrow(id uint, req *models.UpdateBorrowRequest) (*models.Borrow, error)
-	GetActiveBorrows() ([]models.Borrow, error)
-	GetOverdueBorrows() ([]models.Borrow, error)
-	GetBorrowHistory(userID uint) ([]models.Borrow, error)
+	GetBorrowsByUser(userID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	GetBorrowsByBook(bookID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	UpdateBorrow(actor *models.Actor, id uint, req *models.UpdateBorrowRequest) (*models.Borrow, error)
+	GetActiveBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	GetOverdueBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error)
+	GetBorrowHistory(userID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error)
 	UpdateOverdueStatus() error
+	ExpireDigitalLoans() error
+	DetachOldHistory() error
 }
 
 type borrowService struct {
-	borrowRepo repositories.BorrowRepository
-	userRepo   repositories.UserRepository
-	bookRepo   repositories.BookRepository
+	borrowRepo       repositories.BorrowRepository
+	userRepo         repositories.UserRepository
+	bookRepo         repositories.BookRepository
+	holdService      HoldService
+	auditService     AuditService
+	historyRetention time.Duration
 }
 
 func NewBorrowService(
 	borrowRepo repositories.BorrowRepository,
 	userRepo repositories.UserRepository,
 	bookRepo repositories.BookRepository,
+	holdService HoldService,
+	auditService AuditService,
+	historyRetention time.Duration,
 ) BorrowService {
 	return &borrowService{
-		borrowRepo: borrowRepo,
-		userRepo:   userRepo,
-		bookRepo:   bookRepo,
+		borrowRepo:       borrowRepo,
+		userRepo:         userRepo,
+		bookRepo:         bookRepo,
+		holdService:      holdService,
+		auditService:     auditService,
+		historyRetention: historyRetention,
 	}
 }
 
-func (s *borrowService) BorrowBook(req *models.CreateBorrowRequest) (*models.Borrow, error) {
+func (s *borrowService) BorrowBook(actor *models.Actor, req *models.CreateBorrowRequest) (*models.Borrow, error) {
 	// Check if user exists and is active
 	user, err := s.userRepo.GetByID(req.UserID)
 	if err != nil {
@@ -65,9 +78,6 @@ func (s *borrowService) BorrowBook(req *models.CreateBorrowRequest) (*models.Bor
 	if !book.IsActive {
 		return nil, errors.New("book is not active")
 	}
-	if book.Available <= 0 {
-		return nil, errors.New("book is not available for borrowing")
-	}
 
 	// Check if user already has an active borrow for this book
 	activeBorrow, err := s.borrowRepo.CheckActiveUserBorrow(req.UserID, req.BookID)
@@ -75,9 +85,19 @@ func (s *borrowService) BorrowBook(req *models.CreateBorrowRequest) (*models.Bor
 		return nil, errors.New("user already has an active borrow for this book")
 	}
 
+	// A copy set aside for the user's hold is already off the shelf
+	setAside, err := s.holdService.HasReadyHold(req.UserID, req.BookID)
+	if err != nil {
+		return nil, err
+	}
+	if !setAside && book.Available <= 0 {
+		return nil, errors.New("book is not available for borrowing")
+	}
+
 	// Create borrow record
+	userID := req.UserID
 	borrow := &models.Borrow{
-		UserID:     req.UserID,
+		UserID:     &userID,
 		BookID:     req.BookID,
 		BorrowDate: time.Now(),
 		DueDate:    req.DueDate,
@@ -85,17 +105,20 @@ func (s *borrowService) BorrowBook(req *models.CreateBorrowRequest) (*models.Bor
 		Notes:      req.Notes,
 	}
 
-	err = s.borrowRepo.Create(borrow)
-	if err != nil {
+	// The copy comes off the shelf with the loan, unless it was set aside
+	if err := s.borrowRepo.Lend(borrow, !setAside); err != nil {
+		if errors.Is(err, repositories.ErrNoCopy) {
+			return nil, errors.New("book is not available for borrowing")
+		}
 		return nil, err
 	}
 
-	// Update book availability
-	err = s.bookRepo.UpdateStock(req.BookID, book.Stock, book.Available-1)
-	if err != nil {
+	if err := s.holdService.FulfillHold(actor, req.UserID, book); err != nil {
 		return nil, err
 	}
 
+	s.auditService.Record(actor, "borrow.create", "borrow", borrow.ID, nil, borrow)
+
 	// Get the created borrow with relations
 	createdBorrow, err := s.borrowRepo.GetByID(borrow.ID)
 	if err != nil {
@@ -105,7 +128,7 @@ func (s *borrowService) BorrowBook(req *models.CreateBorrowRequest) (*models.Bor
 	return createdBorrow, nil
 }
 
-func (s *borrowService) ReturnBook(id uint, req *models.ReturnBookRequest) (*models.Borrow, error) {
+func (s *borrowService) ReturnBook(actor *models.Actor, id uint, req *models.ReturnBookRequest) (*models.Borrow, error) {
 	borrow, err := s.borrowRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -118,6 +141,8 @@ func (s *borrowService) ReturnBook(id uint, req *models.ReturnBookRequest) (*mod
 		return nil, errors.New("book is not currently borrowed")
 	}
 
+	before := snapshot(borrow)
+
 	// Update borrow record
 	now := time.Now()
 	borrow.ReturnDate = &now
@@ -125,27 +150,26 @@ func (s *borrowService) ReturnBook(id uint, req *models.ReturnBookRequest) (*mod
 	borrow.Fine = req.Fine
 	borrow.Notes = req.Notes
 
-	err = s.borrowRepo.Update(borrow)
-	if err != nil {
+	// The copy goes back on the shelf with the return
+	if err := s.borrowRepo.Return(borrow); err != nil {
+		if errors.Is(err, repositories.ErrNotBorrowed) {
+			return nil, errors.New("book is not currently borrowed")
+		}
 		return nil, err
 	}
 
-	// Update book availability
-	book, err := s.bookRepo.GetByID(borrow.BookID)
-	if err != nil {
-		return nil, err
-	}
+	s.auditService.Record(actor, "borrow.return", "borrow", borrow.ID, before, borrow)
 
-	err = s.bookRepo.UpdateStock(borrow.BookID, book.Stock, book.Available+1)
-	if err != nil {
-		return nil, err
+	// The returned copy goes to the next hold in line
+	if err := s.holdService.AllocateCopies(borrow.BookID); err != nil {
+		log.Printf("holds: failed to allocate book %d: %v", borrow.BookID, err)
 	}
 
 	return borrow, nil
 }
 
-func (s *borrowService) GetAllBorrows() ([]models.Borrow, error) {
-	return s.borrowRepo.GetAll()
+func (s *borrowService) GetAllBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return s.borrowRepo.GetAll(spec)
 }
 
 func (s *borrowService) GetBorrowByID(id uint) (*models.Borrow, error) {
@@ -159,33 +183,33 @@ func (s *borrowService) GetBorrowByID(id uint) (*models.Borrow, error) {
 	return borrow, nil
 }
 
-func (s *borrowService) GetBorrowsByUser(userID uint) ([]models.Borrow, error) {
+func (s *borrowService) GetBorrowsByUser(userID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
 	// Check if user exists
 	_, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, nil, errors.New("user not found")
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
-	return s.borrowRepo.GetByUserID(userID)
+	return s.borrowRepo.GetByUserID(userID, spec)
 }
 
-func (s *borrowService) GetBorrowsByBook(bookID uint) ([]models.Borrow, error) {
+func (s *borrowService) GetBorrowsByBook(bookID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
 	// Check if book exists
 	_, err := s.bookRepo.GetByID(bookID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("book not found")
+			return nil, nil, errors.New("book not found")
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
-	return s.borrowRepo.GetByBookID(bookID)
+	return s.borrowRepo.GetByBookID(bookID, spec)
 }
 
-func (s *borrowService) UpdateBorrow(id uint, req *models.UpdateBorrowRequest) (*models.Borrow, error) {
+func (s *borrowService) UpdateBorrow(actor *models.Actor, id uint, req *models.UpdateBorrowRequest) (*models.Borrow, error) {
 	borrow, err := s.borrowRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -194,6 +218,8 @@ func (s *borrowService) UpdateBorrow(id uint, req *models.UpdateBorrowRequest) (
 		return nil, err
 	}
 
+	before := snapshot(borrow)
+
 	// Update fields
 	if req.DueDate != nil {
 		borrow.DueDate = *req.DueDate
@@ -207,6 +233,9 @@ func (s *borrowService) UpdateBorrow(id uint, req *models.UpdateBorrowRequest) (
 	if req.Fine != nil {
 		borrow.Fine = *req.Fine
 	}
+	if req.FinePaid != nil {
+		borrow.FinePaid = *req.FinePaid
+	}
 	if req.Notes != "" {
 		borrow.Notes = req.Notes
 	}
@@ -216,45 +245,91 @@ func (s *borrowService) UpdateBorrow(id uint, req *models.UpdateBorrowRequest) (
 		return nil, err
 	}
 
+	s.auditService.Record(actor, "borrow.update", "borrow", borrow.ID, before, borrow)
+
 	return borrow, nil
 }
 
-func (s *borrowService) GetActiveBorrows() ([]models.Borrow, error) {
-	return s.borrowRepo.GetActiveBorrows()
+func (s *borrowService) GetActiveBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return s.borrowRepo.GetActiveBorrows(spec)
 }
 
-func (s *borrowService) GetOverdueBorrows() ([]models.Borrow, error) {
-	return s.borrowRepo.GetOverdueBorrows()
+func (s *borrowService) GetOverdueBorrows(spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return s.borrowRepo.GetOverdueBorrows(spec)
 }
 
-func (s *borrowService) GetBorrowHistory(userID uint) ([]models.Borrow, error) {
+func (s *borrowService) GetBorrowHistory(userID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
 	// Check if user exists
-	_, err := s.userRepo.GetByID(userID)
+	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user not found")
+			return nil, nil, errors.New("user not found")
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
-	return s.borrowRepo.GetBorrowHistory(userID)
+	// Users who opted out only see loans that are still open or have unpaid fines
+	return s.borrowRepo.GetBorrowHistory(userID, !user.KeepReadingHistory, spec)
 }
 
 func (s *borrowService) UpdateOverdueStatus() error {
-	overdueBorrows, err := s.borrowRepo.GetOverdueBorrows()
+	overdueBorrows, _, err := s.borrowRepo.GetOverdueBorrows(nil)
 	if err != nil {
 		return err
 	}
 
 	for _, borrow := range overdueBorrows {
 		if borrow.Status == models.StatusBorrowed {
+			before := snapshot(borrow)
 			borrow.Status = models.StatusOverdue
 			err = s.borrowRepo.Update(&borrow)
 			if err != nil {
 				return err
 			}
+			s.auditService.Record(nil, "borrow.mark_overdue", "borrow", borrow.ID, before, borrow)
 		}
 	}
 
 	return nil
 }
+
+// ExpireDigitalLoans returns digital loans at their due date, freeing the
+// license for the next reader. Digital loans never collect fines.
+func (s *borrowService) ExpireDigitalLoans() error {
+	expired, err := s.borrowRepo.GetExpiredDigitalLoans(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, borrow := range expired {
+		if _, err := s.ReturnBook(nil, borrow.ID, &models.ReturnBookRequest{Notes: borrow.Notes}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DetachOldHistory removes the link between readers and returned loans older
+// than the history retention period, for readers who opted out of keeping
+// their reading history.
+func (s *borrowService) DetachOldHistory() error {
+	cutoff := time.Now().Add(-s.historyRetention)
+	detached, err := s.borrowRepo.DetachReturnedBefore(cutoff)
+	if err != nil {
+		return err
+	}
+	// Their own requests, e.g. holds they placed, point to them as well
+	forgotten, err := s.auditService.ForgetMemberActorsBefore(cutoff)
+	if err != nil {
+		return err
+	}
+
+	if detached > 0 || forgotten > 0 {
+		s.auditService.Record(nil, "borrow.detach_history", "borrow", 0, nil, map[string]interface{}{
+			"returned_before": cutoff,
+			"detached":        detached,
+			"audit_unlinked":  forgotten,
+		})
+	}
+	return nil
+}
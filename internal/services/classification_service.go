@@ -0,0 +1,346 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/search"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/utils"
+	"gorm.io/gorm"
+)
+
+var deweyNotation = regexp.MustCompile(`^\d{3}(\.\d+)?$`)
+
+// Leading articles skipped for the title letter of a call number
+var titleArticles = map[string]bool{
+	"the": true, "a": true, "an": true, "sang": true, "si": true,
+	"le": true, "la": true, "les": true, "der": true, "die": true, "das": true,
+}
+
+type ClassificationService interface {
+	GetClassifications(spec *query.Spec) ([]models.Classification, *query.Page, error)
+	GetClassificationByID(id uint) (*models.Classification, error)
+	GetClassificationBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	CreateClassification(actor *models.Actor, req *models.CreateClassificationRequest) (*models.Classification, error)
+	UpdateClassification(actor *models.Actor, id uint, req *models.UpdateClassificationRequest) (*models.Classification, error)
+	DeleteClassification(actor *models.Actor, id uint) error
+	SeedDewey() error
+}
+
+type classificationService struct {
+	classificationRepo repositories.ClassificationRepository
+	bookRepo           repositories.BookRepository
+	bookService        BookService
+	auditService       AuditService
+}
+
+func NewClassificationService(
+	classificationRepo repositories.ClassificationRepository,
+	bookRepo repositories.BookRepository,
+	bookService BookService,
+	auditService AuditService,
+) ClassificationService {
+	return &classificationService{
+		classificationRepo: classificationRepo,
+		bookRepo:           bookRepo,
+		bookService:        bookService,
+		auditService:       auditService,
+	}
+}
+
+// GetClassifications lists classification nodes; ?root=true gives the top
+// of each scheme and ?parent_id= the children of a node.
+func (s *classificationService) GetClassifications(spec *query.Spec) ([]models.Classification, *query.Page, error) {
+	return s.classificationRepo.GetAll(spec)
+}
+
+// GetClassificationByID returns a node with the path down to it and its
+// children.
+func (s *classificationService) GetClassificationByID(id uint) (*models.Classification, error) {
+	classification, err := s.getClassification(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if classification.Ancestors, err = s.classificationRepo.GetAncestors(classification); err != nil {
+		return nil, err
+	}
+	if classification.Children, err = s.classificationRepo.GetChildren(classification.ID); err != nil {
+		return nil, err
+	}
+	return classification, nil
+}
+
+// GetClassificationBooks lists the books classified under a node or any
+// node below it.
+func (s *classificationService) GetClassificationBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	classification, err := s.getClassification(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.bookRepo.GetByClassification(classification, spec)
+}
+
+func (s *classificationService) CreateClassification(actor *models.Actor, req *models.CreateClassificationRequest) (*models.Classification, error) {
+	if errs := utils.ValidateStruct(req); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	classification := &models.Classification{
+		Scheme:   req.Scheme,
+		Notation: strings.TrimSpace(req.Notation),
+		Label:    strings.TrimSpace(req.Label),
+		ParentID: req.ParentID,
+	}
+	if classification.Notation == "" || classification.Label == "" {
+		return nil, errors.New("notation and label are required")
+	}
+	if classification.Scheme == models.SchemeDewey && !deweyNotation.MatchString(classification.Notation) {
+		return nil, errors.New("invalid Dewey notation")
+	}
+	if _, err := s.classificationRepo.GetByNotation(classification.Scheme, classification.Notation); err == nil {
+		return nil, errors.New("classification with this notation already exists")
+	}
+
+	if classification.ParentID != nil {
+		parent, err := s.getClassification(*classification.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Scheme != classification.Scheme {
+			return nil, errors.New("parent belongs to another scheme")
+		}
+	}
+
+	if err := createClassification(s.classificationRepo, classification); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "classification.create", "classification", classification.ID, nil, classification)
+
+	return classification, nil
+}
+
+func (s *classificationService) UpdateClassification(actor *models.Actor, id uint, req *models.UpdateClassificationRequest) (*models.Classification, error) {
+	if errs := utils.ValidateStruct(req); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	classification, err := s.getClassification(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(classification)
+	relabeled := false
+
+	if notation := strings.TrimSpace(req.Notation); notation != "" && notation != classification.Notation {
+		if classification.Scheme == models.SchemeDewey && !deweyNotation.MatchString(notation) {
+			return nil, errors.New("invalid Dewey notation")
+		}
+		if _, err := s.classificationRepo.GetByNotation(classification.Scheme, notation); err == nil {
+			return nil, errors.New("classification with this notation already exists")
+		}
+		classification.Notation = notation
+	}
+	if label := strings.TrimSpace(req.Label); label != "" && label != classification.Label {
+		classification.Label = label
+		relabeled = true
+	}
+
+	if err := s.classificationRepo.Update(classification); err != nil {
+		return nil, err
+	}
+
+	if req.ParentID != nil {
+		var parent *models.Classification
+		if *req.ParentID != 0 {
+			parent, err = s.getClassification(*req.ParentID)
+			if err != nil {
+				return nil, err
+			}
+			if parent.Scheme != classification.Scheme {
+				return nil, errors.New("parent belongs to another scheme")
+			}
+			if strings.HasPrefix(parent.Path, classification.Path) {
+				return nil, errors.New("cannot move a classification below itself")
+			}
+		}
+		if err := s.classificationRepo.Move(classification, parent); err != nil {
+			return nil, err
+		}
+	}
+
+	s.auditService.Record(actor, "classification.update", "classification", classification.ID, before, classification)
+
+	if relabeled {
+		if ids, err := s.bookRepo.GetIDsByClassification(classification.ID); err == nil {
+			s.bookService.RefreshBooks(ids)
+		}
+	}
+
+	return classification, nil
+}
+
+// DeleteClassification removes a node that has no children and no books.
+func (s *classificationService) DeleteClassification(actor *models.Actor, id uint) error {
+	classification, err := s.getClassification(id)
+	if err != nil {
+		return err
+	}
+
+	hasChildren, err := s.classificationRepo.HasChildren(id)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return errors.New("cannot delete a classification with children")
+	}
+	if classification.BookCount > 0 {
+		return errors.New("cannot delete a classification with books")
+	}
+
+	if err := s.classificationRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, "classification.delete", "classification", classification.ID, classification, nil)
+
+	return nil
+}
+
+// SeedDewey adds the Dewey main classes and divisions the first time the
+// service runs.
+func (s *classificationService) SeedDewey() error {
+	count, err := s.classificationRepo.CountScheme(models.SchemeDewey)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	var mainClass *models.Classification
+	for _, division := range deweyDivisions {
+		classification := &models.Classification{
+			Scheme:   models.SchemeDewey,
+			Notation: division.Notation,
+			Label:    division.Label,
+		}
+		if deweyMainClass(division.Notation) {
+			mainClass = classification
+		} else {
+			classification.ParentID = &mainClass.ID
+		}
+		if err := s.classificationRepo.Create(classification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *classificationService) getClassification(id uint) (*models.Classification, error) {
+	classification, err := s.classificationRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("classification not found")
+		}
+		return nil, err
+	}
+	return classification, nil
+}
+
+// createClassification stores a node. Dewey numbers given without a parent
+// are placed under the narrowest broader number that exists, so 899.221
+// lands under 899.2, 899 or 890.
+func createClassification(repo repositories.ClassificationRepository, classification *models.Classification) error {
+	if classification.ParentID == nil && classification.Scheme == models.SchemeDewey {
+		for _, notation := range deweyBroader(classification.Notation) {
+			if parent, err := repo.GetByNotation(models.SchemeDewey, notation); err == nil {
+				classification.ParentID = &parent.ID
+				break
+			}
+		}
+	}
+	return repo.Create(classification)
+}
+
+// customNotation derives the notation of a custom node from its label,
+// e.g. "Science Fiction" becomes "science-fiction".
+func customNotation(label string) string {
+	return truncate(strings.Join(search.Words(label), "-"), 30)
+}
+
+// callNumber builds a shelf mark from the classification, the first
+// author's surname and the first word of the title, e.g. "899.221 TOE b".
+// Custom classifications contribute the start of their label.
+func callNumber(classification *models.Classification, book *models.Book) string {
+	var parts []string
+	if classification != nil {
+		if classification.Scheme == models.SchemeCustom {
+			parts = append(parts, strings.ToUpper(prefixRunes(strings.Join(search.Words(classification.Label), ""), 3)))
+		} else {
+			parts = append(parts, classification.Notation)
+		}
+	}
+
+	if surname := mainSurname(book); surname != "" {
+		parts = append(parts, strings.ToUpper(prefixRunes(surname, 3)))
+	}
+
+	for _, word := range search.Words(book.Title) {
+		if !titleArticles[word] {
+			parts = append(parts, prefixRunes(word, 1))
+			break
+		}
+	}
+
+	return truncate(strings.Join(parts, " "), 50)
+}
+
+// mainSurname returns the folded surname of the first author of a book.
+func mainSurname(book *models.Book) string {
+	words := search.Words(mainAuthorName(book))
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// mainAuthorName is the first credited author of a book, or else the first
+// name in its author line.
+func mainAuthorName(book *models.Book) string {
+	for _, c := range book.Contributors {
+		if c.Author != nil && c.Role == models.RoleAuthor {
+			return c.Author.Name
+		}
+	}
+	if names := splitNames(book.Author); len(names) > 0 {
+		return names[0]
+	}
+	return ""
+}
+
+func prefixRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) > n {
+		runes = runes[:n]
+	}
+	return string(runes)
+}
+
+// subjectHeading tidies a heading and returns it with its key, which
+// ignores case, accents and punctuation within each subdivision.
+func subjectHeading(heading string) (string, string) {
+	var parts, keys []string
+	for _, part := range strings.Split(heading, "--") {
+		part = strings.Join(strings.Fields(part), " ")
+		if part == "" {
+			continue
+		}
+		parts = append(parts, part)
+		keys = append(keys, strings.Join(search.Words(part), " "))
+	}
+	return truncate(strings.Join(parts, " -- "), 200), truncate(strings.Join(keys, " -- "), 200)
+}
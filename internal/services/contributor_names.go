@@ -0,0 +1,112 @@
+package services
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/search"
+)
+
+// Separators between several names in one author string
+var nameSeparator = regexp.MustCompile(`(?i)\s*(?:;|&|\s+and\s+|\s+dan\s+)\s*`)
+
+// Legal forms and trade words ignored when comparing publisher names
+var publisherNoise = map[string]bool{
+	"pt": true, "cv": true, "tbk": true, "penerbit": true, "inc": true,
+	"ltd": true, "llc": true, "co": true, "company": true, "publisher": true,
+	"publishers": true, "publishing": true,
+}
+
+// splitNames splits a free-text author string into display names. Names
+// may be separated by ";", "&", "and", "dan" or commas. A single comma with
+// one word before it is read as an inverted name, e.g. "Toer, Pramoedya".
+func splitNames(text string) []string {
+	var names []string
+	for _, part := range nameSeparator.Split(text, -1) {
+		pieces := strings.Split(part, ",")
+		if len(pieces) == 2 && !strings.Contains(strings.TrimSpace(pieces[0]), " ") {
+			names = appendName(names, uninvert(part))
+			continue
+		}
+		for _, piece := range pieces {
+			names = appendName(names, piece)
+		}
+	}
+	return names
+}
+
+func appendName(names []string, name string) []string {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || slices.Contains(names, name) {
+		return names
+	}
+	return append(names, name)
+}
+
+// uninvert turns "Toer, Pramoedya Ananta" into "Pramoedya Ananta Toer".
+func uninvert(name string) string {
+	last, first, ok := strings.Cut(name, ",")
+	if !ok {
+		return strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(strings.TrimSpace(first) + " " + strings.TrimSpace(last))
+}
+
+// sortName returns the "Last, First" form of a personal name.
+func sortName(name string) string {
+	words := strings.Fields(name)
+	if len(words) < 2 {
+		return name
+	}
+	return words[len(words)-1] + ", " + strings.Join(words[:len(words)-1], " ")
+}
+
+// authorKey identifies an author regardless of word order, case and
+// diacritics, so "Toer, Pramoedya" and "Pramoedya Toer" share a key.
+func authorKey(name string) string {
+	words := search.Words(name)
+	slices.Sort(words)
+	return truncate(strings.Join(words, " "), 100)
+}
+
+// publisherKey identifies a publisher regardless of case, punctuation and
+// legal form, so "PT Gramedia Pustaka Utama" matches "Gramedia Pustaka Utama".
+func publisherKey(name string) string {
+	words := search.Words(name)
+	kept := words[:0]
+	for _, word := range words {
+		if !publisherNoise[word] {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) == 0 {
+		kept = search.Words(name)
+	}
+	return truncate(strings.Join(kept, " "), 100)
+}
+
+// surnameKey groups authors that may be the same person: the folded last
+// name and first initial, so "P. A. Toer" and "Pramoedya Ananta Toer" meet.
+func surnameKey(name string) string {
+	words := search.Words(name)
+	if len(words) < 2 {
+		return strings.Join(words, " ")
+	}
+	last := words[len(words)-1]
+	return last + " " + string([]rune(words[0])[:1])
+}
+
+// givenInitials returns the first letters of the given names, i.e. every
+// word but the last.
+func givenInitials(name string) []rune {
+	words := search.Words(name)
+	if len(words) < 2 {
+		return nil
+	}
+	initials := make([]rune, 0, len(words)-1)
+	for _, word := range words[:len(words)-1] {
+		initials = append(initials, []rune(word)[0])
+	}
+	return initials
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/storage"
+	"github.com/yooerizkilab/library-system/pkg/imaging"
+	"gorm.io/gorm"
+)
+
+// Largest picture accepted as a cover, in pixels
+const coverMaxPixels = 40_000_000
+
+// How long one cover upload may spend writing to the blob store
+const coverStoreTimeout = 30 * time.Second
+
+const coverQuality = 85
+
+type coverSize struct {
+	name          string
+	width, height int
+}
+
+// Sizes a cover is stored in, smallest first
+var coverSizes = []coverSize{
+	{name: "small", width: 120, height: 180},
+	{name: "medium", width: 300, height: 450},
+	{name: "large", width: 600, height: 900},
+}
+
+// DefaultCoverSize is served when no size is asked for.
+const DefaultCoverSize = "medium"
+
+type CoverService interface {
+	UploadCover(actor *models.Actor, bookID uint, data []byte) (*models.Book, error)
+	GetCover(bookID uint, size string) (*models.CoverImage, error)
+	DeleteCover(actor *models.Actor, bookID uint) (*models.Book, error)
+}
+
+type coverService struct {
+	bookRepo     repositories.BookRepository
+	store        storage.BlobStore
+	auditService AuditService
+	maxBytes     int
+}
+
+func NewCoverService(
+	bookRepo repositories.BookRepository,
+	store storage.BlobStore,
+	auditService AuditService,
+	maxBytes int,
+) CoverService {
+	return &coverService{
+		bookRepo:     bookRepo,
+		store:        store,
+		auditService: auditService,
+		maxBytes:     maxBytes,
+	}
+}
+
+// UploadCover replaces the cover of a book with a JPEG, PNG or GIF image,
+// stored as a JPEG thumbnail in each cover size. The image type is sniffed
+// from its content.
+func (s *coverService) UploadCover(actor *models.Actor, bookID uint, data []byte) (*models.Book, error) {
+	book, err := s.getBook(bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("cover image is required")
+	}
+	if len(data) > s.maxBytes {
+		return nil, fmt.Errorf("cover image is larger than %d MB", s.maxBytes>>20)
+	}
+	img, err := imaging.Decode(data, coverMaxPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coverStoreTimeout)
+	defer cancel()
+
+	for _, size := range coverSizes {
+		thumbnail, err := imaging.EncodeJPEG(imaging.Fit(img, size.width, size.height), coverQuality)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.store.Put(ctx, coverKey(book.ID, size.name), thumbnail, "image/jpeg"); err != nil {
+			return nil, err
+		}
+	}
+
+	before := snapshot(book)
+	now := time.Now()
+	if err := s.bookRepo.UpdateCover(book.ID, &now); err != nil {
+		return nil, err
+	}
+	book.CoverUpdatedAt = &now
+
+	s.auditService.Record(actor, "book.update_cover", "book", book.ID, before, book)
+
+	return book, nil
+}
+
+// GetCover returns one size of a book's cover.
+func (s *coverService) GetCover(bookID uint, size string) (*models.CoverImage, error) {
+	if size == "" {
+		size = DefaultCoverSize
+	}
+	if !validCoverSize(size) {
+		return nil, errors.New("unknown cover size")
+	}
+
+	book, err := s.getBook(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.CoverUpdatedAt == nil {
+		return nil, errors.New("book has no cover")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coverStoreTimeout)
+	defer cancel()
+
+	blob, err := s.store.Get(ctx, coverKey(book.ID, size))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errors.New("book has no cover")
+		}
+		return nil, err
+	}
+
+	return &models.CoverImage{
+		Size:        size,
+		Data:        blob.Data,
+		ContentType: blob.ContentType,
+		UpdatedAt:   *book.CoverUpdatedAt,
+	}, nil
+}
+
+func (s *coverService) DeleteCover(actor *models.Actor, bookID uint) (*models.Book, error) {
+	book, err := s.getBook(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if book.CoverUpdatedAt == nil {
+		return nil, errors.New("book has no cover")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coverStoreTimeout)
+	defer cancel()
+
+	for _, size := range coverSizes {
+		if err := s.store.Delete(ctx, coverKey(book.ID, size.name)); err != nil {
+			return nil, err
+		}
+	}
+
+	before := snapshot(book)
+	if err := s.bookRepo.UpdateCover(book.ID, nil); err != nil {
+		return nil, err
+	}
+	book.CoverUpdatedAt = nil
+
+	s.auditService.Record(actor, "book.delete_cover", "book", book.ID, before, book)
+
+	return book, nil
+}
+
+func (s *coverService) getBook(id uint) (*models.Book, error) {
+	book, err := s.bookRepo.GetSummary(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book not found")
+		}
+		return nil, err
+	}
+	return book, nil
+}
+
+func validCoverSize(name string) bool {
+	for _, size := range coverSizes {
+		if size.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func coverKey(bookID uint, size string) string {
+	return fmt.Sprintf("covers/%d/%s.jpg", bookID, size)
+}
@@ -0,0 +1,139 @@
+package services
+
+import "strings"
+
+// deweyDivisions are the hundred divisions of the Dewey Decimal
+// Classification. Those ending in 0 head their main class.
+var deweyDivisions = []struct {
+	Notation string
+	Label    string
+}{
+	{"000", "Computer science, information & general works"},
+	{"010", "Bibliographies"},
+	{"020", "Library & information sciences"},
+	{"030", "Encyclopedias & books of facts"},
+	{"040", "Unassigned"},
+	{"050", "Magazines, journals & serials"},
+	{"060", "Associations, organizations & museums"},
+	{"070", "News media, journalism & publishing"},
+	{"080", "Quotations"},
+	{"090", "Manuscripts & rare books"},
+	{"100", "Philosophy & psychology"},
+	{"110", "Metaphysics"},
+	{"120", "Epistemology"},
+	{"130", "Parapsychology & occultism"},
+	{"140", "Philosophical schools of thought"},
+	{"150", "Psychology"},
+	{"160", "Logic"},
+	{"170", "Ethics"},
+	{"180", "Ancient, medieval & eastern philosophy"},
+	{"190", "Modern western philosophy"},
+	{"200", "Religion"},
+	{"210", "Philosophy & theory of religion"},
+	{"220", "The Bible"},
+	{"230", "Christianity"},
+	{"240", "Christian practice & observance"},
+	{"250", "Christian pastoral practice & religious orders"},
+	{"260", "Christian organization, social work & worship"},
+	{"270", "History of Christianity"},
+	{"280", "Christian denominations"},
+	{"290", "Other religions"},
+	{"300", "Social sciences"},
+	{"310", "Statistics"},
+	{"320", "Political science"},
+	{"330", "Economics"},
+	{"340", "Law"},
+	{"350", "Public administration & military science"},
+	{"360", "Social problems & social services"},
+	{"370", "Education"},
+	{"380", "Commerce, communications & transportation"},
+	{"390", "Customs, etiquette & folklore"},
+	{"400", "Language"},
+	{"410", "Linguistics"},
+	{"420", "English & Old English languages"},
+	{"430", "German & related languages"},
+	{"440", "French & related languages"},
+	{"450", "Italian, Romanian & related languages"},
+	{"460", "Spanish, Portuguese, Galician"},
+	{"470", "Latin & Italic languages"},
+	{"480", "Classical & modern Greek languages"},
+	{"490", "Other languages"},
+	{"500", "Science"},
+	{"510", "Mathematics"},
+	{"520", "Astronomy"},
+	{"530", "Physics"},
+	{"540", "Chemistry"},
+	{"550", "Earth sciences & geology"},
+	{"560", "Fossils & prehistoric life"},
+	{"570", "Biology"},
+	{"580", "Plants (Botany)"},
+	{"590", "Animals (Zoology)"},
+	{"600", "Technology"},
+	{"610", "Medicine & health"},
+	{"620", "Engineering"},
+	{"630", "Agriculture"},
+	{"640", "Home & family management"},
+	{"650", "Management & public relations"},
+	{"660", "Chemical engineering"},
+	{"670", "Manufacturing"},
+	{"680", "Manufacture for specific uses"},
+	{"690", "Construction of buildings"},
+	{"700", "Arts & recreation"},
+	{"710", "Area planning & landscape architecture"},
+	{"720", "Architecture"},
+	{"730", "Sculpture, ceramics & metalwork"},
+	{"740", "Graphic arts & decorative arts"},
+	{"750", "Painting"},
+	{"760", "Printmaking & prints"},
+	{"770", "Photography, computer art, film, video"},
+	{"780", "Music"},
+	{"790", "Sports, games & entertainment"},
+	{"800", "Literature"},
+	{"810", "American literature in English"},
+	{"820", "English & Old English literatures"},
+	{"830", "German & related literatures"},
+	{"840", "French & related literatures"},
+	{"850", "Italian, Romanian & related literatures"},
+	{"860", "Spanish, Portuguese, Galician literatures"},
+	{"870", "Latin & Italic literatures"},
+	{"880", "Classical & modern Greek literatures"},
+	{"890", "Other literatures"},
+	{"900", "History"},
+	{"910", "Geography & travel"},
+	{"920", "Biography & genealogy"},
+	{"930", "History of ancient world (to ca. 499)"},
+	{"940", "History of Europe"},
+	{"950", "History of Asia"},
+	{"960", "History of Africa"},
+	{"970", "History of North America"},
+	{"980", "History of South America"},
+	{"990", "History of other areas"},
+}
+
+// deweyMainClass tells whether a division heads one of the ten main
+// classes (000, 100, ... 900).
+func deweyMainClass(notation string) bool {
+	return strings.HasSuffix(notation, "00")
+}
+
+// deweyBroader returns the notations a Dewey number falls under, narrowest
+// first: 899.221 gives 899.22, 899.2, 899, 890 and 800.
+func deweyBroader(notation string) []string {
+	var broader []string
+	if whole, fraction, ok := strings.Cut(notation, "."); ok {
+		for i := len(fraction) - 1; i > 0; i-- {
+			broader = append(broader, whole+"."+fraction[:i])
+		}
+		broader = append(broader, whole)
+		notation = whole
+	}
+	if len(notation) == 3 {
+		if notation[2] != '0' {
+			broader = append(broader, notation[:2]+"0")
+		}
+		if notation[1:] != "00" {
+			broader = append(broader, notation[:1]+"00")
+		}
+	}
+	return broader
+}
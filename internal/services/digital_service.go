@@ -0,0 +1,344 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/storage"
+	"gorm.io/gorm"
+)
+
+// How long one file upload or download may spend in the blob store
+const digitalStoreTimeout = 5 * time.Minute
+
+type digitalFormat struct {
+	name        string
+	contentType string
+	itemType    models.ItemType
+	magic       func(data []byte) bool
+}
+
+// Files accepted for digital items, by extension
+var digitalFormats = map[string]digitalFormat{
+	".epub": {name: "epub", contentType: "application/epub+zip", itemType: models.ItemEBook, magic: isZip},
+	".pdf":  {name: "pdf", contentType: "application/pdf", itemType: models.ItemEBook, magic: isPDF},
+	".mp3":  {name: "mp3", contentType: "audio/mpeg", itemType: models.ItemAudiobook, magic: isMP3},
+	".m4b":  {name: "m4b", contentType: "audio/mp4", itemType: models.ItemAudiobook, magic: isMP4},
+	".m4a":  {name: "m4a", contentType: "audio/mp4", itemType: models.ItemAudiobook, magic: isMP4},
+	".zip":  {name: "zip", contentType: "application/zip", itemType: models.ItemAudiobook, magic: isZip},
+}
+
+// DigitalLending configures digital loans. Download links are signed with
+// Secret and last LinkTTL, but never past the end of the loan.
+type DigitalLending struct {
+	LoanPeriod time.Duration
+	LinkTTL    time.Duration
+	Secret     string
+	MaxBytes   int
+}
+
+type DigitalService interface {
+	UploadFile(actor *models.Actor, bookID uint, fileName string, data []byte) (*models.DigitalFile, error)
+	DeleteFile(actor *models.Actor, bookID, fileID uint) error
+	Checkout(actor *models.Actor, userID, bookID uint) (*models.Borrow, error)
+	Return(actor *models.Actor, userID, borrowID uint) (*models.Borrow, error)
+	GetDownloads(userID, borrowID uint, baseURL string) ([]models.DigitalDownload, error)
+	Download(req *models.DownloadRequest) (*models.DigitalContent, error)
+}
+
+type digitalService struct {
+	fileRepo      repositories.DigitalFileRepository
+	bookRepo      repositories.BookRepository
+	borrowService BorrowService
+	store         storage.BlobStore
+	auditService  AuditService
+	lending       DigitalLending
+}
+
+func NewDigitalService(
+	fileRepo repositories.DigitalFileRepository,
+	bookRepo repositories.BookRepository,
+	borrowService BorrowService,
+	store storage.BlobStore,
+	auditService AuditService,
+	lending DigitalLending,
+) DigitalService {
+	return &digitalService{
+		fileRepo:      fileRepo,
+		bookRepo:      bookRepo,
+		borrowService: borrowService,
+		store:         store,
+		auditService:  auditService,
+		lending:       lending,
+	}
+}
+
+// UploadFile adds a file to an e-book or audiobook. The format follows
+// from the extension of fileName and must suit the item type.
+func (s *digitalService) UploadFile(actor *models.Actor, bookID uint, fileName string, data []byte) (*models.DigitalFile, error) {
+	book, err := s.getDigitalBook(bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, errors.New("file is required")
+	}
+	if len(data) > s.lending.MaxBytes {
+		return nil, fmt.Errorf("file is larger than %d MB", s.lending.MaxBytes>>20)
+	}
+	format, ok := digitalFormats[strings.ToLower(path.Ext(fileName))]
+	if !ok {
+		return nil, errors.New("unsupported file type, use epub or pdf for e-books and mp3, m4b, m4a or zip for audiobooks")
+	}
+	if format.itemType != book.ItemType {
+		return nil, fmt.Errorf("%s files cannot be added to %s items", format.name, book.ItemType)
+	}
+	if !format.magic(data) {
+		return nil, fmt.Errorf("file is not a valid %s file", format.name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), digitalStoreTimeout)
+	defer cancel()
+
+	key := fmt.Sprintf("digital/%d/%d.%s", book.ID, time.Now().UnixNano(), format.name)
+	if err := s.store.Put(ctx, key, data, format.contentType); err != nil {
+		return nil, err
+	}
+
+	file := &models.DigitalFile{
+		BookID:      book.ID,
+		Format:      format.name,
+		FileName:    path.Base(fileName),
+		ContentType: format.contentType,
+		Size:        int64(len(data)),
+		StorageKey:  key,
+	}
+	if err := s.fileRepo.Create(file); err != nil {
+		_ = s.store.Delete(ctx, key)
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "digital_file.upload", "digital_file", file.ID, nil, file)
+
+	return file, nil
+}
+
+func (s *digitalService) DeleteFile(actor *models.Actor, bookID, fileID uint) error {
+	file, err := s.getFile(fileID)
+	if err != nil {
+		return err
+	}
+	if file.BookID != bookID {
+		return errors.New("file not found")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), digitalStoreTimeout)
+	defer cancel()
+
+	if err := s.store.Delete(ctx, file.StorageKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	if err := s.fileRepo.Delete(file.ID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, "digital_file.delete", "digital_file", file.ID, file, nil)
+
+	return nil
+}
+
+// Checkout lends a license of a digital item for the loan period, under
+// the same rules as any other loan.
+func (s *digitalService) Checkout(actor *models.Actor, userID, bookID uint) (*models.Borrow, error) {
+	book, err := s.getDigitalBook(bookID)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.fileRepo.GetByBookID(book.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("book has no files")
+	}
+
+	return s.borrowService.BorrowBook(actor, &models.CreateBorrowRequest{
+		UserID:  userID,
+		BookID:  book.ID,
+		DueDate: time.Now().Add(s.lending.LoanPeriod),
+	})
+}
+
+// Return ends a reader's digital loan early.
+func (s *digitalService) Return(actor *models.Actor, userID, borrowID uint) (*models.Borrow, error) {
+	borrow, err := s.getLoan(userID, borrowID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.borrowService.ReturnBook(actor, borrow.ID, &models.ReturnBookRequest{Notes: borrow.Notes})
+}
+
+// GetDownloads signs a download link for each file of an open loan.
+func (s *digitalService) GetDownloads(userID, borrowID uint, baseURL string) ([]models.DigitalDownload, error) {
+	borrow, err := s.getLoan(userID, borrowID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if borrow.Status != models.StatusBorrowed || !borrow.DueDate.After(now) {
+		return nil, errors.New("loan has ended")
+	}
+
+	files, err := s.fileRepo.GetByBookID(borrow.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := now.Add(s.lending.LinkTTL)
+	if borrow.DueDate.Before(expiresAt) {
+		expiresAt = borrow.DueDate
+	}
+	expires := expiresAt.Unix()
+
+	downloads := make([]models.DigitalDownload, 0, len(files))
+	for _, file := range files {
+		downloads = append(downloads, models.DigitalDownload{
+			FileID:      file.ID,
+			Format:      file.Format,
+			FileName:    file.FileName,
+			ContentType: file.ContentType,
+			Size:        file.Size,
+			URL: fmt.Sprintf("%s/digital/files/%d?loan=%d&expires=%d&signature=%s",
+				baseURL, file.ID, borrow.ID, expires, s.sign(file.ID, borrow.ID, expires)),
+			ExpiresAt: time.Unix(expires, 0),
+		})
+	}
+	return downloads, nil
+}
+
+// Download checks a signed link and returns the file. Links stop working
+// when they expire or when the loan is returned, whichever comes first.
+func (s *digitalService) Download(req *models.DownloadRequest) (*models.DigitalContent, error) {
+	expected := s.sign(req.FileID, req.LoanID, req.Expires)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(req.Signature))) {
+		return nil, errors.New("download link is invalid")
+	}
+	now := time.Now()
+	if now.Unix() >= req.Expires {
+		return nil, errors.New("download link has expired")
+	}
+
+	borrow, err := s.borrowService.GetBorrowByID(req.LoanID)
+	if err != nil {
+		if err.Error() == "borrow record not found" {
+			return nil, errors.New("loan has ended")
+		}
+		return nil, err
+	}
+	if borrow.Status != models.StatusBorrowed || !borrow.DueDate.After(now) {
+		return nil, errors.New("loan has ended")
+	}
+
+	file, err := s.getFile(req.FileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.BookID != borrow.BookID {
+		return nil, errors.New("download link is invalid")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), digitalStoreTimeout)
+	defer cancel()
+
+	blob, err := s.store.Get(ctx, file.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errors.New("file not found")
+		}
+		return nil, err
+	}
+
+	return &models.DigitalContent{
+		FileName:    file.FileName,
+		ContentType: file.ContentType,
+		Data:        blob.Data,
+	}, nil
+}
+
+func (s *digitalService) sign(fileID, loanID uint, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(s.lending.Secret))
+	mac.Write([]byte(strconv.FormatUint(uint64(fileID), 10) + ":" +
+		strconv.FormatUint(uint64(loanID), 10) + ":" +
+		strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *digitalService) getDigitalBook(id uint) (*models.Book, error) {
+	book, err := s.bookRepo.GetSummary(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book not found")
+		}
+		return nil, err
+	}
+	if !book.IsDigital() {
+		return nil, errors.New("book is not a digital item")
+	}
+	return book, nil
+}
+
+func (s *digitalService) getFile(id uint) (*models.DigitalFile, error) {
+	file, err := s.fileRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("file not found")
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// getLoan returns a reader's own loan of a digital item.
+func (s *digitalService) getLoan(userID, borrowID uint) (*models.Borrow, error) {
+	borrow, err := s.borrowService.GetBorrowByID(borrowID)
+	if err != nil {
+		return nil, err
+	}
+	if borrow.UserID == nil || *borrow.UserID != userID {
+		return nil, errors.New("borrow record not found")
+	}
+	if !borrow.Book.IsDigital() {
+		return nil, errors.New("book is not a digital item")
+	}
+	return borrow, nil
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+func isPDF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+// isMP3 accepts an ID3 tag or a bare MPEG audio frame.
+func isMP3(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("ID3")) || (len(data) > 1 && data[0] == 0xFF && data[1]&0xE0 == 0xE0)
+}
+
+func isMP4(data []byte) bool {
+	return len(data) > 8 && string(data[4:8]) == "ftyp"
+}
@@ -0,0 +1,630 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/pdf"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type DonationService interface {
+	CreateDonation(actor *models.Actor, req *models.CreateDonationRequest) (*models.Donation, error)
+	GetAllDonations(spec *query.Spec) ([]models.Donation, *query.Page, error)
+	GetDonationByID(id uint) (*models.Donation, error)
+	AddItems(actor *models.Actor, id uint, req *models.AddDonationItemsRequest) (*models.Donation, error)
+	DecideItems(actor *models.Actor, id uint, req *models.DecideDonationRequest) (*models.Donation, error)
+	AcknowledgmentLetter(id uint) ([]byte, *models.Donation, error)
+	AcknowledgeDonation(actor *models.Actor, id uint) (*models.Donation, error)
+	GetDonorReport(year int) (*models.DonorReport, error)
+	DonorReportPDF(report *models.DonorReport) []byte
+	GetBookProvenance(bookID uint) ([]models.DonationItem, error)
+}
+
+type donationService struct {
+	donationRepo    repositories.DonationRepository
+	bookRepo        repositories.BookRepository
+	donorService    DonorService
+	bookService     BookService
+	holdService     HoldService
+	metadataService MetadataService
+	auditService    AuditService
+	libraryName     string
+	libraryAddress  string
+}
+
+func NewDonationService(
+	donationRepo repositories.DonationRepository,
+	bookRepo repositories.BookRepository,
+	donorService DonorService,
+	bookService BookService,
+	holdService HoldService,
+	metadataService MetadataService,
+	auditService AuditService,
+	libraryName string,
+	libraryAddress string,
+) DonationService {
+	return &donationService{
+		donationRepo:    donationRepo,
+		bookRepo:        bookRepo,
+		donorService:    donorService,
+		bookService:     bookService,
+		holdService:     holdService,
+		metadataService: metadataService,
+		auditService:    auditService,
+		libraryName:     libraryName,
+		libraryAddress:  libraryAddress,
+	}
+}
+
+// CreateDonation records a donation as it arrives, from a known donor or
+// a new one. Every item waits for a decision.
+func (s *donationService) CreateDonation(actor *models.Actor, req *models.CreateDonationRequest) (*models.Donation, error) {
+	now := time.Now()
+	receivedAt := now
+	if req.ReceivedAt != nil {
+		if req.ReceivedAt.After(now) {
+			return nil, errors.New("received_at cannot be in the future")
+		}
+		receivedAt = *req.ReceivedAt
+	}
+	items, err := s.resolveItems(req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	var donor *models.Donor
+	switch {
+	case req.DonorID != nil:
+		if donor, err = s.donorService.GetDonorByID(*req.DonorID); err != nil {
+			return nil, err
+		}
+	case req.Donor != nil:
+		if donor, err = s.donorService.CreateDonor(actor, req.Donor); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("donor_id or donor is required")
+	}
+
+	donation := &models.Donation{
+		DonorID:    donor.ID,
+		ReceivedAt: receivedAt,
+		Status:     models.DonationPending,
+		Notes:      req.Notes,
+		Items:      items,
+	}
+	if actor != nil && actor.UserID != 0 {
+		creator := actor.UserID
+		donation.CreatedBy = &creator
+	}
+
+	if err := s.donationRepo.Create(donation); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "donation.create", "donation", donation.ID, nil, donation)
+
+	return s.GetDonationByID(donation.ID)
+}
+
+func (s *donationService) GetAllDonations(spec *query.Spec) ([]models.Donation, *query.Page, error) {
+	return s.donationRepo.GetAll(spec)
+}
+
+func (s *donationService) GetDonationByID(id uint) (*models.Donation, error) {
+	donation, err := s.donationRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("donation not found")
+		}
+		return nil, err
+	}
+	return donation, nil
+}
+
+// AddItems records more items of a donation, e.g. boxes unpacked later.
+func (s *donationService) AddItems(actor *models.Actor, id uint, req *models.AddDonationItemsRequest) (*models.Donation, error) {
+	donation, err := s.GetDonationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.resolveItems(req.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(donation)
+
+	if err := s.donationRepo.AddItems(donation.ID, items); err != nil {
+		return nil, err
+	}
+	donation.Items = append(donation.Items, items...)
+	if err := s.settle(donation); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.GetDonationByID(donation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "donation.add_items", "donation", donation.ID, before, updated)
+
+	return updated, nil
+}
+
+// DecideItems accepts or discards pending items. Accepted copies are added
+// to the catalog, as a new book if the library does not have the ISBN
+// yet, and go to waiting holds first.
+func (s *donationService) DecideItems(actor *models.Actor, id uint, req *models.DecideDonationRequest) (*models.Donation, error) {
+	donation, err := s.GetDonationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Items) == 0 {
+		return nil, errors.New("at least one item is required")
+	}
+
+	// Check every decision before acting on any of them
+	items := make(map[uint]*models.DonationItem, len(donation.Items))
+	for i := range donation.Items {
+		items[donation.Items[i].ID] = &donation.Items[i]
+	}
+	decided := make(map[uint]bool, len(req.Items))
+	for _, input := range req.Items {
+		item, ok := items[input.ItemID]
+		if !ok {
+			return nil, fmt.Errorf("item %d is not in this donation", input.ItemID)
+		}
+		if decided[item.ID] {
+			return nil, fmt.Errorf("item %d is listed twice", item.ID)
+		}
+		decided[item.ID] = true
+		if item.Decision != models.ItemPending {
+			return nil, fmt.Errorf("item %d is already %s", item.ID, item.Decision)
+		}
+		switch input.Decision {
+		case models.ItemAccepted:
+			if item.ISBN == "" {
+				return nil, fmt.Errorf("item %d has no ISBN and cannot be catalogued", item.ID)
+			}
+		case models.ItemDiscarded:
+		default:
+			return nil, errors.New("decision must be accepted or discarded")
+		}
+	}
+
+	before := snapshot(donation)
+	now := time.Now()
+	var decidedBy *uint
+	if actor != nil && actor.UserID != 0 {
+		decidedBy = &actor.UserID
+	}
+
+	// Items decided before a failure stay decided. Each item is claimed
+	// before its copies are added, so a concurrent decision cannot add them
+	// twice.
+	var failed error
+	for _, input := range req.Items {
+		item := items[input.ItemID]
+		item.Decision = input.Decision
+		item.Reason = truncate(input.Reason, 255)
+		item.DecidedBy = decidedBy
+		item.DecidedAt = &now
+		if err := s.donationRepo.DecideItem(item); err != nil {
+			item.Decision, item.Reason, item.DecidedBy, item.DecidedAt = models.ItemPending, "", nil, nil
+			if errors.Is(err, repositories.ErrItemDecided) {
+				err = errors.New("item was decided meanwhile")
+			}
+			failed = fmt.Errorf("item %d: %w", item.ID, err)
+			break
+		}
+		if input.Decision != models.ItemAccepted {
+			continue
+		}
+
+		location := input.Location
+		if location == "" {
+			location = req.Location
+		}
+		book, err := s.catalogue(actor, item, location)
+		if err != nil {
+			failed = fmt.Errorf("item %d: %w", item.ID, err)
+			item.Decision, item.Reason, item.DecidedBy, item.DecidedAt = models.ItemPending, "", nil, nil
+			if err := s.donationRepo.UpdateItem(item); err != nil {
+				return nil, err
+			}
+			break
+		}
+		item.BookID = &book.ID
+		item.Book = book
+		if err := s.donationRepo.UpdateItem(item); err != nil {
+			return nil, err
+		}
+		if err := s.holdService.AllocateCopies(book.ID); err != nil {
+			log.Printf("holds: failed to allocate book %d: %v", book.ID, err)
+		}
+	}
+
+	if err := s.settle(donation); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "donation.decide", "donation", donation.ID, before, donation)
+
+	if failed != nil {
+		return nil, failed
+	}
+	return donation, nil
+}
+
+// AcknowledgmentLetter renders the thank-you letter for a donation as a
+// PDF.
+func (s *donationService) AcknowledgmentLetter(id uint) ([]byte, *models.Donation, error) {
+	donation, err := s.GetDonationByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.renderLetter(donation, time.Now()), donation, nil
+}
+
+// AcknowledgeDonation marks a donation as acknowledged, e.g. once its
+// letter was sent. The first acknowledgment is kept.
+func (s *donationService) AcknowledgeDonation(actor *models.Actor, id uint) (*models.Donation, error) {
+	donation, err := s.GetDonationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if donation.AcknowledgedAt != nil {
+		return donation, nil
+	}
+
+	before := snapshot(donation)
+	now := time.Now()
+	donation.AcknowledgedAt = &now
+	if err := s.donationRepo.Acknowledge(donation); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "donation.acknowledge", "donation", donation.ID, before, donation)
+
+	return s.GetDonationByID(donation.ID)
+}
+
+// GetDonorReport sums the donations received in a calendar year per
+// donor, most generous first.
+func (s *donationService) GetDonorReport(year int) (*models.DonorReport, error) {
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	if year < 1900 || year > 9999 {
+		return nil, errors.New("invalid year")
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	donations, err := s.donationRepo.GetReceivedBetween(from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.DonorReport{Year: year, Donors: []models.DonorReportRow{}}
+	rows := make(map[uint]int)
+	for _, donation := range donations {
+		i, ok := rows[donation.DonorID]
+		if !ok {
+			row := models.DonorReportRow{DonorID: donation.DonorID}
+			if donation.Donor != nil {
+				row.Name = donation.Donor.Name
+			}
+			report.Donors = append(report.Donors, row)
+			i = len(report.Donors) - 1
+			rows[donation.DonorID] = i
+		}
+		row := &report.Donors[i]
+		row.Donations++
+		for _, item := range donation.Items {
+			row.Items += item.Quantity
+			switch item.Decision {
+			case models.ItemAccepted:
+				row.Accepted += item.Quantity
+			case models.ItemDiscarded:
+				row.Discarded += item.Quantity
+			default:
+				row.Pending += item.Quantity
+			}
+		}
+	}
+
+	sortDonorRows(report.Donors)
+	for _, row := range report.Donors {
+		report.Total.Donations += row.Donations
+		report.Total.Items += row.Items
+		report.Total.Accepted += row.Accepted
+		report.Total.Discarded += row.Discarded
+		report.Total.Pending += row.Pending
+	}
+	return report, nil
+}
+
+// DonorReportPDF renders a donor report as a table.
+func (s *donationService) DonorReportPDF(report *models.DonorReport) []byte {
+	title := fmt.Sprintf("Donor report %d", report.Year)
+	doc := pdf.New(title)
+	doc.Text(pdf.Bold, 16, s.libraryName)
+	doc.Text(pdf.Regular, 12, title)
+	doc.Space(12)
+
+	number := pdf.Column{Width: pdf.ContentWidth * 0.12, Right: true}
+	columns := []pdf.Column{{Width: pdf.ContentWidth * 0.40}, number, number, number, number, number}
+	doc.Row(pdf.Bold, 10, columns, "Donor", "Donations", "Items", "Accepted", "Discarded", "Pending")
+	doc.Rule()
+	for _, row := range report.Donors {
+		doc.Row(pdf.Regular, 10, columns, row.Name, strconv.Itoa(row.Donations), strconv.Itoa(row.Items),
+			strconv.Itoa(row.Accepted), strconv.Itoa(row.Discarded), strconv.Itoa(row.Pending))
+	}
+	doc.Rule()
+	total := report.Total
+	doc.Row(pdf.Bold, 10, columns, "Total", strconv.Itoa(total.Donations), strconv.Itoa(total.Items),
+		strconv.Itoa(total.Accepted), strconv.Itoa(total.Discarded), strconv.Itoa(total.Pending))
+
+	return doc.Bytes()
+}
+
+// GetBookProvenance lists the donations a book's copies came from.
+func (s *donationService) GetBookProvenance(bookID uint) ([]models.DonationItem, error) {
+	if _, err := s.bookService.GetBookByID(bookID); err != nil {
+		return nil, err
+	}
+	return s.donationRepo.GetItemsByBook(bookID)
+}
+
+// resolveItems checks donated items and completes their details from the
+// catalog or the metadata provider.
+func (s *donationService) resolveItems(inputs []models.DonationItemInput) ([]models.DonationItem, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("at least one item is required")
+	}
+
+	items := make([]models.DonationItem, 0, len(inputs))
+	for i, input := range inputs {
+		n := i + 1
+		if input.Quantity < 0 {
+			return nil, fmt.Errorf("item %d: quantity cannot be negative", n)
+		}
+		switch input.Condition {
+		case "", "new", "good", "fair", "poor":
+		default:
+			return nil, fmt.Errorf("item %d: condition must be new, good, fair or poor", n)
+		}
+
+		item := models.DonationItem{
+			Title:       truncate(input.Title, 200),
+			Author:      truncate(input.Author, 100),
+			Publisher:   truncate(input.Publisher, 100),
+			Category:    truncate(input.Category, 50),
+			PublishYear: input.PublishYear,
+			Quantity:    input.Quantity,
+			Condition:   input.Condition,
+			Decision:    models.ItemPending,
+		}
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+
+		if strings.TrimSpace(input.ISBN) != "" {
+			canonical, err := isbn.Normalize(input.ISBN)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: invalid ISBN", n)
+			}
+			item.ISBN = canonical
+
+			book, err := s.bookRepo.GetByISBN(canonical)
+			switch {
+			case err == nil:
+				fillItem(&item, book.Title, book.Author, book.Publisher, book.Category, book.PublishYear)
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return nil, err
+			case item.Title == "":
+				if found, err := s.metadataService.LookupISBN(canonical); err == nil {
+					fillItem(&item, found.Title, found.Author, found.Publisher, found.Category, found.PublishYear)
+				}
+			}
+		}
+		if item.Title == "" {
+			return nil, fmt.Errorf("item %d: title is required", n)
+		}
+
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// catalogue adds an accepted item's copies to its book, cataloguing the
+// book first if the library does not have it yet.
+func (s *donationService) catalogue(actor *models.Actor, item *models.DonationItem, location string) (*models.Book, error) {
+	book, err := s.bookRepo.GetByISBN(item.ISBN)
+	switch {
+	case err == nil:
+		return s.bookService.AddStock(actor, book.ID, item.Quantity)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	req, err := s.metadataService.LookupISBN(item.ISBN)
+	if err != nil {
+		req = &models.CreateBookRequest{ISBN: item.ISBN}
+	}
+	req.Title = item.Title
+	if item.Author != "" && item.Author != req.Author {
+		req.Author = item.Author
+		req.Contributors = nil
+	}
+	if item.Publisher != "" {
+		req.Publisher = item.Publisher
+	}
+	if item.Category != "" {
+		req.Category = item.Category
+	}
+	if item.PublishYear != 0 {
+		req.PublishYear = item.PublishYear
+	}
+	req.Stock = item.Quantity
+	req.Location = location
+
+	return s.bookService.CreateBook(actor, req)
+}
+
+// settle marks a donation processed once no item waits for a decision.
+func (s *donationService) settle(donation *models.Donation) error {
+	// Settle on the items as saved, with concurrent decisions included
+	saved, err := s.donationRepo.GetByID(donation.ID)
+	if err != nil {
+		return err
+	}
+	donation.Items = saved.Items
+
+	status := models.DonationProcessed
+	for _, item := range donation.Items {
+		if item.Decision == models.ItemPending {
+			status = models.DonationPending
+			break
+		}
+	}
+	if status == donation.Status {
+		return nil
+	}
+	donation.Status = status
+	return s.donationRepo.Update(donation)
+}
+
+// renderLetter writes the thank-you letter for a donation, listing the
+// items added to the collection.
+func (s *donationService) renderLetter(donation *models.Donation, date time.Time) []byte {
+	donorName := "Donor"
+	var donorAddress string
+	if donation.Donor != nil {
+		donorName = donation.Donor.Name
+		donorAddress = donation.Donor.Address
+	}
+
+	var donated, pending, discarded int
+	var accepted []models.DonationItem
+	acceptedCopies := 0
+	for _, item := range donation.Items {
+		donated += item.Quantity
+		switch item.Decision {
+		case models.ItemAccepted:
+			accepted = append(accepted, item)
+			acceptedCopies += item.Quantity
+		case models.ItemDiscarded:
+			discarded += item.Quantity
+		default:
+			pending += item.Quantity
+		}
+	}
+
+	doc := pdf.New("Donation " + donation.Number)
+	doc.Text(pdf.Bold, 16, s.libraryName)
+	if s.libraryAddress != "" {
+		doc.Text(pdf.Regular, 9, s.libraryAddress)
+	}
+	doc.Rule()
+	doc.Space(12)
+	doc.TextRight(pdf.Regular, 11, formatLetterDate(date))
+	doc.Space(12)
+	doc.Text(pdf.Regular, 11, donorName)
+	if donorAddress != "" {
+		doc.Text(pdf.Regular, 11, donorAddress)
+	}
+	doc.Space(24)
+	doc.Text(pdf.Bold, 11, "Re: donation "+donation.Number)
+	doc.Space(12)
+	doc.Text(pdf.Regular, 11, "Dear "+donorName+",")
+	doc.Space(8)
+	doc.Text(pdf.Regular, 11, fmt.Sprintf("Thank you for your donation of %s, which we received on %s.",
+		countItems(donated), formatLetterDate(donation.ReceivedAt)))
+
+	if len(accepted) > 0 {
+		doc.Space(8)
+		doc.Text(pdf.Regular, 11, fmt.Sprintf("We are pleased to have added %s to our collection:", countItems(acceptedCopies)))
+		doc.Space(4)
+		for _, item := range accepted {
+			line := "- " + item.Title
+			if item.Author != "" {
+				line += ", " + item.Author
+			}
+			if item.Quantity > 1 {
+				line += fmt.Sprintf(" (%d copies)", item.Quantity)
+			}
+			doc.Text(pdf.Regular, 10, line)
+		}
+	}
+	if discarded > 0 {
+		doc.Space(8)
+		doc.Text(pdf.Regular, 11, fmt.Sprintf("We could not add %s to the collection, for example because we already hold enough copies.",
+			countItems(discarded)))
+	}
+	if pending > 0 {
+		doc.Space(8)
+		doc.Text(pdf.Regular, 11, fmt.Sprintf("We are still reviewing %s.", countItems(pending)))
+	}
+
+	doc.Space(8)
+	doc.Text(pdf.Regular, 11, "Your generosity helps us offer our readers more to discover.")
+	doc.Space(24)
+	doc.Text(pdf.Regular, 11, "Sincerely,")
+	doc.Space(24)
+	doc.Text(pdf.Regular, 11, s.libraryName)
+
+	return doc.Bytes()
+}
+
+// fillItem completes the details a donated item left out.
+func fillItem(item *models.DonationItem, title, author, publisher, category string, publishYear int) {
+	if item.Title == "" {
+		item.Title = truncate(title, 200)
+	}
+	if item.Author == "" {
+		item.Author = truncate(author, 100)
+	}
+	if item.Publisher == "" {
+		item.Publisher = truncate(publisher, 100)
+	}
+	if item.Category == "" {
+		item.Category = truncate(category, 50)
+	}
+	if item.PublishYear == 0 {
+		item.PublishYear = publishYear
+	}
+}
+
+// sortDonorRows orders donors by copies given, then by name.
+func sortDonorRows(rows []models.DonorReportRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Items != rows[j].Items {
+			return rows[i].Items > rows[j].Items
+		}
+		return rows[i].Name < rows[j].Name
+	})
+}
+
+func countItems(n int) string {
+	if n == 1 {
+		return "one item"
+	}
+	return fmt.Sprintf("%d items", n)
+}
+
+func formatLetterDate(t time.Time) string {
+	return t.Format("2 January 2006")
+}
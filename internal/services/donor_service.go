@@ -0,0 +1,101 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type DonorService interface {
+	CreateDonor(actor *models.Actor, req *models.DonorInput) (*models.Donor, error)
+	GetAllDonors(spec *query.Spec) ([]models.Donor, *query.Page, error)
+	GetDonorByID(id uint) (*models.Donor, error)
+	UpdateDonor(actor *models.Actor, id uint, req *models.UpdateDonorRequest) (*models.Donor, error)
+}
+
+type donorService struct {
+	donorRepo    repositories.DonorRepository
+	auditService AuditService
+}
+
+func NewDonorService(donorRepo repositories.DonorRepository, auditService AuditService) DonorService {
+	return &donorService{
+		donorRepo:    donorRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *donorService) CreateDonor(actor *models.Actor, req *models.DonorInput) (*models.Donor, error) {
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" {
+		return nil, errors.New("donor name is required")
+	}
+
+	donor := &models.Donor{
+		Name:    truncate(name, 150),
+		Email:   strings.TrimSpace(req.Email),
+		Phone:   strings.TrimSpace(req.Phone),
+		Address: strings.TrimSpace(req.Address),
+		Notes:   strings.TrimSpace(req.Notes),
+	}
+
+	if err := s.donorRepo.Create(donor); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "donor.create", "donor", donor.ID, nil, donor)
+
+	return donor, nil
+}
+
+func (s *donorService) GetAllDonors(spec *query.Spec) ([]models.Donor, *query.Page, error) {
+	return s.donorRepo.GetAll(spec)
+}
+
+func (s *donorService) GetDonorByID(id uint) (*models.Donor, error) {
+	donor, err := s.donorRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("donor not found")
+		}
+		return nil, err
+	}
+	return donor, nil
+}
+
+func (s *donorService) UpdateDonor(actor *models.Actor, id uint, req *models.UpdateDonorRequest) (*models.Donor, error) {
+	donor, err := s.GetDonorByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(donor)
+
+	if name := strings.Join(strings.Fields(req.Name), " "); name != "" {
+		donor.Name = truncate(name, 150)
+	}
+	if req.Email != nil {
+		donor.Email = strings.TrimSpace(*req.Email)
+	}
+	if req.Phone != nil {
+		donor.Phone = strings.TrimSpace(*req.Phone)
+	}
+	if req.Address != nil {
+		donor.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.Notes != nil {
+		donor.Notes = strings.TrimSpace(*req.Notes)
+	}
+
+	if err := s.donorRepo.Update(donor); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "donor.update", "donor", donor.ID, before, donor)
+
+	return donor, nil
+}
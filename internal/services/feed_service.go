@@ -0,0 +1,128 @@
+package services
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/syndication"
+)
+
+// feedSize is how many of the newest books a feed carries.
+const feedSize = 50
+
+// FeedService builds news feeds of recently added books. baseURL is the
+// API base URL, e.g. https://library.example/api/v1, that items link into.
+type FeedService interface {
+	GetNewArrivals(baseURL string) (*syndication.Feed, error)
+	GetCategoryFeed(baseURL string, classificationID uint) (*syndication.Feed, error)
+	GetAuthorFeed(baseURL string, authorID uint) (*syndication.Feed, error)
+}
+
+type feedService struct {
+	bookService           BookService
+	classificationService ClassificationService
+	authorService         AuthorService
+	libraryName           string
+}
+
+func NewFeedService(bookService BookService, classificationService ClassificationService, authorService AuthorService, libraryName string) FeedService {
+	return &feedService{
+		bookService:           bookService,
+		classificationService: classificationService,
+		authorService:         authorService,
+		libraryName:           libraryName,
+	}
+}
+
+func (s *feedService) GetNewArrivals(baseURL string) (*syndication.Feed, error) {
+	books, _, err := s.bookService.GetAllBooks(newestSpec())
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &syndication.Feed{
+		ID:          baseURL + "/feeds/new",
+		Title:       "New arrivals at " + s.libraryName,
+		Description: "Books recently added to the " + s.libraryName + " catalog",
+		Link:        baseURL + "/books?sort=-created_at",
+	}
+	return s.fill(feed, baseURL, books, time.Unix(0, 0)), nil
+}
+
+// GetCategoryFeed covers books classified under the node or anything below it.
+func (s *feedService) GetCategoryFeed(baseURL string, classificationID uint) (*syndication.Feed, error) {
+	class, err := s.classificationService.GetClassificationByID(classificationID)
+	if err != nil {
+		return nil, err
+	}
+	books, _, err := s.classificationService.GetClassificationBooks(classificationID, newestSpec())
+	if err != nil {
+		return nil, err
+	}
+
+	id := strconv.FormatUint(uint64(class.ID), 10)
+	feed := &syndication.Feed{
+		ID:          baseURL + "/feeds/categories/" + id,
+		Title:       "New in " + class.Notation + " " + class.Label,
+		Description: "Books recently added to " + class.Label + " at " + s.libraryName,
+		Link:        baseURL + "/classifications/" + id + "/books",
+	}
+	return s.fill(feed, baseURL, books, class.UpdatedAt), nil
+}
+
+func (s *feedService) GetAuthorFeed(baseURL string, authorID uint) (*syndication.Feed, error) {
+	author, err := s.authorService.GetAuthorByID(authorID)
+	if err != nil {
+		return nil, err
+	}
+	books, _, err := s.authorService.GetAuthorBooks(authorID, newestSpec())
+	if err != nil {
+		return nil, err
+	}
+
+	id := strconv.FormatUint(uint64(author.ID), 10)
+	feed := &syndication.Feed{
+		ID:          baseURL + "/feeds/authors/" + id,
+		Title:       "New books by " + author.Name,
+		Description: "Books by " + author.Name + " recently added to the " + s.libraryName + " catalog",
+		Link:        baseURL + "/authors/" + id + "/books",
+	}
+	return s.fill(feed, baseURL, books, author.UpdatedAt), nil
+}
+
+// fill adds books as items. The feed was last updated when its newest
+// item was, or at empty if it has none.
+func (s *feedService) fill(feed *syndication.Feed, baseURL string, books []models.Book, empty time.Time) *syndication.Feed {
+	feed.Author = s.libraryName
+	feed.Updated = empty
+	for _, book := range books {
+		link := baseURL + "/books/" + strconv.FormatUint(uint64(book.ID), 10)
+		item := syndication.Item{
+			ID:        link,
+			Title:     book.Title,
+			Link:      link,
+			Summary:   book.Description,
+			Authors:   splitNames(book.Author),
+			Published: book.CreatedAt,
+			Updated:   book.UpdatedAt,
+		}
+		if book.Category != "" {
+			item.Categories = []string{book.Category}
+		}
+		if len(feed.Items) == 0 || book.UpdatedAt.After(feed.Updated) {
+			feed.Updated = book.UpdatedAt
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed
+}
+
+func newestSpec() *query.Spec {
+	return &query.Spec{
+		Page:  1,
+		Limit: feedSize,
+		Sort:  []query.SortField{{Field: "created_at", Desc: true}},
+	}
+}
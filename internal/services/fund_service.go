@@ -0,0 +1,554 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// FundService keeps the books of the acquisition funds. Placing an order
+// encumbers its lines against their funds' budgets for the fiscal year,
+// invoices turn the encumbrance into spend, and cancelled copies release
+// it.
+type FundService interface {
+	CreateFund(actor *models.Actor, req *models.CreateFundRequest) (*models.Fund, error)
+	GetAllFunds(spec *query.Spec) ([]models.Fund, *query.Page, error)
+	GetFundByID(id uint) (*models.Fund, error)
+	UpdateFund(actor *models.Actor, id uint, req *models.UpdateFundRequest) (*models.Fund, error)
+	AllocateBudget(actor *models.Actor, fundID uint, fiscalYear int, req *models.AllocateBudgetRequest) (*models.Budget, error)
+	GetTransactions(fundID uint, spec *query.Spec) ([]models.FundTransaction, *query.Page, error)
+	GetBudgetReport(fiscalYear int) (*models.BudgetReport, error)
+	Rollover(actor *models.Actor, req *models.RolloverRequest) (*models.BudgetReport, error)
+	FiscalYear(t time.Time) int
+	Encumber(actor *models.Actor, order *models.PurchaseOrder) ([]models.FundTransaction, error)
+	Release(actor *models.Actor, order *models.PurchaseOrder, line *models.OrderLine, copies int, note string) ([]models.FundTransaction, error)
+	Spend(actor *models.Actor, order *models.PurchaseOrder, line *models.OrderLine, invoice *models.Invoice, copies int, amount float64) ([]models.FundTransaction, error)
+}
+
+type fundService struct {
+	fundRepo     repositories.FundRepository
+	orderRepo    repositories.PurchaseOrderRepository
+	auditService AuditService
+	fiscalStart  time.Month
+}
+
+// NewFundService creates the fund service. Fiscal years start on the first
+// day of fiscalStart and are named after the calendar year they start in.
+func NewFundService(
+	fundRepo repositories.FundRepository,
+	orderRepo repositories.PurchaseOrderRepository,
+	auditService AuditService,
+	fiscalStart time.Month,
+) FundService {
+	if fiscalStart < time.January || fiscalStart > time.December {
+		fiscalStart = time.January
+	}
+	return &fundService{
+		fundRepo:     fundRepo,
+		orderRepo:    orderRepo,
+		auditService: auditService,
+		fiscalStart:  fiscalStart,
+	}
+}
+
+func (s *fundService) CreateFund(actor *models.Actor, req *models.CreateFundRequest) (*models.Fund, error) {
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if code == "" || name == "" {
+		return nil, errors.New("code and name are required")
+	}
+	if len(code) > 20 {
+		return nil, errors.New("code must be at most 20 characters")
+	}
+	if _, err := s.fundRepo.GetByCode(code); err == nil {
+		return nil, errors.New("fund code is already in use")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	fund := &models.Fund{
+		Code:               code,
+		Name:               truncate(name, 100),
+		Description:        strings.TrimSpace(req.Description),
+		RolloverAllocation: req.RolloverAllocation,
+		RolloverBalance:    req.RolloverBalance,
+		IsActive:           true,
+	}
+	if err := s.fundRepo.Create(fund); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "fund.create", "fund", fund.ID, nil, fund)
+
+	return fund, nil
+}
+
+func (s *fundService) GetAllFunds(spec *query.Spec) ([]models.Fund, *query.Page, error) {
+	return s.fundRepo.GetAll(spec)
+}
+
+func (s *fundService) GetFundByID(id uint) (*models.Fund, error) {
+	fund, err := s.fundRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("fund not found")
+		}
+		return nil, err
+	}
+	return fund, nil
+}
+
+func (s *fundService) UpdateFund(actor *models.Actor, id uint, req *models.UpdateFundRequest) (*models.Fund, error) {
+	fund, err := s.GetFundByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(fund)
+
+	if name := strings.Join(strings.Fields(req.Name), " "); name != "" {
+		fund.Name = truncate(name, 100)
+	}
+	if req.Description != nil {
+		fund.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.RolloverAllocation != nil {
+		fund.RolloverAllocation = *req.RolloverAllocation
+	}
+	if req.RolloverBalance != nil {
+		fund.RolloverBalance = *req.RolloverBalance
+	}
+	if req.IsActive != nil {
+		fund.IsActive = *req.IsActive
+	}
+
+	if err := s.fundRepo.Update(fund); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "fund.update", "fund", fund.ID, before, fund)
+
+	return fund, nil
+}
+
+// AllocateBudget sets what a fund may spend in a fiscal year. It cannot
+// drop below what is already committed and spent.
+func (s *fundService) AllocateBudget(actor *models.Actor, fundID uint, fiscalYear int, req *models.AllocateBudgetRequest) (*models.Budget, error) {
+	fund, err := s.GetFundByID(fundID)
+	if err != nil {
+		return nil, err
+	}
+	if fiscalYear < 2000 || fiscalYear > 2100 {
+		return nil, errors.New("invalid fiscal year")
+	}
+	allocated := money(req.Allocated)
+	if allocated < 0 {
+		return nil, errors.New("allocated cannot be negative")
+	}
+
+	budget, err := s.budget(fund.ID, fiscalYear, true)
+	if err != nil {
+		return nil, err
+	}
+	if budget.Closed {
+		return nil, fmt.Errorf("budget for %d is closed", fiscalYear)
+	}
+
+	delta := money(allocated - budget.Allocated)
+	if delta == 0 {
+		return budget, nil
+	}
+	if budget.Free()+delta < 0 {
+		committed := money(budget.Allocated - budget.Free())
+		return nil, fmt.Errorf("allocation cannot be less than the %.2f already committed and spent", committed)
+	}
+
+	before := snapshot(budget)
+	note := req.Note
+	if note == "" {
+		note = "Allocation set to " + formatMoney(allocated)
+	}
+	err = s.fundRepo.Post([]models.FundTransaction{
+		s.entry(actor, budget, models.FundAllocation, delta, truncate(note, 255)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	budget, err = s.fundRepo.GetBudget(fund.ID, fiscalYear)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "budget.allocate", "budget", budget.ID, before, budget)
+
+	return budget, nil
+}
+
+func (s *fundService) GetTransactions(fundID uint, spec *query.Spec) ([]models.FundTransaction, *query.Page, error) {
+	if _, err := s.GetFundByID(fundID); err != nil {
+		return nil, nil, err
+	}
+	return s.fundRepo.GetTransactions(fundID, spec)
+}
+
+// GetBudgetReport sums every fund's budget for a fiscal year, the current
+// one when fiscalYear is 0.
+func (s *fundService) GetBudgetReport(fiscalYear int) (*models.BudgetReport, error) {
+	if fiscalYear == 0 {
+		fiscalYear = s.FiscalYear(time.Now())
+	}
+	budgets, err := s.fundRepo.GetBudgetsByYear(fiscalYear)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.BudgetReport{
+		FiscalYear: fiscalYear,
+		Funds:      make([]models.BudgetReportRow, 0, len(budgets)),
+	}
+	total := &report.Total
+	for _, budget := range budgets {
+		row := models.BudgetReportRow{
+			FundID:     budget.FundID,
+			Allocated:  budget.Allocated,
+			CarriedIn:  budget.CarriedIn,
+			CarriedOut: budget.CarriedOut,
+			Encumbered: budget.Encumbered,
+			Spent:      budget.Spent,
+			Available:  money(budget.Free()),
+			Closed:     budget.Closed,
+		}
+		if budget.Fund != nil {
+			row.Code = budget.Fund.Code
+			row.Name = budget.Fund.Name
+		}
+		row.SpentPercent = spentPercent(row)
+		report.Funds = append(report.Funds, row)
+
+		total.Allocated += row.Allocated
+		total.CarriedIn += row.CarriedIn
+		total.CarriedOut += row.CarriedOut
+		total.Encumbered += row.Encumbered
+		total.Spent += row.Spent
+		total.Available += row.Available
+	}
+	total.Allocated = money(total.Allocated)
+	total.CarriedIn = money(total.CarriedIn)
+	total.CarriedOut = money(total.CarriedOut)
+	total.Encumbered = money(total.Encumbered)
+	total.Spent = money(total.Spent)
+	total.Available = money(total.Available)
+	total.SpentPercent = spentPercent(*total)
+
+	return report, nil
+}
+
+// Rollover closes the budgets of a fiscal year that has ended. Orders
+// still open take their encumbrances into the next year, and each fund's
+// rollover rules decide whether next year starts with the same allocation
+// and with this year's unspent balance.
+func (s *fundService) Rollover(actor *models.Actor, req *models.RolloverRequest) (*models.BudgetReport, error) {
+	year := req.FiscalYear
+	if year == 0 {
+		return nil, errors.New("fiscal_year is required")
+	}
+	if year >= s.FiscalYear(time.Now()) {
+		return nil, fmt.Errorf("fiscal year %d has not ended yet", year)
+	}
+
+	budgets, err := s.fundRepo.GetBudgetsByYear(year)
+	if err != nil {
+		return nil, err
+	}
+	rolled := 0
+	for i := range budgets {
+		if budgets[i].Closed {
+			continue
+		}
+		if err := s.rollover(actor, &budgets[i]); err != nil {
+			return nil, fmt.Errorf("fund %d: %w", budgets[i].FundID, err)
+		}
+		rolled++
+	}
+	if rolled == 0 {
+		return nil, fmt.Errorf("no open budgets for fiscal year %d", year)
+	}
+
+	return s.GetBudgetReport(year + 1)
+}
+
+func (s *fundService) rollover(actor *models.Actor, budget *models.Budget) error {
+	fund := budget.Fund
+	if fund == nil {
+		return errors.New("fund not found")
+	}
+
+	next, err := s.budget(budget.FundID, budget.FiscalYear+1, true)
+	if err != nil {
+		return err
+	}
+
+	from := fmt.Sprintf("Rolled over from %d", budget.FiscalYear)
+	var entries []models.FundTransaction
+	// A budget already allocated for next year keeps its own figure; one
+	// left empty by an earlier, failed rollover still gets this year's
+	if next.Allocated == 0 && fund.RolloverAllocation && budget.Allocated > 0 {
+		entries = append(entries, s.entry(actor, next, models.FundAllocation, budget.Allocated, from))
+	}
+
+	// Open orders keep their commitment in the new year
+	lines, err := s.orderRepo.GetOpenLines(budget.ID)
+	if err != nil {
+		return err
+	}
+	moved := 0.0
+	for _, line := range lines {
+		if line.Encumbered <= 0 {
+			continue
+		}
+		release := s.entry(actor, budget, models.FundRelease, line.Encumbered, fmt.Sprintf("Rolled over to %d", next.FiscalYear))
+		encumbrance := s.entry(actor, next, models.FundEncumbrance, line.Encumbered, from)
+		for _, entry := range []*models.FundTransaction{&release, &encumbrance} {
+			entry.OrderID = &line.OrderID
+			entry.OrderLineID = &line.ID
+		}
+		entries = append(entries, release, encumbrance)
+		moved += line.Encumbered
+	}
+
+	if fund.RolloverBalance {
+		if balance := money(budget.Free() + moved); balance > 0 {
+			entries = append(entries,
+				s.entry(actor, budget, models.FundCarryOut, balance, fmt.Sprintf("Carried to %d", next.FiscalYear)),
+				s.entry(actor, next, models.FundCarryIn, balance, fmt.Sprintf("Carried from %d", budget.FiscalYear)))
+		}
+	}
+
+	if err := s.fundRepo.Rollover(budget, next, lines, entries); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrBudgetClosed):
+			return errors.New("budget was rolled over meanwhile")
+		case errors.Is(err, repositories.ErrLineChanged):
+			return errors.New("an open order changed during the rollover, try again")
+		case errors.Is(err, repositories.ErrBudgetExceeded):
+			return errors.New("next year's budget is closed")
+		}
+		return err
+	}
+
+	s.auditService.Record(actor, "budget.rollover", "budget", budget.ID, budget, map[string]interface{}{
+		"next_budget_id":      next.ID,
+		"encumbrances_moved":  money(moved),
+		"open_lines_moved":    len(lines),
+		"rollover_allocation": fund.RolloverAllocation,
+		"rollover_balance":    fund.RolloverBalance,
+	})
+
+	return nil
+}
+
+func (s *fundService) FiscalYear(t time.Time) int {
+	if t.Month() >= s.fiscalStart {
+		return t.Year()
+	}
+	return t.Year() - 1
+}
+
+// Encumber works out the entries committing the money of an order being
+// placed against the budgets of its lines' funds for the fiscal year of the
+// order date, and sets the lines' budgets and encumbrances. It fails unless
+// every fund can afford its share. The caller posts the entries with the
+// order.
+func (s *fundService) Encumber(actor *models.Actor, order *models.PurchaseOrder) ([]models.FundTransaction, error) {
+	orderedAt := time.Now()
+	if order.OrderedAt != nil {
+		orderedAt = *order.OrderedAt
+	}
+	year := s.FiscalYear(orderedAt)
+
+	budgets := make(map[uint]*models.Budget)
+	needs := make(map[uint]float64)
+	funds := make(map[uint]*models.Fund)
+	for _, line := range order.Lines {
+		if line.FundID == nil {
+			continue
+		}
+		fundID := *line.FundID
+		if _, ok := budgets[fundID]; !ok {
+			fund, err := s.GetFundByID(fundID)
+			if err != nil {
+				return nil, err
+			}
+			if !fund.IsActive {
+				return nil, fmt.Errorf("fund %s is not active", fund.Code)
+			}
+			budget, err := s.budget(fundID, year, false)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("fund %s has no budget for %d", fund.Code, year)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if budget.Closed {
+				return nil, fmt.Errorf("budget of fund %s for %d is closed", fund.Code, year)
+			}
+			funds[fundID], budgets[fundID] = fund, budget
+		}
+		needs[fundID] += float64(line.Quantity) * line.UnitPrice
+	}
+
+	for fundID, need := range needs {
+		if free := budgets[fundID].Free(); money(need) > money(free) {
+			return nil, fmt.Errorf("fund %s has %s left for %d, the order needs %s",
+				funds[fundID].Code, formatMoney(free), year, formatMoney(need))
+		}
+	}
+
+	var entries []models.FundTransaction
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if line.FundID == nil {
+			continue
+		}
+		budget := budgets[*line.FundID]
+		line.BudgetID = &budget.ID
+		line.Encumbered = money(float64(line.Quantity) * line.UnitPrice)
+		if line.Encumbered > 0 {
+			entries = append(entries, s.lineEntry(actor, budget, order, line, models.FundEncumbrance, line.Encumbered, "Order "+order.Number))
+		}
+	}
+	return entries, nil
+}
+
+// Release works out the entries giving back the encumbrance of copies
+// that will not be billed, and takes it off the line. Once nothing is left
+// to bill, the rest of the line's encumbrance goes. The caller posts the
+// entries with the cancellation.
+func (s *fundService) Release(actor *models.Actor, order *models.PurchaseOrder, line *models.OrderLine, copies int, note string) ([]models.FundTransaction, error) {
+	entries, err := s.releaseEntries(actor, order, line, copies, note)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	line.Encumbered = money(line.Encumbered - entries[0].Amount)
+	return entries, nil
+}
+
+// Spend works out the entries charging an invoiced amount to the line's
+// budget and releasing the encumbrance of the copies billed, and takes the
+// release off the line. The caller posts the entries with the invoice.
+func (s *fundService) Spend(actor *models.Actor, order *models.PurchaseOrder, line *models.OrderLine, invoice *models.Invoice, copies int, amount float64) ([]models.FundTransaction, error) {
+	if line.BudgetID == nil {
+		return nil, nil
+	}
+	note := "Invoice " + invoice.Number
+	entries, err := s.releaseEntries(actor, order, line, copies, note)
+	if err != nil {
+		return nil, err
+	}
+	released := 0.0
+	if len(entries) > 0 {
+		released = entries[0].Amount
+	}
+
+	if amount = money(amount); amount != 0 {
+		budget, err := s.budgetByID(*line.BudgetID)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, s.lineEntry(actor, budget, order, line, models.FundExpenditure, amount, note))
+	}
+
+	line.Encumbered = money(line.Encumbered - released)
+	return entries, nil
+}
+
+func (s *fundService) releaseEntries(actor *models.Actor, order *models.PurchaseOrder, line *models.OrderLine, copies int, note string) ([]models.FundTransaction, error) {
+	if line.BudgetID == nil || line.Encumbered <= 0 {
+		return nil, nil
+	}
+	amount := money(float64(copies) * line.UnitPrice)
+	if line.Uninvoiced() == 0 || amount > line.Encumbered {
+		amount = line.Encumbered
+	}
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	budget, err := s.budgetByID(*line.BudgetID)
+	if err != nil {
+		return nil, err
+	}
+	return []models.FundTransaction{s.lineEntry(actor, budget, order, line, models.FundRelease, amount, note)}, nil
+}
+
+// budget finds a fund's budget for a year, creating an empty one if asked.
+func (s *fundService) budget(fundID uint, fiscalYear int, create bool) (*models.Budget, error) {
+	budget, err := s.fundRepo.GetBudget(fundID, fiscalYear)
+	if err == nil || !create || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return budget, err
+	}
+	budget = &models.Budget{FundID: fundID, FiscalYear: fiscalYear}
+	if err := s.fundRepo.CreateBudget(budget); err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *fundService) budgetByID(id uint) (*models.Budget, error) {
+	budget, err := s.fundRepo.GetBudgetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("budget not found")
+		}
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (s *fundService) entry(actor *models.Actor, budget *models.Budget, kind models.FundTransactionType, amount float64, note string) models.FundTransaction {
+	entry := models.FundTransaction{
+		FundID:     budget.FundID,
+		BudgetID:   budget.ID,
+		FiscalYear: budget.FiscalYear,
+		Type:       kind,
+		Amount:     money(amount),
+		Note:       note,
+	}
+	if actor != nil && actor.UserID != 0 {
+		userID := actor.UserID
+		entry.CreatedBy = &userID
+	}
+	return entry
+}
+
+func (s *fundService) lineEntry(actor *models.Actor, budget *models.Budget, order *models.PurchaseOrder, line *models.OrderLine, kind models.FundTransactionType, amount float64, note string) models.FundTransaction {
+	entry := s.entry(actor, budget, kind, amount, note)
+	entry.OrderID = &order.ID
+	entry.OrderLineID = &line.ID
+	return entry
+}
+
+// money rounds an amount to whole cents.
+func money(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func formatMoney(amount float64) string {
+	return fmt.Sprintf("%.2f", money(amount))
+}
+
+func spentPercent(row models.BudgetReportRow) float64 {
+	funded := row.Allocated + row.CarriedIn
+	if funded <= 0 {
+		return 0
+	}
+	return math.Round(row.Spent/funded*1000) / 10
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type HoldService interface {
+	PlaceHold(actor *models.Actor, req *models.PlaceHoldRequest) (*models.Hold, error)
+	GetAllHolds(spec *query.Spec) ([]models.Hold, *query.Page, error)
+	GetHoldByID(id uint) (*models.Hold, error)
+	GetHoldsByUser(userID uint, spec *query.Spec) ([]models.Hold, *query.Page, error)
+	CancelHold(actor *models.Actor, id uint) (*models.Hold, error)
+	CancelUserHolds(actor *models.Actor, userID uint) error
+	HasReadyHold(userID, bookID uint) (bool, error)
+	FulfillHold(actor *models.Actor, userID uint, book *models.Book) error
+	AllocateCopies(bookID uint) error
+	ProcessHolds() error
+}
+
+type holdService struct {
+	holdRepo     repositories.HoldRepository
+	bookRepo     repositories.BookRepository
+	workRepo     repositories.WorkRepository
+	userRepo     repositories.UserRepository
+	auditService AuditService
+	pickupPeriod time.Duration
+}
+
+func NewHoldService(
+	holdRepo repositories.HoldRepository,
+	bookRepo repositories.BookRepository,
+	workRepo repositories.WorkRepository,
+	userRepo repositories.UserRepository,
+	auditService AuditService,
+	pickupPeriod time.Duration,
+) HoldService {
+	return &holdService{
+		holdRepo:     holdRepo,
+		bookRepo:     bookRepo,
+		workRepo:     workRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+		pickupPeriod: pickupPeriod,
+	}
+}
+
+// PlaceHold queues a user for a specific edition, or with work_id or
+// any_edition for whichever edition of the work comes back first. A copy
+// already on the shelf is set aside straight away.
+func (s *holdService) PlaceHold(actor *models.Actor, req *models.PlaceHoldRequest) (*models.Hold, error) {
+	user, err := s.userRepo.GetByID(req.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	userID := user.ID
+	hold := &models.Hold{
+		UserID: &userID,
+		Status: models.HoldWaiting,
+	}
+
+	switch {
+	case req.BookID != 0:
+		book, err := s.bookRepo.GetByID(req.BookID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("book not found")
+			}
+			return nil, err
+		}
+		if !book.IsActive {
+			return nil, errors.New("book is not active")
+		}
+		if book.WorkID == nil {
+			return nil, errors.New("book belongs to no work")
+		}
+		hold.WorkID = *book.WorkID
+		if !req.AnyEdition {
+			hold.BookID = &book.ID
+		}
+	case req.WorkID != 0:
+		work, err := s.workRepo.GetByID(req.WorkID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("work not found")
+			}
+			return nil, err
+		}
+		if len(work.Editions) == 0 {
+			return nil, errors.New("work has no editions to hold")
+		}
+		hold.WorkID = work.ID
+	default:
+		return nil, errors.New("book_id or work_id is required")
+	}
+
+	active, err := s.holdRepo.GetActive(userID, hold.WorkID)
+	if err == nil && active != nil {
+		return nil, errors.New("user already has an active hold on this work")
+	}
+
+	if err := s.holdRepo.Create(hold); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "hold.create", "hold", hold.ID, nil, hold)
+
+	// Older holds come first for copies on the shelf
+	if err := s.allocateWaiting(); err != nil {
+		return nil, err
+	}
+
+	return s.GetHoldByID(hold.ID)
+}
+
+func (s *holdService) GetAllHolds(spec *query.Spec) ([]models.Hold, *query.Page, error) {
+	return s.holdRepo.GetAll(spec)
+}
+
+func (s *holdService) GetHoldByID(id uint) (*models.Hold, error) {
+	hold, err := s.holdRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("hold not found")
+		}
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (s *holdService) GetHoldsByUser(userID uint, spec *query.Spec) ([]models.Hold, *query.Page, error) {
+	if _, err := s.userRepo.GetByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("user not found")
+		}
+		return nil, nil, err
+	}
+	return s.holdRepo.GetByUserID(userID, spec)
+}
+
+// CancelHold cancels a waiting or ready hold. Members may only cancel their
+// own holds. A copy set aside for the hold goes to the next hold in line.
+func (s *holdService) CancelHold(actor *models.Actor, id uint) (*models.Hold, error) {
+	hold, err := s.GetHoldByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if actor != nil && actor.Role == "member" && (hold.UserID == nil || *hold.UserID != actor.UserID) {
+		return nil, errors.New("hold not found")
+	}
+	if hold.Status != models.HoldWaiting && hold.Status != models.HoldReady {
+		return nil, errors.New("only waiting or ready holds can be cancelled")
+	}
+
+	if err := s.close(actor, hold, models.HoldCancelled, "hold.cancel"); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// CancelUserHolds cancels every active hold of a user, e.g. when the
+// account is closed.
+func (s *holdService) CancelUserHolds(actor *models.Actor, userID uint) error {
+	holds, err := s.holdRepo.GetActiveByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for i := range holds {
+		if err := s.close(actor, &holds[i], models.HoldCancelled, "hold.cancel"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HasReadyHold reports whether a copy of a book is set aside for a user,
+// in which case lending it to them takes that copy instead of one from
+// the shelf.
+func (s *holdService) HasReadyHold(userID, bookID uint) (bool, error) {
+	_, err := s.holdRepo.GetReady(userID, bookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FulfillHold closes the hold that a loan of book to a user satisfies: the
+// one the copy was set aside for, or a waiting hold on that edition or on
+// any edition of its work.
+func (s *holdService) FulfillHold(actor *models.Actor, userID uint, book *models.Book) error {
+	hold, err := s.holdRepo.GetReady(userID, book.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if book.WorkID == nil {
+			return nil
+		}
+		hold, err = s.holdRepo.GetActive(userID, *book.WorkID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// A hold on another edition, or with a copy waiting elsewhere,
+		// stays open
+		if hold.Status != models.HoldWaiting || (hold.BookID != nil && *hold.BookID != book.ID) {
+			return nil
+		}
+	} else if err != nil {
+		return err
+	}
+
+	before := snapshot(hold)
+	now := time.Now()
+	hold.Status = models.HoldFulfilled
+	hold.ClosedAt = &now
+	if err := s.holdRepo.Update(hold); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, "hold.fulfill", "hold", hold.ID, before, hold)
+	return nil
+}
+
+// AllocateCopies sets copies of a book on the shelf aside for the oldest
+// holds they can satisfy, e.g. after a copy was returned.
+func (s *holdService) AllocateCopies(bookID uint) error {
+	for {
+		book, err := s.bookRepo.GetByID(bookID)
+		if err != nil {
+			return err
+		}
+		if !book.IsActive || book.Available <= 0 {
+			return nil
+		}
+
+		hold, err := s.holdRepo.NextWaiting(book)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		assigned, err := s.assign(hold, book.ID)
+		if err != nil || !assigned {
+			return err
+		}
+	}
+}
+
+// ProcessHolds expires ready holds that were not picked up in time and
+// sets copies on the shelf aside for waiting holds, oldest first.
+func (s *holdService) ProcessHolds() error {
+	expired, err := s.holdRepo.GetExpired(time.Now())
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		if err := s.close(nil, &expired[i], models.HoldExpired, "hold.expire"); err != nil {
+			return err
+		}
+	}
+
+	return s.allocateWaiting()
+}
+
+// allocateWaiting goes through the waiting holds, oldest first, and sets
+// aside a copy for each one that has a matching edition on the shelf.
+func (s *holdService) allocateWaiting() error {
+	holds, err := s.holdRepo.GetWaiting()
+	if err != nil {
+		return err
+	}
+
+	for i := range holds {
+		hold := &holds[i]
+		var candidates []models.Book
+		if hold.BookID != nil {
+			book, err := s.bookRepo.GetByID(*hold.BookID)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			if book.IsActive && book.Available > 0 {
+				candidates = append(candidates, *book)
+			}
+		} else {
+			candidates, err = s.bookRepo.GetAvailableEditions(hold.WorkID)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, book := range candidates {
+			assigned, err := s.assign(hold, book.ID)
+			if err != nil {
+				return err
+			}
+			if assigned {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// assign sets a copy of a book aside for a hold. It reports false when the
+// copy was taken in the meantime.
+func (s *holdService) assign(hold *models.Hold, bookID uint) (bool, error) {
+	before := snapshot(hold)
+	err := s.holdRepo.Assign(hold, bookID, time.Now().Add(s.pickupPeriod))
+	if errors.Is(err, repositories.ErrNoCopy) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.auditService.Record(nil, "hold.ready", "hold", hold.ID, before, hold)
+	return true, nil
+}
+
+// close ends a hold with status. A copy set aside for it goes back on the
+// shelf and on to the next hold in line.
+func (s *holdService) close(actor *models.Actor, hold *models.Hold, status models.HoldStatus, action string) error {
+	before := snapshot(hold)
+	wasReady := hold.Status == models.HoldReady
+	now := time.Now()
+	hold.Status = status
+	hold.ClosedAt = &now
+
+	if wasReady {
+		if err := s.holdRepo.Release(hold); err != nil {
+			return err
+		}
+	} else if err := s.holdRepo.Update(hold); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, action, "hold", hold.ID, before, hold)
+
+	if wasReady && hold.AssignedBookID != nil {
+		if err := s.AllocateCopies(*hold.AssignedBookID); err != nil {
+			log.Printf("holds: failed to allocate book %d: %v", *hold.AssignedBookID, err)
+		}
+	}
+	return nil
+}
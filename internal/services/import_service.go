@@ -0,0 +1,444 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/spreadsheet"
+	"github.com/yooerizkilab/library-system/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Rows processed between progress updates
+const importProgressInterval = 100
+
+// importFields are the CreateBookRequest fields an import column can map to.
+var importFields = []string{
+	"title", "author", "isbn", "publisher", "category", "language",
+	"pages", "publish_year", "stock", "description", "location",
+	"class_number", "call_number", "subjects",
+}
+
+type ImportService interface {
+	StartImport(actor *models.Actor, req *models.ImportRequest) (*models.ImportJob, error)
+	CommitImport(actor *models.Actor, id uint) (*models.ImportJob, error)
+	GetImportJob(id uint) (*models.ImportJob, error)
+	GetImportJobs(spec *query.Spec) ([]models.ImportJob, *query.Page, error)
+	FailInterrupted() error
+}
+
+type importService struct {
+	importRepo   repositories.ImportRepository
+	bookRepo     repositories.BookRepository
+	bookService  BookService
+	auditService AuditService
+}
+
+func NewImportService(
+	importRepo repositories.ImportRepository,
+	bookRepo repositories.BookRepository,
+	bookService BookService,
+	auditService AuditService,
+) ImportService {
+	return &importService{
+		importRepo:   importRepo,
+		bookRepo:     bookRepo,
+		bookService:  bookService,
+		auditService: auditService,
+	}
+}
+
+// StartImport checks the file and column mapping, then validates every row
+// in the background as a dry run.
+func (s *importService) StartImport(actor *models.Actor, req *models.ImportRequest) (*models.ImportJob, error) {
+	if len(req.Data) == 0 {
+		return nil, errors.New("import file is empty")
+	}
+	if req.OnDuplicate == "" {
+		req.OnDuplicate = models.DuplicateMerge
+	}
+	if req.OnDuplicate != models.DuplicateMerge && req.OnDuplicate != models.DuplicateSkip {
+		return nil, errors.New("on_duplicate must be merge or skip")
+	}
+
+	format, err := spreadsheet.DetectFormat(req.FileName, req.Data)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := spreadsheet.Read(format, req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read import file: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("import file has no header row")
+	}
+	if _, err := importColumns(rows[0], req.Mapping); err != nil {
+		return nil, err
+	}
+
+	job := &models.ImportJob{
+		CreatedBy:   actor.UserID,
+		FileName:    req.FileName,
+		Format:      format,
+		Mapping:     req.Mapping,
+		OnDuplicate: req.OnDuplicate,
+		DryRun:      true,
+		Status:      models.ImportPending,
+		TotalRows:   countDataRows(rows),
+		Data:        req.Data,
+	}
+	if err := s.importRepo.Create(job); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "import.create", "import", job.ID, nil, job)
+
+	// The background run works on its own copy of the job
+	running := *job
+	go s.run(actor, &running, rows)
+
+	return job, nil
+}
+
+// CommitImport runs a finished dry run for real.
+func (s *importService) CommitImport(actor *models.Actor, id uint) (*models.ImportJob, error) {
+	job, err := s.GetImportJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if !job.DryRun || job.Status != models.ImportCompleted {
+		return nil, errors.New("only a completed dry run can be committed")
+	}
+
+	rows, err := spreadsheet.Read(job.Format, job.Data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read import file: %w", err)
+	}
+
+	before := snapshot(job)
+	job.DryRun = false
+	job.Status = models.ImportPending
+	job.Report = nil
+	job.StartedAt = nil
+	job.FinishedAt = nil
+	resetImportCounters(job)
+	if err := s.importRepo.Commit(job); err != nil {
+		if errors.Is(err, repositories.ErrNotDryRun) {
+			return nil, errors.New("only a completed dry run can be committed")
+		}
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "import.commit", "import", job.ID, before, job)
+
+	// The background run works on its own copy of the job
+	running := *job
+	go s.run(actor, &running, rows)
+
+	return job, nil
+}
+
+func (s *importService) GetImportJob(id uint) (*models.ImportJob, error) {
+	job, err := s.importRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("import job not found")
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *importService) GetImportJobs(spec *query.Spec) ([]models.ImportJob, *query.Page, error) {
+	return s.importRepo.GetAll(spec)
+}
+
+// FailInterrupted marks jobs left unfinished by a previous server process.
+func (s *importService) FailInterrupted() error {
+	return s.importRepo.FailInterrupted()
+}
+
+func (s *importService) run(actor *models.Actor, job *models.ImportJob, rows [][]string) {
+	now := time.Now()
+	job.Status = models.ImportRunning
+	job.StartedAt = &now
+	if err := s.importRepo.Update(job); err != nil {
+		log.Printf("import %d: failed to start: %v", job.ID, err)
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			job.Status = models.ImportFailed
+			job.Error = fmt.Sprint(r)
+		}
+		finished := time.Now()
+		job.FinishedAt = &finished
+		if !job.DryRun {
+			job.Data = nil
+		}
+		if err := s.importRepo.Update(job); err != nil {
+			log.Printf("import %d: failed to save result: %v", job.ID, err)
+		}
+	}()
+
+	columns, err := importColumns(rows[0], job.Mapping)
+	if err != nil {
+		job.Status = models.ImportFailed
+		job.Error = err.Error()
+		return
+	}
+
+	// ISBNs earlier in the file count as duplicates too
+	seen := make(map[string]uint)
+	for i, row := range rows[1:] {
+		if isEmptyRow(row) {
+			continue
+		}
+
+		result := s.importRow(actor, job, importRowRequest(row, columns), seen)
+		result.Row = i + 2
+		job.Report = append(job.Report, result)
+		countImportRow(job, result.Status)
+
+		if job.ProcessedRows%importProgressInterval == 0 {
+			if err := s.importRepo.UpdateProgress(job); err != nil {
+				log.Printf("import %d: failed to save progress: %v", job.ID, err)
+			}
+		}
+	}
+
+	job.Status = models.ImportCompleted
+}
+
+func (s *importService) importRow(actor *models.Actor, job *models.ImportJob, parsed importRow, seen map[string]uint) models.ImportRowResult {
+	req := parsed.request
+	result := models.ImportRowResult{ISBN: req.ISBN, Errors: parsed.errors}
+
+	// Duplicates are found by canonical ISBN, whatever form the file uses
+	if req.ISBN != "" {
+		canonical, err := isbn.Normalize(req.ISBN)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			req.ISBN = canonical
+			result.ISBN = canonical
+		}
+	}
+
+	// Same defaults as CreateBook
+	if req.Language == "" {
+		req.Language = "Indonesian"
+	}
+	if req.Stock == 0 {
+		req.Stock = 1
+	}
+	result.Errors = append(result.Errors, utils.ValidateStruct(req)...)
+	if len(result.Errors) > 0 {
+		result.Status = models.RowInvalid
+		return result
+	}
+
+	existingID, duplicate := seen[req.ISBN]
+	if !duplicate {
+		if existing, err := s.bookRepo.GetByISBN(req.ISBN); err == nil {
+			existingID, duplicate = existing.ID, true
+		}
+	}
+
+	switch {
+	case duplicate && job.OnDuplicate == models.DuplicateSkip:
+		result.Status = models.RowSkipped
+		result.BookID = existingID
+	case duplicate:
+		result.Status = models.RowMerged
+		result.BookID = existingID
+		if !job.DryRun {
+			if _, err := s.bookService.AddStock(actor, existingID, req.Stock); err != nil {
+				result.Status = models.RowFailed
+				result.Errors = []string{err.Error()}
+			}
+		}
+	default:
+		result.Status = models.RowCreated
+		if !job.DryRun {
+			book, err := s.bookService.CreateBook(actor, req)
+			if err != nil {
+				result.Status = models.RowFailed
+				result.Errors = []string{err.Error()}
+				return result
+			}
+			result.BookID = book.ID
+		}
+		seen[req.ISBN] = result.BookID
+	}
+
+	return result
+}
+
+// importColumns resolves each book field to a column of the header row.
+func importColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := positions[key]; !ok && key != "" {
+			positions[key] = i
+		}
+	}
+
+	for field := range mapping {
+		if !isImportField(field) {
+			return nil, fmt.Errorf("unknown book field %q in mapping", field)
+		}
+	}
+
+	columns := make(map[string]int)
+	for _, field := range importFields {
+		column, mapped := mapping[field]
+		if !mapped {
+			column = field
+		}
+		position, ok := positions[strings.ToLower(strings.TrimSpace(column))]
+		if !ok {
+			if mapped {
+				return nil, fmt.Errorf("column %q mapped to %s not found", column, field)
+			}
+			continue
+		}
+		columns[field] = position
+	}
+
+	for _, field := range []string{"title", "author", "isbn", "category"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("no column for required field %s", field)
+		}
+	}
+	return columns, nil
+}
+
+func isImportField(field string) bool {
+	for _, f := range importFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+type importRow struct {
+	request *models.CreateBookRequest
+	errors  []string
+}
+
+func importRowRequest(row []string, columns map[string]int) importRow {
+	parsed := importRow{request: &models.CreateBookRequest{}}
+	req := parsed.request
+
+	for field, position := range columns {
+		if position >= len(row) {
+			continue
+		}
+		value := strings.TrimSpace(row[position])
+
+		number := func() int {
+			if value == "" {
+				return 0
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				parsed.errors = append(parsed.errors, field+" must be a number")
+			}
+			return n
+		}
+
+		switch field {
+		case "title":
+			req.Title = value
+		case "author":
+			req.Author = value
+		case "isbn":
+			req.ISBN = value
+		case "publisher":
+			req.Publisher = value
+		case "category":
+			req.Category = value
+		case "language":
+			req.Language = value
+		case "pages":
+			req.Pages = number()
+		case "publish_year":
+			req.PublishYear = number()
+		case "stock":
+			req.Stock = number()
+		case "description":
+			req.Description = value
+		case "location":
+			req.Location = value
+		case "class_number":
+			req.ClassNumber = value
+		case "call_number":
+			req.CallNumber = value
+		case "subjects":
+			// Several headings are separated by semicolons
+			for _, heading := range strings.Split(value, ";") {
+				if heading = strings.TrimSpace(heading); heading != "" {
+					req.Subjects = append(req.Subjects, heading)
+				}
+			}
+		}
+	}
+	return parsed
+}
+
+func countDataRows(rows [][]string) int {
+	count := 0
+	for _, row := range rows[1:] {
+		if !isEmptyRow(row) {
+			count++
+		}
+	}
+	return count
+}
+
+func isEmptyRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func countImportRow(job *models.ImportJob, status models.ImportRowStatus) {
+	job.ProcessedRows++
+	switch status {
+	case models.RowCreated:
+		job.Created++
+	case models.RowMerged:
+		job.Merged++
+	case models.RowSkipped:
+		job.Skipped++
+	case models.RowInvalid:
+		job.Invalid++
+	case models.RowFailed:
+		job.Failed++
+	}
+}
+
+func resetImportCounters(job *models.ImportJob) {
+	job.ProcessedRows = 0
+	job.Created = 0
+	job.Merged = 0
+	job.Skipped = 0
+	job.Invalid = 0
+	job.Failed = 0
+	job.Error = ""
+}
@@ -0,0 +1,502 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/marc"
+	"github.com/yooerizkilab/library-system/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// MARC 21 language codes for the languages we catalog in
+var marcLanguageCodes = map[string]string{
+	"Indonesian": "ind",
+	"English":    "eng",
+	"Malay":      "may",
+	"Javanese":   "jav",
+	"Sundanese":  "sun",
+	"Arabic":     "ara",
+	"Dutch":      "dut",
+	"Chinese":    "chi",
+	"Japanese":   "jpn",
+	"Korean":     "kor",
+	"French":     "fre",
+	"German":     "ger",
+}
+
+// MARC relator codes ($4) and common relator terms ($e) for contributor
+// roles; anything else is read as author
+var marcRelators = map[string]models.ContributorRole{
+	"aut": models.RoleAuthor, "author": models.RoleAuthor, "penulis": models.RoleAuthor,
+	"edt": models.RoleEditor, "editor": models.RoleEditor, "ed": models.RoleEditor, "penyunting": models.RoleEditor,
+	"trl": models.RoleTranslator, "translator": models.RoleTranslator, "tr": models.RoleTranslator, "penerjemah": models.RoleTranslator,
+	"ill": models.RoleIllustrator, "illustrator": models.RoleIllustrator, "ilustrator": models.RoleIllustrator,
+}
+
+var (
+	yearPattern   = regexp.MustCompile(`(?:^|\D)(1\d{3}|20\d{2}|2100)(?:\D|$)`)
+	numberPattern = regexp.MustCompile(`\d+`)
+	// The ISBN at the start of an 020 $a, before qualifiers such as "(pbk.)"
+	marcISBNPattern = regexp.MustCompile(`^\d[\d-]*(?: \d[\d-]*)*[Xx]?`)
+)
+
+type MARCService interface {
+	ImportRecords(actor *models.Actor, data []byte) (*models.RecordImportResult, error)
+	ExportBook(id uint, format string) ([]byte, error)
+	ExportCatalog(w io.Writer, format string) error
+}
+
+type marcService struct {
+	bookRepo    repositories.BookRepository
+	bookService BookService
+}
+
+func NewMARCService(bookRepo repositories.BookRepository, bookService BookService) MARCService {
+	return &marcService{
+		bookRepo:    bookRepo,
+		bookService: bookService,
+	}
+}
+
+// ImportRecords creates a book for every MARC21 or MARCXML record with a new
+// ISBN and updates the book that has it otherwise.
+func (s *marcService) ImportRecords(actor *models.Actor, data []byte) (*models.RecordImportResult, error) {
+	records, err := marc.ReadAll(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read MARC records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no MARC records found")
+	}
+
+	result := &models.RecordImportResult{Records: []models.ImportRowResult{}}
+	for i, record := range records {
+		row := s.importRecord(actor, record)
+		row.Row = i + 1
+		result.Records = append(result.Records, row)
+
+		switch row.Status {
+		case models.RowCreated:
+			result.Created++
+		case models.RowUpdated:
+			result.Updated++
+		case models.RowInvalid:
+			result.Invalid++
+		case models.RowFailed:
+			result.Failed++
+		}
+	}
+	return result, nil
+}
+
+func (s *marcService) importRecord(actor *models.Actor, record *marc.Record) models.ImportRowResult {
+	req := recordToBookRequest(record)
+	result := models.ImportRowResult{ISBN: req.ISBN}
+	if req.ISBN == "" {
+		result.Status = models.RowInvalid
+		result.Errors = []string{"record has no ISBN (020 $a)"}
+		return result
+	}
+	canonical, err := isbn.Normalize(req.ISBN)
+	if err != nil {
+		result.Status = models.RowInvalid
+		result.Errors = []string{err.Error()}
+		return result
+	}
+	req.ISBN = canonical
+	result.ISBN = canonical
+
+	existing, err := s.bookRepo.GetByISBN(req.ISBN)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		result.Status = models.RowFailed
+		result.Errors = []string{err.Error()}
+		return result
+	}
+
+	if existing != nil {
+		update := &models.UpdateBookRequest{
+			Title:       req.Title,
+			Author:      req.Author,
+			Publisher:   req.Publisher,
+			Category:    req.Category,
+			Language:    req.Language,
+			Pages:       req.Pages,
+			PublishYear: req.PublishYear,
+			Description: req.Description,
+			// Holdings are ours, not part of the bibliographic record
+			Stock:    existing.Stock,
+			Location: existing.Location,
+
+			Contributors: req.Contributors,
+			ClassNumber:  req.ClassNumber,
+			Subjects:     req.Subjects,
+		}
+		book, err := s.bookService.UpdateBook(actor, existing.ID, update)
+		if err != nil {
+			result.Status = models.RowFailed
+			result.Errors = []string{err.Error()}
+			return result
+		}
+		result.Status = models.RowUpdated
+		result.BookID = book.ID
+		return result
+	}
+
+	if req.Language == "" {
+		req.Language = "Indonesian"
+	}
+	req.Stock = 1
+	if errs := utils.ValidateStruct(req); len(errs) > 0 {
+		result.Status = models.RowInvalid
+		result.Errors = errs
+		return result
+	}
+
+	book, err := s.bookService.CreateBook(actor, req)
+	if err != nil {
+		result.Status = models.RowFailed
+		result.Errors = []string{err.Error()}
+		return result
+	}
+	result.Status = models.RowCreated
+	result.BookID = book.ID
+	return result
+}
+
+func (s *marcService) ExportBook(id uint, format string) ([]byte, error) {
+	book, err := s.bookService.GetBookByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer, err := marc.NewWriter(&buf, format)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Write(bookToRecord(book)); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExportCatalog writes every active book.
+func (s *marcService) ExportCatalog(w io.Writer, format string) error {
+	writer, err := marc.NewWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	books, err := s.bookRepo.GetCatalog()
+	if err != nil {
+		return err
+	}
+	for i := range books {
+		if err := writer.Write(bookToRecord(&books[i])); err != nil {
+			return fmt.Errorf("book %d: %w", books[i].ID, err)
+		}
+	}
+	return writer.Close()
+}
+
+// bookToRecord maps a book to a MARC 21 bibliographic record.
+func bookToRecord(book *models.Book) *marc.Record {
+	record := marc.NewRecord()
+	record.AddControlField("001", strconv.FormatUint(uint64(book.ID), 10))
+	record.AddControlField("005", book.UpdatedAt.UTC().Format("20060102150405.0"))
+	record.AddControlField("008", fixedLengthData(book))
+
+	year := ""
+	if book.PublishYear > 0 {
+		year = strconv.Itoa(book.PublishYear)
+	}
+	pages := ""
+	if book.Pages > 0 {
+		pages = strconv.Itoa(book.Pages) + " pages"
+	}
+
+	record.AddDataField("020", ' ', ' ', marc.Subfield{Code: 'a', Value: book.ISBN})
+	addClassificationField(record, book.Classification)
+	addCreditFields(record, book)
+	record.AddDataField("245", '1', '0', marc.Subfield{Code: 'a', Value: book.Title})
+	record.AddDataField("264", ' ', '1',
+		marc.Subfield{Code: 'b', Value: book.Publisher},
+		marc.Subfield{Code: 'c', Value: year},
+	)
+	record.AddDataField("300", ' ', ' ', marc.Subfield{Code: 'a', Value: pages})
+	for _, summary := range splitSummary(book.Description, summaryLength) {
+		record.AddDataField("520", ' ', ' ', marc.Subfield{Code: 'a', Value: summary})
+	}
+	record.AddDataField("546", ' ', ' ', marc.Subfield{Code: 'a', Value: book.Language})
+	addSubjectFields(record, book)
+	record.AddDataField("852", ' ', ' ',
+		marc.Subfield{Code: 'c', Value: book.Location},
+		marc.Subfield{Code: 'h', Value: book.CallNumber},
+	)
+	return record
+}
+
+// addClassificationField writes the Dewey (082) or UDC (080) number.
+func addClassificationField(record *marc.Record, classification *models.Classification) {
+	if classification == nil {
+		return
+	}
+	switch classification.Scheme {
+	case models.SchemeDewey:
+		record.AddDataField("082", '0', '4', marc.Subfield{Code: 'a', Value: classification.Notation})
+	case models.SchemeUDC:
+		record.AddDataField("080", ' ', ' ', marc.Subfield{Code: 'a', Value: classification.Notation})
+	}
+}
+
+// addSubjectFields writes a 650 topical term per subject heading, with
+// subdivisions in $x, or the category when the book has no subjects.
+func addSubjectFields(record *marc.Record, book *models.Book) {
+	if len(book.Subjects) == 0 {
+		record.AddDataField("650", ' ', '4', marc.Subfield{Code: 'a', Value: book.Category})
+		return
+	}
+	for _, subject := range book.Subjects {
+		if subject.Subject == nil {
+			continue
+		}
+		parts := strings.Split(subject.Subject.Heading, " -- ")
+		subfields := []marc.Subfield{{Code: 'a', Value: parts[0]}}
+		for _, part := range parts[1:] {
+			subfields = append(subfields, marc.Subfield{Code: 'x', Value: part})
+		}
+		record.AddDataField("650", ' ', '4', subfields...)
+	}
+}
+
+// addCreditFields writes the first author as the 100 main entry and every
+// other contributor as a 700 added entry with its role. Books loaded
+// without contributors are credited from their author line.
+func addCreditFields(record *marc.Record, book *models.Book) {
+	contributors := book.Contributors
+	if len(contributors) == 0 {
+		for _, name := range splitNames(book.Author) {
+			contributors = append(contributors, models.BookContributor{
+				Role:   models.RoleAuthor,
+				Author: &models.Author{Name: name},
+			})
+		}
+	}
+
+	var added []models.BookContributor
+	mainEntry := false
+	for _, c := range contributors {
+		if c.Author == nil {
+			continue
+		}
+		if !mainEntry && c.Role == models.RoleAuthor {
+			record.AddDataField("100", '1', ' ', marc.Subfield{Code: 'a', Value: c.Author.Name})
+			mainEntry = true
+			continue
+		}
+		added = append(added, c)
+	}
+	for _, c := range added {
+		record.AddDataField("700", '1', ' ',
+			marc.Subfield{Code: 'a', Value: c.Author.Name},
+			marc.Subfield{Code: 'e', Value: string(c.Role)},
+		)
+	}
+}
+
+// fixedLengthData builds the 40 character 008 field with the date entered,
+// publication year and language.
+func fixedLengthData(book *models.Book) string {
+	field := []byte(strings.Repeat(" ", 40))
+	copy(field[0:6], book.CreatedAt.UTC().Format("060102"))
+	field[6] = 's'
+	if book.PublishYear > 0 {
+		copy(field[7:11], fmt.Sprintf("%04d", book.PublishYear))
+	} else {
+		copy(field[7:11], "uuuu")
+	}
+	copy(field[15:18], "xx ")
+	language, ok := marcLanguageCodes[book.Language]
+	if !ok {
+		language = "und"
+	}
+	copy(field[35:38], language)
+	field[39] = 'd'
+	return string(field)
+}
+
+// summaryLength keeps each 520 well inside the 9999 bytes a MARC field
+// may hold.
+const summaryLength = 9000
+
+// splitSummary cuts a description into pieces of at most max bytes, at a
+// space where there is one, for repeated 520 fields.
+func splitSummary(text string, max int) []string {
+	var parts []string
+	for text = strings.TrimSpace(text); len(text) > max; {
+		cut := strings.LastIndexByte(text[:max+1], ' ')
+		if cut <= 0 {
+			cut = max
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	if text != "" {
+		parts = append(parts, text)
+	}
+	return parts
+}
+
+// recordSummary joins the $a of every 520, which long descriptions are
+// split over.
+func recordSummary(record *marc.Record) string {
+	var parts []string
+	for _, field := range record.FieldsByTag("520") {
+		if summary := strings.TrimSpace(field.Subfield('a')); summary != "" {
+			parts = append(parts, summary)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// recordToBookRequest reads the book fields from a MARC record. Common
+// alternatives are accepted, e.g. 260 for 264 and 110 or 700 for 100.
+func recordToBookRequest(record *marc.Record) *models.CreateBookRequest {
+	req := &models.CreateBookRequest{
+		ISBN:        marcISBN(record.SubfieldValue('a', "020")),
+		Author:      trimISBD(record.SubfieldValue('a', "100", "110", "700", "710")),
+		Publisher:   trimISBD(record.SubfieldValue('b', "264", "260")),
+		Description: recordSummary(record),
+		Category:    trimISBD(record.SubfieldValue('a', "650", "653")),
+		Location:    strings.TrimSpace(record.SubfieldValue('c', "852")),
+	}
+
+	title := trimISBD(record.SubfieldValue('a', "245"))
+	if subtitle := trimISBD(record.SubfieldValue('b', "245")); subtitle != "" {
+		title += ": " + subtitle
+	}
+	req.Title = title
+	req.Contributors = recordContributors(record)
+	req.Subjects = recordSubjects(record)
+	req.ClassNumber = strings.ReplaceAll(firstWord(record.SubfieldValue('a', "082", "080")), "/", "")
+
+	fixed := record.ControlField("008")
+	if len(fixed) >= 11 {
+		req.PublishYear, _ = strconv.Atoi(fixed[7:11])
+	}
+	if req.PublishYear == 0 {
+		if match := yearPattern.FindStringSubmatch(record.SubfieldValue('c', "264", "260")); match != nil {
+			req.PublishYear, _ = strconv.Atoi(match[1])
+		}
+	}
+
+	if pages := numberPattern.FindString(record.SubfieldValue('a', "300")); pages != "" {
+		req.Pages, _ = strconv.Atoi(pages)
+	}
+
+	req.Language = strings.TrimSpace(record.SubfieldValue('a', "546"))
+	if req.Language == "" {
+		code := record.SubfieldValue('a', "041")
+		if code == "" && len(fixed) >= 38 {
+			code = fixed[35:38]
+		}
+		req.Language = languageName(code)
+	}
+
+	return req
+}
+
+// recordContributors reads the 100, 110, 700 and 710 name entries with
+// their relator codes or terms.
+func recordContributors(record *marc.Record) []models.ContributorInput {
+	var contributors []models.ContributorInput
+	for _, tag := range []string{"100", "110", "700", "710"} {
+		for _, field := range record.FieldsByTag(tag) {
+			name := trimISBD(field.Subfield('a'))
+			if name == "" {
+				continue
+			}
+			if strings.Count(name, ",") == 1 {
+				name = uninvert(name)
+			}
+			role := models.RoleAuthor
+			for _, relator := range []string{field.Subfield('4'), field.Subfield('e')} {
+				if r, ok := marcRelators[strings.ToLower(trimISBD(relator))]; ok {
+					role = r
+					break
+				}
+			}
+			contributors = append(contributors, models.ContributorInput{Name: truncate(name, 100), Role: role})
+		}
+	}
+	return contributors
+}
+
+// recordSubjects reads the 650 and 651 subject headings, joining their
+// subdivisions with "--".
+func recordSubjects(record *marc.Record) []string {
+	var subjects []string
+	for _, tag := range []string{"650", "651"} {
+		for _, field := range record.FieldsByTag(tag) {
+			var parts []string
+			for _, sf := range field.Subfields {
+				switch sf.Code {
+				case 'a', 'x', 'y', 'z', 'v':
+					if part := trimISBD(sf.Value); part != "" {
+						parts = append(parts, part)
+					}
+				}
+			}
+			if len(parts) > 0 {
+				subjects = append(subjects, strings.Join(parts, " -- "))
+			}
+		}
+	}
+	return subjects
+}
+
+func languageName(code string) string {
+	for name, c := range marcLanguageCodes {
+		if c == code {
+			return name
+		}
+	}
+	return ""
+}
+
+// trimISBD removes the trailing punctuation MARC cataloguing rules add
+// between elements, e.g. "Toer, Pramoedya Ananta," or "Bumi manusia /".
+func trimISBD(value string) string {
+	return strings.TrimSpace(strings.TrimRight(strings.TrimSpace(value), " /:;,=."))
+}
+
+// marcISBN drops qualifiers such as "(pbk.)" or ": Rp50.000" after an ISBN,
+// with or without a space before them.
+func marcISBN(value string) string {
+	return strings.TrimRight(marcISBNPattern.FindString(strings.TrimSpace(value)), "-")
+}
+
+// firstWord returns value up to the first space.
+func firstWord(value string) string {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/marc"
+)
+
+func TestMARCISBNQualifiers(t *testing.T) {
+	for value, want := range map[string]string{
+		"9789799731234":                         "9789799731234",
+		"9789799731234 (pbk.)":                  "9789799731234",
+		"9789799731234(pbk.)":                   "9789799731234",
+		"978-979-97312-3-4 (hbk. : alk. paper)": "978-979-97312-3-4",
+		"0-306-40615-2 : Rp50.000":              "0-306-40615-2",
+		"0-8044-2957-X (v. 1)":                  "0-8044-2957-X",
+		"080442957x":                            "080442957x",
+		"978 979 97312 3 4 (pbk.)":              "978 979 97312 3 4",
+		"9789799731234 xii":                     "9789799731234",
+		"(pbk.)":                                "",
+		"":                                      "",
+	} {
+		if got := marcISBN(value); got != want {
+			t.Errorf("marcISBN(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestRecordToBookRequestSamples(t *testing.T) {
+	data, err := os.ReadFile("../../pkg/marc/testdata/books.mrc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := marc.ReadAll(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	first := recordToBookRequest(records[0])
+	want := &models.CreateBookRequest{
+		Title:        "Bumi manusia",
+		Author:       "Toer, Pramoedya Ananta",
+		ISBN:         "9789799731234",
+		Publisher:    "Hasta Mitra",
+		Category:     "Novel",
+		Language:     "Indonesian",
+		Pages:        535,
+		PublishYear:  1980,
+		Description:  "Kisah Minke, pemuda pribumi terpelajar di akhir abad ke-19.",
+		Location:     "Rak A-1",
+		Contributors: []models.ContributorInput{{Name: "Pramoedya Ananta Toer", Role: models.RoleAuthor}},
+		Subjects:     []string{"Novel"},
+	}
+	if !reflect.DeepEqual(first, want) {
+		t.Errorf("record 1\ngot:  %+v\nwant: %+v", first, want)
+	}
+
+	second := recordToBookRequest(records[1])
+	if second.Title != "Laskar pelangi: sebuah novel" {
+		t.Errorf("record 2 title = %q", second.Title)
+	}
+	if canonical, err := isbn.Normalize(second.ISBN); err != nil || canonical != "9789793062792" {
+		t.Errorf("record 2 ISBN %q normalizes to %q, %v", second.ISBN, canonical, err)
+	}
+	if second.Publisher != "Bentang Pustaka" || second.PublishYear != 2005 || second.Pages != 529 {
+		t.Errorf("record 2 imprint = %q %d, %d pages", second.Publisher, second.PublishYear, second.Pages)
+	}
+
+	third := recordToBookRequest(records[2])
+	if third.Language != "English" || third.ISBN != "9780099590088" {
+		t.Errorf("record 3 language %q, ISBN %q", third.Language, third.ISBN)
+	}
+}
+
+func TestBookRecordRoundTrip(t *testing.T) {
+	book := &models.Book{
+		ID:          7,
+		Title:       "Cantik itu luka",
+		Author:      "Eka Kurniawan",
+		ISBN:        "9789792257090",
+		Publisher:   "Gramedia Pustaka Utama",
+		Category:    "Novel",
+		Language:    "English",
+		Pages:       505,
+		PublishYear: 2015,
+		Description: "A saga of a family in Halimunda.",
+		Location:    "Rak B-2",
+		CallNumber:  "899.221 KUR c",
+		CreatedAt:   time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:   time.Date(2024, 2, 1, 10, 30, 0, 0, time.UTC),
+		Contributors: []models.BookContributor{
+			{Role: models.RoleAuthor, Author: &models.Author{Name: "Eka Kurniawan"}},
+			{Role: models.RoleTranslator, Author: &models.Author{Name: "Annie Tucker"}},
+		},
+		Classification: &models.Classification{Scheme: models.SchemeDewey, Notation: "899.2213"},
+		Subjects: []models.BookSubject{
+			{Subject: &models.Subject{Heading: "Indonesian fiction -- 21st century"}},
+			{Subject: &models.Subject{Heading: "Families"}},
+		},
+	}
+
+	record := bookToRecord(book)
+	if got := record.ControlField("001"); got != "7" {
+		t.Errorf("001 = %q", got)
+	}
+	fixed := record.ControlField("008")
+	if len(fixed) != 40 || fixed[0:6] != "240115" || fixed[7:11] != "2015" || fixed[35:38] != "eng" {
+		t.Errorf("008 = %q", fixed)
+	}
+	if got := record.SubfieldValue('a', "082"); got != "899.2213" {
+		t.Errorf("082 $a = %q", got)
+	}
+	if added := record.FieldsByTag("700"); len(added) != 1 || added[0].Subfield('e') != "translator" {
+		t.Errorf("700 = %+v", added)
+	}
+
+	// Through the binary form and back
+	raw, err := marc.EncodeISO2709(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := marc.DecodeISO2709(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := recordToBookRequest(decoded)
+	want := &models.CreateBookRequest{
+		Title:       book.Title,
+		Author:      "Eka Kurniawan",
+		ISBN:        book.ISBN,
+		Publisher:   book.Publisher,
+		Category:    "Indonesian fiction",
+		Language:    "English",
+		Pages:       505,
+		PublishYear: 2015,
+		Description: book.Description,
+		Location:    "Rak B-2",
+		Contributors: []models.ContributorInput{
+			{Name: "Eka Kurniawan", Role: models.RoleAuthor},
+			{Name: "Annie Tucker", Role: models.RoleTranslator},
+		},
+		ClassNumber: "899.2213",
+		Subjects:    []string{"Indonesian fiction -- 21st century", "Families"},
+	}
+	if !reflect.DeepEqual(req, want) {
+		t.Errorf("round trip\ngot:  %+v\nwant: %+v", req, want)
+	}
+}
+
+// Books saved before contributors existed are credited from their author
+// line, inverted names included.
+func TestBookToRecordAuthorLine(t *testing.T) {
+	record := bookToRecord(&models.Book{
+		Title:  "Ronggeng Dukuh Paruk",
+		Author: "Tohari, Ahmad",
+		ISBN:   "9789792218763",
+	})
+	if got := record.SubfieldValue('a', "100"); got != "Ahmad Tohari" {
+		t.Errorf("100 $a = %q", got)
+	}
+	if got := record.SubfieldValue('a', "650"); got != "" {
+		t.Errorf("650 without category = %q", got)
+	}
+	if fixed := record.ControlField("008"); fixed[7:11] != "uuuu" || fixed[35:38] != "und" {
+		t.Errorf("008 = %q", fixed)
+	}
+}
+
+// A description longer than one MARC field allows is split over repeated
+// 520s and comes back whole.
+func TestLongDescriptionRoundTrip(t *testing.T) {
+	description := strings.TrimSpace(strings.Repeat("Sebuah kisah panjang tentang keluarga. ", 700))
+	record := bookToRecord(&models.Book{Title: "Cantik Itu Luka", ISBN: "9789792215256", Description: description})
+	if summaries := record.FieldsByTag("520"); len(summaries) != 4 {
+		t.Errorf("%d 520 fields, want 4", len(summaries))
+	}
+
+	raw, err := marc.EncodeISO2709(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := marc.DecodeISO2709(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := recordToBookRequest(decoded).Description; got != description {
+		t.Errorf("description came back as %d bytes, want %d", len(got), len(description))
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/metadata"
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+)
+
+// How long one lookup may wait for the provider
+const metadataLookupTimeout = 15 * time.Second
+
+type MetadataService interface {
+	LookupISBN(code string) (*models.CreateBookRequest, error)
+}
+
+type metadataService struct {
+	provider metadata.MetadataProvider
+}
+
+func NewMetadataService(provider metadata.MetadataProvider) MetadataService {
+	return &metadataService{
+		provider: provider,
+	}
+}
+
+// LookupISBN prefills a new book from the metadata provider. Staff review
+// and complete it before creating the book.
+func (s *metadataService) LookupISBN(code string) (*models.CreateBookRequest, error) {
+	canonical, err := isbn.Normalize(code)
+	if err != nil {
+		return nil, errors.New("invalid ISBN")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), metadataLookupTimeout)
+	defer cancel()
+
+	record, err := s.provider.LookupISBN(ctx, canonical)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			return nil, errors.New("no metadata found for this ISBN")
+		}
+		return nil, err
+	}
+
+	title := record.Title
+	if record.Subtitle != "" {
+		title += ": " + record.Subtitle
+	}
+
+	req := &models.CreateBookRequest{
+		Title:       truncate(title, 200),
+		Author:      truncate(strings.Join(record.Authors, "; "), 100),
+		ISBN:        canonical,
+		Language:    languageName(record.Language),
+		Pages:       record.Pages,
+		PublishYear: record.PublishYear,
+		Stock:       1,
+		Description: record.Description,
+	}
+	for _, author := range record.Authors {
+		req.Contributors = append(req.Contributors, models.ContributorInput{Name: truncate(author, 100)})
+	}
+	if len(record.Publishers) > 0 {
+		req.Publisher = truncate(record.Publishers[0], 100)
+	}
+	if len(record.Subjects) > 0 {
+		req.Category = truncate(record.Subjects[0], 50)
+	}
+	return req, nil
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:n]))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// NotificationService keeps each user's inbox of messages from the library.
+type NotificationService interface {
+	Notify(userID uint, subject, message, entityType string, entityID uint)
+	GetNotifications(userID uint, spec *query.Spec) ([]models.Notification, *query.Page, error)
+	MarkRead(userID, id uint) (*models.Notification, error)
+	MarkAllRead(userID uint) (int64, error)
+}
+
+type notificationService struct {
+	notificationRepo repositories.NotificationRepository
+}
+
+func NewNotificationService(notificationRepo repositories.NotificationRepository) NotificationService {
+	return &notificationService{
+		notificationRepo: notificationRepo,
+	}
+}
+
+// Notify adds a message to a user's inbox. A failure is logged rather
+// than failing whatever the message is about.
+func (s *notificationService) Notify(userID uint, subject, message, entityType string, entityID uint) {
+	notification := &models.Notification{
+		UserID:     userID,
+		Subject:    truncate(subject, 200),
+		Message:    message,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}
+	if err := s.notificationRepo.Create(notification); err != nil {
+		log.Printf("notifications: failed to notify user %d about %s %d: %v", userID, entityType, entityID, err)
+	}
+}
+
+func (s *notificationService) GetNotifications(userID uint, spec *query.Spec) ([]models.Notification, *query.Page, error) {
+	return s.notificationRepo.GetByUserID(userID, spec)
+}
+
+func (s *notificationService) MarkRead(userID, id uint) (*models.Notification, error) {
+	notification, err := s.notificationRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("notification not found")
+		}
+		return nil, err
+	}
+	if notification.UserID != userID {
+		return nil, errors.New("notification not found")
+	}
+	if notification.ReadAt != nil {
+		return notification, nil
+	}
+
+	now := time.Now()
+	if err := s.notificationRepo.MarkRead(notification.ID, now); err != nil {
+		return nil, err
+	}
+	notification.ReadAt = &now
+	return notification, nil
+}
+
+func (s *notificationService) MarkAllRead(userID uint) (int64, error) {
+	return s.notificationRepo.MarkAllRead(userID, time.Now())
+}
@@ -0,0 +1,427 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/opds"
+	"github.com/yooerizkilab/library-system/pkg/query"
+)
+
+// ISO 639-1 codes for the languages we catalog in, as OPDS wants BCP 47
+var opdsLanguageCodes = map[string]string{
+	"Indonesian": "id",
+	"English":    "en",
+	"Malay":      "ms",
+	"Javanese":   "jv",
+	"Sundanese":  "su",
+	"Arabic":     "ar",
+	"Dutch":      "nl",
+	"Chinese":    "zh",
+	"Japanese":   "ja",
+	"Korean":     "ko",
+	"French":     "fr",
+	"German":     "de",
+}
+
+// OPDSCatalog says where feeds are served from: the API base URL, e.g.
+// https://library.example/api/v1, and OPDS version 1 (Atom) or 2 (JSON).
+type OPDSCatalog struct {
+	BaseURL string
+	Version int
+}
+
+func (c OPDSCatalog) root() string {
+	if c.Version == 2 {
+		return c.BaseURL + "/opds/v2"
+	}
+	return c.BaseURL + "/opds"
+}
+
+func (c OPDSCatalog) url(path string, params url.Values) string {
+	u := c.root() + path
+	if encoded := params.Encode(); encoded != "" {
+		u += "?" + encoded
+	}
+	return u
+}
+
+// SearchTemplate is the OpenSearch template of the OPDS 1.2 search feed.
+func (c OPDSCatalog) SearchTemplate() string {
+	return c.root() + "/search?q={searchTerms}&page={startPage?}"
+}
+
+type OPDSService interface {
+	GetRoot(catalog OPDSCatalog) *opds.Feed
+	GetNewArrivals(catalog OPDSCatalog, spec *query.Spec) (*opds.Feed, error)
+	GetCategories(catalog OPDSCatalog) (*opds.Feed, error)
+	GetCategory(catalog OPDSCatalog, id uint) (*opds.Feed, error)
+	GetCategoryBooks(catalog OPDSCatalog, id uint, spec *query.Spec) (*opds.Feed, error)
+	Search(catalog OPDSCatalog, q string, spec *query.Spec) (*opds.Feed, error)
+	GetPublication(catalog OPDSCatalog, bookID uint) (*opds.Publication, error)
+	LibraryName() string
+}
+
+type opdsService struct {
+	bookService           BookService
+	classificationService ClassificationService
+	libraryName           string
+}
+
+func NewOPDSService(bookService BookService, classificationService ClassificationService, libraryName string) OPDSService {
+	return &opdsService{
+		bookService:           bookService,
+		classificationService: classificationService,
+		libraryName:           libraryName,
+	}
+}
+
+func (s *opdsService) LibraryName() string {
+	return s.libraryName
+}
+
+func (s *opdsService) GetRoot(catalog OPDSCatalog) *opds.Feed {
+	feed := s.navigationFeed(catalog, "", s.libraryName)
+	now := time.Now()
+	feed.Navigation = []opds.NavigationItem{
+		{
+			ID:      catalog.url("/new", nil),
+			Title:   "New arrivals",
+			Summary: "The latest additions to the catalog",
+			Href:    catalog.url("/new", nil),
+			Type:    opds.TypeAcquisition,
+			Updated: now,
+		},
+		{
+			ID:      catalog.url("/categories", nil),
+			Title:   "By category",
+			Summary: "Browse the catalog by classification",
+			Href:    catalog.url("/categories", nil),
+			Type:    opds.TypeNavigation,
+			Updated: now,
+		},
+	}
+	feed.Links = append(feed.Links, opds.Link{
+		Rel:   opds.RelNew,
+		Href:  catalog.url("/new", nil),
+		Type:  opds.TypeAcquisition,
+		Title: "New arrivals",
+	})
+	return feed
+}
+
+func (s *opdsService) GetNewArrivals(catalog OPDSCatalog, spec *query.Spec) (*opds.Feed, error) {
+	spec = feedSpec(spec, query.SortField{Field: "created_at", Desc: true})
+	books, page, err := s.bookService.GetAllBooks(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.acquisitionFeed(catalog, "/new", "New arrivals", "", spec, nil, page, books), nil
+}
+
+// GetCategories lists the top classes that have books.
+func (s *opdsService) GetCategories(catalog OPDSCatalog) (*opds.Feed, error) {
+	roots, _, err := s.classificationService.GetClassifications(&query.Spec{
+		Sort:    []query.SortField{{Field: "notation"}},
+		Filters: map[string]string{"root": "true"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	feed := s.navigationFeed(catalog, "/categories", "By category")
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: catalog.url("", nil), Type: opds.TypeNavigation})
+	feed.Navigation = categoryItems(catalog, roots)
+	return feed, nil
+}
+
+// GetCategory lists the narrower classes of a class that have books, after
+// an entry for every book in the class.
+func (s *opdsService) GetCategory(catalog OPDSCatalog, id uint) (*opds.Feed, error) {
+	classification, err := s.classificationService.GetClassificationByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/categories/" + strconv.FormatUint(uint64(id), 10)
+	feed := s.navigationFeed(catalog, path, classification.Label)
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelUp, Href: categoryUp(catalog, classification), Type: opds.TypeNavigation})
+	feed.Navigation = append([]opds.NavigationItem{{
+		ID:      catalog.url(path+"/books", nil),
+		Title:   "All " + classification.Label,
+		Summary: strconv.FormatInt(classification.BookCount, 10) + " books",
+		Href:    catalog.url(path+"/books", nil),
+		Type:    opds.TypeAcquisition,
+		Count:   classification.BookCount,
+		Updated: classification.UpdatedAt,
+	}}, categoryItems(catalog, classification.Children)...)
+	return feed, nil
+}
+
+func (s *opdsService) GetCategoryBooks(catalog OPDSCatalog, id uint, spec *query.Spec) (*opds.Feed, error) {
+	classification, err := s.classificationService.GetClassificationByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	spec = feedSpec(spec, query.SortField{Field: "title"})
+	books, page, err := s.classificationService.GetClassificationBooks(id, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	path := "/categories/" + strconv.FormatUint(uint64(id), 10)
+	return s.acquisitionFeed(catalog, path+"/books", classification.Label, path, spec, nil, page, books), nil
+}
+
+func (s *opdsService) Search(catalog OPDSCatalog, q string, spec *query.Spec) (*opds.Feed, error) {
+	q = strings.TrimSpace(q)
+	if q == "" {
+		return nil, errors.New("search query is required")
+	}
+
+	// Relevance order
+	spec = feedSpec(spec)
+	books, page, _, err := s.bookService.SearchBooks(q, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"q": {q}}
+	return s.acquisitionFeed(catalog, "/search", "Search results for "+q, "", spec, params, page, books), nil
+}
+
+func (s *opdsService) GetPublication(catalog OPDSCatalog, bookID uint) (*opds.Publication, error) {
+	book, err := s.bookService.GetBookByID(bookID)
+	if err != nil {
+		return nil, err
+	}
+	if !book.IsActive {
+		return nil, errors.New("book not found")
+	}
+
+	publication := opdsPublication(catalog, book)
+	publication.Authors = contributorNames(book)
+	publication.Subjects = nil
+	if book.Classification != nil {
+		publication.Subjects = append(publication.Subjects, opds.Subject{
+			Term:   book.Classification.Notation,
+			Label:  book.Classification.Label,
+			Scheme: book.Classification.Scheme,
+		})
+	}
+	for _, subject := range book.Subjects {
+		if subject.Subject != nil {
+			publication.Subjects = append(publication.Subjects, opds.Subject{
+				Term:  subject.Subject.Heading,
+				Label: subject.Subject.Heading,
+			})
+		}
+	}
+	return publication, nil
+}
+
+func (s *opdsService) navigationFeed(catalog OPDSCatalog, path, title string) *opds.Feed {
+	return &opds.Feed{
+		ID:      catalog.url(path, nil),
+		Title:   title,
+		Author:  s.libraryName,
+		Updated: time.Now(),
+		Kind:    opds.TypeNavigation,
+		Links:   append(catalogLinks(catalog), opds.Link{Rel: opds.RelSelf, Href: catalog.url(path, nil), Type: opds.TypeNavigation}),
+	}
+}
+
+// acquisitionFeed lists books as one page of the feed at path, with links
+// to the neighbouring pages that keep params and the spec's filters.
+func (s *opdsService) acquisitionFeed(catalog OPDSCatalog, path, title, up string, spec *query.Spec, params url.Values, page *query.Page, books []models.Book) *opds.Feed {
+	if params == nil {
+		params = url.Values{}
+	}
+	for key, value := range spec.Filters {
+		params.Set(key, value)
+	}
+	pageURL := func(n int) string {
+		pageParams := url.Values{}
+		for key, values := range params {
+			pageParams[key] = values
+		}
+		pageParams.Set("page", strconv.Itoa(n))
+		pageParams.Set("limit", strconv.Itoa(spec.Limit))
+		return catalog.url(path, pageParams)
+	}
+
+	feed := &opds.Feed{
+		ID:           catalog.url(path, params),
+		Title:        title,
+		Author:       s.libraryName,
+		Kind:         opds.TypeAcquisition,
+		TotalResults: page.Total,
+		ItemsPerPage: spec.Limit,
+		StartIndex:   (spec.Page-1)*spec.Limit + 1,
+		Publications: make([]opds.Publication, 0, len(books)),
+	}
+	feed.Links = append(catalogLinks(catalog),
+		opds.Link{Rel: opds.RelSelf, Href: pageURL(spec.Page), Type: opds.TypeAcquisition},
+		opds.Link{Rel: opds.RelUp, Href: catalog.url(up, nil), Type: opds.TypeNavigation},
+		opds.Link{Rel: opds.RelFirst, Href: pageURL(1), Type: opds.TypeAcquisition},
+	)
+	lastPage := int((page.Total + int64(spec.Limit) - 1) / int64(spec.Limit))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+	if spec.Page > 1 {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelPrevious, Href: pageURL(spec.Page - 1), Type: opds.TypeAcquisition})
+	}
+	if spec.Page < lastPage {
+		feed.Links = append(feed.Links, opds.Link{Rel: opds.RelNext, Href: pageURL(spec.Page + 1), Type: opds.TypeAcquisition})
+	}
+	feed.Links = append(feed.Links, opds.Link{Rel: opds.RelLast, Href: pageURL(lastPage), Type: opds.TypeAcquisition})
+
+	for i := range books {
+		publication := opdsPublication(catalog, &books[i])
+		feed.Publications = append(feed.Publications, *publication)
+		if publication.Updated.After(feed.Updated) {
+			feed.Updated = publication.Updated
+		}
+	}
+	if feed.Updated.IsZero() {
+		feed.Updated = time.Now()
+	}
+	return feed
+}
+
+// catalogLinks are the start and search links every feed carries.
+func catalogLinks(catalog OPDSCatalog) []opds.Link {
+	search := opds.Link{Rel: opds.RelSearch, Href: catalog.url("/opensearch.xml", nil), Type: opds.TypeSearch, Title: "Search the catalog"}
+	if catalog.Version == 2 {
+		search.Href = catalog.root() + "/search{?query}"
+		search.Templated = true
+	}
+	return []opds.Link{
+		{Rel: opds.RelStart, Href: catalog.url("", nil), Type: opds.TypeNavigation},
+		search,
+	}
+}
+
+// feedSpec pages by number in the given order, as feeds link to page
+// numbers.
+func feedSpec(spec *query.Spec, sort ...query.SortField) *query.Spec {
+	paged := query.Spec{Page: 1, Limit: query.DefaultLimit, Filters: map[string]string{}}
+	if spec != nil {
+		paged = *spec
+	}
+	paged.Cursor = nil
+	paged.Sort = sort
+	return &paged
+}
+
+func categoryItems(catalog OPDSCatalog, classifications []models.Classification) []opds.NavigationItem {
+	items := []opds.NavigationItem{}
+	for _, classification := range classifications {
+		if classification.BookCount == 0 {
+			continue
+		}
+		href := catalog.url("/categories/"+strconv.FormatUint(uint64(classification.ID), 10), nil)
+		items = append(items, opds.NavigationItem{
+			ID:      href,
+			Title:   classification.Label,
+			Summary: strings.TrimSpace(classification.Notation + " " + classification.Label),
+			Href:    href,
+			Type:    opds.TypeNavigation,
+			Count:   classification.BookCount,
+			Updated: classification.UpdatedAt,
+		})
+	}
+	return items
+}
+
+func categoryUp(catalog OPDSCatalog, classification *models.Classification) string {
+	if classification.ParentID == nil {
+		return catalog.url("/categories", nil)
+	}
+	return catalog.url("/categories/"+strconv.FormatUint(uint64(*classification.ParentID), 10), nil)
+}
+
+// opdsPublication describes a book from its list fields. Only digital
+// items have acquisition links.
+func opdsPublication(catalog OPDSCatalog, book *models.Book) *opds.Publication {
+	entryURL := catalog.url("/books/"+strconv.FormatUint(uint64(book.ID), 10), nil)
+	publication := &opds.Publication{
+		ID:         "urn:isbn:" + book.ISBN,
+		Identifier: "urn:isbn:" + book.ISBN,
+		Title:      book.Title,
+		Publisher:  book.Publisher,
+		Language:   opdsLanguageCodes[book.Language],
+		Issued:     book.PublishYear,
+		Summary:    book.Description,
+		Pages:      book.Pages,
+		Updated:    book.UpdatedAt,
+		Links: []opds.Link{
+			{Rel: opds.RelAlternate, Href: entryURL, Type: opds.TypeEntry, Title: "Full entry"},
+		},
+	}
+	publication.Authors = splitNames(book.Author)
+	if book.Category != "" {
+		publication.Subjects = []opds.Subject{{Term: book.Category, Label: book.Category}}
+	}
+
+	if book.CoverUpdatedAt != nil {
+		coverURL := func(size coverSize) string {
+			params := url.Values{
+				"size": {size.name},
+				"v":    {strconv.FormatInt(book.CoverUpdatedAt.Unix(), 10)},
+			}
+			return catalog.BaseURL + "/books/" + strconv.FormatUint(uint64(book.ID), 10) + "/cover?" + params.Encode()
+		}
+		large, small := coverSizes[len(coverSizes)-1], coverSizes[0]
+		publication.Links = append(publication.Links,
+			opds.Link{Rel: opds.RelImage, Href: coverURL(large), Type: "image/jpeg", Width: large.width, Height: large.height},
+			opds.Link{Rel: opds.RelThumbnail, Href: coverURL(small), Type: "image/jpeg", Width: small.width, Height: small.height},
+		)
+	}
+
+	// Digital items are borrowed through the checkout endpoint, one link per
+	// format on offer
+	if book.IsDigital() {
+		checkoutURL := catalog.BaseURL + "/digital/books/" + strconv.FormatUint(uint64(book.ID), 10) + "/checkout"
+		seen := map[string]bool{}
+		for _, file := range book.Files {
+			if seen[file.ContentType] {
+				continue
+			}
+			seen[file.ContentType] = true
+			publication.Links = append(publication.Links,
+				opds.Link{Rel: opds.RelBorrow, Href: checkoutURL, Type: file.ContentType, Title: "Borrow " + file.Format})
+		}
+	}
+	return publication
+}
+
+// contributorNames lists the credited authors, or else everyone credited,
+// or else the names in the author string.
+func contributorNames(book *models.Book) []string {
+	var authors, others []string
+	for _, contributor := range book.Contributors {
+		if contributor.Author == nil {
+			continue
+		}
+		if contributor.Role == models.RoleAuthor {
+			authors = append(authors, contributor.Author.Name)
+		} else {
+			others = append(others, contributor.Author.Name)
+		}
+	}
+	if len(authors) > 0 {
+		return authors
+	}
+	if len(others) > 0 {
+		return others
+	}
+	return splitNames(book.Author)
+}
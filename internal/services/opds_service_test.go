@@ -0,0 +1,130 @@
+package services
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/pkg/opds"
+	"github.com/yooerizkilab/library-system/pkg/query"
+)
+
+func feedLinks(feed *opds.Feed) map[string]opds.Link {
+	links := map[string]opds.Link{}
+	for _, link := range feed.Links {
+		links[link.Rel] = link
+	}
+	return links
+}
+
+func TestOPDSAcquisitionFeedPaging(t *testing.T) {
+	service := &opdsService{libraryName: "Perpustakaan Kota"}
+	catalog := OPDSCatalog{BaseURL: "https://library.example/api/v1", Version: 1}
+	pageURL := func(page string) string {
+		return "https://library.example/api/v1/opds/search?" + url.Values{
+			"q": {"novel"}, "language": {"Indonesian"}, "limit": {"2"}, "page": {page},
+		}.Encode()
+	}
+
+	tests := []struct {
+		page           int
+		total          int64
+		previous, next string
+		last           string
+	}{
+		{page: 1, total: 5, next: pageURL("2"), last: pageURL("3")},
+		{page: 2, total: 5, previous: pageURL("1"), next: pageURL("3"), last: pageURL("3")},
+		{page: 3, total: 5, previous: pageURL("2"), last: pageURL("3")},
+		{page: 1, total: 0, last: pageURL("1")},
+	}
+	for _, tt := range tests {
+		spec := &query.Spec{Page: tt.page, Limit: 2, Filters: map[string]string{"language": "Indonesian"}}
+		feed := service.acquisitionFeed(catalog, "/search", "Search results for novel", "", spec,
+			url.Values{"q": {"novel"}}, &query.Page{Total: tt.total}, nil)
+		links := feedLinks(feed)
+
+		if got := links[opds.RelSelf].Href; got != pageURL(strconv.Itoa(tt.page)) {
+			t.Errorf("page %d of %d: self = %q", tt.page, tt.total, got)
+		}
+		if got := links[opds.RelPrevious].Href; got != tt.previous {
+			t.Errorf("page %d of %d: previous = %q, want %q", tt.page, tt.total, got, tt.previous)
+		}
+		if got := links[opds.RelNext].Href; got != tt.next {
+			t.Errorf("page %d of %d: next = %q, want %q", tt.page, tt.total, got, tt.next)
+		}
+		if got := links[opds.RelLast].Href; got != tt.last {
+			t.Errorf("page %d of %d: last = %q, want %q", tt.page, tt.total, got, tt.last)
+		}
+		if got := links[opds.RelFirst].Href; got != pageURL("1") {
+			t.Errorf("page %d of %d: first = %q", tt.page, tt.total, got)
+		}
+		if feed.StartIndex != (tt.page-1)*2+1 || feed.TotalResults != tt.total {
+			t.Errorf("page %d of %d: start %d, total %d", tt.page, tt.total, feed.StartIndex, feed.TotalResults)
+		}
+	}
+}
+
+func TestOPDSAcquisitionFeedEntries(t *testing.T) {
+	service := &opdsService{libraryName: "Perpustakaan Kota"}
+	catalog := OPDSCatalog{BaseURL: "https://library.example/api/v1", Version: 2}
+	older := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)
+	books := []models.Book{
+		{ID: 1, Title: "Bumi manusia", ISBN: "9789799731234", Author: "Pramoedya Ananta Toer", Language: "Indonesian", UpdatedAt: older},
+		{
+			ID: 2, Title: "Laut bercerita", ISBN: "9786024246945", Author: "Leila S. Chudori", Language: "Indonesian",
+			ItemType: models.ItemEBook, UpdatedAt: newer,
+			Files: []models.DigitalFile{
+				{Format: "epub", ContentType: "application/epub+zip"},
+				{Format: "pdf", ContentType: "application/pdf"},
+			},
+		},
+	}
+
+	spec := &query.Spec{Page: 1, Limit: 20, Filters: map[string]string{}}
+	feed := service.acquisitionFeed(catalog, "/new", "New arrivals", "", spec, nil, &query.Page{Total: 2}, books)
+
+	if feed.ID != "https://library.example/api/v1/opds/v2/new" || feed.Title != "New arrivals" || feed.Kind != opds.TypeAcquisition {
+		t.Errorf("feed id %q, title %q, kind %q", feed.ID, feed.Title, feed.Kind)
+	}
+	if !feed.Updated.Equal(newer) {
+		t.Errorf("updated = %v, want the newest book's %v", feed.Updated, newer)
+	}
+	links := feedLinks(feed)
+	if links[opds.RelStart].Href != "https://library.example/api/v1/opds/v2" || links[opds.RelStart].Type != opds.TypeNavigation {
+		t.Errorf("start link = %+v", links[opds.RelStart])
+	}
+	if search := links[opds.RelSearch]; !search.Templated || search.Href != "https://library.example/api/v1/opds/v2/search{?query}" {
+		t.Errorf("search link = %+v", search)
+	}
+	if _, ok := links[opds.RelNext]; ok {
+		t.Error("single page feed has a next link")
+	}
+
+	if len(feed.Publications) != 2 {
+		t.Fatalf("got %d publications, want 2", len(feed.Publications))
+	}
+	printed, digital := feed.Publications[0], feed.Publications[1]
+	if printed.ID != "urn:isbn:9789799731234" || printed.Language != "id" {
+		t.Errorf("print publication %+v", printed)
+	}
+	for _, link := range printed.Links {
+		if link.Rel == opds.RelBorrow {
+			t.Errorf("print book has an acquisition link %+v", link)
+		}
+	}
+	var borrow []string
+	for _, link := range digital.Links {
+		if link.Rel == opds.RelBorrow {
+			if link.Href != "https://library.example/api/v1/digital/books/2/checkout" {
+				t.Errorf("borrow href = %q", link.Href)
+			}
+			borrow = append(borrow, link.Type)
+		}
+	}
+	if len(borrow) != 2 || borrow[0] != "application/epub+zip" || borrow[1] != "application/pdf" {
+		t.Errorf("borrow link types = %v", borrow)
+	}
+}
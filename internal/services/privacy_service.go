@@ -0,0 +1,407 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type PrivacyService interface {
+	ExportUserData(userID uint) (*models.UserDataExport, error)
+	RequestErasure(actor *models.Actor, userID uint, req *models.ErasureRequestInput) (*models.ErasureRequest, error)
+	GetErasureRequests(spec *query.Spec) ([]models.ErasureRequest, *query.Page, error)
+	CancelErasure(actor *models.Actor, id uint) (*models.ErasureRequest, error)
+	ProcessDueErasures() error
+}
+
+type privacyService struct {
+	userRepo     repositories.UserRepository
+	borrowRepo   repositories.BorrowRepository
+	erasureRepo  repositories.ErasureRepository
+	holdService  HoldService
+	auditService AuditService
+	retention    time.Duration
+}
+
+func NewPrivacyService(
+	userRepo repositories.UserRepository,
+	borrowRepo repositories.BorrowRepository,
+	erasureRepo repositories.ErasureRepository,
+	holdService HoldService,
+	auditService AuditService,
+	retention time.Duration,
+) PrivacyService {
+	return &privacyService{
+		userRepo:     userRepo,
+		borrowRepo:   borrowRepo,
+		erasureRepo:  erasureRepo,
+		holdService:  holdService,
+		auditService: auditService,
+		retention:    retention,
+	}
+}
+
+func (s *privacyService) ExportUserData(userID uint) (*models.UserDataExport, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	borrows, _, err := s.borrowRepo.GetBorrowHistory(userID, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	holds, _, err := s.holdService.GetHoldsByUser(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.UserDataExport{
+		ExportedAt: time.Now(),
+		Profile:    *user,
+		Loans:      []models.ExportedLoan{},
+		Fines:      []models.ExportedFine{},
+		Holds:      []models.ExportedHold{},
+	}
+	export.Profile.Borrows = nil
+
+	for _, borrow := range borrows {
+		export.Loans = append(export.Loans, models.ExportedLoan{
+			ID:         borrow.ID,
+			BookID:     borrow.BookID,
+			BookTitle:  borrow.Book.Title,
+			BookISBN:   borrow.Book.ISBN,
+			BorrowDate: borrow.BorrowDate,
+			DueDate:    borrow.DueDate,
+			ReturnDate: borrow.ReturnDate,
+			Status:     borrow.Status,
+			Notes:      borrow.Notes,
+		})
+		if borrow.Fine > 0 {
+			export.Fines = append(export.Fines, models.ExportedFine{
+				BorrowID:  borrow.ID,
+				BookTitle: borrow.Book.Title,
+				Amount:    borrow.Fine,
+				DueDate:   borrow.DueDate,
+			})
+		}
+	}
+
+	for _, hold := range holds {
+		workTitle := ""
+		if hold.Work != nil {
+			workTitle = hold.Work.Title
+		}
+		export.Holds = append(export.Holds, models.ExportedHold{
+			ID:         hold.ID,
+			WorkTitle:  workTitle,
+			BookID:     hold.BookID,
+			AnyEdition: hold.AnyEdition(),
+			Status:     hold.Status,
+			PlacedAt:   hold.CreatedAt,
+			ReadyAt:    hold.ReadyAt,
+			ExpiresAt:  hold.ExpiresAt,
+			ClosedAt:   hold.ClosedAt,
+		})
+	}
+
+	return export, nil
+}
+
+func (s *privacyService) RequestErasure(actor *models.Actor, userID uint, req *models.ErasureRequestInput) (*models.ErasureRequest, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	// Personal data can't be erased while the user still holds library items
+	for _, borrow := range user.Borrows {
+		if borrow.Status == models.StatusBorrowed || borrow.Status == models.StatusOverdue {
+			return nil, errors.New("cannot erase user with active borrows")
+		}
+	}
+
+	pending, err := s.erasureRepo.GetPendingByUserID(userID)
+	if err == nil && pending != nil {
+		return nil, errors.New("erasure already requested for this user")
+	}
+
+	request := newErasureRequest(userID, actor.UserID, req.Reason, s.retention)
+	if err := s.erasureRepo.Create(request); err != nil {
+		return nil, err
+	}
+
+	// The account is closed right away; anonymization happens once the
+	// retention period has passed.
+	if err := s.holdService.CancelUserHolds(actor, userID); err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.Delete(userID); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "user.request_erasure", "user", userID, nil, request)
+
+	return request, nil
+}
+
+func (s *privacyService) GetErasureRequests(spec *query.Spec) ([]models.ErasureRequest, *query.Page, error) {
+	return s.erasureRepo.GetAll(spec)
+}
+
+func (s *privacyService) CancelErasure(actor *models.Actor, id uint) (*models.ErasureRequest, error) {
+	request, err := s.erasureRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("erasure request not found")
+		}
+		return nil, err
+	}
+
+	if request.Status != models.ErasurePending && request.Status != models.ErasureFailed {
+		return nil, errors.New("only pending or failed erasure requests can be cancelled")
+	}
+
+	// Restore the closed account
+	user, err := s.userRepo.GetByIDUnscoped(request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	if err := s.userRepo.UpdateUnscoped(user); err != nil {
+		return nil, err
+	}
+
+	before := snapshot(request)
+	request.Status = models.ErasureCancelled
+	if err := s.erasureRepo.Update(request); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "user.cancel_erasure", "user", request.UserID, before, request)
+
+	return request, nil
+}
+
+// ProcessDueErasures anonymizes every user whose erasure request has passed
+// its retention period. Loans are kept so circulation statistics stay intact,
+// but they no longer point to identifiable personal data. A request that
+// fails is marked failed and retried on the next run; the others go ahead.
+func (s *privacyService) ProcessDueErasures() error {
+	requests, err := s.erasureRepo.GetDue(time.Now())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range requests {
+		request := &requests[i]
+		err := s.erase(request)
+		if err == nil {
+			continue
+		}
+		failed++
+		log.Printf("privacy: failed to erase user %d (request %d): %v", request.UserID, request.ID, err)
+		request.Status = models.ErasureFailed
+		request.Error = err.Error()
+		if err := s.erasureRepo.Update(request); err != nil {
+			log.Printf("privacy: failed to mark erasure request %d failed: %v", request.ID, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d erasures failed", failed, len(requests))
+	}
+	return nil
+}
+
+func (s *privacyService) erase(request *models.ErasureRequest) error {
+	user, err := s.userRepo.GetByIDUnscoped(request.UserID)
+	if err != nil {
+		return err
+	}
+
+	before := snapshot(user)
+	anonymizeUser(user)
+	if err := s.userRepo.UpdateUnscoped(user); err != nil {
+		return err
+	}
+
+	if err := s.borrowRepo.ClearNotesByUserID(user.ID); err != nil {
+		return err
+	}
+	if err := s.auditService.ForgetActor(user.ID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	request.Status = models.ErasureCompleted
+	request.CompletedAt = &now
+	request.Error = ""
+	if err := s.erasureRepo.Update(request); err != nil {
+		return err
+	}
+
+	s.auditService.Record(nil, "user.anonymize", "user", user.ID, before, user)
+	return nil
+}
+
+func newErasureRequest(userID, requestedBy uint, reason string, retention time.Duration) *models.ErasureRequest {
+	return &models.ErasureRequest{
+		UserID:       userID,
+		RequestedBy:  requestedBy,
+		Reason:       reason,
+		Status:       models.ErasurePending,
+		ScheduledFor: time.Now().Add(retention),
+	}
+}
+
+func anonymizeUser(user *models.User) {
+	now := time.Now()
+	user.Name = fmt.Sprintf("Erased User %d", user.ID)
+	user.Email = fmt.Sprintf("erased-%d@anonymized.invalid", user.ID)
+	user.Password = "!" // never matches a bcrypt hash
+	user.Phone = "0000000000"
+	user.Address = ""
+	user.IsActive = false
+	user.AnonymizedAt = &now
+	if !user.DeletedAt.Valid {
+		user.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	}
+}
+
+// WriteExportArchive writes a zip archive with the export as JSON and one CSV
+// file per section.
+func WriteExportArchive(w io.Writer, export *models.UserDataExport) error {
+	archive := zip.NewWriter(w)
+
+	jsonFile, err := archive.Create("export.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(jsonFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return err
+	}
+
+	profile := export.Profile
+	profileRows := [][]string{
+		{"id", "name", "email", "phone", "address", "role", "is_active", "created_at"},
+		{
+			strconv.FormatUint(uint64(profile.ID), 10),
+			profile.Name,
+			profile.Email,
+			profile.Phone,
+			profile.Address,
+			profile.Role,
+			strconv.FormatBool(profile.IsActive),
+			profile.CreatedAt.Format(time.RFC3339),
+		},
+	}
+	if err := writeCSVFile(archive, "profile.csv", profileRows); err != nil {
+		return err
+	}
+
+	loanRows := [][]string{
+		{"id", "book_id", "book_title", "book_isbn", "borrow_date", "due_date", "return_date", "status", "notes"},
+	}
+	for _, loan := range export.Loans {
+		returnDate := ""
+		if loan.ReturnDate != nil {
+			returnDate = loan.ReturnDate.Format(time.RFC3339)
+		}
+		loanRows = append(loanRows, []string{
+			strconv.FormatUint(uint64(loan.ID), 10),
+			strconv.FormatUint(uint64(loan.BookID), 10),
+			loan.BookTitle,
+			loan.BookISBN,
+			loan.BorrowDate.Format(time.RFC3339),
+			loan.DueDate.Format(time.RFC3339),
+			returnDate,
+			string(loan.Status),
+			loan.Notes,
+		})
+	}
+	if err := writeCSVFile(archive, "loans.csv", loanRows); err != nil {
+		return err
+	}
+
+	fineRows := [][]string{
+		{"borrow_id", "book_title", "amount", "due_date"},
+	}
+	for _, fine := range export.Fines {
+		fineRows = append(fineRows, []string{
+			strconv.FormatUint(uint64(fine.BorrowID), 10),
+			fine.BookTitle,
+			strconv.FormatFloat(fine.Amount, 'f', 2, 64),
+			fine.DueDate.Format(time.RFC3339),
+		})
+	}
+	if err := writeCSVFile(archive, "fines.csv", fineRows); err != nil {
+		return err
+	}
+
+	holdRows := [][]string{
+		{"id", "work_title", "book_id", "any_edition", "status", "placed_at", "ready_at", "expires_at", "closed_at"},
+	}
+	for _, hold := range export.Holds {
+		bookID := ""
+		if hold.BookID != nil {
+			bookID = strconv.FormatUint(uint64(*hold.BookID), 10)
+		}
+		holdRows = append(holdRows, []string{
+			strconv.FormatUint(uint64(hold.ID), 10),
+			hold.WorkTitle,
+			bookID,
+			strconv.FormatBool(hold.AnyEdition),
+			string(hold.Status),
+			hold.PlacedAt.Format(time.RFC3339),
+			formatOptionalTime(hold.ReadyAt),
+			formatOptionalTime(hold.ExpiresAt),
+			formatOptionalTime(hold.ClosedAt),
+		})
+	}
+	if err := writeCSVFile(archive, "holds.csv", holdRows); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func writeCSVFile(archive *zip.Writer, name string, rows [][]string) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+	return writer.Error()
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// memoryErasureRepository holds erasure requests in a slice. Other methods
+// are not implemented.
+type memoryErasureRepository struct {
+	repositories.ErasureRepository
+	requests []models.ErasureRequest
+}
+
+func (r *memoryErasureRepository) GetDue(now time.Time) ([]models.ErasureRequest, error) {
+	var due []models.ErasureRequest
+	for _, request := range r.requests {
+		if (request.Status == models.ErasurePending || request.Status == models.ErasureFailed) && !request.ScheduledFor.After(now) {
+			due = append(due, request)
+		}
+	}
+	return due, nil
+}
+
+func (r *memoryErasureRepository) Update(request *models.ErasureRequest) error {
+	for i := range r.requests {
+		if r.requests[i].ID == request.ID {
+			r.requests[i] = *request
+		}
+	}
+	return nil
+}
+
+// memoryUserRepository serves users from a map; a missing user is not
+// found. Other methods are not implemented.
+type memoryUserRepository struct {
+	repositories.UserRepository
+	users map[uint]*models.User
+}
+
+func (r *memoryUserRepository) GetByIDUnscoped(id uint) (*models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *memoryUserRepository) UpdateUnscoped(user *models.User) error {
+	copied := *user
+	r.users[user.ID] = &copied
+	return nil
+}
+
+type noteClearingBorrowRepository struct {
+	repositories.BorrowRepository
+}
+
+func (r *noteClearingBorrowRepository) ClearNotesByUserID(userID uint) error {
+	return nil
+}
+
+// One request that cannot be carried out is marked failed and does not hold
+// back the ones after it.
+func TestProcessDueErasuresContinuesAfterFailure(t *testing.T) {
+	due := time.Now().Add(-time.Hour)
+	erasures := &memoryErasureRepository{requests: []models.ErasureRequest{
+		{ID: 1, UserID: 404, Status: models.ErasurePending, ScheduledFor: due},
+		{ID: 2, UserID: 7, Status: models.ErasurePending, ScheduledFor: due},
+	}}
+	users := &memoryUserRepository{users: map[uint]*models.User{
+		7: {ID: 7, Name: "Siti Rahma", Email: "siti@example.com", Role: "member", IsActive: true},
+	}}
+	service := NewPrivacyService(users, &noteClearingBorrowRepository{}, erasures, nil, NewAuditService(&memoryAuditRepository{}), 0)
+
+	if err := service.ProcessDueErasures(); err == nil {
+		t.Error("expected the failed erasure to be reported")
+	}
+
+	failed, completed := erasures.requests[0], erasures.requests[1]
+	if failed.Status != models.ErasureFailed || failed.Error == "" {
+		t.Errorf("request 1 status %q, error %q", failed.Status, failed.Error)
+	}
+	if completed.Status != models.ErasureCompleted || completed.CompletedAt == nil {
+		t.Errorf("request 2 status %q, completed at %v", completed.Status, completed.CompletedAt)
+	}
+	if user := users.users[7]; user.Name == "Siti Rahma" || user.AnonymizedAt == nil {
+		t.Errorf("user 7 not anonymized: %+v", user)
+	}
+
+	// The failed request is retried on the next run
+	users.users[404] = &models.User{ID: 404, Name: "Budi", Role: "member"}
+	if err := service.ProcessDueErasures(); err != nil {
+		t.Fatal(err)
+	}
+	if retried := erasures.requests[0]; retried.Status != models.ErasureCompleted || retried.Error != "" {
+		t.Errorf("retried request status %q, error %q", retried.Status, retried.Error)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type PublisherService interface {
+	GetAllPublishers(spec *query.Spec) ([]models.Publisher, *query.Page, error)
+	GetPublisherByID(id uint) (*models.Publisher, error)
+	GetPublisherBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+	UpdatePublisher(actor *models.Actor, id uint, req *models.UpdatePublisherRequest) (*models.Publisher, error)
+	MergePublishers(actor *models.Actor, req *models.MergeRequest) (*models.Publisher, error)
+	FindDuplicates() ([]models.PublisherDuplicates, error)
+}
+
+type publisherService struct {
+	publisherRepo repositories.PublisherRepository
+	bookRepo      repositories.BookRepository
+	bookService   BookService
+	auditService  AuditService
+}
+
+func NewPublisherService(
+	publisherRepo repositories.PublisherRepository,
+	bookRepo repositories.BookRepository,
+	bookService BookService,
+	auditService AuditService,
+) PublisherService {
+	return &publisherService{
+		publisherRepo: publisherRepo,
+		bookRepo:      bookRepo,
+		bookService:   bookService,
+		auditService:  auditService,
+	}
+}
+
+func (s *publisherService) GetAllPublishers(spec *query.Spec) ([]models.Publisher, *query.Page, error) {
+	return s.publisherRepo.GetAll(spec)
+}
+
+func (s *publisherService) GetPublisherByID(id uint) (*models.Publisher, error) {
+	publisher, err := s.publisherRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("publisher not found")
+		}
+		return nil, err
+	}
+	return publisher, nil
+}
+
+func (s *publisherService) GetPublisherBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	if _, err := s.GetPublisherByID(id); err != nil {
+		return nil, nil, err
+	}
+	return s.bookRepo.GetByPublisher(id, spec)
+}
+
+func (s *publisherService) UpdatePublisher(actor *models.Actor, id uint, req *models.UpdatePublisherRequest) (*models.Publisher, error) {
+	publisher, err := s.GetPublisherByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(publisher)
+	renamed := false
+
+	if name := strings.Join(strings.Fields(req.Name), " "); name != "" && name != publisher.Name {
+		if key := publisherKey(name); key != publisher.NameKey {
+			other, err := s.publisherRepo.GetByNameKey(key)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if other != nil && other.ID != publisher.ID {
+				return nil, errors.New("another publisher has this name, merge them instead")
+			}
+			publisher.NameKey = key
+		}
+		publisher.Name = name
+		renamed = true
+	}
+	if req.City != nil {
+		publisher.City = *req.City
+	}
+
+	if err := s.publisherRepo.Update(publisher); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "publisher.update", "publisher", publisher.ID, before, publisher)
+	if renamed {
+		s.refreshBooks(publisher.ID)
+	}
+
+	return publisher, nil
+}
+
+// MergePublishers moves the books of the source publishers to the target
+// and deletes the sources. Their names keep resolving to the target.
+func (s *publisherService) MergePublishers(actor *models.Actor, req *models.MergeRequest) (*models.Publisher, error) {
+	if req.TargetID == 0 || len(req.SourceIDs) == 0 {
+		return nil, errors.New("target_id and source_ids are required")
+	}
+	if slices.Contains(req.SourceIDs, req.TargetID) {
+		return nil, errors.New("cannot merge a publisher into itself")
+	}
+
+	target, err := s.GetPublisherByID(req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]*models.Publisher, 0, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		source, err := s.GetPublisherByID(id)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	bookIDs, err := s.publisherRepo.Merge(target.ID, req.SourceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		s.auditService.Record(actor, "publisher.merge", "publisher", source.ID, source, target)
+	}
+	s.bookService.RefreshBooks(bookIDs)
+
+	return s.GetPublisherByID(target.ID)
+}
+
+// FindDuplicates groups publishers whose names start with the same word,
+// e.g. "Gramedia" and "Gramedia Pustaka Utama".
+func (s *publisherService) FindDuplicates() ([]models.PublisherDuplicates, error) {
+	publishers, _, err := s.publisherRepo.GetAll(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]models.Publisher)
+	for _, publisher := range publishers {
+		words := strings.Fields(publisher.NameKey)
+		if len(words) == 0 {
+			continue
+		}
+		groups[words[0]] = append(groups[words[0]], publisher)
+	}
+
+	duplicates := []models.PublisherDuplicates{}
+	for key, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		duplicates = append(duplicates, models.PublisherDuplicates{Key: key, Publishers: group})
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].Key < duplicates[j].Key
+	})
+	return duplicates, nil
+}
+
+func (s *publisherService) refreshBooks(publisherID uint) {
+	books, _, err := s.bookRepo.GetByPublisher(publisherID, nil)
+	if err != nil {
+		return
+	}
+	ids := make([]uint, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+	}
+	s.bookService.RefreshBooks(ids)
+}
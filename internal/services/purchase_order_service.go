@@ -0,0 +1,784 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type PurchaseOrderService interface {
+	CreateOrder(actor *models.Actor, req *models.CreateOrderRequest) (*models.PurchaseOrder, error)
+	GetAllOrders(spec *query.Spec) ([]models.PurchaseOrder, *query.Page, error)
+	GetOrderByID(id uint) (*models.PurchaseOrder, error)
+	UpdateOrder(actor *models.Actor, id uint, req *models.UpdateOrderRequest) (*models.PurchaseOrder, error)
+	PlaceOrder(actor *models.Actor, id uint) (*models.PurchaseOrder, error)
+	ReceiveOrder(actor *models.Actor, id uint, req *models.ReceiveOrderRequest) (*models.PurchaseOrder, error)
+	CancelOrder(actor *models.Actor, id uint, req *models.CancelOrderRequest) (*models.PurchaseOrder, error)
+	ClaimOrder(actor *models.Actor, id uint, req *models.ClaimOrderRequest) (*models.PurchaseOrder, error)
+	InvoiceOrder(actor *models.Actor, id uint, req *models.CreateInvoiceRequest) (*models.PurchaseOrder, error)
+}
+
+type purchaseOrderService struct {
+	orderRepo         repositories.PurchaseOrderRepository
+	vendorRepo        repositories.VendorRepository
+	bookRepo          repositories.BookRepository
+	bookService       BookService
+	holdService       HoldService
+	metadataService   MetadataService
+	suggestionService SuggestionService
+	fundService       FundService
+	auditService      AuditService
+}
+
+func NewPurchaseOrderService(
+	orderRepo repositories.PurchaseOrderRepository,
+	vendorRepo repositories.VendorRepository,
+	bookRepo repositories.BookRepository,
+	bookService BookService,
+	holdService HoldService,
+	metadataService MetadataService,
+	suggestionService SuggestionService,
+	fundService FundService,
+	auditService AuditService,
+) PurchaseOrderService {
+	return &purchaseOrderService{
+		orderRepo:         orderRepo,
+		vendorRepo:        vendorRepo,
+		bookRepo:          bookRepo,
+		bookService:       bookService,
+		holdService:       holdService,
+		metadataService:   metadataService,
+		suggestionService: suggestionService,
+		fundService:       fundService,
+		auditService:      auditService,
+	}
+}
+
+// CreateOrder drafts an order. Lines for books already in the catalog are
+// linked to them; details missing from other lines are looked up by ISBN.
+func (s *purchaseOrderService) CreateOrder(actor *models.Actor, req *models.CreateOrderRequest) (*models.PurchaseOrder, error) {
+	vendor, err := s.getActiveVendor(req.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.resolveLines(req.Lines, req.FundID)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.PurchaseOrder{
+		VendorID:   vendor.ID,
+		Status:     models.OrderDraft,
+		Notes:      req.Notes,
+		ExpectedAt: req.ExpectedAt,
+		Lines:      lines,
+	}
+	if actor != nil && actor.UserID != 0 {
+		creator := actor.UserID
+		order.CreatedBy = &creator
+	}
+
+	if err := s.orderRepo.Create(order); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "purchase_order.create", "purchase_order", order.ID, nil, order)
+
+	return s.GetOrderByID(order.ID)
+}
+
+func (s *purchaseOrderService) GetAllOrders(spec *query.Spec) ([]models.PurchaseOrder, *query.Page, error) {
+	return s.orderRepo.GetAll(spec)
+}
+
+func (s *purchaseOrderService) GetOrderByID(id uint) (*models.PurchaseOrder, error) {
+	order, err := s.orderRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("purchase order not found")
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *purchaseOrderService) UpdateOrder(actor *models.Actor, id uint, req *models.UpdateOrderRequest) (*models.PurchaseOrder, error) {
+	order, err := s.GetOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderDraft {
+		return nil, errors.New("only draft orders can be edited")
+	}
+
+	before := snapshot(order)
+
+	if req.VendorID != 0 && req.VendorID != order.VendorID {
+		vendor, err := s.getActiveVendor(req.VendorID)
+		if err != nil {
+			return nil, err
+		}
+		order.VendorID = vendor.ID
+		order.Vendor = vendor
+	}
+	if req.ExpectedAt != nil {
+		order.ExpectedAt = req.ExpectedAt
+	}
+	if req.Notes != nil {
+		order.Notes = *req.Notes
+	}
+	if req.Lines != nil {
+		lines, err := s.resolveLines(req.Lines, req.FundID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.orderRepo.ReplaceLines(order.ID, lines); err != nil {
+			return nil, err
+		}
+	} else if req.FundID != nil {
+		// A fund alone charges the whole order to it
+		if _, err := s.getActiveFund(*req.FundID); err != nil {
+			return nil, err
+		}
+		for i := range order.Lines {
+			order.Lines[i].FundID = req.FundID
+			if err := s.orderRepo.UpdateLine(&order.Lines[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.orderRepo.Update(order); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.GetOrderByID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "purchase_order.update", "purchase_order", order.ID, before, updated)
+
+	return updated, nil
+}
+
+// PlaceOrder sends a draft to the vendor, committing the cost of lines
+// charged to funds against their budgets. Without an expected date the
+// order is due after the vendor's usual lead time. Suggestions the lines
+// were bought for are marked as ordered.
+func (s *purchaseOrderService) PlaceOrder(actor *models.Actor, id uint) (*models.PurchaseOrder, error) {
+	order, err := s.GetOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderDraft {
+		return nil, fmt.Errorf("order is already %s", order.Status)
+	}
+	if len(order.Lines) == 0 {
+		return nil, errors.New("order has no lines")
+	}
+	vendor, err := s.getActiveVendor(order.VendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(order)
+	now := time.Now()
+	order.Status = models.OrderPlaced
+	order.OrderedAt = &now
+	if order.ExpectedAt == nil {
+		expected := now.AddDate(0, 0, vendor.DeliveryDays)
+		order.ExpectedAt = &expected
+	}
+
+	entries, err := s.fundService.Encumber(actor, order)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orderRepo.Place(order, entries); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrOrderNotDraft):
+			return nil, errors.New("order is no longer a draft")
+		case errors.Is(err, repositories.ErrBudgetExceeded):
+			return nil, errors.New("a fund can no longer cover the order, check its budget")
+		}
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "purchase_order.place", "purchase_order", order.ID, before, order)
+
+	for _, line := range order.Lines {
+		if line.SuggestionID == nil {
+			continue
+		}
+		suggestion, err := s.suggestionService.GetSuggestionByID(*line.SuggestionID)
+		if err != nil || suggestion.Status == models.SuggestionOrdered || !suggestion.Open() {
+			continue
+		}
+		_, err = s.suggestionService.ReviewSuggestion(actor, suggestion.ID, &models.ReviewSuggestionRequest{
+			Status: models.SuggestionOrdered,
+		})
+		if err != nil {
+			log.Printf("acquisitions: failed to mark suggestion %d as ordered: %v", suggestion.ID, err)
+		}
+	}
+
+	return order, nil
+}
+
+// ReceiveOrder books in a delivery. Each copy received adds stock to the
+// line's book, which is catalogued on first delivery if the library did
+// not have it yet. New copies go to waiting holds first.
+func (s *purchaseOrderService) ReceiveOrder(actor *models.Actor, id uint, req *models.ReceiveOrderRequest) (*models.PurchaseOrder, error) {
+	order, err := s.getOpenOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Lines) == 0 {
+		return nil, errors.New("at least one line is required")
+	}
+
+	// Check the whole delivery before booking any of it in
+	lines := orderLines(order)
+	quantities := make(map[uint]int, len(req.Lines))
+	for _, input := range req.Lines {
+		line, ok := lines[input.LineID]
+		if !ok {
+			return nil, fmt.Errorf("line %d is not on this order", input.LineID)
+		}
+		if input.Quantity < 1 {
+			return nil, errors.New("quantity must be at least 1")
+		}
+		quantities[line.ID] += input.Quantity
+		if quantities[line.ID] > line.Outstanding() {
+			return nil, fmt.Errorf("line %d has only %d copies outstanding", line.ID, line.Outstanding())
+		}
+	}
+
+	before := snapshot(order)
+
+	// Lines booked in before a failure stay received
+	var failed error
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		quantity := quantities[line.ID]
+		if quantity == 0 {
+			continue
+		}
+		if failed = s.receive(actor, line, quantity, req.Location); failed != nil {
+			failed = fmt.Errorf("line %d: %w", line.ID, failed)
+			break
+		}
+	}
+
+	if err := s.settle(order); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "purchase_order.receive", "purchase_order", order.ID, before, order)
+
+	if failed != nil {
+		return nil, failed
+	}
+	return order, nil
+}
+
+// CancelOrder cancels the copies still outstanding on some lines, or on
+// the whole order, releasing what they had committed from the funds.
+// Copies already received are kept.
+func (s *purchaseOrderService) CancelOrder(actor *models.Actor, id uint, req *models.CancelOrderRequest) (*models.PurchaseOrder, error) {
+	order, err := s.GetOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != models.OrderDraft && !order.Open() {
+		return nil, fmt.Errorf("order is already %s", order.Status)
+	}
+
+	cancel := make(map[uint]bool, len(order.Lines))
+	if len(req.LineIDs) == 0 || order.Status == models.OrderDraft {
+		for _, line := range order.Lines {
+			cancel[line.ID] = true
+		}
+	} else {
+		lines := orderLines(order)
+		for _, lineID := range req.LineIDs {
+			line, ok := lines[lineID]
+			if !ok {
+				return nil, fmt.Errorf("line %d is not on this order", lineID)
+			}
+			if line.Outstanding() == 0 {
+				return nil, fmt.Errorf("line %d has nothing outstanding", lineID)
+			}
+			cancel[lineID] = true
+		}
+	}
+
+	before := snapshot(order)
+
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		if !cancel[line.ID] || line.Outstanding() == 0 {
+			continue
+		}
+		copies := line.Outstanding()
+		line.Cancelled += copies
+		entries, err := s.fundService.Release(actor, order, line, copies, "Cancelled on order "+order.Number)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.orderRepo.Cancel(line, copies, entries); err != nil {
+			if errors.Is(err, repositories.ErrLineChanged) {
+				return nil, fmt.Errorf("line %d changed while the order was cancelled, try again", line.ID)
+			}
+			return nil, err
+		}
+	}
+	if req.Reason != "" {
+		order.CancelReason = req.Reason
+	}
+
+	if err := s.settle(order); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "purchase_order.cancel", "purchase_order", order.ID, before, order)
+
+	return order, nil
+}
+
+// ClaimOrder records that the vendor was chased for a late delivery,
+// with the new date they promised if any.
+func (s *purchaseOrderService) ClaimOrder(actor *models.Actor, id uint, req *models.ClaimOrderRequest) (*models.PurchaseOrder, error) {
+	order, err := s.getOpenOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !order.Late(now) {
+		return nil, errors.New("order is not late yet")
+	}
+	if req.ExpectedAt != nil && !req.ExpectedAt.After(now) {
+		return nil, errors.New("expected_at must be in the future")
+	}
+
+	before := snapshot(order)
+	order.ClaimCount++
+	order.ClaimedAt = &now
+	if req.ExpectedAt != nil {
+		order.ExpectedAt = req.ExpectedAt
+	}
+	if req.Note != "" {
+		if order.Notes != "" {
+			order.Notes += "\n"
+		}
+		order.Notes += fmt.Sprintf("Claim %d on %s: %s", order.ClaimCount, now.Format("2006-01-02"), req.Note)
+	}
+
+	if err := s.orderRepo.Update(order); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "purchase_order.claim", "purchase_order", order.ID, before, order)
+
+	return order, nil
+}
+
+// InvoiceOrder records a vendor's bill for copies on an order. Each line
+// billed is charged to its fund, in place of what was committed for it.
+func (s *purchaseOrderService) InvoiceOrder(actor *models.Actor, id uint, req *models.CreateInvoiceRequest) (*models.PurchaseOrder, error) {
+	order, err := s.GetOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == models.OrderDraft {
+		return nil, errors.New("order has not been placed")
+	}
+	number := strings.TrimSpace(req.Number)
+	if number == "" {
+		return nil, errors.New("number is required")
+	}
+	if len(req.Lines) == 0 {
+		return nil, errors.New("at least one line is required")
+	}
+	exists, err := s.orderRepo.InvoiceExists(order.VendorID, number)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("invoice %s from this vendor is already recorded", number)
+	}
+
+	invoice := &models.Invoice{
+		OrderID:     order.ID,
+		VendorID:    order.VendorID,
+		Number:      truncate(number, 50),
+		InvoiceDate: time.Now(),
+		Notes:       req.Notes,
+	}
+	if req.InvoiceDate != nil {
+		invoice.InvoiceDate = *req.InvoiceDate
+	}
+	if actor != nil && actor.UserID != 0 {
+		creator := actor.UserID
+		invoice.CreatedBy = &creator
+	}
+
+	lines := orderLines(order)
+	billed := make(map[uint]int, len(req.Lines))
+	for _, input := range req.Lines {
+		line, ok := lines[input.LineID]
+		if !ok {
+			return nil, fmt.Errorf("line %d is not on this order", input.LineID)
+		}
+		if input.Quantity < 1 {
+			return nil, errors.New("quantity must be at least 1")
+		}
+		billed[line.ID] += input.Quantity
+		if billed[line.ID] > line.Uninvoiced() {
+			return nil, fmt.Errorf("line %d has only %d copies left to invoice", line.ID, line.Uninvoiced())
+		}
+		amount := float64(input.Quantity) * line.UnitPrice
+		if input.Amount != nil {
+			if *input.Amount < 0 {
+				return nil, errors.New("amount cannot be negative")
+			}
+			amount = *input.Amount
+		}
+		invoice.Lines = append(invoice.Lines, models.InvoiceLine{
+			OrderLineID: line.ID,
+			Quantity:    input.Quantity,
+			Amount:      money(amount),
+			BudgetID:    line.BudgetID,
+		})
+		invoice.Total += money(amount)
+	}
+	invoice.Total = money(invoice.Total)
+
+	var changed []*models.OrderLine
+	var entries []models.FundTransaction
+	for _, billedLine := range invoice.Lines {
+		line := lines[billedLine.OrderLineID]
+		if _, ok := billed[line.ID]; ok {
+			changed = append(changed, line)
+			delete(billed, line.ID)
+		}
+		line.Invoiced += billedLine.Quantity
+		line.Spent = money(line.Spent + billedLine.Amount)
+		spend, err := s.fundService.Spend(actor, order, line, invoice, billedLine.Quantity, billedLine.Amount)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, spend...)
+	}
+
+	if err := s.orderRepo.CreateInvoice(invoice, changed, entries); err != nil {
+		if errors.Is(err, repositories.ErrLineChanged) {
+			return nil, errors.New("the order changed while the invoice was recorded, try again")
+		}
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "invoice.create", "invoice", invoice.ID, nil, invoice)
+
+	return s.GetOrderByID(order.ID)
+}
+
+// resolveLines checks order lines and completes them from the catalog or,
+// for books the library does not have, the metadata provider. Lines that
+// name no fund are charged to defaultFund, if given.
+func (s *purchaseOrderService) resolveLines(inputs []models.OrderLineInput, defaultFund *uint) ([]models.OrderLine, error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("at least one line is required")
+	}
+
+	lines := make([]models.OrderLine, 0, len(inputs))
+	seen := make(map[string]int, len(inputs))
+	for i, input := range inputs {
+		n := i + 1
+		canonical, err := isbn.Normalize(input.ISBN)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid ISBN", n)
+		}
+		if other, ok := seen[canonical]; ok {
+			return nil, fmt.Errorf("line %d: ISBN %s is already on line %d", n, canonical, other)
+		}
+		seen[canonical] = n
+		if input.Quantity < 1 {
+			return nil, fmt.Errorf("line %d: quantity must be at least 1", n)
+		}
+		if input.UnitPrice < 0 {
+			return nil, fmt.Errorf("line %d: unit_price cannot be negative", n)
+		}
+
+		line := models.OrderLine{
+			ISBN:         canonical,
+			Title:        truncate(input.Title, 200),
+			Author:       truncate(input.Author, 100),
+			Publisher:    truncate(input.Publisher, 100),
+			Category:     truncate(input.Category, 50),
+			PublishYear:  input.PublishYear,
+			Quantity:     input.Quantity,
+			UnitPrice:    input.UnitPrice,
+			SuggestionID: input.SuggestionID,
+			FundID:       input.FundID,
+		}
+		if line.FundID == nil {
+			line.FundID = defaultFund
+		}
+		if line.FundID != nil {
+			if _, err := s.getActiveFund(*line.FundID); err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+		}
+
+		book, err := s.bookRepo.GetByISBN(canonical)
+		switch {
+		case err == nil:
+			line.BookID = &book.ID
+			fillLine(&line, book.Title, book.Author, book.Publisher, book.Category, book.PublishYear)
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		case line.Title == "":
+			found, err := s.metadataService.LookupISBN(canonical)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: title is required, no metadata found for ISBN %s", n, canonical)
+			}
+			fillLine(&line, found.Title, found.Author, found.Publisher, found.Category, found.PublishYear)
+		}
+
+		if line.SuggestionID != nil {
+			suggestion, err := s.suggestionService.GetSuggestionByID(*line.SuggestionID)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			if !suggestion.Open() {
+				return nil, fmt.Errorf("line %d: suggestion is already %s", n, suggestion.Status)
+			}
+		}
+
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// receive books in copies of one line, then hands them to the suggestion
+// they were bought for and to waiting holds. Copies of a book the library
+// has go on its shelf together with booking them in on the line; a new
+// book is catalogued once they are booked in.
+func (s *purchaseOrderService) receive(actor *models.Actor, line *models.OrderLine, quantity int, location string) error {
+	book, err := s.receiveLine(actor, line, quantity, location)
+	if err != nil {
+		if errors.Is(err, repositories.ErrLineChanged) {
+			return errors.New("copies were received or cancelled meanwhile, try again")
+		}
+		return err
+	}
+	line.BookID = &book.ID
+	line.Book = book
+
+	if line.SuggestionID != nil {
+		s.catalogueSuggestion(actor, *line.SuggestionID, book.ID)
+	}
+	if err := s.holdService.AllocateCopies(book.ID); err != nil {
+		log.Printf("holds: failed to allocate book %d: %v", book.ID, err)
+	}
+	return nil
+}
+
+// receiveLine books in copies of the line and adds them to the line's
+// book, cataloguing it first if the library does not have it yet.
+func (s *purchaseOrderService) receiveLine(actor *models.Actor, line *models.OrderLine, quantity int, location string) (*models.Book, error) {
+	bookID := line.BookID
+	if bookID == nil {
+		book, err := s.bookRepo.GetByISBN(line.ISBN)
+		switch {
+		case err == nil:
+			bookID = &book.ID
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		}
+	}
+	if bookID != nil {
+		before, err := s.bookRepo.GetByID(*bookID)
+		if err != nil {
+			return nil, err
+		}
+		line.BookID = bookID
+		if err := s.orderRepo.Receive(line, quantity, true); err != nil {
+			return nil, err
+		}
+		book, err := s.bookRepo.GetByID(*bookID)
+		if err != nil {
+			return nil, err
+		}
+		s.auditService.Record(actor, "book.update", "book", book.ID, before, book)
+		return book, nil
+	}
+
+	// Claim the copies before cataloguing, so a second delivery of the same
+	// copies cannot catalogue them again
+	if err := s.orderRepo.Receive(line, quantity, false); err != nil {
+		return nil, err
+	}
+	book, err := s.catalogueLine(actor, line, quantity, location)
+	if err != nil {
+		if undo := s.orderRepo.Unreceive(line, quantity); undo != nil {
+			log.Printf("acquisitions: failed to take back copies of line %d: %v", line.ID, undo)
+		}
+		return nil, err
+	}
+	if err := s.orderRepo.SetLineBook(line.ID, book.ID); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+// catalogueLine adds the book of a line the library does not have yet,
+// with the copies received.
+func (s *purchaseOrderService) catalogueLine(actor *models.Actor, line *models.OrderLine, quantity int, location string) (*models.Book, error) {
+	// The provider fills in what the order does not say, e.g. pages and
+	// subjects
+	req, err := s.metadataService.LookupISBN(line.ISBN)
+	if err != nil {
+		req = &models.CreateBookRequest{ISBN: line.ISBN}
+	}
+	req.Title = line.Title
+	if line.Author != "" && line.Author != req.Author {
+		req.Author = line.Author
+		req.Contributors = nil
+	}
+	if line.Publisher != "" {
+		req.Publisher = line.Publisher
+	}
+	if line.Category != "" {
+		req.Category = line.Category
+	}
+	if line.PublishYear != 0 {
+		req.PublishYear = line.PublishYear
+	}
+	req.Stock = quantity
+	req.Location = location
+
+	return s.bookService.CreateBook(actor, req)
+}
+
+func (s *purchaseOrderService) catalogueSuggestion(actor *models.Actor, suggestionID, bookID uint) {
+	suggestion, err := s.suggestionService.GetSuggestionByID(suggestionID)
+	if err != nil || !suggestion.Open() {
+		return
+	}
+	_, err = s.suggestionService.CatalogueSuggestion(actor, suggestion.ID, &models.CatalogueSuggestionRequest{BookID: bookID})
+	if err != nil {
+		log.Printf("acquisitions: failed to catalogue suggestion %d: %v", suggestion.ID, err)
+	}
+}
+
+// settle moves an order on after a delivery or cancellation. It closes
+// once nothing is outstanding, as received if any copy arrived.
+func (s *purchaseOrderService) settle(order *models.PurchaseOrder) error {
+	// Settle on the lines as saved, with concurrent deliveries included
+	saved, err := s.orderRepo.GetByID(order.ID)
+	if err != nil {
+		return err
+	}
+	order.Lines = saved.Lines
+
+	outstanding, received := 0, 0
+	for _, line := range order.Lines {
+		outstanding += line.Outstanding()
+		received += line.Received
+	}
+
+	switch {
+	case outstanding > 0 && received > 0:
+		order.Status = models.OrderPartial
+	case outstanding > 0:
+		// Nothing arrived yet; a draft stays a draft
+	case received > 0:
+		order.Status = models.OrderReceived
+	default:
+		order.Status = models.OrderCancelled
+	}
+	if outstanding == 0 {
+		now := time.Now()
+		order.ClosedAt = &now
+	}
+
+	return s.orderRepo.Update(order)
+}
+
+func (s *purchaseOrderService) getOpenOrder(id uint) (*models.PurchaseOrder, error) {
+	order, err := s.GetOrderByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status == models.OrderDraft {
+		return nil, errors.New("order has not been placed")
+	}
+	if !order.Open() {
+		return nil, fmt.Errorf("order is already %s", order.Status)
+	}
+	return order, nil
+}
+
+func (s *purchaseOrderService) getActiveVendor(id uint) (*models.Vendor, error) {
+	vendor, err := s.vendorRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("vendor not found")
+		}
+		return nil, err
+	}
+	if !vendor.IsActive {
+		return nil, errors.New("vendor is not active")
+	}
+	return vendor, nil
+}
+
+func (s *purchaseOrderService) getActiveFund(id uint) (*models.Fund, error) {
+	fund, err := s.fundService.GetFundByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !fund.IsActive {
+		return nil, fmt.Errorf("fund %s is not active", fund.Code)
+	}
+	return fund, nil
+}
+
+// orderLines indexes the lines of an order by ID.
+func orderLines(order *models.PurchaseOrder) map[uint]*models.OrderLine {
+	lines := make(map[uint]*models.OrderLine, len(order.Lines))
+	for i := range order.Lines {
+		lines[order.Lines[i].ID] = &order.Lines[i]
+	}
+	return lines
+}
+
+// fillLine completes the details an order line left out.
+func fillLine(line *models.OrderLine, title, author, publisher, category string, publishYear int) {
+	if line.Title == "" {
+		line.Title = truncate(title, 200)
+	}
+	if line.Author == "" {
+		line.Author = truncate(author, 100)
+	}
+	if line.Publisher == "" {
+		line.Publisher = truncate(publisher, 100)
+	}
+	if line.Category == "" {
+		line.Category = truncate(category, 50)
+	}
+	if line.PublishYear == 0 {
+		line.PublishYear = publishYear
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type SerialLoanService interface {
+	BorrowIssue(actor *models.Actor, issueID uint, req *models.BorrowIssueRequest) (*models.SerialLoan, error)
+	ReturnIssue(actor *models.Actor, id uint, req *models.ReturnIssueRequest) (*models.SerialLoan, error)
+	GetAllLoans(spec *query.Spec) ([]models.SerialLoan, *query.Page, error)
+	GetLoansByUser(userID uint, spec *query.Spec) ([]models.SerialLoan, *query.Page, error)
+	UpdateOverdueLoans() error
+}
+
+type serialLoanService struct {
+	loanRepo     repositories.SerialLoanRepository
+	serialRepo   repositories.SerialRepository
+	userRepo     repositories.UserRepository
+	auditService AuditService
+}
+
+func NewSerialLoanService(
+	loanRepo repositories.SerialLoanRepository,
+	serialRepo repositories.SerialRepository,
+	userRepo repositories.UserRepository,
+	auditService AuditService,
+) SerialLoanService {
+	return &serialLoanService{
+		loanRepo:     loanRepo,
+		serialRepo:   serialRepo,
+		userRepo:     userRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *serialLoanService) BorrowIssue(actor *models.Actor, issueID uint, req *models.BorrowIssueRequest) (*models.SerialLoan, error) {
+	issue, err := s.serialRepo.GetIssueByID(issueID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("issue not found")
+		}
+		return nil, err
+	}
+	if issue.Status != models.IssueReceived {
+		return nil, errors.New("issue has not been received")
+	}
+	if !issue.Borrowable {
+		return nil, errors.New("issue is reference-only")
+	}
+	if issue.OnLoan {
+		return nil, errors.New("issue is already on loan")
+	}
+
+	user, err := s.userRepo.GetByID(req.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, errors.New("user is not active")
+	}
+
+	loanDays := issue.LoanDays
+	if loanDays == 0 && issue.Serial != nil {
+		loanDays = issue.Serial.LoanDays
+	}
+	if loanDays <= 0 {
+		loanDays = 7
+	}
+
+	now := time.Now()
+	userID := user.ID
+	loan := &models.SerialLoan{
+		IssueID:    issue.ID,
+		UserID:     &userID,
+		BorrowDate: now,
+		DueDate:    now.AddDate(0, 0, loanDays),
+		Status:     models.StatusBorrowed,
+		Notes:      req.Notes,
+	}
+	if err := s.loanRepo.Create(loan); err != nil {
+		return nil, err
+	}
+
+	issue.OnLoan = true
+	if err := s.serialRepo.UpdateIssue(issue); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial_loan.create", "serial_loan", loan.ID, nil, loan)
+
+	return s.loanRepo.GetByID(loan.ID)
+}
+
+func (s *serialLoanService) ReturnIssue(actor *models.Actor, id uint, req *models.ReturnIssueRequest) (*models.SerialLoan, error) {
+	loan, err := s.loanRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("serial loan not found")
+		}
+		return nil, err
+	}
+	if loan.Status != models.StatusBorrowed && loan.Status != models.StatusOverdue {
+		return nil, errors.New("issue is not currently borrowed")
+	}
+
+	before := snapshot(loan)
+
+	now := time.Now()
+	loan.ReturnDate = &now
+	loan.Status = models.StatusReturned
+	if req.Notes != "" {
+		loan.Notes = req.Notes
+	}
+	if err := s.loanRepo.Update(loan); err != nil {
+		return nil, err
+	}
+
+	if loan.Issue != nil {
+		loan.Issue.OnLoan = false
+		if err := s.serialRepo.UpdateIssue(loan.Issue); err != nil {
+			return nil, err
+		}
+	}
+
+	s.auditService.Record(actor, "serial_loan.return", "serial_loan", loan.ID, before, loan)
+
+	return loan, nil
+}
+
+func (s *serialLoanService) GetAllLoans(spec *query.Spec) ([]models.SerialLoan, *query.Page, error) {
+	return s.loanRepo.GetAll(spec)
+}
+
+func (s *serialLoanService) GetLoansByUser(userID uint, spec *query.Spec) ([]models.SerialLoan, *query.Page, error) {
+	return s.loanRepo.GetByUser(userID, spec)
+}
+
+func (s *serialLoanService) UpdateOverdueLoans() error {
+	loans, err := s.loanRepo.GetOverdue(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, loan := range loans {
+		before := snapshot(loan)
+		loan.Status = models.StatusOverdue
+		if err := s.loanRepo.Update(&loan); err != nil {
+			return err
+		}
+		s.auditService.Record(nil, "serial_loan.mark_overdue", "serial_loan", loan.ID, before, loan)
+	}
+
+	return nil
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type SerialService interface {
+	CreateSerial(actor *models.Actor, req *models.CreateSerialRequest) (*models.Serial, error)
+	GetAllSerials(spec *query.Spec) ([]models.Serial, *query.Page, error)
+	GetSerialByID(id uint) (*models.Serial, error)
+	UpdateSerial(actor *models.Actor, id uint, req *models.UpdateSerialRequest) (*models.Serial, error)
+	AddSubscription(actor *models.Actor, serialID uint, req *models.CreateSubscriptionRequest) (*models.SerialSubscription, error)
+	GetIssues(serialID uint, spec *query.Spec) ([]models.SerialIssue, *query.Page, error)
+	GetIssueByID(id uint) (*models.SerialIssue, error)
+	AddIssue(actor *models.Actor, serialID uint, req *models.AddIssueRequest) (*models.SerialIssue, error)
+	CheckInIssue(actor *models.Actor, id uint, req *models.CheckInIssueRequest) (*models.SerialIssue, error)
+	ClaimIssue(actor *models.Actor, id uint, req *models.ClaimIssueRequest) (*models.SerialIssue, error)
+	MarkIssueMissing(actor *models.Actor, id uint, req *models.MissingIssueRequest) (*models.SerialIssue, error)
+	SetLoanRules(actor *models.Actor, id uint, req *models.IssueLoanRulesRequest) (*models.SerialIssue, error)
+	ProcessClaims() error
+}
+
+type serialService struct {
+	serialRepo          repositories.SerialRepository
+	vendorRepo          repositories.VendorRepository
+	userRepo            repositories.UserRepository
+	notificationService NotificationService
+	auditService        AuditService
+}
+
+func NewSerialService(
+	serialRepo repositories.SerialRepository,
+	vendorRepo repositories.VendorRepository,
+	userRepo repositories.UserRepository,
+	notificationService NotificationService,
+	auditService AuditService,
+) SerialService {
+	return &serialService{
+		serialRepo:          serialRepo,
+		vendorRepo:          vendorRepo,
+		userRepo:            userRepo,
+		notificationService: notificationService,
+		auditService:        auditService,
+	}
+}
+
+// Longest subscription accepted, to keep predictions bounded
+const maxSubscriptionYears = 5
+
+// Days past the expected date before an issue is claimed, unless the
+// subscription says otherwise
+const defaultClaimAfterDays = 14
+
+func (s *serialService) CreateSerial(actor *models.Actor, req *models.CreateSerialRequest) (*models.Serial, error) {
+	title := strings.Join(strings.Fields(req.Title), " ")
+	if title == "" {
+		return nil, errors.New("title is required")
+	}
+	if !validFrequency(req.Frequency) {
+		return nil, errors.New("frequency must be weekly, monthly or quarterly")
+	}
+	if req.LoanDays < 0 {
+		return nil, errors.New("loan_days cannot be negative")
+	}
+
+	serial := &models.Serial{
+		Title:     truncate(title, 200),
+		ISSN:      strings.ToUpper(strings.TrimSpace(req.ISSN)),
+		Publisher: truncate(req.Publisher, 100),
+		Category:  truncate(req.Category, 50),
+		Frequency: req.Frequency,
+		Location:  truncate(req.Location, 50),
+		Notes:     req.Notes,
+		LoanDays:  req.LoanDays,
+		IsActive:  true,
+	}
+	if serial.LoanDays == 0 {
+		serial.LoanDays = 7
+	}
+
+	if err := s.serialRepo.Create(serial); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial.create", "serial", serial.ID, nil, serial)
+
+	return serial, nil
+}
+
+func (s *serialService) GetAllSerials(spec *query.Spec) ([]models.Serial, *query.Page, error) {
+	return s.serialRepo.GetAll(spec)
+}
+
+func (s *serialService) GetSerialByID(id uint) (*models.Serial, error) {
+	serial, err := s.serialRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("serial not found")
+		}
+		return nil, err
+	}
+	return serial, nil
+}
+
+func (s *serialService) UpdateSerial(actor *models.Actor, id uint, req *models.UpdateSerialRequest) (*models.Serial, error) {
+	serial, err := s.GetSerialByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(serial)
+
+	if title := strings.Join(strings.Fields(req.Title), " "); title != "" {
+		serial.Title = truncate(title, 200)
+	}
+	if req.ISSN != nil {
+		serial.ISSN = strings.ToUpper(strings.TrimSpace(*req.ISSN))
+	}
+	if req.Publisher != nil {
+		serial.Publisher = truncate(*req.Publisher, 100)
+	}
+	if req.Category != nil {
+		serial.Category = truncate(*req.Category, 50)
+	}
+	if req.Frequency != "" {
+		// Issues already predicted keep their dates
+		if !validFrequency(req.Frequency) {
+			return nil, errors.New("frequency must be weekly, monthly or quarterly")
+		}
+		serial.Frequency = req.Frequency
+	}
+	if req.Location != nil {
+		serial.Location = truncate(*req.Location, 50)
+	}
+	if req.Notes != nil {
+		serial.Notes = *req.Notes
+	}
+	if req.LoanDays != nil {
+		if *req.LoanDays < 1 {
+			return nil, errors.New("loan_days must be at least 1")
+		}
+		serial.LoanDays = *req.LoanDays
+	}
+	if req.IsActive != nil {
+		serial.IsActive = *req.IsActive
+	}
+
+	if err := s.serialRepo.Update(serial); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial.update", "serial", serial.ID, before, serial)
+
+	return serial, nil
+}
+
+// AddSubscription adds a subscription period and predicts the issues it
+// should bring from the serial's frequency.
+func (s *serialService) AddSubscription(actor *models.Actor, serialID uint, req *models.CreateSubscriptionRequest) (*models.SerialSubscription, error) {
+	serial, err := s.GetSerialByID(serialID)
+	if err != nil {
+		return nil, err
+	}
+	if !serial.IsActive {
+		return nil, errors.New("serial is not active")
+	}
+
+	if req.StartDate.IsZero() || req.EndDate.IsZero() {
+		return nil, errors.New("start_date and end_date are required")
+	}
+	if !req.EndDate.After(req.StartDate) {
+		return nil, errors.New("end_date must be after start_date")
+	}
+	if req.EndDate.After(req.StartDate.AddDate(maxSubscriptionYears, 0, 0)) {
+		return nil, fmt.Errorf("a subscription cannot be longer than %d years", maxSubscriptionYears)
+	}
+	first := req.StartDate
+	if req.FirstIssueDate != nil {
+		first = *req.FirstIssueDate
+	}
+	if first.Before(req.StartDate) || first.After(req.EndDate) {
+		return nil, errors.New("first_issue_date must be within the subscription")
+	}
+	if req.StartVolume < 0 || req.StartNumber < 0 || req.IssuesPerVolume < 0 || req.ClaimAfterDays < 0 || req.Price < 0 {
+		return nil, errors.New("numbers and price cannot be negative")
+	}
+	for _, other := range serial.Subscriptions {
+		if other.StartDate.Before(req.EndDate) && req.StartDate.Before(other.EndDate) {
+			return nil, fmt.Errorf("subscription overlaps the one from %s to %s",
+				other.StartDate.Format("2006-01-02"), other.EndDate.Format("2006-01-02"))
+		}
+	}
+	if req.VendorID != nil {
+		vendor, err := s.vendorRepo.GetByID(*req.VendorID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("vendor not found")
+			}
+			return nil, err
+		}
+		if !vendor.IsActive {
+			return nil, errors.New("vendor is not active")
+		}
+	}
+
+	subscription := &models.SerialSubscription{
+		SerialID:        serial.ID,
+		VendorID:        req.VendorID,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		FirstIssueDate:  first,
+		IssuesPerVolume: req.IssuesPerVolume,
+		ClaimAfterDays:  req.ClaimAfterDays,
+		Price:           money(req.Price),
+		Notes:           req.Notes,
+	}
+	if subscription.ClaimAfterDays == 0 {
+		subscription.ClaimAfterDays = defaultClaimAfterDays
+	}
+
+	volume, number := req.StartVolume, req.StartNumber
+	if volume == 0 && number == 0 {
+		// Carry on from the last issue of the previous subscription
+		last, err := s.serialRepo.GetLastIssue(serial.ID)
+		switch {
+		case err == nil:
+			volume, number = nextNumbering(last.Volume, last.Number, req.IssuesPerVolume)
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return nil, err
+		}
+	}
+	if volume == 0 {
+		volume = 1
+	}
+	if number == 0 {
+		number = 1
+	}
+
+	issues := predictIssues(serial.ID, serial.Frequency, subscription, volume, number)
+
+	if err := s.serialRepo.CreateSubscription(subscription, issues); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial.subscribe", "serial", serial.ID, nil, subscription)
+
+	return subscription, nil
+}
+
+func (s *serialService) GetIssues(serialID uint, spec *query.Spec) ([]models.SerialIssue, *query.Page, error) {
+	if serialID != 0 {
+		if _, err := s.GetSerialByID(serialID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s.serialRepo.GetIssues(serialID, spec)
+}
+
+func (s *serialService) GetIssueByID(id uint) (*models.SerialIssue, error) {
+	issue, err := s.serialRepo.GetIssueByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("issue not found")
+		}
+		return nil, err
+	}
+	return issue, nil
+}
+
+// AddIssue records an issue that arrived outside the predicted pattern,
+// such as a special edition or supplement.
+func (s *serialService) AddIssue(actor *models.Actor, serialID uint, req *models.AddIssueRequest) (*models.SerialIssue, error) {
+	serial, err := s.GetSerialByID(serialID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Volume < 0 || req.Number < 0 {
+		return nil, errors.New("volume and number cannot be negative")
+	}
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		if req.Number == 0 {
+			return nil, errors.New("label or number is required")
+		}
+		label = issueLabel(req.Volume, req.Number)
+	}
+
+	now := time.Now()
+	issue := &models.SerialIssue{
+		SerialID:   serial.ID,
+		Volume:     req.Volume,
+		Number:     req.Number,
+		Label:      truncate(label, 100),
+		ExpectedAt: now,
+		ClaimAt:    now,
+		Status:     models.IssueReceived,
+		ReceivedAt: &now,
+		Location:   truncate(req.Location, 50),
+		Notes:      req.Notes,
+	}
+	if issue.Location == "" {
+		issue.Location = serial.Location
+	}
+
+	if err := s.serialRepo.CreateIssue(issue); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial_issue.create", "serial_issue", issue.ID, nil, issue)
+
+	issue.Serial = serial
+	return issue, nil
+}
+
+// CheckInIssue records the arrival of an awaited issue. An issue given
+// up as missing can still be checked in if it turns up.
+func (s *serialService) CheckInIssue(actor *models.Actor, id uint, req *models.CheckInIssueRequest) (*models.SerialIssue, error) {
+	issue, err := s.GetIssueByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !issue.Open() && issue.Status != models.IssueMissing {
+		return nil, fmt.Errorf("issue is already %s", issue.Status)
+	}
+
+	now := time.Now()
+	receivedAt := now
+	if req.ReceivedAt != nil {
+		if req.ReceivedAt.After(now) {
+			return nil, errors.New("received_at cannot be in the future")
+		}
+		receivedAt = *req.ReceivedAt
+	}
+
+	before := snapshot(issue)
+	issue.Status = models.IssueReceived
+	issue.ReceivedAt = &receivedAt
+	issue.Location = truncate(req.Location, 50)
+	if issue.Location == "" && issue.Serial != nil {
+		issue.Location = issue.Serial.Location
+	}
+	appendIssueNote(issue, req.Notes)
+
+	if err := s.serialRepo.UpdateIssue(issue); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial_issue.check_in", "serial_issue", issue.ID, before, issue)
+
+	return issue, nil
+}
+
+// ClaimIssue records chasing the vendor for an issue that has not
+// arrived. It turns late again if still missing after the claim period.
+func (s *serialService) ClaimIssue(actor *models.Actor, id uint, req *models.ClaimIssueRequest) (*models.SerialIssue, error) {
+	issue, err := s.GetIssueByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !issue.Open() {
+		return nil, fmt.Errorf("issue is already %s", issue.Status)
+	}
+	now := time.Now()
+	if !issue.ExpectedAt.Before(now) {
+		return nil, errors.New("issue is not due yet")
+	}
+	claimAfter, err := s.claimAfterDays(issue)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(issue)
+	issue.Status = models.IssueClaimed
+	issue.ClaimCount++
+	issue.ClaimedAt = &now
+	issue.ClaimAt = now.AddDate(0, 0, claimAfter)
+	if req.Note != "" {
+		appendIssueNote(issue, fmt.Sprintf("Claim %d on %s: %s", issue.ClaimCount, now.Format("2006-01-02"), req.Note))
+	}
+
+	if err := s.serialRepo.UpdateIssue(issue); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial_issue.claim", "serial_issue", issue.ID, before, issue)
+
+	return issue, nil
+}
+
+// MarkIssueMissing gives up on an issue the vendor cannot supply.
+func (s *serialService) MarkIssueMissing(actor *models.Actor, id uint, req *models.MissingIssueRequest) (*models.SerialIssue, error) {
+	issue, err := s.GetIssueByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !issue.Open() {
+		return nil, fmt.Errorf("issue is already %s", issue.Status)
+	}
+
+	before := snapshot(issue)
+	issue.Status = models.IssueMissing
+	appendIssueNote(issue, req.Note)
+
+	if err := s.serialRepo.UpdateIssue(issue); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial_issue.missing", "serial_issue", issue.ID, before, issue)
+
+	return issue, nil
+}
+
+// SetLoanRules makes an issue borrowable for its own loan period, or
+// reference-only again. A loan in progress runs its course.
+func (s *serialService) SetLoanRules(actor *models.Actor, id uint, req *models.IssueLoanRulesRequest) (*models.SerialIssue, error) {
+	issue, err := s.GetIssueByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if issue.Status == models.IssueMissing {
+		return nil, errors.New("issue is missing")
+	}
+	if req.LoanDays < 0 {
+		return nil, errors.New("loan_days cannot be negative")
+	}
+
+	before := snapshot(issue)
+	issue.Borrowable = req.Borrowable
+	issue.LoanDays = req.LoanDays
+
+	if err := s.serialRepo.UpdateIssue(issue); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "serial_issue.loan_rules", "serial_issue", issue.ID, before, issue)
+
+	return issue, nil
+}
+
+// ProcessClaims marks issues that are past their claim date as late and
+// alerts the staff, one message per serial.
+func (s *serialService) ProcessClaims() error {
+	issues, err := s.serialRepo.GetIssuesToClaim(time.Now())
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+
+	var serials []*models.Serial
+	late := make(map[uint][]string)
+	for i := range issues {
+		issue := &issues[i]
+		before := snapshot(issue)
+		issue.Status = models.IssueLate
+		if err := s.serialRepo.UpdateIssue(issue); err != nil {
+			return err
+		}
+		s.auditService.Record(nil, "serial_issue.late", "serial_issue", issue.ID, before, issue)
+
+		if _, ok := late[issue.SerialID]; !ok && issue.Serial != nil {
+			serials = append(serials, issue.Serial)
+		}
+		late[issue.SerialID] = append(late[issue.SerialID],
+			fmt.Sprintf("%s, expected %s", issue.Label, issue.ExpectedAt.Format("2006-01-02")))
+	}
+
+	staff, _, err := s.userRepo.GetAll(&query.Spec{Filters: map[string]string{
+		"role":      "admin,librarian",
+		"is_active": "true",
+	}})
+	if err != nil {
+		return err
+	}
+	for _, serial := range serials {
+		labels := late[serial.ID]
+		subject := fmt.Sprintf("%s: %d issues to claim", serial.Title, len(labels))
+		if len(labels) == 1 {
+			subject = fmt.Sprintf("%s: an issue to claim", serial.Title)
+		}
+		message := "Not received yet: " + strings.Join(labels, "; ") + "."
+		for _, user := range staff {
+			s.notificationService.Notify(user.ID, subject, message, "serial", serial.ID)
+		}
+	}
+	return nil
+}
+
+func (s *serialService) claimAfterDays(issue *models.SerialIssue) (int, error) {
+	if issue.SubscriptionID == nil {
+		return defaultClaimAfterDays, nil
+	}
+	subscription, err := s.serialRepo.GetSubscription(*issue.SubscriptionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultClaimAfterDays, nil
+		}
+		return 0, err
+	}
+	return subscription.ClaimAfterDays, nil
+}
+
+// predictIssues lists the issues a subscription should bring, numbered
+// from volume and number on.
+func predictIssues(serialID uint, frequency models.SerialFrequency, subscription *models.SerialSubscription, volume, number int) []models.SerialIssue {
+	var issues []models.SerialIssue
+	for k := 0; ; k++ {
+		var expected time.Time
+		switch frequency {
+		case models.FrequencyWeekly:
+			expected = subscription.FirstIssueDate.AddDate(0, 0, 7*k)
+		case models.FrequencyQuarterly:
+			expected = addMonths(subscription.FirstIssueDate, 3*k)
+		default:
+			expected = addMonths(subscription.FirstIssueDate, k)
+		}
+		if expected.After(subscription.EndDate) {
+			return issues
+		}
+
+		issues = append(issues, models.SerialIssue{
+			SerialID:   serialID,
+			Volume:     volume,
+			Number:     number,
+			Label:      issueLabel(volume, number),
+			ExpectedAt: expected,
+			ClaimAt:    expected.AddDate(0, 0, subscription.ClaimAfterDays),
+			Status:     models.IssueExpected,
+		})
+		volume, number = nextNumbering(volume, number, subscription.IssuesPerVolume)
+	}
+}
+
+// nextNumbering returns the volume and number of the issue after the
+// given one.
+func nextNumbering(volume, number, issuesPerVolume int) (int, int) {
+	if issuesPerVolume > 0 && number >= issuesPerVolume {
+		return volume + 1, 1
+	}
+	return volume, number + 1
+}
+
+// addMonths moves a date by whole months, keeping it in the target month:
+// the 31st falls back to the last day of shorter months.
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+func issueLabel(volume, number int) string {
+	if volume > 0 {
+		return fmt.Sprintf("Vol. %d No. %d", volume, number)
+	}
+	return fmt.Sprintf("No. %d", number)
+}
+
+func appendIssueNote(issue *models.SerialIssue, note string) {
+	if note = strings.TrimSpace(note); note == "" {
+		return
+	}
+	if issue.Notes != "" {
+		issue.Notes += "\n"
+	}
+	issue.Notes += note
+}
+
+func validFrequency(frequency models.SerialFrequency) bool {
+	switch frequency {
+	case models.FrequencyWeekly, models.FrequencyMonthly, models.FrequencyQuarterly:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,686 @@
+package services
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/cql"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/marc"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/sru"
+)
+
+const (
+	sruDefaultRecords = 10
+	sruMaxRecords     = 100
+	sruDefaultTerms   = 20
+	sruMaxTerms       = 100
+)
+
+// Fields an SRU index searches
+const (
+	sruKeywords   = "keywords" // the full-text search index
+	sruAllRecords = "all"
+)
+
+type sruIndex struct {
+	set, name, title, field string
+}
+
+// Indexes we answer to, by context set
+var sruIndexes = []sruIndex{
+	{"cql", "serverChoice", "Any field", sruKeywords},
+	{"cql", "allRecords", "All records", sruAllRecords},
+	{"dc", "title", "Title", "title"},
+	{"dc", "creator", "Author", "author"},
+	{"dc", "subject", "Subject", "subject"},
+	{"dc", "publisher", "Publisher", "publisher"},
+	{"dc", "date", "Year of publication", "year"},
+	{"dc", "language", "Language", "language"},
+	{"dc", "identifier", "ISBN", "isbn"},
+	{"bath", "title", "Title", "title"},
+	{"bath", "author", "Author", "author"},
+	{"bath", "subject", "Subject", "subject"},
+	{"bath", "isbn", "ISBN", "isbn"},
+	{"rec", "identifier", "Record identifier", "id"},
+}
+
+var sruSets = []sru.ExplainSet{
+	{Name: "cql", Identifier: "info:srw/cql-context-set/1/cql-v1.2"},
+	{Name: "dc", Identifier: "info:srw/cql-context-set/1/dc-v1.1"},
+	{Name: "bath", Identifier: "http://zing.z3950.org/cql/bath/2.0/"},
+	{Name: "rec", Identifier: "info:srw/cql-context-set/2/rec-1.1"},
+}
+
+var sruSchemas = []sru.ExplainSchema{
+	{Name: "dc", Identifier: sru.SchemaDC, Title: "Dublin Core"},
+	{Name: "marcxml", Identifier: sru.SchemaMARCXML, Title: "MARCXML"},
+}
+
+// Sort keys, by index field, and the book list field they sort on
+var sruSortFields = map[string]string{
+	"title":  "title",
+	"author": "author",
+	"year":   "publish_year",
+	"id":     "id",
+}
+
+// Relation modifiers that do not change our matching
+var sruIgnoredModifiers = map[string]bool{
+	"relevant": true, "stem": true, "ignorecase": true, "ignoreaccents": true,
+	"masked": true, "word": true, "string": true,
+}
+
+type SRUService interface {
+	Explain(host string, port int, database string) (*sru.ExplainResponse, error)
+	SearchRetrieve(req *models.SRURequest) (*sru.SearchRetrieveResponse, error)
+	Scan(req *models.SRURequest) (*sru.ScanResponse, error)
+}
+
+type sruService struct {
+	bookRepo    repositories.BookRepository
+	bookService BookService
+	libraryName string
+}
+
+func NewSRUService(bookRepo repositories.BookRepository, bookService BookService, libraryName string) SRUService {
+	return &sruService{
+		bookRepo:    bookRepo,
+		bookService: bookService,
+		libraryName: libraryName,
+	}
+}
+
+func (s *sruService) Explain(host string, port int, database string) (*sru.ExplainResponse, error) {
+	explain := &sru.Explain{
+		Host:           host,
+		Port:           port,
+		Database:       database,
+		Title:          s.libraryName,
+		Description:    "Catalog of " + s.libraryName,
+		Sets:           sruSets,
+		Schemas:        sruSchemas,
+		DefaultRecords: sruDefaultRecords,
+		MaxRecords:     sruMaxRecords,
+	}
+	for _, index := range sruIndexes {
+		explain.Indexes = append(explain.Indexes, sru.ExplainIndex{Title: index.title, Set: index.set, Name: index.name})
+	}
+
+	data, err := explain.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return &sru.ExplainResponse{Record: &sru.Record{
+		Schema:  sru.ExplainNS,
+		Packing: sru.PackingXML,
+		Data:    data,
+	}}, nil
+}
+
+func (s *sruService) SearchRetrieve(req *models.SRURequest) (*sru.SearchRetrieveResponse, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, sru.NewDiagnostic(sru.DiagMissingParameter, "query", "Mandatory parameter not supplied")
+	}
+	schema, err := sruSchema(req.RecordSchema)
+	if err != nil {
+		return nil, err
+	}
+	packing, err := sruPacking(req.RecordPacking)
+	if err != nil {
+		return nil, err
+	}
+	start := req.StartRecord
+	if start == 0 {
+		start = 1
+	}
+	if start < 1 {
+		return nil, sru.NewDiagnostic(sru.DiagUnsupportedValue, "startRecord", "Unsupported parameter value")
+	}
+	maximum := sruDefaultRecords
+	if req.MaximumRecords != nil {
+		maximum = min(*req.MaximumRecords, sruMaxRecords)
+	}
+	if maximum < 0 {
+		return nil, sru.NewDiagnostic(sru.DiagUnsupportedValue, "maximumRecords", "Unsupported parameter value")
+	}
+
+	parsed, err := cql.Parse(req.Query)
+	if err != nil {
+		return nil, sru.NewDiagnostic(sru.DiagQuerySyntax, err.Error(), "Query syntax error")
+	}
+	result, err := s.evaluate(parsed.Root)
+	if err != nil {
+		return nil, err
+	}
+	ids := result.ids
+	if len(parsed.Sort) > 0 {
+		if ids, err = s.sortIDs(ids, parsed.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &sru.SearchRetrieveResponse{NumberOfRecords: int64(len(ids)), Query: req.Query}
+	if len(ids) == 0 || maximum == 0 {
+		return response, nil
+	}
+	if start > len(ids) {
+		response.Diagnostics = []*sru.Diagnostic{
+			sru.NewDiagnostic(sru.DiagStartOutOfRange, strconv.Itoa(start), "First record position out of range"),
+		}
+		return response, nil
+	}
+
+	end := min(start-1+maximum, len(ids))
+	pageIDs := ids[start-1 : end]
+	books, err := s.bookRepo.GetCatalogByIDs(pageIDs)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]*models.Book, len(books))
+	for i := range books {
+		byID[books[i].ID] = &books[i]
+	}
+
+	for i, id := range pageIDs {
+		book, ok := byID[id]
+		if !ok {
+			continue
+		}
+		data, err := sruRecordData(book, schema)
+		if err != nil {
+			return nil, err
+		}
+		response.Records = append(response.Records, sru.Record{
+			Schema:   schema,
+			Packing:  packing,
+			Data:     data,
+			Position: start + i,
+		})
+	}
+	if end < len(ids) {
+		response.NextRecordPosition = end + 1
+	}
+	return response, nil
+}
+
+// Scan browses the values of one index around a term, e.g.
+// scanClause=dc.title=bumi.
+func (s *sruService) Scan(req *models.SRURequest) (*sru.ScanResponse, error) {
+	if strings.TrimSpace(req.ScanClause) == "" {
+		return nil, sru.NewDiagnostic(sru.DiagMissingParameter, "scanClause", "Mandatory parameter not supplied")
+	}
+	maximum := req.MaximumTerms
+	if maximum == 0 {
+		maximum = sruDefaultTerms
+	}
+	if maximum < 0 {
+		return nil, sru.NewDiagnostic(sru.DiagUnsupportedValue, "maximumTerms", "Unsupported parameter value")
+	}
+	maximum = min(maximum, sruMaxTerms)
+	position := 1
+	if req.ResponsePosition != nil {
+		position = *req.ResponsePosition
+	}
+	if position < 0 || position > maximum+1 {
+		return nil, sru.NewDiagnostic(sru.DiagScanPositionOutOfRange, strconv.Itoa(position), "Response position out of range")
+	}
+
+	parsed, err := cql.Parse(req.ScanClause)
+	if err != nil {
+		return nil, sru.NewDiagnostic(sru.DiagQuerySyntax, err.Error(), "Query syntax error")
+	}
+	clause, ok := parsed.Root.(*cql.Clause)
+	if !ok {
+		return nil, sru.NewDiagnostic(sru.DiagQuerySyntax, "scanClause must be a single search clause", "Query syntax error")
+	}
+	index, err := sruLookupIndex(clause.Index)
+	if err != nil {
+		return nil, err
+	}
+	field := index.field
+	// Scanning any field browses titles
+	if field == sruKeywords {
+		field = "title"
+	}
+	if field == sruAllRecords {
+		return nil, sru.NewDiagnostic(sru.DiagUnsupportedIndex, clause.Index, "Unsupported index")
+	}
+	term := clause.Term
+	if field == "language" {
+		term = sruLanguage(term)
+	}
+
+	var terms []models.FacetCount
+	if before := position - 1; before > 0 {
+		previous, err := s.bookRepo.ScanTerms(field, term, before, true)
+		if err != nil {
+			return nil, err
+		}
+		for i := len(previous) - 1; i >= 0; i-- {
+			terms = append(terms, previous[i])
+		}
+	}
+	if after := maximum - len(terms); after > 0 {
+		next, err := s.bookRepo.ScanTerms(field, term, after, false)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, next...)
+	}
+
+	response := &sru.ScanResponse{}
+	for _, t := range terms {
+		response.Terms = append(response.Terms, sru.ScanTerm{Value: t.Value, NumberOfRecords: t.Count, DisplayTerm: t.Value})
+	}
+	return response, nil
+}
+
+// sruResult is the set of books a query matches. Ranked results keep the
+// search index order; the others are in id order.
+type sruResult struct {
+	ids    []uint
+	ranked bool
+}
+
+func (s *sruService) evaluate(node cql.Node) (*sruResult, error) {
+	switch n := node.(type) {
+	case *cql.Clause:
+		return s.evaluateClause(n)
+	case *cql.Boolean:
+		if n.Op == "prox" {
+			return nil, sru.NewDiagnostic(sru.DiagUnsupportedBoolean, n.Op, "Unsupported boolean operator")
+		}
+		if len(n.Modifiers) > 0 {
+			return nil, sru.NewDiagnostic(sru.DiagUnsupportedModifier, n.Modifiers[0].Name, "Unsupported boolean modifier")
+		}
+		left, err := s.evaluate(n.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := s.evaluate(n.Right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "and":
+			// Keep relevance order from whichever side has it
+			if !left.ranked && right.ranked {
+				return &sruResult{ids: intersectIDs(right.ids, left.ids), ranked: true}, nil
+			}
+			return &sruResult{ids: intersectIDs(left.ids, right.ids), ranked: left.ranked}, nil
+		case "or":
+			return &sruResult{ids: unionIDs(left.ids, right.ids), ranked: left.ranked || right.ranked}, nil
+		case "not":
+			return &sruResult{ids: subtractIDs(left.ids, right.ids), ranked: left.ranked}, nil
+		}
+		return nil, sru.NewDiagnostic(sru.DiagUnsupportedBoolean, n.Op, "Unsupported boolean operator")
+	}
+	return nil, errors.New("unknown query node")
+}
+
+func (s *sruService) evaluateClause(clause *cql.Clause) (*sruResult, error) {
+	index, err := sruLookupIndex(clause.Index)
+	if err != nil {
+		return nil, err
+	}
+	for _, modifier := range clause.Modifiers {
+		if !sruIgnoredModifiers[strings.TrimPrefix(modifier.Name, "cql.")] {
+			return nil, sru.NewDiagnostic(sru.DiagUnsupportedModifier, modifier.Name, "Unsupported relation modifier")
+		}
+	}
+	relation := strings.TrimPrefix(clause.Relation, "cql.")
+	unsupported := sru.NewDiagnostic(sru.DiagUnsupportedRelation, clause.Relation, "Unsupported relation")
+
+	switch index.field {
+	case sruAllRecords:
+		ids, err := s.bookRepo.GetActiveIDs()
+		return &sruResult{ids: ids}, err
+
+	case sruKeywords:
+		switch relation {
+		case "=", "adj", "any":
+			ids, err := s.bookService.SearchBookIDs(unmask(clause.Term))
+			return &sruResult{ids: ids, ranked: true}, err
+		case "all":
+			ids, err := s.bookService.SearchBookIDs(unmask(clause.Term))
+			if err != nil {
+				return nil, err
+			}
+			for _, word := range strings.Fields(clause.Term) {
+				matches, err := s.bookService.SearchBookIDs(unmask(word))
+				if err != nil {
+					return nil, err
+				}
+				ids = intersectIDs(ids, matches)
+			}
+			return &sruResult{ids: ids, ranked: true}, nil
+		}
+		return nil, unsupported
+
+	case "year", "id":
+		return s.matchNumber(index.field, relation, clause.Term, unsupported)
+
+	case "isbn", "language":
+		if relation != "=" && relation != "==" && relation != "exact" && relation != "any" {
+			return nil, unsupported
+		}
+		var ids []uint
+		for _, value := range strings.Fields(clause.Term) {
+			if index.field == "isbn" {
+				if canonical, err := isbn.Normalize(value); err == nil {
+					value = canonical
+				}
+			} else {
+				value = sruLanguage(value)
+			}
+			matches, err := s.bookRepo.MatchIDs(index.field, "like", likePattern(value, false))
+			if err != nil {
+				return nil, err
+			}
+			ids = unionIDs(ids, matches)
+			// Only any takes several values
+			if relation != "any" {
+				break
+			}
+		}
+		return &sruResult{ids: ids}, nil
+	}
+
+	// Text fields
+	switch relation {
+	case "=", "adj":
+		ids, err := s.bookRepo.MatchIDs(index.field, "like", likePattern(clause.Term, true))
+		return &sruResult{ids: ids}, err
+	case "==", "exact":
+		ids, err := s.bookRepo.MatchIDs(index.field, "like", likePattern(clause.Term, false))
+		return &sruResult{ids: ids}, err
+	case "all", "any":
+		var ids []uint
+		for i, word := range strings.Fields(clause.Term) {
+			matches, err := s.bookRepo.MatchIDs(index.field, "like", likePattern(word, true))
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case i == 0:
+				ids = matches
+			case relation == "all":
+				ids = intersectIDs(ids, matches)
+			default:
+				ids = unionIDs(ids, matches)
+			}
+		}
+		return &sruResult{ids: ids}, nil
+	case "<", "<=", ">", ">=", "<>":
+		ids, err := s.bookRepo.MatchIDs(index.field, relation, unmask(clause.Term))
+		return &sruResult{ids: ids}, err
+	}
+	return nil, unsupported
+}
+
+// matchNumber compares a numeric field, where within takes two bounds,
+// e.g. dc.date within "1990 1999".
+func (s *sruService) matchNumber(field, relation, term string, unsupported *sru.Diagnostic) (*sruResult, error) {
+	values := strings.Fields(term)
+	for _, value := range values {
+		if _, err := strconv.Atoi(value); err != nil {
+			return nil, sru.NewDiagnostic(sru.DiagInvalidTerm, term, "Query term is not a number")
+		}
+	}
+	if len(values) == 0 {
+		return nil, sru.NewDiagnostic(sru.DiagInvalidTerm, term, "Query term is not a number")
+	}
+
+	switch relation {
+	case "=", "==", "exact", "<", "<=", ">", ">=", "<>":
+		if len(values) != 1 {
+			return nil, sru.NewDiagnostic(sru.DiagInvalidTerm, term, "Query term must be one number")
+		}
+		op := relation
+		if op == "==" || op == "exact" {
+			op = "="
+		}
+		ids, err := s.bookRepo.MatchIDs(field, op, values[0])
+		return &sruResult{ids: ids}, err
+	case "within":
+		if len(values) != 2 {
+			return nil, sru.NewDiagnostic(sru.DiagInvalidTerm, term, "within needs two numbers")
+		}
+		from, err := s.bookRepo.MatchIDs(field, ">=", values[0])
+		if err != nil {
+			return nil, err
+		}
+		to, err := s.bookRepo.MatchIDs(field, "<=", values[1])
+		return &sruResult{ids: intersectIDs(from, to)}, err
+	case "any":
+		var ids []uint
+		for _, value := range values {
+			matches, err := s.bookRepo.MatchIDs(field, "=", value)
+			if err != nil {
+				return nil, err
+			}
+			ids = unionIDs(ids, matches)
+		}
+		return &sruResult{ids: ids}, nil
+	}
+	return nil, unsupported
+}
+
+// sortIDs orders ids by the sortBy keys of the query.
+func (s *sruService) sortIDs(ids []uint, keys []cql.SortKey) ([]uint, error) {
+	spec := &query.Spec{Page: 1, Filters: map[string]string{}}
+	for _, key := range keys {
+		index, err := sruLookupIndex(key.Index)
+		if err != nil {
+			return nil, err
+		}
+		field, ok := sruSortFields[index.field]
+		if !ok {
+			return nil, sru.NewDiagnostic(sru.DiagUnsupportedIndex, key.Index, "Cannot sort by this index")
+		}
+		spec.Sort = append(spec.Sort, query.SortField{Field: field, Desc: cql.HasModifier(key.Modifiers, "descending")})
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	books, _, err := s.bookRepo.GetByIDs(ids, spec)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]uint, len(books))
+	for i := range books {
+		sorted[i] = books[i].ID
+	}
+	return sorted, nil
+}
+
+// sruLookupIndex resolves an index name. Names without a context set are
+// read as Dublin Core.
+func sruLookupIndex(name string) (*sruIndex, error) {
+	set, short, found := strings.Cut(name, ".")
+	if !found {
+		set, short = "dc", name
+	}
+	for i, index := range sruIndexes {
+		if strings.EqualFold(index.set, set) && strings.EqualFold(index.name, short) {
+			return &sruIndexes[i], nil
+		}
+	}
+	return nil, sru.NewDiagnostic(sru.DiagUnsupportedIndex, name, "Unsupported index")
+}
+
+func sruSchema(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", "dc", sru.SchemaDC:
+		return sru.SchemaDC, nil
+	case "marcxml", "marc21", sru.SchemaMARCXML:
+		return sru.SchemaMARCXML, nil
+	}
+	return "", sru.NewDiagnostic(sru.DiagUnknownSchema, name, "Unknown schema for retrieval")
+}
+
+func sruPacking(packing string) (string, error) {
+	switch packing {
+	case "", sru.PackingXML:
+		return sru.PackingXML, nil
+	case sru.PackingString:
+		return sru.PackingString, nil
+	}
+	return "", sru.NewDiagnostic(sru.DiagUnsupportedPacking, packing, "Unsupported record packing")
+}
+
+// sruLanguage turns an ISO 639 code into the language name we store.
+func sruLanguage(value string) string {
+	lower := strings.ToLower(value)
+	for name, code := range marcLanguageCodes {
+		if code == lower || opdsLanguageCodes[name] == lower {
+			return name
+		}
+	}
+	return value
+}
+
+func sruRecordData(book *models.Book, schema string) ([]byte, error) {
+	if schema == sru.SchemaMARCXML {
+		return sru.EncodeElement(bookToRecord(book), "record", marc.Namespace)
+	}
+	return dublinCore(book).Encode()
+}
+
+// dublinCore maps a book to simple Dublin Core. Authors are creators and
+// other contributors are contributors.
+func dublinCore(book *models.Book) *sru.DublinCore {
+	dc := &sru.DublinCore{
+		Title:       book.Title,
+		Description: book.Description,
+		Publisher:   book.Publisher,
+		Type:        "Text",
+		Identifiers: []string{"urn:isbn:" + book.ISBN},
+		Language:    book.Language,
+	}
+	if code, ok := marcLanguageCodes[book.Language]; ok {
+		dc.Language = code
+	}
+	if book.PublishYear > 0 {
+		dc.Date = strconv.Itoa(book.PublishYear)
+	}
+	for _, contributor := range book.Contributors {
+		if contributor.Author == nil {
+			continue
+		}
+		if contributor.Role == models.RoleAuthor {
+			dc.Creators = append(dc.Creators, contributor.Author.Name)
+		} else {
+			dc.Contributor = append(dc.Contributor, contributor.Author.Name)
+		}
+	}
+	if len(dc.Creators) == 0 && len(dc.Contributor) == 0 {
+		dc.Creators = splitNames(book.Author)
+	}
+	if book.Category != "" {
+		dc.Subjects = append(dc.Subjects, book.Category)
+	}
+	for _, subject := range book.Subjects {
+		if subject.Subject != nil {
+			dc.Subjects = append(dc.Subjects, subject.Subject.Heading)
+		}
+	}
+	return dc
+}
+
+// likePattern turns a CQL term into a LIKE pattern: * and ? are masks, a
+// backslash makes the next character literal, and ^ anchors are dropped.
+// A contains pattern matches the term anywhere in the value.
+func likePattern(term string, contains bool) string {
+	var b strings.Builder
+	if contains {
+		b.WriteByte('%')
+	}
+	runes := []rune(term)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				writeLikeLiteral(&b, runes[i])
+			}
+		case '*':
+			b.WriteByte('%')
+		case '?':
+			b.WriteByte('_')
+		case '^':
+		default:
+			writeLikeLiteral(&b, r)
+		}
+	}
+	if contains {
+		b.WriteByte('%')
+	}
+	return b.String()
+}
+
+func writeLikeLiteral(b *strings.Builder, r rune) {
+	if r == '%' || r == '_' || r == '\\' {
+		b.WriteByte('\\')
+	}
+	b.WriteRune(r)
+}
+
+// unmask drops the CQL masking characters from a term for the full-text
+// index, which matches words on its own.
+func unmask(term string) string {
+	return strings.NewReplacer(`\`, "", "*", "", "?", "", "^", "").Replace(term)
+}
+
+// intersectIDs keeps the ids of a that are also in b, in the order of a.
+func intersectIDs(a, b []uint) []uint {
+	in := make(map[uint]bool, len(b))
+	for _, id := range b {
+		in[id] = true
+	}
+	result := []uint{}
+	for _, id := range a {
+		if in[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// unionIDs lists a, then the ids of b that are not in a.
+func unionIDs(a, b []uint) []uint {
+	in := make(map[uint]bool, len(a))
+	result := make([]uint, 0, len(a)+len(b))
+	for _, id := range a {
+		in[id] = true
+		result = append(result, id)
+	}
+	for _, id := range b {
+		if !in[id] {
+			in[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// subtractIDs keeps the ids of a that are not in b.
+func subtractIDs(a, b []uint) []uint {
+	in := make(map[uint]bool, len(b))
+	for _, id := range b {
+		in[id] = true
+	}
+	result := []uint{}
+	for _, id := range a {
+		if !in[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,614 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type StocktakeService interface {
+	CreateStocktake(actor *models.Actor, req *models.CreateStocktakeRequest) (*models.Stocktake, error)
+	GetAllStocktakes(spec *query.Spec) ([]models.Stocktake, *query.Page, error)
+	GetStocktakeByID(id uint) (*models.Stocktake, error)
+	CloseStocktake(actor *models.Actor, id uint) (*models.Stocktake, error)
+	RecordScans(actor *models.Actor, id uint, req *models.ScanRequest) (*models.ScanResult, error)
+	GetScans(id uint, spec *query.Spec) ([]models.StocktakeScan, *query.Page, error)
+	DeleteScan(id, scanID uint) error
+	Reconcile(id uint) (*models.StocktakeReport, error)
+	MarkMissing(actor *models.Actor, id uint, req *models.MarkMissingRequest) (*models.StocktakeReport, error)
+	CorrectLocations(actor *models.Actor, id uint, req *models.CorrectLocationsRequest) (*models.StocktakeReport, error)
+}
+
+type stocktakeService struct {
+	stocktakeRepo repositories.StocktakeRepository
+	bookRepo      repositories.BookRepository
+	borrowRepo    repositories.BorrowRepository
+	auditService  AuditService
+}
+
+func NewStocktakeService(
+	stocktakeRepo repositories.StocktakeRepository,
+	bookRepo repositories.BookRepository,
+	borrowRepo repositories.BorrowRepository,
+	auditService AuditService,
+) StocktakeService {
+	return &stocktakeService{
+		stocktakeRepo: stocktakeRepo,
+		bookRepo:      bookRepo,
+		borrowRepo:    borrowRepo,
+		auditService:  auditService,
+	}
+}
+
+// Most codes accepted in one scan request
+const maxScansPerRequest = 1000
+
+func (s *stocktakeService) CreateStocktake(actor *models.Actor, req *models.CreateStocktakeRequest) (*models.Stocktake, error) {
+	from := strings.TrimSpace(req.LocationFrom)
+	to := strings.TrimSpace(req.LocationTo)
+	if from == "" {
+		return nil, errors.New("location_from is required")
+	}
+	if to == "" {
+		to = from
+	}
+	if strings.ToUpper(to) < strings.ToUpper(from) {
+		return nil, errors.New("location_to cannot sort before location_from")
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = from
+		if to != from {
+			name = from + " - " + to
+		}
+	}
+
+	stocktake := &models.Stocktake{
+		Name:         truncate(name, 100),
+		LocationFrom: truncate(from, 50),
+		LocationTo:   truncate(to, 50),
+		Status:       models.StocktakeOpen,
+		Notes:        req.Notes,
+	}
+	if actor != nil && actor.UserID != 0 {
+		creator := actor.UserID
+		stocktake.CreatedBy = &creator
+	}
+
+	if err := s.stocktakeRepo.Create(stocktake); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "stocktake.create", "stocktake", stocktake.ID, nil, stocktake)
+
+	return stocktake, nil
+}
+
+func (s *stocktakeService) GetAllStocktakes(spec *query.Spec) ([]models.Stocktake, *query.Page, error) {
+	return s.stocktakeRepo.GetAll(spec)
+}
+
+func (s *stocktakeService) GetStocktakeByID(id uint) (*models.Stocktake, error) {
+	stocktake, err := s.stocktakeRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("stocktake not found")
+		}
+		return nil, err
+	}
+
+	count, err := s.stocktakeRepo.CountScans(id)
+	if err != nil {
+		return nil, err
+	}
+	stocktake.ScanCount = int(count)
+
+	return stocktake, nil
+}
+
+// CloseStocktake stops taking scans and records how many copies of each
+// book the catalog expects on the shelves, which the stocktake is
+// reconciled against from then on. Missing copies can only be written off
+// once every shelf has been scanned.
+func (s *stocktakeService) CloseStocktake(actor *models.Actor, id uint) (*models.Stocktake, error) {
+	stocktake, err := s.GetStocktakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if stocktake.Status != models.StocktakeOpen {
+		return nil, errors.New("stocktake is already closed")
+	}
+
+	shelved, err := s.bookRepo.GetShelvedBetween(stocktake.LocationFrom, stocktake.LocationTo)
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]models.StocktakeCount, len(shelved))
+	for i, book := range shelved {
+		counts[i] = models.StocktakeCount{BookID: book.ID, Stock: book.Stock, Expected: book.Available}
+	}
+
+	before := snapshot(stocktake)
+	if err := s.stocktakeRepo.Close(stocktake, counts); err != nil {
+		if errors.Is(err, repositories.ErrStocktakeClosed) {
+			return nil, errors.New("stocktake is already closed")
+		}
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "stocktake.close", "stocktake", stocktake.ID, before, stocktake)
+
+	return stocktake, nil
+}
+
+// RecordScans stores scanned codes, one per copy. Codes are matched to
+// active print books by ISBN, including EAN-13 bar codes with an add-on;
+// anything else is kept as an unknown code.
+func (s *stocktakeService) RecordScans(actor *models.Actor, id uint, req *models.ScanRequest) (*models.ScanResult, error) {
+	stocktake, err := s.GetStocktakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if stocktake.Status != models.StocktakeOpen {
+		return nil, errors.New("stocktake is closed")
+	}
+
+	var codes []string
+	for _, code := range req.Codes {
+		if code = strings.TrimSpace(code); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return nil, errors.New("codes are required")
+	}
+	if len(codes) > maxScansPerRequest {
+		return nil, fmt.Errorf("at most %d codes can be sent at once", maxScansPerRequest)
+	}
+
+	canonical := make([]string, len(codes))
+	var lookup []string
+	for i, code := range codes {
+		if normalized, err := isbn.Normalize(code); err == nil {
+			canonical[i] = normalized
+			lookup = append(lookup, normalized)
+		}
+	}
+	byISBN := make(map[string]uint)
+	if len(lookup) > 0 {
+		books, err := s.bookRepo.GetShelvedByISBNs(lookup)
+		if err != nil {
+			return nil, err
+		}
+		for _, book := range books {
+			byISBN[book.ISBN] = book.ID
+		}
+	}
+
+	var scannedBy *uint
+	if actor != nil && actor.UserID != 0 {
+		userID := actor.UserID
+		scannedBy = &userID
+	}
+	location := truncate(strings.TrimSpace(req.Location), 50)
+
+	result := &models.ScanResult{Unknown: []string{}}
+	scans := make([]models.StocktakeScan, len(codes))
+	for i, code := range codes {
+		scans[i] = models.StocktakeScan{
+			StocktakeID: stocktake.ID,
+			Code:        truncate(code, 40),
+			Location:    location,
+			ScannedBy:   scannedBy,
+		}
+		if bookID, ok := byISBN[canonical[i]]; ok {
+			scans[i].BookID = &bookID
+			result.Matched++
+		} else {
+			result.Unknown = append(result.Unknown, code)
+		}
+	}
+
+	if err := s.stocktakeRepo.AddScans(scans); err != nil {
+		return nil, err
+	}
+	result.Recorded = len(scans)
+
+	return result, nil
+}
+
+func (s *stocktakeService) GetScans(id uint, spec *query.Spec) ([]models.StocktakeScan, *query.Page, error) {
+	if _, err := s.GetStocktakeByID(id); err != nil {
+		return nil, nil, err
+	}
+	return s.stocktakeRepo.GetScans(id, spec)
+}
+
+// DeleteScan takes back a scan, e.g. a copy scanned twice.
+func (s *stocktakeService) DeleteScan(id, scanID uint) error {
+	stocktake, err := s.GetStocktakeByID(id)
+	if err != nil {
+		return err
+	}
+	if stocktake.Status != models.StocktakeOpen {
+		return errors.New("stocktake is closed")
+	}
+
+	if _, err := s.stocktakeRepo.GetScan(id, scanID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("scan not found")
+		}
+		return err
+	}
+	return s.stocktakeRepo.DeleteScan(scanID)
+}
+
+func (s *stocktakeService) Reconcile(id uint) (*models.StocktakeReport, error) {
+	stocktake, err := s.GetStocktakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	report, _, err := s.reconcile(stocktake)
+	return report, err
+}
+
+// MarkMissing writes the missing copies of books off their stock.
+func (s *stocktakeService) MarkMissing(actor *models.Actor, id uint, req *models.MarkMissingRequest) (*models.StocktakeReport, error) {
+	stocktake, err := s.GetStocktakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if stocktake.Status == models.StocktakeOpen {
+		return nil, errors.New("close the stocktake before marking copies missing")
+	}
+
+	counts, err := s.stocktakeRepo.GetCounts(stocktake.ID)
+	if err != nil {
+		return nil, err
+	}
+	report, books, err := s.reconcileCounts(stocktake, counts)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := pickLines(report.Missing, req.BookIDs, "has no missing copies")
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, errors.New("no copies are missing")
+	}
+
+	byBook := make(map[uint]*models.StocktakeCount, len(counts))
+	for i := range counts {
+		byBook[counts[i].BookID] = &counts[i]
+	}
+	for _, line := range lines {
+		book := books[line.BookID]
+		before := snapshot(book)
+		if err := s.stocktakeRepo.WriteOff(byBook[line.BookID], line.Count); err != nil {
+			switch {
+			case errors.Is(err, repositories.ErrCountChanged):
+				return nil, fmt.Errorf("missing copies of book %d were written off meanwhile", book.ID)
+			case errors.Is(err, repositories.ErrNoCopy):
+				return nil, fmt.Errorf("book %d has fewer than %d copies on the shelf now", book.ID, line.Count)
+			}
+			return nil, err
+		}
+		book.Stock -= line.Count
+		book.Available -= line.Count
+		s.auditService.Record(actor, "book.mark_missing", "book", book.ID, before, book)
+	}
+
+	report, _, err = s.reconcile(stocktake)
+	return report, err
+}
+
+// CorrectLocations moves the catalog location of misplaced books, by
+// default to where they were scanned.
+func (s *stocktakeService) CorrectLocations(actor *models.Actor, id uint, req *models.CorrectLocationsRequest) (*models.StocktakeReport, error) {
+	stocktake, err := s.GetStocktakeByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, books, err := s.reconcile(stocktake)
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make(map[uint]string)
+	var order []uint
+	if len(req.Items) == 0 {
+		for _, line := range report.Misplaced {
+			if len(line.ScannedLocations) == 1 {
+				locations[line.BookID] = line.ScannedLocations[0]
+				order = append(order, line.BookID)
+			}
+		}
+		if len(order) == 0 {
+			return nil, errors.New("no misplaced book was scanned at a single location")
+		}
+	} else {
+		ids := make([]uint, len(req.Items))
+		for i, item := range req.Items {
+			ids[i] = item.BookID
+		}
+		lines, err := pickLines(report.Misplaced, ids, "is not misplaced")
+		if err != nil {
+			return nil, err
+		}
+		byBook := make(map[uint]models.StocktakeLine)
+		for _, line := range lines {
+			byBook[line.BookID] = line
+		}
+		for _, item := range req.Items {
+			if _, seen := locations[item.BookID]; seen {
+				continue
+			}
+			location := strings.TrimSpace(item.Location)
+			if line := byBook[item.BookID]; location == "" && len(line.ScannedLocations) == 1 {
+				location = line.ScannedLocations[0]
+			}
+			if location == "" {
+				return nil, fmt.Errorf("location is required for book %d", item.BookID)
+			}
+			locations[item.BookID] = truncate(location, 50)
+			order = append(order, item.BookID)
+		}
+	}
+
+	for _, bookID := range order {
+		book := books[bookID]
+		before := snapshot(book)
+		book.Location = locations[bookID]
+		if err := s.bookRepo.UpdateLocation(book.ID, book.Location); err != nil {
+			return nil, err
+		}
+		s.auditService.Record(actor, "book.relocate", "book", book.ID, before, book)
+	}
+
+	report, _, err = s.reconcile(stocktake)
+	return report, err
+}
+
+// scanTally sums the scans of one book.
+type scanTally struct {
+	found     int
+	misplaced int
+	locations []string
+}
+
+// reconcile compares the scans of a stocktake with the copies the catalog
+// expects on its shelves, those not on loan or set aside. It also returns
+// the books the report mentions.
+func (s *stocktakeService) reconcile(stocktake *models.Stocktake) (*models.StocktakeReport, map[uint]*models.Book, error) {
+	var counts []models.StocktakeCount
+	if stocktake.Status != models.StocktakeOpen {
+		var err error
+		if counts, err = s.stocktakeRepo.GetCounts(stocktake.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+	return s.reconcileCounts(stocktake, counts)
+}
+
+// reconcileCounts reconciles an open stocktake with the catalog as it is
+// now, and a closed one with the given counts taken at closing.
+func (s *stocktakeService) reconcileCounts(stocktake *models.Stocktake, counts []models.StocktakeCount) (*models.StocktakeReport, map[uint]*models.Book, error) {
+	var shelved []models.Book
+	var err error
+	expected := make(map[uint]models.StocktakeCount)
+	if stocktake.Status == models.StocktakeOpen {
+		if shelved, err = s.bookRepo.GetShelvedBetween(stocktake.LocationFrom, stocktake.LocationTo); err != nil {
+			return nil, nil, err
+		}
+		for _, book := range shelved {
+			expected[book.ID] = models.StocktakeCount{BookID: book.ID, Stock: book.Stock, Expected: book.Available}
+		}
+	} else if len(counts) > 0 {
+		ids := make([]uint, len(counts))
+		for i, count := range counts {
+			ids[i] = count.BookID
+			expected[count.BookID] = count
+		}
+		if shelved, err = s.bookRepo.GetShelvedByIDs(ids); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	scans, err := s.stocktakeRepo.GetAllScans(stocktake.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	report := &models.StocktakeReport{
+		Stocktake:       stocktake,
+		Scanned:         len(scans),
+		Missing:         []models.StocktakeLine{},
+		Misplaced:       []models.StocktakeLine{},
+		CheckedOutFound: []models.StocktakeLine{},
+		Surplus:         []models.StocktakeLine{},
+		Unknown:         []models.UnknownCode{},
+	}
+
+	books := make(map[uint]*models.Book, len(shelved))
+	for i := range shelved {
+		books[shelved[i].ID] = &shelved[i]
+		report.Expected += expected[shelved[i].ID].Expected
+	}
+
+	tallies := make(map[uint]*scanTally)
+	var elsewhere []uint
+	unknown := make(map[string]*models.UnknownCode)
+	var unknownOrder []string
+	for _, scan := range scans {
+		if scan.BookID == nil {
+			code, ok := unknown[scan.Code]
+			if !ok {
+				code = &models.UnknownCode{Code: scan.Code}
+				unknown[scan.Code] = code
+				unknownOrder = append(unknownOrder, scan.Code)
+			}
+			code.Count++
+			code.Locations = addLocation(code.Locations, scan.Location)
+			continue
+		}
+
+		tally, ok := tallies[*scan.BookID]
+		if !ok {
+			tally = &scanTally{}
+			tallies[*scan.BookID] = tally
+			if books[*scan.BookID] == nil {
+				elsewhere = append(elsewhere, *scan.BookID)
+			}
+		}
+		tally.found++
+		book := books[*scan.BookID]
+		switch {
+		case book == nil:
+			tally.locations = addLocation(tally.locations, scan.Location)
+		case scan.Location != "" && !strings.EqualFold(scan.Location, book.Location):
+			// On a shelf of the stocktake, but not its own
+			tally.misplaced++
+			tally.locations = addLocation(tally.locations, scan.Location)
+		}
+	}
+
+	for i := range shelved {
+		book := &shelved[i]
+		count := expected[book.ID]
+		tally := tallies[book.ID]
+		if tally == nil {
+			tally = &scanTally{}
+		}
+
+		if missing := count.Expected - tally.found - count.WrittenOff; missing > 0 {
+			report.Missing = append(report.Missing, stocktakeLine(book, count, tally, missing))
+		}
+		if tally.misplaced > 0 {
+			report.Misplaced = append(report.Misplaced, stocktakeLine(book, count, tally, tally.misplaced))
+		}
+		if extra := tally.found - count.Expected; extra > 0 {
+			var loans []uint
+			if count.Stock > count.Expected {
+				if loans, err = s.openLoans(book.ID); err != nil {
+					return nil, nil, err
+				}
+			}
+			onLoan := extra
+			if len(loans) < onLoan {
+				onLoan = len(loans)
+			}
+			if onLoan > 0 {
+				line := stocktakeLine(book, count, tally, onLoan)
+				line.Loans = loans
+				report.CheckedOutFound = append(report.CheckedOutFound, line)
+			}
+			if extra > onLoan {
+				report.Surplus = append(report.Surplus, stocktakeLine(book, count, tally, extra-onLoan))
+			}
+		}
+	}
+
+	// Books scanned here that belong on shelves outside the stocktake
+	if len(elsewhere) > 0 {
+		others, _, err := s.bookRepo.GetByIDs(elsewhere, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		sort.Slice(others, func(i, j int) bool {
+			if others[i].Location != others[j].Location {
+				return others[i].Location < others[j].Location
+			}
+			return others[i].ID < others[j].ID
+		})
+		for i := range others {
+			book := &others[i]
+			tally := tallies[book.ID]
+			books[book.ID] = book
+			line := stocktakeLine(book, models.StocktakeCount{}, tally, tally.found)
+			report.Misplaced = append(report.Misplaced, line)
+		}
+	}
+
+	for _, code := range unknownOrder {
+		report.Unknown = append(report.Unknown, *unknown[code])
+	}
+
+	return report, books, nil
+}
+
+// openLoans returns the IDs of the loans of a book not returned yet.
+func (s *stocktakeService) openLoans(bookID uint) ([]uint, error) {
+	borrows, _, err := s.borrowRepo.GetByBookID(bookID, &query.Spec{Filters: map[string]string{
+		"status": string(models.StatusBorrowed) + "," + string(models.StatusOverdue),
+	}})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(borrows))
+	for i, borrow := range borrows {
+		ids[i] = borrow.ID
+	}
+	return ids, nil
+}
+
+func stocktakeLine(book *models.Book, expected models.StocktakeCount, tally *scanTally, count int) models.StocktakeLine {
+	return models.StocktakeLine{
+		BookID:           book.ID,
+		Title:            book.Title,
+		ISBN:             book.ISBN,
+		CallNumber:       book.CallNumber,
+		Location:         book.Location,
+		ScannedLocations: tally.locations,
+		Expected:         expected.Expected,
+		Found:            tally.found,
+		Count:            count,
+	}
+}
+
+// pickLines returns the lines of the given books, or all lines when no
+// books are given. A book without a line fails with the given reason.
+func pickLines(lines []models.StocktakeLine, bookIDs []uint, reason string) ([]models.StocktakeLine, error) {
+	if len(bookIDs) == 0 {
+		return lines, nil
+	}
+	byBook := make(map[uint]models.StocktakeLine, len(lines))
+	for _, line := range lines {
+		byBook[line.BookID] = line
+	}
+
+	var picked []models.StocktakeLine
+	seen := make(map[uint]bool)
+	for _, id := range bookIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		line, ok := byBook[id]
+		if !ok {
+			return nil, fmt.Errorf("book %d %s", id, reason)
+		}
+		picked = append(picked, line)
+	}
+	return picked, nil
+}
+
+func addLocation(locations []string, location string) []string {
+	if location == "" {
+		return locations
+	}
+	for _, existing := range locations {
+		if strings.EqualFold(existing, location) {
+			return locations
+		}
+	}
+	return append(locations, location)
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// shelfBookRepository serves the book lookups a stocktake makes from a
+// map. Other methods are not implemented.
+type shelfBookRepository struct {
+	repositories.BookRepository
+	books map[uint]*models.Book
+}
+
+func (r *shelfBookRepository) shelved(keep func(*models.Book) bool) []models.Book {
+	var books []models.Book
+	for _, book := range r.books {
+		if book.IsActive && keep(book) {
+			books = append(books, *book)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool {
+		if books[i].Location != books[j].Location {
+			return books[i].Location < books[j].Location
+		}
+		return books[i].ID < books[j].ID
+	})
+	return books
+}
+
+func (r *shelfBookRepository) GetShelvedBetween(from, to string) ([]models.Book, error) {
+	return r.shelved(func(book *models.Book) bool {
+		return book.Stock > 0 && book.Location >= from && book.Location <= to
+	}), nil
+}
+
+func (r *shelfBookRepository) GetShelvedByIDs(ids []uint) ([]models.Book, error) {
+	return r.shelved(func(book *models.Book) bool {
+		for _, id := range ids {
+			if book.ID == id {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *shelfBookRepository) GetShelvedByISBNs(codes []string) ([]models.Book, error) {
+	return r.shelved(func(book *models.Book) bool {
+		for _, code := range codes {
+			if book.ISBN == code {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *shelfBookRepository) GetByIDs(ids []uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	books, err := r.GetShelvedByIDs(ids)
+	return books, &query.Page{Total: int64(len(books))}, err
+}
+
+func (r *shelfBookRepository) UpdateLocation(id uint, location string) error {
+	r.books[id].Location = location
+	return nil
+}
+
+// memoryStocktakeRepository keeps one stocktake and writes copies off the
+// books of a shelfBookRepository.
+type memoryStocktakeRepository struct {
+	stocktake *models.Stocktake
+	scans     []models.StocktakeScan
+	counts    []models.StocktakeCount
+	books     *shelfBookRepository
+}
+
+func (r *memoryStocktakeRepository) Create(stocktake *models.Stocktake) error {
+	stocktake.ID = 1
+	copied := *stocktake
+	r.stocktake = &copied
+	return nil
+}
+
+func (r *memoryStocktakeRepository) GetAll(spec *query.Spec) ([]models.Stocktake, *query.Page, error) {
+	return []models.Stocktake{*r.stocktake}, &query.Page{Total: 1}, nil
+}
+
+func (r *memoryStocktakeRepository) GetByID(id uint) (*models.Stocktake, error) {
+	if r.stocktake == nil || id != r.stocktake.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *r.stocktake
+	return &copied, nil
+}
+
+func (r *memoryStocktakeRepository) AddScans(scans []models.StocktakeScan) error {
+	for _, scan := range scans {
+		scan.ID = uint(len(r.scans) + 1)
+		r.scans = append(r.scans, scan)
+	}
+	return nil
+}
+
+func (r *memoryStocktakeRepository) GetScans(stocktakeID uint, spec *query.Spec) ([]models.StocktakeScan, *query.Page, error) {
+	return r.scans, &query.Page{Total: int64(len(r.scans))}, nil
+}
+
+func (r *memoryStocktakeRepository) GetAllScans(stocktakeID uint) ([]models.StocktakeScan, error) {
+	return r.scans, nil
+}
+
+func (r *memoryStocktakeRepository) CountScans(stocktakeID uint) (int64, error) {
+	return int64(len(r.scans)), nil
+}
+
+func (r *memoryStocktakeRepository) GetScan(stocktakeID, id uint) (*models.StocktakeScan, error) {
+	for _, scan := range r.scans {
+		if scan.ID == id {
+			return &scan, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryStocktakeRepository) DeleteScan(id uint) error {
+	for i, scan := range r.scans {
+		if scan.ID == id {
+			r.scans = append(r.scans[:i], r.scans[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *memoryStocktakeRepository) Close(stocktake *models.Stocktake, counts []models.StocktakeCount) error {
+	if r.stocktake.Status != models.StocktakeOpen {
+		return repositories.ErrStocktakeClosed
+	}
+	r.stocktake.Status = models.StocktakeClosed
+	for i := range counts {
+		counts[i].ID = uint(i + 1)
+		counts[i].StocktakeID = stocktake.ID
+	}
+	r.counts = append([]models.StocktakeCount{}, counts...)
+	stocktake.Status = models.StocktakeClosed
+	return nil
+}
+
+func (r *memoryStocktakeRepository) GetCounts(stocktakeID uint) ([]models.StocktakeCount, error) {
+	return append([]models.StocktakeCount{}, r.counts...), nil
+}
+
+func (r *memoryStocktakeRepository) WriteOff(count *models.StocktakeCount, copies int) error {
+	stored := &r.counts[count.ID-1]
+	if stored.WrittenOff != count.WrittenOff {
+		return repositories.ErrCountChanged
+	}
+	book := r.books.books[count.BookID]
+	if book.Available < copies {
+		return repositories.ErrNoCopy
+	}
+	stored.WrittenOff += copies
+	book.Stock -= copies
+	book.Available -= copies
+	count.WrittenOff += copies
+	return nil
+}
+
+// noLoansRepository has no open loans.
+type noLoansRepository struct {
+	repositories.BorrowRepository
+}
+
+func (r *noLoansRepository) GetByBookID(bookID uint, spec *query.Spec) ([]models.Borrow, *query.Page, error) {
+	return nil, &query.Page{}, nil
+}
+
+func newTestStocktake(t *testing.T) (StocktakeService, *shelfBookRepository) {
+	t.Helper()
+	books := &shelfBookRepository{books: map[uint]*models.Book{
+		1: {ID: 1, Title: "Bumi manusia", ISBN: "9789799731234", Location: "Rak A-1", Stock: 3, Available: 3, IsActive: true},
+		2: {ID: 2, Title: "Laskar pelangi", ISBN: "9789793062792", Location: "Rak A-2", Stock: 2, Available: 2, IsActive: true},
+	}}
+	service := NewStocktakeService(
+		&memoryStocktakeRepository{books: books},
+		books,
+		&noLoansRepository{},
+		NewAuditService(&memoryAuditRepository{}),
+	)
+
+	if _, err := service.CreateStocktake(nil, &models.CreateStocktakeRequest{LocationFrom: "Rak A-1", LocationTo: "Rak A-9"}); err != nil {
+		t.Fatal(err)
+	}
+	codes := "9789799731234\n9789799731234\n978-979-3062-79-2\n9780000000000"
+	if _, err := service.RecordScans(nil, 1, &models.ScanRequest{Location: "Rak A-1", Codes: strings.Split(codes, "\n")}); err != nil {
+		t.Fatal(err)
+	}
+	return service, books
+}
+
+func missingCounts(report *models.StocktakeReport) map[uint]int {
+	missing := map[uint]int{}
+	for _, line := range report.Missing {
+		missing[line.BookID] = line.Count
+	}
+	return missing
+}
+
+// Loans made after closing must not turn into missing copies, nor be
+// written off.
+func TestStocktakeReconcilesAgainstClosingCounts(t *testing.T) {
+	service, books := newTestStocktake(t)
+
+	if _, err := service.CloseStocktake(nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.CloseStocktake(nil, 1); err == nil {
+		t.Error("closed the stocktake twice")
+	}
+
+	// A copy of each book goes out on loan after closing
+	books.books[1].Available--
+	books.books[2].Available--
+
+	report, err := service.Reconcile(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Expected != 5 || report.Scanned != 4 {
+		t.Errorf("expected %d, scanned %d; want 5 and 4", report.Expected, report.Scanned)
+	}
+	if got := missingCounts(report); len(got) != 2 || got[1] != 1 || got[2] != 1 {
+		t.Errorf("missing = %v, want one copy of books 1 and 2", got)
+	}
+	if len(report.Misplaced) != 1 || report.Misplaced[0].BookID != 2 {
+		t.Errorf("misplaced = %+v, want book 2", report.Misplaced)
+	}
+	if len(report.Unknown) != 1 || report.Unknown[0].Code != "9780000000000" {
+		t.Errorf("unknown = %+v", report.Unknown)
+	}
+
+	report, err = service.MarkMissing(nil, 1, &models.MarkMissingRequest{BookIDs: []uint{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if book := books.books[1]; book.Stock != 2 || book.Available != 1 {
+		t.Errorf("book 1 stock %d, available %d; want 2 and 1", book.Stock, book.Available)
+	}
+	if got := missingCounts(report); len(got) != 1 || got[2] != 1 {
+		t.Errorf("missing after write-off = %v, want only book 2", got)
+	}
+
+	// Book 1 was written off already
+	if _, err := service.MarkMissing(nil, 1, &models.MarkMissingRequest{BookIDs: []uint{1}}); err == nil {
+		t.Error("wrote book 1 off twice")
+	}
+
+	// The last copy of book 2 is on loan, so there is nothing to write off
+	books.books[2].Available = 0
+	if _, err := service.MarkMissing(nil, 1, &models.MarkMissingRequest{}); err == nil {
+		t.Error("wrote off a copy that is on loan")
+	}
+	if book := books.books[2]; book.Stock != 2 {
+		t.Errorf("book 2 stock = %d, want 2", book.Stock)
+	}
+}
+
+func TestStocktakeOpenReportIsLive(t *testing.T) {
+	service, books := newTestStocktake(t)
+
+	if _, err := service.MarkMissing(nil, 1, &models.MarkMissingRequest{}); err == nil {
+		t.Error("wrote copies off an open stocktake")
+	}
+
+	books.books[1].Available = 2
+	books.books[2].Available = 1
+	report, err := service.Reconcile(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := missingCounts(report); len(got) != 0 {
+		t.Errorf("missing = %v, want none", got)
+	}
+	if report.Expected != 3 {
+		t.Errorf("expected = %d, want 3", report.Expected)
+	}
+}
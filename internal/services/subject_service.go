@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type SubjectService interface {
+	GetAllSubjects(spec *query.Spec) ([]models.Subject, *query.Page, error)
+	GetSubjectByID(id uint) (*models.Subject, error)
+	GetSubjectBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error)
+}
+
+type subjectService struct {
+	subjectRepo repositories.SubjectRepository
+	bookRepo    repositories.BookRepository
+}
+
+func NewSubjectService(subjectRepo repositories.SubjectRepository, bookRepo repositories.BookRepository) SubjectService {
+	return &subjectService{
+		subjectRepo: subjectRepo,
+		bookRepo:    bookRepo,
+	}
+}
+
+func (s *subjectService) GetAllSubjects(spec *query.Spec) ([]models.Subject, *query.Page, error) {
+	return s.subjectRepo.GetAll(spec)
+}
+
+func (s *subjectService) GetSubjectByID(id uint) (*models.Subject, error) {
+	subject, err := s.subjectRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("subject not found")
+		}
+		return nil, err
+	}
+	return subject, nil
+}
+
+func (s *subjectService) GetSubjectBooks(id uint, spec *query.Spec) ([]models.Book, *query.Page, error) {
+	if _, err := s.GetSubjectByID(id); err != nil {
+		return nil, nil, err
+	}
+	return s.bookRepo.GetBySubject(id, spec)
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+// Review decisions staff may take from each status
+var suggestionTransitions = map[models.SuggestionStatus][]models.SuggestionStatus{
+	models.SuggestionPending:  {models.SuggestionAccepted, models.SuggestionRejected, models.SuggestionOrdered},
+	models.SuggestionAccepted: {models.SuggestionRejected, models.SuggestionOrdered},
+	models.SuggestionOrdered:  {models.SuggestionRejected},
+}
+
+type SuggestionService interface {
+	Suggest(actor *models.Actor, userID uint, req *models.CreateSuggestionRequest) (*models.PurchaseSuggestion, error)
+	GetAllSuggestions(spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error)
+	GetSuggestionByID(id uint) (*models.PurchaseSuggestion, error)
+	GetUserSuggestions(userID uint, spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error)
+	ReviewSuggestion(actor *models.Actor, id uint, req *models.ReviewSuggestionRequest) (*models.PurchaseSuggestion, error)
+	CatalogueSuggestion(actor *models.Actor, id uint, req *models.CatalogueSuggestionRequest) (*models.PurchaseSuggestion, error)
+	MergeSuggestions(actor *models.Actor, req *models.MergeRequest) (*models.PurchaseSuggestion, error)
+	LinkCatalogued() error
+}
+
+type suggestionService struct {
+	suggestionRepo      repositories.SuggestionRepository
+	bookRepo            repositories.BookRepository
+	holdService         HoldService
+	notificationService NotificationService
+	auditService        AuditService
+}
+
+func NewSuggestionService(
+	suggestionRepo repositories.SuggestionRepository,
+	bookRepo repositories.BookRepository,
+	holdService HoldService,
+	notificationService NotificationService,
+	auditService AuditService,
+) SuggestionService {
+	return &suggestionService{
+		suggestionRepo:      suggestionRepo,
+		bookRepo:            bookRepo,
+		holdService:         holdService,
+		notificationService: notificationService,
+		auditService:        auditService,
+	}
+}
+
+// Suggest records a reader's request to buy a book. A request for a book
+// already suggested, by ISBN or by title and author, adds a vote to that
+// suggestion instead.
+func (s *suggestionService) Suggest(actor *models.Actor, userID uint, req *models.CreateSuggestionRequest) (*models.PurchaseSuggestion, error) {
+	req.Title = strings.TrimSpace(req.Title)
+	req.Author = strings.TrimSpace(req.Author)
+	if req.Title == "" {
+		return nil, errors.New("title is required")
+	}
+	if req.ISBN != "" {
+		canonical, err := isbn.Normalize(req.ISBN)
+		if err != nil {
+			return nil, err
+		}
+		req.ISBN = canonical
+
+		if book, err := s.bookRepo.GetByISBN(req.ISBN); err == nil && book.IsActive {
+			return nil, errors.New("book is already in the catalog")
+		}
+	}
+	matchKey := workKey(&models.Book{Title: req.Title, Author: req.Author})
+
+	suggestion, err := s.suggestionRepo.FindOpen(req.ISBN, matchKey)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if suggestion == nil {
+		suggestion = &models.PurchaseSuggestion{
+			Title:    truncate(req.Title, 200),
+			Author:   truncate(req.Author, 100),
+			ISBN:     req.ISBN,
+			MatchKey: matchKey,
+			Status:   models.SuggestionPending,
+		}
+		if err := s.suggestionRepo.Create(suggestion); err != nil {
+			return nil, err
+		}
+		s.auditService.Record(actor, "suggestion.create", "suggestion", suggestion.ID, nil, suggestion)
+	} else {
+		if _, err := s.suggestionRepo.GetVote(suggestion.ID, userID); err == nil {
+			return nil, errors.New("you have already suggested this book")
+		}
+		// A later suggester may know more about the book
+		if suggestion.ISBN == "" && req.ISBN != "" || suggestion.Author == "" && req.Author != "" {
+			if suggestion.ISBN == "" {
+				suggestion.ISBN = req.ISBN
+			}
+			if suggestion.Author == "" {
+				suggestion.Author = truncate(req.Author, 100)
+			}
+			if err := s.suggestionRepo.Update(suggestion); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	vote := &models.SuggestionVote{
+		SuggestionID: suggestion.ID,
+		UserID:       userID,
+		Reason:       req.Reason,
+		AutoHold:     req.AutoHold,
+	}
+	if err := s.suggestionRepo.AddVote(vote); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(actor, "suggestion.vote", "suggestion", suggestion.ID, nil, vote)
+
+	suggestion, err = s.GetSuggestionByID(suggestion.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Readers don't see who else asked for the book
+	suggestion.Suggesters = nil
+	return suggestion, nil
+}
+
+func (s *suggestionService) GetAllSuggestions(spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error) {
+	return s.suggestionRepo.GetAll(spec)
+}
+
+func (s *suggestionService) GetSuggestionByID(id uint) (*models.PurchaseSuggestion, error) {
+	suggestion, err := s.suggestionRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("suggestion not found")
+		}
+		return nil, err
+	}
+	return suggestion, nil
+}
+
+func (s *suggestionService) GetUserSuggestions(userID uint, spec *query.Spec) ([]models.PurchaseSuggestion, *query.Page, error) {
+	return s.suggestionRepo.GetByUserID(userID, spec)
+}
+
+// ReviewSuggestion accepts, rejects or marks a suggestion as ordered and
+// tells everyone who asked for the book.
+func (s *suggestionService) ReviewSuggestion(actor *models.Actor, id uint, req *models.ReviewSuggestionRequest) (*models.PurchaseSuggestion, error) {
+	suggestion, err := s.GetSuggestionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canMoveSuggestion(suggestion.Status, req.Status) {
+		return nil, fmt.Errorf("cannot move suggestion from %s to %s", suggestion.Status, req.Status)
+	}
+
+	before := snapshot(suggestion)
+	now := time.Now()
+	suggestion.Status = req.Status
+	if req.Note != "" {
+		suggestion.StaffNote = req.Note
+	}
+	if actor != nil && actor.UserID != 0 {
+		reviewer := actor.UserID
+		suggestion.ReviewedBy = &reviewer
+	}
+	suggestion.ReviewedAt = &now
+
+	if err := s.suggestionRepo.Update(suggestion); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "suggestion.review", "suggestion", suggestion.ID, before, suggestion)
+
+	var subject, message string
+	switch req.Status {
+	case models.SuggestionAccepted:
+		subject = "Your suggestion was accepted"
+		message = fmt.Sprintf("The library will buy %q.", suggestion.Title)
+	case models.SuggestionRejected:
+		subject = "Your suggestion was declined"
+		message = fmt.Sprintf("The library will not buy %q.", suggestion.Title)
+	case models.SuggestionOrdered:
+		subject = "Your suggested book has been ordered"
+		message = fmt.Sprintf("%q is on order.", suggestion.Title)
+	}
+	if req.Note != "" {
+		message += " " + req.Note
+	}
+	s.notifySuggesters(suggestion, subject, message)
+
+	return suggestion, nil
+}
+
+// CatalogueSuggestion links a suggestion to the book bought for it, tells
+// the suggesters and places the holds they asked for.
+func (s *suggestionService) CatalogueSuggestion(actor *models.Actor, id uint, req *models.CatalogueSuggestionRequest) (*models.PurchaseSuggestion, error) {
+	suggestion, err := s.GetSuggestionByID(id)
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.bookRepo.GetByID(req.BookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("book not found")
+		}
+		return nil, err
+	}
+
+	return s.catalogue(actor, suggestion, book)
+}
+
+func (s *suggestionService) catalogue(actor *models.Actor, suggestion *models.PurchaseSuggestion, book *models.Book) (*models.PurchaseSuggestion, error) {
+	if !suggestion.Open() {
+		return nil, fmt.Errorf("suggestion is already %s", suggestion.Status)
+	}
+
+	before := snapshot(suggestion)
+	suggestion.Status = models.SuggestionCatalogued
+	suggestion.BookID = &book.ID
+	suggestion.Book = book
+	if err := s.suggestionRepo.Update(suggestion); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "suggestion.catalogue", "suggestion", suggestion.ID, before, suggestion)
+
+	for _, vote := range suggestion.Suggesters {
+		message := fmt.Sprintf("%q is now in the catalog.", book.Title)
+		if vote.AutoHold {
+			_, err := s.holdService.PlaceHold(actor, &models.PlaceHoldRequest{UserID: vote.UserID, BookID: book.ID})
+			if err != nil {
+				log.Printf("suggestions: failed to place hold for user %d on book %d: %v", vote.UserID, book.ID, err)
+			} else {
+				message += " A hold has been placed for you."
+			}
+		}
+		s.notificationService.Notify(vote.UserID, "Your suggested book has arrived", message, "suggestion", suggestion.ID)
+	}
+
+	return suggestion, nil
+}
+
+// MergeSuggestions folds duplicate suggestions into the target, keeping
+// every reader's vote once.
+func (s *suggestionService) MergeSuggestions(actor *models.Actor, req *models.MergeRequest) (*models.PurchaseSuggestion, error) {
+	target, err := s.GetSuggestionByID(req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	if !target.Open() {
+		return nil, fmt.Errorf("suggestion is already %s", target.Status)
+	}
+	if len(req.SourceIDs) == 0 {
+		return nil, errors.New("source_ids is required")
+	}
+
+	sources := make([]*models.PurchaseSuggestion, 0, len(req.SourceIDs))
+	seen := make(map[uint]bool, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		if id == target.ID {
+			return nil, errors.New("cannot merge a suggestion into itself")
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("suggestion %d is listed twice", id)
+		}
+		seen[id] = true
+		source, err := s.GetSuggestionByID(id)
+		if err != nil {
+			return nil, err
+		}
+		// Order lines refer to ordered suggestions, so those stay
+		if !source.Open() || source.Status == models.SuggestionOrdered {
+			return nil, fmt.Errorf("suggestion %d is already %s", source.ID, source.Status)
+		}
+		sources = append(sources, source)
+	}
+
+	if err := s.suggestionRepo.Merge(target.ID, req.SourceIDs); err != nil {
+		if errors.Is(err, repositories.ErrSuggestionChanged) {
+			return nil, errors.New("a suggestion changed while merging, try again")
+		}
+		return nil, err
+	}
+
+	for _, source := range sources {
+		s.auditService.Record(actor, "suggestion.merge", "suggestion", source.ID, source, target)
+	}
+
+	return s.GetSuggestionByID(target.ID)
+}
+
+// LinkCatalogued catalogues open suggestions whose ISBN has since been
+// added to the catalog, e.g. when an order was received.
+func (s *suggestionService) LinkCatalogued() error {
+	suggestions, err := s.suggestionRepo.GetUncatalogued()
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range suggestions {
+		book, err := s.bookRepo.GetByISBN(candidate.ISBN)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		if !book.IsActive {
+			continue
+		}
+
+		suggestion, err := s.GetSuggestionByID(candidate.ID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.catalogue(nil, suggestion, book); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *suggestionService) notifySuggesters(suggestion *models.PurchaseSuggestion, subject, message string) {
+	for _, vote := range suggestion.Suggesters {
+		s.notificationService.Notify(vote.UserID, subject, message, "suggestion", suggestion.ID)
+	}
+}
+
+func canMoveSuggestion(from, to models.SuggestionStatus) bool {
+	for _, allowed := range suggestionTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
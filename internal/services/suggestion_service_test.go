@@ -0,0 +1,87 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// memorySuggestionRepository serves suggestions from a map and records
+// merges. Other methods are not implemented.
+type memorySuggestionRepository struct {
+	repositories.SuggestionRepository
+	suggestions map[uint]*models.PurchaseSuggestion
+	merged      []uint
+}
+
+func (r *memorySuggestionRepository) GetByID(id uint) (*models.PurchaseSuggestion, error) {
+	suggestion, ok := r.suggestions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *suggestion
+	return &copied, nil
+}
+
+func (r *memorySuggestionRepository) Merge(targetID uint, sourceIDs []uint) error {
+	r.merged = append(r.merged, sourceIDs...)
+	for _, id := range sourceIDs {
+		delete(r.suggestions, id)
+	}
+	return nil
+}
+
+func newMergeTestService() (*memorySuggestionRepository, SuggestionService) {
+	repo := &memorySuggestionRepository{suggestions: map[uint]*models.PurchaseSuggestion{
+		1: {ID: 1, Status: models.SuggestionAccepted, Votes: 2},
+		2: {ID: 2, Status: models.SuggestionPending, Votes: 1},
+		3: {ID: 3, Status: models.SuggestionPending, Votes: 1},
+		4: {ID: 4, Status: models.SuggestionOrdered},
+		5: {ID: 5, Status: models.SuggestionRejected},
+	}}
+	service := NewSuggestionService(repo, nil, nil, nil, NewAuditService(&memoryAuditRepository{}))
+	return repo, service
+}
+
+func TestMergeSuggestions(t *testing.T) {
+	repo, service := newMergeTestService()
+
+	target, err := service.MergeSuggestions(nil, &models.MergeRequest{TargetID: 1, SourceIDs: []uint{2, 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.ID != 1 {
+		t.Errorf("got suggestion %d, want 1", target.ID)
+	}
+	if want := []uint{2, 3}; !reflect.DeepEqual(repo.merged, want) {
+		t.Errorf("merged %v, want %v", repo.merged, want)
+	}
+}
+
+func TestMergeSuggestionsRejected(t *testing.T) {
+	for name, tc := range map[string]struct {
+		req  models.MergeRequest
+		want string
+	}{
+		"no sources":    {models.MergeRequest{TargetID: 1}, "source_ids is required"},
+		"itself":        {models.MergeRequest{TargetID: 1, SourceIDs: []uint{1}}, "into itself"},
+		"listed twice":  {models.MergeRequest{TargetID: 1, SourceIDs: []uint{2, 2}}, "listed twice"},
+		"ordered":       {models.MergeRequest{TargetID: 1, SourceIDs: []uint{2, 4}}, "already ordered"},
+		"closed":        {models.MergeRequest{TargetID: 1, SourceIDs: []uint{5}}, "already rejected"},
+		"missing":       {models.MergeRequest{TargetID: 1, SourceIDs: []uint{9}}, "not found"},
+		"closed target": {models.MergeRequest{TargetID: 5, SourceIDs: []uint{2}}, "already rejected"},
+	} {
+		repo, service := newMergeTestService()
+		_, err := service.MergeSuggestions(nil, &tc.req)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", name, err, tc.want)
+		}
+		if len(repo.merged) != 0 {
+			t.Errorf("%s: merged %v", name, repo.merged)
+		}
+	}
+}
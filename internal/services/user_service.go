@@ -2,35 +2,52 @@ package services
 
 import (
 	"errors"
+	"time"
 
 	"github.com/yooerizkilab/library-system/internal/models"
 	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
 	"github.com/yooerizkilab/library-system/pkg/utils"
 	"gorm.io/gorm"
 )
 
 type UserService interface {
-	CreateUser(req *models.CreateUserRequest) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
+	CreateUser(actor *models.Actor, req *models.CreateUserRequest) (*models.User, error)
+	GetAllUsers(spec *query.Spec) ([]models.User, *query.Page, error)
 	GetUserByID(id uint) (*models.User, error)
-	UpdateUser(id uint, req *models.UpdateUserRequest) (*models.User, error)
-	DeleteUser(id uint) error
-	SearchUsers(query string) ([]models.User, error)
+	UpdateUser(actor *models.Actor, id uint, req *models.UpdateUserRequest) (*models.User, error)
+	DeleteUser(actor *models.Actor, id uint) error
+	SearchUsers(q string, spec *query.Spec) ([]models.User, *query.Page, error)
 	Login(req *models.LoginRequest) (*models.LoginResponse, error)
-	ChangePassword(userID uint, req *models.ChangePasswordRequest) error
+	ChangePassword(actor *models.Actor, userID uint, req *models.ChangePasswordRequest) error
+	UpdatePrivacyPreferences(actor *models.Actor, userID uint, req *models.PrivacyPreferencesRequest) (*models.User, error)
 }
 
 type userService struct {
-	userRepo repositories.UserRepository
+	userRepo         repositories.UserRepository
+	erasureRepo      repositories.ErasureRepository
+	holdService      HoldService
+	auditService     AuditService
+	erasureRetention time.Duration
 }
 
-func NewUserService(userRepo repositories.UserRepository) UserService {
+func NewUserService(
+	userRepo repositories.UserRepository,
+	erasureRepo repositories.ErasureRepository,
+	holdService HoldService,
+	auditService AuditService,
+	erasureRetention time.Duration,
+) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:         userRepo,
+		erasureRepo:      erasureRepo,
+		holdService:      holdService,
+		auditService:     auditService,
+		erasureRetention: erasureRetention,
 	}
 }
 
-func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.User, error) {
+func (s *userService) CreateUser(actor *models.Actor, req *models.CreateUserRequest) (*models.User, error) {
 	// Check if email already exists
 	existingUser, err := s.userRepo.GetByEmail(req.Email)
 	if err == nil && existingUser != nil {
@@ -63,6 +80,8 @@ func (s *userService) CreateUser(req *models.CreateUserRequest) (*models.User, e
 		return nil, err
 	}
 
+	s.auditService.Record(actor, "user.create", "user", user.ID, nil, user)
+
 	return user, nil
 }
 
@@ -98,7 +117,7 @@ func (s *userService) Login(req *models.LoginRequest) (*models.LoginResponse, er
 	}, nil
 }
 
-func (s *userService) ChangePassword(userID uint, req *models.ChangePasswordRequest) error {
+func (s *userService) ChangePassword(actor *models.Actor, userID uint, req *models.ChangePasswordRequest) error {
 	// Get user
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
@@ -126,11 +145,39 @@ func (s *userService) ChangePassword(userID uint, req *models.ChangePasswordRequ
 		return err
 	}
 
+	// The password itself is never serialized, so only the event is logged
+	s.auditService.Record(actor, "user.change_password", "user", user.ID, nil, nil)
+
 	return nil
 }
 
-func (s *userService) GetAllUsers() ([]models.User, error) {
-	return s.userRepo.GetAll()
+func (s *userService) UpdatePrivacyPreferences(actor *models.Actor, userID uint, req *models.PrivacyPreferencesRequest) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	if req.KeepReadingHistory == nil {
+		return nil, errors.New("keep_reading_history is required")
+	}
+
+	before := snapshot(user)
+	user.KeepReadingHistory = *req.KeepReadingHistory
+	err = s.userRepo.Update(user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "user.update_privacy", "user", user.ID, before, user)
+
+	return user, nil
+}
+
+func (s *userService) GetAllUsers(spec *query.Spec) ([]models.User, *query.Page, error) {
+	return s.userRepo.GetAll(spec)
 }
 
 func (s *userService) GetUserByID(id uint) (*models.User, error) {
@@ -144,7 +191,7 @@ func (s *userService) GetUserByID(id uint) (*models.User, error) {
 	return user, nil
 }
 
-func (s *userService) UpdateUser(id uint, req *models.UpdateUserRequest) (*models.User, error) {
+func (s *userService) UpdateUser(actor *models.Actor, id uint, req *models.UpdateUserRequest) (*models.User, error) {
 	user, err := s.userRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -161,6 +208,8 @@ func (s *userService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 		}
 	}
 
+	before := snapshot(user)
+
 	// Update fields
 	if req.Name != "" {
 		user.Name = req.Name
@@ -186,10 +235,12 @@ func (s *userService) UpdateUser(id uint, req *models.UpdateUserRequest) (*model
 		return nil, err
 	}
 
+	s.auditService.Record(actor, "user.update", "user", user.ID, before, user)
+
 	return user, nil
 }
 
-func (s *userService) DeleteUser(id uint) error {
+func (s *userService) DeleteUser(actor *models.Actor, id uint) error {
 	user, err := s.userRepo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -207,9 +258,23 @@ func (s *userService) DeleteUser(id uint) error {
 		}
 	}
 
-	return s.userRepo.Delete(id)
+	if err := s.holdService.CancelUserHolds(actor, id); err != nil {
+		return err
+	}
+	if err := s.userRepo.Delete(id); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, "user.delete", "user", user.ID, user, nil)
+
+	// Deleted accounts keep their personal data only for the retention period
+	pending, err := s.erasureRepo.GetPendingByUserID(id)
+	if err == nil && pending != nil {
+		return nil
+	}
+	return s.erasureRepo.Create(newErasureRequest(id, actor.UserID, "account deleted", s.erasureRetention))
 }
 
-func (s *userService) SearchUsers(query string) ([]models.User, error) {
-	return s.userRepo.Search(query)
+func (s *userService) SearchUsers(q string, spec *query.Spec) ([]models.User, *query.Page, error) {
+	return s.userRepo.Search(q, spec)
 }
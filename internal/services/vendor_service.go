@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"gorm.io/gorm"
+)
+
+type VendorService interface {
+	CreateVendor(actor *models.Actor, req *models.CreateVendorRequest) (*models.Vendor, error)
+	GetAllVendors(spec *query.Spec) ([]models.Vendor, *query.Page, error)
+	GetVendorByID(id uint) (*models.Vendor, error)
+	UpdateVendor(actor *models.Actor, id uint, req *models.UpdateVendorRequest) (*models.Vendor, error)
+	DeleteVendor(actor *models.Actor, id uint) error
+}
+
+type vendorService struct {
+	vendorRepo   repositories.VendorRepository
+	orderRepo    repositories.PurchaseOrderRepository
+	auditService AuditService
+}
+
+func NewVendorService(
+	vendorRepo repositories.VendorRepository,
+	orderRepo repositories.PurchaseOrderRepository,
+	auditService AuditService,
+) VendorService {
+	return &vendorService{
+		vendorRepo:   vendorRepo,
+		orderRepo:    orderRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *vendorService) CreateVendor(actor *models.Actor, req *models.CreateVendorRequest) (*models.Vendor, error) {
+	name := strings.Join(strings.Fields(req.Name), " ")
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.DeliveryDays < 0 {
+		return nil, errors.New("delivery_days cannot be negative")
+	}
+
+	vendor := &models.Vendor{
+		Name:          name,
+		ContactName:   strings.TrimSpace(req.ContactName),
+		Email:         strings.TrimSpace(req.Email),
+		Phone:         strings.TrimSpace(req.Phone),
+		Address:       strings.TrimSpace(req.Address),
+		AccountNumber: strings.TrimSpace(req.AccountNumber),
+		DeliveryDays:  req.DeliveryDays,
+		IsActive:      true,
+	}
+	if vendor.DeliveryDays == 0 {
+		vendor.DeliveryDays = 14
+	}
+
+	if err := s.vendorRepo.Create(vendor); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "vendor.create", "vendor", vendor.ID, nil, vendor)
+
+	return vendor, nil
+}
+
+func (s *vendorService) GetAllVendors(spec *query.Spec) ([]models.Vendor, *query.Page, error) {
+	return s.vendorRepo.GetAll(spec)
+}
+
+func (s *vendorService) GetVendorByID(id uint) (*models.Vendor, error) {
+	vendor, err := s.vendorRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("vendor not found")
+		}
+		return nil, err
+	}
+	return vendor, nil
+}
+
+func (s *vendorService) UpdateVendor(actor *models.Actor, id uint, req *models.UpdateVendorRequest) (*models.Vendor, error) {
+	vendor, err := s.GetVendorByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(vendor)
+
+	if name := strings.Join(strings.Fields(req.Name), " "); name != "" {
+		vendor.Name = name
+	}
+	if req.ContactName != nil {
+		vendor.ContactName = strings.TrimSpace(*req.ContactName)
+	}
+	if req.Email != nil {
+		vendor.Email = strings.TrimSpace(*req.Email)
+	}
+	if req.Phone != nil {
+		vendor.Phone = strings.TrimSpace(*req.Phone)
+	}
+	if req.Address != nil {
+		vendor.Address = strings.TrimSpace(*req.Address)
+	}
+	if req.AccountNumber != nil {
+		vendor.AccountNumber = strings.TrimSpace(*req.AccountNumber)
+	}
+	if req.DeliveryDays != nil {
+		if *req.DeliveryDays < 0 {
+			return nil, errors.New("delivery_days cannot be negative")
+		}
+		vendor.DeliveryDays = *req.DeliveryDays
+	}
+	if req.IsActive != nil {
+		vendor.IsActive = *req.IsActive
+	}
+
+	if err := s.vendorRepo.Update(vendor); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "vendor.update", "vendor", vendor.ID, before, vendor)
+
+	return vendor, nil
+}
+
+// DeleteVendor removes a vendor with no orders in progress.
+func (s *vendorService) DeleteVendor(actor *models.Actor, id uint) error {
+	vendor, err := s.GetVendorByID(id)
+	if err != nil {
+		return err
+	}
+
+	open, err := s.orderRepo.CountOpenByVendor(vendor.ID)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return errors.New("vendor has orders in progress")
+	}
+
+	if err := s.vendorRepo.Delete(vendor.ID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(actor, "vendor.delete", "vendor", vendor.ID, vendor, nil)
+
+	return nil
+}
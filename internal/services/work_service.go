@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/yooerizkilab/library-system/internal/models"
+	"github.com/yooerizkilab/library-system/internal/repositories"
+	"github.com/yooerizkilab/library-system/internal/search"
+	"github.com/yooerizkilab/library-system/pkg/query"
+	"github.com/yooerizkilab/library-system/pkg/utils"
+	"gorm.io/gorm"
+)
+
+type WorkService interface {
+	GetAllWorks(spec *query.Spec) ([]models.Work, *query.Page, error)
+	GetWorkByID(id uint) (*models.Work, error)
+	UpdateWork(actor *models.Actor, id uint, req *models.UpdateWorkRequest) (*models.Work, error)
+	MergeWorks(actor *models.Actor, req *models.MergeRequest) (*models.Work, error)
+}
+
+type workService struct {
+	workRepo     repositories.WorkRepository
+	auditService AuditService
+}
+
+func NewWorkService(workRepo repositories.WorkRepository, auditService AuditService) WorkService {
+	return &workService{
+		workRepo:     workRepo,
+		auditService: auditService,
+	}
+}
+
+func (s *workService) GetAllWorks(spec *query.Spec) ([]models.Work, *query.Page, error) {
+	return s.workRepo.GetAll(spec)
+}
+
+// GetWorkByID returns a work with its active editions.
+func (s *workService) GetWorkByID(id uint) (*models.Work, error) {
+	work, err := s.workRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("work not found")
+		}
+		return nil, err
+	}
+	return work, nil
+}
+
+// UpdateWork renames a work. Its editions keep their own titles.
+func (s *workService) UpdateWork(actor *models.Actor, id uint, req *models.UpdateWorkRequest) (*models.Work, error) {
+	if errs := utils.ValidateStruct(req); len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+
+	work, err := s.GetWorkByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	before := snapshot(work)
+	if title := strings.Join(strings.Fields(req.Title), " "); title != "" {
+		work.Title = title
+	}
+	if author := strings.Join(strings.Fields(req.Author), " "); author != "" {
+		work.Author = author
+	}
+
+	if err := s.workRepo.Update(work); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(actor, "work.update", "work", work.ID, before, work)
+
+	return work, nil
+}
+
+// MergeWorks moves the editions and holds of the source works to the
+// target, e.g. to group a translation with its original, and deletes the
+// sources.
+func (s *workService) MergeWorks(actor *models.Actor, req *models.MergeRequest) (*models.Work, error) {
+	if req.TargetID == 0 || len(req.SourceIDs) == 0 {
+		return nil, errors.New("target_id and source_ids are required")
+	}
+	if slices.Contains(req.SourceIDs, req.TargetID) {
+		return nil, errors.New("cannot merge a work into itself")
+	}
+
+	target, err := s.GetWorkByID(req.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]*models.Work, 0, len(req.SourceIDs))
+	for _, id := range req.SourceIDs {
+		source, err := s.GetWorkByID(id)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+
+	if _, err := s.workRepo.Merge(target.ID, req.SourceIDs); err != nil {
+		return nil, err
+	}
+
+	for _, source := range sources {
+		s.auditService.Record(actor, "work.merge", "work", source.ID, source, target)
+	}
+
+	return s.GetWorkByID(target.ID)
+}
+
+// workKey identifies the work of a book by its title, without subtitle or
+// leading article, and its main author, so editions that differ only in
+// subtitle, case or punctuation share it.
+func workKey(book *models.Book) string {
+	words := search.Words(workTitle(book.Title))
+	if len(words) > 1 && titleArticles[words[0]] {
+		words = words[1:]
+	}
+	return truncate(strings.Join(words, " ")+"|"+authorKey(mainAuthorName(book)), 255)
+}
+
+// workTitle drops the subtitle from a book title.
+func workTitle(title string) string {
+	if i := strings.Index(title, ":"); i > 0 {
+		title = title[:i]
+	}
+	return strings.TrimSpace(title)
+}
+
+// findOrCreateWork returns the work whose key matches the book, creating
+// it from the book when there is none.
+func findOrCreateWork(repo repositories.WorkRepository, book *models.Book) (*models.Work, error) {
+	key := workKey(book)
+	work, err := repo.GetByKey(key)
+	if err == nil {
+		return work, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return createWork(repo, book)
+}
+
+// createWork starts a new work with a single edition, the book.
+func createWork(repo repositories.WorkRepository, book *models.Book) (*models.Work, error) {
+	work := &models.Work{
+		Title:   truncate(workTitle(book.Title), 200),
+		Author:  truncate(mainAuthorName(book), 100),
+		WorkKey: workKey(book),
+	}
+	if err := repo.Create(work); err != nil {
+		return nil, err
+	}
+	return work, nil
+}
@@ -0,0 +1,27 @@
+// Package storage keeps binary objects such as cover images outside the
+// database.
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound means no object is stored under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores objects by key. Keys are slash separated paths such as
+// "covers/12/small.jpg".
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (*Blob, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Blob is a stored object.
+type Blob struct {
+	Data        []byte
+	ContentType string
+	ModTime     time.Time
+}
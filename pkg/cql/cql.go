@@ -0,0 +1,100 @@
+// Package cql parses queries in the Contextual Query Language used by SRU,
+// e.g. dc.title any "bumi manusia" and dc.date >= 1980 sortBy dc.title.
+package cql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query is a parsed CQL query.
+type Query struct {
+	Root Node
+	Sort []SortKey
+}
+
+// Node is a *Clause or a *Boolean.
+type Node interface {
+	String() string
+}
+
+// Clause is a search clause. A bare term has the index
+// cql.serverchoice and the relation "=".
+type Clause struct {
+	Index     string // lower case, e.g. dc.title
+	Relation  string // lower case, e.g. "=", "any", "<="
+	Modifiers []Modifier
+	Term      string
+}
+
+// Boolean joins two nodes with and, or, not or prox.
+type Boolean struct {
+	Op        string // lower case
+	Modifiers []Modifier
+	Left      Node
+	Right     Node
+}
+
+// Modifier qualifies a relation, boolean or sort key, e.g. /sort.descending
+// or /distance<3.
+type Modifier struct {
+	Name       string
+	Comparison string
+	Value      string
+}
+
+type SortKey struct {
+	Index     string
+	Modifiers []Modifier
+}
+
+// ServerChoice is the index of a term given without one.
+const ServerChoice = "cql.serverchoice"
+
+// SyntaxError reports where a query could not be parsed.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("cql: %s at position %d", e.Msg, e.Pos)
+}
+
+func (c *Clause) String() string {
+	return fmt.Sprintf("%s %s%s %s", c.Index, c.Relation, modifierString(c.Modifiers), quote(c.Term))
+}
+
+func (b *Boolean) String() string {
+	return fmt.Sprintf("(%s %s%s %s)", b.Left, b.Op, modifierString(b.Modifiers), b.Right)
+}
+
+// HasModifier reports whether a modifier with this name, ignoring case and
+// any context set prefix, is present.
+func HasModifier(modifiers []Modifier, name string) bool {
+	for _, m := range modifiers {
+		if strings.EqualFold(m.Name, name) || strings.HasSuffix(strings.ToLower(m.Name), "."+strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func modifierString(modifiers []Modifier) string {
+	var b strings.Builder
+	for _, m := range modifiers {
+		b.WriteString("/" + m.Name)
+		if m.Comparison != "" {
+			b.WriteString(m.Comparison + quote(m.Value))
+		}
+	}
+	return b.String()
+}
+
+func quote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t()=<>/\"") {
+		return s
+	}
+	// Backslashes in a term are still escapes, so only quotes need one
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
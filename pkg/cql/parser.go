@@ -0,0 +1,261 @@
+package cql
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenSlash
+	tokenComparison
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// Words that join clauses, matched ignoring case
+var booleans = map[string]bool{"and": true, "or": true, "not": true, "prox": true}
+
+// Parse reads a CQL query, including a trailing sortBy.
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &SyntaxError{Pos: 0, Msg: "empty query"}
+	}
+	root, err := p.scopedClause()
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{Root: root}
+	if p.peekKeyword("sortby") {
+		p.next()
+		if query.Sort, err = p.sortKeys(); err != nil {
+			return nil, err
+		}
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, &SyntaxError{Pos: t.pos, Msg: "unexpected " + describe(t)}
+	}
+	return query, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.value, word)
+}
+
+func (p *parser) peekBoolean() bool {
+	t := p.peek()
+	return t.kind == tokenWord && booleans[strings.ToLower(t.value)]
+}
+
+// scopedClause reads clauses joined by booleans, which bind left to right
+// with equal precedence.
+func (p *parser) scopedClause() (Node, error) {
+	left, err := p.searchClause()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekBoolean() {
+		op := strings.ToLower(p.next().value)
+		modifiers, err := p.modifiers()
+		if err != nil {
+			return nil, err
+		}
+		right, err := p.searchClause()
+		if err != nil {
+			return nil, err
+		}
+		left = &Boolean{Op: op, Modifiers: modifiers, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) searchClause() (Node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenLParen:
+		p.next()
+		node, err := p.scopedClause()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, &SyntaxError{Pos: closing.pos, Msg: "expected ) but found " + describe(closing)}
+		}
+		return node, nil
+	case tokenWord, tokenString:
+	default:
+		return nil, &SyntaxError{Pos: t.pos, Msg: "expected a search term but found " + describe(t)}
+	}
+
+	first := p.next()
+	if !p.startsRelation() {
+		return &Clause{Index: ServerChoice, Relation: "=", Term: first.value}, nil
+	}
+	if first.kind != tokenWord {
+		return nil, &SyntaxError{Pos: first.pos, Msg: "an index name cannot be quoted"}
+	}
+
+	relation := p.next()
+	modifiers, err := p.modifiers()
+	if err != nil {
+		return nil, err
+	}
+	term := p.next()
+	if term.kind != tokenWord && term.kind != tokenString {
+		return nil, &SyntaxError{Pos: term.pos, Msg: "expected a search term but found " + describe(term)}
+	}
+	return &Clause{
+		Index:     strings.ToLower(first.value),
+		Relation:  strings.ToLower(relation.value),
+		Modifiers: modifiers,
+		Term:      term.value,
+	}, nil
+}
+
+// startsRelation tells an index from a bare term: an index is followed by
+// a comparison symbol or by a named relation such as any.
+func (p *parser) startsRelation() bool {
+	t := p.peek()
+	if t.kind == tokenComparison {
+		return true
+	}
+	return t.kind == tokenWord && !booleans[strings.ToLower(t.value)] && !strings.EqualFold(t.value, "sortby")
+}
+
+func (p *parser) modifiers() ([]Modifier, error) {
+	var modifiers []Modifier
+	for p.peek().kind == tokenSlash {
+		p.next()
+		name := p.next()
+		if name.kind != tokenWord {
+			return nil, &SyntaxError{Pos: name.pos, Msg: "expected a modifier name but found " + describe(name)}
+		}
+		modifier := Modifier{Name: strings.ToLower(name.value)}
+		if p.peek().kind == tokenComparison {
+			modifier.Comparison = p.next().value
+			value := p.next()
+			if value.kind != tokenWord && value.kind != tokenString {
+				return nil, &SyntaxError{Pos: value.pos, Msg: "expected a modifier value but found " + describe(value)}
+			}
+			modifier.Value = value.value
+		}
+		modifiers = append(modifiers, modifier)
+	}
+	return modifiers, nil
+}
+
+func (p *parser) sortKeys() ([]SortKey, error) {
+	var keys []SortKey
+	for p.peek().kind == tokenWord || p.peek().kind == tokenString {
+		index := p.next()
+		modifiers, err := p.modifiers()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, SortKey{Index: strings.ToLower(index.value), Modifiers: modifiers})
+	}
+	if len(keys) == 0 {
+		t := p.peek()
+		return nil, &SyntaxError{Pos: t.pos, Msg: "expected a sort key but found " + describe(t)}
+	}
+	return keys, nil
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, value: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, value: ")", pos: i})
+			i++
+		case r == '/':
+			tokens = append(tokens, token{kind: tokenSlash, value: "/", pos: i})
+			i++
+		case r == '=' || r == '<' || r == '>':
+			start := i
+			i++
+			if i < len(runes) {
+				pair := string(runes[start : i+1])
+				if pair == "==" || pair == "<=" || pair == ">=" || pair == "<>" {
+					i++
+				}
+			}
+			tokens = append(tokens, token{kind: tokenComparison, value: string(runes[start:i]), pos: start})
+		case r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				// A backslash keeps the next character, except before the
+				// masking characters * ? ^ where it stays for the term
+				if runes[i] == '\\' && i+1 < len(runes) {
+					if strings.ContainsRune(`*?^\`, runes[i+1]) {
+						b.WriteRune('\\')
+					}
+					i++
+				}
+				b.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, &SyntaxError{Pos: start, Msg: "unterminated string"}
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, value: b.String(), pos: start})
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t\n\r()/=<>\"", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: string(runes[start:i]), pos: start})
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return `"` + t.value + `"`
+}
@@ -0,0 +1,128 @@
+// Package imaging decodes uploaded pictures and scales them down to
+// thumbnails using only the standard library.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	// Registered decoders
+	_ "image/gif"
+	_ "image/png"
+)
+
+// ErrUnsupported means the data is not a JPEG, PNG or GIF image.
+var ErrUnsupported = errors.New("unsupported image type, use JPEG, PNG or GIF")
+
+// Types that Decode accepts, by sniffed content type
+var supportedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// DetectType sniffs the content type of data, ignoring any name or header
+// that came with it.
+func DetectType(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Decode decodes a JPEG, PNG or GIF image. Images with more than maxPixels
+// pixels are refused before they are decoded, so a small file cannot
+// expand into a huge bitmap.
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	if !supportedTypes[DetectType(data)] {
+		return nil, ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("invalid image: empty")
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("image is too large: %dx%d pixels", config.Width, config.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %w", err)
+	}
+	return img, nil
+}
+
+// Fit scales img down to fit within maxWidth by maxHeight, keeping its
+// aspect ratio, and flattens transparency onto white. Smaller images keep
+// their size.
+func Fit(img image.Image, maxWidth, maxHeight int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := srcW, srcH
+	if dstW > maxWidth {
+		dstW, dstH = maxWidth, max(1, srcH*maxWidth/srcW)
+	}
+	if dstH > maxHeight {
+		dstW, dstH = max(1, srcW*maxHeight/srcH), maxHeight
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+	if dstW == srcW && dstH == srcH {
+		return src
+	}
+	return shrink(src, dstW, dstH)
+}
+
+// shrink averages the source pixels under each destination pixel. src is
+// opaque.
+func shrink(src *image.RGBA, dstW, dstH int) *image.RGBA {
+	srcW, srcH := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+
+	for y := 0; y < dstH; y++ {
+		y0 := y * srcH / dstH
+		y1 := max(y0+1, (y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := x * srcW / dstW
+			x1 := max(x0+1, (x+1)*srcW/dstW)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4:]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					n++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0] = uint8(r / n)
+			d[1] = uint8(g / n)
+			d[2] = uint8(b / n)
+			d[3] = 0xff
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG encodes img as a baseline JPEG.
+func EncodeJPEG(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,152 @@
+// Package isbn validates and converts International Standard Book Numbers.
+// The canonical form is the 13 digit ISBN without separators.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalid = errors.New("invalid ISBN")
+
+// Normalize validates an ISBN-10, ISBN-13 or scanned EAN-13 (optionally
+// followed by a 2 or 5 digit add-on) and returns its canonical ISBN-13.
+// Hyphens, spaces and an "ISBN" label are ignored.
+func Normalize(input string) (string, error) {
+	code := clean(input)
+
+	switch len(code) {
+	case 10:
+		return To13(code)
+	case 13:
+		return check13(code)
+	case 15, 18:
+		// Bar codes on books carry a price or issue add-on after the EAN
+		for i := 13; i < len(code); i++ {
+			if !isDigit(code[i]) {
+				return "", fmt.Errorf("%w: add-on of %q contains non-digits", ErrInvalid, input)
+			}
+		}
+		return check13(code[:13])
+	}
+	return "", fmt.Errorf("%w: %q must have 10 or 13 digits", ErrInvalid, input)
+}
+
+// Valid tells whether input is a valid ISBN in any accepted form.
+func Valid(input string) bool {
+	_, err := Normalize(input)
+	return err == nil
+}
+
+// To13 converts a valid ISBN-10 to its ISBN-13.
+func To13(isbn10 string) (string, error) {
+	code := clean(isbn10)
+	if len(code) != 10 {
+		return "", fmt.Errorf("%w: ISBN-10 must have 10 characters", ErrInvalid)
+	}
+	for i := 0; i < 9; i++ {
+		if !isDigit(code[i]) {
+			return "", fmt.Errorf("%w: %q contains non-digits", ErrInvalid, isbn10)
+		}
+	}
+	if want := checkDigit10(code[:9]); code[9] != want {
+		return "", fmt.Errorf("%w: check digit of %s should be %c", ErrInvalid, isbn10, want)
+	}
+
+	body := "978" + code[:9]
+	return body + string(checkDigit13(body)), nil
+}
+
+// To10 converts an ISBN-13 with the 978 prefix to its ISBN-10. ISBNs with
+// the 979 prefix have no ISBN-10.
+func To10(isbn13 string) (string, error) {
+	code, err := check13(clean(isbn13))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(code, "978") {
+		return "", fmt.Errorf("%w: %s has no ISBN-10 form", ErrInvalid, isbn13)
+	}
+
+	body := code[3:12]
+	return body + string(checkDigit10(body)), nil
+}
+
+// Variants returns the unhyphenated forms a stored ISBN may have: the
+// canonical ISBN-13 and, where one exists, the ISBN-10.
+func Variants(input string) []string {
+	canonical, err := Normalize(input)
+	if err != nil {
+		return nil
+	}
+	variants := []string{canonical}
+	if isbn10, err := To10(canonical); err == nil {
+		variants = append(variants, isbn10)
+	}
+	return variants
+}
+
+func check13(code string) (string, error) {
+	for i := 0; i < len(code); i++ {
+		if !isDigit(code[i]) {
+			return "", fmt.Errorf("%w: %q contains non-digits", ErrInvalid, code)
+		}
+	}
+	if !strings.HasPrefix(code, "978") && !strings.HasPrefix(code, "979") {
+		return "", fmt.Errorf("%w: ISBN-13 must start with 978 or 979", ErrInvalid)
+	}
+	if want := checkDigit13(code[:12]); code[12] != want {
+		return "", fmt.Errorf("%w: check digit of %s should be %c", ErrInvalid, code, want)
+	}
+	return code, nil
+}
+
+// checkDigit10 computes the mod 11 check character for nine digits.
+func checkDigit10(body string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 computes the EAN-13 check digit for twelve digits.
+func checkDigit13(body string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(body[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// clean strips labels and separators and upper-cases a trailing x.
+func clean(input string) string {
+	s := strings.ToUpper(strings.TrimSpace(input))
+	for _, label := range []string{"ISBN-13", "ISBN-10", "ISBN13", "ISBN10", "ISBN"} {
+		if strings.HasPrefix(s, label) {
+			s = strings.TrimLeft(s[len(label):], ": ")
+			break
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		if r != '-' && r != ' ' && r != '‐' && r != '‑' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
@@ -0,0 +1,39 @@
+package isbn
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for input, want := range map[string]string{
+		"9789799731234":           "9789799731234",
+		"978-979-97312-3-4":       "9789799731234",
+		"ISBN: 0-306-40615-2":     "9780306406157",
+		"080442957x":              "9780804429573",
+		"978979973123452":         "9789799731234",
+		"978979973123450000":      "9789799731234",
+		"978-979-97312-3-4 50000": "9789799731234",
+	} {
+		got, err := Normalize(input)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"978979973123",
+		"9789799731235",
+		"1234567890123",
+		"97897997312345X",
+		"9789799731234ABCDE",
+		"0306406153",
+	} {
+		if got, err := Normalize(input); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Normalize(%q) = %q, %v; want ErrInvalid", input, got, err)
+		}
+	}
+}
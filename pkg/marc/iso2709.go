@@ -0,0 +1,174 @@
+package marc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	subfieldDelimiter = 0x1F
+	fieldTerminator   = 0x1E
+	recordTerminator  = 0x1D
+
+	leaderLength         = 24
+	directoryEntryLength = 12
+	maxFieldLength       = 9999
+	maxRecordLength      = 99999
+)
+
+var (
+	ErrFieldTooLong  = errors.New("marc: field exceeds 9999 bytes")
+	ErrRecordTooLong = errors.New("marc: record exceeds 99999 bytes")
+)
+
+// EncodeISO2709 returns the binary form of the record. The leader's length,
+// base address and encoding (UTF-8) positions are filled in. The directory
+// has room for fields of up to 9999 bytes and records of up to 99999;
+// anything longer is an error rather than a corrupt directory.
+func EncodeISO2709(r *Record) ([]byte, error) {
+	var directory, data bytes.Buffer
+	for _, f := range r.Fields {
+		start := data.Len()
+		if f.IsControl() {
+			data.WriteString(f.Value)
+		} else {
+			data.WriteByte(indicator(f.Ind1))
+			data.WriteByte(indicator(f.Ind2))
+			for _, sf := range f.Subfields {
+				data.WriteByte(subfieldDelimiter)
+				data.WriteByte(sf.Code)
+				data.WriteString(sf.Value)
+			}
+		}
+		data.WriteByte(fieldTerminator)
+
+		if len(f.Tag) != 3 {
+			return nil, fmt.Errorf("marc: invalid tag %q", f.Tag)
+		}
+		if data.Len()-start > maxFieldLength {
+			return nil, fmt.Errorf("%w: %s", ErrFieldTooLong, f.Tag)
+		}
+		if start > maxRecordLength {
+			return nil, ErrRecordTooLong
+		}
+		fmt.Fprintf(&directory, "%s%04d%05d", f.Tag, data.Len()-start, start)
+	}
+	directory.WriteByte(fieldTerminator)
+	data.WriteByte(recordTerminator)
+
+	baseAddress := leaderLength + directory.Len()
+	length := baseAddress + data.Len()
+	if length > maxRecordLength {
+		return nil, ErrRecordTooLong
+	}
+
+	leader := []byte(normalizeLeader(r.Leader))
+	copy(leader[0:5], fmt.Sprintf("%05d", length))
+	leader[9] = 'a'
+	copy(leader[10:12], "22")
+	copy(leader[12:17], fmt.Sprintf("%05d", baseAddress))
+	copy(leader[20:24], "4500")
+
+	out := make([]byte, 0, length)
+	out = append(out, leader...)
+	out = append(out, directory.Bytes()...)
+	out = append(out, data.Bytes()...)
+	return out, nil
+}
+
+// DecodeISO2709 parses one binary record.
+func DecodeISO2709(raw []byte) (*Record, error) {
+	if len(raw) < leaderLength+1 {
+		return nil, errors.New("marc: record too short")
+	}
+	leader := string(raw[:leaderLength])
+	baseAddress, err := strconv.Atoi(leader[12:17])
+	if err != nil || baseAddress <= leaderLength || baseAddress > len(raw) {
+		return nil, fmt.Errorf("marc: invalid base address %q", leader[12:17])
+	}
+
+	record := &Record{Leader: leader}
+	directory := raw[leaderLength : baseAddress-1]
+	if len(directory)%directoryEntryLength != 0 {
+		return nil, errors.New("marc: malformed directory")
+	}
+	data := raw[baseAddress:]
+
+	for i := 0; i < len(directory); i += directoryEntryLength {
+		entry := directory[i : i+directoryEntryLength]
+		tag := string(entry[0:3])
+		length, err1 := strconv.Atoi(string(entry[3:7]))
+		start, err2 := strconv.Atoi(string(entry[7:12]))
+		if err1 != nil || err2 != nil || start+length > len(data) || length < 1 {
+			return nil, fmt.Errorf("marc: malformed directory entry for tag %s", tag)
+		}
+		// Drop the field terminator
+		content := data[start : start+length-1]
+
+		if IsControl(tag) {
+			record.Fields = append(record.Fields, Field{Tag: tag, Value: string(content)})
+			continue
+		}
+
+		field := Field{Tag: tag, Ind1: ' ', Ind2: ' '}
+		if len(content) >= 2 {
+			field.Ind1, field.Ind2 = content[0], content[1]
+			content = content[2:]
+		}
+		for _, part := range bytes.Split(content, []byte{subfieldDelimiter}) {
+			if len(part) == 0 {
+				continue
+			}
+			field.Subfields = append(field.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+		}
+		record.Fields = append(record.Fields, field)
+	}
+	return record, nil
+}
+
+// ISO2709Reader reads consecutive binary records from a stream.
+type ISO2709Reader struct {
+	r *bufio.Reader
+}
+
+func NewISO2709Reader(r io.Reader) *ISO2709Reader {
+	return &ISO2709Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, or io.EOF after the last one.
+func (r *ISO2709Reader) Read() (*Record, error) {
+	for {
+		raw, err := r.r.ReadBytes(recordTerminator)
+		// Skip line breaks some tools add between records
+		raw = bytes.TrimLeft(raw, "\r\n")
+		if len(raw) == 0 {
+			if err == nil {
+				continue
+			}
+			return nil, err
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return DecodeISO2709(raw)
+	}
+}
+
+func indicator(b byte) byte {
+	if b == 0 {
+		return ' '
+	}
+	return b
+}
+
+// normalizeLeader pads or trims a leader to 24 bytes.
+func normalizeLeader(leader string) string {
+	if len(leader) >= leaderLength {
+		return leader[:leaderLength]
+	}
+	return leader + "                        "[:leaderLength-len(leader)]
+}
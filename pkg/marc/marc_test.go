@@ -0,0 +1,176 @@
+package marc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestISO2709RoundTrip(t *testing.T) {
+	data := readTestdata(t, "books.mrc")
+
+	records, err := ReadAll(data)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	var out bytes.Buffer
+	writer, err := NewWriter(&out, FormatISO2709)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(out.Bytes(), data) {
+		t.Errorf("re-encoded records differ from books.mrc\ngot:  %q\nwant: %q", out.Bytes(), data)
+	}
+}
+
+func TestMARCXMLRoundTrip(t *testing.T) {
+	records, err := ReadAll(readTestdata(t, "books.xml"))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3", len(records))
+	}
+
+	var out bytes.Buffer
+	writer, err := NewWriter(&out, FormatXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, record := range records {
+		if err := writer.Write(record); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := ReadAll(out.Bytes())
+	if err != nil {
+		t.Fatalf("ReadAll of written MARCXML: %v", err)
+	}
+	if len(again) != len(records) {
+		t.Fatalf("got %d records back, want %d", len(again), len(records))
+	}
+	for i := range records {
+		if !reflect.DeepEqual(again[i], records[i]) {
+			t.Errorf("record %d changed\ngot:  %+v\nwant: %+v", i+1, again[i], records[i])
+		}
+	}
+}
+
+// The two sample files hold the same records, so both codecs must agree
+// on every field.
+func TestSampleFilesAgree(t *testing.T) {
+	binary, err := ReadAll(readTestdata(t, "books.mrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xmlRecords, err := ReadAll(readTestdata(t, "books.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(binary) != len(xmlRecords) {
+		t.Fatalf("books.mrc has %d records, books.xml %d", len(binary), len(xmlRecords))
+	}
+	for i := range binary {
+		if !reflect.DeepEqual(binary[i].Fields, xmlRecords[i].Fields) {
+			t.Errorf("record %d fields differ\nmrc: %+v\nxml: %+v", i+1, binary[i].Fields, xmlRecords[i].Fields)
+		}
+	}
+}
+
+func TestEncodeISO2709Leader(t *testing.T) {
+	record := NewRecord()
+	record.AddControlField("001", "42")
+	record.AddDataField("245", '1', '0', Subfield{Code: 'a', Value: "Judul"}, Subfield{Code: 'b', Value: ""})
+
+	raw, err := EncodeISO2709(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeISO2709(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.Leader[0:5], "00063"; got != want || len(raw) != 63 {
+		t.Errorf("record length %q (%d bytes), want %q", got, len(raw), want)
+	}
+	if got := decoded.Leader[12:17]; got != "00049" {
+		t.Errorf("base address %q, want 00049", got)
+	}
+	if got := decoded.SubfieldValue('a', "245"); got != "Judul" {
+		t.Errorf("245$a = %q, want Judul", got)
+	}
+	if fields := decoded.FieldsByTag("245"); len(fields) != 1 || len(fields[0].Subfields) != 1 {
+		t.Errorf("empty subfields should be dropped, got %+v", fields)
+	}
+}
+
+func TestEncodeISO2709TooLong(t *testing.T) {
+	record := NewRecord()
+	record.AddControlField("001", "42")
+	record.AddDataField("520", ' ', ' ', Subfield{Code: 'a', Value: strings.Repeat("x", 9995)})
+	if _, err := EncodeISO2709(record); !errors.Is(err, ErrFieldTooLong) {
+		t.Errorf("10000-byte 520: got %v, want ErrFieldTooLong", err)
+	}
+
+	// Just fits: indicators, delimiter and code, and the terminator
+	record.Fields[1].Subfields[0].Value = strings.Repeat("x", 9994)
+	raw, err := EncodeISO2709(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeISO2709(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.SubfieldValue('a', "520"); len(got) != 9994 {
+		t.Errorf("520 $a is %d bytes, want 9994", len(got))
+	}
+
+	for i := 0; i < 10; i++ {
+		record.AddDataField("520", ' ', ' ', Subfield{Code: 'a', Value: strings.Repeat("y", 9994)})
+	}
+	if _, err := EncodeISO2709(record); !errors.Is(err, ErrRecordTooLong) {
+		t.Errorf("110000-byte record: got %v, want ErrRecordTooLong", err)
+	}
+}
+
+func TestDecodeISO2709Malformed(t *testing.T) {
+	for name, raw := range map[string]string{
+		"too short":    "00010nam",
+		"base address": "00030nam a2200999 i 4500\x1e\x1d",
+		"directory":    "00040nam a2200031 i 4500245\x1eabc\x1e\x1d",
+	} {
+		if _, err := DecodeISO2709([]byte(raw)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package marc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// Namespace is the MARCXML schema namespace.
+const Namespace = "http://www.loc.gov/MARC21/slim"
+
+type xmlRecord struct {
+	XMLName       xml.Name          `xml:"record"`
+	Leader        string            `xml:"leader"`
+	ControlFields []xmlControlField `xml:"controlfield"`
+	DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+	Tag   string `xml:"tag,attr"`
+	Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+	Tag       string        `xml:"tag,attr"`
+	Ind1      string        `xml:"ind1,attr"`
+	Ind2      string        `xml:"ind2,attr"`
+	Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+	Code  string `xml:"code,attr"`
+	Value string `xml:",chardata"`
+}
+
+// MarshalXML writes the record as a MARCXML <record> element. Control
+// fields come first, as the schema requires.
+func (r *Record) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	out := xmlRecord{Leader: normalizeLeader(r.Leader)}
+	for _, f := range r.Fields {
+		if f.IsControl() {
+			out.ControlFields = append(out.ControlFields, xmlControlField{Tag: f.Tag, Value: f.Value})
+			continue
+		}
+		df := xmlDataField{
+			Tag:  f.Tag,
+			Ind1: string(indicator(f.Ind1)),
+			Ind2: string(indicator(f.Ind2)),
+		}
+		for _, sf := range f.Subfields {
+			df.Subfields = append(df.Subfields, xmlSubfield{Code: string(sf.Code), Value: sf.Value})
+		}
+		out.DataFields = append(out.DataFields, df)
+	}
+
+	start.Name = xml.Name{Local: "record"}
+	return e.EncodeElement(out, start)
+}
+
+func (r *Record) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var in xmlRecord
+	if err := d.DecodeElement(&in, &start); err != nil {
+		return err
+	}
+
+	r.Leader = normalizeLeader(in.Leader)
+	r.Fields = nil
+	for _, cf := range in.ControlFields {
+		r.Fields = append(r.Fields, Field{Tag: cf.Tag, Value: cf.Value})
+	}
+	for _, df := range in.DataFields {
+		field := Field{Tag: df.Tag, Ind1: firstByte(df.Ind1), Ind2: firstByte(df.Ind2)}
+		for _, sf := range df.Subfields {
+			field.Subfields = append(field.Subfields, Subfield{Code: firstByte(sf.Code), Value: sf.Value})
+		}
+		r.Fields = append(r.Fields, field)
+	}
+	return nil
+}
+
+// ReadXML parses a MARCXML <collection> or a single <record>.
+func ReadXML(data []byte) ([]*Record, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var records []*Record
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "record" {
+			continue
+		}
+		record := &Record{}
+		if err := decoder.DecodeElement(record, &start); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func firstByte(s string) byte {
+	if s == "" {
+		return ' '
+	}
+	return s[0]
+}
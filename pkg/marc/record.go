@@ -0,0 +1,114 @@
+// Package marc reads and writes MARC 21 bibliographic records in ISO 2709
+// (binary) and MARCXML form.
+package marc
+
+import (
+	"strings"
+)
+
+const (
+	FormatISO2709 = "marc"
+	FormatXML     = "marcxml"
+)
+
+// Record is one MARC record. Fields keep their order.
+type Record struct {
+	Leader string
+	Fields []Field
+}
+
+// Field is a control field (tags 001-009), which has only a Value, or a
+// data field with indicators and subfields.
+type Field struct {
+	Tag       string
+	Value     string
+	Ind1      byte
+	Ind2      byte
+	Subfields []Subfield
+}
+
+type Subfield struct {
+	Code  byte
+	Value string
+}
+
+// IsControl tells whether tag is a control field tag.
+func IsControl(tag string) bool {
+	return len(tag) == 3 && strings.HasPrefix(tag, "00")
+}
+
+func (f *Field) IsControl() bool {
+	return IsControl(f.Tag)
+}
+
+// Subfield returns the first value of the subfield with code.
+func (f *Field) Subfield(code byte) string {
+	for _, sf := range f.Subfields {
+		if sf.Code == code {
+			return sf.Value
+		}
+	}
+	return ""
+}
+
+// NewRecord returns a record with a default leader for a UTF-8 encoded
+// book (language material, monograph).
+func NewRecord() *Record {
+	return &Record{Leader: "00000nam a2200000 i 4500"}
+}
+
+func (r *Record) AddControlField(tag, value string) {
+	r.Fields = append(r.Fields, Field{Tag: tag, Value: value})
+}
+
+// AddDataField adds a data field unless every subfield is empty. Empty
+// subfields are dropped.
+func (r *Record) AddDataField(tag string, ind1, ind2 byte, subfields ...Subfield) {
+	kept := make([]Subfield, 0, len(subfields))
+	for _, sf := range subfields {
+		if sf.Value != "" {
+			kept = append(kept, sf)
+		}
+	}
+	if len(kept) == 0 {
+		return
+	}
+	r.Fields = append(r.Fields, Field{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: kept})
+}
+
+// FieldsByTag returns every field with tag.
+func (r *Record) FieldsByTag(tag string) []Field {
+	var fields []Field
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// ControlField returns the value of the first control field with tag.
+func (r *Record) ControlField(tag string) string {
+	for _, f := range r.Fields {
+		if f.Tag == tag {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// SubfieldValue returns the first value of subfield code in any field with
+// one of the tags, trying the tags in order.
+func (r *Record) SubfieldValue(code byte, tags ...string) string {
+	for _, tag := range tags {
+		for _, f := range r.Fields {
+			if f.Tag != tag {
+				continue
+			}
+			if value := f.Subfield(code); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
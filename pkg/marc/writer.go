@@ -0,0 +1,113 @@
+package marc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrUnsupportedFormat = errors.New("marc: unsupported format, use marc or marcxml")
+
+// Writer writes a stream of records in one format.
+type Writer interface {
+	Write(r *Record) error
+	// Close finishes the stream; it does not close the underlying writer.
+	Close() error
+}
+
+// NewWriter returns a writer for format (FormatISO2709 or FormatXML).
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatISO2709:
+		return &iso2709Writer{w: w}, nil
+	case FormatXML:
+		return &xmlWriter{w: w, enc: xml.NewEncoder(w)}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+// ContentType is the media type of format.
+func ContentType(format string) string {
+	if format == FormatXML {
+		return "application/marcxml+xml"
+	}
+	return "application/marc"
+}
+
+// ReadAll parses every record in data, telling MARCXML from ISO 2709 by
+// content.
+func ReadAll(data []byte) ([]*Record, error) {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		return ReadXML(trimmed)
+	}
+
+	reader := NewISO2709Reader(bytes.NewReader(trimmed))
+	var records []*Record
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(records)+1, err)
+		}
+		records = append(records, record)
+	}
+}
+
+type iso2709Writer struct {
+	w io.Writer
+}
+
+func (w *iso2709Writer) Write(r *Record) error {
+	raw, err := EncodeISO2709(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(raw)
+	return err
+}
+
+func (w *iso2709Writer) Close() error {
+	return nil
+}
+
+type xmlWriter struct {
+	w       io.Writer
+	enc     *xml.Encoder
+	started bool
+}
+
+func (w *xmlWriter) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if _, err := io.WriteString(w.w, xml.Header); err != nil {
+		return err
+	}
+	return w.enc.EncodeToken(xml.StartElement{
+		Name: xml.Name{Local: "collection"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: Namespace}},
+	})
+}
+
+func (w *xmlWriter) Write(r *Record) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.enc.Encode(r)
+}
+
+func (w *xmlWriter) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if err := w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "collection"}}); err != nil {
+		return err
+	}
+	return w.enc.Flush()
+}
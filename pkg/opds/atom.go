@@ -0,0 +1,210 @@
+package opds
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+)
+
+// Media types of OPDS 1.2 documents
+const (
+	AtomNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	AtomAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	AtomEntryType       = "application/atom+xml;type=entry;profile=opds-catalog"
+	OpenSearchType      = "application/opensearchdescription+xml"
+)
+
+const (
+	atomNS       = "http://www.w3.org/2005/Atom"
+	dcNS         = "http://purl.org/dc/terms/"
+	opdsNS       = "http://opds-spec.org/2010/catalog"
+	openSearchNS = "http://a9.com/-/spec/opensearch/1.1/"
+	threadNS     = "http://purl.org/syndication/thread/1.0"
+)
+
+type atomFeed struct {
+	XMLName         xml.Name `xml:"feed"`
+	Xmlns           string   `xml:"xmlns,attr"`
+	XmlnsDC         string   `xml:"xmlns:dc,attr"`
+	XmlnsOPDS       string   `xml:"xmlns:opds,attr"`
+	XmlnsOpenSearch string   `xml:"xmlns:opensearch,attr"`
+	XmlnsThread     string   `xml:"xmlns:thr,attr"`
+
+	ID           string      `xml:"id"`
+	Title        string      `xml:"title"`
+	Updated      string      `xml:"updated"`
+	Author       *atomPerson `xml:"author,omitempty"`
+	Links        []atomLink  `xml:"link"`
+	TotalResults string      `xml:"opensearch:totalResults,omitempty"`
+	ItemsPerPage string      `xml:"opensearch:itemsPerPage,omitempty"`
+	StartIndex   string      `xml:"opensearch:startIndex,omitempty"`
+	Entries      []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	XMLName   xml.Name `xml:"entry"`
+	Xmlns     string   `xml:"xmlns,attr,omitempty"`
+	XmlnsDC   string   `xml:"xmlns:dc,attr,omitempty"`
+	XmlnsOPDS string   `xml:"xmlns:opds,attr,omitempty"`
+
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Identifier string         `xml:"dc:identifier,omitempty"`
+	Publisher  string         `xml:"dc:publisher,omitempty"`
+	Language   string         `xml:"dc:language,omitempty"`
+	Issued     string         `xml:"dc:issued,omitempty"`
+	Extent     string         `xml:"dc:extent,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Links      []atomLink     `xml:"link"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+	Count string `xml:"thr:count,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr,omitempty"`
+	Scheme string `xml:"scheme,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// EncodeAtom writes feed as an OPDS 1.2 catalog feed.
+func EncodeAtom(feed *Feed) ([]byte, error) {
+	doc := atomFeed{
+		Xmlns:           atomNS,
+		XmlnsDC:         dcNS,
+		XmlnsOPDS:       opdsNS,
+		XmlnsOpenSearch: openSearchNS,
+		XmlnsThread:     threadNS,
+		ID:              feed.ID,
+		Title:           feed.Title,
+		Updated:         atomTime(feed.Updated),
+		Links:           atomLinks(feed.Links),
+		Entries:         []atomEntry{},
+	}
+	// Navigation entries have no author of their own, so the feed needs one
+	if feed.Author != "" {
+		doc.Author = &atomPerson{Name: feed.Author}
+	}
+	if feed.ItemsPerPage > 0 {
+		doc.TotalResults = strconv.FormatInt(feed.TotalResults, 10)
+		doc.ItemsPerPage = strconv.Itoa(feed.ItemsPerPage)
+		doc.StartIndex = strconv.Itoa(feed.StartIndex)
+	}
+
+	for _, item := range feed.Navigation {
+		link := atomLink{Rel: RelSubsection, Href: item.Href, Type: atomType(item.Type), Title: item.Title}
+		if item.Count > 0 {
+			link.Count = strconv.FormatInt(item.Count, 10)
+		}
+		doc.Entries = append(doc.Entries, atomEntry{
+			ID:      item.ID,
+			Title:   item.Title,
+			Updated: atomTime(item.Updated),
+			Content: &atomText{Type: "text", Text: item.Summary},
+			Links:   []atomLink{link},
+		})
+	}
+	for i := range feed.Publications {
+		doc.Entries = append(doc.Entries, atomPublication(&feed.Publications[i]))
+	}
+
+	return marshalXML(doc)
+}
+
+// EncodeAtomEntry writes publication as a standalone OPDS 1.2 entry.
+func EncodeAtomEntry(publication *Publication) ([]byte, error) {
+	entry := atomPublication(publication)
+	entry.Xmlns = atomNS
+	entry.XmlnsDC = dcNS
+	entry.XmlnsOPDS = opdsNS
+	return marshalXML(entry)
+}
+
+func atomPublication(publication *Publication) atomEntry {
+	entry := atomEntry{
+		ID:         publication.ID,
+		Title:      publication.Title,
+		Updated:    atomTime(publication.Updated),
+		Identifier: publication.Identifier,
+		Publisher:  publication.Publisher,
+		Language:   publication.Language,
+		Links:      atomLinks(publication.Links),
+	}
+	for _, name := range publication.Authors {
+		entry.Authors = append(entry.Authors, atomPerson{Name: name})
+	}
+	if publication.Issued > 0 {
+		entry.Issued = strconv.Itoa(publication.Issued)
+	}
+	if publication.Pages > 0 {
+		entry.Extent = strconv.Itoa(publication.Pages) + " pages"
+	}
+	for _, subject := range publication.Subjects {
+		entry.Categories = append(entry.Categories, atomCategory{
+			Term:   subject.Term,
+			Label:  subject.Label,
+			Scheme: subject.Scheme,
+		})
+	}
+	if publication.Summary != "" {
+		entry.Summary = &atomText{Type: "text", Text: publication.Summary}
+	}
+	return entry
+}
+
+func atomLinks(links []Link) []atomLink {
+	result := make([]atomLink, 0, len(links))
+	for _, link := range links {
+		result = append(result, atomLink{
+			Rel:   link.Rel,
+			Href:  link.Href,
+			Type:  atomType(link.Type),
+			Title: link.Title,
+		})
+	}
+	return result
+}
+
+func atomType(linkType string) string {
+	switch linkType {
+	case TypeNavigation:
+		return AtomNavigationType
+	case TypeAcquisition:
+		return AtomAcquisitionType
+	case TypeEntry:
+		return AtomEntryType
+	case TypeSearch:
+		return OpenSearchType
+	}
+	return linkType
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,190 @@
+package opds
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Media types of OPDS 2.0 documents
+const (
+	JSONType            = "application/opds+json"
+	JSONPublicationType = "application/opds-publication+json"
+)
+
+const schemaBook = "http://schema.org/Book"
+
+type jsonFeed struct {
+	Metadata     jsonFeedMetadata   `json:"metadata"`
+	Links        []jsonLink         `json:"links"`
+	Navigation   *[]jsonLink        `json:"navigation,omitempty"`
+	Publications *[]jsonPublication `json:"publications,omitempty"`
+}
+
+type jsonFeedMetadata struct {
+	Title         string     `json:"title"`
+	Modified      *time.Time `json:"modified,omitempty"`
+	NumberOfItems *int64     `json:"numberOfItems,omitempty"`
+	ItemsPerPage  int        `json:"itemsPerPage,omitempty"`
+	CurrentPage   int        `json:"currentPage,omitempty"`
+}
+
+type jsonLink struct {
+	Rel        string          `json:"rel,omitempty"`
+	Href       string          `json:"href"`
+	Type       string          `json:"type,omitempty"`
+	Title      string          `json:"title,omitempty"`
+	Templated  bool            `json:"templated,omitempty"`
+	Width      int             `json:"width,omitempty"`
+	Height     int             `json:"height,omitempty"`
+	Properties *jsonProperties `json:"properties,omitempty"`
+}
+
+type jsonProperties struct {
+	NumberOfItems int64 `json:"numberOfItems,omitempty"`
+}
+
+type jsonPublication struct {
+	Metadata jsonPublicationMetadata `json:"metadata"`
+	Links    []jsonLink              `json:"links"`
+	Images   []jsonLink              `json:"images,omitempty"`
+}
+
+type jsonPublicationMetadata struct {
+	Type          string        `json:"@type"`
+	Identifier    string        `json:"identifier,omitempty"`
+	Title         string        `json:"title"`
+	Author        []jsonName    `json:"author,omitempty"`
+	Publisher     string        `json:"publisher,omitempty"`
+	Language      string        `json:"language,omitempty"`
+	Published     string        `json:"published,omitempty"`
+	Modified      *time.Time    `json:"modified,omitempty"`
+	Description   string        `json:"description,omitempty"`
+	NumberOfPages int           `json:"numberOfPages,omitempty"`
+	Subject       []jsonSubject `json:"subject,omitempty"`
+}
+
+type jsonName struct {
+	Name string `json:"name"`
+}
+
+type jsonSubject struct {
+	Name   string `json:"name"`
+	Code   string `json:"code,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// EncodeJSON writes feed as an OPDS 2.0 catalog feed.
+func EncodeJSON(feed *Feed) ([]byte, error) {
+	doc := jsonFeed{
+		Metadata: jsonFeedMetadata{Title: feed.Title, Modified: jsonTime(feed.Updated)},
+		Links:    jsonLinks(feed.Links),
+	}
+	if feed.ItemsPerPage > 0 {
+		total := feed.TotalResults
+		doc.Metadata.NumberOfItems = &total
+		doc.Metadata.ItemsPerPage = feed.ItemsPerPage
+		doc.Metadata.CurrentPage = (feed.StartIndex-1)/feed.ItemsPerPage + 1
+	}
+
+	// A feed needs at least one collection, even an empty one
+	if feed.Kind == TypeNavigation {
+		navigation := make([]jsonLink, 0, len(feed.Navigation))
+		for _, item := range feed.Navigation {
+			link := jsonLink{Href: item.Href, Type: jsonType(item.Type), Title: item.Title, Rel: RelSubsection}
+			if item.Count > 0 {
+				link.Properties = &jsonProperties{NumberOfItems: item.Count}
+			}
+			navigation = append(navigation, link)
+		}
+		doc.Navigation = &navigation
+	} else {
+		publications := make([]jsonPublication, 0, len(feed.Publications))
+		for i := range feed.Publications {
+			publications = append(publications, jsonPublicationDoc(&feed.Publications[i]))
+		}
+		doc.Publications = &publications
+	}
+
+	return json.Marshal(doc)
+}
+
+// EncodePublicationJSON writes publication as a standalone OPDS 2.0
+// publication.
+func EncodePublicationJSON(publication *Publication) ([]byte, error) {
+	return json.Marshal(jsonPublicationDoc(publication))
+}
+
+func jsonPublicationDoc(publication *Publication) jsonPublication {
+	doc := jsonPublication{
+		Metadata: jsonPublicationMetadata{
+			Type:          schemaBook,
+			Identifier:    publication.ID,
+			Title:         publication.Title,
+			Publisher:     publication.Publisher,
+			Language:      publication.Language,
+			Modified:      jsonTime(publication.Updated),
+			Description:   publication.Summary,
+			NumberOfPages: publication.Pages,
+		},
+		Links: []jsonLink{},
+	}
+	for _, name := range publication.Authors {
+		doc.Metadata.Author = append(doc.Metadata.Author, jsonName{Name: name})
+	}
+	if publication.Issued > 0 {
+		doc.Metadata.Published = strconv.Itoa(publication.Issued)
+	}
+	for _, subject := range publication.Subjects {
+		doc.Metadata.Subject = append(doc.Metadata.Subject, jsonSubject{
+			Name:   subject.Label,
+			Code:   subject.Term,
+			Scheme: subject.Scheme,
+		})
+	}
+
+	// Covers go in images, everything else in links
+	for _, link := range jsonLinks(publication.Links) {
+		if isImage(link.Rel) {
+			link.Rel = ""
+			doc.Images = append(doc.Images, link)
+			continue
+		}
+		doc.Links = append(doc.Links, link)
+	}
+	return doc
+}
+
+func jsonLinks(links []Link) []jsonLink {
+	result := make([]jsonLink, 0, len(links))
+	for _, link := range links {
+		result = append(result, jsonLink{
+			Rel:       link.Rel,
+			Href:      link.Href,
+			Type:      jsonType(link.Type),
+			Title:     link.Title,
+			Templated: link.Templated,
+			Width:     link.Width,
+			Height:    link.Height,
+		})
+	}
+	return result
+}
+
+func jsonType(linkType string) string {
+	switch linkType {
+	case TypeNavigation, TypeAcquisition, TypeSearch:
+		return JSONType
+	case TypeEntry:
+		return JSONPublicationType
+	}
+	return linkType
+}
+
+func jsonTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
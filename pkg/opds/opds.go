@@ -0,0 +1,101 @@
+// Package opds builds OPDS catalog feeds for e-reader apps, encoded as
+// OPDS 1.2 (Atom) or OPDS 2.0 (JSON).
+package opds
+
+import "time"
+
+// Link types that depend on the encoding. Any other link type is a media
+// type and is written as is.
+const (
+	TypeNavigation  = "navigation"
+	TypeAcquisition = "acquisition"
+	TypeEntry       = "entry"
+	TypeSearch      = "search"
+)
+
+// Link relations
+const (
+	RelSelf        = "self"
+	RelStart       = "start"
+	RelUp          = "up"
+	RelSearch      = "search"
+	RelNext        = "next"
+	RelPrevious    = "previous"
+	RelFirst       = "first"
+	RelLast        = "last"
+	RelAlternate   = "alternate"
+	RelSubsection  = "subsection"
+	RelNew         = "http://opds-spec.org/sort/new"
+	RelImage       = "http://opds-spec.org/image"
+	RelThumbnail   = "http://opds-spec.org/image/thumbnail"
+	RelAcquisition = "http://opds-spec.org/acquisition"
+	RelBorrow      = "http://opds-spec.org/acquisition/borrow"
+	RelOpenAccess  = "http://opds-spec.org/acquisition/open-access"
+)
+
+// Feed is either a navigation feed, listing other feeds, or an acquisition
+// feed, listing publications.
+type Feed struct {
+	ID      string
+	Title   string
+	Author  string
+	Updated time.Time
+	Kind    string // TypeNavigation or TypeAcquisition
+	Links   []Link
+
+	// Set on paged feeds; StartIndex counts from 1
+	TotalResults int64
+	ItemsPerPage int
+	StartIndex   int
+
+	Navigation   []NavigationItem
+	Publications []Publication
+}
+
+type Link struct {
+	Rel       string
+	Href      string
+	Type      string
+	Title     string
+	Templated bool
+
+	// Image size in pixels, when known
+	Width  int
+	Height int
+}
+
+// NavigationItem points at another feed.
+type NavigationItem struct {
+	ID      string
+	Title   string
+	Summary string
+	Href    string
+	Type    string // TypeNavigation or TypeAcquisition
+	Count   int64
+	Updated time.Time
+}
+
+type Publication struct {
+	ID         string // e.g. urn:isbn:9786020332956
+	Identifier string
+	Title      string
+	Authors    []string
+	Publisher  string
+	Language   string // BCP 47 code
+	Issued     int    // year
+	Summary    string
+	Pages      int
+	Updated    time.Time
+	Subjects   []Subject
+	Links      []Link
+}
+
+type Subject struct {
+	Term   string
+	Label  string
+	Scheme string
+}
+
+func isImage(rel string) bool {
+	return rel == RelImage || rel == RelThumbnail
+}
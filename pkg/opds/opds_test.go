@@ -0,0 +1,268 @@
+package opds
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+var testUpdated = time.Date(2024, 3, 1, 8, 30, 0, 0, time.FixedZone("WIB", 7*60*60))
+
+func testFeed() *Feed {
+	return &Feed{
+		ID:      "https://library.example/api/v1/opds/new",
+		Title:   "New arrivals",
+		Author:  "Perpustakaan Kota",
+		Updated: testUpdated,
+		Kind:    TypeAcquisition,
+		Links: []Link{
+			{Rel: RelStart, Href: "https://library.example/api/v1/opds", Type: TypeNavigation},
+			{Rel: RelSelf, Href: "https://library.example/api/v1/opds/new?limit=2&page=2", Type: TypeAcquisition},
+			{Rel: RelPrevious, Href: "https://library.example/api/v1/opds/new?limit=2&page=1", Type: TypeAcquisition},
+			{Rel: RelNext, Href: "https://library.example/api/v1/opds/new?limit=2&page=3", Type: TypeAcquisition},
+		},
+		TotalResults: 5,
+		ItemsPerPage: 2,
+		StartIndex:   3,
+		Publications: []Publication{{
+			ID:         "urn:isbn:9789799731234",
+			Identifier: "urn:isbn:9789799731234",
+			Title:      "Bumi manusia",
+			Authors:    []string{"Pramoedya Ananta Toer"},
+			Language:   "id",
+			Issued:     1980,
+			Pages:      535,
+			Updated:    testUpdated,
+			Subjects:   []Subject{{Term: "Novel", Label: "Novel"}},
+			Links: []Link{
+				{Rel: RelAlternate, Href: "https://library.example/api/v1/opds/books/1", Type: TypeEntry},
+				{Rel: RelThumbnail, Href: "https://library.example/api/v1/books/1/cover?size=small", Type: "image/jpeg", Width: 120, Height: 180},
+				{Rel: RelBorrow, Href: "https://library.example/api/v1/digital/books/1/checkout", Type: "application/epub+zip", Title: "Borrow epub"},
+			},
+		}},
+	}
+}
+
+// Atom documents are read back with encoding/xml, which matches elements
+// by local name whatever their prefix.
+type atomDoc struct {
+	ID           string        `xml:"id"`
+	Title        string        `xml:"title"`
+	Updated      string        `xml:"updated"`
+	Links        []atomDocLink `xml:"link"`
+	TotalResults string        `xml:"totalResults"`
+	ItemsPerPage string        `xml:"itemsPerPage"`
+	StartIndex   string        `xml:"startIndex"`
+	Entries      []struct {
+		ID         string        `xml:"id"`
+		Title      string        `xml:"title"`
+		Authors    []string      `xml:"author>name"`
+		Identifier string        `xml:"identifier"`
+		Language   string        `xml:"language"`
+		Issued     string        `xml:"issued"`
+		Links      []atomDocLink `xml:"link"`
+	} `xml:"entry"`
+}
+
+type atomDocLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr"`
+}
+
+func findAtomLink(links []atomDocLink, rel string) *atomDocLink {
+	for i := range links {
+		if links[i].Rel == rel {
+			return &links[i]
+		}
+	}
+	return nil
+}
+
+func TestEncodeAtomAcquisitionFeed(t *testing.T) {
+	data, err := EncodeAtom(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc atomDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, data)
+	}
+
+	if doc.ID != "https://library.example/api/v1/opds/new" || doc.Title != "New arrivals" {
+		t.Errorf("id %q, title %q", doc.ID, doc.Title)
+	}
+	if doc.Updated != "2024-03-01T01:30:00Z" {
+		t.Errorf("updated = %q, want UTC RFC 3339", doc.Updated)
+	}
+	if doc.TotalResults != "5" || doc.ItemsPerPage != "2" || doc.StartIndex != "3" {
+		t.Errorf("opensearch totals %q/%q/%q", doc.TotalResults, doc.ItemsPerPage, doc.StartIndex)
+	}
+
+	for rel, want := range map[string]atomDocLink{
+		RelStart:    {Href: "https://library.example/api/v1/opds", Type: AtomNavigationType},
+		RelSelf:     {Href: "https://library.example/api/v1/opds/new?limit=2&page=2", Type: AtomAcquisitionType},
+		RelPrevious: {Href: "https://library.example/api/v1/opds/new?limit=2&page=1", Type: AtomAcquisitionType},
+		RelNext:     {Href: "https://library.example/api/v1/opds/new?limit=2&page=3", Type: AtomAcquisitionType},
+	} {
+		link := findAtomLink(doc.Links, rel)
+		if link == nil {
+			t.Errorf("no %s link", rel)
+			continue
+		}
+		if link.Href != want.Href || link.Type != want.Type {
+			t.Errorf("%s link = %+v, want href %q type %q", rel, *link, want.Href, want.Type)
+		}
+	}
+
+	if len(doc.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(doc.Entries))
+	}
+	entry := doc.Entries[0]
+	if entry.ID != "urn:isbn:9789799731234" || entry.Identifier != "urn:isbn:9789799731234" {
+		t.Errorf("entry id %q, identifier %q", entry.ID, entry.Identifier)
+	}
+	if len(entry.Authors) != 1 || entry.Authors[0] != "Pramoedya Ananta Toer" || entry.Language != "id" || entry.Issued != "1980" {
+		t.Errorf("entry metadata %+v", entry)
+	}
+	if link := findAtomLink(entry.Links, RelBorrow); link == nil || link.Type != "application/epub+zip" {
+		t.Errorf("borrow link = %+v", link)
+	}
+	if link := findAtomLink(entry.Links, RelAlternate); link == nil || link.Type != AtomEntryType {
+		t.Errorf("alternate link = %+v", link)
+	}
+}
+
+func TestEncodeAtomNavigationFeed(t *testing.T) {
+	data, err := EncodeAtom(&Feed{
+		ID:      "https://library.example/api/v1/opds",
+		Title:   "Perpustakaan Kota",
+		Author:  "Perpustakaan Kota",
+		Updated: testUpdated,
+		Kind:    TypeNavigation,
+		Links:   []Link{{Rel: RelSearch, Href: "https://library.example/api/v1/opds/opensearch.xml", Type: TypeSearch}},
+		Navigation: []NavigationItem{{
+			ID:    "https://library.example/api/v1/opds/categories/8",
+			Title: "Sastra",
+			Href:  "https://library.example/api/v1/opds/categories/8",
+			Type:  TypeNavigation,
+			Count: 12,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc atomDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if link := findAtomLink(doc.Links, RelSearch); link == nil || link.Type != OpenSearchType {
+		t.Errorf("search link = %+v", link)
+	}
+	if len(doc.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(doc.Entries))
+	}
+	link := findAtomLink(doc.Entries[0].Links, RelSubsection)
+	if link == nil || link.Type != AtomNavigationType {
+		t.Errorf("subsection link = %+v", link)
+	}
+}
+
+func TestEncodeAtomEntry(t *testing.T) {
+	feed := testFeed()
+	data, err := EncodeAtomEntry(&feed.Publications[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry struct {
+		XMLName xml.Name
+		Title   string `xml:"title"`
+	}
+	if err := xml.Unmarshal(data, &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.XMLName.Space != atomNS || entry.XMLName.Local != "entry" || entry.Title != "Bumi manusia" {
+		t.Errorf("entry %+v", entry)
+	}
+}
+
+func TestEncodeJSONAcquisitionFeed(t *testing.T) {
+	data, err := EncodeJSON(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Metadata struct {
+			Title         string    `json:"title"`
+			Modified      time.Time `json:"modified"`
+			NumberOfItems int64     `json:"numberOfItems"`
+			ItemsPerPage  int       `json:"itemsPerPage"`
+			CurrentPage   int       `json:"currentPage"`
+		} `json:"metadata"`
+		Links        []jsonLink        `json:"links"`
+		Navigation   []jsonLink        `json:"navigation"`
+		Publications []jsonPublication `json:"publications"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, data)
+	}
+
+	if doc.Metadata.Title != "New arrivals" || !doc.Metadata.Modified.Equal(testUpdated) {
+		t.Errorf("metadata %+v", doc.Metadata)
+	}
+	if doc.Metadata.NumberOfItems != 5 || doc.Metadata.ItemsPerPage != 2 || doc.Metadata.CurrentPage != 2 {
+		t.Errorf("paging metadata %+v", doc.Metadata)
+	}
+
+	links := map[string]jsonLink{}
+	for _, link := range doc.Links {
+		links[link.Rel] = link
+	}
+	for _, rel := range []string{RelStart, RelSelf, RelPrevious, RelNext} {
+		if link, ok := links[rel]; !ok || link.Type != JSONType {
+			t.Errorf("%s link = %+v", rel, link)
+		}
+	}
+	if links[RelNext].Href != "https://library.example/api/v1/opds/new?limit=2&page=3" {
+		t.Errorf("next href = %q", links[RelNext].Href)
+	}
+
+	if doc.Navigation != nil {
+		t.Errorf("acquisition feed has navigation %+v", doc.Navigation)
+	}
+	if len(doc.Publications) != 1 {
+		t.Fatalf("got %d publications, want 1", len(doc.Publications))
+	}
+	publication := doc.Publications[0]
+	if publication.Metadata.Type != schemaBook || publication.Metadata.Identifier != "urn:isbn:9789799731234" ||
+		publication.Metadata.Published != "1980" || publication.Metadata.NumberOfPages != 535 {
+		t.Errorf("publication metadata %+v", publication.Metadata)
+	}
+	if len(publication.Images) != 1 || publication.Images[0].Rel != "" || publication.Images[0].Width != 120 {
+		t.Errorf("images %+v", publication.Images)
+	}
+	if len(publication.Links) != 2 || publication.Links[0].Type != JSONPublicationType || publication.Links[1].Rel != RelBorrow {
+		t.Errorf("links %+v", publication.Links)
+	}
+}
+
+// Feeds need a collection even when it is empty.
+func TestEncodeJSONEmptyCollections(t *testing.T) {
+	for kind, collection := range map[string]string{TypeNavigation: "navigation", TypeAcquisition: "publications"} {
+		data, err := EncodeJSON(&Feed{Title: "Kosong", Kind: kind})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if string(doc[collection]) != "[]" {
+			t.Errorf("%s feed: %s = %s, want []", kind, collection, doc[collection])
+		}
+		if string(doc["links"]) != "[]" {
+			t.Errorf("%s feed: links = %s, want []", kind, doc["links"])
+		}
+	}
+}
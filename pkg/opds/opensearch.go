@@ -0,0 +1,32 @@
+package opds
+
+import "encoding/xml"
+
+type openSearchDescription struct {
+	XMLName        xml.Name        `xml:"OpenSearchDescription"`
+	Xmlns          string          `xml:"xmlns,attr"`
+	ShortName      string          `xml:"ShortName"`
+	Description    string          `xml:"Description"`
+	InputEncoding  string          `xml:"InputEncoding"`
+	OutputEncoding string          `xml:"OutputEncoding"`
+	URLs           []openSearchURL `xml:"Url"`
+}
+
+type openSearchURL struct {
+	Type     string `xml:"type,attr"`
+	Template string `xml:"template,attr"`
+}
+
+// EncodeOpenSearch writes an OpenSearch description whose template holds
+// {searchTerms}, and optionally {startPage?}, and returns OPDS 1.2
+// acquisition feeds.
+func EncodeOpenSearch(shortName, description, template string) ([]byte, error) {
+	return marshalXML(openSearchDescription{
+		Xmlns:          openSearchNS,
+		ShortName:      shortName,
+		Description:    description,
+		InputEncoding:  "UTF-8",
+		OutputEncoding: "UTF-8",
+		URLs:           []openSearchURL{{Type: AtomAcquisitionType, Template: template}},
+	})
+}
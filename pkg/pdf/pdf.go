@@ -0,0 +1,309 @@
+// Package pdf writes plain text documents, such as letters and simple
+// tables, as A4 PDF pages set in the standard Helvetica fonts.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+const ContentType = "application/pdf"
+
+// A4 in points, with 2 cm margins
+const (
+	pageWidth  = 595.28
+	pageHeight = 841.89
+	margin     = 56.69
+
+	// Width of the text area
+	ContentWidth = pageWidth - 2*margin
+)
+
+// Line height relative to the font size
+const leading = 1.4
+
+// Column of a table row.
+type Column struct {
+	Width float64
+	Right bool
+}
+
+// Document lays text out from the top of the first page down, starting a
+// new page when one is full.
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.newPage()
+	return d
+}
+
+// Text writes a paragraph, wrapped to the width of the page. Newlines
+// start new lines.
+func (d *Document) Text(font Font, size float64, text string) {
+	for _, paragraph := range strings.Split(text, "\n") {
+		lines := wrap(font, size, paragraph, ContentWidth)
+		if len(lines) == 0 {
+			d.Space(size * leading)
+			continue
+		}
+		for _, line := range lines {
+			d.line(size)
+			d.show(font, size, margin, line)
+		}
+	}
+}
+
+// TextRight writes a single line against the right margin.
+func (d *Document) TextRight(font Font, size float64, text string) {
+	d.line(size)
+	d.show(font, size, pageWidth-margin-Width(font, size, text), text)
+}
+
+// Row writes one line of a table. Cells too wide for their column are
+// shortened; columns left over get no cell.
+func (d *Document) Row(font Font, size float64, columns []Column, cells ...string) {
+	d.line(size)
+	x := margin
+	for i, column := range columns {
+		if i >= len(cells) {
+			break
+		}
+		cell := fit(font, size, cells[i], column.Width-size/2)
+		left := x
+		if column.Right {
+			left = x + column.Width - size/2 - Width(font, size, cell)
+		}
+		d.show(font, size, left, cell)
+		x += column.Width
+	}
+}
+
+// Rule draws a thin line across the page.
+func (d *Document) Rule() {
+	d.Space(4)
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, d.y, pageWidth-margin, d.y)
+	d.Space(4)
+}
+
+// Space moves down by the given number of points.
+func (d *Document) Space(points float64) {
+	if d.y-points < margin {
+		d.newPage()
+		return
+	}
+	d.y -= points
+}
+
+// Bytes renders the document.
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catalog, 2 page tree, 3 and 4 fonts, 5 info, then a page and
+	// its content per page
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /CreationDate (D:%s) >>", escape(d.title), time.Now().UTC().Format("20060102150405Z")))
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, firstPage+2*i+1))
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		zw.Write(content.Bytes())
+		zw.Close()
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream",
+			compressed.Len(), compressed.Bytes()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// line moves down to the baseline of the next line of text.
+func (d *Document) line(size float64) {
+	height := size * leading
+	if d.y-height < margin {
+		d.newPage()
+	}
+	d.y -= height
+}
+
+func (d *Document) show(font Font, size, x float64, text string) {
+	fmt.Fprintf(d.page(), "BT /F%d %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font+1, size, x, d.y, escape(text))
+}
+
+// Width is the width of text in points.
+func Width(font Font, size float64, text string) float64 {
+	widths := &helvetica
+	if font == Bold {
+		widths = &helveticaBold
+	}
+	total := 0
+	for _, b := range encode(text) {
+		switch {
+		case b >= 32 && b < 127:
+			total += widths[b-32]
+		case punctuationWidths[b] != 0:
+			total += punctuationWidths[b]
+		default:
+			// Close enough for accented letters
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// wrap breaks text into lines no wider than width, splitting words only
+// when a single word does not fit.
+func wrap(font Font, size float64, text string, width float64) []string {
+	var lines []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if Width(font, size, candidate) <= width {
+			current = candidate
+			continue
+		}
+		if current != "" {
+			lines = append(lines, current)
+		}
+		for Width(font, size, word) > width {
+			runes := []rune(word)
+			cut := len(runes) - 1
+			for cut > 1 && Width(font, size, string(runes[:cut])) > width {
+				cut--
+			}
+			lines = append(lines, string(runes[:cut]))
+			word = string(runes[cut:])
+		}
+		current = word
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// fit shortens text to width, marking the cut with an ellipsis.
+func fit(font Font, size float64, text string, width float64) string {
+	if Width(font, size, text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if candidate := string(runes) + "…"; Width(font, size, candidate) <= width {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// encode converts text to the Windows-1252 bytes the standard fonts
+// expect. Characters outside it are printed as question marks.
+func encode(text string) []byte {
+	out := make([]byte, 0, len(text))
+	for _, r := range text {
+		b, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			b = '?'
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+// escape encodes text as the body of a PDF string literal.
+func escape(text string) string {
+	var b strings.Builder
+	for _, c := range encode(text) {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 32 || c > 126:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Advance widths of characters 32 to 126, in thousandths of the font size
+var helvetica = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// Widths of Windows-1252 punctuation that differs much from a letter,
+// the same in both weights
+var punctuationWidths = map[byte]int{
+	0x85: 1000,                                 // ellipsis
+	0x91: 222, 0x92: 222, 0x93: 333, 0x94: 333, // curly quotes
+	0x95: 350,  // bullet
+	0x97: 1000, // em dash
+}
+
+var helveticaBold = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
@@ -0,0 +1,187 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalid is wrapped by every error caused by a bad query string, so
+// handlers can answer with 400 instead of 500.
+var ErrInvalid = errors.New("invalid query")
+
+// Parameters with a fixed meaning; everything else is treated as a filter.
+var reserved = map[string]bool{
+	"page":   true,
+	"limit":  true,
+	"cursor": true,
+	"sort":   true,
+	"q":      true,
+	"format": true,
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor marks the last row of the previous page for keyset pagination.
+type Cursor struct {
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// Spec describes which page of a list to return, in which order and with
+// which filters. A nil *Spec means "every row, default order".
+type Spec struct {
+	Page    int
+	Limit   int
+	Cursor  *Cursor
+	Sort    []SortField
+	Filters map[string]string
+}
+
+// Page is the pagination metadata returned with a list.
+type Page struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// FromRequest builds a Spec from ?page=&limit=&cursor=&sort=-due_date,id and
+// any other query parameters, which become filters.
+func FromRequest(c *fiber.Ctx) (*Spec, error) {
+	spec := &Spec{
+		Page:    1,
+		Limit:   DefaultLimit,
+		Filters: map[string]string{},
+	}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("%w: page must be a positive number", ErrInvalid)
+		}
+		spec.Page = page
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("%w: limit must be a positive number", ErrInvalid)
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+		spec.Limit = limit
+	}
+
+	if value := c.Query("sort"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			desc := strings.HasPrefix(field, "-")
+			spec.Sort = append(spec.Sort, SortField{Field: strings.TrimPrefix(field, "-"), Desc: desc})
+		}
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := DecodeCursor(value)
+		if err != nil {
+			return nil, err
+		}
+		spec.Cursor = cursor
+	}
+
+	for key, value := range c.Queries() {
+		if !reserved[key] && value != "" {
+			spec.Filters[key] = value
+		}
+	}
+
+	return spec, nil
+}
+
+func EncodeCursor(cursor *Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	}
+	return &cursor, nil
+}
+
+// Values splits a comma separated filter value.
+func Values(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Unpaged returns a copy of the spec that keeps filters and sort order but
+// returns every row.
+func (s *Spec) Unpaged() *Spec {
+	if s == nil {
+		return nil
+	}
+	unpaged := *s
+	unpaged.Page = 1
+	unpaged.Limit = 0
+	unpaged.Cursor = nil
+	return &unpaged
+}
+
+// Slice pages items that were already ordered in memory, e.g. by relevance.
+func Slice[T any](items []T, spec *Spec) ([]T, *Page, error) {
+	if spec == nil || spec.Limit <= 0 {
+		return items, &Page{Limit: len(items), Total: int64(len(items))}, nil
+	}
+	if spec.Cursor != nil {
+		return nil, nil, fmt.Errorf("%w: cursor pagination requires a sort field", ErrInvalid)
+	}
+
+	total := len(items)
+	start := (spec.Page - 1) * spec.Limit
+	if start > total {
+		start = total
+	}
+	end := start + spec.Limit
+	if end > total {
+		end = total
+	}
+
+	return items[start:end], &Page{
+		Page:       spec.Page,
+		Limit:      spec.Limit,
+		Total:      int64(total),
+		TotalPages: (total + spec.Limit - 1) / spec.Limit,
+		HasMore:    end < total,
+	}, nil
+}
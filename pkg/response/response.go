@@ -1,11 +1,16 @@
 package response
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yooerizkilab/library-system/pkg/query"
+)
 
 type Response struct {
 	Status  string      `json:"status"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
+	Meta    *query.Page `json:"meta,omitempty"`
+	Facets  interface{} `json:"facets,omitempty"`
 	Error   interface{} `json:"error,omitempty"`
 }
 
@@ -17,6 +22,25 @@ func Success(c *fiber.Ctx, message string, data interface{}) error {
 	})
 }
 
+func Paginated(c *fiber.Ctx, message string, data interface{}, meta *query.Page) error {
+	return c.Status(fiber.StatusOK).JSON(Response{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+	})
+}
+
+func Faceted(c *fiber.Ctx, message string, data interface{}, meta *query.Page, facets interface{}) error {
+	return c.Status(fiber.StatusOK).JSON(Response{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+		Meta:    meta,
+		Facets:  facets,
+	})
+}
+
 func Created(c *fiber.Ctx, message string, data interface{}) error {
 	return c.Status(fiber.StatusCreated).JSON(Response{
 		Status:  "success",
@@ -25,6 +49,14 @@ func Created(c *fiber.Ctx, message string, data interface{}) error {
 	})
 }
 
+func Accepted(c *fiber.Ctx, message string, data interface{}) error {
+	return c.Status(fiber.StatusAccepted).JSON(Response{
+		Status:  "success",
+		Message: message,
+		Data:    data,
+	})
+}
+
 func BadRequest(c *fiber.Ctx, message string, err interface{}) error {
 	return c.Status(fiber.StatusBadRequest).JSON(Response{
 		Status:  "error",
@@ -40,6 +72,35 @@ func NotFound(c *fiber.Ctx, message string) error {
 	})
 }
 
+func Forbidden(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusForbidden).JSON(Response{
+		Status:  "error",
+		Message: message,
+	})
+}
+
+func Gone(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusGone).JSON(Response{
+		Status:  "error",
+		Message: message,
+	})
+}
+
+func PayloadTooLarge(c *fiber.Ctx, message string) error {
+	return c.Status(fiber.StatusRequestEntityTooLarge).JSON(Response{
+		Status:  "error",
+		Message: message,
+	})
+}
+
+func BadGateway(c *fiber.Ctx, message string, err interface{}) error {
+	return c.Status(fiber.StatusBadGateway).JSON(Response{
+		Status:  "error",
+		Message: message,
+		Error:   err,
+	})
+}
+
 func InternalServerError(c *fiber.Ctx, message string, err interface{}) error {
 	return c.Status(fiber.StatusInternalServerError).JSON(Response{
 		Status:  "error",
@@ -0,0 +1,48 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// ReadCSV reads comma or semicolon separated values. Spreadsheet programs
+// in Indonesian locales export with semicolons.
+func ReadCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = sniffDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, record)
+	}
+}
+
+// sniffDelimiter picks the separator that occurs most on the header line.
+func sniffDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+
+	best, bestCount := ',', bytes.Count(line, []byte{','})
+	for _, candidate := range []rune{';', '\t'} {
+		if count := bytes.Count(line, []byte(string(candidate))); count > bestCount {
+			best, bestCount = candidate, count
+		}
+	}
+	return best
+}
@@ -0,0 +1,49 @@
+// Package spreadsheet reads tabular uploads (CSV and XLSX) into rows of
+// strings.
+package spreadsheet
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported file format, use CSV or XLSX")
+
+// DetectFormat tells the format of an upload from its name, falling back to
+// its content.
+func DetectFormat(name string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+
+	// XLSX files are zip archives
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX, nil
+	}
+	if bytes.IndexByte(data, 0) == -1 {
+		return FormatCSV, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Read returns every row of the upload. Row i of the result is line i+1 of
+// the file; rows may have different lengths.
+func Read(format string, data []byte) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(data)
+	case FormatXLSX:
+		return ReadXLSX(data)
+	}
+	return nil, ErrUnsupportedFormat
+}
@@ -0,0 +1,208 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var errNoWorksheet = errors.New("xlsx: workbook has no worksheet")
+
+// Upper bound on a single decompressed part, to reject zip bombs
+const maxXLSXPartSize = 256 << 20
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string, either plain or made of rich text
+// runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX reads the cell text of the first worksheet of an Office Open XML
+// workbook. Formulas are read as their cached values.
+func ReadXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeXMLPart(file, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	file, ok := files[sheetPath]
+	if !ok {
+		return nil, errNoWorksheet
+	}
+	var sheet xlsxWorksheet
+	if err := decodeXMLPart(file, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// Keep row numbers aligned with the sheet when rows are skipped
+		index := row.Index
+		if index <= len(rows) {
+			index = len(rows) + 1
+		}
+		for len(rows) < index-1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			column := i
+			if cell.Ref != "" {
+				column = columnIndex(cell.Ref)
+			}
+			for len(values) < column {
+				values = append(values, "")
+			}
+
+			value := cell.Value
+			switch cell.Type {
+			case "s":
+				var n int
+				if _, err := fmt.Sscan(cell.Value, &n); err != nil || n < 0 || n >= len(shared.Items) {
+					return nil, fmt.Errorf("xlsx: cell %s refers to a missing shared string", cell.Ref)
+				}
+				value = shared.Items[n].String()
+			case "inlineStr":
+				value = cell.Inline.String()
+			case "b":
+				value = map[string]string{"0": "false", "1": "true"}[cell.Value]
+			}
+
+			if column < len(values) {
+				values[column] = value
+			} else {
+				values = append(values, value)
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstSheetPath resolves the part name of the first sheet in workbook order.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	file, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", errors.New("xlsx: missing workbook")
+	}
+	if err := decodeXMLPart(file, &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errNoWorksheet
+	}
+
+	var rels xlsxRelationships
+	if file, ok := files["xl/_rels/workbook.xml.rels"]; ok {
+		if err := decodeXMLPart(file, &rels); err != nil {
+			return "", err
+		}
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+
+	// Workbooks without relationships use the conventional name
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+func decodeXMLPart(file *zip.File, v interface{}) error {
+	if file.UncompressedSize64 > maxXLSXPartSize {
+		return fmt.Errorf("xlsx: %s is too large", file.Name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("xlsx: %s: %w", file.Name, err)
+	}
+	return nil
+}
+
+// columnIndex converts the letters of a cell reference such as "AB12" to a
+// zero-based column number.
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
@@ -0,0 +1,175 @@
+package sru
+
+import (
+	"bytes"
+	"encoding/xml"
+)
+
+// DublinCore is a record in the SRU Dublin Core schema.
+type DublinCore struct {
+	Title       string
+	Creators    []string
+	Subjects    []string
+	Description string
+	Publisher   string
+	Contributor []string
+	Date        string
+	Type        string
+	Format      string
+	Identifiers []string
+	Language    string
+}
+
+type xmlDublinCore struct {
+	XMLName      xml.Name `xml:"srw_dc:dc"`
+	XmlnsRecord  string   `xml:"xmlns:srw_dc,attr"`
+	XmlnsDC      string   `xml:"xmlns:dc,attr"`
+	Title        string   `xml:"dc:title,omitempty"`
+	Creators     []string `xml:"dc:creator"`
+	Subjects     []string `xml:"dc:subject"`
+	Description  string   `xml:"dc:description,omitempty"`
+	Publisher    string   `xml:"dc:publisher,omitempty"`
+	Contributors []string `xml:"dc:contributor"`
+	Date         string   `xml:"dc:date,omitempty"`
+	Type         string   `xml:"dc:type,omitempty"`
+	Format       string   `xml:"dc:format,omitempty"`
+	Identifiers  []string `xml:"dc:identifier"`
+	Language     string   `xml:"dc:language,omitempty"`
+}
+
+// Encode writes the record as a standalone XML element.
+func (dc *DublinCore) Encode() ([]byte, error) {
+	return xml.Marshal(xmlDublinCore{
+		XmlnsRecord:  DCRecordNS,
+		XmlnsDC:      DCNamespace,
+		Title:        dc.Title,
+		Creators:     dc.Creators,
+		Subjects:     dc.Subjects,
+		Description:  dc.Description,
+		Publisher:    dc.Publisher,
+		Contributors: dc.Contributor,
+		Date:         dc.Date,
+		Type:         dc.Type,
+		Format:       dc.Format,
+		Identifiers:  dc.Identifiers,
+		Language:     dc.Language,
+	})
+}
+
+// EncodeElement writes v as the element name in a default namespace, e.g.
+// a MARCXML record.
+func EncodeElement(v interface{}, name, namespace string) ([]byte, error) {
+	var buf bytes.Buffer
+	start := xml.StartElement{
+		Name: xml.Name{Local: name},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: namespace}},
+	}
+	if err := xml.NewEncoder(&buf).EncodeElement(v, start); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// Explain describes the database in a ZeeRex record.
+type Explain struct {
+	Host        string
+	Port        int
+	Database    string
+	Title       string
+	Description string
+	Sets        []ExplainSet
+	Indexes     []ExplainIndex
+	Schemas     []ExplainSchema
+	// Records returned by default and at most
+	DefaultRecords int
+	MaxRecords     int
+}
+
+type ExplainSet struct {
+	Identifier string `xml:"identifier,attr"`
+	Name       string `xml:"name,attr"`
+}
+
+type ExplainIndex struct {
+	Title string
+	Set   string
+	Name  string
+}
+
+type ExplainSchema struct {
+	Identifier string `xml:"identifier,attr"`
+	Name       string `xml:"name,attr"`
+	Title      string `xml:"title"`
+}
+
+type zeerex struct {
+	XMLName    xml.Name `xml:"explain"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	ServerInfo struct {
+		Protocol string `xml:"protocol,attr"`
+		Version  string `xml:"version,attr"`
+		Host     string `xml:"host"`
+		Port     int    `xml:"port"`
+		Database string `xml:"database"`
+	} `xml:"serverInfo"`
+	DatabaseInfo struct {
+		Title       string `xml:"title"`
+		Description string `xml:"description,omitempty"`
+	} `xml:"databaseInfo"`
+	IndexInfo struct {
+		Sets    []ExplainSet  `xml:"set"`
+		Indexes []zeerexIndex `xml:"index"`
+	} `xml:"indexInfo"`
+	SchemaInfo struct {
+		Schemas []ExplainSchema `xml:"schema"`
+	} `xml:"schemaInfo"`
+	ConfigInfo struct {
+		Defaults []zeerexSetting `xml:"default"`
+		Settings []zeerexSetting `xml:"setting"`
+	} `xml:"configInfo"`
+}
+
+type zeerexIndex struct {
+	Title string `xml:"title"`
+	Map   struct {
+		Name struct {
+			Set  string `xml:"set,attr"`
+			Name string `xml:",chardata"`
+		} `xml:"name"`
+	} `xml:"map"`
+}
+
+type zeerexSetting struct {
+	Type  string `xml:"type,attr"`
+	Value int    `xml:",chardata"`
+}
+
+// Encode writes the ZeeRex record as a standalone XML element.
+func (e *Explain) Encode() ([]byte, error) {
+	doc := zeerex{Xmlns: ExplainNS}
+	doc.ServerInfo.Protocol = "SRU"
+	doc.ServerInfo.Version = Version
+	doc.ServerInfo.Host = e.Host
+	doc.ServerInfo.Port = e.Port
+	doc.ServerInfo.Database = e.Database
+	doc.DatabaseInfo.Title = e.Title
+	doc.DatabaseInfo.Description = e.Description
+	doc.IndexInfo.Sets = e.Sets
+	for _, index := range e.Indexes {
+		var entry zeerexIndex
+		entry.Title = index.Title
+		entry.Map.Name.Set = index.Set
+		entry.Map.Name.Name = index.Name
+		doc.IndexInfo.Indexes = append(doc.IndexInfo.Indexes, entry)
+	}
+	doc.SchemaInfo.Schemas = e.Schemas
+	doc.ConfigInfo.Defaults = []zeerexSetting{{Type: "numberOfRecords", Value: e.DefaultRecords}}
+	doc.ConfigInfo.Settings = []zeerexSetting{{Type: "maximumRecords", Value: e.MaxRecords}}
+	return xml.Marshal(doc)
+}
@@ -0,0 +1,243 @@
+// Package sru writes SRU 1.2 (Search/Retrieve via URL) responses: explain,
+// searchRetrieve and scan, with diagnostics.
+package sru
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+const (
+	Version     = "1.2"
+	Namespace   = "http://www.loc.gov/zing/srw/"
+	ContentType = "text/xml; charset=utf-8"
+
+	diagnosticNS = "http://www.loc.gov/zing/srw/diagnostic/"
+	ExplainNS    = "http://explain.z3950.org/dtd/2.0/"
+)
+
+// Record schemas
+const (
+	SchemaDC      = "info:srw/schema/1/dc-v1.1"
+	SchemaMARCXML = "info:srw/schema/1/marcxml-v1.1"
+	DCNamespace   = "http://purl.org/dc/elements/1.1/"
+	DCRecordNS    = "info:srw/schema/1/dc-schema"
+)
+
+// Record packings
+const (
+	PackingXML    = "xml"
+	PackingString = "string"
+)
+
+// Diagnostic numbers from the SRU diagnostics list
+const (
+	DiagGeneral                = 1
+	DiagUnsupportedOperation   = 4
+	DiagUnsupportedVersion     = 5
+	DiagUnsupportedValue       = 6
+	DiagMissingParameter       = 7
+	DiagQuerySyntax            = 10
+	DiagUnsupportedIndex       = 16
+	DiagInvalidTerm            = 36
+	DiagUnsupportedRelation    = 19
+	DiagUnsupportedModifier    = 20
+	DiagUnsupportedBoolean     = 37
+	DiagStartOutOfRange        = 61
+	DiagUnknownSchema          = 66
+	DiagUnsupportedPacking     = 71
+	DiagScanPositionOutOfRange = 120
+)
+
+// Diagnostic is an SRU error. It is returned in the response body, never
+// as an HTTP error.
+type Diagnostic struct {
+	URI     string `xml:"uri"`
+	Details string `xml:"details,omitempty"`
+	Message string `xml:"message,omitempty"`
+}
+
+func NewDiagnostic(number int, details, message string) *Diagnostic {
+	return &Diagnostic{
+		URI:     fmt.Sprintf("info:srw/diagnostic/1/%d", number),
+		Details: details,
+		Message: message,
+	}
+}
+
+func (d *Diagnostic) Error() string {
+	if d.Details != "" {
+		return d.Message + ": " + d.Details
+	}
+	return d.Message
+}
+
+// Record is one result. Data holds the record as XML.
+type Record struct {
+	Schema   string
+	Packing  string
+	Data     []byte
+	Position int
+}
+
+type xmlRecord struct {
+	Schema   string        `xml:"recordSchema"`
+	Packing  string        `xml:"recordPacking"`
+	Data     xmlRecordData `xml:"recordData"`
+	Position int           `xml:"recordPosition,omitempty"`
+}
+
+type xmlRecordData struct {
+	Inner string `xml:",innerxml"`
+}
+
+func recordXML(r Record) xmlRecord {
+	data := string(r.Data)
+	// Packed as a string, the record is escaped text
+	if r.Packing == PackingString {
+		data = escape(data)
+	}
+	return xmlRecord{Schema: r.Schema, Packing: r.Packing, Data: xmlRecordData{Inner: data}, Position: r.Position}
+}
+
+type xmlDiagnostics struct {
+	Diagnostics []xmlDiagnostic `xml:"diagnostic"`
+}
+
+type xmlDiagnostic struct {
+	Xmlns string `xml:"xmlns,attr"`
+	Diagnostic
+}
+
+func diagnosticsXML(diagnostics []*Diagnostic) *xmlDiagnostics {
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	result := &xmlDiagnostics{}
+	for _, d := range diagnostics {
+		result.Diagnostics = append(result.Diagnostics, xmlDiagnostic{Xmlns: diagnosticNS, Diagnostic: *d})
+	}
+	return result
+}
+
+// SearchRetrieveResponse answers a searchRetrieve request.
+type SearchRetrieveResponse struct {
+	NumberOfRecords    int64
+	Records            []Record
+	NextRecordPosition int
+	Query              string
+	Diagnostics        []*Diagnostic
+}
+
+type xmlSearchRetrieveResponse struct {
+	XMLName            xml.Name        `xml:"searchRetrieveResponse"`
+	Xmlns              string          `xml:"xmlns,attr"`
+	Version            string          `xml:"version"`
+	NumberOfRecords    int64           `xml:"numberOfRecords"`
+	Records            *xmlRecords     `xml:"records,omitempty"`
+	NextRecordPosition int             `xml:"nextRecordPosition,omitempty"`
+	Echoed             *echoedQuery    `xml:"echoedSearchRetrieveRequest,omitempty"`
+	Diagnostics        *xmlDiagnostics `xml:"diagnostics,omitempty"`
+}
+
+type xmlRecords struct {
+	Records []xmlRecord `xml:"record"`
+}
+
+type echoedQuery struct {
+	Version string `xml:"version"`
+	Query   string `xml:"query"`
+}
+
+func (r *SearchRetrieveResponse) Encode() ([]byte, error) {
+	doc := xmlSearchRetrieveResponse{
+		Xmlns:              Namespace,
+		Version:            Version,
+		NumberOfRecords:    r.NumberOfRecords,
+		NextRecordPosition: r.NextRecordPosition,
+		Diagnostics:        diagnosticsXML(r.Diagnostics),
+	}
+	if len(r.Records) > 0 {
+		doc.Records = &xmlRecords{}
+		for _, record := range r.Records {
+			doc.Records.Records = append(doc.Records.Records, recordXML(record))
+		}
+	}
+	if r.Query != "" {
+		doc.Echoed = &echoedQuery{Version: Version, Query: r.Query}
+	}
+	return marshal(doc)
+}
+
+// ScanTerm is one index term with the number of records it finds.
+type ScanTerm struct {
+	Value           string `xml:"value"`
+	NumberOfRecords int64  `xml:"numberOfRecords"`
+	DisplayTerm     string `xml:"displayTerm,omitempty"`
+}
+
+// ScanResponse answers a scan request.
+type ScanResponse struct {
+	Terms       []ScanTerm
+	Diagnostics []*Diagnostic
+}
+
+type xmlScanResponse struct {
+	XMLName     xml.Name        `xml:"scanResponse"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	Version     string          `xml:"version"`
+	Terms       *xmlTerms       `xml:"terms,omitempty"`
+	Diagnostics *xmlDiagnostics `xml:"diagnostics,omitempty"`
+}
+
+type xmlTerms struct {
+	Terms []ScanTerm `xml:"term"`
+}
+
+func (r *ScanResponse) Encode() ([]byte, error) {
+	doc := xmlScanResponse{
+		Xmlns:       Namespace,
+		Version:     Version,
+		Diagnostics: diagnosticsXML(r.Diagnostics),
+	}
+	if len(r.Terms) > 0 {
+		doc.Terms = &xmlTerms{Terms: r.Terms}
+	}
+	return marshal(doc)
+}
+
+// ExplainResponse describes the server in a ZeeRex record. It also carries
+// diagnostics for requests that fail before an operation is known.
+type ExplainResponse struct {
+	Record      *Record
+	Diagnostics []*Diagnostic
+}
+
+type xmlExplainResponse struct {
+	XMLName     xml.Name        `xml:"explainResponse"`
+	Xmlns       string          `xml:"xmlns,attr"`
+	Version     string          `xml:"version"`
+	Record      *xmlRecord      `xml:"record,omitempty"`
+	Diagnostics *xmlDiagnostics `xml:"diagnostics,omitempty"`
+}
+
+func (r *ExplainResponse) Encode() ([]byte, error) {
+	doc := xmlExplainResponse{
+		Xmlns:       Namespace,
+		Version:     Version,
+		Diagnostics: diagnosticsXML(r.Diagnostics),
+	}
+	if r.Record != nil {
+		record := recordXML(*r.Record)
+		doc.Record = &record
+	}
+	return marshal(doc)
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,204 @@
+// Package syndication writes news feeds as Atom 1.0 or RSS 2.0.
+package syndication
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Feed formats
+const (
+	FormatAtom = "atom"
+	FormatRSS  = "rss"
+)
+
+var ErrUnsupportedFormat = errors.New("syndication: unsupported format, use atom or rss")
+
+type Feed struct {
+	ID          string
+	Title       string
+	Description string
+	Link        string // the page the feed is about
+	Self        string // the feed itself
+	Author      string
+	Updated     time.Time
+	Items       []Item
+}
+
+type Item struct {
+	ID         string
+	Title      string
+	Link       string
+	Summary    string
+	Authors    []string
+	Categories []string
+	Published  time.Time
+	Updated    time.Time
+}
+
+// ContentType returns the media type of a feed format.
+func ContentType(format string) string {
+	if format == FormatRSS {
+		return "application/rss+xml"
+	}
+	return "application/atom+xml"
+}
+
+// Encode writes feed in format.
+func Encode(feed *Feed, format string) ([]byte, error) {
+	switch format {
+	case FormatAtom:
+		return marshal(atomFeed(feed))
+	case FormatRSS:
+		return marshal(rssFeed(feed))
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type atomDoc struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  *atomPerson `xml:"author,omitempty"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Link       atomLink       `xml:"link"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+func atomFeed(feed *Feed) atomDoc {
+	doc := atomDoc{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      feed.ID,
+		Title:   feed.Title,
+		Updated: feed.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Rel: "self", Href: feed.Self, Type: ContentType(FormatAtom)},
+			{Rel: "alternate", Href: feed.Link},
+		},
+		Entries: []atomEntry{},
+	}
+	// Entries without authors take the feed's
+	if feed.Author != "" {
+		doc.Author = &atomPerson{Name: feed.Author}
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Published: item.Published.UTC().Format(time.RFC3339),
+			Updated:   item.Updated.UTC().Format(time.RFC3339),
+			Summary:   item.Summary,
+			Link:      atomLink{Rel: "alternate", Href: item.Link},
+		}
+		for _, name := range item.Authors {
+			entry.Authors = append(entry.Authors, atomPerson{Name: name})
+		}
+		for _, category := range item.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+	return doc
+}
+
+type rssDoc struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	XmlnsAtom string     `xml:"xmlns:atom,attr"`
+	XmlnsDC   string     `xml:"xmlns:dc,attr"`
+	Channel   rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          rssSelf   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssSelf is the atom:link RSS feeds use to point at themselves.
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description,omitempty"`
+	Creators    []string `xml:"dc:creator"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+func rssFeed(feed *Feed) rssDoc {
+	doc := rssDoc{
+		Version:   "2.0",
+		XmlnsAtom: "http://www.w3.org/2005/Atom",
+		XmlnsDC:   "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			Description:   feed.Description,
+			Self:          rssSelf{Href: feed.Self, Rel: "self", Type: ContentType(FormatRSS)},
+			LastBuildDate: feed.Updated.UTC().Format(http.TimeFormat),
+		},
+	}
+	for _, item := range feed.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Summary,
+			Creators:    item.Authors,
+			Categories:  item.Categories,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.UTC().Format(http.TimeFormat),
+		})
+	}
+	return doc
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/yooerizkilab/library-system/pkg/isbn"
 )
 
 var validate *validator.Validate
@@ -21,6 +22,11 @@ func init() {
 		}
 		return name
 	})
+
+	// isbn accepts any form isbn.Normalize does, scanned add-ons included
+	validate.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		return isbn.Valid(fl.Field().String())
+	})
 }
 
 // ValidateStruct validates a struct and returns formatted error messages
@@ -52,6 +58,8 @@ func formatError(err validator.FieldError) string {
 		return fmt.Sprintf("%s must be at least %s characters long", field, param)
 	case "max":
 		return fmt.Sprintf("%s must be at most %s characters long", field, param)
+	case "isbn":
+		return fmt.Sprintf("%s must be a valid ISBN-10 or ISBN-13", field)
 	case "oneof":
 		return fmt.Sprintf("%s must be one of: %s", field, param)
 	default: